@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/services"
+	"chat_app_backend/config"
+	"chat_app_backend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AuditLogController 處理伺服器稽核紀錄相關請求
+type AuditLogController struct {
+	config          *config.Config
+	mongoConnect    *mongo.Database
+	auditLogService services.AuditLogService
+}
+
+func NewAuditLogController(cfg *config.Config, mongodb *mongo.Database, auditLogService services.AuditLogService) *AuditLogController {
+	return &AuditLogController{
+		config:          cfg,
+		mongoConnect:    mongodb,
+		auditLogService: auditLogService,
+	}
+}
+
+// GetServerAuditLog 獲取伺服器稽核紀錄（可依 action、actor_id 篩選，以 before 游標分頁）
+func (ac *AuditLogController) GetServerAuditLog(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var query models.AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "查詢參數格式錯誤",
+		})
+		return
+	}
+
+	results, msgOpt := ac.auditLogService.GetServerAuditLogs(c.Request.Context(), userID, c.Param("server_id"), query)
+	if msgOpt != nil {
+		ErrorResponse(c, auditLogErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, results, "獲取稽核紀錄成功")
+}
+
+// auditLogErrorStatus 將稽核紀錄錯誤碼對應到 HTTP 狀態碼
+func auditLogErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams:
+		return http.StatusBadRequest
+	case models.ErrNoServerPermission, models.ErrNotServerMember:
+		return http.StatusForbidden
+	case models.ErrServerNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
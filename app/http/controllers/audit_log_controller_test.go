@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestAuditLogController_GetServerAuditLog 測試獲取伺服器稽核紀錄
+func TestAuditLogController_GetServerAuditLog(t *testing.T) {
+	t.Run("依篩選條件查詢", func(t *testing.T) {
+		mockAuditLogService := new(mocks.AuditLogService)
+		query := models.AuditLogQuery{Action: "role_update", ActorID: "actor123", Before: "log123", Limit: 10}
+
+		mockAuditLogService.On("GetServerAuditLogs", mock.Anything, "user123", "server123", query).
+			Return(&models.AuditLogResults{Entries: []models.AuditLogResponse{{ID: "log100", Action: "role_update"}}, NextCursor: "log100"}, nil)
+
+		controller := NewAuditLogController(&config.Config{}, nil, mockAuditLogService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.GET("/servers/:server_id/audit-log", controller.GetServerAuditLog)
+
+		req, _ := http.NewRequest(http.MethodGet, "/servers/server123/audit-log?action=role_update&actor_id=actor123&before=log123&limit=10", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "獲取稽核紀錄成功", response.Message)
+
+		mockAuditLogService.AssertExpectations(t)
+	})
+
+	t.Run("沒有查看權限", func(t *testing.T) {
+		mockAuditLogService := new(mocks.AuditLogService)
+		mockAuditLogService.On("GetServerAuditLogs", mock.Anything, "user123", "server123", models.AuditLogQuery{}).
+			Return(nil, &models.MessageOptions{Code: models.ErrNoServerPermission, Message: "沒有權限"})
+
+		controller := NewAuditLogController(&config.Config{}, nil, mockAuditLogService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.GET("/servers/:server_id/audit-log", controller.GetServerAuditLog)
+
+		req, _ := http.NewRequest(http.MethodGet, "/servers/server123/audit-log", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockAuditLogService.AssertExpectations(t)
+	})
+}
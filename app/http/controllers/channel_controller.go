@@ -190,11 +190,7 @@ func (cc *ChannelController) CreateChannel(c *gin.Context) {
 	// 創建頻道
 	createdChannel, msgOpt := cc.channelService.CreateChannel(userID, channel)
 	if msgOpt != nil {
-		statusCode := http.StatusInternalServerError
-		if msgOpt.Code == models.ErrUnauthorized {
-			statusCode = http.StatusForbidden
-		}
-		ErrorResponse(c, statusCode, *msgOpt)
+		ErrorResponse(c, channelErrorStatus(msgOpt.Code), *msgOpt)
 		return
 	}
 
@@ -279,11 +275,7 @@ func (cc *ChannelController) UpdateChannel(c *gin.Context) {
 	// 更新頻道
 	updatedChannel, msgOpt := cc.channelService.UpdateChannel(userID, channelID, updates)
 	if msgOpt != nil {
-		statusCode := http.StatusInternalServerError
-		if msgOpt.Code == models.ErrUnauthorized {
-			statusCode = http.StatusForbidden
-		}
-		ErrorResponse(c, statusCode, *msgOpt)
+		ErrorResponse(c, channelErrorStatus(msgOpt.Code), *msgOpt)
 		return
 	}
 
@@ -334,6 +326,121 @@ func (cc *ChannelController) DeleteChannel(c *gin.Context) {
 	SuccessResponse(c, nil, "刪除頻道成功")
 }
 
+// CreateCategory 創建頻道類別
+func (cc *ChannelController) CreateCategory(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var request models.CreateChannelCategoryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的請求格式",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	category, msgOpt := cc.channelService.CreateCategory(userID, c.Param("server_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, channelErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Status:  "success",
+		Message: "創建頻道類別成功",
+		Data:    category,
+	})
+}
+
+// UpdateCategory 重新命名頻道類別
+func (cc *ChannelController) UpdateCategory(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var request models.UpdateChannelCategoryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的請求格式",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	category, msgOpt := cc.channelService.UpdateCategory(userID, c.Param("category_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, channelErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, category, "更新頻道類別成功")
+}
+
+// DeleteCategory 刪除頻道類別，類別內的頻道移到未分類
+func (cc *ChannelController) DeleteCategory(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	if msgOpt := cc.channelService.DeleteCategory(userID, c.Param("category_id")); msgOpt != nil {
+		ErrorResponse(c, channelErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "刪除頻道類別成功")
+}
+
+// ReorderChannels 批量調整類別與頻道排序，返回調整後的頻道樹
+func (cc *ChannelController) ReorderChannels(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var request models.ReorderChannelsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的請求格式",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	tree, msgOpt := cc.channelService.ReorderChannels(userID, c.Param("server_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, channelErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, tree, "調整頻道排序成功")
+}
+
+// channelErrorStatus 將頻道與類別服務錯誤碼對應到 HTTP 狀態碼
+func channelErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams:
+		return http.StatusBadRequest
+	case models.ErrUnauthorized:
+		return http.StatusForbidden
+	case models.ErrChannelNotFound, models.ErrCategoryNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // Request DTOs for Channel operations
 type CreateChannelRequest struct {
 	Name       string `json:"name" binding:"required" example:"一般"`
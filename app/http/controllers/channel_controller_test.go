@@ -34,12 +34,22 @@ func TestChannelController_GetChannelsByServerID(t *testing.T) {
 
 		channelID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
-		expectedChannels := []models.ChannelResponse{
-			{
-				ID:       channelID,
-				ServerID: serverID,
-				Name:     "一般",
-				Type:     "text",
+		expectedChannels := &models.ChannelTreeResponse{
+			Uncategorized: []models.ChannelResponse{},
+			Categories: []models.ChannelCategoryResponse{
+				{
+					ID:       primitive.NewObjectID().Hex(),
+					ServerID: serverID.Hex(),
+					Name:     "文字頻道",
+					Channels: []models.ChannelResponse{
+						{
+							ID:       channelID,
+							ServerID: serverID,
+							Name:     "一般",
+							Type:     "text",
+						},
+					},
+				},
 			},
 		}
 
@@ -62,6 +72,10 @@ func TestChannelController_GetChannelsByServerID(t *testing.T) {
 		json.Unmarshal(w.Body.Bytes(), &response)
 		assert.Equal(t, "success", response.Status)
 		assert.Equal(t, "獲取頻道列表成功", response.Message)
+		data := response.Data.(map[string]any)
+		categories := data["categories"].([]any)
+		assert.Len(t, categories, 1)
+		assert.Len(t, categories[0].(map[string]any)["channels"], 1)
 
 		mockChannelService.AssertExpectations(t)
 	})
@@ -671,3 +685,212 @@ func TestChannelController_DeleteChannel(t *testing.T) {
 		mockChannelService.AssertExpectations(t)
 	})
 }
+
+// TestChannelController_CreateCategory 測試創建頻道類別
+func TestChannelController_CreateCategory(t *testing.T) {
+	t.Run("成功創建類別", func(t *testing.T) {
+		mockChannelService := new(mocks.ChannelService)
+		request := models.CreateChannelCategoryRequest{Name: "公告", CategoryType: "custom"}
+		mockChannelService.On("CreateCategory", "user123", "server123", request).
+			Return(&models.ChannelCategoryResponse{ID: "category123", Name: "公告", Position: 3}, (*models.MessageOptions)(nil))
+
+		controller := NewChannelController(&config.Config{}, nil, mockChannelService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/categories", controller.CreateCategory)
+
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server123/categories", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, "創建頻道類別成功", response.Message)
+
+		mockChannelService.AssertExpectations(t)
+	})
+
+	t.Run("缺少類別名稱", func(t *testing.T) {
+		mockChannelService := new(mocks.ChannelService)
+		controller := NewChannelController(&config.Config{}, nil, mockChannelService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/categories", controller.CreateCategory)
+
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server123/categories", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockChannelService.AssertNotCalled(t, "CreateCategory")
+	})
+
+	t.Run("沒有管理頻道權限", func(t *testing.T) {
+		mockChannelService := new(mocks.ChannelService)
+		mockChannelService.On("CreateCategory", "user123", "server123", models.CreateChannelCategoryRequest{Name: "公告"}).
+			Return(nil, &models.MessageOptions{Code: models.ErrUnauthorized, Message: "用戶沒有權限在該伺服器創建頻道類別"})
+
+		controller := NewChannelController(&config.Config{}, nil, mockChannelService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/categories", controller.CreateCategory)
+
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server123/categories", bytes.NewBufferString(`{"name":"公告"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockChannelService.AssertExpectations(t)
+	})
+}
+
+// TestChannelController_UpdateCategory 測試重新命名頻道類別
+func TestChannelController_UpdateCategory(t *testing.T) {
+	t.Run("成功重新命名", func(t *testing.T) {
+		mockChannelService := new(mocks.ChannelService)
+		mockChannelService.On("UpdateCategory", "user123", "category123", models.UpdateChannelCategoryRequest{Name: "新名稱"}).
+			Return(&models.ChannelCategoryResponse{ID: "category123", Name: "新名稱"}, (*models.MessageOptions)(nil))
+
+		controller := NewChannelController(&config.Config{}, nil, mockChannelService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/categories/:category_id", controller.UpdateCategory)
+
+		req, _ := http.NewRequest(http.MethodPut, "/categories/category123", bytes.NewBufferString(`{"name":"新名稱"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockChannelService.AssertExpectations(t)
+	})
+
+	t.Run("類別不存在", func(t *testing.T) {
+		mockChannelService := new(mocks.ChannelService)
+		mockChannelService.On("UpdateCategory", "user123", "category123", models.UpdateChannelCategoryRequest{Name: "新名稱"}).
+			Return(nil, &models.MessageOptions{Code: models.ErrCategoryNotFound, Message: "頻道類別不存在"})
+
+		controller := NewChannelController(&config.Config{}, nil, mockChannelService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/categories/:category_id", controller.UpdateCategory)
+
+		req, _ := http.NewRequest(http.MethodPut, "/categories/category123", bytes.NewBufferString(`{"name":"新名稱"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockChannelService.AssertExpectations(t)
+	})
+}
+
+// TestChannelController_DeleteCategory 測試刪除頻道類別
+func TestChannelController_DeleteCategory(t *testing.T) {
+	mockChannelService := new(mocks.ChannelService)
+	mockChannelService.On("DeleteCategory", "user123", "category123").Return((*models.MessageOptions)(nil))
+
+	controller := NewChannelController(&config.Config{}, nil, mockChannelService)
+
+	router := setupTestRouter()
+	router.Use(mocks.MockAuthMiddleware("user123"))
+	router.DELETE("/categories/:category_id", controller.DeleteCategory)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/categories/category123", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response models.APIResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "刪除頻道類別成功", response.Message)
+
+	mockChannelService.AssertExpectations(t)
+}
+
+// TestChannelController_ReorderChannels 測試批量調整頻道排序
+func TestChannelController_ReorderChannels(t *testing.T) {
+	t.Run("成功調整排序", func(t *testing.T) {
+		mockChannelService := new(mocks.ChannelService)
+		request := models.ReorderChannelsRequest{
+			Categories: []models.CategoryPositionRequest{{ID: "category1", Position: 0}},
+			Channels:   []models.ChannelPositionRequest{{ID: "channel1", CategoryID: "category1", Position: 2}},
+		}
+		mockChannelService.On("ReorderChannels", "user123", "server123", request).
+			Return(&models.ChannelTreeResponse{Uncategorized: []models.ChannelResponse{}, Categories: []models.ChannelCategoryResponse{}}, (*models.MessageOptions)(nil))
+
+		controller := NewChannelController(&config.Config{}, nil, mockChannelService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/servers/:server_id/channels/order", controller.ReorderChannels)
+
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPut, "/servers/server123/channels/order", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "調整頻道排序成功", response.Message)
+
+		mockChannelService.AssertExpectations(t)
+	})
+
+	t.Run("排序位置為負數", func(t *testing.T) {
+		mockChannelService := new(mocks.ChannelService)
+		controller := NewChannelController(&config.Config{}, nil, mockChannelService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/servers/:server_id/channels/order", controller.ReorderChannels)
+
+		req, _ := http.NewRequest(http.MethodPut, "/servers/server123/channels/order", bytes.NewBufferString(`{"channels":[{"id":"channel1","position":-1}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockChannelService.AssertNotCalled(t, "ReorderChannels")
+	})
+
+	t.Run("包含不存在的頻道", func(t *testing.T) {
+		mockChannelService := new(mocks.ChannelService)
+		request := models.ReorderChannelsRequest{
+			Channels: []models.ChannelPositionRequest{{ID: "channel1", Position: 0}},
+		}
+		mockChannelService.On("ReorderChannels", "user123", "server123", request).
+			Return(nil, &models.MessageOptions{Code: models.ErrChannelNotFound, Message: "頻道不存在"})
+
+		controller := NewChannelController(&config.Config{}, nil, mockChannelService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/servers/:server_id/channels/order", controller.ReorderChannels)
+
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPut, "/servers/server123/channels/order", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockChannelService.AssertExpectations(t)
+	})
+}
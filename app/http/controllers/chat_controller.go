@@ -66,12 +66,13 @@ func (cc *ChatController) HandleConnections(c *gin.Context) {
 	// 解析參數
 	token := c.Query("token")
 
-	// 取得 userID
-	userID, _, err := utils.GetUserFromToken(token)
+	// 取得 userID 與登入工作階段
+	claims, err := utils.ParseAccessToken(token)
 	if err != nil {
 		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrInvalidToken})
 		return
 	}
+	userID := claims.UserID
 
 	// 升級 HTTP 連接為 WebSocket
 	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
@@ -82,7 +83,7 @@ func (cc *ChatController) HandleConnections(c *gin.Context) {
 
 	slog.Info("用戶 WebSocket 連線已建立", "user_id", userID)
 	// 使用聊天服務處理連接
-	cc.chatService.HandleWebSocket(ws, userID)
+	cc.chatService.HandleWebSocket(ws, userID, claims.SessionID)
 }
 
 // GetDMRoomList 獲取用戶的聊天列表
@@ -202,7 +203,10 @@ func (cc *ChatController) GetChannelMessages(c *gin.Context) {
 	limit := c.Query("limit")
 
 	// 使用service層的業務邏輯
-	messages, msgOpt := cc.chatService.GetChannelMessages(c.Request.Context(), userID, channelID, before, after, limit)
+	// top_level=true 時只回傳頂層訊息（不含討論串回覆）
+	topLevelOnly := c.Query("top_level") == "true"
+
+	messages, msgOpt := cc.chatService.GetChannelMessages(c.Request.Context(), userID, channelID, before, after, limit, topLevelOnly)
 	if msgOpt != nil {
 		ErrorResponse(c, http.StatusBadRequest, *msgOpt)
 		return
@@ -210,3 +214,247 @@ func (cc *ChatController) GetChannelMessages(c *gin.Context) {
 
 	SuccessResponse(c, messages, "獲取頻道訊息成功")
 }
+
+// SearchMessages 全文搜尋訊息（僅限用戶可讀取的私聊與頻道）
+func (cc *ChatController) SearchMessages(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var request models.MessageSearchRequest
+	if err := c.ShouldBindQuery(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "查詢參數格式錯誤",
+		})
+		return
+	}
+
+	results, msgOpt := cc.chatService.SearchMessages(c.Request.Context(), userID, request)
+	if msgOpt != nil {
+		ErrorResponse(c, messageErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, results, "搜尋完成")
+}
+
+// GetAttachment 獲取訊息附件資訊與檔案連結（需具備附件所屬房間的存取權限）
+func (cc *ChatController) GetAttachment(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	attachment, msgOpt := cc.chatService.GetAttachment(c.Request.Context(), userID, c.Param("file_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, messageErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, attachment, "獲取附件成功")
+}
+
+// GetDMThreadMessages 獲取私聊討論串回覆
+func (cc *ChatController) GetDMThreadMessages(c *gin.Context) {
+	cc.getThreadMessages(c, models.RoomTypeDM, c.Param("room_id"))
+}
+
+// GetChannelThreadMessages 獲取頻道討論串回覆
+func (cc *ChatController) GetChannelThreadMessages(c *gin.Context) {
+	cc.getThreadMessages(c, models.RoomTypeChannel, c.Param("channel_id"))
+}
+
+// getThreadMessages 獲取討論串回覆的共用處理
+func (cc *ChatController) getThreadMessages(c *gin.Context, roomType models.RoomType, roomID string) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	before := c.Query("before")
+	after := c.Query("after")
+	limit := c.Query("limit")
+
+	messages, msgOpt := cc.chatService.GetThreadMessages(c.Request.Context(), userID, roomType, roomID, c.Param("id"), before, after, limit)
+	if msgOpt != nil {
+		ErrorResponse(c, messageErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, messages, "獲取討論串訊息成功")
+}
+
+// EditDMMessage 編輯私聊訊息
+func (cc *ChatController) EditDMMessage(c *gin.Context) {
+	cc.editMessage(c, models.RoomTypeDM, c.Param("room_id"))
+}
+
+// DeleteDMMessage 刪除私聊訊息
+func (cc *ChatController) DeleteDMMessage(c *gin.Context) {
+	cc.deleteMessage(c, models.RoomTypeDM, c.Param("room_id"))
+}
+
+// EditChannelMessage 編輯頻道訊息
+func (cc *ChatController) EditChannelMessage(c *gin.Context) {
+	cc.editMessage(c, models.RoomTypeChannel, c.Param("channel_id"))
+}
+
+// DeleteChannelMessage 刪除頻道訊息
+func (cc *ChatController) DeleteChannelMessage(c *gin.Context) {
+	cc.deleteMessage(c, models.RoomTypeChannel, c.Param("channel_id"))
+}
+
+// editMessage 編輯訊息的共用處理
+func (cc *ChatController) editMessage(c *gin.Context, roomType models.RoomType, roomID string) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var requestBody EditMessageRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "請求參數錯誤",
+		})
+		return
+	}
+
+	message, msgOpt := cc.chatService.EditMessage(c.Request.Context(), userID, roomType, roomID, c.Param("id"), requestBody.Content)
+	if msgOpt != nil {
+		ErrorResponse(c, messageErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, message, "訊息編輯成功")
+}
+
+// deleteMessage 刪除訊息的共用處理
+func (cc *ChatController) deleteMessage(c *gin.Context, roomType models.RoomType, roomID string) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	msgOpt := cc.chatService.DeleteMessage(c.Request.Context(), userID, roomType, roomID, c.Param("id"))
+	if msgOpt != nil {
+		ErrorResponse(c, messageErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "訊息刪除成功")
+}
+
+// AddDMReaction 新增私聊訊息表情回應
+func (cc *ChatController) AddDMReaction(c *gin.Context) {
+	cc.updateReaction(c, models.RoomTypeDM, c.Param("room_id"), true)
+}
+
+// RemoveDMReaction 移除私聊訊息表情回應
+func (cc *ChatController) RemoveDMReaction(c *gin.Context) {
+	cc.updateReaction(c, models.RoomTypeDM, c.Param("room_id"), false)
+}
+
+// AddChannelReaction 新增頻道訊息表情回應
+func (cc *ChatController) AddChannelReaction(c *gin.Context) {
+	cc.updateReaction(c, models.RoomTypeChannel, c.Param("channel_id"), true)
+}
+
+// RemoveChannelReaction 移除頻道訊息表情回應
+func (cc *ChatController) RemoveChannelReaction(c *gin.Context) {
+	cc.updateReaction(c, models.RoomTypeChannel, c.Param("channel_id"), false)
+}
+
+// updateReaction 表情回應的共用處理
+func (cc *ChatController) updateReaction(c *gin.Context, roomType models.RoomType, roomID string, add bool) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var message *models.MessageResponse
+	var msgOpt *models.MessageOptions
+	if add {
+		message, msgOpt = cc.chatService.AddReaction(c.Request.Context(), userID, roomType, roomID, c.Param("id"), c.Param("emoji"))
+	} else {
+		message, msgOpt = cc.chatService.RemoveReaction(c.Request.Context(), userID, roomType, roomID, c.Param("id"), c.Param("emoji"))
+	}
+	if msgOpt != nil {
+		ErrorResponse(c, messageErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, message, "表情回應更新成功")
+}
+
+// MarkDMRoomRead 標記私聊房間已讀
+func (cc *ChatController) MarkDMRoomRead(c *gin.Context) {
+	cc.markRoomRead(c, models.RoomTypeDM, c.Param("room_id"))
+}
+
+// MarkChannelRead 標記頻道已讀
+func (cc *ChatController) MarkChannelRead(c *gin.Context) {
+	cc.markRoomRead(c, models.RoomTypeChannel, c.Param("channel_id"))
+}
+
+// markRoomRead 標記已讀的共用處理，未指定 message_id 時標記至最新訊息
+func (cc *ChatController) markRoomRead(c *gin.Context, roomType models.RoomType, roomID string) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var requestBody MarkReadRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&requestBody); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "請求參數錯誤",
+			})
+			return
+		}
+	}
+
+	readState, msgOpt := cc.chatService.MarkRoomRead(c.Request.Context(), userID, roomType, roomID, requestBody.MessageID)
+	if msgOpt != nil {
+		ErrorResponse(c, messageErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, readState, "已讀狀態更新成功")
+}
+
+// messageErrorStatus 將訊息相關錯誤碼對應至 HTTP 狀態碼
+func messageErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams, models.ErrInvalidAttachment:
+		return http.StatusBadRequest
+	case models.ErrNoPermission:
+		return http.StatusForbidden
+	case models.ErrMessageNotFound, models.ErrNotFound:
+		return http.StatusNotFound
+	case models.ErrMessageDeleted, models.ErrTooManyReactions:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// EditMessageRequest 編輯訊息請求結構
+type EditMessageRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// MarkReadRequest 標記已讀請求
+type MarkReadRequest struct {
+	MessageID string `json:"message_id"`
+}
@@ -282,7 +282,7 @@ func TestChatController_GetChannelMessages(t *testing.T) {
 			},
 		}
 
-		mockChatService.On("GetChannelMessages", mock.Anything, "user123", "channel1", "", "", "").Return(expectedMessages, nil)
+		mockChatService.On("GetChannelMessages", mock.Anything, "user123", "channel1", "", "", "", false).Return(expectedMessages, nil)
 
 		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
 
@@ -324,3 +324,389 @@ func TestChatController_GetChannelMessages(t *testing.T) {
 		assert.Equal(t, "error", response.Status)
 	})
 }
+
+// TestChatController_EditChannelMessage 測試編輯頻道訊息
+func TestChatController_EditChannelMessage(t *testing.T) {
+	t.Run("成功編輯訊息", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		messageID := primitive.NewObjectID()
+
+		mockChatService.On("EditMessage", mock.Anything, "user123", models.RoomTypeChannel, "channel123", messageID.Hex(), "edited").Return(
+			&models.MessageResponse{ID: messageID, Content: "edited"}, nil)
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/channels/:channel_id/messages/:id", controller.EditChannelMessage)
+
+		body, _ := json.Marshal(map[string]string{"content": "edited"})
+		req, _ := http.NewRequest(http.MethodPut, "/channels/channel123/messages/"+messageID.Hex(), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, "訊息編輯成功", response.Message)
+
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("非發送者編輯", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+
+		mockChatService.On("EditMessage", mock.Anything, "user123", models.RoomTypeChannel, "channel123", "msg123", "edited").Return(
+			nil, &models.MessageOptions{Code: models.ErrNoPermission, Message: "只有發送者可以編輯訊息"})
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/channels/:channel_id/messages/:id", controller.EditChannelMessage)
+
+		body, _ := json.Marshal(map[string]string{"content": "edited"})
+		req, _ := http.NewRequest(http.MethodPut, "/channels/channel123/messages/msg123", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, models.ErrNoPermission, response.Code)
+
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("缺少內容", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/channels/:channel_id/messages/:id", controller.EditChannelMessage)
+
+		req, _ := http.NewRequest(http.MethodPut, "/channels/channel123/messages/msg123", bytes.NewBufferString("{}"))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockChatService.AssertNotCalled(t, "EditMessage")
+	})
+}
+
+// TestChatController_DeleteDMMessage 測試刪除私聊訊息
+func TestChatController_DeleteDMMessage(t *testing.T) {
+	t.Run("成功刪除訊息", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("DeleteMessage", mock.Anything, "user123", models.RoomTypeDM, "room123", "msg123").Return(nil)
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.DELETE("/dm_rooms/:room_id/messages/:id", controller.DeleteDMMessage)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/dm_rooms/room123/messages/msg123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "訊息刪除成功", response.Message)
+
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("訊息不存在", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("DeleteMessage", mock.Anything, "user123", models.RoomTypeDM, "room123", "msg123").Return(
+			&models.MessageOptions{Code: models.ErrMessageNotFound, Message: "訊息不存在"})
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.DELETE("/dm_rooms/:room_id/messages/:id", controller.DeleteDMMessage)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/dm_rooms/room123/messages/msg123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockChatService.AssertExpectations(t)
+	})
+}
+
+func TestChatController_MarkChannelRead(t *testing.T) {
+	t.Run("成功標記已讀", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("MarkRoomRead", mock.Anything, "user123", models.RoomTypeChannel, "channel123", "msg123").Return(
+			&models.ReadStateResponse{RoomType: models.RoomTypeChannel, RoomID: "channel123", LastReadMessageID: "msg123"}, nil)
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/channels/:channel_id/read", controller.MarkChannelRead)
+
+		body, _ := json.Marshal(MarkReadRequest{MessageID: "msg123"})
+		req, _ := http.NewRequest(http.MethodPut, "/channels/channel123/read", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "已讀狀態更新成功", response.Message)
+
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("未帶內容時標記至最新訊息", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("MarkRoomRead", mock.Anything, "user123", models.RoomTypeChannel, "channel123", "").Return(
+			&models.ReadStateResponse{RoomType: models.RoomTypeChannel, RoomID: "channel123"}, nil)
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/channels/:channel_id/read", controller.MarkChannelRead)
+
+		req, _ := http.NewRequest(http.MethodPut, "/channels/channel123/read", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("沒有房間權限", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("MarkRoomRead", mock.Anything, "user123", models.RoomTypeChannel, "channel123", "").Return(
+			nil, &models.MessageOptions{Code: models.ErrNoPermission, Message: "您沒有權限存取此房間"})
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/channels/:channel_id/read", controller.MarkChannelRead)
+
+		req, _ := http.NewRequest(http.MethodPut, "/channels/channel123/read", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockChatService.AssertExpectations(t)
+	})
+}
+
+func TestChatController_GetChannelThreadMessages(t *testing.T) {
+	t.Run("成功獲取討論串回覆", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		expectedMessages := []models.MessageResponse{
+			{ID: primitive.NewObjectID(), Content: "reply", ThreadID: "msg123"},
+		}
+		mockChatService.On("GetThreadMessages", mock.Anything, "user123", models.RoomTypeChannel, "channel123", "msg123", "", "", "20").Return(expectedMessages, nil)
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.GET("/channels/:channel_id/messages/:id/thread", controller.GetChannelThreadMessages)
+
+		req, _ := http.NewRequest(http.MethodGet, "/channels/channel123/messages/msg123/thread?limit=20", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "獲取討論串訊息成功", response.Message)
+
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("父訊息不存在", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("GetThreadMessages", mock.Anything, "user123", models.RoomTypeChannel, "channel123", "msg123", "", "", "").Return(
+			nil, &models.MessageOptions{Code: models.ErrMessageNotFound, Message: "訊息不存在"})
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.GET("/channels/:channel_id/messages/:id/thread", controller.GetChannelThreadMessages)
+
+		req, _ := http.NewRequest(http.MethodGet, "/channels/channel123/messages/msg123/thread", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockChatService.AssertExpectations(t)
+	})
+}
+
+func TestChatController_GetAttachment(t *testing.T) {
+	t.Run("成功獲取附件", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("GetAttachment", mock.Anything, "user123", "file123").Return(
+			&models.MessageAttachment{FileName: "photo.png", URL: "/uploads/photo.png"}, nil)
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.GET("/attachments/:file_id", controller.GetAttachment)
+
+		req, _ := http.NewRequest(http.MethodGet, "/attachments/file123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "獲取附件成功", response.Message)
+
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("無權限存取附件", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("GetAttachment", mock.Anything, "user123", "file123").Return(
+			nil, &models.MessageOptions{Code: models.ErrNoPermission, Message: "您沒有權限存取此附件"})
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.GET("/attachments/:file_id", controller.GetAttachment)
+
+		req, _ := http.NewRequest(http.MethodGet, "/attachments/file123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockChatService.AssertExpectations(t)
+	})
+}
+
+func TestChatController_AddChannelReaction(t *testing.T) {
+	t.Run("成功新增表情回應", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("AddReaction", mock.Anything, "user123", models.RoomTypeChannel, "channel123", "msg123", "👍").Return(
+			&models.MessageResponse{Reactions: []models.ReactionSummary{{Emoji: "👍", Count: 1, Reacted: true}}}, nil)
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/channels/:channel_id/messages/:id/reactions/:emoji", controller.AddChannelReaction)
+
+		req, _ := http.NewRequest(http.MethodPut, "/channels/channel123/messages/msg123/reactions/%F0%9F%91%8D", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "表情回應更新成功", response.Message)
+
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("表情種類已達上限", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("RemoveReaction", mock.Anything, "user123", models.RoomTypeChannel, "channel123", "msg123", "x").Return(
+			nil, &models.MessageOptions{Code: models.ErrTooManyReactions, Message: "訊息表情回應種類已達上限"})
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.DELETE("/channels/:channel_id/messages/:id/reactions/:emoji", controller.RemoveChannelReaction)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/channels/channel123/messages/msg123/reactions/x", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockChatService.AssertExpectations(t)
+	})
+}
+
+func TestChatController_SearchMessages(t *testing.T) {
+	t.Run("成功搜尋訊息", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		expectedRequest := models.MessageSearchRequest{Query: "hello", ServerID: "server123", From: 1000, Limit: 10}
+		mockChatService.On("SearchMessages", mock.Anything, "user123", expectedRequest).Return(
+			&models.MessageSearchResults{Messages: []models.MessageResponse{{Content: "hello world"}}, NextCursor: "msg1"}, nil)
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.GET("/search/messages", controller.SearchMessages)
+
+		req, _ := http.NewRequest(http.MethodGet, "/search/messages?q=hello&server_id=server123&from=1000&limit=10", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "搜尋完成", response.Message)
+
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("無權限搜尋伺服器", func(t *testing.T) {
+		mockChatService := new(mocks.ChatService)
+		mockChatService.On("SearchMessages", mock.Anything, "user123", mock.AnythingOfType("models.MessageSearchRequest")).Return(
+			nil, &models.MessageOptions{Code: models.ErrNoPermission, Message: "您不是該伺服器的成員"})
+
+		controller := NewChatController(&config.Config{}, nil, mockChatService, nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.GET("/search/messages", controller.SearchMessages)
+
+		req, _ := http.NewRequest(http.MethodGet, "/search/messages?q=hello&server_id=server123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("查詢參數格式錯誤", func(t *testing.T) {
+		controller := NewChatController(&config.Config{}, nil, new(mocks.ChatService), nil)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.GET("/search/messages", controller.SearchMessages)
+
+		req, _ := http.NewRequest(http.MethodGet, "/search/messages?q=hello&from=yesterday", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
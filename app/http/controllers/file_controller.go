@@ -4,24 +4,39 @@ import (
 	"chat_app_backend/app/models"
 	"chat_app_backend/app/services"
 	"chat_app_backend/config"
+	"chat_app_backend/utils"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 	"log/slog"
 )
 
+// 檔案內容的快取策略：公開資源可由瀏覽器與 CDN 快取，其他檔案每次以 ETag 重新驗證
+const (
+	publicFileCacheControl  = "public, max-age=86400"
+	privateFileCacheControl = "private, no-cache"
+)
+
 type FileController struct {
 	config            *config.Config
 	mongoConnect      *mongo.Database
 	fileUploadService services.FileUploadService
+	chatService       services.ChatService
 }
 
-func NewFileController(cfg *config.Config, mongodb *mongo.Database, fileUploadService services.FileUploadService) *FileController {
+func NewFileController(cfg *config.Config, mongodb *mongo.Database, fileUploadService services.FileUploadService, chatService services.ChatService) *FileController {
 	return &FileController{
 		config:            cfg,
 		mongoConnect:      mongodb,
 		fileUploadService: fileUploadService,
+		chatService:       chatService,
 	}
 }
 
@@ -52,8 +67,14 @@ func (fc *FileController) UploadFile(c *gin.Context) {
 		}
 	}()
 
-	// 上傳檔案
-	result, msgOpt := fc.fileUploadService.UploadFile(file, header, userID.(string))
+	// 上傳檔案，指定 server_id 時同時計入伺服器的儲存配額
+	var result *models.FileResult
+	var msgOpt *models.MessageOptions
+	if serverID := c.PostForm("server_id"); serverID != "" {
+		result, msgOpt = fc.fileUploadService.UploadServerFile(file, header, userID.(string), serverID, models.GetGeneralUploadConfig())
+	} else {
+		result, msgOpt = fc.fileUploadService.UploadFile(file, header, userID.(string))
+	}
 	if msgOpt != nil {
 		ErrorResponse(c, http.StatusBadRequest, *msgOpt)
 		return
@@ -128,8 +149,14 @@ func (fc *FileController) UploadDocument(c *gin.Context) {
 		}
 	}()
 
-	// 上傳文件
-	result, msgOpt := fc.fileUploadService.UploadDocument(file, header, userID.(string))
+	// 上傳文件，指定 server_id 時同時計入伺服器的儲存配額
+	var result *models.FileResult
+	var msgOpt *models.MessageOptions
+	if serverID := c.PostForm("server_id"); serverID != "" {
+		result, msgOpt = fc.fileUploadService.UploadServerFile(file, header, userID.(string), serverID, models.GetDocumentUploadConfig())
+	} else {
+		result, msgOpt = fc.fileUploadService.UploadDocument(file, header, userID.(string))
+	}
 	if msgOpt != nil {
 		ErrorResponse(c, http.StatusBadRequest, *msgOpt)
 		return
@@ -160,6 +187,26 @@ func (fc *FileController) GetUserFiles(c *gin.Context) {
 	SuccessResponse(c, files, "獲取檔案列表成功")
 }
 
+// GetStorageUsage 獲取儲存用量與配額，帶 server_id 時一併返回伺服器用量
+func (fc *FileController) GetStorageUsage(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "未找到用戶ID",
+		})
+		return
+	}
+
+	usage, msgOpt := fc.fileUploadService.GetStorageUsage(userID.(string), c.Query("server_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, usage, "獲取儲存用量成功")
+}
+
 // DeleteFile 刪除檔案
 func (fc *FileController) DeleteFile(c *gin.Context) {
 	// 從 JWT 中獲取用戶ID
@@ -191,3 +238,312 @@ func (fc *FileController) DeleteFile(c *gin.Context) {
 
 	SuccessResponse(c, nil, "檔案刪除成功")
 }
+
+// GetFileVariant 獲取檔案指定尺寸的連結（small、medium、large 或 original）
+// 非公開檔案需為上傳者或能存取引用該附件的房間
+func (fc *FileController) GetFileVariant(c *gin.Context) {
+	variant, msgOpt := fc.fileUploadService.GetFileVariant(c.Param("file_id"), c.Param("size"))
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	if !variant.Public && !fc.authorizeFileAccess(c, []string{variant.OwnerID}, []string{variant.FileID}) {
+		return
+	}
+
+	SuccessResponse(c, variant, "獲取檔案連結成功")
+}
+
+// InitiateChunkedUpload 開始分段上傳（可續傳），需提供完整檔案的 SHA256
+func (fc *FileController) InitiateChunkedUpload(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "未找到用戶ID",
+		})
+		return
+	}
+
+	var request models.InitiateChunkedUploadRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的請求參數",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	upload, msgOpt := fc.fileUploadService.InitiateChunkedUpload(userID.(string), request)
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, upload, "分段上傳已建立")
+}
+
+// UploadChunk 上傳單一分段，請求內容即為分段資料，需提供 Content-Length
+func (fc *FileController) UploadChunk(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "未找到用戶ID",
+		})
+		return
+	}
+
+	chunkNumber, err := strconv.Atoi(c.Param("chunk_number"))
+	if err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的分段編號",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	size := c.Request.ContentLength
+	if size <= 0 || size > services.MaxChunkSize {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "分段內容長度無效",
+			Details: fmt.Sprintf("Content-Length: %d, 上限: %d bytes", size, services.MaxChunkSize),
+		})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, size)
+	upload, msgOpt := fc.fileUploadService.UploadChunk(userID.(string), c.Param("upload_id"), chunkNumber, body, size)
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, upload, "分段上傳成功")
+}
+
+// GetChunkedUpload 查詢分段上傳已接收的分段與範圍
+func (fc *FileController) GetChunkedUpload(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "未找到用戶ID",
+		})
+		return
+	}
+
+	upload, msgOpt := fc.fileUploadService.GetChunkedUpload(userID.(string), c.Param("upload_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, upload, "獲取分段上傳狀態成功")
+}
+
+// CompleteChunkedUpload 完成分段上傳，合併分段並驗證 SHA256 後建立檔案
+func (fc *FileController) CompleteChunkedUpload(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "未找到用戶ID",
+		})
+		return
+	}
+
+	result, msgOpt := fc.fileUploadService.CompleteChunkedUpload(userID.(string), c.Param("upload_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, result, "檔案上傳成功")
+}
+
+// AbortChunkedUpload 放棄分段上傳
+func (fc *FileController) AbortChunkedUpload(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "未找到用戶ID",
+		})
+		return
+	}
+
+	if msgOpt := fc.fileUploadService.AbortChunkedUpload(userID.(string), c.Param("upload_id")); msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "分段上傳已取消")
+}
+
+// InitiatePresignedUpload 取得預簽名上傳網址，客戶端直接上傳到儲存服務，需提供完整檔案的 SHA256
+func (fc *FileController) InitiatePresignedUpload(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "未找到用戶ID",
+		})
+		return
+	}
+
+	var request models.PresignedUploadRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的請求參數",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	upload, msgOpt := fc.fileUploadService.InitiatePresignedUpload(userID.(string), request)
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, upload, "上傳網址已建立")
+}
+
+// ConfirmPresignedUpload 確認預簽名上傳完成，驗證檔案後建立檔案記錄
+func (fc *FileController) ConfirmPresignedUpload(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "未找到用戶ID",
+		})
+		return
+	}
+
+	result, msgOpt := fc.fileUploadService.ConfirmPresignedUpload(userID.(string), c.Param("upload_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, result, "檔案上傳成功")
+}
+
+// ServeFile 傳送上傳的檔案內容，支援 Range、ETag/If-None-Match 與 HEAD
+// 頭像、橫幅與伺服器圖示不需登入且可快取，其他檔案需為上傳者或能存取引用該附件的房間
+func (fc *FileController) ServeFile(c *gin.Context) {
+	served, msgOpt := fc.fileUploadService.ResolveServedFile(c.Param("filepath"))
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	if served.Public {
+		c.Header("Cache-Control", publicFileCacheControl)
+	} else {
+		if !fc.authorizeFileAccess(c, served.OwnerIDs, served.FileIDs) {
+			return
+		}
+		c.Header("Cache-Control", privateFileCacheControl)
+	}
+
+	content, msgOpt := fc.fileUploadService.OpenFileContent(served.FilePath)
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+	defer func() {
+		if err := content.Close(); err != nil {
+			slog.Warn("無法關閉檔案內容", "path", served.FilePath, "error", err)
+		}
+	}()
+
+	c.Header("Content-Type", served.MimeType)
+	c.Header("Content-Disposition", contentDisposition(served))
+	c.Header("X-Content-Type-Options", "nosniff")
+	if served.ETag != "" {
+		c.Header("ETag", served.ETag)
+	}
+
+	// 本地檔案與 MinIO 物件皆可隨機存取，由 http.ServeContent 處理 Range 與條件請求
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, served.FileName, served.ModTime, seeker)
+		return
+	}
+	c.DataFromReader(http.StatusOK, served.FileSize, served.MimeType, content, nil)
+}
+
+// authorizeFileAccess 檢查非公開檔案的存取權限，需為上傳者或能存取引用該附件的房間，未通過時寫入錯誤回應並返回 false
+func (fc *FileController) authorizeFileAccess(c *gin.Context, ownerIDs []string, fileIDs []string) bool {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return false
+	}
+
+	if slices.Contains(ownerIDs, userID) {
+		return true
+	}
+
+	allowed, msgOpt := fc.chatService.CanAccessAttachment(c.Request.Context(), userID, fileIDs)
+	if msgOpt != nil {
+		ErrorResponse(c, fileErrorStatus(msgOpt.Code), *msgOpt)
+		return false
+	}
+	if !allowed {
+		ErrorResponse(c, http.StatusForbidden, models.MessageOptions{
+			Code:    models.ErrNoPermission,
+			Message: "您沒有權限存取此檔案",
+		})
+		return false
+	}
+	return true
+}
+
+// contentDisposition 圖片、影音以 inline 顯示，其他檔案（包含可執行腳本的 SVG）一律下載
+func contentDisposition(served *models.ServedFile) string {
+	disposition := "attachment"
+	mimeType := strings.ToLower(served.MimeType)
+	if mimeType != "image/svg+xml" && (strings.HasPrefix(mimeType, "image/") ||
+		strings.HasPrefix(mimeType, "video/") ||
+		strings.HasPrefix(mimeType, "audio/")) {
+		disposition = "inline"
+	}
+
+	if served.FileName == "" {
+		return disposition
+	}
+	// 非 ASCII 檔名以 RFC 2231 編碼
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": served.FileName}); value != "" {
+		return value
+	}
+	return disposition
+}
+
+// fileErrorStatus 將檔案相關錯誤碼對應至 HTTP 狀態碼
+func fileErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams:
+		return http.StatusBadRequest
+	case models.ErrNotFound:
+		return http.StatusNotFound
+	case models.ErrFileProcessing, models.ErrUploadIncomplete:
+		return http.StatusConflict
+	case models.ErrHashMismatch:
+		return http.StatusUnprocessableEntity
+	case models.ErrQuotaExceeded:
+		return http.StatusRequestEntityTooLarge
+	case models.ErrNotServerMember:
+		return http.StatusForbidden
+	case models.ErrPresignUnsupported:
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
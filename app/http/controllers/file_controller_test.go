@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -23,7 +24,7 @@ func TestNewFileController(t *testing.T) {
 	cfg := &config.Config{}
 	mockFileService := new(mocks.FileUploadService)
 
-	controller := NewFileController(cfg, nil, mockFileService)
+	controller := NewFileController(cfg, nil, mockFileService, nil)
 
 	assert.NotNil(t, controller)
 	assert.Equal(t, cfg, controller.config)
@@ -46,7 +47,7 @@ func TestFileController_UploadFile(t *testing.T) {
 		mockFileService.On("UploadFile", mock.Anything, mock.Anything, "user123").
 			Return(expectedResult, (*models.MessageOptions)(nil))
 
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -79,7 +80,7 @@ func TestFileController_UploadFile(t *testing.T) {
 
 	t.Run("未授權用戶", func(t *testing.T) {
 		mockFileService := new(mocks.FileUploadService)
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.POST("/files/upload", controller.UploadFile)
@@ -93,7 +94,7 @@ func TestFileController_UploadFile(t *testing.T) {
 
 	t.Run("缺少檔案", func(t *testing.T) {
 		mockFileService := new(mocks.FileUploadService)
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -126,7 +127,7 @@ func TestFileController_UploadAvatar(t *testing.T) {
 		mockFileService.On("UploadAvatar", mock.Anything, mock.Anything, "user123").
 			Return(expectedResult, (*models.MessageOptions)(nil))
 
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -167,7 +168,7 @@ func TestFileController_UploadAvatar(t *testing.T) {
 				Message: "檔案格式不正確",
 			})
 
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -210,7 +211,7 @@ func TestFileController_UploadDocument(t *testing.T) {
 		mockFileService.On("UploadDocument", mock.Anything, mock.Anything, "user123").
 			Return(expectedResult, (*models.MessageOptions)(nil))
 
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -265,7 +266,7 @@ func TestFileController_GetUserFiles(t *testing.T) {
 
 		mockFileService.On("GetUserFiles", "user123").Return(expectedFiles, (*models.MessageOptions)(nil))
 
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -298,7 +299,7 @@ func TestFileController_GetUserFiles(t *testing.T) {
 			},
 		)
 
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -324,7 +325,7 @@ func TestFileController_DeleteFile(t *testing.T) {
 
 		mockFileService.On("DeleteFileByID", "file123", "user123").Return((*models.MessageOptions)(nil))
 
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -349,7 +350,7 @@ func TestFileController_DeleteFile(t *testing.T) {
 
 	t.Run("檔案ID為空", func(t *testing.T) {
 		mockFileService := new(mocks.FileUploadService)
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -375,7 +376,7 @@ func TestFileController_DeleteFile(t *testing.T) {
 			},
 		)
 
-		controller := NewFileController(&config.Config{}, nil, mockFileService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
 
 		router := setupTestRouter()
 		router.Use(func(c *gin.Context) {
@@ -393,3 +394,469 @@ func TestFileController_DeleteFile(t *testing.T) {
 		mockFileService.AssertExpectations(t)
 	})
 }
+
+// TestFileController_GetFileVariant 測試獲取指定尺寸的檔案連結
+func TestFileController_GetFileVariant(t *testing.T) {
+	ownerID := primitive.NewObjectID()
+	newVariant := func(public bool) *models.FileVariantResponse {
+		return &models.FileVariantResponse{
+			FileID:  "file123",
+			Size:    "small",
+			URL:     "/uploads/image/photo_small.jpg",
+			Width:   128,
+			Height:  96,
+			Public:  public,
+			OwnerID: ownerID.Hex(),
+		}
+	}
+	newRouter := func(controller *FileController, userID *primitive.ObjectID) *gin.Engine {
+		router := setupTestRouter()
+		if userID != nil {
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", userID.Hex())
+				c.Set("user_object_id", *userID)
+				c.Next()
+			})
+		}
+		router.GET("/files/:file_id/variants/:size", controller.GetFileVariant)
+		return router
+	}
+
+	t.Run("上傳者獲取縮圖", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("GetFileVariant", "file123", "small").Return(newVariant(false), nil)
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, nil), &ownerID)
+		req, _ := http.NewRequest(http.MethodGet, "/files/file123/variants/small", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "獲取檔案連結成功", response.Message)
+		assert.NotContains(t, w.Body.String(), ownerID.Hex(), "回應不應包含上傳者ID")
+
+		mockFileService.AssertExpectations(t)
+	})
+
+	t.Run("公開資源不檢查房間權限", func(t *testing.T) {
+		otherID := primitive.NewObjectID()
+		mockFileService := new(mocks.FileUploadService)
+		mockChatService := new(mocks.ChatService)
+		mockFileService.On("GetFileVariant", "file123", "small").Return(newVariant(true), nil)
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, mockChatService), &otherID)
+		req, _ := http.NewRequest(http.MethodGet, "/files/file123/variants/small", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockChatService.AssertNotCalled(t, "CanAccessAttachment", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("房間成員可獲取附件縮圖", func(t *testing.T) {
+		memberID := primitive.NewObjectID()
+		mockFileService := new(mocks.FileUploadService)
+		mockChatService := new(mocks.ChatService)
+		mockFileService.On("GetFileVariant", "file123", "original").Return(newVariant(false), nil)
+		mockChatService.On("CanAccessAttachment", mock.Anything, memberID.Hex(), []string{"file123"}).Return(true, nil).Once()
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, mockChatService), &memberID)
+		req, _ := http.NewRequest(http.MethodGet, "/files/file123/variants/original", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("非上傳者且無房間權限", func(t *testing.T) {
+		otherID := primitive.NewObjectID()
+		mockFileService := new(mocks.FileUploadService)
+		mockChatService := new(mocks.ChatService)
+		mockFileService.On("GetFileVariant", "file123", "original").Return(newVariant(false), nil)
+		mockChatService.On("CanAccessAttachment", mock.Anything, otherID.Hex(), []string{"file123"}).Return(false, nil).Once()
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, mockChatService), &otherID)
+		req, _ := http.NewRequest(http.MethodGet, "/files/file123/variants/original", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.NotContains(t, w.Body.String(), "/uploads/image/photo_small.jpg")
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("檔案處理中", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("GetFileVariant", "file123", "small").Return(nil, &models.MessageOptions{
+			Code:    models.ErrFileProcessing,
+			Message: "檔案處理中，請稍後再試",
+		})
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, nil), &ownerID)
+		req, _ := http.NewRequest(http.MethodGet, "/files/file123/variants/small", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockFileService.AssertExpectations(t)
+	})
+}
+
+func TestFileController_GetStorageUsage(t *testing.T) {
+	t.Run("成功獲取儲存用量", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("GetStorageUsage", "user123", "server123").Return(&models.StorageUsageResponse{
+			User:   models.StorageQuotaUsage{OwnerID: "user123", UsedBytes: 100, QuotaBytes: 1000, RemainingBytes: 900},
+			Server: &models.StorageQuotaUsage{OwnerID: "server123", UsedBytes: 50, RemainingBytes: -1},
+		}, nil)
+
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
+
+		router := setupTestRouter()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", "user123")
+			c.Next()
+		})
+		router.GET("/files/usage", controller.GetStorageUsage)
+
+		req, _ := http.NewRequest(http.MethodGet, "/files/usage?server_id=server123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "獲取儲存用量成功", response.Message)
+
+		mockFileService.AssertExpectations(t)
+	})
+
+	t.Run("非伺服器成員", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("GetStorageUsage", "user123", "server123").Return(nil, &models.MessageOptions{
+			Code:    models.ErrNotServerMember,
+			Message: "您不是此伺服器的成員",
+		})
+
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
+
+		router := setupTestRouter()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", "user123")
+			c.Next()
+		})
+		router.GET("/files/usage", controller.GetStorageUsage)
+
+		req, _ := http.NewRequest(http.MethodGet, "/files/usage?server_id=server123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockFileService.AssertExpectations(t)
+	})
+}
+
+func TestFileController_UploadChunk(t *testing.T) {
+	t.Run("成功上傳分段", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("UploadChunk", "user123", "upload123", 2, mock.Anything, int64(5)).Return(&models.ChunkedUploadResponse{
+			UploadID:       "upload123",
+			TotalChunks:    2,
+			ReceivedChunks: []int{2},
+		}, nil)
+
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
+
+		router := setupTestRouter()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", "user123")
+			c.Next()
+		})
+		router.PUT("/upload/sessions/:upload_id/chunks/:chunk_number", controller.UploadChunk)
+
+		req, _ := http.NewRequest(http.MethodPut, "/upload/sessions/upload123/chunks/2", bytes.NewReader([]byte("hello")))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "分段上傳成功", response.Message)
+
+		mockFileService.AssertExpectations(t)
+	})
+
+	t.Run("無效的分段編號", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
+
+		router := setupTestRouter()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", "user123")
+			c.Next()
+		})
+		router.PUT("/upload/sessions/:upload_id/chunks/:chunk_number", controller.UploadChunk)
+
+		req, _ := http.NewRequest(http.MethodPut, "/upload/sessions/upload123/chunks/abc", bytes.NewReader([]byte("hello")))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockFileService.AssertNotCalled(t, "UploadChunk", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("缺少分段內容", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
+
+		router := setupTestRouter()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", "user123")
+			c.Next()
+		})
+		router.PUT("/upload/sessions/:upload_id/chunks/:chunk_number", controller.UploadChunk)
+
+		req, _ := http.NewRequest(http.MethodPut, "/upload/sessions/upload123/chunks/1", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockFileService.AssertNotCalled(t, "UploadChunk", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestFileController_CompleteChunkedUpload(t *testing.T) {
+	t.Run("成功完成上傳", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("CompleteChunkedUpload", "user123", "upload123").Return(&models.FileResult{
+			ID:       primitive.NewObjectID(),
+			FileName: "report.pdf",
+			Status:   "verified",
+		}, nil)
+
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
+
+		router := setupTestRouter()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", "user123")
+			c.Next()
+		})
+		router.POST("/upload/sessions/:upload_id/complete", controller.CompleteChunkedUpload)
+
+		req, _ := http.NewRequest(http.MethodPost, "/upload/sessions/upload123/complete", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockFileService.AssertExpectations(t)
+	})
+
+	t.Run("雜湊不符", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("CompleteChunkedUpload", "user123", "upload123").Return(nil, &models.MessageOptions{
+			Code:    models.ErrHashMismatch,
+			Message: "檔案雜湊與宣告值不符",
+		})
+
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
+
+		router := setupTestRouter()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", "user123")
+			c.Next()
+		})
+		router.POST("/upload/sessions/:upload_id/complete", controller.CompleteChunkedUpload)
+
+		req, _ := http.NewRequest(http.MethodPost, "/upload/sessions/upload123/complete", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("尚有分段未上傳", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("CompleteChunkedUpload", "user123", "upload123").Return(nil, &models.MessageOptions{
+			Code:    models.ErrUploadIncomplete,
+			Message: "尚有分段未上傳",
+		})
+
+		controller := NewFileController(&config.Config{}, nil, mockFileService, nil)
+
+		router := setupTestRouter()
+		router.Use(func(c *gin.Context) {
+			c.Set("userID", "user123")
+			c.Next()
+		})
+		router.POST("/upload/sessions/:upload_id/complete", controller.CompleteChunkedUpload)
+
+		req, _ := http.NewRequest(http.MethodPost, "/upload/sessions/upload123/complete", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+// seekableContent 可隨機存取的檔案內容（對應本地檔案與 MinIO 物件）
+type seekableContent struct {
+	*bytes.Reader
+}
+
+func (seekableContent) Close() error { return nil }
+
+// TestFileController_ServeFile 測試上傳檔案的存取控制與 HTTP 快取、Range 處理
+func TestFileController_ServeFile(t *testing.T) {
+	content := []byte("0123456789abcdef")
+	ownerID := primitive.NewObjectID()
+	fileID := primitive.NewObjectID()
+	newServedFile := func(public bool) *models.ServedFile {
+		return &models.ServedFile{
+			FilePath: "document/123_abc.pdf",
+			FileName: "季報.pdf",
+			MimeType: "application/pdf",
+			FileSize: int64(len(content)),
+			ETag:     `"abc123"`,
+			ModTime:  time.Unix(1700000000, 0),
+			Public:   public,
+			OwnerIDs: []string{ownerID.Hex()},
+			FileIDs:  []string{fileID.Hex()},
+		}
+	}
+	newRouter := func(controller *FileController, userID *primitive.ObjectID) *gin.Engine {
+		router := setupTestRouter()
+		if userID != nil {
+			router.Use(func(c *gin.Context) {
+				c.Set("user_id", userID.Hex())
+				c.Set("user_object_id", *userID)
+				c.Next()
+			})
+		}
+		router.GET("/uploads/*filepath", controller.ServeFile)
+		return router
+	}
+
+	t.Run("公開資源不需登入且可快取", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		served := newServedFile(true)
+		served.FilePath = "avatar/123_abc.png"
+		served.FileName = "me.png"
+		served.MimeType = "image/png"
+		mockFileService.On("ResolveServedFile", "/avatar/123_abc.png").Return(served, nil).Once()
+		mockFileService.On("OpenFileContent", "avatar/123_abc.png").Return(seekableContent{bytes.NewReader(content)}, nil).Once()
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, nil), nil)
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/avatar/123_abc.png", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, publicFileCacheControl, w.Header().Get("Cache-Control"))
+		assert.Equal(t, `inline; filename=me.png`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, content, w.Body.Bytes())
+		mockFileService.AssertExpectations(t)
+	})
+
+	t.Run("私人檔案未登入", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("ResolveServedFile", "/document/123_abc.pdf").Return(newServedFile(false), nil).Once()
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, nil), nil)
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/document/123_abc.pdf", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockFileService.AssertNotCalled(t, "OpenFileContent", mock.Anything)
+	})
+
+	t.Run("上傳者以 Range 讀取部分內容", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("ResolveServedFile", "/document/123_abc.pdf").Return(newServedFile(false), nil).Once()
+		mockFileService.On("OpenFileContent", "document/123_abc.pdf").Return(seekableContent{bytes.NewReader(content)}, nil).Once()
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, nil), &ownerID)
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/document/123_abc.pdf", nil)
+		req.Header.Set("Range", "bytes=4-7")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "bytes 4-7/16", w.Header().Get("Content-Range"))
+		assert.Equal(t, "4567", w.Body.String())
+		assert.Equal(t, privateFileCacheControl, w.Header().Get("Cache-Control"))
+		assert.Equal(t, `attachment; filename*=utf-8''%E5%AD%A3%E5%A0%B1.pdf`, w.Header().Get("Content-Disposition"))
+		assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	})
+
+	t.Run("ETag 相符時返回 304", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("ResolveServedFile", "/document/123_abc.pdf").Return(newServedFile(false), nil).Once()
+		mockFileService.On("OpenFileContent", "document/123_abc.pdf").Return(seekableContent{bytes.NewReader(content)}, nil).Once()
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, nil), &ownerID)
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/document/123_abc.pdf", nil)
+		req.Header.Set("If-None-Match", `"abc123"`)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.Bytes())
+	})
+
+	t.Run("房間成員可讀取附件", func(t *testing.T) {
+		memberID := primitive.NewObjectID()
+		mockFileService := new(mocks.FileUploadService)
+		mockChatService := new(mocks.ChatService)
+		mockFileService.On("ResolveServedFile", "/document/123_abc.pdf").Return(newServedFile(false), nil).Once()
+		mockChatService.On("CanAccessAttachment", mock.Anything, memberID.Hex(), []string{fileID.Hex()}).Return(true, nil).Once()
+		mockFileService.On("OpenFileContent", "document/123_abc.pdf").Return(seekableContent{bytes.NewReader(content)}, nil).Once()
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, mockChatService), &memberID)
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/document/123_abc.pdf", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, content, w.Body.Bytes())
+		mockChatService.AssertExpectations(t)
+	})
+
+	t.Run("非上傳者且無房間權限", func(t *testing.T) {
+		otherID := primitive.NewObjectID()
+		mockFileService := new(mocks.FileUploadService)
+		mockChatService := new(mocks.ChatService)
+		mockFileService.On("ResolveServedFile", "/document/123_abc.pdf").Return(newServedFile(false), nil).Once()
+		mockChatService.On("CanAccessAttachment", mock.Anything, otherID.Hex(), []string{fileID.Hex()}).Return(false, nil).Once()
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, mockChatService), &otherID)
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/document/123_abc.pdf", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockFileService.AssertNotCalled(t, "OpenFileContent", mock.Anything)
+	})
+
+	t.Run("檔案不存在", func(t *testing.T) {
+		mockFileService := new(mocks.FileUploadService)
+		mockFileService.On("ResolveServedFile", "/document/missing.pdf").Return(nil, &models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "檔案不存在",
+		}).Once()
+
+		router := newRouter(NewFileController(&config.Config{}, nil, mockFileService, nil), &ownerID)
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/document/missing.pdf", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
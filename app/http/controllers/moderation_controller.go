@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/services"
+	"chat_app_backend/config"
+	"chat_app_backend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ModerationController 處理伺服器成員管理（踢出、封鎖、禁言）相關請求
+type ModerationController struct {
+	config            *config.Config
+	mongoConnect      *mongo.Database
+	moderationService services.ModerationService
+}
+
+func NewModerationController(cfg *config.Config, mongodb *mongo.Database, moderationService services.ModerationService) *ModerationController {
+	return &ModerationController{
+		config:            cfg,
+		mongoConnect:      mongodb,
+		moderationService: moderationService,
+	}
+}
+
+// KickMember 將成員踢出伺服器
+func (mc *ModerationController) KickMember(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	// 請求內容為選填（可不附原因）
+	var request models.KickMemberRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "請求格式錯誤",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
+	msgOpt := mc.moderationService.KickMember(c.Request.Context(), userID, c.Param("server_id"), c.Param("user_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, moderationErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "已將成員踢出伺服器")
+}
+
+// GetServerBans 獲取伺服器封鎖列表
+func (mc *ModerationController) GetServerBans(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	bans, msgOpt := mc.moderationService.GetServerBans(c.Request.Context(), userID, c.Param("server_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, moderationErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, bans, "獲取封鎖列表成功")
+}
+
+// BanMember 封鎖用戶
+func (mc *ModerationController) BanMember(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	// 請求內容為選填（未指定期限即為永久封鎖）
+	var request models.BanMemberRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "請求格式錯誤",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
+	msgOpt := mc.moderationService.BanMember(c.Request.Context(), userID, c.Param("server_id"), c.Param("user_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, moderationErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "封鎖用戶成功")
+}
+
+// UnbanMember 解除用戶封鎖
+func (mc *ModerationController) UnbanMember(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	msgOpt := mc.moderationService.UnbanMember(c.Request.Context(), userID, c.Param("server_id"), c.Param("user_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, moderationErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "解除封鎖成功")
+}
+
+// TimeoutMember 禁言成員
+func (mc *ModerationController) TimeoutMember(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var request models.TimeoutMemberRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "請求格式錯誤",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	msgOpt := mc.moderationService.TimeoutMember(c.Request.Context(), userID, c.Param("server_id"), c.Param("user_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, moderationErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "禁言成員成功")
+}
+
+// RemoveTimeout 解除成員禁言
+func (mc *ModerationController) RemoveTimeout(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	msgOpt := mc.moderationService.RemoveTimeout(c.Request.Context(), userID, c.Param("server_id"), c.Param("user_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, moderationErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "解除禁言成功")
+}
+
+// moderationErrorStatus 將成員管理錯誤碼對應到 HTTP 狀態碼
+func moderationErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams:
+		return http.StatusBadRequest
+	case models.ErrNoServerPermission, models.ErrNotServerMember:
+		return http.StatusForbidden
+	case models.ErrServerNotFound, models.ErrBanNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
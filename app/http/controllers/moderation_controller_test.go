@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"bytes"
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestModerationController_KickMember 測試踢出成員
+func TestModerationController_KickMember(t *testing.T) {
+	t.Run("不附原因成功踢出", func(t *testing.T) {
+		mockModerationService := new(mocks.ModerationService)
+		mockModerationService.On("KickMember", mock.Anything, "user123", "server123", "member123", models.KickMemberRequest{}).Return(nil)
+
+		controller := NewModerationController(&config.Config{}, nil, mockModerationService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/members/:user_id/kick", controller.KickMember)
+
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server123/members/member123/kick", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockModerationService.AssertExpectations(t)
+	})
+
+	t.Run("權限不足", func(t *testing.T) {
+		mockModerationService := new(mocks.ModerationService)
+		request := models.KickMemberRequest{Reason: "spam"}
+		mockModerationService.On("KickMember", mock.Anything, "user123", "server123", "member123", request).
+			Return(&models.MessageOptions{Code: models.ErrNoServerPermission, Message: "沒有踢出成員的權限"})
+
+		controller := NewModerationController(&config.Config{}, nil, mockModerationService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/members/:user_id/kick", controller.KickMember)
+
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server123/members/member123/kick", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockModerationService.AssertExpectations(t)
+	})
+}
+
+// TestModerationController_Bans 測試封鎖與解除封鎖
+func TestModerationController_Bans(t *testing.T) {
+	t.Run("成功封鎖", func(t *testing.T) {
+		mockModerationService := new(mocks.ModerationService)
+		request := models.BanMemberRequest{Reason: "spam", Duration: 3600}
+		mockModerationService.On("BanMember", mock.Anything, "user123", "server123", "member123", request).Return(nil)
+
+		controller := NewModerationController(&config.Config{}, nil, mockModerationService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/servers/:server_id/bans/:user_id", controller.BanMember)
+
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPut, "/servers/server123/bans/member123", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "封鎖用戶成功", response.Message)
+
+		mockModerationService.AssertExpectations(t)
+	})
+
+	t.Run("解除不存在的封鎖", func(t *testing.T) {
+		mockModerationService := new(mocks.ModerationService)
+		mockModerationService.On("UnbanMember", mock.Anything, "user123", "server123", "member123").
+			Return(&models.MessageOptions{Code: models.ErrBanNotFound, Message: "該用戶未被封鎖"})
+
+		controller := NewModerationController(&config.Config{}, nil, mockModerationService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.DELETE("/servers/:server_id/bans/:user_id", controller.UnbanMember)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/servers/server123/bans/member123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockModerationService.AssertExpectations(t)
+	})
+}
+
+// TestModerationController_TimeoutMember 測試禁言成員
+func TestModerationController_TimeoutMember(t *testing.T) {
+	t.Run("缺少禁言時長", func(t *testing.T) {
+		mockModerationService := new(mocks.ModerationService)
+		controller := NewModerationController(&config.Config{}, nil, mockModerationService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/servers/:server_id/members/:user_id/timeout", controller.TimeoutMember)
+
+		req, _ := http.NewRequest(http.MethodPut, "/servers/server123/members/member123/timeout", bytes.NewBufferString(`{"reason":"spam"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockModerationService.AssertNotCalled(t, "TimeoutMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("成功禁言", func(t *testing.T) {
+		mockModerationService := new(mocks.ModerationService)
+		request := models.TimeoutMemberRequest{Duration: 600}
+		mockModerationService.On("TimeoutMember", mock.Anything, "user123", "server123", "member123", request).Return(nil)
+
+		controller := NewModerationController(&config.Config{}, nil, mockModerationService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/servers/:server_id/members/:user_id/timeout", controller.TimeoutMember)
+
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPut, "/servers/server123/members/member123/timeout", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockModerationService.AssertExpectations(t)
+	})
+}
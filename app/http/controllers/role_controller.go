@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/services"
+	"chat_app_backend/config"
+	"chat_app_backend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// RoleController 處理伺服器角色與權限相關請求
+type RoleController struct {
+	config            *config.Config
+	mongoConnect      *mongo.Database
+	permissionService services.PermissionService
+}
+
+func NewRoleController(cfg *config.Config, mongodb *mongo.Database, permissionService services.PermissionService) *RoleController {
+	return &RoleController{
+		config:            cfg,
+		mongoConnect:      mongodb,
+		permissionService: permissionService,
+	}
+}
+
+// GetServerRoles 獲取伺服器角色列表
+func (rc *RoleController) GetServerRoles(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	roles, msgOpt := rc.permissionService.GetServerRoles(c.Request.Context(), userID, c.Param("server_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, roleErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, roles, "獲取角色列表成功")
+}
+
+// GetMyPermissions 獲取目前用戶在伺服器中的有效權限
+func (rc *RoleController) GetMyPermissions(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	permissions, msgOpt := rc.permissionService.GetMyPermissions(c.Request.Context(), userID, c.Param("server_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, roleErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, permissions, "獲取權限成功")
+}
+
+// CreateRole 創建伺服器角色
+func (rc *RoleController) CreateRole(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var request models.CreateServerRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "請求格式錯誤",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	role, msgOpt := rc.permissionService.CreateRole(c.Request.Context(), userID, c.Param("server_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, roleErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, role, "創建角色成功")
+}
+
+// UpdateRole 更新伺服器角色
+func (rc *RoleController) UpdateRole(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var request models.UpdateServerRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "請求格式錯誤",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	role, msgOpt := rc.permissionService.UpdateRole(c.Request.Context(), userID, c.Param("server_id"), c.Param("role_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, roleErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, role, "更新角色成功")
+}
+
+// DeleteRole 刪除伺服器角色
+func (rc *RoleController) DeleteRole(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	msgOpt := rc.permissionService.DeleteRole(c.Request.Context(), userID, c.Param("server_id"), c.Param("role_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, roleErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "刪除角色成功")
+}
+
+// AssignRole 將角色指派給成員
+func (rc *RoleController) AssignRole(c *gin.Context) {
+	rc.updateMemberRole(c, true)
+}
+
+// UnassignRole 移除成員的角色
+func (rc *RoleController) UnassignRole(c *gin.Context) {
+	rc.updateMemberRole(c, false)
+}
+
+// updateMemberRole 指派或移除成員角色的共用處理
+func (rc *RoleController) updateMemberRole(c *gin.Context, assign bool) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	serverID := c.Param("server_id")
+	roleID := c.Param("role_id")
+	targetUserID := c.Param("user_id")
+
+	var msgOpt *models.MessageOptions
+	if assign {
+		msgOpt = rc.permissionService.AssignRole(c.Request.Context(), userID, serverID, roleID, targetUserID)
+	} else {
+		msgOpt = rc.permissionService.UnassignRole(c.Request.Context(), userID, serverID, roleID, targetUserID)
+	}
+	if msgOpt != nil {
+		ErrorResponse(c, roleErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "成員角色更新成功")
+}
+
+// GetChannelOverwrites 獲取頻道權限覆寫列表
+func (rc *RoleController) GetChannelOverwrites(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	overwrites, msgOpt := rc.permissionService.GetChannelOverwrites(c.Request.Context(), userID, c.Param("channel_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, roleErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, overwrites, "獲取頻道權限覆寫成功")
+}
+
+// SetChannelOverwrite 新增或取代頻道權限覆寫
+func (rc *RoleController) SetChannelOverwrite(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var request models.ChannelOverwriteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "請求格式錯誤",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	overwrites, msgOpt := rc.permissionService.SetChannelOverwrite(c.Request.Context(), userID, c.Param("channel_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, roleErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, overwrites, "更新頻道權限覆寫成功")
+}
+
+// DeleteChannelOverwrite 刪除頻道權限覆寫，對象以 target_type 與 target_id 查詢參數指定
+func (rc *RoleController) DeleteChannelOverwrite(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	msgOpt := rc.permissionService.DeleteChannelOverwrite(c.Request.Context(), userID, c.Param("channel_id"), c.Query("target_type"), c.Query("target_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, roleErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "刪除頻道權限覆寫成功")
+}
+
+// roleErrorStatus 將角色與權限相關錯誤碼對應為 HTTP 狀態碼
+func roleErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams:
+		return http.StatusBadRequest
+	case models.ErrNoServerPermission, models.ErrNotServerMember:
+		return http.StatusForbidden
+	case models.ErrServerNotFound, models.ErrRoleNotFound, models.ErrChannelNotFound, models.ErrOverwriteNotFound:
+		return http.StatusNotFound
+	case models.ErrTooManyRoles:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
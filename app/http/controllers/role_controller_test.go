@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"bytes"
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/config"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// TestRoleController_CreateRole 測試創建伺服器角色
+func TestRoleController_CreateRole(t *testing.T) {
+	t.Run("成功創建角色", func(t *testing.T) {
+		mockPermissionService := new(mocks.PermissionService)
+		request := models.CreateServerRoleRequest{Name: "Moderator", Permissions: []string{"kick_members"}}
+
+		mockPermissionService.On("CreateRole", mock.Anything, "user123", "server123", request).
+			Return(&models.ServerRoleResponse{ID: "role123", Name: "Moderator", Permissions: []string{"kick_members"}}, nil)
+
+		controller := NewRoleController(&config.Config{}, nil, mockPermissionService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/roles", controller.CreateRole)
+
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server123/roles", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "success", response.Status)
+		assert.Equal(t, "創建角色成功", response.Message)
+
+		mockPermissionService.AssertExpectations(t)
+	})
+
+	t.Run("缺少角色名稱", func(t *testing.T) {
+		mockPermissionService := new(mocks.PermissionService)
+		controller := NewRoleController(&config.Config{}, nil, mockPermissionService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/roles", controller.CreateRole)
+
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server123/roles", bytes.NewBufferString(`{"permissions":[]}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockPermissionService.AssertNotCalled(t, "CreateRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("沒有管理角色權限", func(t *testing.T) {
+		mockPermissionService := new(mocks.PermissionService)
+		request := models.CreateServerRoleRequest{Name: "Moderator"}
+
+		mockPermissionService.On("CreateRole", mock.Anything, "user123", "server123", request).
+			Return(nil, &models.MessageOptions{Code: models.ErrNoServerPermission, Message: "沒有管理角色的權限"})
+
+		controller := NewRoleController(&config.Config{}, nil, mockPermissionService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/roles", controller.CreateRole)
+
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server123/roles", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockPermissionService.AssertExpectations(t)
+	})
+}
+
+// TestRoleController_AssignRole 測試指派與移除成員角色
+func TestRoleController_AssignRole(t *testing.T) {
+	t.Run("成功指派角色", func(t *testing.T) {
+		mockPermissionService := new(mocks.PermissionService)
+		mockPermissionService.On("AssignRole", mock.Anything, "user123", "server123", "role123", "member123").Return(nil)
+
+		controller := NewRoleController(&config.Config{}, nil, mockPermissionService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/servers/:server_id/members/:user_id/roles/:role_id", controller.AssignRole)
+
+		req, _ := http.NewRequest(http.MethodPut, "/servers/server123/members/member123/roles/role123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockPermissionService.AssertExpectations(t)
+	})
+
+	t.Run("角色不存在", func(t *testing.T) {
+		mockPermissionService := new(mocks.PermissionService)
+		mockPermissionService.On("UnassignRole", mock.Anything, "user123", "server123", "role123", "member123").
+			Return(&models.MessageOptions{Code: models.ErrRoleNotFound, Message: "角色不存在"})
+
+		controller := NewRoleController(&config.Config{}, nil, mockPermissionService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.DELETE("/servers/:server_id/members/:user_id/roles/:role_id", controller.UnassignRole)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/servers/server123/members/member123/roles/role123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockPermissionService.AssertExpectations(t)
+	})
+}
+
+// TestRoleController_ChannelOverwrites 測試頻道權限覆寫的設定與刪除
+func TestRoleController_ChannelOverwrites(t *testing.T) {
+	t.Run("成功設定覆寫", func(t *testing.T) {
+		mockPermissionService := new(mocks.PermissionService)
+		request := models.ChannelOverwriteRequest{TargetType: models.OverwriteTargetEveryone, Deny: []string{"send_messages"}}
+
+		mockPermissionService.On("SetChannelOverwrite", mock.Anything, "user123", "channel123", request).
+			Return([]models.ChannelOverwriteResponse{{TargetType: models.OverwriteTargetEveryone, Allow: []string{}, Deny: []string{"send_messages"}}}, nil)
+
+		controller := NewRoleController(&config.Config{}, nil, mockPermissionService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.PUT("/channels/:channel_id/overwrites", controller.SetChannelOverwrite)
+
+		body, _ := json.Marshal(request)
+		req, _ := http.NewRequest(http.MethodPut, "/channels/channel123/overwrites", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "更新頻道權限覆寫成功", response.Message)
+
+		mockPermissionService.AssertExpectations(t)
+	})
+
+	t.Run("刪除不存在的覆寫", func(t *testing.T) {
+		mockPermissionService := new(mocks.PermissionService)
+		mockPermissionService.On("DeleteChannelOverwrite", mock.Anything, "user123", "channel123", "role", "role123").
+			Return(&models.MessageOptions{Code: models.ErrOverwriteNotFound, Message: "頻道權限覆寫不存在"})
+
+		controller := NewRoleController(&config.Config{}, nil, mockPermissionService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.DELETE("/channels/:channel_id/overwrites", controller.DeleteChannelOverwrite)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/channels/channel123/overwrites?target_type=role&target_id=role123", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockPermissionService.AssertExpectations(t)
+	})
+}
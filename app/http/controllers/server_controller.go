@@ -369,3 +369,100 @@ func (sc *ServerController) LeaveServer(c *gin.Context) {
 	// 返回響應
 	SuccessResponse(c, nil, "成功離開伺服器")
 }
+
+// CreateInvite 創建伺服器邀請碼
+func (sc *ServerController) CreateInvite(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	// 未帶請求內容時使用預設值（不限次數、永不過期）
+	var request models.CreateServerInviteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "請求格式錯誤",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
+
+	invite, msgOpt := sc.serverService.CreateInvite(userID, c.Param("server_id"), request)
+	if msgOpt != nil {
+		ErrorResponse(c, inviteErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, invite, "創建邀請成功")
+}
+
+// GetServerInvites 獲取伺服器邀請列表
+func (sc *ServerController) GetServerInvites(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	invites, msgOpt := sc.serverService.GetServerInvites(userID, c.Param("server_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, inviteErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, invites, "獲取邀請列表成功")
+}
+
+// RevokeInvite 撤銷伺服器邀請碼
+func (sc *ServerController) RevokeInvite(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	msgOpt := sc.serverService.RevokeInvite(userID, c.Param("server_id"), c.Param("invite_id"))
+	if msgOpt != nil {
+		ErrorResponse(c, inviteErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "撤銷邀請成功")
+}
+
+// AcceptInvite 透過邀請碼加入伺服器
+func (sc *ServerController) AcceptInvite(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	server, msgOpt := sc.serverService.AcceptInvite(userID, c.Param("code"))
+	if msgOpt != nil {
+		ErrorResponse(c, inviteErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, server, "加入伺服器成功")
+}
+
+// inviteErrorStatus 將邀請相關錯誤碼對應為 HTTP 狀態碼
+func inviteErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams, models.ErrOperationFailed:
+		return http.StatusBadRequest
+	case models.ErrForbidden, models.ErrNoServerPermission, models.ErrNotServerMember, models.ErrUserBanned:
+		return http.StatusForbidden
+	case models.ErrNotFound, models.ErrServerNotFound, models.ErrInviteNotFound:
+		return http.StatusNotFound
+	case models.ErrInviteInvalid:
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
+	}
+}
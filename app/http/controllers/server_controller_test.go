@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -136,6 +137,50 @@ func TestServerController_CreateServer(t *testing.T) {
 		mockServerService.AssertExpectations(t)
 	})
 
+	t.Run("成功創建伺服器 - 圖片處理中", func(t *testing.T) {
+		mockServerService := new(mocks.ServerService)
+
+		serverID := primitive.NewObjectID()
+		pictureID := primitive.NewObjectID()
+		mockServerService.On("CreateServer", "user123", "New Server", mock.Anything, mock.AnythingOfType("*multipart.FileHeader")).Return(&models.ServerResponse{
+			ID:            serverID,
+			Name:          "New Server",
+			PictureID:     pictureID.Hex(),
+			PictureStatus: "processing",
+		}, nil)
+
+		controller := NewServerController(&config.Config{}, nil, mockServerService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers", controller.CreateServer)
+
+		body := &strings.Builder{}
+		writer := multipart.NewWriter(body)
+		writer.WriteField("name", "New Server")
+		part, _ := writer.CreateFormFile("picture", "icon.png")
+		part.Write([]byte("png"))
+		writer.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, "/servers", strings.NewReader(body.String()))
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data map[string]any `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "", response.Data["picture_url"])
+		assert.Equal(t, pictureID.Hex(), response.Data["picture_id"])
+		assert.Equal(t, "processing", response.Data["picture_status"])
+
+		mockServerService.AssertExpectations(t)
+	})
+
 	t.Run("創建伺服器失敗 - 缺少名稱", func(t *testing.T) {
 		mockServerService := new(mocks.ServerService)
 		controller := NewServerController(&config.Config{}, nil, mockServerService)
@@ -333,3 +378,85 @@ func TestServerController_LeaveServer(t *testing.T) {
 		mockServerService.AssertExpectations(t)
 	})
 }
+
+func TestServerController_AcceptInvite(t *testing.T) {
+	t.Run("成功透過邀請加入", func(t *testing.T) {
+		mockServerService := new(mocks.ServerService)
+		mockServerService.On("AcceptInvite", "user123", "abc123").Return(&models.ServerResponse{Name: "Private"}, nil)
+
+		controller := NewServerController(&config.Config{}, nil, mockServerService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/invites/:code/accept", controller.AcceptInvite)
+
+		req, _ := http.NewRequest(http.MethodPost, "/invites/abc123/accept", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "success", response.Status)
+
+		mockServerService.AssertExpectations(t)
+	})
+
+	t.Run("邀請已失效", func(t *testing.T) {
+		mockServerService := new(mocks.ServerService)
+		mockServerService.On("AcceptInvite", "user123", "abc123").Return(nil,
+			&models.MessageOptions{Code: models.ErrInviteInvalid, Message: "邀請已過期"})
+
+		controller := NewServerController(&config.Config{}, nil, mockServerService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/invites/:code/accept", controller.AcceptInvite)
+
+		req, _ := http.NewRequest(http.MethodPost, "/invites/abc123/accept", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusGone, w.Code)
+		mockServerService.AssertExpectations(t)
+	})
+}
+
+func TestServerController_CreateInvite(t *testing.T) {
+	t.Run("未帶請求內容使用預設值", func(t *testing.T) {
+		mockServerService := new(mocks.ServerService)
+		mockServerService.On("CreateInvite", "user123", "server1", models.CreateServerInviteRequest{}).
+			Return(&models.ServerInviteResponse{Code: "abc123"}, nil)
+
+		controller := NewServerController(&config.Config{}, nil, mockServerService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/invites", controller.CreateInvite)
+
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server1/invites", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockServerService.AssertExpectations(t)
+	})
+
+	t.Run("最大使用次數為負數", func(t *testing.T) {
+		mockServerService := new(mocks.ServerService)
+		controller := NewServerController(&config.Config{}, nil, mockServerService)
+
+		router := setupTestRouter()
+		router.Use(mocks.MockAuthMiddleware("user123"))
+		router.POST("/servers/:server_id/invites", controller.CreateInvite)
+
+		req, _ := http.NewRequest(http.MethodPost, "/servers/server1/invites", strings.NewReader(`{"max_uses":-1}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockServerService.AssertNotCalled(t, "CreateInvite", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
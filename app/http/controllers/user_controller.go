@@ -71,7 +71,7 @@ func (uc *UserController) Login(c *gin.Context) {
 	}
 
 	// 調用服務層處理登入邏輯
-	response, appErr := uc.userService.Login(loginUser)
+	response, appErr := uc.userService.Login(loginUser, requestDeviceInfo(c))
 	if appErr != nil {
 		statusCode := http.StatusInternalServerError
 		if appErr.Code == models.ErrLoginFailed {
@@ -85,6 +85,60 @@ func (uc *UserController) Login(c *gin.Context) {
 		return
 	}
 
+	// 已啟用兩步驟驗證，返回挑戰 token，待 /login/2fa 驗證後才發放登入 token
+	if response.TwoFactorRequired {
+		SuccessResponse(c, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     response.ChallengeToken,
+		}, "請輸入兩步驟驗證碼")
+		return
+	}
+
+	uc.completeLogin(c, response)
+}
+
+// 兩步驟登入：以挑戰 token 與驗證碼（或復原碼）完成登入
+func (uc *UserController) VerifyTwoFactorLogin(c *gin.Context) {
+	var req models.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "登入失敗",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	response, appErr := uc.userService.VerifyTwoFactorLogin(req.ChallengeToken, req.Code, requestDeviceInfo(c))
+	if appErr != nil {
+		statusCode := http.StatusInternalServerError
+		if appErr.Code == models.ErrInvalidToken || appErr.Code == models.ErrInvalidTwoFactorCode {
+			statusCode = http.StatusUnauthorized
+		}
+		ErrorResponse(c, statusCode, models.MessageOptions{
+			Code:    appErr.Code,
+			Message: "登入失敗",
+			Details: appErr.Message,
+		})
+		return
+	}
+
+	uc.completeLogin(c, response)
+}
+
+// maxUserAgentLength 記錄的 User-Agent 長度上限
+const maxUserAgentLength = 512
+
+// requestDeviceInfo 取得請求的裝置資訊，記錄於登入工作階段
+func requestDeviceInfo(c *gin.Context) models.DeviceInfo {
+	return models.DeviceInfo{
+		UserAgent: utils.SafeSubstring(c.Request.UserAgent(), 0, maxUserAgentLength),
+		IP:        c.ClientIP(),
+	}
+}
+
+// completeLogin 將登入 token 寫入 cookie 並返回 access token
+func (uc *UserController) completeLogin(c *gin.Context, response *models.LoginResponse) {
 	// 將 refresh token 寫入 cookie
 	utils.SetCookie(c, uc.config, "refresh_token", response.RefreshToken, uc.config.JWT.RefreshExpireHours*3600, true)
 
@@ -159,7 +213,7 @@ func (uc *UserController) RefreshToken(c *gin.Context) {
 	}
 
 	// 調用服務層的 RefreshToken 方法
-	response, appErr := uc.userService.RefreshToken(token)
+	response, appErr := uc.userService.RefreshToken(token, requestDeviceInfo(c))
 	if appErr != nil {
 		statusCode := http.StatusInternalServerError
 		if appErr.Code == models.ErrInvalidToken {
@@ -173,7 +227,8 @@ func (uc *UserController) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// 將新的 csrf token 寫入 cookie
+	// 將輪替後的 refresh token 與新的 csrf token 寫入 cookie
+	utils.SetCookie(c, uc.config, "refresh_token", response.RefreshToken, uc.config.JWT.RefreshExpireHours*3600, true)
 	utils.SetCookie(c, uc.config, "csrf_token", response.CSRFToken, uc.config.JWT.RefreshExpireHours*3600, false)
 
 	SuccessResponse(c, gin.H{"access_token": response.AccessToken}, "令牌刷新成功")
@@ -389,6 +444,9 @@ func (uc *UserController) UpdateUserPassword(c *gin.Context) {
 		return
 	}
 
+	// 所有 token 已被撤銷，清除目前裝置的 refresh token
+	utils.ClearCookie(c, uc.config, "refresh_token")
+
 	SuccessResponse(c, nil, "密碼更新成功")
 }
 
@@ -413,37 +471,262 @@ func (uc *UserController) GetTwoFactorStatus(c *gin.Context) {
 	SuccessResponse(c, status, "獲取兩步驟驗證狀態成功")
 }
 
-// UpdateTwoFactorStatus 啟用/停用兩步驟驗證
-func (uc *UserController) UpdateTwoFactorStatus(c *gin.Context) {
+// SetupTwoFactor 產生兩步驟驗證密鑰，需再以驗證碼呼叫 EnableTwoFactor 確認
+func (uc *UserController) SetupTwoFactor(c *gin.Context) {
 	userID, _, err := utils.GetUserIDFromHeader(c)
 	if err != nil {
 		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
 		return
 	}
 
-	var req struct {
-		Enabled bool `json:"enabled"`
+	setup, msgOpt := uc.userService.SetupTwoFactor(userID)
+	if msgOpt != nil {
+		ErrorResponse(c, twoFactorErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, setup, "兩步驟驗證密鑰已產生，請以驗證碼確認")
+}
+
+// EnableTwoFactor 以驗證碼確認並啟用兩步驟驗證
+func (uc *UserController) EnableTwoFactor(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
 	}
+
+	var req models.TwoFactorCodeRequest
 	if err = c.ShouldBindJSON(&req); err != nil {
 		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
 			Code:    models.ErrInvalidParams,
-			Message: "兩步驟驗證狀態更新失敗",
+			Message: "兩步驟驗證啟用失敗",
 			Details: err.Error(),
 		})
 		return
 	}
 
-	err = uc.userService.UpdateTwoFactorStatus(userID, req.Enabled)
+	recoveryCodes, msgOpt := uc.userService.EnableTwoFactor(userID, req.Code)
+	if msgOpt != nil {
+		ErrorResponse(c, twoFactorErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, recoveryCodes, "兩步驟驗證已啟用，請妥善保存復原碼")
+}
+
+// DisableTwoFactor 以驗證碼或復原碼停用兩步驟驗證
+func (uc *UserController) DisableTwoFactor(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
 	if err != nil {
-		ErrorResponse(c, http.StatusInternalServerError, models.MessageOptions{
-			Code:    models.ErrInternalServer,
-			Message: "兩步驟驗證狀態更新失敗",
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "兩步驟驗證停用失敗",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if msgOpt := uc.userService.DisableTwoFactor(userID, req.Code); msgOpt != nil {
+		ErrorResponse(c, twoFactorErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "兩步驟驗證已停用")
+}
+
+// twoFactorErrorStatus 將兩步驟驗證相關錯誤碼對應為 HTTP 狀態碼
+func twoFactorErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams, models.ErrInvalidTwoFactorCode, models.ErrTwoFactorNotEnabled:
+		return http.StatusBadRequest
+	case models.ErrTwoFactorAlreadyEnabled:
+		return http.StatusConflict
+	case models.ErrUserNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetSessions 獲取目前登入的裝置列表
+func (uc *UserController) GetSessions(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	// 舊版 access token 沒有工作階段資訊，此時僅無法標示目前裝置
+	currentSessionID, _ := utils.GetSessionIDFromHeader(c)
+
+	sessions, msgOpt := uc.userService.GetSessions(userID, currentSessionID)
+	if msgOpt != nil {
+		ErrorResponse(c, sessionErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, sessions, "獲取登入裝置成功")
+}
+
+// RevokeSession 登出指定裝置，並斷開該裝置的 WebSocket 連線
+func (uc *UserController) RevokeSession(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	sessionID := c.Param("id")
+	if msgOpt := uc.userService.RevokeSession(userID, sessionID); msgOpt != nil {
+		ErrorResponse(c, sessionErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	if uc.clientManager != nil {
+		uc.clientManager.DisconnectSession(userID, sessionID)
+	}
+
+	SuccessResponse(c, nil, "已登出該裝置")
+}
+
+// RevokeOtherSessions 登出目前裝置以外的所有裝置
+func (uc *UserController) RevokeOtherSessions(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	currentSessionID, _ := utils.GetSessionIDFromHeader(c)
+
+	sessionIDs, msgOpt := uc.userService.RevokeOtherSessions(userID, currentSessionID)
+	if msgOpt != nil {
+		ErrorResponse(c, sessionErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	if uc.clientManager != nil {
+		for _, sessionID := range sessionIDs {
+			uc.clientManager.DisconnectSession(userID, sessionID)
+		}
+	}
+
+	SuccessResponse(c, gin.H{"revoked_count": len(sessionIDs)}, "已登出其他裝置")
+}
+
+// sessionErrorStatus 將登入工作階段相關錯誤碼對應為 HTTP 狀態碼
+func sessionErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams:
+		return http.StatusBadRequest
+	case models.ErrInvalidToken:
+		return http.StatusUnauthorized
+	case models.ErrNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// SendEmailVerification 重新寄送電子郵件驗證信
+func (uc *UserController) SendEmailVerification(c *gin.Context) {
+	userID, _, err := utils.GetUserIDFromHeader(c)
+	if err != nil {
+		ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrUnauthorized})
+		return
+	}
+
+	if msgOpt := uc.userService.SendEmailVerification(userID); msgOpt != nil {
+		ErrorResponse(c, accountEmailErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "驗證信已寄出")
+}
+
+// VerifyEmail 以驗證信中的 token 完成電子郵件驗證
+func (uc *UserController) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "電子郵件驗證失敗",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if msgOpt := uc.userService.VerifyEmail(req.Token); msgOpt != nil {
+		ErrorResponse(c, accountEmailErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "電子郵件驗證成功")
+}
+
+// ForgotPassword 申請重設密碼，無論電子郵件是否已註冊都返回相同結果
+func (uc *UserController) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "申請重設密碼失敗",
 			Details: err.Error(),
 		})
 		return
 	}
 
-	SuccessResponse(c, nil, "兩步驟驗證狀態更新成功")
+	if msgOpt := uc.userService.RequestPasswordReset(req.Email); msgOpt != nil {
+		ErrorResponse(c, accountEmailErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	SuccessResponse(c, nil, "若該電子郵件已註冊，您將收到重設密碼信")
+}
+
+// ResetPassword 以重設密碼信中的 token 設定新密碼，所有裝置需重新登入
+func (uc *UserController) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		ErrorResponse(c, http.StatusBadRequest, models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "重設密碼失敗",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if msgOpt := uc.userService.ResetPassword(req.Token, req.NewPassword); msgOpt != nil {
+		ErrorResponse(c, accountEmailErrorStatus(msgOpt.Code), *msgOpt)
+		return
+	}
+
+	// 所有 token 已被撤銷，清除目前裝置的 refresh token
+	utils.ClearCookie(c, uc.config, "refresh_token")
+
+	SuccessResponse(c, nil, "密碼已重設，請重新登入")
+}
+
+// accountEmailErrorStatus 將電子郵件驗證與重設密碼相關錯誤碼對應為 HTTP 狀態碼
+// 連結失效屬於請求內容錯誤，不使用 401，避免前端誤以為登入已過期而嘗試刷新 token
+func accountEmailErrorStatus(code models.ErrorCode) int {
+	switch code {
+	case models.ErrInvalidParams, models.ErrInvalidToken:
+		return http.StatusBadRequest
+	case models.ErrEmailAlreadyVerified:
+		return http.StatusConflict
+	case models.ErrUserNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 // DeactivateAccount 停用帳號
@@ -464,6 +747,9 @@ func (uc *UserController) DeactivateAccount(c *gin.Context) {
 		return
 	}
 
+	// 所有 token 已被撤銷，清除目前裝置的 refresh token
+	utils.ClearCookie(c, uc.config, "refresh_token")
+
 	SuccessResponse(c, nil, "帳號已停用")
 }
 
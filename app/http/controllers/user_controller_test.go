@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"chat_app_backend/app/mocks"
 	"chat_app_backend/app/models"
+	"chat_app_backend/app/services"
 	"chat_app_backend/config"
 	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -13,8 +15,19 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// sessionClientManager 僅記錄 DisconnectSession 呼叫的 ClientManager mock
+type sessionClientManager struct {
+	services.ClientManager
+	mock.Mock
+}
+
+func (m *sessionClientManager) DisconnectSession(userID string, sessionID string) {
+	m.Called(userID, sessionID)
+}
+
 // setupTestConfig 設置測試用的 config
 func setupTestConfig() {
 	if config.AppConfig == nil {
@@ -185,7 +198,7 @@ func TestUserController_Login(t *testing.T) {
 		}
 
 		mockUserService := new(mocks.UserService)
-		mockUserService.On("Login", mock.AnythingOfType("models.User")).Return(loginResponse, nil)
+		mockUserService.On("Login", mock.AnythingOfType("models.User"), mock.AnythingOfType("models.DeviceInfo")).Return(loginResponse, nil)
 
 		cfg := &config.Config{
 			JWT: config.JWTConfig{
@@ -251,7 +264,7 @@ func TestUserController_Login(t *testing.T) {
 		}
 
 		mockUserService := new(mocks.UserService)
-		mockUserService.On("Login", mock.AnythingOfType("models.User")).Return(
+		mockUserService.On("Login", mock.AnythingOfType("models.User"), mock.AnythingOfType("models.DeviceInfo")).Return(
 			(*models.LoginResponse)(nil),
 			&models.MessageOptions{
 				Code:    models.ErrLoginFailed,
@@ -288,7 +301,7 @@ func TestUserController_Login(t *testing.T) {
 		}
 
 		mockUserService := new(mocks.UserService)
-		mockUserService.On("Login", mock.AnythingOfType("models.User")).Return(
+		mockUserService.On("Login", mock.AnythingOfType("models.User"), mock.AnythingOfType("models.DeviceInfo")).Return(
 			(*models.LoginResponse)(nil),
 			&models.MessageOptions{
 				Code:    models.ErrInternalServer,
@@ -317,6 +330,191 @@ func TestUserController_Login(t *testing.T) {
 
 		mockUserService.AssertExpectations(t)
 	})
+
+	t.Run("需要兩步驟驗證時不設置 cookies", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("Login", mock.AnythingOfType("models.User"), mock.AnythingOfType("models.DeviceInfo")).Return(&models.LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    "challenge_token_123",
+		}, nil)
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+
+		router := setupTestRouter()
+		router.POST("/login", controller.Login)
+
+		body, _ := json.Marshal(models.User{Email: "test@example.com", Password: "password123"})
+		req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Result().Cookies(), "尚未完成兩步驟驗證不應設置 cookies")
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		dataMap, ok := response.Data.(map[string]interface{})
+		assert.True(t, ok)
+		assert.Equal(t, true, dataMap["two_factor_required"])
+		assert.Equal(t, "challenge_token_123", dataMap["challenge_token"])
+		assert.NotContains(t, dataMap, "access_token")
+
+		mockUserService.AssertExpectations(t)
+	})
+}
+
+// TestUserController_VerifyTwoFactorLogin 測試兩步驟登入
+func TestUserController_VerifyTwoFactorLogin(t *testing.T) {
+	t.Run("驗證成功後設置 cookies", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("VerifyTwoFactorLogin", "challenge_token_123", "123456", mock.AnythingOfType("models.DeviceInfo")).Return(&models.LoginResponse{
+			AccessToken:  "access_token_123",
+			RefreshToken: "refresh_token_123",
+			CSRFToken:    "csrf_token_123",
+		}, nil)
+
+		cfg := &config.Config{
+			JWT: config.JWTConfig{
+				RefreshExpireHours: 24,
+			},
+		}
+		controller := NewUserController(cfg, nil, mockUserService, nil)
+
+		router := setupTestRouter()
+		router.POST("/login/2fa", controller.VerifyTwoFactorLogin)
+
+		body, _ := json.Marshal(models.TwoFactorLoginRequest{ChallengeToken: "challenge_token_123", Code: "123456"})
+		req, _ := http.NewRequest(http.MethodPost, "/login/2fa", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		dataMap, ok := response.Data.(map[string]interface{})
+		assert.True(t, ok)
+		assert.Equal(t, "access_token_123", dataMap["access_token"])
+
+		cookieNames := []string{}
+		for _, cookie := range w.Result().Cookies() {
+			cookieNames = append(cookieNames, cookie.Name)
+		}
+		assert.ElementsMatch(t, []string{"refresh_token", "csrf_token"}, cookieNames)
+
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("驗證碼錯誤", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("VerifyTwoFactorLogin", "challenge_token_123", "000000", mock.AnythingOfType("models.DeviceInfo")).Return(nil, &models.MessageOptions{
+			Code:    models.ErrInvalidTwoFactorCode,
+			Message: "驗證碼錯誤",
+		})
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+
+		router := setupTestRouter()
+		router.POST("/login/2fa", controller.VerifyTwoFactorLogin)
+
+		body, _ := json.Marshal(models.TwoFactorLoginRequest{ChallengeToken: "challenge_token_123", Code: "000000"})
+		req, _ := http.NewRequest(http.MethodPost, "/login/2fa", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Result().Cookies())
+
+		var response models.APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, models.ErrInvalidTwoFactorCode, response.Code)
+
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("缺少驗證碼", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+
+		router := setupTestRouter()
+		router.POST("/login/2fa", controller.VerifyTwoFactorLogin)
+
+		body, _ := json.Marshal(models.TwoFactorLoginRequest{ChallengeToken: "challenge_token_123"})
+		req, _ := http.NewRequest(http.MethodPost, "/login/2fa", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockUserService.AssertNotCalled(t, "VerifyTwoFactorLogin", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// TestUserController_RefreshToken 測試刷新令牌
+func TestUserController_RefreshToken(t *testing.T) {
+	cfg := &config.Config{
+		JWT: config.JWTConfig{
+			RefreshExpireHours: 24,
+		},
+	}
+
+	t.Run("刷新成功後寫入輪替的 refresh token", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("RefreshToken", "old_refresh_token", mock.AnythingOfType("models.DeviceInfo")).Return(&models.RefreshTokenResponse{
+			AccessToken:  "access_token_123",
+			RefreshToken: "new_refresh_token",
+			CSRFToken:    "csrf_token_123",
+		}, nil)
+
+		controller := NewUserController(cfg, nil, mockUserService, nil)
+
+		router := setupTestRouter()
+		router.POST("/refresh_token", controller.RefreshToken)
+
+		req, _ := http.NewRequest(http.MethodPost, "/refresh_token", nil)
+		req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "old_refresh_token"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		cookies := map[string]string{}
+		for _, cookie := range w.Result().Cookies() {
+			cookies[cookie.Name] = cookie.Value
+		}
+		assert.Equal(t, "new_refresh_token", cookies["refresh_token"])
+		assert.Equal(t, "csrf_token_123", cookies["csrf_token"])
+
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("refresh token 已被重複使用", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("RefreshToken", "reused_refresh_token", mock.AnythingOfType("models.DeviceInfo")).Return(nil, &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "登入憑證已被重複使用，請重新登入",
+		})
+
+		controller := NewUserController(cfg, nil, mockUserService, nil)
+
+		router := setupTestRouter()
+		router.POST("/refresh_token", controller.RefreshToken)
+
+		req, _ := http.NewRequest(http.MethodPost, "/refresh_token", nil)
+		req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "reused_refresh_token"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, w.Result().Cookies())
+	})
 }
 
 // TestUserController_Logout 測試用戶登出
@@ -370,3 +568,310 @@ func TestUserController_Logout(t *testing.T) {
 		mockUserService.AssertExpectations(t)
 	})
 }
+
+// setupSessionRouter 設置已登入用戶的裝置管理路由
+func setupSessionRouter(controller *UserController, userID primitive.ObjectID, sessionID string) *gin.Engine {
+	router := setupTestRouter()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID.Hex())
+		c.Set("user_object_id", userID)
+		c.Set("session_id", sessionID)
+		c.Next()
+	})
+	router.GET("/user/sessions", controller.GetSessions)
+	router.DELETE("/user/sessions/:id", controller.RevokeSession)
+	router.POST("/user/sessions/revoke-others", controller.RevokeOtherSessions)
+	return router
+}
+
+// TestUserController_UploadUserImage 測試上傳用戶頭像或橫幅
+func TestUserController_UploadUserImage(t *testing.T) {
+	userID := primitive.NewObjectID()
+	fileID := primitive.NewObjectID()
+
+	mockUserService := new(mocks.UserService)
+	mockUserService.On("UploadUserImage", userID.Hex(), mock.Anything, mock.AnythingOfType("*multipart.FileHeader"), "avatar").Return(&models.UserImageResponse{
+		Type:   "avatar",
+		FileID: fileID.Hex(),
+		Status: "processing",
+	}, nil)
+
+	controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+	router := setupTestRouter()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", userID.Hex())
+		c.Set("user_object_id", userID)
+		c.Next()
+	})
+	router.POST("/user/images", controller.UploadUserImage)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	writer.WriteField("type", "avatar")
+	part, _ := writer.CreateFormFile("image", "me.png")
+	part.Write([]byte("png"))
+	writer.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, "/user/images", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// 圖片處理中時連結為空，客戶端依檔案ID與狀態在處理完成後重新取得
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data models.UserImageResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Empty(t, response.Data.ImageURL)
+	assert.Equal(t, fileID.Hex(), response.Data.FileID)
+	assert.Equal(t, "processing", response.Data.Status)
+	mockUserService.AssertExpectations(t)
+}
+
+// TestUserController_Sessions 測試登入裝置管理
+func TestUserController_Sessions(t *testing.T) {
+	userID := primitive.NewObjectID()
+	currentSessionID := primitive.NewObjectID().Hex()
+
+	t.Run("獲取登入裝置列表", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("GetSessions", userID.Hex(), currentSessionID).Return([]models.SessionResponse{
+			{ID: currentSessionID, UserAgent: "Chrome", Current: true},
+		}, nil)
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+		router := setupSessionRouter(controller, userID, currentSessionID)
+
+		req, _ := http.NewRequest(http.MethodGet, "/user/sessions", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data []models.SessionResponse `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Len(t, response.Data, 1)
+		assert.True(t, response.Data[0].Current)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("登出指定裝置並斷開連線", func(t *testing.T) {
+		sessionID := primitive.NewObjectID().Hex()
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("RevokeSession", userID.Hex(), sessionID).Return(nil)
+		mockClientManager := new(sessionClientManager)
+		mockClientManager.On("DisconnectSession", userID.Hex(), sessionID)
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, mockClientManager)
+		router := setupSessionRouter(controller, userID, currentSessionID)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/user/sessions/"+sessionID, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockUserService.AssertExpectations(t)
+		mockClientManager.AssertExpectations(t)
+	})
+
+	t.Run("登出不存在的裝置", func(t *testing.T) {
+		sessionID := primitive.NewObjectID().Hex()
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("RevokeSession", userID.Hex(), sessionID).Return(&models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "登入裝置不存在或已登出",
+		})
+		mockClientManager := new(sessionClientManager)
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, mockClientManager)
+		router := setupSessionRouter(controller, userID, currentSessionID)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/user/sessions/"+sessionID, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockClientManager.AssertNotCalled(t, "DisconnectSession", mock.Anything, mock.Anything)
+	})
+
+	t.Run("登出其他裝置", func(t *testing.T) {
+		revokedIDs := []string{primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()}
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("RevokeOtherSessions", userID.Hex(), currentSessionID).Return(revokedIDs, nil)
+		mockClientManager := new(sessionClientManager)
+		mockClientManager.On("DisconnectSession", userID.Hex(), revokedIDs[0]).Once()
+		mockClientManager.On("DisconnectSession", userID.Hex(), revokedIDs[1]).Once()
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, mockClientManager)
+		router := setupSessionRouter(controller, userID, currentSessionID)
+
+		req, _ := http.NewRequest(http.MethodPost, "/user/sessions/revoke-others", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Data map[string]int `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, 2, response.Data["revoked_count"])
+		mockUserService.AssertExpectations(t)
+		mockClientManager.AssertExpectations(t)
+	})
+}
+
+// TestUserController_AccountEmail 測試電子郵件驗證與重設密碼
+func TestUserController_AccountEmail(t *testing.T) {
+	t.Run("驗證電子郵件成功", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("VerifyEmail", "verification_token").Return(nil)
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+		router := setupTestRouter()
+		router.POST("/email/verify", controller.VerifyEmail)
+
+		body, _ := json.Marshal(models.VerifyEmailRequest{Token: "verification_token"})
+		req, _ := http.NewRequest(http.MethodPost, "/email/verify", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("驗證連結無效", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("VerifyEmail", "expired_token").Return(&models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "驗證連結無效或已過期",
+		})
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+		router := setupTestRouter()
+		router.POST("/email/verify", controller.VerifyEmail)
+
+		body, _ := json.Marshal(models.VerifyEmailRequest{Token: "expired_token"})
+		req, _ := http.NewRequest(http.MethodPost, "/email/verify", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("重新寄送驗證信時電子郵件已驗證", func(t *testing.T) {
+		userID := primitive.NewObjectID()
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("SendEmailVerification", userID.Hex()).Return(&models.MessageOptions{
+			Code:    models.ErrEmailAlreadyVerified,
+			Message: "電子郵件已驗證",
+		})
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+		router := setupTestRouter()
+		router.Use(func(c *gin.Context) {
+			c.Set("user_id", userID.Hex())
+			c.Set("user_object_id", userID)
+			c.Next()
+		})
+		router.POST("/user/email/verification", controller.SendEmailVerification)
+
+		req, _ := http.NewRequest(http.MethodPost, "/user/email/verification", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("申請重設密碼", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("RequestPasswordReset", "test@example.com").Return(nil)
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+		router := setupTestRouter()
+		router.POST("/password/forgot", controller.ForgotPassword)
+
+		body, _ := json.Marshal(models.ForgotPasswordRequest{Email: "test@example.com"})
+		req, _ := http.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("申請重設密碼時電子郵件格式錯誤", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+		router := setupTestRouter()
+		router.POST("/password/forgot", controller.ForgotPassword)
+
+		body, _ := json.Marshal(models.ForgotPasswordRequest{Email: "invalid"})
+		req, _ := http.NewRequest(http.MethodPost, "/password/forgot", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockUserService.AssertNotCalled(t, "RequestPasswordReset", mock.Anything)
+	})
+
+	t.Run("重設密碼成功並清除 refresh token", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("ResetPassword", "reset_token", "newpassword123").Return(nil)
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+		router := setupTestRouter()
+		router.POST("/password/reset", controller.ResetPassword)
+
+		body, _ := json.Marshal(models.ResetPasswordRequest{Token: "reset_token", NewPassword: "newpassword123"})
+		req, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "old_refresh_token"})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var refreshCookie *http.Cookie
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == "refresh_token" {
+				refreshCookie = cookie
+			}
+		}
+		if assert.NotNil(t, refreshCookie) {
+			assert.Empty(t, refreshCookie.Value)
+			assert.Less(t, refreshCookie.MaxAge, 0)
+		}
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("重設密碼連結已使用", func(t *testing.T) {
+		mockUserService := new(mocks.UserService)
+		mockUserService.On("ResetPassword", "used_token", "newpassword123").Return(&models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "重設密碼連結已使用或已失效，請重新申請",
+		})
+
+		controller := NewUserController(&config.Config{}, nil, mockUserService, nil)
+		router := setupTestRouter()
+		router.POST("/password/reset", controller.ResetPassword)
+
+		body, _ := json.Marshal(models.ResetPasswordRequest{Token: "used_token", NewPassword: "newpassword123"})
+		req, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, w.Result().Cookies(), "重設失敗時不應清除 cookie")
+		mockUserService.AssertExpectations(t)
+	})
+}
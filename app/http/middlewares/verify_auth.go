@@ -3,6 +3,7 @@ package middlewares
 import (
 	"chat_app_backend/app/http/controllers"
 	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
 	"chat_app_backend/utils"
 	"net/http"
 
@@ -10,7 +11,7 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-func Auth() gin.HandlerFunc {
+func Auth(tokenDenylist providers.TokenDenylist) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var accessToken string
 		var err error
@@ -27,8 +28,8 @@ func Auth() gin.HandlerFunc {
 			}
 		}
 
-		res, err := utils.ValidateAccessToken(accessToken)
-		if err != nil || !res {
+		claims, err := utils.ParseAccessToken(accessToken)
+		if err != nil || isAccessTokenRevoked(tokenDenylist, claims) {
 			controllers.ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrInvalidToken})
 			c.Abort()
 			return
@@ -37,3 +38,31 @@ func Auth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// OptionalAuth 用於登入與否皆可存取的路由：未帶 token 時放行，帶有已撤銷的 token 時拒絕
+func OptionalAuth(tokenDenylist providers.TokenDenylist) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accessToken, err := utils.GetAccessTokenByHeader(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		if claims, err := utils.ParseAccessToken(accessToken); err == nil && isAccessTokenRevoked(tokenDenylist, claims) {
+			controllers.ErrorResponse(c, http.StatusUnauthorized, models.MessageOptions{Code: models.ErrInvalidToken})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isAccessTokenRevoked 檢查 access token 是否已列入撤銷清單
+func isAccessTokenRevoked(tokenDenylist providers.TokenDenylist, claims *utils.AccessTokenClaims) bool {
+	if tokenDenylist == nil {
+		return false
+	}
+
+	return tokenDenylist.IsRevoked(claims.ID, claims.SessionID, claims.UserID, claims.IssuedAtTime())
+}
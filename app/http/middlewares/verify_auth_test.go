@@ -2,12 +2,14 @@ package middlewares
 
 import (
 	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
 	"chat_app_backend/config"
 	"chat_app_backend/utils"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -44,7 +46,7 @@ func TestAuthMiddleware(t *testing.T) {
 		c, w := setupTestRouter(req)
 
 		// 執行
-		Auth()(c)
+		Auth(nil)(c)
 
 		// 斷言
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
@@ -62,7 +64,7 @@ func TestAuthMiddleware(t *testing.T) {
 		c, w := setupTestRouter(req)
 
 		// 執行
-		Auth()(c)
+		Auth(nil)(c)
 
 		// 斷言
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
@@ -75,7 +77,7 @@ func TestAuthMiddleware(t *testing.T) {
 
 	t.Run("HTTP 請求使用有效令牌", func(t *testing.T) {
 		// Setup
-		tokenRes, err := utils.GenAccessToken(dummyUserID)
+		tokenRes, err := utils.GenAccessToken(dummyUserID, "")
 		assert.NoError(t, err)
 
 		req, _ := http.NewRequest(http.MethodGet, "/", nil)
@@ -86,7 +88,7 @@ func TestAuthMiddleware(t *testing.T) {
 		c.Next()
 
 		// 執行
-		Auth()(c)
+		Auth(nil)(c)
 
 		// 斷言
 		assert.NotEqual(t, http.StatusUnauthorized, w.Code)
@@ -95,7 +97,7 @@ func TestAuthMiddleware(t *testing.T) {
 
 	t.Run("WebSocket 請求使用有效令牌", func(t *testing.T) {
 		// Setup
-		tokenRes, err := utils.GenAccessToken(dummyUserID)
+		tokenRes, err := utils.GenAccessToken(dummyUserID, "")
 		assert.NoError(t, err)
 
 		req, _ := http.NewRequest(http.MethodGet, "/?token="+tokenRes.Token, nil)
@@ -104,7 +106,7 @@ func TestAuthMiddleware(t *testing.T) {
 		c, w := setupTestRouter(req)
 
 		// 執行
-		Auth()(c)
+		Auth(nil)(c)
 
 		// 斷言
 		assert.NotEqual(t, http.StatusUnauthorized, w.Code)
@@ -119,10 +121,127 @@ func TestAuthMiddleware(t *testing.T) {
 		c, w := setupTestRouter(req)
 
 		// 執行
-		Auth()(c)
+		Auth(nil)(c)
 
 		// 斷言
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 		assert.True(t, c.IsAborted())
 	})
 }
+
+func TestAuthMiddleware_Denylist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestAppConfig()
+
+	dummyUserID := "60d5ecb8b3920215a8204803"
+	sessionID := "60d5ecb8b3920215a8204804"
+
+	newRequest := func(token string, websocketUpgrade bool) *http.Request {
+		if websocketUpgrade {
+			req, _ := http.NewRequest(http.MethodGet, "/?token="+token, nil)
+			req.Header.Set("Upgrade", "websocket")
+			req.Header.Set("Connection", "Upgrade")
+			return req
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+
+	t.Run("已撤銷的令牌", func(t *testing.T) {
+		tokenDenylist := providers.NewCacheTokenDenylist(nil, time.Hour)
+		tokenRes, err := utils.GenAccessToken(dummyUserID, sessionID)
+		assert.NoError(t, err)
+		claims, err := utils.ParseAccessToken(tokenRes.Token)
+		assert.NoError(t, err)
+		assert.NoError(t, tokenDenylist.RevokeToken(claims.ID, claims.ExpiresAt.Time))
+
+		for _, websocketUpgrade := range []bool{false, true} {
+			c, w := setupTestRouter(newRequest(tokenRes.Token, websocketUpgrade))
+			Auth(tokenDenylist)(c)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.True(t, c.IsAborted())
+		}
+	})
+
+	t.Run("已撤銷的工作階段", func(t *testing.T) {
+		tokenDenylist := providers.NewCacheTokenDenylist(nil, time.Hour)
+		tokenRes, err := utils.GenAccessToken(dummyUserID, sessionID)
+		assert.NoError(t, err)
+		otherSession, err := utils.GenAccessToken(dummyUserID, "60d5ecb8b3920215a8204805")
+		assert.NoError(t, err)
+		assert.NoError(t, tokenDenylist.RevokeSession(sessionID))
+
+		c, w := setupTestRouter(newRequest(tokenRes.Token, true))
+		Auth(tokenDenylist)(c)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		c, w = setupTestRouter(newRequest(otherSession.Token, false))
+		Auth(tokenDenylist)(c)
+		assert.NotEqual(t, http.StatusUnauthorized, w.Code)
+		assert.False(t, c.IsAborted())
+	})
+
+	t.Run("撤銷用戶所有令牌", func(t *testing.T) {
+		tokenDenylist := providers.NewCacheTokenDenylist(nil, time.Hour)
+		tokenRes, err := utils.GenAccessToken(dummyUserID, sessionID)
+		assert.NoError(t, err)
+		assert.NoError(t, tokenDenylist.RevokeUserTokens(dummyUserID))
+
+		c, w := setupTestRouter(newRequest(tokenRes.Token, false))
+		Auth(tokenDenylist)(c)
+
+		var response models.APIResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, models.ErrInvalidToken, response.Code)
+	})
+}
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestAppConfig()
+
+	dummyUserID := "60d5ecb8b3920215a8204803"
+	tokenDenylist := providers.NewCacheTokenDenylist(nil, time.Hour)
+
+	t.Run("沒有令牌時放行", func(t *testing.T) {
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/avatar/a.png", nil)
+		c, _ := setupTestRouter(req)
+
+		OptionalAuth(tokenDenylist)(c)
+
+		assert.False(t, c.IsAborted())
+	})
+
+	t.Run("有效令牌放行", func(t *testing.T) {
+		tokenRes, err := utils.GenAccessToken(dummyUserID, "")
+		assert.NoError(t, err)
+
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/general/a.pdf", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenRes.Token)
+		c, _ := setupTestRouter(req)
+
+		OptionalAuth(tokenDenylist)(c)
+
+		assert.False(t, c.IsAborted())
+	})
+
+	t.Run("已撤銷的令牌", func(t *testing.T) {
+		tokenRes, err := utils.GenAccessToken(dummyUserID, "")
+		assert.NoError(t, err)
+		claims, err := utils.ParseAccessToken(tokenRes.Token)
+		assert.NoError(t, err)
+		assert.NoError(t, tokenDenylist.RevokeToken(claims.ID, claims.ExpiresAt.Time))
+
+		req, _ := http.NewRequest(http.MethodGet, "/uploads/general/a.pdf", nil)
+		req.Header.Set("Authorization", "Bearer "+tokenRes.Token)
+		c, w := setupTestRouter(req)
+
+		OptionalAuth(tokenDenylist)(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.True(t, c.IsAborted())
+	})
+}
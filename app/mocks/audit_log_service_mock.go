@@ -0,0 +1,22 @@
+package mocks
+
+import (
+	"chat_app_backend/app/models"
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// AuditLogService 是 services.AuditLogService 介面的 mock 實作
+type AuditLogService struct {
+	mock.Mock
+}
+
+// GetServerAuditLogs 獲取伺服器稽核紀錄
+func (m *AuditLogService) GetServerAuditLogs(ctx context.Context, userID string, serverID string, query models.AuditLogQuery) (*models.AuditLogResults, *models.MessageOptions) {
+	args := m.Called(ctx, userID, serverID, query)
+	if args.Get(0) == nil {
+		return nil, messageOptionsAt(args, 1)
+	}
+	return args.Get(0).(*models.AuditLogResults), messageOptionsAt(args, 1)
+}
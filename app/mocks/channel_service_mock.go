@@ -11,13 +11,13 @@ type ChannelService struct {
 	mock.Mock
 }
 
-// GetChannelsByServerID 根據伺服器ID獲取頻道列表
-func (m *ChannelService) GetChannelsByServerID(userID string, serverID string) ([]models.ChannelResponse, *models.MessageOptions) {
+// GetChannelsByServerID 根據伺服器ID獲取頻道樹
+func (m *ChannelService) GetChannelsByServerID(userID string, serverID string) (*models.ChannelTreeResponse, *models.MessageOptions) {
 	args := m.Called(userID, serverID)
 	if args.Get(0) == nil {
 		return nil, args.Get(1).(*models.MessageOptions)
 	}
-	return args.Get(0).([]models.ChannelResponse), args.Get(1).(*models.MessageOptions)
+	return args.Get(0).(*models.ChannelTreeResponse), args.Get(1).(*models.MessageOptions)
 }
 
 // GetChannelByID 根據頻道ID獲取頻道詳細信息
@@ -55,3 +55,39 @@ func (m *ChannelService) DeleteChannel(userID string, channelID string) *models.
 	}
 	return args.Get(0).(*models.MessageOptions)
 }
+
+// CreateCategory 創建頻道類別
+func (m *ChannelService) CreateCategory(userID string, serverID string, request models.CreateChannelCategoryRequest) (*models.ChannelCategoryResponse, *models.MessageOptions) {
+	args := m.Called(userID, serverID, request)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*models.MessageOptions)
+	}
+	return args.Get(0).(*models.ChannelCategoryResponse), args.Get(1).(*models.MessageOptions)
+}
+
+// UpdateCategory 重新命名頻道類別
+func (m *ChannelService) UpdateCategory(userID string, categoryID string, request models.UpdateChannelCategoryRequest) (*models.ChannelCategoryResponse, *models.MessageOptions) {
+	args := m.Called(userID, categoryID, request)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*models.MessageOptions)
+	}
+	return args.Get(0).(*models.ChannelCategoryResponse), args.Get(1).(*models.MessageOptions)
+}
+
+// DeleteCategory 刪除頻道類別
+func (m *ChannelService) DeleteCategory(userID string, categoryID string) *models.MessageOptions {
+	args := m.Called(userID, categoryID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+// ReorderChannels 批量調整類別與頻道排序
+func (m *ChannelService) ReorderChannels(userID string, serverID string, request models.ReorderChannelsRequest) (*models.ChannelTreeResponse, *models.MessageOptions) {
+	args := m.Called(userID, serverID, request)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(*models.MessageOptions)
+	}
+	return args.Get(0).(*models.ChannelTreeResponse), args.Get(1).(*models.MessageOptions)
+}
@@ -47,3 +47,19 @@ func (m *ChatRepository) DeleteMessagesByRoomID(ctx context.Context, roomID stri
 	args := m.Called(ctx, roomID)
 	return args.Error(0)
 }
+
+func (m *ChatRepository) GetRoomReadStates(ctx context.Context, userID string, roomIDs []string) (map[string]models.RoomReadState, error) {
+	args := m.Called(ctx, userID, roomIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]models.RoomReadState), args.Error(1)
+}
+
+func (m *ChatRepository) SearchMessages(ctx context.Context, roomIDs []string, request models.MessageSearchRequest, limit int64) ([]models.Message, error) {
+	args := m.Called(ctx, roomIDs, request, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Message), args.Error(1)
+}
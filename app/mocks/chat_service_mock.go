@@ -14,8 +14,8 @@ type ChatService struct {
 }
 
 // HandleWebSocket 處理 WebSocket 連接
-func (m *ChatService) HandleWebSocket(ws *websocket.Conn, userID string) {
-	m.Called(ws, userID)
+func (m *ChatService) HandleWebSocket(ws *websocket.Conn, userID string, sessionID string) {
+	m.Called(ws, userID, sessionID)
 }
 
 // GetDMRoomResponseList 獲取聊天列表response
@@ -76,8 +76,8 @@ func (m *ChatService) GetDMMessages(ctx context.Context, userID string, roomID s
 }
 
 // GetChannelMessages 獲取頻道訊息
-func (m *ChatService) GetChannelMessages(ctx context.Context, userID string, channelID string, before string, after string, limit string) ([]models.MessageResponse, *models.MessageOptions) {
-	args := m.Called(ctx, userID, channelID, before, after, limit)
+func (m *ChatService) GetChannelMessages(ctx context.Context, userID string, channelID string, before string, after string, limit string, topLevelOnly bool) ([]models.MessageResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, channelID, before, after, limit, topLevelOnly)
 	var messages []models.MessageResponse
 	var msgOpts *models.MessageOptions
 
@@ -90,3 +90,145 @@ func (m *ChatService) GetChannelMessages(ctx context.Context, userID string, cha
 
 	return messages, msgOpts
 }
+
+// GetThreadMessages 獲取討論串回覆
+func (m *ChatService) GetThreadMessages(ctx context.Context, userID string, roomType models.RoomType, roomID string, threadID string, before string, after string, limit string) ([]models.MessageResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, roomType, roomID, threadID, before, after, limit)
+	var messages []models.MessageResponse
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		messages = args.Get(0).([]models.MessageResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return messages, msgOpts
+}
+
+// EditMessage 編輯訊息
+func (m *ChatService) EditMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, content string) (*models.MessageResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, roomType, roomID, messageID, content)
+	var message *models.MessageResponse
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		message = args.Get(0).(*models.MessageResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return message, msgOpts
+}
+
+// DeleteMessage 刪除訊息
+func (m *ChatService) DeleteMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) *models.MessageOptions {
+	args := m.Called(ctx, userID, roomType, roomID, messageID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+// AddReaction 新增訊息表情回應
+func (m *ChatService) AddReaction(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, emoji string) (*models.MessageResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, roomType, roomID, messageID, emoji)
+	var message *models.MessageResponse
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		message = args.Get(0).(*models.MessageResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return message, msgOpts
+}
+
+// RemoveReaction 移除訊息表情回應
+func (m *ChatService) RemoveReaction(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, emoji string) (*models.MessageResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, roomType, roomID, messageID, emoji)
+	var message *models.MessageResponse
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		message = args.Get(0).(*models.MessageResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return message, msgOpts
+}
+
+// MarkRoomRead 標記房間已讀
+func (m *ChatService) MarkRoomRead(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*models.ReadStateResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, roomType, roomID, messageID)
+	var state *models.ReadStateResponse
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		state = args.Get(0).(*models.ReadStateResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return state, msgOpts
+}
+
+// GetAttachment 獲取訊息附件
+func (m *ChatService) GetAttachment(ctx context.Context, userID string, fileID string) (*models.MessageAttachment, *models.MessageOptions) {
+	args := m.Called(ctx, userID, fileID)
+	var attachment *models.MessageAttachment
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		attachment = args.Get(0).(*models.MessageAttachment)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return attachment, msgOpts
+}
+
+// CanAccessAttachment 檢查用戶能否存取附件所屬房間
+func (m *ChatService) CanAccessAttachment(ctx context.Context, userID string, fileIDs []string) (bool, *models.MessageOptions) {
+	args := m.Called(ctx, userID, fileIDs)
+	var msgOpts *models.MessageOptions
+
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return args.Bool(0), msgOpts
+}
+
+// SearchMessages 全文搜尋訊息
+func (m *ChatService) SearchMessages(ctx context.Context, userID string, request models.MessageSearchRequest) (*models.MessageSearchResults, *models.MessageOptions) {
+	args := m.Called(ctx, userID, request)
+	var results *models.MessageSearchResults
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		results = args.Get(0).(*models.MessageSearchResults)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return results, msgOpts
+}
+
+// LeaveServerRooms 將用戶的連線移出伺服器所有頻道房間
+func (m *ChatService) LeaveServerRooms(ctx context.Context, userID string, serverID string) *models.MessageOptions {
+	args := m.Called(ctx, userID, serverID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"chat_app_backend/app/models"
+	"io"
 	"mime/multipart"
 
 	"github.com/stretchr/testify/mock"
@@ -45,6 +46,19 @@ func (m *FileUploadService) UploadFileWithConfig(file multipart.File, header *mu
 	return args.Get(0).(*models.FileResult), args.Get(1).(*models.MessageOptions)
 }
 
+func (m *FileUploadService) UploadServerFile(file multipart.File, header *multipart.FileHeader, userID string, serverID string, config *models.FileUploadConfig) (*models.FileResult, *models.MessageOptions) {
+	args := m.Called(file, header, userID, serverID, config)
+	var result *models.FileResult
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		result = args.Get(0).(*models.FileResult)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
+
 // 驗證方法
 func (m *FileUploadService) ValidateFile(header *multipart.FileHeader) *models.MessageOptions {
 	args := m.Called(header)
@@ -120,6 +134,14 @@ func (m *FileUploadService) GetFileURLByID(fileID string) (string, *models.Messa
 	return args.String(0), args.Get(1).(*models.MessageOptions)
 }
 
+func (m *FileUploadService) GetFileDownloadURL(fileID string) (string, *models.MessageOptions) {
+	args := m.Called(fileID)
+	if args.Get(1) == nil {
+		return args.String(0), nil
+	}
+	return args.String(0), args.Get(1).(*models.MessageOptions)
+}
+
 func (m *FileUploadService) GetFileInfoByID(fileID string) (*models.UploadedFile, *models.MessageOptions) {
 	args := m.Called(fileID)
 	if args.Get(0) == nil {
@@ -128,6 +150,19 @@ func (m *FileUploadService) GetFileInfoByID(fileID string) (*models.UploadedFile
 	return args.Get(0).(*models.UploadedFile), args.Get(1).(*models.MessageOptions)
 }
 
+func (m *FileUploadService) GetFileVariant(fileID string, size string) (*models.FileVariantResponse, *models.MessageOptions) {
+	args := m.Called(fileID, size)
+	var variant *models.FileVariantResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		variant = args.Get(0).(*models.FileVariantResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return variant, msgOpt
+}
+
 func (m *FileUploadService) GetUserFiles(userID string) ([]*models.UploadedFile, *models.MessageOptions) {
 	args := m.Called(userID)
 	if args.Get(0) == nil {
@@ -136,6 +171,40 @@ func (m *FileUploadService) GetUserFiles(userID string) ([]*models.UploadedFile,
 	return args.Get(0).([]*models.UploadedFile), args.Get(1).(*models.MessageOptions)
 }
 
+func (m *FileUploadService) ResolveServedFile(filePath string) (*models.ServedFile, *models.MessageOptions) {
+	args := m.Called(filePath)
+	var result *models.ServedFile
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		result = args.Get(0).(*models.ServedFile)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
+
+func (m *FileUploadService) OpenFileContent(filePath string) (io.ReadCloser, *models.MessageOptions) {
+	args := m.Called(filePath)
+	var result io.ReadCloser
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		result = args.Get(0).(io.ReadCloser)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
+
+func (m *FileUploadService) MarkFileForCleanup(fileID string) *models.MessageOptions {
+	args := m.Called(fileID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
 func (m *FileUploadService) CleanupExpiredFiles() *models.MessageOptions {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -143,3 +212,114 @@ func (m *FileUploadService) CleanupExpiredFiles() *models.MessageOptions {
 	}
 	return args.Get(0).(*models.MessageOptions)
 }
+
+// 儲存配額方法
+
+func (m *FileUploadService) GetStorageUsage(userID string, serverID string) (*models.StorageUsageResponse, *models.MessageOptions) {
+	args := m.Called(userID, serverID)
+	var usage *models.StorageUsageResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		usage = args.Get(0).(*models.StorageUsageResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return usage, msgOpt
+}
+
+// 分段上傳方法
+
+func (m *FileUploadService) InitiateChunkedUpload(userID string, request models.InitiateChunkedUploadRequest) (*models.ChunkedUploadResponse, *models.MessageOptions) {
+	args := m.Called(userID, request)
+	var response *models.ChunkedUploadResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		response = args.Get(0).(*models.ChunkedUploadResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return response, msgOpt
+}
+
+func (m *FileUploadService) UploadChunk(userID string, uploadID string, chunkNumber int, reader io.Reader, size int64) (*models.ChunkedUploadResponse, *models.MessageOptions) {
+	args := m.Called(userID, uploadID, chunkNumber, reader, size)
+	var response *models.ChunkedUploadResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		response = args.Get(0).(*models.ChunkedUploadResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return response, msgOpt
+}
+
+func (m *FileUploadService) GetChunkedUpload(userID string, uploadID string) (*models.ChunkedUploadResponse, *models.MessageOptions) {
+	args := m.Called(userID, uploadID)
+	var response *models.ChunkedUploadResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		response = args.Get(0).(*models.ChunkedUploadResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return response, msgOpt
+}
+
+func (m *FileUploadService) CompleteChunkedUpload(userID string, uploadID string) (*models.FileResult, *models.MessageOptions) {
+	args := m.Called(userID, uploadID)
+	var result *models.FileResult
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		result = args.Get(0).(*models.FileResult)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
+
+func (m *FileUploadService) AbortChunkedUpload(userID string, uploadID string) *models.MessageOptions {
+	args := m.Called(userID, uploadID)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.MessageOptions)
+	}
+	return nil
+}
+
+func (m *FileUploadService) CleanupExpiredChunkedUploads() *models.MessageOptions {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.MessageOptions)
+	}
+	return nil
+}
+
+func (m *FileUploadService) InitiatePresignedUpload(userID string, request models.PresignedUploadRequest) (*models.PresignedUploadResponse, *models.MessageOptions) {
+	args := m.Called(userID, request)
+	var response *models.PresignedUploadResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		response = args.Get(0).(*models.PresignedUploadResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return response, msgOpt
+}
+
+func (m *FileUploadService) ConfirmPresignedUpload(userID string, uploadID string) (*models.FileResult, *models.MessageOptions) {
+	args := m.Called(userID, uploadID)
+	var result *models.FileResult
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		result = args.Get(0).(*models.FileResult)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
@@ -0,0 +1,52 @@
+package mocks
+
+import (
+	"chat_app_backend/app/models"
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// ModerationService 是 services.ModerationService 介面的 mock 實作
+type ModerationService struct {
+	mock.Mock
+}
+
+// KickMember 將成員踢出伺服器
+func (m *ModerationService) KickMember(ctx context.Context, userID string, serverID string, targetUserID string, request models.KickMemberRequest) *models.MessageOptions {
+	args := m.Called(ctx, userID, serverID, targetUserID, request)
+	return messageOptionsAt(args, 0)
+}
+
+// BanMember 封鎖成員
+func (m *ModerationService) BanMember(ctx context.Context, userID string, serverID string, targetUserID string, request models.BanMemberRequest) *models.MessageOptions {
+	args := m.Called(ctx, userID, serverID, targetUserID, request)
+	return messageOptionsAt(args, 0)
+}
+
+// UnbanMember 解除封鎖
+func (m *ModerationService) UnbanMember(ctx context.Context, userID string, serverID string, targetUserID string) *models.MessageOptions {
+	args := m.Called(ctx, userID, serverID, targetUserID)
+	return messageOptionsAt(args, 0)
+}
+
+// GetServerBans 獲取伺服器封鎖列表
+func (m *ModerationService) GetServerBans(ctx context.Context, userID string, serverID string) ([]models.ServerBanResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, serverID)
+	if args.Get(0) == nil {
+		return nil, messageOptionsAt(args, 1)
+	}
+	return args.Get(0).([]models.ServerBanResponse), messageOptionsAt(args, 1)
+}
+
+// TimeoutMember 禁言成員
+func (m *ModerationService) TimeoutMember(ctx context.Context, userID string, serverID string, targetUserID string, request models.TimeoutMemberRequest) *models.MessageOptions {
+	args := m.Called(ctx, userID, serverID, targetUserID, request)
+	return messageOptionsAt(args, 0)
+}
+
+// RemoveTimeout 解除禁言
+func (m *ModerationService) RemoveTimeout(ctx context.Context, userID string, serverID string, targetUserID string) *models.MessageOptions {
+	args := m.Called(ctx, userID, serverID, targetUserID)
+	return messageOptionsAt(args, 0)
+}
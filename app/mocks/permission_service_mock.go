@@ -0,0 +1,138 @@
+package mocks
+
+import (
+	"chat_app_backend/app/models"
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// PermissionService 是 services.PermissionService 介面的 mock 實作
+type PermissionService struct {
+	mock.Mock
+}
+
+// messageOptionsAt 取出 mock 回傳的錯誤訊息選項
+func messageOptionsAt(args mock.Arguments, index int) *models.MessageOptions {
+	if args.Get(index) == nil {
+		return nil
+	}
+	return args.Get(index).(*models.MessageOptions)
+}
+
+// GetMemberPermissions 計算用戶在伺服器中的有效權限
+func (m *PermissionService) GetMemberPermissions(ctx context.Context, serverID string, userID string) (models.Permission, *models.MessageOptions) {
+	args := m.Called(ctx, serverID, userID)
+	return args.Get(0).(models.Permission), messageOptionsAt(args, 1)
+}
+
+// CheckPermission 檢查用戶在伺服器中是否具有指定權限
+func (m *PermissionService) CheckPermission(ctx context.Context, serverID string, userID string, required models.Permission) *models.MessageOptions {
+	args := m.Called(ctx, serverID, userID, required)
+	return messageOptionsAt(args, 0)
+}
+
+// CheckMemberPosition 檢查用戶的最高角色位階是否高於目標成員
+func (m *PermissionService) CheckMemberPosition(ctx context.Context, serverID string, userID string, targetUserID string) *models.MessageOptions {
+	args := m.Called(ctx, serverID, userID, targetUserID)
+	return messageOptionsAt(args, 0)
+}
+
+// GetChannelPermissions 計算用戶在頻道中的有效權限
+func (m *PermissionService) GetChannelPermissions(ctx context.Context, channelID string, userID string) (models.Permission, *models.MessageOptions) {
+	args := m.Called(ctx, channelID, userID)
+	return args.Get(0).(models.Permission), messageOptionsAt(args, 1)
+}
+
+// CheckChannelPermission 檢查用戶在頻道中是否具有指定權限
+func (m *PermissionService) CheckChannelPermission(ctx context.Context, channelID string, userID string, required models.Permission) *models.MessageOptions {
+	args := m.Called(ctx, channelID, userID, required)
+	return messageOptionsAt(args, 0)
+}
+
+// FilterViewableChannels 篩選用戶可查看的頻道
+func (m *PermissionService) FilterViewableChannels(ctx context.Context, serverID string, userID string, channels []models.Channel) ([]models.Channel, *models.MessageOptions) {
+	args := m.Called(ctx, serverID, userID, channels)
+	if args.Get(0) == nil {
+		return nil, messageOptionsAt(args, 1)
+	}
+	return args.Get(0).([]models.Channel), messageOptionsAt(args, 1)
+}
+
+// GetMyPermissions 獲取用戶在伺服器中的有效權限名稱
+func (m *PermissionService) GetMyPermissions(ctx context.Context, userID string, serverID string) (*models.MemberPermissionsResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, serverID)
+	if args.Get(0) == nil {
+		return nil, messageOptionsAt(args, 1)
+	}
+	return args.Get(0).(*models.MemberPermissionsResponse), messageOptionsAt(args, 1)
+}
+
+// GetServerRoles 獲取伺服器角色列表
+func (m *PermissionService) GetServerRoles(ctx context.Context, userID string, serverID string) ([]models.ServerRoleResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, serverID)
+	if args.Get(0) == nil {
+		return nil, messageOptionsAt(args, 1)
+	}
+	return args.Get(0).([]models.ServerRoleResponse), messageOptionsAt(args, 1)
+}
+
+// CreateRole 創建伺服器角色
+func (m *PermissionService) CreateRole(ctx context.Context, userID string, serverID string, request models.CreateServerRoleRequest) (*models.ServerRoleResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, serverID, request)
+	if args.Get(0) == nil {
+		return nil, messageOptionsAt(args, 1)
+	}
+	return args.Get(0).(*models.ServerRoleResponse), messageOptionsAt(args, 1)
+}
+
+// UpdateRole 更新伺服器角色
+func (m *PermissionService) UpdateRole(ctx context.Context, userID string, serverID string, roleID string, request models.UpdateServerRoleRequest) (*models.ServerRoleResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, serverID, roleID, request)
+	if args.Get(0) == nil {
+		return nil, messageOptionsAt(args, 1)
+	}
+	return args.Get(0).(*models.ServerRoleResponse), messageOptionsAt(args, 1)
+}
+
+// DeleteRole 刪除伺服器角色
+func (m *PermissionService) DeleteRole(ctx context.Context, userID string, serverID string, roleID string) *models.MessageOptions {
+	args := m.Called(ctx, userID, serverID, roleID)
+	return messageOptionsAt(args, 0)
+}
+
+// AssignRole 將角色指派給成員
+func (m *PermissionService) AssignRole(ctx context.Context, userID string, serverID string, roleID string, targetUserID string) *models.MessageOptions {
+	args := m.Called(ctx, userID, serverID, roleID, targetUserID)
+	return messageOptionsAt(args, 0)
+}
+
+// UnassignRole 移除成員的角色
+func (m *PermissionService) UnassignRole(ctx context.Context, userID string, serverID string, roleID string, targetUserID string) *models.MessageOptions {
+	args := m.Called(ctx, userID, serverID, roleID, targetUserID)
+	return messageOptionsAt(args, 0)
+}
+
+// GetChannelOverwrites 獲取頻道權限覆寫列表
+func (m *PermissionService) GetChannelOverwrites(ctx context.Context, userID string, channelID string) ([]models.ChannelOverwriteResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, channelID)
+	if args.Get(0) == nil {
+		return nil, messageOptionsAt(args, 1)
+	}
+	return args.Get(0).([]models.ChannelOverwriteResponse), messageOptionsAt(args, 1)
+}
+
+// SetChannelOverwrite 新增或取代頻道權限覆寫
+func (m *PermissionService) SetChannelOverwrite(ctx context.Context, userID string, channelID string, request models.ChannelOverwriteRequest) ([]models.ChannelOverwriteResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, channelID, request)
+	if args.Get(0) == nil {
+		return nil, messageOptionsAt(args, 1)
+	}
+	return args.Get(0).([]models.ChannelOverwriteResponse), messageOptionsAt(args, 1)
+}
+
+// DeleteChannelOverwrite 刪除頻道權限覆寫
+func (m *PermissionService) DeleteChannelOverwrite(ctx context.Context, userID string, channelID string, targetType string, targetID string) *models.MessageOptions {
+	args := m.Called(ctx, userID, channelID, targetType, targetID)
+	return messageOptionsAt(args, 0)
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"chat_app_backend/app/models"
+	"context"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -58,3 +59,24 @@ func (m *ServerMemberRepository) GetMemberCount(serverID string) (int64, error)
 	args := m.Called(serverID)
 	return args.Get(0).(int64), args.Error(1)
 }
+
+// GetServerMember 獲取用戶在伺服器中的成員資料
+func (m *ServerMemberRepository) GetServerMember(ctx context.Context, serverID, userID string) (*models.ServerMember, error) {
+	args := m.Called(ctx, serverID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServerMember), args.Error(1)
+}
+
+// AddRoleToMember 為成員指派角色
+func (m *ServerMemberRepository) AddRoleToMember(ctx context.Context, serverID, userID, roleID string) error {
+	args := m.Called(ctx, serverID, userID, roleID)
+	return args.Error(0)
+}
+
+// RemoveRoleFromMember 移除成員的角色
+func (m *ServerMemberRepository) RemoveRoleFromMember(ctx context.Context, serverID, userID, roleID string) error {
+	args := m.Called(ctx, serverID, userID, roleID)
+	return args.Error(0)
+}
@@ -150,3 +150,60 @@ func (m *ServerService) LeaveServer(userID string, serverID string) *models.Mess
 	}
 	return args.Get(0).(*models.MessageOptions)
 }
+
+// CreateInvite 創建伺服器邀請碼
+func (m *ServerService) CreateInvite(userID string, serverID string, request models.CreateServerInviteRequest) (*models.ServerInviteResponse, *models.MessageOptions) {
+	args := m.Called(userID, serverID, request)
+	var resp *models.ServerInviteResponse
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		resp = args.Get(0).(*models.ServerInviteResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return resp, msgOpts
+}
+
+// GetServerInvites 獲取伺服器仍有效的邀請列表
+func (m *ServerService) GetServerInvites(userID string, serverID string) ([]models.ServerInviteResponse, *models.MessageOptions) {
+	args := m.Called(userID, serverID)
+	var resp []models.ServerInviteResponse
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		resp = args.Get(0).([]models.ServerInviteResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return resp, msgOpts
+}
+
+// RevokeInvite 撤銷邀請碼
+func (m *ServerService) RevokeInvite(userID string, serverID string, inviteID string) *models.MessageOptions {
+	args := m.Called(userID, serverID, inviteID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+// AcceptInvite 透過邀請碼加入伺服器
+func (m *ServerService) AcceptInvite(userID string, code string) (*models.ServerResponse, *models.MessageOptions) {
+	args := m.Called(userID, code)
+	var resp *models.ServerResponse
+	var msgOpts *models.MessageOptions
+
+	if args.Get(0) != nil {
+		resp = args.Get(0).(*models.ServerResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpts = args.Get(1).(*models.MessageOptions)
+	}
+
+	return resp, msgOpts
+}
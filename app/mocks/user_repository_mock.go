@@ -69,3 +69,39 @@ func (m *UserRepository) DeleteUser(userID string) error {
 	args := m.Called(userID)
 	return args.Error(0)
 }
+
+func (m *UserRepository) GetUserCredentials(userID string) (*models.User, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserRepository) ConsumeTOTPCounter(userID string, counter int64) (bool, error) {
+	args := m.Called(userID, counter)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepository) ConsumeRecoveryCode(userID string, codeHash string) (bool, error) {
+	args := m.Called(userID, codeHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepository) MarkRefreshTokenRotated(tokenID string, replacedByID string) (bool, error) {
+	args := m.Called(tokenID, replacedByID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepository) GetUserByEmail(email string) (*models.User, error) {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *UserRepository) ConsumeUserToken(userID string, field string, tokenID string, updates map[string]any) (bool, error) {
+	args := m.Called(userID, field, tokenID, updates)
+	return args.Bool(0), args.Error(1)
+}
@@ -49,8 +49,8 @@ func (m *UserService) RegisterUser(user models.User) *models.MessageOptions {
 }
 
 // Login 處理用戶登入
-func (m *UserService) Login(loginUser models.User) (*models.LoginResponse, *models.MessageOptions) {
-	args := m.Called(loginUser)
+func (m *UserService) Login(loginUser models.User, device models.DeviceInfo) (*models.LoginResponse, *models.MessageOptions) {
+	args := m.Called(loginUser, device)
 	var loginResp *models.LoginResponse
 	var msgOpts *models.MessageOptions
 
@@ -74,8 +74,8 @@ func (m *UserService) Logout(c *gin.Context) *models.MessageOptions {
 }
 
 // RefreshToken 刷新令牌
-func (m *UserService) RefreshToken(refreshToken string) (*models.RefreshTokenResponse, *models.MessageOptions) {
-	args := m.Called(refreshToken)
+func (m *UserService) RefreshToken(refreshToken string, device models.DeviceInfo) (*models.RefreshTokenResponse, *models.MessageOptions) {
+	args := m.Called(refreshToken, device)
 	var resp *models.RefreshTokenResponse
 	var msgOpts *models.MessageOptions
 
@@ -170,10 +170,128 @@ func (m *UserService) GetTwoFactorStatus(userID string) (*models.TwoFactorStatus
 	return args.Get(0).(*models.TwoFactorStatusResponse), args.Error(1)
 }
 
-// UpdateTwoFactorStatus 啟用/停用兩步驟驗證
-func (m *UserService) UpdateTwoFactorStatus(userID string, enabled bool) error {
-	args := m.Called(userID, enabled)
-	return args.Error(0)
+// SetupTwoFactor 產生待確認的 TOTP 密鑰與 otpauth URI
+func (m *UserService) SetupTwoFactor(userID string) (*models.TwoFactorSetupResponse, *models.MessageOptions) {
+	args := m.Called(userID)
+	var result *models.TwoFactorSetupResponse
+	if args.Get(0) != nil {
+		result = args.Get(0).(*models.TwoFactorSetupResponse)
+	}
+	var msgOpt *models.MessageOptions
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
+
+// EnableTwoFactor 以驗證碼確認密鑰並啟用兩步驟驗證
+func (m *UserService) EnableTwoFactor(userID string, code string) (*models.TwoFactorRecoveryCodesResponse, *models.MessageOptions) {
+	args := m.Called(userID, code)
+	var result *models.TwoFactorRecoveryCodesResponse
+	if args.Get(0) != nil {
+		result = args.Get(0).(*models.TwoFactorRecoveryCodesResponse)
+	}
+	var msgOpt *models.MessageOptions
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
+
+// DisableTwoFactor 以驗證碼或復原碼停用兩步驟驗證
+func (m *UserService) DisableTwoFactor(userID string, code string) *models.MessageOptions {
+	args := m.Called(userID, code)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+// VerifyTwoFactorLogin 以挑戰 token 與驗證碼完成兩步驟登入
+func (m *UserService) VerifyTwoFactorLogin(challengeToken string, code string, device models.DeviceInfo) (*models.LoginResponse, *models.MessageOptions) {
+	args := m.Called(challengeToken, code, device)
+	var result *models.LoginResponse
+	if args.Get(0) != nil {
+		result = args.Get(0).(*models.LoginResponse)
+	}
+	var msgOpt *models.MessageOptions
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
+
+// GetSessions 獲取目前登入的裝置列表
+func (m *UserService) GetSessions(userID string, currentSessionID string) ([]models.SessionResponse, *models.MessageOptions) {
+	args := m.Called(userID, currentSessionID)
+	var result []models.SessionResponse
+	if args.Get(0) != nil {
+		result = args.Get(0).([]models.SessionResponse)
+	}
+	var msgOpt *models.MessageOptions
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
+
+// RevokeSession 登出指定裝置
+func (m *UserService) RevokeSession(userID string, sessionID string) *models.MessageOptions {
+	args := m.Called(userID, sessionID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+// RevokeOtherSessions 登出目前裝置以外的所有裝置
+func (m *UserService) RevokeOtherSessions(userID string, currentSessionID string) ([]string, *models.MessageOptions) {
+	args := m.Called(userID, currentSessionID)
+	var result []string
+	if args.Get(0) != nil {
+		result = args.Get(0).([]string)
+	}
+	var msgOpt *models.MessageOptions
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return result, msgOpt
+}
+
+// SendEmailVerification 重新寄送電子郵件驗證信
+func (m *UserService) SendEmailVerification(userID string) *models.MessageOptions {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+// VerifyEmail 完成電子郵件驗證
+func (m *UserService) VerifyEmail(token string) *models.MessageOptions {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+// RequestPasswordReset 寄送重設密碼信
+func (m *UserService) RequestPasswordReset(email string) *models.MessageOptions {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+// ResetPassword 以重設密碼 token 設定新密碼
+func (m *UserService) ResetPassword(token string, newPassword string) *models.MessageOptions {
+	args := m.Called(token, newPassword)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
 }
 
 // DeactivateAccount 停用帳號
@@ -39,6 +39,13 @@ const (
 	ErrInvalidOrigin ErrorCode = "INVALID_ORIGIN" // 無效的 Origin
 )
 
+// 兩步驟驗證相關錯誤碼
+const (
+	ErrInvalidTwoFactorCode    ErrorCode = "INVALID_TWO_FACTOR_CODE"    // 兩步驟驗證碼或復原碼錯誤
+	ErrTwoFactorNotEnabled     ErrorCode = "TWO_FACTOR_NOT_ENABLED"     // 尚未啟用兩步驟驗證
+	ErrTwoFactorAlreadyEnabled ErrorCode = "TWO_FACTOR_ALREADY_ENABLED" // 已啟用兩步驟驗證
+)
+
 // 使用者相關錯誤碼
 const (
 	ErrUserNotFound   ErrorCode = "USER_NOT_FOUND"  // 使用者不存在
@@ -46,6 +53,11 @@ const (
 	ErrEmailExists    ErrorCode = "EMAIL_EXISTS"    // 信箱已存在
 )
 
+// 電子郵件驗證相關錯誤碼
+const (
+	ErrEmailAlreadyVerified ErrorCode = "EMAIL_ALREADY_VERIFIED" // 電子郵件已驗證
+)
+
 // 好友相關錯誤碼
 const (
 	ErrFriendExists          ErrorCode = "FRIEND_EXISTS"            // 已是好友
@@ -61,19 +73,58 @@ const (
 	ErrCreateServerFailed ErrorCode = "CREATE_SERVER_FAILED" // 創建伺服器失敗
 )
 
+// 角色相關錯誤碼
+const (
+	ErrRoleNotFound    ErrorCode = "ROLE_NOT_FOUND"    // 角色不存在
+	ErrTooManyRoles    ErrorCode = "TOO_MANY_ROLES"    // 伺服器角色數量已達上限
+	ErrNotServerMember ErrorCode = "NOT_SERVER_MEMBER" // 用戶不是伺服器成員
+)
+
+// 邀請相關錯誤碼
+const (
+	ErrInviteNotFound ErrorCode = "INVITE_NOT_FOUND" // 邀請不存在
+	ErrInviteInvalid  ErrorCode = "INVITE_INVALID"   // 邀請已撤銷、過期或用盡
+)
+
+// 成員管理相關錯誤碼
+const (
+	ErrUserBanned  ErrorCode = "USER_BANNED"   // 用戶已被伺服器封鎖
+	ErrBanNotFound ErrorCode = "BAN_NOT_FOUND" // 封鎖紀錄不存在
+)
+
+// 頻道類別相關錯誤碼
+const (
+	ErrCategoryNotFound ErrorCode = "CATEGORY_NOT_FOUND" // 頻道類別不存在
+)
+
 // 頻道相關錯誤碼
 const (
 	ErrChannelNotFound     ErrorCode = "CHANNEL_NOT_FOUND"     // 頻道不存在
 	ErrCreateChannelFailed ErrorCode = "CREATE_CHANNEL_FAILED" // 創建頻道失敗
+	ErrOverwriteNotFound   ErrorCode = "OVERWRITE_NOT_FOUND"   // 頻道權限覆寫不存在
 )
 
 // 訊息相關錯誤碼
 const (
 	ErrSendMessageFailed ErrorCode = "SEND_MESSAGE_FAILED" // 訊息發送失敗
 	ErrGetMessagesFailed ErrorCode = "GET_MESSAGES_FAILED" // 獲取訊息失敗
+	ErrMessageNotFound   ErrorCode = "MESSAGE_NOT_FOUND"   // 訊息不存在
+	ErrMessageDeleted    ErrorCode = "MESSAGE_DELETED"     // 訊息已被刪除
+	ErrTooManyReactions  ErrorCode = "TOO_MANY_REACTIONS"  // 訊息表情回應種類已達上限
+	ErrInvalidAttachment ErrorCode = "INVALID_ATTACHMENT"  // 附件不存在、不屬於發送者或尚未驗證
 )
 
 // 聊天室相關錯誤碼
 const (
 	ErrRoomNotFound ErrorCode = "ROOM_NOT_FOUND" // 聊天室不存在
 )
+
+// 檔案相關錯誤碼
+const (
+	ErrFileProcessing   ErrorCode = "FILE_PROCESSING"    // 檔案仍在處理中（例如產生縮圖）
+	ErrUploadIncomplete ErrorCode = "UPLOAD_INCOMPLETE"  // 分段上傳尚缺分段
+	ErrHashMismatch     ErrorCode = "FILE_HASH_MISMATCH" // 檔案雜湊與宣告值不符
+	ErrQuotaExceeded    ErrorCode = "QUOTA_EXCEEDED"     // 儲存空間配額不足
+
+	ErrPresignUnsupported ErrorCode = "PRESIGN_UNSUPPORTED" // 儲存服務不支援預簽名網址（例如本地儲存）
+)
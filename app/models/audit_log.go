@@ -0,0 +1,80 @@
+package models
+
+import (
+	"chat_app_backend/app/providers"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 稽核紀錄動作類型
+const (
+	AuditActionServerUpdate           = "server_update"
+	AuditActionChannelCreate          = "channel_create"
+	AuditActionChannelUpdate          = "channel_update"
+	AuditActionChannelDelete          = "channel_delete"
+	AuditActionChannelOverwriteUpdate = "channel_overwrite_update"
+	AuditActionChannelOverwriteDelete = "channel_overwrite_delete"
+	AuditActionCategoryCreate         = "category_create"
+	AuditActionCategoryUpdate         = "category_update"
+	AuditActionCategoryDelete         = "category_delete"
+	AuditActionRoleCreate             = "role_create"
+	AuditActionRoleUpdate             = "role_update"
+	AuditActionRoleDelete             = "role_delete"
+	AuditActionMemberRoleUpdate       = "member_role_update"
+	AuditActionMemberJoin             = "member_join"
+	AuditActionMemberLeave            = "member_leave"
+	AuditActionMemberKick             = "member_kick"
+	AuditActionMemberBan              = "member_ban"
+	AuditActionMemberUnban            = "member_unban"
+	AuditActionMemberTimeout          = "member_timeout"
+	AuditActionMemberRemoveTimeout    = "member_remove_timeout"
+)
+
+// 稽核紀錄目標類型
+const (
+	AuditTargetServer   = "server"
+	AuditTargetChannel  = "channel"
+	AuditTargetCategory = "category"
+	AuditTargetRole     = "role"
+	AuditTargetMember   = "member"
+)
+
+// auditActions 所有可查詢的動作類型
+var auditActions = []string{
+	AuditActionServerUpdate,
+	AuditActionChannelCreate, AuditActionChannelUpdate, AuditActionChannelDelete,
+	AuditActionChannelOverwriteUpdate, AuditActionChannelOverwriteDelete,
+	AuditActionCategoryCreate, AuditActionCategoryUpdate, AuditActionCategoryDelete,
+	AuditActionRoleCreate, AuditActionRoleUpdate, AuditActionRoleDelete, AuditActionMemberRoleUpdate,
+	AuditActionMemberJoin, AuditActionMemberLeave, AuditActionMemberKick,
+	AuditActionMemberBan, AuditActionMemberUnban, AuditActionMemberTimeout, AuditActionMemberRemoveTimeout,
+}
+
+// IsValidAuditAction 檢查是否為已知的稽核動作類型
+func IsValidAuditAction(action string) bool {
+	return slices.Contains(auditActions, action)
+}
+
+// AuditLogChange 單一欄位的變更，前後值以 JSON 字串保存，空字串表示不存在
+type AuditLogChange struct {
+	Key    string `json:"key" bson:"key"`
+	Before string `json:"before,omitempty" bson:"before,omitempty"`
+	After  string `json:"after,omitempty" bson:"after,omitempty"`
+}
+
+// AuditLog 伺服器稽核紀錄（獨立集合），記錄誰在何時對什麼做了哪些變更
+type AuditLog struct {
+	providers.BaseModel `bson:",inline"`
+	ServerID            primitive.ObjectID `json:"server_id" bson:"server_id"`
+	ActorID             primitive.ObjectID `json:"actor_id" bson:"actor_id"`
+	Action              string             `json:"action" bson:"action"`
+	TargetType          string             `json:"target_type" bson:"target_type"`
+	TargetID            primitive.ObjectID `json:"target_id" bson:"target_id"`
+	Changes             []AuditLogChange   `json:"changes,omitempty" bson:"changes,omitempty"`
+	Reason              string             `json:"reason,omitempty" bson:"reason,omitempty"`
+}
+
+func (al *AuditLog) GetCollectionName() string {
+	return "audit_logs"
+}
@@ -21,6 +21,17 @@ type User struct {
 	LastActiveAt        int64                `json:"last_active_at" bson:"last_active_at"`         // 最後活動時間戳
 	TwoFactorEnabled    bool                 `json:"two_factor_enabled" bson:"two_factor_enabled"` // 兩步驟驗證是否啟用
 	IsActive            bool                 `json:"is_active" bson:"is_active"`                   // 帳號是否啟用
+	EmailVerified       bool                 `json:"email_verified" bson:"email_verified"`         // 電子郵件是否已驗證
+
+	// 兩步驟驗證（TOTP）相關欄位，不對外輸出
+	TwoFactorSecret        string   `json:"-" bson:"two_factor_secret,omitempty"`         // 已啟用的 TOTP 密鑰（AES-GCM 加密）
+	TwoFactorPendingSecret string   `json:"-" bson:"two_factor_pending_secret,omitempty"` // 設定中尚未確認的 TOTP 密鑰（AES-GCM 加密）
+	TwoFactorLastCounter   int64    `json:"-" bson:"two_factor_last_counter,omitempty"`   // 最後使用的 TOTP 時間步，防止驗證碼重放
+	RecoveryCodes          []string `json:"-" bson:"recovery_codes,omitempty"`            // 未使用的復原碼雜湊
+
+	// 電子郵件驗證與重設密碼，記錄最後寄出的 token ID（jti），使用後清除，確保連結只能使用一次
+	EmailVerificationTokenID string `json:"-" bson:"email_verification_token_id,omitempty"`
+	PasswordResetTokenID     string `json:"-" bson:"password_reset_token_id,omitempty"`
 }
 
 // 好友
@@ -37,7 +48,21 @@ type RefreshToken struct {
 	Token               string             `json:"token" bson:"token"`
 	ExpiresAt           int64              `json:"expires_at" bson:"expires_at"`
 	Revoked             bool               `json:"revoked" bson:"revoked"`
-	// 可以加入裝置資訊或限制使用者token數量判斷多餘token是否要刪除
+	UserAgent           string             `json:"user_agent" bson:"user_agent"`     // 登入裝置的 User-Agent
+	IP                  string             `json:"ip" bson:"ip"`                     // 最後使用的 IP 位址
+	LastUsedAt          int64              `json:"last_used_at" bson:"last_used_at"` // 最後使用時間戳（登入或刷新 access token）
+
+	// 每次刷新都會輪替為同一 family 的新 token，family 即為登入工作階段
+	FamilyID         primitive.ObjectID `json:"family_id" bson:"family_id,omitempty"`
+	SessionStartedAt int64              `json:"session_started_at" bson:"session_started_at,omitempty"` // 登入工作階段的建立時間戳
+	ReplacedByID     primitive.ObjectID `json:"replaced_by_id" bson:"replaced_by_id,omitempty"`         // 輪替後的新 token 記錄 ID
+	RotatedAt        int64              `json:"rotated_at" bson:"rotated_at,omitempty"`                 // 輪替時間戳
+}
+
+// DeviceInfo 登入或刷新 token 時的裝置資訊
+type DeviceInfo struct {
+	UserAgent string
+	IP        string
 }
 
 // 添加到 models/auth.go 文件中
@@ -52,3 +77,19 @@ func (f *Friend) GetCollectionName() string {
 func (rt *RefreshToken) GetCollectionName() string {
 	return "refresh_tokens"
 }
+
+// SessionID 返回所屬的登入工作階段 ID（輪替前建立的舊記錄沒有 family，以自身 ID 代替）
+func (rt *RefreshToken) SessionID() primitive.ObjectID {
+	if rt.FamilyID.IsZero() {
+		return rt.ID
+	}
+	return rt.FamilyID
+}
+
+// SessionCreatedAt 返回登入工作階段的建立時間戳
+func (rt *RefreshToken) SessionCreatedAt() int64 {
+	if rt.SessionStartedAt == 0 {
+		return rt.CreatedAt.Unix()
+	}
+	return rt.SessionStartedAt
+}
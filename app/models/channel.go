@@ -8,21 +8,39 @@ import (
 )
 
 // Channel 頻道模型
-// 注意：頻道權限繼承自伺服器權限，不單獨管理成員
-// 只要是伺服器成員就可以訪問該伺服器的所有頻道
+// 注意：頻道權限預設繼承自伺服器權限，不單獨管理成員
+// 可透過 PermissionOverwrites 針對所有成員、角色或個別用戶允許或拒絕特定權限（例如私人頻道、唯讀公告頻道）
 type Channel struct {
-	providers.BaseModel `bson:",inline"`
-	Name                string             `json:"name" bson:"name"`
-	ServerID            primitive.ObjectID `json:"server_id" bson:"server_id"` // 所屬伺服器
-	CategoryID          primitive.ObjectID `json:"category_id" bson:"category_id"`
-	Type                string             `json:"type" bson:"type"`                       // "text" or "voice"
-	LastMessageAt       *time.Time         `json:"last_message_at" bson:"last_message_at"` // 最後訊息時間
+	providers.BaseModel  `bson:",inline"`
+	Name                 string                `json:"name" bson:"name"`
+	ServerID             primitive.ObjectID    `json:"server_id" bson:"server_id"`                                             // 所屬伺服器
+	CategoryID           primitive.ObjectID    `json:"category_id" bson:"category_id"`                                         // 所屬類別，零值表示未分類
+	Position             int                   `json:"position" bson:"position"`                                               // 類別內的排序位置
+	Type                 string                `json:"type" bson:"type"`                                                       // "text" or "voice"
+	LastMessageAt        *time.Time            `json:"last_message_at" bson:"last_message_at"`                                 // 最後訊息時間
+	PermissionOverwrites []PermissionOverwrite `json:"permission_overwrites,omitempty" bson:"permission_overwrites,omitempty"` // 頻道權限覆寫
 }
 
 func (c *Channel) GetCollectionName() string {
 	return "channels"
 }
 
+// 頻道權限覆寫的對象類型
+const (
+	OverwriteTargetEveryone = "everyone" // 伺服器所有成員
+	OverwriteTargetRole     = "role"     // 指定角色
+	OverwriteTargetUser     = "user"     // 指定用戶
+)
+
+// PermissionOverwrite 頻道權限覆寫
+// 套用順序：所有成員 → 角色（合併）→ 個別用戶，後者優先；拒絕先於允許套用
+type PermissionOverwrite struct {
+	TargetType string             `json:"target_type" bson:"target_type"`
+	TargetID   primitive.ObjectID `json:"target_id" bson:"target_id"` // 對象為所有成員時為零值
+	Allow      Permission         `json:"allow" bson:"allow"`
+	Deny       Permission         `json:"deny" bson:"deny"`
+}
+
 type ChannelCategory struct {
 	providers.BaseModel `bson:",inline"`
 	Name                string             `json:"name" bson:"name"`
@@ -34,3 +52,23 @@ type ChannelCategory struct {
 func (cc *ChannelCategory) GetCollectionName() string {
 	return "channel_categories"
 }
+
+// 頻道類別類型
+const (
+	CategoryTypeText   = "text"
+	CategoryTypeVoice  = "voice"
+	CategoryTypeCustom = "custom"
+)
+
+// ChannelPosition 頻道的類別與排序位置（批量排序用），CategoryID 為零值表示未分類
+type ChannelPosition struct {
+	ChannelID  primitive.ObjectID
+	CategoryID primitive.ObjectID
+	Position   int
+}
+
+// CategoryPosition 頻道類別的排序位置（批量排序用）
+type CategoryPosition struct {
+	CategoryID primitive.ObjectID
+	Position   int
+}
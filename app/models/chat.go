@@ -50,6 +50,31 @@ type Message struct {
 	Content             string             `json:"content" bson:"content"`
 	SenderID            primitive.ObjectID `json:"sender_id" bson:"sender_id"`
 	RoomID              primitive.ObjectID `json:"room_id" bson:"room_id"`
+	EditedAt            *time.Time         `json:"edited_at,omitempty" bson:"edited_at,omitempty"`   // 最後編輯時間
+	IsDeleted           bool               `json:"is_deleted" bson:"is_deleted"`                     // 是否已刪除（軟刪除，保留墓碑）
+	DeletedAt           *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"` // 刪除時間
+	DeletedBy           primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"` // 刪除者（發送者或伺服器管理員）
+	// 附件（發送時保存的檔案中繼資料，刪除訊息時清空）
+	Attachments []MessageAttachment `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	// 引用與討論串
+	ReplyToMessageID   primitive.ObjectID   `json:"reply_to_message_id,omitempty" bson:"reply_to_message_id,omitempty"` // 引用的訊息
+	ThreadID           primitive.ObjectID   `json:"thread_id,omitempty" bson:"thread_id,omitempty"`                     // 所屬討論串（父訊息ID），頂層訊息為空
+	ReplyCount         int64                `json:"reply_count,omitempty" bson:"reply_count,omitempty"`                 // 討論串回覆數（僅父訊息）
+	LastReplyAt        *time.Time           `json:"last_reply_at,omitempty" bson:"last_reply_at,omitempty"`             // 最後回覆時間（僅父訊息）
+	ThreadParticipants []primitive.ObjectID `json:"-" bson:"thread_participants,omitempty"`                             // 討論串回覆者（僅父訊息）
+	// 表情回應：emoji -> 回應的用戶，數量由用戶集合彙總
+	Reactions map[string][]primitive.ObjectID `json:"-" bson:"reactions,omitempty"`
+}
+
+// MessageAttachment 訊息附件，保存發送時的檔案中繼資料，下載連結於讀取時產生
+type MessageAttachment struct {
+	FileID   primitive.ObjectID `json:"file_id" bson:"file_id"`     // 對應 UploadedFile
+	FileName string             `json:"file_name" bson:"file_name"` // 原始檔名
+	FileSize int64              `json:"file_size" bson:"file_size"`
+	MimeType string             `json:"mime_type" bson:"mime_type"`
+	URL      string             `json:"url" bson:"-"`                           // 讀取時依 file_id 產生，不寫入資料庫（MinIO 預簽名網址為短效）
+	Width    int                `json:"width,omitempty" bson:"width,omitempty"` // 圖片尺寸（非圖片為零值）
+	Height   int                `json:"height,omitempty" bson:"height,omitempty"`
 }
 
 // GetCollectionName 返回Message的集合名稱
@@ -76,6 +101,8 @@ type RoomReads struct {
 	providers.BaseModel `bson:",inline"`
 	UserID              primitive.ObjectID `json:"user_id" bson:"user_id"`
 	RoomID              primitive.ObjectID `json:"room_id" bson:"room_id"`
+	RoomType            RoomType           `json:"room_type" bson:"room_type"`
+	LastReadMessageID   primitive.ObjectID `json:"last_read_message_id,omitempty" bson:"last_read_message_id,omitempty"` // 最後已讀訊息
 	LastReadAt          time.Time          `json:"last_read_at" bson:"last_read_at"`
 }
 
@@ -83,3 +110,9 @@ type RoomReads struct {
 func (rr *RoomReads) GetCollectionName() string {
 	return "room_reads"
 }
+
+// RoomReadState 房間的已讀狀態（未讀數量與最後已讀訊息）
+type RoomReadState struct {
+	LastReadMessageID string
+	UnreadCount       int64
+}
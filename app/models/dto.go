@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -18,12 +20,34 @@ type LoginResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token,omitempty"`
 	CSRFToken    string `json:"csrf_token"`
+
+	// 已啟用兩步驟驗證時不發放 token，改返回挑戰 token，需以驗證碼呼叫 /login/2fa 完成登入
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+// SessionResponse 登入工作階段（裝置）資訊
+type SessionResponse struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	ExpiresAt  int64  `json:"expires_at"`
+	Current    bool   `json:"current"` // 是否為目前發出請求的工作階段
+}
+
+// TwoFactorLoginRequest 兩步驟登入請求（驗證碼可為 TOTP 驗證碼或復原碼）
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
 }
 
 // RefreshTokenResponse 包含刷新令牌後返回的資訊
 type RefreshTokenResponse struct {
-	AccessToken string `json:"access_token"`
-	CSRFToken   string `json:"csrf_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"` // 輪替後的 refresh token
+	CSRFToken    string `json:"csrf_token"`
 }
 
 type ServerResponse struct {
@@ -31,18 +55,195 @@ type ServerResponse struct {
 	Name        string             `json:"name" bson:"name"`
 	PictureURL  string             `json:"picture_url" bson:"picture_url"`
 	Description string             `json:"description" bson:"description"`
+	// 創建時上傳的圖片，狀態為 "processing" 時 picture_url 為空，處理完成後重新取得伺服器資料
+	PictureID     string `json:"picture_id,omitempty" bson:"-"`
+	PictureStatus string `json:"picture_status,omitempty" bson:"-"`
 }
 
 // ServerMemberResponse 伺服器成員響應模型
 type ServerMemberResponse struct {
-	UserID       string `json:"user_id" bson:"user_id"`
-	Username     string `json:"username" bson:"username"`
-	Nickname     string `json:"nickname" bson:"nickname"` // 伺服器內暱稱
-	PictureURL   string `json:"picture_url"`
-	Role         string `json:"role" bson:"role"`                     // "owner", "admin", "member"
-	IsOnline     bool   `json:"is_online" bson:"is_online"`           // 在線狀態
-	LastActiveAt int64  `json:"last_active_at" bson:"last_active_at"` // 最後活動時間
-	JoinedAt     int64  `json:"joined_at" bson:"joined_at"`           // 加入時間
+	UserID       string   `json:"user_id" bson:"user_id"`
+	Username     string   `json:"username" bson:"username"`
+	Nickname     string   `json:"nickname" bson:"nickname"` // 伺服器內暱稱
+	PictureURL   string   `json:"picture_url"`
+	Role         string   `json:"role" bson:"role"`                     // "owner", "admin", "member"
+	IsOnline     bool     `json:"is_online" bson:"is_online"`           // 在線狀態
+	LastActiveAt int64    `json:"last_active_at" bson:"last_active_at"` // 最後活動時間
+	JoinedAt     int64    `json:"joined_at" bson:"joined_at"`           // 加入時間
+	RoleIDs      []string `json:"role_ids,omitempty"`                   // 指派的伺服器角色
+}
+
+// ServerRoleResponse 伺服器角色響應
+type ServerRoleResponse struct {
+	ID          string   `json:"id"`
+	ServerID    string   `json:"server_id"`
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"` // 權限名稱列表
+	Position    int      `json:"position"`    // 角色位階，數字越大位階越高
+	CreatedAt   int64    `json:"created_at"`
+}
+
+// CreateServerRoleRequest 創建伺服器角色請求
+type CreateServerRoleRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Permissions []string `json:"permissions"`
+	Position    *int     `json:"position" binding:"omitempty,min=0"` // 未提供時為最低位階 0
+}
+
+// UpdateServerRoleRequest 更新伺服器角色請求，未提供的欄位保持不變
+type UpdateServerRoleRequest struct {
+	Name        *string   `json:"name"`
+	Permissions *[]string `json:"permissions"`
+	Position    *int      `json:"position" binding:"omitempty,min=0"`
+}
+
+// MemberPermissionsResponse 成員在伺服器中的有效權限
+type MemberPermissionsResponse struct {
+	ServerID    string   `json:"server_id"`
+	UserID      string   `json:"user_id"`
+	IsOwner     bool     `json:"is_owner"`
+	Permissions []string `json:"permissions"`
+}
+
+// ChannelOverwriteRequest 設定頻道權限覆寫請求，同一對象已有覆寫時整筆取代
+type ChannelOverwriteRequest struct {
+	TargetType string   `json:"target_type" binding:"required"` // "everyone", "role", "user"
+	TargetID   string   `json:"target_id"`                      // 對象為所有成員時可省略
+	Allow      []string `json:"allow"`
+	Deny       []string `json:"deny"`
+}
+
+// ChannelOverwriteResponse 頻道權限覆寫響應
+type ChannelOverwriteResponse struct {
+	TargetType string   `json:"target_type"`
+	TargetID   string   `json:"target_id,omitempty"`
+	Allow      []string `json:"allow"`
+	Deny       []string `json:"deny"`
+}
+
+// CreateChannelCategoryRequest 創建頻道類別請求，新類別排在最後
+type CreateChannelCategoryRequest struct {
+	Name         string `json:"name" binding:"required"`
+	CategoryType string `json:"category_type"` // "text", "voice", "custom"，預設為 "custom"
+}
+
+// UpdateChannelCategoryRequest 重新命名頻道類別請求
+type UpdateChannelCategoryRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ReorderChannelsRequest 批量調整類別與頻道排序，所有項目驗證通過後才一併套用
+type ReorderChannelsRequest struct {
+	Categories []CategoryPositionRequest `json:"categories" binding:"dive"`
+	Channels   []ChannelPositionRequest  `json:"channels" binding:"dive"`
+}
+
+// CategoryPositionRequest 單一類別的新排序位置
+type CategoryPositionRequest struct {
+	ID       string `json:"id" binding:"required"`
+	Position int    `json:"position" binding:"min=0"`
+}
+
+// ChannelPositionRequest 單一頻道的新類別與排序位置
+type ChannelPositionRequest struct {
+	ID         string `json:"id" binding:"required"`
+	CategoryID string `json:"category_id"` // 為空表示移出類別（未分類）
+	Position   int    `json:"position" binding:"min=0"`
+}
+
+// ChannelCategoryResponse 頻道類別響應，包含依排序位置排列的頻道
+type ChannelCategoryResponse struct {
+	ID           string            `json:"id"`
+	ServerID     string            `json:"server_id"`
+	Name         string            `json:"name"`
+	CategoryType string            `json:"category_type"`
+	Position     int               `json:"position"`
+	Channels     []ChannelResponse `json:"channels"`
+}
+
+// ChannelTreeResponse 伺服器頻道樹：未分類頻道在前，其後為依排序位置排列的類別
+type ChannelTreeResponse struct {
+	Uncategorized []ChannelResponse         `json:"uncategorized"`
+	Categories    []ChannelCategoryResponse `json:"categories"`
+}
+
+// CreateServerInviteRequest 創建伺服器邀請請求
+type CreateServerInviteRequest struct {
+	MaxUses   int   `json:"max_uses" binding:"min=0"`   // 最大使用次數，0 表示不限
+	ExpiresIn int64 `json:"expires_in" binding:"min=0"` // 有效秒數，0 表示永不過期
+}
+
+// ServerInviteResponse 伺服器邀請響應
+type ServerInviteResponse struct {
+	ID        string `json:"id"`
+	Code      string `json:"code"`
+	ServerID  string `json:"server_id"`
+	CreatedBy string `json:"created_by"`
+	MaxUses   int    `json:"max_uses"`
+	Uses      int    `json:"uses"`
+	ExpiresAt int64  `json:"expires_at"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// KickMemberRequest 踢出成員請求
+type KickMemberRequest struct {
+	Reason string `json:"reason"`
+}
+
+// BanMemberRequest 封鎖成員請求
+type BanMemberRequest struct {
+	Reason   string `json:"reason"`
+	Duration int64  `json:"duration" binding:"min=0"` // 封鎖秒數，0 表示永久
+}
+
+// TimeoutMemberRequest 禁言成員請求
+type TimeoutMemberRequest struct {
+	Reason   string `json:"reason"`
+	Duration int64  `json:"duration" binding:"required,min=1"` // 禁言秒數
+}
+
+// ServerBanResponse 伺服器封鎖響應
+type ServerBanResponse struct {
+	UserID    string `json:"user_id"`
+	Username  string `json:"username"`
+	BannedBy  string `json:"banned_by"`
+	Reason    string `json:"reason,omitempty"`
+	ExpiresAt int64  `json:"expires_at"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+// AuditLogQuery 稽核紀錄查詢參數
+type AuditLogQuery struct {
+	Action  string `json:"action" form:"action"`     // 限定動作類型
+	ActorID string `json:"actor_id" form:"actor_id"` // 限定操作者
+	Before  string `json:"before" form:"before"`     // 游標：只回傳比此紀錄ID更舊的結果
+	Limit   int64  `json:"limit" form:"limit"`       // 每頁數量
+}
+
+// AuditLogChangeResponse 稽核紀錄欄位變更響應，前後值為原始 JSON
+type AuditLogChangeResponse struct {
+	Key    string          `json:"key"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditLogResponse 稽核紀錄響應
+type AuditLogResponse struct {
+	ID            string                   `json:"id"`
+	Action        string                   `json:"action"`
+	ActorID       string                   `json:"actor_id"`
+	ActorUsername string                   `json:"actor_username"`
+	TargetType    string                   `json:"target_type"`
+	TargetID      string                   `json:"target_id"`
+	Changes       []AuditLogChangeResponse `json:"changes"`
+	Reason        string                   `json:"reason,omitempty"`
+	CreatedAt     int64                    `json:"created_at"`
+}
+
+// AuditLogResults 稽核紀錄查詢結果
+type AuditLogResults struct {
+	Entries    []AuditLogResponse `json:"entries"`
+	NextCursor string             `json:"next_cursor,omitempty"` // 下一頁游標，為空表示沒有更多結果
 }
 
 // ServerDetailResponse 伺服器詳細信息響應（包含成員列表）
@@ -63,8 +264,13 @@ type ChannelResponse struct {
 	ServerID    primitive.ObjectID `json:"server_id" bson:"server_id"`
 	Name        string             `json:"name" bson:"name"`
 	Type        string             `json:"type" bson:"type"`
+	CategoryID  string             `json:"category_id,omitempty" bson:"category_id,omitempty"` // 為空表示未分類
+	Position    int                `json:"position" bson:"position"`
 	PictureURL  string             `json:"picture_url" bson:"picture_url"`
 	Description string             `json:"description" bson:"description"`
+	// 已讀狀態
+	UnreadCount       int64  `json:"unread_count" bson:"unread_count"`
+	LastReadMessageID string `json:"last_read_message_id,omitempty" bson:"last_read_message_id,omitempty"`
 }
 
 type DMRoomResponse struct {
@@ -73,6 +279,9 @@ type DMRoomResponse struct {
 	PictureURL string             `json:"picture_url"`
 	Timestamp  int64              `json:"timestamp" bson:"timestamp"`
 	IsOnline   bool               `json:"is_online" bson:"is_online"` // 聊天對象的在線狀態
+	// 已讀狀態
+	UnreadCount       int64  `json:"unread_count" bson:"unread_count"`
+	LastReadMessageID string `json:"last_read_message_id,omitempty" bson:"last_read_message_id,omitempty"`
 }
 
 type MessageResponse struct {
@@ -82,6 +291,32 @@ type MessageResponse struct {
 	SenderID  string             `json:"sender_id" bson:"sender_id"`
 	Content   string             `json:"content" bson:"content"`
 	Timestamp int64              `json:"timestamp" bson:"timestamp"`
+	EditedAt  int64              `json:"edited_at,omitempty" bson:"edited_at,omitempty"` // 最後編輯時間（毫秒）
+	IsDeleted bool               `json:"is_deleted" bson:"is_deleted"`                   // 是否已刪除
+	// 附件
+	Attachments []MessageAttachment `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	// 引用與討論串
+	ReplyToMessageID string `json:"reply_to_message_id,omitempty" bson:"reply_to_message_id,omitempty"`
+	ThreadID         string `json:"thread_id,omitempty" bson:"thread_id,omitempty"`
+	ReplyCount       int64  `json:"reply_count,omitempty" bson:"reply_count,omitempty"`
+	LastReplyAt      int64  `json:"last_reply_at,omitempty" bson:"last_reply_at,omitempty"` // 最後回覆時間（毫秒）
+	// 表情回應
+	Reactions []ReactionSummary `json:"reactions,omitempty" bson:"reactions,omitempty"`
+}
+
+// ReactionSummary 訊息表情回應彙總
+type ReactionSummary struct {
+	Emoji   string `json:"emoji"`
+	Count   int64  `json:"count"`
+	Reacted bool   `json:"reacted"` // 目前用戶是否已回應
+}
+
+// ReadStateResponse 房間已讀狀態
+type ReadStateResponse struct {
+	RoomType          RoomType `json:"room_type"`
+	RoomID            string   `json:"room_id"`
+	LastReadMessageID string   `json:"last_read_message_id,omitempty"`
+	LastReadAt        int64    `json:"last_read_at"`
 }
 
 type FriendRequest struct {
@@ -159,6 +394,25 @@ type ServerSearchResults struct {
 	TotalPages int                    `json:"total_pages"` // 總頁數
 }
 
+// MessageSearchRequest 訊息搜尋請求
+type MessageSearchRequest struct {
+	Query    string   `json:"q" form:"q"`                 // 搜尋關鍵字
+	RoomType RoomType `json:"room_type" form:"room_type"` // 限定房間類型：dm, channel（搭配 room_id）
+	RoomID   string   `json:"room_id" form:"room_id"`     // 限定單一房間
+	ServerID string   `json:"server_id" form:"server_id"` // 限定伺服器內所有頻道
+	SenderID string   `json:"sender_id" form:"sender_id"` // 限定發送者
+	From     int64    `json:"from" form:"from"`           // 起始時間（毫秒時間戳）
+	To       int64    `json:"to" form:"to"`               // 結束時間（毫秒時間戳）
+	Before   string   `json:"before" form:"before"`       // 游標：只回傳比此訊息ID更舊的結果
+	Limit    int64    `json:"limit" form:"limit"`         // 每頁數量
+}
+
+// MessageSearchResults 訊息搜尋結果
+type MessageSearchResults struct {
+	Messages   []MessageResponse `json:"messages"`
+	NextCursor string            `json:"next_cursor,omitempty"` // 下一頁游標，為空表示沒有更多結果
+}
+
 // UserProfileResponse 用戶個人資料響應
 type UserProfileResponse struct {
 	ID         string `json:"id" bson:"_id"`
@@ -169,15 +423,73 @@ type UserProfileResponse struct {
 	BannerURL  string `json:"banner_url"`  // 橫幅 URL（從 BannerID 解析）
 	Status     string `json:"status" bson:"status"`
 	Bio        string `json:"bio" bson:"bio"`
+
+	EmailVerified bool `json:"email_verified"` // 電子郵件是否已驗證
 }
 
 // UserImageResponse 用戶圖片上傳響應
 type UserImageResponse struct {
-	ImageURL string `json:"image_url"`
-	Type     string `json:"type"` // "avatar" 或 "banner"
+	ImageURL string `json:"image_url"` // 狀態為 "processing" 時為空，處理完成後重新取得個人資料
+	Type     string `json:"type"`      // "avatar" 或 "banner"
+	FileID   string `json:"file_id"`
+	Status   string `json:"status"` // 圖片掃描與產生縮圖期間為 "processing"
 }
 
 // TwoFactorStatusResponse 兩步驟驗證狀態響應
 type TwoFactorStatusResponse struct {
-	Enabled bool `json:"enabled"`
+	Enabled                bool `json:"enabled"`
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"` // 剩餘可用的復原碼數量
+}
+
+// TwoFactorSetupResponse 兩步驟驗證設定響應，客戶端以 otpauth URI 產生 QR Code
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorCodeRequest 兩步驟驗證碼請求（啟用時僅接受 TOTP 驗證碼，停用時亦可使用復原碼）
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorRecoveryCodesResponse 啟用兩步驟驗證後返回的復原碼，僅顯示這一次
+type TwoFactorRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// VerifyEmailRequest 電子郵件驗證請求，token 來自驗證信中的連結
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ForgotPasswordRequest 申請重設密碼請求
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest 重設密碼請求，token 來自重設密碼信中的連結
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+// InitiateChunkedUploadRequest 開始分段上傳請求
+type InitiateChunkedUploadRequest struct {
+	FileName  string `json:"file_name" binding:"required"`
+	FileSize  int64  `json:"file_size" binding:"required,min=1"`
+	MimeType  string `json:"mime_type" binding:"required"`
+	SHA256    string `json:"sha256" binding:"required"`  // 完整檔案的 SHA256（十六進位）
+	FileType  string `json:"file_type"`                  // "general"（預設）、"document"、"image"
+	ChunkSize int64  `json:"chunk_size" binding:"min=0"` // 分段大小，0 表示使用預設值
+	ServerID  string `json:"server_id"`                  // 同時計入此伺服器的儲存配額（需為成員）
+}
+
+// PresignedUploadRequest 取得預簽名上傳網址請求
+type PresignedUploadRequest struct {
+	FileName string `json:"file_name" binding:"required"`
+	FileSize int64  `json:"file_size" binding:"required,min=1"`
+	MimeType string `json:"mime_type" binding:"required"`
+	SHA256   string `json:"sha256" binding:"required"` // 完整檔案的 SHA256（十六進位），確認時比對
+	FileType string `json:"file_type"`                 // "general"（預設）、"document"、"image"
+	ServerID string `json:"server_id"`                 // 同時計入此伺服器的儲存配額（需為成員）
 }
@@ -1,6 +1,10 @@
 package models
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 // FileResult 上傳結果結構
 type FileResult struct {
@@ -12,6 +16,79 @@ type FileResult struct {
 	MimeType   string             `json:"mime_type"`
 	UploadedAt int64              `json:"uploaded_at"`
 	UserID     string             `json:"user_id"`
+	Status     string             `json:"status"` // 圖片產生縮圖期間為 "processing"
+}
+
+// FileVariantResponse 指定尺寸的檔案連結，圖片小於該尺寸時返回原圖
+type FileVariantResponse struct {
+	FileID   string `json:"file_id"`
+	Size     string `json:"size"`
+	URL      string `json:"url"`
+	Width    int    `json:"width,omitempty"`
+	Height   int    `json:"height,omitempty"`
+	FileSize int64  `json:"file_size"`
+	MimeType string `json:"mime_type"`
+	Public   bool   `json:"-"` // 公開資源不需檢查存取權限
+	OwnerID  string `json:"-"` // 檔案上傳者，非公開檔案由呼叫端依此與 FileID 檢查存取權限
+}
+
+// ServedFile 檔案服務端點傳送的內容資訊，相同內容的多筆檔案記錄共用同一儲存路徑
+type ServedFile struct {
+	FilePath string
+	FileName string // 下載時使用的檔名
+	MimeType string
+	FileSize int64
+	ETag     string // 依內容雜湊產生（內容不會變更），沒有雜湊時為空
+	ModTime  time.Time
+	Public   bool     // 任一記錄為公開資源時不需登入
+	OwnerIDs []string // 引用此內容的檔案上傳者
+	FileIDs  []string // 引用此內容的檔案ID，用於檢查附件所屬房間的存取權限
+}
+
+// ChunkedUploadResponse 分段上傳狀態，用於續傳時判斷尚缺的分段
+type ChunkedUploadResponse struct {
+	UploadID       string      `json:"upload_id"`
+	FileName       string      `json:"file_name"`
+	FileSize       int64       `json:"file_size"`
+	MimeType       string      `json:"mime_type"`
+	ChunkSize      int64       `json:"chunk_size"`
+	TotalChunks    int         `json:"total_chunks"`
+	ReceivedChunks []int       `json:"received_chunks"` // 已接收的分段編號（由 1 開始，遞增排序）
+	ReceivedRanges []ByteRange `json:"received_ranges"` // 已接收的位元組範圍（合併相鄰分段）
+	ReceivedBytes  int64       `json:"received_bytes"`
+	Status         string      `json:"status"`
+	FileID         string      `json:"file_id,omitempty"` // 完成後的檔案ID
+	ExpiresAt      int64       `json:"expires_at"`
+}
+
+// PresignedUploadResponse 預簽名上傳網址，客戶端以 Method 上傳並帶上 Headers 中的標頭，完成後呼叫確認端點
+type PresignedUploadResponse struct {
+	UploadID  string            `json:"upload_id"`
+	UploadURL string            `json:"upload_url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt int64             `json:"expires_at"` // 上傳網址到期時間（Unix 秒）
+}
+
+// ByteRange 位元組範圍，Start 與 End 皆包含在內
+type ByteRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// StorageUsageResponse 儲存用量，指定伺服器時一併返回伺服器用量
+type StorageUsageResponse struct {
+	User   StorageQuotaUsage  `json:"user"`
+	Server *StorageQuotaUsage `json:"server,omitempty"`
+}
+
+// StorageQuotaUsage 單一擁有者的儲存用量與配額，QuotaBytes 為 0 表示不限制
+type StorageQuotaUsage struct {
+	OwnerID        string `json:"owner_id"`
+	UsedBytes      int64  `json:"used_bytes"`
+	FileCount      int64  `json:"file_count"`
+	QuotaBytes     int64  `json:"quota_bytes"`
+	RemainingBytes int64  `json:"remaining_bytes"` // 不限制時為 -1
 }
 
 // FileInfo 檔案資訊結構
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"chat_app_backend/app/providers"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServerInvite 伺服器邀請碼（獨立集合），私人伺服器只能透過邀請加入
+type ServerInvite struct {
+	providers.BaseModel `bson:",inline"`
+	ServerID            primitive.ObjectID `json:"server_id" bson:"server_id"`
+	Code                string             `json:"code" bson:"code"`
+	CreatedBy           primitive.ObjectID `json:"created_by" bson:"created_by"`
+	MaxUses             int                `json:"max_uses" bson:"max_uses"`     // 最大使用次數，0 表示不限
+	Uses                int                `json:"uses" bson:"uses"`             // 已使用次數
+	ExpiresAt           int64              `json:"expires_at" bson:"expires_at"` // 過期時間戳，0 表示永不過期
+	Revoked             bool               `json:"revoked" bson:"revoked"`
+}
+
+func (si *ServerInvite) GetCollectionName() string {
+	return "server_invites"
+}
+
+// IsExpired 檢查邀請碼在指定時間是否已過期
+func (si *ServerInvite) IsExpired(now int64) bool {
+	return si.ExpiresAt > 0 && now >= si.ExpiresAt
+}
+
+// IsExhausted 檢查邀請碼是否已達最大使用次數
+func (si *ServerInvite) IsExhausted() bool {
+	return si.MaxUses > 0 && si.Uses >= si.MaxUses
+}
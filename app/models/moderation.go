@@ -0,0 +1,50 @@
+package models
+
+import (
+	"chat_app_backend/app/providers"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 管理動作類型
+const (
+	ModerationActionKick          = "kick"
+	ModerationActionBan           = "ban"
+	ModerationActionUnban         = "unban"
+	ModerationActionTimeout       = "timeout"
+	ModerationActionRemoveTimeout = "remove_timeout"
+)
+
+// ServerBan 伺服器封鎖紀錄（獨立集合），每位用戶在每個伺服器最多一筆
+type ServerBan struct {
+	providers.BaseModel `bson:",inline"`
+	ServerID            primitive.ObjectID `json:"server_id" bson:"server_id"`
+	UserID              primitive.ObjectID `json:"user_id" bson:"user_id"`
+	BannedBy            primitive.ObjectID `json:"banned_by" bson:"banned_by"`
+	Reason              string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	ExpiresAt           int64              `json:"expires_at" bson:"expires_at"` // 解除封鎖時間戳，0 表示永久
+}
+
+func (sb *ServerBan) GetCollectionName() string {
+	return "server_bans"
+}
+
+// IsActive 檢查封鎖在指定時間是否仍有效
+func (sb *ServerBan) IsActive(now int64) bool {
+	return sb.ExpiresAt == 0 || now < sb.ExpiresAt
+}
+
+// ModerationAction 管理動作紀錄（獨立集合），記錄踢出、封鎖與禁言等操作
+type ModerationAction struct {
+	providers.BaseModel `bson:",inline"`
+	ServerID            primitive.ObjectID `json:"server_id" bson:"server_id"`
+	ActorID             primitive.ObjectID `json:"actor_id" bson:"actor_id"`
+	TargetID            primitive.ObjectID `json:"target_id" bson:"target_id"`
+	Action              string             `json:"action" bson:"action"`
+	Reason              string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	ExpiresAt           int64              `json:"expires_at,omitempty" bson:"expires_at,omitempty"` // 封鎖或禁言的到期時間戳
+}
+
+func (ma *ModerationAction) GetCollectionName() string {
+	return "moderation_actions"
+}
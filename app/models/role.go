@@ -0,0 +1,107 @@
+package models
+
+import (
+	"chat_app_backend/app/providers"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Permission 伺服器權限位元集合
+type Permission int64
+
+// 伺服器權限位元（新增權限時只能附加在最後，避免已儲存的位元集合錯位）
+const (
+	PermissionManageChannels  Permission = 1 << iota // 管理頻道與類別
+	PermissionManageServer                           // 管理伺服器設定
+	PermissionKickMembers                            // 踢出成員
+	PermissionBanMembers                             // 封鎖成員
+	PermissionManageMessages                         // 管理他人訊息
+	PermissionMentionEveryone                        // 使用 @everyone 與 @here
+	PermissionManageRoles                            // 管理角色與指派角色
+	PermissionViewChannel                            // 查看頻道與讀取訊息
+	PermissionSendMessages                           // 在頻道中發送訊息
+	PermissionTimeoutMembers                         // 禁言成員
+	PermissionViewAuditLog                           // 查看稽核紀錄
+	PermissionCreateInvite                           // 創建邀請碼
+
+	// PermissionAll 所有權限，伺服器擁有者固定擁有
+	PermissionAll = PermissionManageChannels | PermissionManageServer | PermissionKickMembers |
+		PermissionBanMembers | PermissionManageMessages | PermissionMentionEveryone | PermissionManageRoles |
+		PermissionViewChannel | PermissionSendMessages | PermissionTimeoutMembers | PermissionViewAuditLog |
+		PermissionCreateInvite
+
+	// DefaultMemberPermissions 所有成員預設擁有的權限，可透過頻道覆寫拒絕
+	DefaultMemberPermissions = PermissionViewChannel | PermissionSendMessages
+
+	// ChannelScopedPermissions 可在頻道層級覆寫的權限
+	ChannelScopedPermissions = PermissionViewChannel | PermissionSendMessages | PermissionManageMessages | PermissionMentionEveryone
+)
+
+// permissionNames 權限名稱與位元的對應，順序即回應中的排列順序
+var permissionNames = []struct {
+	name       string
+	permission Permission
+}{
+	{"manage_channels", PermissionManageChannels},
+	{"manage_server", PermissionManageServer},
+	{"kick_members", PermissionKickMembers},
+	{"ban_members", PermissionBanMembers},
+	{"manage_messages", PermissionManageMessages},
+	{"mention_everyone", PermissionMentionEveryone},
+	{"manage_roles", PermissionManageRoles},
+	{"view_channel", PermissionViewChannel},
+	{"send_messages", PermissionSendMessages},
+	{"timeout_members", PermissionTimeoutMembers},
+	{"view_audit_log", PermissionViewAuditLog},
+	{"create_invite", PermissionCreateInvite},
+}
+
+// ParsePermissions 將權限名稱列表轉換為位元集合，遇到未知名稱時返回錯誤
+func ParsePermissions(names []string) (Permission, error) {
+	var permissions Permission
+	for _, name := range names {
+		found := false
+		for _, entry := range permissionNames {
+			if entry.name == name {
+				permissions |= entry.permission
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("未知的權限: %s", name)
+		}
+	}
+	return permissions, nil
+}
+
+// Has 檢查是否包含所有指定的權限
+func (p Permission) Has(required Permission) bool {
+	return p&required == required
+}
+
+// Names 返回位元集合中所有權限的名稱
+func (p Permission) Names() []string {
+	names := make([]string, 0, len(permissionNames))
+	for _, entry := range permissionNames {
+		if p.Has(entry.permission) {
+			names = append(names, entry.name)
+		}
+	}
+	return names
+}
+
+// ServerRole 伺服器角色（獨立集合），成員透過 ServerMember.RoleIDs 指派
+type ServerRole struct {
+	providers.BaseModel `bson:",inline"`
+	ServerID            primitive.ObjectID `json:"server_id" bson:"server_id"`
+	Name                string             `json:"name" bson:"name"`
+	Permissions         Permission         `json:"permissions" bson:"permissions"` // 權限位元集合
+	Position            int                `json:"position" bson:"position"`       // 角色位階，數字越大位階越高
+	CreatedBy           primitive.ObjectID `json:"created_by" bson:"created_by"`
+}
+
+func (sr *ServerRole) GetCollectionName() string {
+	return "server_roles"
+}
@@ -24,13 +24,15 @@ type Server struct {
 // 伺服器成員（獨立集合）
 type ServerMember struct {
 	providers.BaseModel `bson:",inline"`
-	ServerID            primitive.ObjectID `json:"server_id" bson:"server_id"`
-	UserID              primitive.ObjectID `json:"user_id" bson:"user_id"`
-	Role                string             `json:"role" bson:"role"` // "owner", "admin", "member"
-	JoinedAt            time.Time          `json:"joined_at" bson:"joined_at"`
-	LastActiveAt        time.Time          `json:"last_active_at" bson:"last_active_at"`
-	Permissions         []string           `json:"permissions,omitempty" bson:"permissions,omitempty"` // 特殊權限
-	Nickname            string             `json:"nickname,omitempty" bson:"nickname,omitempty"`       // 伺服器內暱稱
+	ServerID            primitive.ObjectID   `json:"server_id" bson:"server_id"`
+	UserID              primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	Role                string               `json:"role" bson:"role"` // "owner", "admin", "member"
+	JoinedAt            time.Time            `json:"joined_at" bson:"joined_at"`
+	LastActiveAt        time.Time            `json:"last_active_at" bson:"last_active_at"`
+	Permissions         []string             `json:"permissions,omitempty" bson:"permissions,omitempty"`     // 特殊權限（權限名稱，與角色權限合併計算）
+	RoleIDs             []primitive.ObjectID `json:"role_ids,omitempty" bson:"role_ids,omitempty"`           // 指派的伺服器角色
+	Nickname            string               `json:"nickname,omitempty" bson:"nickname,omitempty"`           // 伺服器內暱稱
+	TimeoutUntil        int64                `json:"timeout_until,omitempty" bson:"timeout_until,omitempty"` // 禁言到期時間戳，期間無法發送訊息
 }
 
 func (sm *ServerMember) GetCollectionName() string {
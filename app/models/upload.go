@@ -20,12 +20,129 @@ type UploadedFile struct {
 	Status              string             `json:"status" bson:"status"`       // "uploaded", "processing", "verified", "failed"
 	Hash                string             `json:"hash" bson:"hash"`           // 檔案SHA256雜湊值
 	ExpiresAt           *time.Time         `json:"expires_at" bson:"expires_at,omitempty"`
+	// 圖片資訊（非圖片或無法解析的格式為零值），由背景處理填入
+	Width    int           `json:"width,omitempty" bson:"width,omitempty"`
+	Height   int           `json:"height,omitempty" bson:"height,omitempty"`
+	Variants []FileVariant `json:"variants,omitempty" bson:"variants,omitempty"` // 縮圖，只產生比原圖小的尺寸
+
+	// 同時計入哪個伺服器的儲存配額（例如伺服器頻道的附件），未指定時只計入上傳者
+	ServerID *primitive.ObjectID `json:"server_id,omitempty" bson:"server_id,omitempty"`
+
+	// 未通過惡意軟體掃描（status 為 failed）時記錄偵測到的特徵或掃描錯誤，檔案隔離保留供管理員檢查
+	ScanResult string `json:"scan_result,omitempty" bson:"scan_result,omitempty"`
+}
+
+// FileVariant 圖片縮圖，與原始檔案存放在同一目錄
+type FileVariant struct {
+	Size     string `json:"size" bson:"size"` // 尺寸名稱，對應 ImageVariantSizes
+	FilePath string `json:"file_path" bson:"file_path"`
+	Width    int    `json:"width" bson:"width"`
+	Height   int    `json:"height" bson:"height"`
+	FileSize int64  `json:"file_size" bson:"file_size"`
+	MimeType string `json:"mime_type" bson:"mime_type"`
+}
+
+// ImageVariantSize 縮圖尺寸，依最長邊等比例縮放
+type ImageVariantSize struct {
+	Name    string
+	MaxEdge int
+}
+
+// 原始檔案的尺寸名稱
+const ImageVariantOriginal = "original"
+
+// ImageVariantSizes 標準縮圖尺寸（由小到大）
+var ImageVariantSizes = []ImageVariantSize{
+	{Name: "small", MaxEdge: 128},
+	{Name: "medium", MaxEdge: 512},
+	{Name: "large", MaxEdge: 1024},
 }
 
 func (u *UploadedFile) GetCollectionName() string {
 	return "uploaded_files"
 }
 
+// PublicFileTypes 公開資源的檔案類型（頭像、橫幅、伺服器圖示），不需登入即可讀取且允許快取
+var PublicFileTypes = []string{"avatar", "banner", "server"}
+
+// 儲存用量的擁有者類型
+const (
+	StorageOwnerUser   = "user"
+	StorageOwnerServer = "server"
+)
+
+// StorageUsage 用戶或伺服器的儲存用量，檔案記錄建立與刪除時增量更新
+// 以邏輯檔案計算，共用相同內容的檔案各自計入
+type StorageUsage struct {
+	providers.BaseModel `bson:",inline"`
+	OwnerType           string             `json:"owner_type" bson:"owner_type"` // "user" 或 "server"
+	OwnerID             primitive.ObjectID `json:"owner_id" bson:"owner_id"`
+	TotalSize           int64              `json:"total_size" bson:"total_size"`
+	FileCount           int64              `json:"file_count" bson:"file_count"`
+}
+
+func (s *StorageUsage) GetCollectionName() string {
+	return "storage_usage"
+}
+
+// FileBlob 以內容雜湊儲存的實際檔案，內容相同的上傳共用同一個儲存物件
+// 用量配額仍以 UploadedFile（邏輯檔案）的大小計算
+type FileBlob struct {
+	providers.BaseModel `bson:",inline"`
+	Hash                string `json:"hash" bson:"hash"` // 檔案SHA256雜湊值（唯一）
+	FilePath            string `json:"file_path" bson:"file_path"`
+	FileSize            int64  `json:"file_size" bson:"file_size"`
+	MimeType            string `json:"mime_type" bson:"mime_type"`
+	RefCount            int64  `json:"ref_count" bson:"ref_count"` // 引用此內容的檔案記錄數量，歸零時刪除實際檔案
+}
+
+func (b *FileBlob) GetCollectionName() string {
+	return "file_blobs"
+}
+
+// 分段上傳狀態
+const (
+	UploadSessionUploading  = "uploading"  // 接收分段中
+	UploadSessionCompleting = "completing" // 合併與驗證中
+	UploadSessionCompleted  = "completed"
+	UploadSessionAborted    = "aborted"
+	UploadSessionFailed     = "failed" // 合併後雜湊或內容驗證失敗
+)
+
+// UploadSession 分段上傳（可續傳）工作階段，結束後保留紀錄直到過期清理
+type UploadSession struct {
+	providers.BaseModel `bson:",inline"`
+	UserID              primitive.ObjectID    `json:"user_id" bson:"user_id"`
+	UploadID            string                `json:"-" bson:"upload_id"` // 儲存端的上傳識別碼（MinIO upload ID、本地暫存目錄或預簽名上傳的暫存物件）
+	FileType            string                `json:"file_type" bson:"file_type"`
+	OriginalName        string                `json:"original_name" bson:"original_name"`
+	FileName            string                `json:"file_name" bson:"file_name"`
+	FilePath            string                `json:"file_path" bson:"file_path"`
+	FileSize            int64                 `json:"file_size" bson:"file_size"` // 客戶端宣告的檔案總大小
+	MimeType            string                `json:"mime_type" bson:"mime_type"`
+	ChunkSize           int64                 `json:"chunk_size" bson:"chunk_size"`
+	TotalChunks         int                   `json:"total_chunks" bson:"total_chunks"`
+	Hash                string                `json:"hash" bson:"hash"`   // 客戶端宣告的 SHA256，完成時比對
+	Parts               map[string]UploadPart `json:"parts" bson:"parts"` // 已接收的分段，以分段編號為鍵
+	Status              string                `json:"status" bson:"status"`
+	FileID              *primitive.ObjectID   `json:"file_id,omitempty" bson:"file_id,omitempty"` // 完成後的檔案記錄
+	ExpiresAt           time.Time             `json:"expires_at" bson:"expires_at"`
+	ServerID            *primitive.ObjectID   `json:"server_id,omitempty" bson:"server_id,omitempty"` // 完成後同時計入此伺服器的儲存配額
+	Presigned           bool                  `json:"presigned" bson:"presigned,omitempty"`           // 客戶端以預簽名網址直接上傳到儲存端（不分段）
+}
+
+// UploadPart 已接收的分段
+type UploadPart struct {
+	Number     int       `json:"number" bson:"number"`
+	Size       int64     `json:"size" bson:"size"`
+	ETag       string    `json:"etag" bson:"etag"`
+	UploadedAt time.Time `json:"uploaded_at" bson:"uploaded_at"`
+}
+
+func (u *UploadSession) GetCollectionName() string {
+	return "upload_sessions"
+}
+
 // FileUploadConfig 檔案上傳配置
 type FileUploadConfig struct {
 	FileType          string   `json:"file_type"`          // 檔案類型，例如 "avatar", "document", "image", "general"
@@ -36,6 +153,7 @@ type FileUploadConfig struct {
 	TempPath          string   `json:"temp_path"`          // 臨時檔案路徑
 	RequireAuth       bool     `json:"require_auth"`       // 是否需要認證
 	ScanMalware       bool     `json:"scan_malware"`       // 是否掃描惡意軟體
+	GenerateVariants  bool     `json:"generate_variants"`  // 圖片是否產生縮圖（非圖片檔案不受影響）
 }
 
 // GetServerUploadConfig 取得伺服器上傳配置
@@ -54,6 +172,7 @@ func GetServerUploadConfig() *FileUploadConfig {
 		TempPath:          "uploads/temp",
 		RequireAuth:       true,
 		ScanMalware:       true,
+		GenerateVariants:  true,
 	}
 }
 
@@ -71,6 +190,7 @@ func GetAvatarUploadConfig() *FileUploadConfig {
 		TempPath:          "uploads/temp",
 		RequireAuth:       true,
 		ScanMalware:       true,
+		GenerateVariants:  true,
 	}
 }
 
@@ -90,6 +210,7 @@ func GetBannerUploadConfig() *FileUploadConfig {
 		TempPath:          "uploads/temp",
 		RequireAuth:       true,
 		ScanMalware:       true,
+		GenerateVariants:  true,
 	}
 }
 
@@ -130,6 +251,7 @@ func GetImageUploadConfig() *FileUploadConfig {
 		TempPath:          "uploads/temp",
 		RequireAuth:       true,
 		ScanMalware:       true,
+		GenerateVariants:  true,
 	}
 }
 
@@ -148,9 +270,10 @@ func GetGeneralUploadConfig() *FileUploadConfig {
 			".jpg", ".jpeg", ".png", ".gif", ".webp",
 			".pdf", ".doc", ".docx", ".txt", ".zip",
 		},
-		UploadPath:  "uploads/files",
-		TempPath:    "uploads/temp",
-		RequireAuth: true,
-		ScanMalware: true,
+		UploadPath:       "uploads/files",
+		TempPath:         "uploads/temp",
+		RequireAuth:      true,
+		ScanMalware:      true,
+		GenerateVariants: true,
 	}
 }
@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"errors"
+	"strconv"
 	"sync"
 	"time"
 
@@ -15,6 +16,8 @@ type CacheProvider interface {
 	Set(key string, value string, expiration time.Duration) error
 	Delete(key string) error
 	SetNX(key string, value string, expiration time.Duration) (bool, error)
+	// Incr 將計數加一並返回結果，鍵不存在時從 1 開始並設定過期時間
+	Incr(key string, expiration time.Duration) (int64, error)
 }
 
 // RedisCacheProvider 是 CacheProvider 的 Redis 實作
@@ -69,6 +72,24 @@ func (p *RedisCacheProvider) SetNX(key string, value string, expiration time.Dur
 	return p.client.SetNX(context.Background(), key, value, expiration).Result()
 }
 
+// Incr 將 Redis 中的計數加一，首次建立時設定過期時間
+func (p *RedisCacheProvider) Incr(key string, expiration time.Duration) (int64, error) {
+	if p.client == nil {
+		return 0, nil
+	}
+	ctx := context.Background()
+	count, err := p.client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if count == 1 && expiration > 0 {
+		if err := p.client.Expire(ctx, key, expiration).Err(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
 // InMemoryCacheProvider 是 CacheProvider 的本地記憶體實作
 type InMemoryCacheProvider struct {
 	data sync.Map
@@ -141,6 +162,40 @@ func (p *InMemoryCacheProvider) SetNX(key string, value string, expiration time.
 	return !loaded, nil
 }
 
+func (p *InMemoryCacheProvider) Incr(key string, expiration time.Duration) (int64, error) {
+	for {
+		val, ok := p.data.Load(key)
+		if ok {
+			item := val.(cacheItem)
+			if item.expiration == 0 || time.Now().UnixNano() <= item.expiration {
+				count, err := strconv.ParseInt(item.value, 10, 64)
+				if err != nil {
+					return 0, err
+				}
+				// 以 CompareAndSwap 確保併發遞增不會遺失
+				if p.data.CompareAndSwap(key, val, cacheItem{value: strconv.FormatInt(count+1, 10), expiration: item.expiration}) {
+					return count + 1, nil
+				}
+				continue
+			}
+		}
+
+		var exp int64 = 0
+		if expiration > 0 {
+			exp = time.Now().Add(expiration).UnixNano()
+		}
+		item := cacheItem{value: "1", expiration: exp}
+		if ok {
+			// 已過期的值直接替換
+			if p.data.CompareAndSwap(key, val, item) {
+				return 1, nil
+			}
+		} else if _, loaded := p.data.LoadOrStore(key, item); !loaded {
+			return 1, nil
+		}
+	}
+}
+
 // NoopCacheProvider 是 CacheProvider 的空實作，不做任何事
 type NoopCacheProvider struct{}
 
@@ -164,3 +219,7 @@ func (p *NoopCacheProvider) Delete(key string) error {
 func (p *NoopCacheProvider) SetNX(key string, value string, expiration time.Duration) (bool, error) {
 	return true, nil
 }
+
+func (p *NoopCacheProvider) Incr(key string, expiration time.Duration) (int64, error) {
+	return 0, nil
+}
@@ -193,12 +193,173 @@ func CreateIndexes(db *mongo.Database) error {
 			Keys:    bson.D{{Key: "token", Value: 1}},
 			Options: options.Index().SetUnique(true),
 		},
+		{
+			// 撤銷整個登入工作階段（token family）時使用
+			Keys: bson.D{{Key: "family_id", Value: 1}},
+		},
 	}
 	_, err = tokensColl.Indexes().CreateMany(ctx, tokenIndexes)
 	if err != nil {
 		return fmt.Errorf("refresh_tokens indexes failed: %v", err)
 	}
 
+	// 3. Room Reads collection（每位用戶在每個房間只有一筆已讀紀錄）
+	roomReadsColl := db.Collection("room_reads")
+	roomReadIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "room_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err = roomReadsColl.Indexes().CreateMany(ctx, roomReadIndexes)
+	if err != nil {
+		return fmt.Errorf("room_reads indexes failed: %v", err)
+	}
+
+	// 4. Messages collection（未讀數量依房間與訊息ID範圍計算，討論串依父訊息分頁，內容全文搜尋，附件反查訊息）
+	messagesColl := db.Collection("messages")
+	messageIndexes := []mongo.IndexModel{
+		{
+			// 不做語系詞幹處理，避免中英文混合內容被錯誤斷詞
+			Keys:    bson.D{{Key: "content", Value: "text"}},
+			Options: options.Index().SetName("content_text").SetDefaultLanguage("none"),
+		},
+		{
+			Keys: bson.D{{Key: "room_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "thread_id", Value: 1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "attachments.file_id", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+	}
+	_, err = messagesColl.Indexes().CreateMany(ctx, messageIndexes)
+	if err != nil {
+		return fmt.Errorf("messages indexes failed: %v", err)
+	}
+
+	// 5. Server Roles collection（依伺服器與位階列出角色）
+	serverRolesColl := db.Collection("server_roles")
+	serverRoleIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "position", Value: -1}, {Key: "_id", Value: 1}},
+		},
+	}
+	_, err = serverRolesColl.Indexes().CreateMany(ctx, serverRoleIndexes)
+	if err != nil {
+		return fmt.Errorf("server_roles indexes failed: %v", err)
+	}
+
+	// 6. Server Invites collection（邀請碼唯一，依伺服器列出邀請）
+	serverInvitesColl := db.Collection("server_invites")
+	serverInviteIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "code", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+	}
+	_, err = serverInvitesColl.Indexes().CreateMany(ctx, serverInviteIndexes)
+	if err != nil {
+		return fmt.Errorf("server_invites indexes failed: %v", err)
+	}
+
+	// 7. Server Bans / Moderation Actions collection（加入時檢查封鎖，依伺服器列出管理紀錄）
+	serverBansColl := db.Collection("server_bans")
+	serverBanIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "user_id", Value: 1}},
+		},
+	}
+	_, err = serverBansColl.Indexes().CreateMany(ctx, serverBanIndexes)
+	if err != nil {
+		return fmt.Errorf("server_bans indexes failed: %v", err)
+	}
+
+	moderationActionsColl := db.Collection("moderation_actions")
+	moderationActionIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+	}
+	_, err = moderationActionsColl.Indexes().CreateMany(ctx, moderationActionIndexes)
+	if err != nil {
+		return fmt.Errorf("moderation_actions indexes failed: %v", err)
+	}
+
+	// 8. Audit Logs collection（依伺服器新到舊列出，可依動作類型或操作者篩選）
+	auditLogsColl := db.Collection("audit_logs")
+	auditLogIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "action", Value: 1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "server_id", Value: 1}, {Key: "actor_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+	}
+	_, err = auditLogsColl.Indexes().CreateMany(ctx, auditLogIndexes)
+	if err != nil {
+		return fmt.Errorf("audit_logs indexes failed: %v", err)
+	}
+
+	// 9. Upload Sessions collection（依過期時間清理未完成的分段上傳）
+	uploadSessionsColl := db.Collection("upload_sessions")
+	uploadSessionIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "expires_at", Value: 1}},
+		},
+	}
+	_, err = uploadSessionsColl.Indexes().CreateMany(ctx, uploadSessionIndexes)
+	if err != nil {
+		return fmt.Errorf("upload_sessions indexes failed: %v", err)
+	}
+
+	// 10. File Blobs collection（以內容雜湊去重，同一內容只有一筆）
+	fileBlobsColl := db.Collection("file_blobs")
+	fileBlobIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err = fileBlobsColl.Indexes().CreateMany(ctx, fileBlobIndexes)
+	if err != nil {
+		return fmt.Errorf("file_blobs indexes failed: %v", err)
+	}
+
+	// 11. Uploaded Files collection（去重時依雜湊尋找已處理完成的檔案）
+	uploadedFilesColl := db.Collection("uploaded_files")
+	uploadedFileIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "hash", Value: 1}},
+		},
+	}
+	_, err = uploadedFilesColl.Indexes().CreateMany(ctx, uploadedFileIndexes)
+	if err != nil {
+		return fmt.Errorf("uploaded_files indexes failed: %v", err)
+	}
+
+	// 12. Storage Usage collection（每個用戶或伺服器只有一筆用量紀錄）
+	storageUsageColl := db.Collection("storage_usage")
+	storageUsageIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "owner_type", Value: 1}, {Key: "owner_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	_, err = storageUsageColl.Indexes().CreateMany(ctx, storageUsageIndexes)
+	if err != nil {
+		return fmt.Errorf("storage_usage indexes failed: %v", err)
+	}
+
 	return nil
 }
 
@@ -2,7 +2,10 @@ package providers
 
 import (
 	"chat_app_backend/config"
+	"crypto/md5" //nolint:gosec // 僅作為分段 ETag，與 S3 行為一致
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log/slog"
@@ -16,6 +19,9 @@ import (
 // BaseUploadPath 上傳檔案的基礎路徑
 const BaseUploadPath = "uploads/"
 
+// ChunkUploadPath 分段上傳的暫存路徑，放在 BaseUploadPath 之外以免與已上傳的檔案混在一起
+const ChunkUploadPath = "tmp/chunks/"
+
 // fileProvider 本地檔案系統提供者
 type fileProvider struct {
 	cfg *config.Config
@@ -68,6 +74,38 @@ func (fp *fileProvider) SaveFile(file multipart.File, filename string) (string,
 	return filename, nil
 }
 
+// SaveReader 將資料流儲存到本地檔案系統，本地儲存不需要 size 與 contentType
+func (fp *fileProvider) SaveReader(reader io.Reader, size int64, filename string, contentType string) (string, error) {
+	fullPath := filepath.Join(BaseUploadPath, filename)
+	// 確保檔案路徑在允許的基礎路徑內（防止路徑遍歷攻擊）
+	if !strings.HasPrefix(fullPath, BaseUploadPath) {
+		return "", fmt.Errorf("檔案路徑不在允許範圍內")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0750); err != nil {
+		return "", fmt.Errorf("無法創建目錄: %w", err)
+	}
+
+	dst, err := os.Create(filepath.Clean(fullPath))
+	if err != nil {
+		return "", fmt.Errorf("無法創建檔案: %w", err)
+	}
+	defer func() {
+		if err := dst.Close(); err != nil {
+			slog.Warn("無法關閉目標檔案 (SaveReader)", "path", fullPath, "error", err)
+		}
+	}()
+
+	if _, err := io.Copy(dst, reader); err != nil {
+		if cleanupErr := os.Remove(fullPath); cleanupErr != nil {
+			slog.Warn("無法刪除損毀的檔案", "path", fullPath, "error", cleanupErr)
+		}
+		return "", fmt.Errorf("無法寫入檔案內容: %w", err)
+	}
+
+	return filename, nil
+}
+
 // DeleteFile 刪除檔案
 func (fp *fileProvider) DeleteFile(filepathStr string) error {
 	// 若傳入的是相對路徑，先補上 BaseUploadPath
@@ -153,6 +191,140 @@ func (fp *fileProvider) GetFile(filepathStr string) (io.ReadCloser, error) {
 	return os.Open(filepath.Clean(fullPath))
 }
 
+// InitiateChunkedUpload 建立分段暫存目錄，以隨機識別碼作為上傳ID
+func (fp *fileProvider) InitiateChunkedUpload(filename string, contentType string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("無法生成上傳ID: %w", err)
+	}
+	uploadID := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Join(ChunkUploadPath, uploadID), 0750); err != nil {
+		return "", fmt.Errorf("無法創建分段暫存目錄: %w", err)
+	}
+
+	return uploadID, nil
+}
+
+// UploadChunk 將分段寫入暫存目錄，先寫入暫存檔再改名，重複上傳同一分段時會覆蓋
+func (fp *fileProvider) UploadChunk(filename string, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	chunkDir, err := chunkUploadDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+
+	partPath := filepath.Join(chunkDir, fmt.Sprintf("%d.part", partNumber))
+	tempFile, err := os.CreateTemp(chunkDir, "part_*")
+	if err != nil {
+		return "", fmt.Errorf("無法創建分段檔案: %w", err)
+	}
+	defer func() {
+		if err := os.Remove(tempFile.Name()); err != nil && !os.IsNotExist(err) {
+			slog.Warn("無法刪除分段暫存檔", "path", tempFile.Name(), "error", err)
+		}
+	}()
+
+	h := md5.New() //nolint:gosec // 僅作為分段 ETag
+	written, err := io.Copy(io.MultiWriter(tempFile, h), reader)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("無法寫入分段內容: %w", err)
+	}
+	if written != size {
+		return "", fmt.Errorf("分段大小不符: 預期 %d bytes, 實際 %d bytes", size, written)
+	}
+
+	if err := os.Rename(tempFile.Name(), partPath); err != nil {
+		return "", fmt.Errorf("無法儲存分段: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CompleteChunkedUpload 依序合併分段為最終檔案，完成後刪除暫存目錄
+func (fp *fileProvider) CompleteChunkedUpload(filename string, uploadID string, parts []ChunkPart) (string, error) {
+	chunkDir, err := chunkUploadDir(uploadID)
+	if err != nil {
+		return "", err
+	}
+
+	fullPath := filepath.Join(BaseUploadPath, filename)
+	if !strings.HasPrefix(fullPath, BaseUploadPath) {
+		return "", fmt.Errorf("檔案路徑不在允許範圍內")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0750); err != nil {
+		return "", fmt.Errorf("無法創建目錄: %w", err)
+	}
+
+	dst, err := os.Create(filepath.Clean(fullPath))
+	if err != nil {
+		return "", fmt.Errorf("無法創建檔案: %w", err)
+	}
+
+	if err := appendChunkParts(dst, chunkDir, parts); err != nil {
+		if closeErr := dst.Close(); closeErr != nil {
+			slog.Warn("無法關閉目標檔案 (CompleteChunkedUpload)", "path", fullPath, "error", closeErr)
+		}
+		if cleanupErr := os.Remove(fullPath); cleanupErr != nil {
+			slog.Warn("無法刪除合併失敗的檔案", "path", fullPath, "error", cleanupErr)
+		}
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		return "", fmt.Errorf("無法關閉合併後的檔案: %w", err)
+	}
+
+	if err := os.RemoveAll(chunkDir); err != nil {
+		slog.Warn("無法刪除分段暫存目錄", "path", chunkDir, "error", err)
+	}
+
+	return filename, nil
+}
+
+// AbortChunkedUpload 刪除分段暫存目錄
+func (fp *fileProvider) AbortChunkedUpload(filename string, uploadID string) error {
+	chunkDir, err := chunkUploadDir(uploadID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(chunkDir); err != nil {
+		return fmt.Errorf("無法刪除分段暫存目錄: %w", err)
+	}
+	return nil
+}
+
+// chunkUploadDir 取得分段暫存目錄，上傳ID必須是 InitiateChunkedUpload 產生的十六進位字串
+func chunkUploadDir(uploadID string) (string, error) {
+	if decoded, err := hex.DecodeString(uploadID); err != nil || len(decoded) != 16 {
+		return "", fmt.Errorf("無效的上傳ID")
+	}
+	return filepath.Join(ChunkUploadPath, uploadID), nil
+}
+
+// appendChunkParts 依序將分段內容寫入目標檔案
+func appendChunkParts(dst io.Writer, chunkDir string, parts []ChunkPart) error {
+	for _, part := range parts {
+		partPath := filepath.Join(chunkDir, fmt.Sprintf("%d.part", part.Number))
+		src, err := os.Open(filepath.Clean(partPath))
+		if err != nil {
+			return fmt.Errorf("無法開啟分段 %d: %w", part.Number, err)
+		}
+
+		_, err = io.Copy(dst, src)
+		if closeErr := src.Close(); closeErr != nil {
+			slog.Warn("無法關閉分段檔案", "path", partPath, "error", closeErr)
+		}
+		if err != nil {
+			return fmt.Errorf("無法合併分段 %d: %w", part.Number, err)
+		}
+	}
+	return nil
+}
+
 // GenerateSecureFileName 生成安全的檔案名稱
 func GenerateSecureFileName(originalName, userID string) string {
 	// 取得副檔名
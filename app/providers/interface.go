@@ -5,6 +5,7 @@ import (
 	"io"
 	"mime/multipart"
 	"os"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -13,10 +14,68 @@ import (
 // FileProvider - 負責底層文件操作
 type FileProvider interface {
 	SaveFile(file multipart.File, filename string) (string, error)
+	SaveReader(reader io.Reader, size int64, filename string, contentType string) (string, error) // 儲存已知大小的資料流（例如縮圖）
 	DeleteFile(filepath string) error
 	GetFileInfo(filepath string) (os.FileInfo, error)
 	GetFileURL(filePath string) string
 	GetFile(filepath string) (io.ReadCloser, error)
+
+	// ===== 分段上傳 =====
+
+	// InitiateChunkedUpload 開始分段上傳，返回儲存端的上傳識別碼
+	InitiateChunkedUpload(filename string, contentType string) (string, error)
+
+	// UploadChunk 儲存單一分段（編號從 1 開始），返回分段的 ETag
+	UploadChunk(filename string, uploadID string, partNumber int, reader io.Reader, size int64) (string, error)
+
+	// CompleteChunkedUpload 依分段編號順序合併為最終檔案，返回檔案相對路徑
+	CompleteChunkedUpload(filename string, uploadID string, parts []ChunkPart) (string, error)
+
+	// AbortChunkedUpload 放棄分段上傳並清除已上傳的分段
+	AbortChunkedUpload(filename string, uploadID string) error
+}
+
+// ChunkPart 已上傳的分段
+type ChunkPart struct {
+	Number int
+	ETag   string
+}
+
+// PresignProvider - 支援預簽名網址的儲存後端（MinIO/S3），客戶端可直接上傳或下載而不經過 API 伺服器
+type PresignProvider interface {
+	// PresignPutURL 產生上傳網址，客戶端必須帶上相同的 Content-Type 與 Content-Length（包含在簽名中）
+	PresignPutURL(filename string, contentType string, size int64, expires time.Duration) (string, error)
+
+	// PresignGetURL 產生短效下載網址，downloadName 非空時以附件方式下載並使用該檔名
+	PresignGetURL(filePath string, expires time.Duration, downloadName string) (string, error)
+
+	// CopyFile 在儲存端複製檔案，返回目標檔案相對路徑
+	CopyFile(srcPath string, dstPath string) (string, error)
+}
+
+// MalwareScanner - 惡意軟體掃描引擎
+type MalwareScanner interface {
+	// Scan 掃描資料流內容，發現惡意內容時返回 Infected 與特徵名稱
+	Scan(ctx context.Context, reader io.Reader) (*ScanResult, error)
+}
+
+// ScanResult 掃描結果
+type ScanResult struct {
+	Infected  bool
+	Signature string // 偵測到的特徵名稱
+}
+
+// Mailer - 寄送電子郵件（驗證信、重設密碼信等）
+type Mailer interface {
+	// Send 寄出一封純文字信件
+	Send(ctx context.Context, message MailMessage) error
+}
+
+// MailMessage 信件內容
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string // 純文字內容
 }
 
 // ODM - 提供對模型的資料庫操作介面
@@ -0,0 +1,261 @@
+package providers
+
+import (
+	"bytes"
+	"chat_app_backend/config"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SMTPImplicitTLSPort = 465 // 使用 TLS 連線（SMTPS）的連接埠，其他連接埠改用 STARTTLS
+	mailBodyLineLength  = 76  // base64 內容每行長度（RFC 2045）
+)
+
+// NewMailer 依設定建立寄信服務，未指定時使用 log 模式，無法識別的模式返回錯誤
+func NewMailer(cfg *config.Config) (Mailer, error) {
+	timeout := time.Duration(cfg.Mail.TimeoutSeconds) * time.Second
+
+	switch cfg.Mail.Driver {
+	case config.MailDriverSMTP:
+		return NewSMTPMailer(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From, timeout)
+	case config.MailDriverLog, "":
+		mailer, err := NewLogMailer(cfg.Mail.From, cfg.Mail.OutboxDir)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Server.Mode == config.ProductionMode {
+			// 信件內容含驗證與重設密碼連結，正式環境不可寫入日誌
+			slog.Warn("正式環境使用 log 寄信模式，信件不會實際寄出，日誌中也不會記錄信件內容")
+			mailer.redactBody = true
+		}
+		return mailer, nil
+	default:
+		return nil, fmt.Errorf("未知的寄信模式: %q", cfg.Mail.Driver)
+	}
+}
+
+// ===== SMTP =====
+
+// smtpMailer 透過 SMTP 伺服器寄信
+type smtpMailer struct {
+	host        string
+	address     string
+	implicitTLS bool // 連線後直接進行 TLS 握手（SMTPS）
+	username    string
+	password    string
+	from        *mail.Address
+	timeout     time.Duration
+}
+
+// NewSMTPMailer 創建 SMTP 寄信服務，port 為 465 時使用 TLS 連線，其他連接埠在伺服器支援時使用 STARTTLS
+func NewSMTPMailer(host string, port int, username string, password string, from string, timeout time.Duration) (*smtpMailer, error) {
+	if host == "" {
+		return nil, errors.New("未設定 SMTP 主機")
+	}
+	fromAddress, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("無效的寄件者: %w", err)
+	}
+
+	return &smtpMailer{
+		host:        host,
+		address:     net.JoinHostPort(host, strconv.Itoa(port)),
+		implicitTLS: port == SMTPImplicitTLSPort,
+		username:    username,
+		password:    password,
+		from:        fromAddress,
+		timeout:     timeout,
+	}, nil
+}
+
+// Send 連線 SMTP 伺服器寄出信件
+func (sm *smtpMailer) Send(ctx context.Context, message MailMessage) error {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("無效的收件者: %w", err)
+	}
+
+	dialer := net.Dialer{Timeout: sm.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", sm.address)
+	if err != nil {
+		return fmt.Errorf("無法連線到 SMTP 伺服器: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	deadline := time.Now().Add(sm.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("無法設定 SMTP 連線逾時: %w", err)
+	}
+
+	if sm.implicitTLS {
+		conn = tls.Client(conn, &tls.Config{ServerName: sm.host})
+	}
+
+	client, err := smtp.NewClient(conn, sm.host)
+	if err != nil {
+		return fmt.Errorf("SMTP 握手失敗: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	if !sm.implicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: sm.host}); err != nil {
+				return fmt.Errorf("SMTP STARTTLS 失敗: %w", err)
+			}
+		}
+	}
+
+	if sm.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", sm.username, sm.password, sm.host)); err != nil {
+			return fmt.Errorf("SMTP 認證失敗: %w", err)
+		}
+	}
+
+	if err := client.Mail(sm.from.Address); err != nil {
+		return fmt.Errorf("SMTP 寄件者被拒絕: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("SMTP 收件者被拒絕: %w", err)
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP 傳送內容失敗: %w", err)
+	}
+	if _, err := writer.Write(buildMailMessage(sm.from, to, message, time.Now())); err != nil {
+		return fmt.Errorf("SMTP 傳送內容失敗: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP 傳送內容失敗: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// ===== Log（開發與測試用） =====
+
+// logMailer 不實際寄信，將信件寫入日誌，設定 outboxDir 時另存為 .eml 檔案
+type logMailer struct {
+	from       *mail.Address
+	outboxDir  string
+	redactBody bool // 不在日誌中記錄信件內容（正式環境）
+}
+
+// NewLogMailer 創建 log 寄信服務，outboxDir 為空時只寫入日誌
+func NewLogMailer(from string, outboxDir string) (*logMailer, error) {
+	fromAddress, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("無效的寄件者: %w", err)
+	}
+
+	if outboxDir != "" {
+		if err := os.MkdirAll(outboxDir, 0o750); err != nil {
+			return nil, fmt.Errorf("無法建立信件輸出目錄: %w", err)
+		}
+	}
+
+	return &logMailer{from: fromAddress, outboxDir: outboxDir}, nil
+}
+
+// Send 將信件寫入日誌與輸出目錄
+func (lm *logMailer) Send(ctx context.Context, message MailMessage) error {
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("無效的收件者: %w", err)
+	}
+
+	if lm.redactBody {
+		slog.Info("寄出信件（log 模式）", "to", to.Address, "subject", message.Subject)
+	} else {
+		slog.Info("寄出信件（log 模式）", "to", to.Address, "subject", message.Subject, "body", message.Body)
+	}
+
+	if lm.outboxDir == "" {
+		return nil
+	}
+
+	now := time.Now()
+	file, err := os.CreateTemp(lm.outboxDir, now.Format("20060102-150405.000000")+"-*.eml")
+	if err != nil {
+		return fmt.Errorf("無法建立信件檔案: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := file.Write(buildMailMessage(lm.from, to, message, now)); err != nil {
+		return fmt.Errorf("無法寫入信件檔案: %w", err)
+	}
+	return nil
+}
+
+// ===== 信件格式 =====
+
+// buildMailMessage 組成 RFC 5322 信件，主旨以 MIME encoded-word、內容以 base64 編碼，避免換行字元造成標頭注入
+func buildMailMessage(from *mail.Address, to *mail.Address, message MailMessage, now time.Time) []byte {
+	var buf bytes.Buffer
+
+	domain := "localhost"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", uuid.New().String(), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(message.Body))
+	for len(encoded) > mailBodyLineLength {
+		buf.WriteString(encoded[:mailBodyLineLength] + "\r\n")
+		encoded = encoded[mailBodyLineLength:]
+	}
+	buf.WriteString(encoded + "\r\n")
+
+	return buf.Bytes()
+}
+
+// ParseMailMessage 解析 buildMailMessage 產生的信件（例如 log 模式輸出的 .eml 檔案）
+func ParseMailMessage(reader io.Reader) (*MailMessage, error) {
+	parsed, err := mail.ReadMessage(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := mail.ParseAddress(parsed.Header.Get("To"))
+	if err != nil {
+		return nil, fmt.Errorf("無效的收件者: %w", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil {
+		return nil, fmt.Errorf("無效的主旨: %w", err)
+	}
+	body, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, parsed.Body))
+	if err != nil {
+		return nil, fmt.Errorf("無效的信件內容: %w", err)
+	}
+
+	return &MailMessage{To: to.Address, Subject: subject, Body: string(body)}, nil
+}
@@ -0,0 +1,239 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"chat_app_backend/config"
+	"context"
+	"log/slog"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPSession 模擬 SMTP 伺服器收到的一封信
+type fakeSMTPSession struct {
+	from string
+	to   string
+	data []byte
+}
+
+// startFakeSMTP 啟動只支援基本指令（不含 STARTTLS 與認證）的模擬 SMTP 伺服器
+func startFakeSMTP(t *testing.T) (string, int, chan fakeSMTPSession) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("無法啟動模擬 SMTP 伺服器: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	received := make(chan fakeSMTPSession, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handleFakeSMTP(conn, received)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+func handleFakeSMTP(conn net.Conn, received chan fakeSMTPSession) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+
+	var session fakeSMTPSession
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			session.from = strings.Trim(strings.TrimPrefix(command, "MAIL FROM:"), "<>")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			session.to = strings.Trim(strings.TrimPrefix(command, "RCPT TO:"), "<>")
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data bytes.Buffer
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			session.data = data.Bytes()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			received <- session
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	host, port, received := startFakeSMTP(t)
+	mailer, err := NewSMTPMailer(host, port, "", "", "Chat App <no-reply@example.com>", 5*time.Second)
+	assert.NoError(t, err)
+
+	err = mailer.Send(context.Background(), MailMessage{
+		To:      "alice@example.com",
+		Subject: "驗證您的電子郵件",
+		Body:    "請點擊以下連結完成驗證：\nhttp://localhost:3000/verify-email?token=abc",
+	})
+	assert.NoError(t, err)
+
+	select {
+	case session := <-received:
+		assert.Equal(t, "no-reply@example.com", session.from)
+		assert.Equal(t, "alice@example.com", session.to)
+
+		message, err := ParseMailMessage(bytes.NewReader(session.data))
+		assert.NoError(t, err)
+		assert.Equal(t, "alice@example.com", message.To)
+		assert.Equal(t, "驗證您的電子郵件", message.Subject)
+		assert.Contains(t, message.Body, "verify-email?token=abc")
+	case <-time.After(5 * time.Second):
+		t.Fatal("模擬 SMTP 伺服器未收到信件")
+	}
+}
+
+func TestSMTPMailer_InvalidRecipient(t *testing.T) {
+	mailer, err := NewSMTPMailer("localhost", 587, "", "", "no-reply@example.com", time.Second)
+	assert.NoError(t, err)
+
+	// 收件者包含換行字元時拒絕寄出，避免標頭注入
+	err = mailer.Send(context.Background(), MailMessage{To: "alice@example.com\r\nBcc: eve@example.com", Subject: "test"})
+	assert.Error(t, err)
+}
+
+func TestNewSMTPMailer(t *testing.T) {
+	_, err := NewSMTPMailer("", 587, "", "", "no-reply@example.com", time.Second)
+	assert.Error(t, err, "未設定主機時應失敗")
+
+	_, err = NewSMTPMailer("localhost", 587, "", "", "not an address", time.Second)
+	assert.Error(t, err, "寄件者格式錯誤時應失敗")
+
+	mailer, err := NewSMTPMailer("smtp.example.com", SMTPImplicitTLSPort, "", "", "no-reply@example.com", time.Second)
+	assert.NoError(t, err)
+	assert.True(t, mailer.implicitTLS)
+	assert.Equal(t, "smtp.example.com:465", mailer.address)
+}
+
+func TestNewMailer(t *testing.T) {
+	newConfig := func(driver config.MailDriver, mode config.ModeConfig) *config.Config {
+		return &config.Config{
+			Server: config.ServerConfig{Mode: mode},
+			Mail:   config.MailConfig{Driver: driver, From: "no-reply@example.com"},
+		}
+	}
+
+	t.Run("未知的寄信模式", func(t *testing.T) {
+		mailer, err := NewMailer(newConfig("sendgrid", config.DevelopmentMode))
+		assert.Error(t, err)
+		assert.Nil(t, mailer)
+	})
+
+	t.Run("正式環境的 log 模式不記錄信件內容", func(t *testing.T) {
+		var logs bytes.Buffer
+		original := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+		t.Cleanup(func() { slog.SetDefault(original) })
+
+		mailer, err := NewMailer(newConfig(config.MailDriverLog, config.ProductionMode))
+		assert.NoError(t, err)
+
+		err = mailer.Send(context.Background(), MailMessage{To: "bob@example.com", Subject: "重設密碼", Body: "https://example.com/reset?token=secret-token"})
+		assert.NoError(t, err)
+		assert.Contains(t, logs.String(), "bob@example.com")
+		assert.NotContains(t, logs.String(), "secret-token")
+	})
+
+	t.Run("開發環境的 log 模式記錄信件內容", func(t *testing.T) {
+		mailer, err := NewMailer(newConfig("", config.DevelopmentMode))
+		assert.NoError(t, err)
+		assert.False(t, mailer.(*logMailer).redactBody)
+	})
+}
+
+func TestLogMailer(t *testing.T) {
+	t.Run("寫入輸出目錄", func(t *testing.T) {
+		outboxDir := filepath.Join(t.TempDir(), "outbox")
+		mailer, err := NewLogMailer("Chat App <no-reply@example.com>", outboxDir)
+		assert.NoError(t, err)
+
+		body := strings.Repeat("重設密碼連結 ", 20)
+		err = mailer.Send(context.Background(), MailMessage{To: "Bob <bob@example.com>", Subject: "重設密碼", Body: body})
+		assert.NoError(t, err)
+
+		files, err := filepath.Glob(filepath.Join(outboxDir, "*.eml"))
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+
+		raw, err := os.ReadFile(files[0])
+		assert.NoError(t, err)
+		parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+		assert.NoError(t, err)
+		assert.Equal(t, "text/plain; charset=UTF-8", parsed.Header.Get("Content-Type"))
+		assert.Contains(t, parsed.Header.Get("Message-ID"), "@example.com>")
+
+		message, err := ParseMailMessage(bytes.NewReader(raw))
+		assert.NoError(t, err)
+		assert.Equal(t, "bob@example.com", message.To)
+		assert.Equal(t, "重設密碼", message.Subject)
+		assert.Equal(t, body, message.Body)
+	})
+
+	t.Run("未設定輸出目錄時只寫入日誌", func(t *testing.T) {
+		mailer, err := NewLogMailer("no-reply@example.com", "")
+		assert.NoError(t, err)
+
+		err = mailer.Send(context.Background(), MailMessage{To: "bob@example.com", Subject: "test", Body: "hello"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("無效的收件者", func(t *testing.T) {
+		mailer, err := NewLogMailer("no-reply@example.com", t.TempDir())
+		assert.NoError(t, err)
+
+		err = mailer.Send(context.Background(), MailMessage{To: "invalid", Subject: "test"})
+		assert.Error(t, err)
+	})
+}
+
+func TestBuildMailMessage_WrapsBody(t *testing.T) {
+	from := &mail.Address{Address: "no-reply@example.com"}
+	to := &mail.Address{Address: "bob@example.com"}
+
+	raw := buildMailMessage(from, to, MailMessage{Subject: "Subject\r\nBcc: eve@example.com", Body: strings.Repeat("a", 500)}, time.Now())
+
+	headers, body, _ := strings.Cut(string(raw), "\r\n\r\n")
+	assert.NotContains(t, headers, "\r\nBcc:", "主旨中的換行字元不應產生新的標頭")
+	for _, line := range strings.Split(strings.TrimRight(body, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), mailBodyLineLength)
+	}
+}
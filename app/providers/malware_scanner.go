@@ -0,0 +1,225 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"chat_app_backend/config"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	HeuristicScanSize = 1024      // 啟發式掃描讀取的檔案開頭大小
+	ClamdChunkSize    = 64 * 1024 // INSTREAM 每次傳送的分段大小
+)
+
+// NewMalwareScanner 依設定建立掃描引擎，未指定或無法識別時使用啟發式掃描
+func NewMalwareScanner(cfg *config.Config) (MalwareScanner, error) {
+	switch cfg.Scanner.Type {
+	case config.ScannerTypeClamd:
+		return NewClamdScanner(cfg.Scanner.ClamdAddress, time.Duration(cfg.Scanner.TimeoutSeconds)*time.Second)
+	case config.ScannerTypeHeuristic, "":
+		return NewHeuristicScanner(), nil
+	default:
+		slog.Warn("未知的惡意軟體掃描引擎，改用啟發式掃描", "type", cfg.Scanner.Type)
+		return NewHeuristicScanner(), nil
+	}
+}
+
+// ===== 啟發式掃描 =====
+
+// heuristicSignature 檔案開頭的二進位特徵
+type heuristicSignature struct {
+	name    string
+	pattern []byte
+}
+
+var heuristicSignatures = []heuristicSignature{
+	{"Heuristic.PE", []byte("MZ")},               // PE執行檔標誌
+	{"Heuristic.HTML", []byte("<!DOCTYPE html")}, // HTML檔案
+	{"Heuristic.JavaScript", []byte("<script")},  // JavaScript
+	{"Heuristic.PHP", []byte("<?php")},           // PHP腳本
+	{"Heuristic.ASP", []byte("<%")},              // ASP/JSP
+	{"Heuristic.Shell", []byte("#!/bin/sh")},     // Shell腳本
+	{"Heuristic.Bash", []byte("#!/bin/bash")},    // Bash腳本
+	{"Heuristic.ELF", []byte("\x7fELF")},         // ELF執行檔（Linux）
+	{"Heuristic.Archive", []byte("PK\x03\x04")},  // ZIP檔案（可能包含惡意軟體）
+}
+
+// heuristicSuspiciousStrings 可疑的文字內容（不分大小寫）
+var heuristicSuspiciousStrings = []string{
+	"eval(",
+	"base64_decode",
+	"shell_exec",
+	"system(",
+	"exec(",
+	"passthru(",
+	"file_get_contents",
+	"fopen(",
+	"javascript:",
+	"vbscript:",
+}
+
+// DetectMaliciousContent 以已知特徵檢查內容，返回偵測到的特徵名稱
+func DetectMaliciousContent(content []byte) (string, bool) {
+	for _, signature := range heuristicSignatures {
+		if bytes.Contains(content, signature.pattern) {
+			return signature.name, true
+		}
+	}
+
+	contentStr := strings.ToLower(string(content))
+	for _, suspicious := range heuristicSuspiciousStrings {
+		if strings.Contains(contentStr, suspicious) {
+			return "Heuristic.Suspicious", true
+		}
+	}
+
+	return "", false
+}
+
+// heuristicScanner 只檢查檔案開頭的內建特徵，未部署 ClamAV 時使用
+type heuristicScanner struct{}
+
+// NewHeuristicScanner 創建啟發式掃描引擎
+func NewHeuristicScanner() *heuristicScanner {
+	return &heuristicScanner{}
+}
+
+// Scan 讀取檔案開頭並比對內建特徵
+func (hs *heuristicScanner) Scan(ctx context.Context, reader io.Reader) (*ScanResult, error) {
+	head := make([]byte, HeuristicScanSize)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("無法讀取檔案內容: %w", err)
+	}
+
+	signature, found := DetectMaliciousContent(head[:n])
+	return &ScanResult{Infected: found, Signature: signature}, nil
+}
+
+// ===== ClamAV =====
+
+// clamdScanner 透過 clamd 的 INSTREAM 指令掃描，支援 TCP 與 Unix socket
+type clamdScanner struct {
+	network string // "tcp" 或 "unix"
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner 創建 clamd 掃描引擎，address 格式為 tcp://host:port 或 unix:///path/to/clamd.sock
+func NewClamdScanner(address string, timeout time.Duration) (*clamdScanner, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("無效的 clamd 位址: %w", err)
+	}
+
+	scanner := &clamdScanner{network: parsed.Scheme, timeout: timeout}
+	switch parsed.Scheme {
+	case "tcp":
+		scanner.address = parsed.Host
+	case "unix":
+		scanner.address = parsed.Path
+	default:
+		return nil, fmt.Errorf("不支援的 clamd 位址類型: %s", parsed.Scheme)
+	}
+	if scanner.address == "" {
+		return nil, fmt.Errorf("無效的 clamd 位址: %s", address)
+	}
+
+	return scanner, nil
+}
+
+// Scan 以 INSTREAM 傳送內容：先送出以 null 結尾的 zINSTREAM 指令，
+// 每個分段前加上 4 位元組大端序長度，最後以長度 0 結束，再讀取以 null 結尾的結果
+func (cs *clamdScanner) Scan(ctx context.Context, reader io.Reader) (*ScanResult, error) {
+	dialer := net.Dialer{Timeout: cs.timeout}
+	conn, err := dialer.DialContext(ctx, cs.network, cs.address)
+	if err != nil {
+		return nil, fmt.Errorf("無法連線到 clamd: %w", err)
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			slog.Warn("無法關閉 clamd 連線", "error", err)
+		}
+	}()
+
+	deadline := time.Now().Add(cs.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("無法設定 clamd 連線逾時: %w", err)
+	}
+
+	// clamd 超過 StreamMaxLength 時會回覆錯誤並關閉連線，寫入失敗時仍嘗試讀取回覆以取得原因
+	writeErr := cs.sendStream(conn, reader)
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		if writeErr != nil {
+			return nil, writeErr
+		}
+		return nil, fmt.Errorf("無法讀取 clamd 回覆: %w", err)
+	}
+
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// sendStream 傳送 INSTREAM 指令與內容
+func (cs *clamdScanner) sendStream(conn net.Conn, reader io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return fmt.Errorf("無法傳送 clamd 指令: %w", err)
+	}
+
+	chunk := make([]byte, ClamdChunkSize)
+	length := make([]byte, 4)
+	for {
+		n, readErr := reader.Read(chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(length, uint32(n)) //nolint:gosec // n 不超過 ClamdChunkSize
+			if _, err := conn.Write(length); err != nil {
+				return fmt.Errorf("無法傳送檔案內容到 clamd: %w", err)
+			}
+			if _, err := conn.Write(chunk[:n]); err != nil {
+				return fmt.Errorf("無法傳送檔案內容到 clamd: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("無法讀取檔案內容: %w", readErr)
+		}
+	}
+
+	binary.BigEndian.PutUint32(length, 0)
+	if _, err := conn.Write(length); err != nil {
+		return fmt.Errorf("無法結束 clamd 傳送: %w", err)
+	}
+	return nil
+}
+
+// parseClamdReply 解析 clamd 回覆，例如 "stream: OK"、"stream: Eicar-Signature FOUND"、"INSTREAM size limit exceeded. ERROR"
+func parseClamdReply(reply string) (*ScanResult, error) {
+	result := strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case result == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	case strings.HasSuffix(result, " ERROR"):
+		return nil, fmt.Errorf("clamd 掃描錯誤: %s", strings.TrimSuffix(result, " ERROR"))
+	default:
+		return nil, fmt.Errorf("無法解析 clamd 回覆: %q", reply)
+	}
+}
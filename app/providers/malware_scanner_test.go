@@ -0,0 +1,196 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClamd 模擬 clamd 的 INSTREAM 指令，內容包含 infectedMarker 時回覆 FOUND
+type fakeClamd struct {
+	listener       net.Listener
+	infectedMarker []byte
+	maxStream      int
+	received       chan []byte
+}
+
+func startFakeClamd(t *testing.T, network string, address string) *fakeClamd {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		t.Skipf("無法啟動模擬 clamd: %v", err)
+	}
+
+	fc := &fakeClamd{
+		listener:       listener,
+		infectedMarker: []byte("EICAR-TEST"),
+		received:       make(chan []byte, 1),
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go fc.handle(conn)
+		}
+	}()
+
+	return fc
+}
+
+func (fc *fakeClamd) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+
+	command, err := reader.ReadString(0)
+	if err != nil || command != "zINSTREAM\x00" {
+		_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+
+	var stream bytes.Buffer
+	length := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(reader, length); err != nil {
+			return
+		}
+		size := binary.BigEndian.Uint32(length)
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&stream, reader, int64(size)); err != nil {
+			return
+		}
+	}
+
+	// 讀完整個串流後才回覆，避免關閉尚有未讀資料的連線時回覆被 RST 丟棄
+	if fc.maxStream > 0 && stream.Len() > fc.maxStream {
+		_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+		return
+	}
+
+	fc.received <- stream.Bytes()
+	if bytes.Contains(stream.Bytes(), fc.infectedMarker) {
+		_, _ = conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		return
+	}
+	_, _ = conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClamdScanner(t *testing.T) {
+	t.Run("TCP 乾淨檔案", func(t *testing.T) {
+		fc := startFakeClamd(t, "tcp", "127.0.0.1:0")
+		scanner, err := NewClamdScanner("tcp://"+fc.listener.Addr().String(), 5*time.Second)
+		assert.NoError(t, err)
+
+		// 超過單一分段大小，確認內容被完整分段傳送
+		content := bytes.Repeat([]byte("a"), ClamdChunkSize*2+10)
+		result, err := scanner.Scan(context.Background(), bytes.NewReader(content))
+
+		assert.NoError(t, err)
+		assert.False(t, result.Infected)
+		assert.Equal(t, content, <-fc.received)
+	})
+
+	t.Run("Unix socket 發現惡意內容", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "clamd.sock")
+		startFakeClamd(t, "unix", socketPath)
+		scanner, err := NewClamdScanner("unix://"+socketPath, 5*time.Second)
+		assert.NoError(t, err)
+
+		result, err := scanner.Scan(context.Background(), strings.NewReader("header EICAR-TEST footer"))
+
+		assert.NoError(t, err)
+		assert.True(t, result.Infected)
+		assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+	})
+
+	t.Run("超過串流上限", func(t *testing.T) {
+		fc := startFakeClamd(t, "tcp", "127.0.0.1:0")
+		fc.maxStream = 1024
+		scanner, err := NewClamdScanner("tcp://"+fc.listener.Addr().String(), 5*time.Second)
+		assert.NoError(t, err)
+
+		result, err := scanner.Scan(context.Background(), bytes.NewReader(make([]byte, 4096)))
+
+		assert.Nil(t, result)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "size limit exceeded")
+	})
+
+	t.Run("無法連線", func(t *testing.T) {
+		scanner, err := NewClamdScanner("unix://"+filepath.Join(t.TempDir(), "missing.sock"), time.Second)
+		assert.NoError(t, err)
+
+		_, err = scanner.Scan(context.Background(), strings.NewReader("content"))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestNewClamdScanner(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		wantErr bool
+	}{
+		{"TCP 位址", "tcp://localhost:3310", false},
+		{"Unix socket", "unix:///var/run/clamav/clamd.ctl", false},
+		{"不支援的類型", "http://localhost:3310", true},
+		{"缺少位址", "tcp://", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewClamdScanner(tt.address, time.Second)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestParseClamdReply(t *testing.T) {
+	result, err := parseClamdReply("stream: OK")
+	assert.NoError(t, err)
+	assert.False(t, result.Infected)
+
+	result, err = parseClamdReply("stream: Win.Test.EICAR_HDB-1 FOUND")
+	assert.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Equal(t, "Win.Test.EICAR_HDB-1", result.Signature)
+
+	_, err = parseClamdReply("INSTREAM size limit exceeded. ERROR")
+	assert.Error(t, err)
+
+	_, err = parseClamdReply("garbage")
+	assert.Error(t, err)
+}
+
+func TestHeuristicScanner(t *testing.T) {
+	scanner := NewHeuristicScanner()
+
+	result, err := scanner.Scan(context.Background(), strings.NewReader("%PDF-1.4 report"))
+	assert.NoError(t, err)
+	assert.False(t, result.Infected)
+
+	result, err = scanner.Scan(context.Background(), strings.NewReader("\x7fELF binary"))
+	assert.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Equal(t, "Heuristic.ELF", result.Signature)
+
+	// 只檢查檔案開頭
+	tail := strings.Repeat(" ", HeuristicScanSize) + "<?php"
+	result, err = scanner.Scan(context.Background(), strings.NewReader(tail))
+	assert.NoError(t, err)
+	assert.False(t, result.Infected)
+}
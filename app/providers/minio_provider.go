@@ -5,8 +5,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,20 +19,35 @@ import (
 )
 
 type minioProvider struct {
-	cfg    *config.Config
-	client *minio.Client
-	bucket string
+	cfg           *config.Config
+	client        *minio.Client
+	presignClient *minio.Client // 以客戶端可連線的位址簽名
+	bucket        string
 }
 
 func NewMinIOProvider(cfg *config.Config) (*minioProvider, error) {
 	client, err := minio.New(cfg.MinIO.Endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(cfg.MinIO.AccessKeyID, cfg.MinIO.SecretAccessKey, ""),
 		Secure: cfg.MinIO.UseSSL,
+		Region: cfg.MinIO.Region,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("初始化 MinIO 客戶端失敗: %w", err)
 	}
 
+	// 預簽名只在本地計算簽名，指定 Region 後不需連線到該位址
+	presignClient := client
+	if cfg.MinIO.PresignEndpoint != "" && cfg.MinIO.PresignEndpoint != cfg.MinIO.Endpoint {
+		presignClient, err = minio.New(cfg.MinIO.PresignEndpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(cfg.MinIO.AccessKeyID, cfg.MinIO.SecretAccessKey, ""),
+			Secure: cfg.MinIO.UseSSL,
+			Region: cfg.MinIO.Region,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("初始化 MinIO 預簽名客戶端失敗: %w", err)
+		}
+	}
+
 	ctx := context.Background()
 	exists, err := client.BucketExists(ctx, cfg.MinIO.BucketName)
 	if err != nil {
@@ -43,9 +62,10 @@ func NewMinIOProvider(cfg *config.Config) (*minioProvider, error) {
 	}
 
 	return &minioProvider{
-		cfg:    cfg,
-		client: client,
-		bucket: cfg.MinIO.BucketName,
+		cfg:           cfg,
+		client:        client,
+		presignClient: presignClient,
+		bucket:        cfg.MinIO.BucketName,
 	}, nil
 }
 
@@ -69,6 +89,20 @@ func (mp *minioProvider) SaveFile(file multipart.File, filename string) (string,
 	return filename, nil
 }
 
+// SaveReader 上傳已知大小的資料流至 MinIO，並保留內容類型
+func (mp *minioProvider) SaveReader(reader io.Reader, size int64, filename string, contentType string) (string, error) {
+	ctx := context.Background()
+
+	_, err := mp.client.PutObject(ctx, mp.bucket, filename, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("上傳至 MinIO 失敗: %w", err)
+	}
+
+	return filename, nil
+}
+
 func (mp *minioProvider) DeleteFile(filepath string) error {
 	ctx := context.Background()
 	err := mp.client.RemoveObject(ctx, mp.bucket, filepath, minio.RemoveObjectOptions{})
@@ -103,6 +137,102 @@ func (mp *minioProvider) GetFile(filepath string) (io.ReadCloser, error) {
 	return object, nil
 }
 
+// core 取得低階 API 客戶端，用於分段上傳
+func (mp *minioProvider) core() minio.Core {
+	return minio.Core{Client: mp.client}
+}
+
+// InitiateChunkedUpload 建立 MinIO multipart upload
+func (mp *minioProvider) InitiateChunkedUpload(filename string, contentType string) (string, error) {
+	ctx := context.Background()
+	uploadID, err := mp.core().NewMultipartUpload(ctx, mp.bucket, filename, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("建立 MinIO 分段上傳失敗: %w", err)
+	}
+	return uploadID, nil
+}
+
+// UploadChunk 上傳單一分段，除最後一段外每段至少需 5MiB（S3 限制）
+func (mp *minioProvider) UploadChunk(filename string, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	ctx := context.Background()
+	part, err := mp.core().PutObjectPart(ctx, mp.bucket, filename, uploadID, partNumber, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", fmt.Errorf("上傳分段至 MinIO 失敗: %w", err)
+	}
+	return part.ETag, nil
+}
+
+// CompleteChunkedUpload 完成 MinIO multipart upload
+func (mp *minioProvider) CompleteChunkedUpload(filename string, uploadID string, parts []ChunkPart) (string, error) {
+	ctx := context.Background()
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{
+			PartNumber: part.Number,
+			ETag:       part.ETag,
+		})
+	}
+
+	if _, err := mp.core().CompleteMultipartUpload(ctx, mp.bucket, filename, uploadID, completeParts, minio.PutObjectOptions{}); err != nil {
+		return "", fmt.Errorf("完成 MinIO 分段上傳失敗: %w", err)
+	}
+	return filename, nil
+}
+
+// AbortChunkedUpload 放棄 MinIO multipart upload，已上傳的分段由 MinIO 清除
+func (mp *minioProvider) AbortChunkedUpload(filename string, uploadID string) error {
+	ctx := context.Background()
+	if err := mp.core().AbortMultipartUpload(ctx, mp.bucket, filename, uploadID); err != nil {
+		return fmt.Errorf("放棄 MinIO 分段上傳失敗: %w", err)
+	}
+	return nil
+}
+
+// PresignPutURL 產生限制內容類型與大小的上傳網址
+// Content-Type 與 Content-Length 包含在簽名中，客戶端送出不同的值時 MinIO 會拒絕請求
+func (mp *minioProvider) PresignPutURL(filename string, contentType string, size int64, expires time.Duration) (string, error) {
+	ctx := context.Background()
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+	headers.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	presignedURL, err := mp.presignClient.PresignHeader(ctx, http.MethodPut, mp.bucket, filename, expires, nil, headers)
+	if err != nil {
+		return "", fmt.Errorf("產生 MinIO 上傳網址失敗: %w", err)
+	}
+	return presignedURL.String(), nil
+}
+
+// PresignGetURL 產生短效下載網址
+func (mp *minioProvider) PresignGetURL(filePath string, expires time.Duration, downloadName string) (string, error) {
+	ctx := context.Background()
+	params := url.Values{}
+	if downloadName != "" {
+		params.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": downloadName}))
+	}
+
+	presignedURL, err := mp.presignClient.PresignedGetObject(ctx, mp.bucket, filePath, expires, params)
+	if err != nil {
+		return "", fmt.Errorf("產生 MinIO 下載網址失敗: %w", err)
+	}
+	return presignedURL.String(), nil
+}
+
+// CopyFile 在 MinIO 內複製物件，內容不經過 API 伺服器
+func (mp *minioProvider) CopyFile(srcPath string, dstPath string) (string, error) {
+	ctx := context.Background()
+	_, err := mp.client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: mp.bucket, Object: dstPath},
+		minio.CopySrcOptions{Bucket: mp.bucket, Object: srcPath},
+	)
+	if err != nil {
+		return "", fmt.Errorf("複製 MinIO 檔案失敗: %w", err)
+	}
+	return dstPath, nil
+}
+
 // minioFileInfo 實作 os.FileInfo 介面
 type minioFileInfo struct {
 	info minio.ObjectInfo
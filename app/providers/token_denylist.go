@@ -0,0 +1,135 @@
+package providers
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// TokenDenylist 記錄已撤銷的 access token，認證時檢查 token 是否仍可使用
+type TokenDenylist interface {
+	// RevokeToken 撤銷單一 access token（以 jti 識別），記錄保留至 token 過期
+	RevokeToken(jti string, expiresAt time.Time) error
+	// RevokeSession 撤銷登入工作階段發放的所有 access token
+	RevokeSession(sessionID string) error
+	// RevokeUserTokens 撤銷用戶目前所有已發放的 access token（之後簽發的 token 不受影響）
+	RevokeUserTokens(userID string) error
+	// IsRevoked 檢查 access token 是否已被撤銷
+	IsRevoked(jti string, sessionID string, userID string, issuedAt time.Time) bool
+}
+
+// CacheTokenDenylist 以 CacheProvider（Redis）實作的撤銷清單，
+// 同時寫入本地記憶體，Redis 無法使用時至少本實例仍能拒絕已撤銷的 token
+type CacheTokenDenylist struct {
+	primary  CacheProvider
+	fallback CacheProvider
+	ttl      time.Duration // 記錄保留時間，需不短於 access token 有效時間
+}
+
+// NewCacheTokenDenylist 建立一個新的 CacheTokenDenylist，ttl 為 access token 的有效時間
+func NewCacheTokenDenylist(primary CacheProvider, ttl time.Duration) *CacheTokenDenylist {
+	return &CacheTokenDenylist{
+		primary:  primary,
+		fallback: NewInMemoryCacheProvider(),
+		ttl:      ttl,
+	}
+}
+
+// legacyRevokedAtThreshold 小於此值的撤銷時間為升級前以秒記錄的值（毫秒時間戳已超過 10^12）
+const legacyRevokedAtThreshold = 1_000_000_000_000
+
+func tokenDenylistKey(kind string, id string) string {
+	return fmt.Sprintf("token_denylist:%s:%s", kind, id)
+}
+
+// RevokeToken 撤銷單一 access token
+func (d *CacheTokenDenylist) RevokeToken(jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return d.set(tokenDenylistKey("jti", jti), "1", ttl)
+}
+
+// RevokeSession 撤銷登入工作階段發放的所有 access token
+func (d *CacheTokenDenylist) RevokeSession(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+	return d.set(tokenDenylistKey("session", sessionID), "1", d.ttl)
+}
+
+// RevokeUserTokens 記錄撤銷時間點（毫秒），在此時間點（含）之前簽發的 token 皆失效
+// 與撤銷同一毫秒內簽發的 token 也會失效，客戶端重新登入即可
+func (d *CacheTokenDenylist) RevokeUserTokens(userID string) error {
+	if userID == "" {
+		return nil
+	}
+	revokedAt := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	return d.set(tokenDenylistKey("user", userID), revokedAt, d.ttl)
+}
+
+// IsRevoked 檢查 access token 是否已被撤銷
+func (d *CacheTokenDenylist) IsRevoked(jti string, sessionID string, userID string, issuedAt time.Time) bool {
+	if jti != "" && d.get(tokenDenylistKey("jti", jti)) != "" {
+		return true
+	}
+	if sessionID != "" && d.get(tokenDenylistKey("session", sessionID)) != "" {
+		return true
+	}
+
+	if userID != "" {
+		if value := d.get(tokenDenylistKey("user", userID)); value != "" {
+			revokedAt, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				slog.Error("token 撤銷清單的撤銷時間格式錯誤", "user_id", userID, "value", value, "error", err)
+				return false
+			}
+			// 升級前以秒記錄的撤銷時間，轉換為毫秒比較
+			if revokedAt < legacyRevokedAtThreshold {
+				revokedAt *= 1000
+			}
+			// 舊版 token 沒有簽發時間，撤銷時間點之後仍無法判斷，一律視為已撤銷
+			if issuedAt.IsZero() || issuedAt.UnixMilli() <= revokedAt {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// set 同時寫入 Redis 與本地記憶體，Redis 寫入失敗時僅記錄警告
+func (d *CacheTokenDenylist) set(key string, value string, ttl time.Duration) error {
+	if err := d.fallback.Set(key, value, ttl); err != nil {
+		return err
+	}
+	if d.primary != nil {
+		if err := d.primary.Set(key, value, ttl); err != nil {
+			slog.Warn("寫入 Redis token 撤銷清單失敗，僅本實例生效", "key", key, "error", err)
+		}
+	}
+	return nil
+}
+
+// get 優先讀取本地記憶體，再查詢 Redis（其他實例撤銷的 token）
+func (d *CacheTokenDenylist) get(key string) string {
+	if value, _ := d.fallback.Get(key); value != "" {
+		return value
+	}
+	if d.primary == nil {
+		return ""
+	}
+	value, err := d.primary.Get(key)
+	if err != nil {
+		// Redis 錯誤時放行，避免全面阻斷服務（與 RateLimiter 相同的 fail-open 策略），
+		// 記錄錯誤以便察覺其他實例撤銷的 token 暫時未被拒絕
+		slog.Error("讀取 Redis token 撤銷清單失敗，暫時放行", "key", key, "error", err)
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingCacheProvider 模擬無法連線的 Redis
+type failingCacheProvider struct{}
+
+func (p *failingCacheProvider) Get(key string) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func (p *failingCacheProvider) Set(key string, value string, expiration time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (p *failingCacheProvider) Delete(key string) error {
+	return errors.New("connection refused")
+}
+
+func (p *failingCacheProvider) SetNX(key string, value string, expiration time.Duration) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func (p *failingCacheProvider) Incr(key string, expiration time.Duration) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestCacheTokenDenylist_RevokeToken(t *testing.T) {
+	denylist := NewCacheTokenDenylist(NewInMemoryCacheProvider(), time.Hour)
+
+	assert.NoError(t, denylist.RevokeToken("jti-1", time.Now().Add(time.Minute)))
+
+	assert.True(t, denylist.IsRevoked("jti-1", "", "", time.Now()))
+	assert.False(t, denylist.IsRevoked("jti-2", "", "", time.Now()))
+
+	// 已過期的 token 不需記錄
+	assert.NoError(t, denylist.RevokeToken("jti-expired", time.Now().Add(-time.Minute)))
+	assert.False(t, denylist.IsRevoked("jti-expired", "", "", time.Now()))
+}
+
+func TestCacheTokenDenylist_RevokeSession(t *testing.T) {
+	denylist := NewCacheTokenDenylist(NewInMemoryCacheProvider(), time.Hour)
+
+	assert.NoError(t, denylist.RevokeSession("session-1"))
+
+	assert.True(t, denylist.IsRevoked("jti-1", "session-1", "user-1", time.Now()))
+	assert.False(t, denylist.IsRevoked("jti-1", "session-2", "user-1", time.Now()))
+}
+
+func TestCacheTokenDenylist_RevokeUserTokens(t *testing.T) {
+	denylist := NewCacheTokenDenylist(NewInMemoryCacheProvider(), time.Hour)
+
+	assert.NoError(t, denylist.RevokeUserTokens("user-1"))
+
+	// 撤銷前簽發的 token 失效，撤銷後簽發的不受影響（包含同一秒內稍後簽發的 token）
+	assert.True(t, denylist.IsRevoked("jti-1", "", "user-1", time.Now().Add(-time.Minute)))
+	assert.False(t, denylist.IsRevoked("jti-2", "", "user-1", time.Now().Add(2*time.Second)))
+	assert.False(t, denylist.IsRevoked("jti-3", "", "user-1", time.Now().Add(10*time.Millisecond)))
+	assert.False(t, denylist.IsRevoked("jti-1", "", "user-2", time.Now().Add(-time.Minute)))
+
+	// 沒有簽發時間的舊版 token 一律視為已撤銷
+	assert.True(t, denylist.IsRevoked("", "", "user-1", time.Time{}))
+}
+
+func TestCacheTokenDenylist_LegacySecondsRevokedAt(t *testing.T) {
+	cache := NewInMemoryCacheProvider()
+	denylist := NewCacheTokenDenylist(cache, time.Hour)
+
+	// 升級前以秒記錄的撤銷時間仍然有效
+	revokedAt := time.Now()
+	assert.NoError(t, cache.Set(tokenDenylistKey("user", "user-1"), strconv.FormatInt(revokedAt.Unix(), 10), time.Hour))
+
+	assert.True(t, denylist.IsRevoked("jti-1", "", "user-1", revokedAt.Add(-time.Minute)))
+	assert.False(t, denylist.IsRevoked("jti-2", "", "user-1", revokedAt.Add(2*time.Second)))
+}
+
+func TestCacheTokenDenylist_SharedThroughPrimary(t *testing.T) {
+	// 其他實例寫入 Redis 的撤銷記錄
+	shared := NewInMemoryCacheProvider()
+	instanceA := NewCacheTokenDenylist(shared, time.Hour)
+	instanceB := NewCacheTokenDenylist(shared, time.Hour)
+
+	assert.NoError(t, instanceA.RevokeSession("session-1"))
+
+	assert.True(t, instanceB.IsRevoked("", "session-1", "", time.Now()))
+}
+
+func TestCacheTokenDenylist_FallbackWhenPrimaryFails(t *testing.T) {
+	denylist := NewCacheTokenDenylist(&failingCacheProvider{}, time.Hour)
+
+	// Redis 寫入失敗時仍記錄於本地記憶體
+	assert.NoError(t, denylist.RevokeToken("jti-1", time.Now().Add(time.Minute)))
+	assert.True(t, denylist.IsRevoked("jti-1", "", "", time.Now()))
+
+	// Redis 讀取失敗時放行未知的 token
+	assert.False(t, denylist.IsRevoked("jti-2", "", "", time.Now()))
+}
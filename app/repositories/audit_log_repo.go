@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type auditLogRepository struct {
+	odm providers.ODM
+}
+
+func NewAuditLogRepository(odm providers.ODM) *auditLogRepository {
+	return &auditLogRepository{
+		odm: odm,
+	}
+}
+
+// CreateAuditLog 寫入稽核紀錄
+func (r *auditLogRepository) CreateAuditLog(entry *models.AuditLog) error {
+	ctx := context.Background()
+	err := r.odm.Create(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("寫入稽核紀錄失敗: %v", err)
+	}
+	return nil
+}
+
+// GetAuditLogs 依條件獲取伺服器稽核紀錄（新到舊）
+func (r *auditLogRepository) GetAuditLogs(serverID string, query models.AuditLogQuery, limit int64) ([]models.AuditLog, error) {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	ctx := context.Background()
+	qb := providers.NewQueryBuilder()
+	qb.Where("server_id", serverObjectID)
+
+	if query.Action != "" {
+		qb.Where("action", query.Action)
+	}
+
+	if query.ActorID != "" {
+		actorObjectID, err := primitive.ObjectIDFromHex(query.ActorID)
+		if err != nil {
+			return nil, fmt.Errorf("無效的操作者ID: %v", err)
+		}
+		qb.Where("actor_id", actorObjectID)
+	}
+
+	// 游標分頁：只取比 before 更舊的紀錄
+	if query.Before != "" {
+		beforeObjectID, err := primitive.ObjectIDFromHex(query.Before)
+		if err != nil {
+			return nil, fmt.Errorf("無效的游標: %v", err)
+		}
+		qb.WhereLt("_id", beforeObjectID)
+	}
+
+	qb.SortDesc("_id").Limit(limit)
+
+	var entries []models.AuditLog
+	err = r.odm.FindWithOptions(ctx, qb.GetFilter(), &entries, qb.GetQueryOptions())
+	if err != nil {
+		return nil, fmt.Errorf("查詢稽核紀錄失敗: %v", err)
+	}
+
+	return entries, nil
+}
@@ -5,9 +5,11 @@ import (
 	"chat_app_backend/app/providers"
 	"context"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type channelCategoryRepository struct {
@@ -56,7 +58,7 @@ func (r *channelCategoryRepository) GetChannelCategoryByID(categoryID string) (*
 
 	err := r.odm.FindByID(ctx, categoryID, &category)
 	if err != nil {
-		return nil, fmt.Errorf("查詢頻道類別失敗: %v", err)
+		return nil, fmt.Errorf("查詢頻道類別失敗: %w", err)
 	}
 
 	return &category, nil
@@ -104,3 +106,37 @@ func (r *channelCategoryRepository) CheckChannelCategoryExists(categoryID string
 
 	return exists, nil
 }
+
+// UpdateChannelCategoryPositions 以單次批量寫入更新頻道類別的排序位置
+// 篩選條件包含伺服器ID，避免誤改其他伺服器的類別；有類別未匹配時返回錯誤
+func (r *channelCategoryRepository) UpdateChannelCategoryPositions(serverID string, positions []models.CategoryPosition) error {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	now := time.Now()
+	operations := make([]mongo.WriteModel, 0, len(positions))
+	for _, position := range positions {
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": position.CategoryID, "server_id": serverObjectID}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"position":   position.Position,
+				"updated_at": now,
+			}}))
+	}
+
+	result, err := r.odm.BulkWrite(context.Background(), operations, &models.ChannelCategory{})
+	if err != nil {
+		return fmt.Errorf("批量更新頻道類別排序失敗: %v", err)
+	}
+	if result.MatchedCount != int64(len(positions)) {
+		return fmt.Errorf("批量更新頻道類別排序失敗: 僅匹配 %d/%d 個類別", result.MatchedCount, len(positions))
+	}
+
+	return nil
+}
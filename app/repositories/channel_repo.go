@@ -5,8 +5,12 @@ import (
 	"chat_app_backend/app/providers"
 	"chat_app_backend/config"
 	"context"
+	"fmt"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type channelRepository struct {
@@ -41,9 +45,9 @@ func (cr *channelRepository) GetChannelsByServerID(serverID string) ([]models.Ch
 }
 
 // GetChannelByID 根據頻道ID獲取頻道
-func (cr *channelRepository) GetChannelByID(channelID string) (*models.Channel, error) {
+func (cr *channelRepository) GetChannelByID(ctx context.Context, channelID string) (*models.Channel, error) {
 	var channel models.Channel
-	err := cr.odm.FindByID(context.Background(), channelID, &channel)
+	err := cr.odm.FindByID(ctx, channelID, &channel)
 	if err != nil {
 		return nil, err
 	}
@@ -98,3 +102,38 @@ func (cr *channelRepository) CheckChannelExists(channelID string) (bool, error)
 	}
 	return true, nil
 }
+
+// UpdateChannelPositions 以單次批量寫入更新頻道的類別與排序位置
+// 篩選條件包含伺服器ID，避免誤改其他伺服器的頻道；有頻道未匹配時返回錯誤
+func (cr *channelRepository) UpdateChannelPositions(serverID string, positions []models.ChannelPosition) error {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	serverObjID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	now := time.Now()
+	operations := make([]mongo.WriteModel, 0, len(positions))
+	for _, position := range positions {
+		operations = append(operations, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": position.ChannelID, "server_id": serverObjID}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"category_id": position.CategoryID,
+				"position":    position.Position,
+				"updated_at":  now,
+			}}))
+	}
+
+	result, err := cr.odm.BulkWrite(context.Background(), operations, &models.Channel{})
+	if err != nil {
+		return fmt.Errorf("批量更新頻道排序失敗: %v", err)
+	}
+	if result.MatchedCount != int64(len(positions)) {
+		return fmt.Errorf("批量更新頻道排序失敗: 僅匹配 %d/%d 個頻道", result.MatchedCount, len(positions))
+	}
+
+	return nil
+}
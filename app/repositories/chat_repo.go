@@ -188,3 +188,145 @@ func (cr *chatRepository) DeleteMessagesByRoomID(ctx context.Context, roomID str
 
 	return nil
 }
+
+// GetRoomReadStates 取得用戶在多個房間的已讀狀態（最後已讀訊息與未讀數量）
+func (cr *chatRepository) GetRoomReadStates(ctx context.Context, userID string, roomIDs []string) (map[string]models.RoomReadState, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roomObjectIDs := make([]primitive.ObjectID, 0, len(roomIDs))
+	for _, roomID := range roomIDs {
+		roomObjectID, err := primitive.ObjectIDFromHex(roomID)
+		if err != nil {
+			return nil, err
+		}
+		roomObjectIDs = append(roomObjectIDs, roomObjectID)
+	}
+
+	states := make(map[string]models.RoomReadState, len(roomIDs))
+	if len(roomObjectIDs) == 0 {
+		return states, nil
+	}
+
+	// 取得用戶在這些房間的已讀紀錄
+	var reads []models.RoomReads
+	err = cr.odm.Find(ctx, bson.M{
+		"user_id": userObjectID,
+		"room_id": bson.M{"$in": roomObjectIDs},
+	}, &reads)
+	if err != nil {
+		return nil, err
+	}
+
+	// 以 $switch 依房間取出最後已讀訊息ID，沒有已讀紀錄的房間從頭計算
+	lastReadBranches := make(bson.A, 0, len(reads))
+	for _, read := range reads {
+		if read.LastReadMessageID.IsZero() {
+			continue
+		}
+		lastReadBranches = append(lastReadBranches, bson.M{
+			"case": bson.M{"$eq": bson.A{"$room_id", read.RoomID}},
+			"then": read.LastReadMessageID,
+		})
+		states[read.RoomID.Hex()] = models.RoomReadState{LastReadMessageID: read.LastReadMessageID.Hex()}
+	}
+
+	// 一次聚合計算所有房間的未讀數量（不含自己發送與已刪除的訊息）
+	match := bson.M{
+		"room_id":    bson.M{"$in": roomObjectIDs},
+		"sender_id":  bson.M{"$ne": userObjectID},
+		"is_deleted": bson.M{"$ne": true},
+	}
+	if len(lastReadBranches) > 0 {
+		match["$expr"] = bson.M{"$gt": bson.A{"$_id", bson.M{"$switch": bson.M{
+			"branches": lastReadBranches,
+			"default":  primitive.NilObjectID,
+		}}}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": "$room_id", "count": bson.M{"$sum": 1}}}},
+	}
+
+	var counts []struct {
+		RoomID primitive.ObjectID `bson:"_id"`
+		Count  int64              `bson:"count"`
+	}
+	if err := cr.odm.Aggregate(ctx, pipeline, &counts, &models.Message{}); err != nil {
+		return nil, err
+	}
+
+	unreadByRoom := make(map[primitive.ObjectID]int64, len(counts))
+	for _, count := range counts {
+		unreadByRoom[count.RoomID] = count.Count
+	}
+
+	for _, roomObjectID := range roomObjectIDs {
+		state := states[roomObjectID.Hex()]
+		state.UnreadCount = unreadByRoom[roomObjectID]
+		states[roomObjectID.Hex()] = state
+	}
+
+	return states, nil
+}
+
+// SearchMessages 在指定房間範圍內以全文索引搜尋訊息，依訊息ID由新到舊排序
+// request 中的 ID 與時間範圍需已由呼叫端驗證，房間範圍為空時直接回傳空結果
+func (cr *chatRepository) SearchMessages(ctx context.Context, roomIDs []string, request models.MessageSearchRequest, limit int64) ([]models.Message, error) {
+	roomObjectIDs := make([]primitive.ObjectID, 0, len(roomIDs))
+	for _, roomID := range roomIDs {
+		roomObjectID, err := primitive.ObjectIDFromHex(roomID)
+		if err != nil {
+			return nil, err
+		}
+		roomObjectIDs = append(roomObjectIDs, roomObjectID)
+	}
+
+	if len(roomObjectIDs) == 0 {
+		return []models.Message{}, nil
+	}
+
+	qb := providers.NewQueryBuilder()
+	qb.Where("$text", bson.M{"$search": request.Query}).
+		WhereIn("room_id", roomObjectIDs).
+		WhereNe("is_deleted", true)
+
+	if request.SenderID != "" {
+		senderObjectID, err := primitive.ObjectIDFromHex(request.SenderID)
+		if err != nil {
+			return nil, err
+		}
+		qb.Where("sender_id", senderObjectID)
+	}
+
+	// 日期範圍（毫秒時間戳）
+	switch {
+	case request.From > 0 && request.To > 0:
+		qb.WhereBetween("created_at", time.UnixMilli(request.From), time.UnixMilli(request.To))
+	case request.From > 0:
+		qb.WhereGte("created_at", time.UnixMilli(request.From))
+	case request.To > 0:
+		qb.WhereLte("created_at", time.UnixMilli(request.To))
+	}
+
+	// 游標分頁：只取比 before 更舊的訊息
+	if request.Before != "" {
+		beforeObjectID, err := primitive.ObjectIDFromHex(request.Before)
+		if err != nil {
+			return nil, err
+		}
+		qb.WhereLt("_id", beforeObjectID)
+	}
+
+	qb.SortDesc("_id").Limit(limit)
+
+	var messages []models.Message
+	err := cr.odm.FindWithOptions(ctx, qb.GetFilter(), &messages, qb.GetQueryOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
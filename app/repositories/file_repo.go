@@ -5,10 +5,15 @@ import (
 	"chat_app_backend/app/providers"
 	"chat_app_backend/config"
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type fileRepository struct {
@@ -23,9 +28,13 @@ func NewFileRepository(cfg *config.Config, odm providers.ODM) FileRepository {
 	}
 }
 
-// CreateFile 創建檔案記錄
+// CreateFile 創建檔案記錄並計入上傳者（與指定伺服器）的儲存用量
 func (fr *fileRepository) CreateFile(file *models.UploadedFile) error {
-	return fr.odm.Create(context.Background(), file)
+	if err := fr.odm.Create(context.Background(), file); err != nil {
+		return err
+	}
+	fr.adjustStorageUsage(file, 1)
+	return nil
 }
 
 // GetFileByID 根據檔案ID獲取檔案
@@ -51,6 +60,26 @@ func (fr *fileRepository) GetFileByPath(filePath string) (*models.UploadedFile,
 	return &file, nil
 }
 
+// GetVerifiedFilesByStoragePath 獲取引用指定儲存路徑（原檔或縮圖）且未標記清理的已驗證檔案記錄
+// 相同內容的檔案共用儲存路徑，可能返回多筆記錄
+func (fr *fileRepository) GetVerifiedFilesByStoragePath(filePath string) ([]models.UploadedFile, error) {
+	qb := providers.NewQueryBuilder()
+	qb.OrWhere([]bson.M{
+		{"file_path": filePath},
+		{"variants.file_path": filePath},
+	})
+	qb.Where("status", "verified")
+	qb.WhereIsNull("expires_at")
+
+	var files []models.UploadedFile
+	err := fr.odm.Find(context.Background(), qb.GetFilter(), &files)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
 // GetFilesByUserID 根據用戶ID獲取檔案列表
 func (fr *fileRepository) GetFilesByUserID(userID string) ([]models.UploadedFile, error) {
 	userObjID, err := primitive.ObjectIDFromHex(userID)
@@ -83,24 +112,217 @@ func (fr *fileRepository) UpdateFileStatus(fileID string, status string) error {
 	return fr.odm.UpdateFields(context.Background(), &file, updates)
 }
 
-// DeleteFileByID 根據檔案ID刪除檔案記錄
-func (fr *fileRepository) DeleteFileByID(fileID string) error {
+// UpdateFile 更新檔案記錄欄位
+func (fr *fileRepository) UpdateFile(fileID string, updates map[string]any) error {
 	var file models.UploadedFile
-	return fr.odm.DeleteByID(context.Background(), fileID, &file)
+	err := fr.odm.FindByID(context.Background(), fileID, &file)
+	if err != nil {
+		return err
+	}
+
+	return fr.odm.UpdateFields(context.Background(), &file, updates)
+}
+
+// SetFileExpiry 設定檔案過期時間
+func (fr *fileRepository) SetFileExpiry(fileID string, expiresAt time.Time) error {
+	var file models.UploadedFile
+	err := fr.odm.FindByID(context.Background(), fileID, &file)
+	if err != nil {
+		return err
+	}
+
+	updates := bson.M{"expires_at": expiresAt}
+	return fr.odm.UpdateFields(context.Background(), &file, updates)
+}
+
+// DeleteFileByID 根據檔案ID刪除檔案記錄並扣除儲存用量
+func (fr *fileRepository) DeleteFileByID(fileID string) error {
+	fileObjID, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return err
+	}
+	return fr.deleteFile(bson.M{"_id": fileObjID})
 }
 
-// DeleteFileByPath 根據檔案路徑刪除檔案記錄
+// DeleteFileByPath 根據檔案路徑刪除檔案記錄並扣除儲存用量
 func (fr *fileRepository) DeleteFileByPath(filePath string) error {
+	return fr.deleteFile(bson.M{"file_path": filePath})
+}
+
+// deleteFile 刪除符合條件的一筆檔案記錄，只有實際刪除的記錄才扣除用量，重複刪除不會重複扣除
+func (fr *fileRepository) deleteFile(filter bson.M) error {
+	var file models.UploadedFile
+	err := fr.odm.Collection(&file).FindOneAndDelete(context.Background(), filter).Decode(&file)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return providers.ErrDocumentNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	fr.adjustStorageUsage(&file, -1)
+	return nil
+}
+
+// adjustStorageUsage 增量更新檔案擁有者的儲存用量，direction 為 1（新增）或 -1（刪除）
+// 檔案記錄已寫入，用量更新失敗只記錄日誌，不影響檔案操作
+func (fr *fileRepository) adjustStorageUsage(file *models.UploadedFile, direction int64) {
+	owners := map[string]primitive.ObjectID{models.StorageOwnerUser: file.UserID}
+	if file.ServerID != nil {
+		owners[models.StorageOwnerServer] = *file.ServerID
+	}
+
+	now := time.Now()
+	for ownerType, ownerID := range owners {
+		filter := bson.M{"owner_type": ownerType, "owner_id": ownerID}
+		update := bson.M{
+			"$inc":         bson.M{"total_size": direction * file.FileSize, "file_count": direction},
+			"$set":         bson.M{"updated_at": now},
+			"$setOnInsert": bson.M{"created_at": now},
+		}
+		_, err := fr.odm.Collection(&models.StorageUsage{}).UpdateOne(context.Background(), filter, update, options.Update().SetUpsert(true))
+		if err != nil {
+			slog.Error("更新儲存用量失敗", "owner_type", ownerType, "owner_id", ownerID.Hex(), "file_id", file.ID.Hex(), "error", err)
+		}
+	}
+}
+
+// GetStorageUsage 獲取用戶或伺服器的儲存用量，尚未上傳過檔案時返回零用量
+func (fr *fileRepository) GetStorageUsage(ownerType string, ownerID string) (*models.StorageUsage, error) {
+	ownerObjID, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return nil, err
+	}
+
 	qb := providers.NewQueryBuilder()
-	qb.Where("file_path", filePath)
+	qb.Where("owner_type", ownerType)
+	qb.Where("owner_id", ownerObjID)
+
+	var usage models.StorageUsage
+	err = fr.odm.FindOne(context.Background(), qb.GetFilter(), &usage)
+	if errors.Is(err, providers.ErrDocumentNotFound) {
+		return &models.StorageUsage{OwnerType: ownerType, OwnerID: ownerObjID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &usage, nil
+}
+
+// GetVerifiedFileByHash 根據雜湊獲取一筆已驗證的檔案
+func (fr *fileRepository) GetVerifiedFileByHash(hash string) (*models.UploadedFile, error) {
+	qb := providers.NewQueryBuilder()
+	qb.Where("hash", hash)
+	qb.Where("status", "verified")
 
 	var file models.UploadedFile
 	err := fr.odm.FindOne(context.Background(), qb.GetFilter(), &file)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return &file, nil
+}
+
+// AcquireBlob 為已存在且仍被引用的內容增加引用數，內容不存在時返回 nil
+// 引用數已歸零的內容即將被刪除，不可再次引用
+func (fr *fileRepository) AcquireBlob(hash string) (*models.FileBlob, error) {
+	filter := bson.M{
+		"hash":      hash,
+		"ref_count": bson.M{"$gt": 0},
 	}
+	update := bson.M{
+		"$inc": bson.M{"ref_count": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var blob models.FileBlob
+	err := fr.odm.Collection(&blob).FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&blob)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("增加內容引用數失敗: %v", err)
+	}
+	return &blob, nil
+}
+
+// RegisterBlob 記錄新儲存的內容並增加引用數
+// 並發上傳相同內容時只有第一筆的路徑會被記錄，其餘呼叫者返回的路徑與自己儲存的不同，需自行刪除多餘的檔案
+func (fr *fileRepository) RegisterBlob(blob *models.FileBlob) (*models.FileBlob, error) {
+	now := time.Now()
+	filter := bson.M{"hash": blob.Hash}
+	update := bson.M{
+		"$inc": bson.M{"ref_count": 1},
+		"$set": bson.M{"updated_at": now},
+		"$setOnInsert": bson.M{
+			"file_path":  blob.FilePath,
+			"file_size":  blob.FileSize,
+			"mime_type":  blob.MimeType,
+			"created_at": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
 
-	return fr.odm.Delete(context.Background(), &file)
+	var result models.FileBlob
+	err := fr.odm.Collection(blob).FindOneAndUpdate(context.Background(), filter, update, opts).Decode(&result)
+	if err != nil {
+		return nil, fmt.Errorf("記錄檔案內容失敗: %v", err)
+	}
+	return &result, nil
+}
+
+// ReleaseBlob 減少指定路徑內容的引用數並返回更新後的內容
+// 沒有對應的內容記錄（去重前上傳的檔案）時返回 nil
+func (fr *fileRepository) ReleaseBlob(hash string, filePath string) (*models.FileBlob, error) {
+	ctx := context.Background()
+	filter := bson.M{
+		"hash":      hash,
+		"file_path": filePath,
+		"ref_count": bson.M{"$gt": 0},
+	}
+	update := bson.M{
+		"$inc": bson.M{"ref_count": -1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var blob models.FileBlob
+	err := fr.odm.Collection(&blob).FindOneAndUpdate(ctx, filter, update, opts).Decode(&blob)
+	if err == nil {
+		return &blob, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("減少內容引用數失敗: %v", err)
+	}
+
+	// 引用數已歸零但記錄仍在（先前的刪除中斷），返回記錄讓呼叫者完成清理
+	qb := providers.NewQueryBuilder()
+	qb.Where("hash", hash)
+	qb.Where("file_path", filePath)
+	err = fr.odm.FindOne(ctx, qb.GetFilter(), &blob)
+	if errors.Is(err, providers.ErrDocumentNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// DeleteBlobIfUnreferenced 在引用數歸零時刪除內容記錄，返回是否已刪除
+// 刪除與引用數檢查在同一個條件中，避免刪除期間剛被重新引用的內容
+func (fr *fileRepository) DeleteBlobIfUnreferenced(hash string) (bool, error) {
+	filter := bson.M{
+		"hash":      hash,
+		"ref_count": bson.M{"$lte": 0},
+	}
+
+	result, err := fr.odm.Collection(&models.FileBlob{}).DeleteOne(context.Background(), filter)
+	if err != nil {
+		return false, fmt.Errorf("刪除檔案內容記錄失敗: %v", err)
+	}
+	return result.DeletedCount > 0, nil
 }
 
 // GetExpiredFiles 獲取過期檔案列表
@@ -120,14 +342,18 @@ func (fr *fileRepository) GetExpiredFiles() ([]models.UploadedFile, error) {
 	return files, nil
 }
 
-// CleanupExpiredFiles 清理過期檔案記錄
+// CleanupExpiredFiles 清理過期檔案記錄，逐筆刪除以扣除儲存用量
 func (fr *fileRepository) CleanupExpiredFiles() error {
-	now := time.Now()
-
-	qb := providers.NewQueryBuilder()
-	qb.WhereLt("expires_at", now)
-	qb.WhereNotNull("expires_at")
+	files, err := fr.GetExpiredFiles()
+	if err != nil {
+		return err
+	}
 
-	var file models.UploadedFile
-	return fr.odm.DeleteMany(context.Background(), &file, qb.GetFilter())
+	for _, file := range files {
+		err := fr.deleteFile(bson.M{"_id": file.ID})
+		if err != nil && !errors.Is(err, providers.ErrDocumentNotFound) {
+			return err
+		}
+	}
+	return nil
 }
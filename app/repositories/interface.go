@@ -3,6 +3,7 @@ package repositories
 import (
 	"chat_app_backend/app/models"
 	"context"
+	"time"
 )
 
 type ChatRepository interface {
@@ -18,6 +19,12 @@ type ChatRepository interface {
 	// UpdateDMRoom 更新聊天列表的刪除狀態
 	UpdateDMRoom(ctx context.Context, userID string, chatWithUserID string, IsHidden bool) error
 
+	// GetRoomReadStates 取得用戶在多個房間的已讀狀態（最後已讀訊息與未讀數量）
+	GetRoomReadStates(ctx context.Context, userID string, roomIDs []string) (map[string]models.RoomReadState, error)
+
+	// SearchMessages 在指定房間範圍內以全文索引搜尋訊息，依訊息ID由新到舊排序
+	SearchMessages(ctx context.Context, roomIDs []string, request models.MessageSearchRequest, limit int64) ([]models.Message, error)
+
 	// SaveOrUpdateDMRoom 保存或更新聊天列表
 	SaveOrUpdateDMRoom(ctx context.Context, chat models.DMRoom) (models.DMRoom, error)
 
@@ -45,7 +52,7 @@ type ServerRepository interface {
 	DeleteServer(serverID string) error
 
 	// GetServerByID 根據ID獲取伺服器
-	GetServerByID(serverID string) (*models.Server, error)
+	GetServerByID(ctx context.Context, serverID string) (*models.Server, error)
 
 	// UpdateMemberCount 更新成員數量快取
 	UpdateMemberCount(serverID string, count int) error
@@ -72,6 +79,38 @@ type ServerMemberRepository interface {
 
 	// GetMemberCount 獲取伺服器成員數量
 	GetMemberCount(serverID string) (int64, error)
+
+	// GetServerMember 獲取用戶在伺服器中的成員資料
+	GetServerMember(ctx context.Context, serverID, userID string) (*models.ServerMember, error)
+
+	// AddRoleToMember 為成員指派角色
+	AddRoleToMember(ctx context.Context, serverID, userID, roleID string) error
+
+	// RemoveRoleFromMember 移除成員的角色
+	RemoveRoleFromMember(ctx context.Context, serverID, userID, roleID string) error
+}
+
+type RoleRepository interface {
+	// CreateRole 創建伺服器角色
+	CreateRole(ctx context.Context, role *models.ServerRole) error
+
+	// GetRolesByServerID 根據伺服器ID獲取角色列表
+	GetRolesByServerID(ctx context.Context, serverID string) ([]models.ServerRole, error)
+
+	// GetRoleByID 根據角色ID獲取角色
+	GetRoleByID(ctx context.Context, roleID string) (*models.ServerRole, error)
+
+	// GetRolesByIDs 根據角色ID陣列獲取角色
+	GetRolesByIDs(ctx context.Context, roleIDs []string) ([]models.ServerRole, error)
+
+	// UpdateRole 更新角色
+	UpdateRole(ctx context.Context, roleID string, updates map[string]any) error
+
+	// DeleteRole 刪除角色並移除所有成員的指派與頻道覆寫
+	DeleteRole(ctx context.Context, roleID string) error
+
+	// CountRolesByServerID 獲取伺服器角色數量
+	CountRolesByServerID(ctx context.Context, serverID string) (int64, error)
 }
 
 type UserRepository interface {
@@ -104,6 +143,24 @@ type UserRepository interface {
 
 	// DeleteUser 刪除用戶
 	DeleteUser(userID string) error
+
+	// GetUserCredentials 獲取包含兩步驟驗證密鑰等敏感欄位的用戶資料（不經過快取）
+	GetUserCredentials(userID string) (*models.User, error)
+
+	// ConsumeTOTPCounter 記錄已使用的 TOTP 時間步，時間步不大於上次使用的值時返回 false（防止重放）
+	ConsumeTOTPCounter(userID string, counter int64) (bool, error)
+
+	// ConsumeRecoveryCode 移除一組復原碼雜湊，復原碼不存在或已使用時返回 false
+	ConsumeRecoveryCode(userID string, codeHash string) (bool, error)
+
+	// MarkRefreshTokenRotated 將有效的 refresh token 標記為已輪替，token 已被撤銷或輪替時返回 false
+	MarkRefreshTokenRotated(tokenID string, replacedByID string) (bool, error)
+
+	// GetUserByEmail 根據電子郵件獲取包含敏感欄位的用戶資料（不經過快取）
+	GetUserByEmail(email string) (*models.User, error)
+
+	// ConsumeUserToken 清除用戶記錄的一次性 token ID 並套用更新，token ID 不符（已使用或已被新 token 取代）時返回 false
+	ConsumeUserToken(userID string, field string, tokenID string, updates map[string]any) (bool, error)
 }
 
 type FriendRepository interface {
@@ -116,7 +173,7 @@ type ChannelRepository interface {
 	GetChannelsByServerID(serverID string) ([]models.Channel, error)
 
 	// GetChannelByID 根據頻道ID獲取頻道
-	GetChannelByID(channelID string) (*models.Channel, error)
+	GetChannelByID(ctx context.Context, channelID string) (*models.Channel, error)
 
 	// CreateChannel 創建新頻道
 	CreateChannel(channel *models.Channel) error
@@ -129,10 +186,13 @@ type ChannelRepository interface {
 
 	// CheckChannelExists 檢查頻道是否存在
 	CheckChannelExists(channelID string) (bool, error)
+
+	// UpdateChannelPositions 以單次批量寫入更新頻道的類別與排序位置（僅限指定伺服器內的頻道）
+	UpdateChannelPositions(serverID string, positions []models.ChannelPosition) error
 }
 
 type FileRepository interface {
-	// CreateFile 創建檔案記錄
+	// CreateFile 創建檔案記錄並計入儲存用量
 	CreateFile(file *models.UploadedFile) error
 
 	// GetFileByID 根據檔案ID獲取檔案
@@ -141,23 +201,101 @@ type FileRepository interface {
 	// GetFileByPath 根據檔案路徑獲取檔案
 	GetFileByPath(filePath string) (*models.UploadedFile, error)
 
+	// GetVerifiedFilesByStoragePath 獲取引用指定儲存路徑（原檔或縮圖）的已驗證檔案記錄
+	GetVerifiedFilesByStoragePath(filePath string) ([]models.UploadedFile, error)
+
 	// GetFilesByUserID 根據用戶ID獲取檔案列表
 	GetFilesByUserID(userID string) ([]models.UploadedFile, error)
 
 	// UpdateFileStatus 更新檔案狀態
 	UpdateFileStatus(fileID string, status string) error
 
-	// DeleteFileByID 根據檔案ID刪除檔案記錄
+	// UpdateFile 更新檔案記錄欄位
+	UpdateFile(fileID string, updates map[string]any) error
+
+	// SetFileExpiry 設定檔案過期時間，過期後由清理任務刪除
+	SetFileExpiry(fileID string, expiresAt time.Time) error
+
+	// DeleteFileByID 根據檔案ID刪除檔案記錄並扣除儲存用量
 	DeleteFileByID(fileID string) error
 
 	// DeleteFileByPath 根據檔案路徑刪除檔案記錄
 	DeleteFileByPath(filePath string) error
 
+	// GetVerifiedFileByHash 根據雜湊獲取一筆已驗證的檔案（用於重複上傳時沿用縮圖等處理結果）
+	GetVerifiedFileByHash(hash string) (*models.UploadedFile, error)
+
+	// AcquireBlob 為已存在且仍被引用的內容增加引用數，內容不存在時返回 nil
+	AcquireBlob(hash string) (*models.FileBlob, error)
+
+	// RegisterBlob 記錄新儲存的內容並增加引用數，並發上傳相同內容時返回先記錄的內容
+	RegisterBlob(blob *models.FileBlob) (*models.FileBlob, error)
+
+	// ReleaseBlob 減少指定路徑內容的引用數並返回更新後的內容，沒有對應的內容記錄時返回 nil
+	ReleaseBlob(hash string, filePath string) (*models.FileBlob, error)
+
+	// DeleteBlobIfUnreferenced 在引用數歸零時刪除內容記錄，返回是否已刪除
+	DeleteBlobIfUnreferenced(hash string) (bool, error)
+
 	// GetExpiredFiles 獲取過期檔案列表
 	GetExpiredFiles() ([]models.UploadedFile, error)
 
 	// CleanupExpiredFiles 清理過期檔案記錄
 	CleanupExpiredFiles() error
+
+	// GetStorageUsage 獲取用戶或伺服器的儲存用量（ownerType 為 models.StorageOwnerUser 或 models.StorageOwnerServer）
+	GetStorageUsage(ownerType string, ownerID string) (*models.StorageUsage, error)
+}
+
+type InviteRepository interface {
+	// CreateInvite 創建邀請碼
+	CreateInvite(invite *models.ServerInvite) error
+
+	// GetInviteByCode 根據邀請碼獲取邀請
+	GetInviteByCode(code string) (*models.ServerInvite, error)
+
+	// GetInviteByID 根據邀請ID獲取邀請
+	GetInviteByID(inviteID string) (*models.ServerInvite, error)
+
+	// GetInvitesByServerID 獲取伺服器尚未撤銷的邀請列表（新到舊）
+	GetInvitesByServerID(serverID string) ([]models.ServerInvite, error)
+
+	// RevokeInvite 撤銷邀請碼
+	RevokeInvite(inviteID string) error
+
+	// ClaimInviteUse 在邀請仍有效時原子性地增加使用次數，返回是否成功佔用
+	ClaimInviteUse(inviteID string, now int64) (bool, error)
+
+	// ReleaseInviteUse 歸還先前佔用的使用次數（加入失敗時使用）
+	ReleaseInviteUse(inviteID string) error
+}
+
+type ModerationRepository interface {
+	// SaveBan 建立或取代用戶在伺服器的封鎖紀錄
+	SaveBan(ban *models.ServerBan) error
+
+	// GetActiveBan 獲取用戶在伺服器仍有效的封鎖，沒有時返回 nil
+	GetActiveBan(serverID, userID string, now int64) (*models.ServerBan, error)
+
+	// GetBansByServerID 獲取伺服器仍有效的封鎖列表（新到舊）
+	GetBansByServerID(serverID string, now int64) ([]models.ServerBan, error)
+
+	// DeleteBan 解除用戶在伺服器的封鎖，返回是否有紀錄被刪除
+	DeleteBan(serverID, userID string) (bool, error)
+
+	// SetMemberTimeout 設定成員禁言到期時間，0 表示解除禁言
+	SetMemberTimeout(serverID, userID string, until int64) error
+
+	// CreateAction 記錄管理動作
+	CreateAction(action *models.ModerationAction) error
+}
+
+type AuditLogRepository interface {
+	// CreateAuditLog 寫入稽核紀錄
+	CreateAuditLog(entry *models.AuditLog) error
+
+	// GetAuditLogs 依條件獲取伺服器稽核紀錄（新到舊），query 中的 ID 需已由呼叫端驗證
+	GetAuditLogs(serverID string, query models.AuditLogQuery, limit int64) ([]models.AuditLog, error)
 }
 
 type ChannelCategoryRepository interface {
@@ -178,4 +316,27 @@ type ChannelCategoryRepository interface {
 
 	// CheckChannelCategoryExists 檢查頻道類別是否存在
 	CheckChannelCategoryExists(categoryID string) (bool, error)
+
+	// UpdateChannelCategoryPositions 以單次批量寫入更新頻道類別的排序位置（僅限指定伺服器內的類別）
+	UpdateChannelCategoryPositions(serverID string, positions []models.CategoryPosition) error
+}
+
+type UploadSessionRepository interface {
+	// CreateUploadSession 創建分段上傳紀錄
+	CreateUploadSession(session *models.UploadSession) error
+
+	// GetUploadSessionByID 根據ID獲取分段上傳紀錄
+	GetUploadSessionByID(sessionID string) (*models.UploadSession, error)
+
+	// SetUploadPart 記錄已接收的分段，只在上傳仍進行中時寫入，返回是否寫入成功
+	SetUploadPart(sessionID string, part models.UploadPart) (bool, error)
+
+	// TransitionUploadSession 在狀態符合 fromStatus 時更新狀態與其他欄位，返回是否更新成功
+	TransitionUploadSession(sessionID string, fromStatus string, toStatus string, fields map[string]any) (bool, error)
+
+	// GetExpiredUploadSessions 獲取已過期的分段上傳紀錄
+	GetExpiredUploadSessions(now time.Time) ([]models.UploadSession, error)
+
+	// DeleteUploadSession 刪除分段上傳紀錄
+	DeleteUploadSession(sessionID string) error
 }
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type inviteRepository struct {
+	odm providers.ODM
+}
+
+func NewInviteRepository(odm providers.ODM) *inviteRepository {
+	return &inviteRepository{
+		odm: odm,
+	}
+}
+
+// CreateInvite 創建邀請碼
+func (r *inviteRepository) CreateInvite(invite *models.ServerInvite) error {
+	ctx := context.Background()
+	err := r.odm.Create(ctx, invite)
+	if err != nil {
+		return fmt.Errorf("創建邀請失敗: %v", err)
+	}
+	return nil
+}
+
+// GetInviteByCode 根據邀請碼獲取邀請
+func (r *inviteRepository) GetInviteByCode(code string) (*models.ServerInvite, error) {
+	ctx := context.Background()
+	var invite models.ServerInvite
+
+	err := r.odm.FindOne(ctx, bson.M{"code": code}, &invite)
+	if err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+// GetInviteByID 根據邀請ID獲取邀請
+func (r *inviteRepository) GetInviteByID(inviteID string) (*models.ServerInvite, error) {
+	ctx := context.Background()
+	var invite models.ServerInvite
+
+	err := r.odm.FindByID(ctx, inviteID, &invite)
+	if err != nil {
+		return nil, err
+	}
+
+	return &invite, nil
+}
+
+// GetInvitesByServerID 獲取伺服器尚未撤銷的邀請列表（新到舊）
+func (r *inviteRepository) GetInvitesByServerID(serverID string) ([]models.ServerInvite, error) {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	ctx := context.Background()
+	qb := providers.NewQueryBuilder()
+	qb.Where("server_id", serverObjectID).Where("revoked", false).SortDesc("_id")
+
+	var invites []models.ServerInvite
+	err = r.odm.FindWithOptions(ctx, qb.GetFilter(), &invites, qb.GetQueryOptions())
+	if err != nil {
+		return nil, fmt.Errorf("查詢邀請失敗: %v", err)
+	}
+
+	return invites, nil
+}
+
+// RevokeInvite 撤銷邀請碼
+func (r *inviteRepository) RevokeInvite(inviteID string) error {
+	inviteObjectID, err := primitive.ObjectIDFromHex(inviteID)
+	if err != nil {
+		return fmt.Errorf("無效的邀請ID: %v", err)
+	}
+
+	ctx := context.Background()
+	err = r.odm.UpdateMany(ctx, &models.ServerInvite{},
+		bson.M{"_id": inviteObjectID},
+		bson.M{"$set": bson.M{"revoked": true}},
+	)
+	if err != nil {
+		return fmt.Errorf("撤銷邀請失敗: %v", err)
+	}
+
+	return nil
+}
+
+// ClaimInviteUse 在邀請仍有效時原子性地增加使用次數，返回是否成功佔用
+// 有效條件（未撤銷、未過期、未達上限）放在同一個更新條件中，避免並發接受時超過最大使用次數
+func (r *inviteRepository) ClaimInviteUse(inviteID string, now int64) (bool, error) {
+	inviteObjectID, err := primitive.ObjectIDFromHex(inviteID)
+	if err != nil {
+		return false, fmt.Errorf("無效的邀請ID: %v", err)
+	}
+
+	ctx := context.Background()
+	filter := bson.M{
+		"_id":     inviteObjectID,
+		"revoked": false,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"max_uses": 0},
+				bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"expires_at": 0},
+				bson.M{"expires_at": bson.M{"$gt": now}},
+			}},
+		},
+	}
+	update := bson.M{
+		"$inc": bson.M{"uses": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.odm.Collection(&models.ServerInvite{}).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("更新邀請使用次數失敗: %v", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// ReleaseInviteUse 歸還先前佔用的使用次數（加入失敗時使用）
+func (r *inviteRepository) ReleaseInviteUse(inviteID string) error {
+	inviteObjectID, err := primitive.ObjectIDFromHex(inviteID)
+	if err != nil {
+		return fmt.Errorf("無效的邀請ID: %v", err)
+	}
+
+	ctx := context.Background()
+	err = r.odm.UpdateMany(ctx, &models.ServerInvite{},
+		bson.M{"_id": inviteObjectID, "uses": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"uses": -1}},
+	)
+	if err != nil {
+		return fmt.Errorf("歸還邀請使用次數失敗: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package repositories
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type moderationRepository struct {
+	odm providers.ODM
+}
+
+func NewModerationRepository(odm providers.ODM) *moderationRepository {
+	return &moderationRepository{
+		odm: odm,
+	}
+}
+
+// SaveBan 建立或取代用戶在伺服器的封鎖紀錄
+func (r *moderationRepository) SaveBan(ban *models.ServerBan) error {
+	ctx := context.Background()
+
+	// 重複封鎖時以新的原因與期限取代舊紀錄
+	err := r.odm.DeleteMany(ctx, &models.ServerBan{}, bson.M{"server_id": ban.ServerID, "user_id": ban.UserID})
+	if err != nil {
+		return fmt.Errorf("移除舊封鎖紀錄失敗: %v", err)
+	}
+
+	err = r.odm.Create(ctx, ban)
+	if err != nil {
+		return fmt.Errorf("創建封鎖紀錄失敗: %v", err)
+	}
+	return nil
+}
+
+// GetActiveBan 獲取用戶在伺服器仍有效的封鎖，沒有時返回 nil
+func (r *moderationRepository) GetActiveBan(serverID, userID string, now int64) (*models.ServerBan, error) {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的用戶ID: %v", err)
+	}
+
+	ctx := context.Background()
+	filter := activeBanFilter(now)
+	filter["server_id"] = serverObjectID
+	filter["user_id"] = userObjectID
+
+	var ban models.ServerBan
+	err = r.odm.FindOne(ctx, filter, &ban)
+	if err != nil {
+		if errors.Is(err, providers.ErrDocumentNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查詢封鎖紀錄失敗: %v", err)
+	}
+
+	return &ban, nil
+}
+
+// GetBansByServerID 獲取伺服器仍有效的封鎖列表（新到舊）
+func (r *moderationRepository) GetBansByServerID(serverID string, now int64) ([]models.ServerBan, error) {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	ctx := context.Background()
+	filter := activeBanFilter(now)
+	filter["server_id"] = serverObjectID
+
+	qb := providers.NewQueryBuilder()
+	qb.SortDesc("_id")
+
+	var bans []models.ServerBan
+	err = r.odm.FindWithOptions(ctx, filter, &bans, qb.GetQueryOptions())
+	if err != nil {
+		return nil, fmt.Errorf("查詢封鎖列表失敗: %v", err)
+	}
+
+	return bans, nil
+}
+
+// DeleteBan 解除用戶在伺服器的封鎖，返回是否有紀錄被刪除
+func (r *moderationRepository) DeleteBan(serverID, userID string) (bool, error) {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return false, fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, fmt.Errorf("無效的用戶ID: %v", err)
+	}
+
+	ctx := context.Background()
+	filter := bson.M{"server_id": serverObjectID, "user_id": userObjectID}
+
+	exists, err := r.odm.Exists(ctx, filter, &models.ServerBan{})
+	if err != nil {
+		return false, fmt.Errorf("查詢封鎖紀錄失敗: %v", err)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	err = r.odm.DeleteMany(ctx, &models.ServerBan{}, filter)
+	if err != nil {
+		return false, fmt.Errorf("刪除封鎖紀錄失敗: %v", err)
+	}
+
+	return true, nil
+}
+
+// SetMemberTimeout 設定成員禁言到期時間，0 表示解除禁言
+func (r *moderationRepository) SetMemberTimeout(serverID, userID string, until int64) error {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("無效的用戶ID: %v", err)
+	}
+
+	ctx := context.Background()
+	filter := bson.M{"server_id": serverObjectID, "user_id": userObjectID}
+
+	update := bson.M{"$set": bson.M{"timeout_until": until}}
+	if until == 0 {
+		update = bson.M{"$unset": bson.M{"timeout_until": ""}}
+	}
+
+	err = r.odm.UpdateMany(ctx, &models.ServerMember{}, filter, update)
+	if err != nil {
+		return fmt.Errorf("更新成員禁言狀態失敗: %v", err)
+	}
+
+	return nil
+}
+
+// CreateAction 記錄管理動作
+func (r *moderationRepository) CreateAction(action *models.ModerationAction) error {
+	ctx := context.Background()
+	err := r.odm.Create(ctx, action)
+	if err != nil {
+		return fmt.Errorf("記錄管理動作失敗: %v", err)
+	}
+	return nil
+}
+
+// activeBanFilter 仍有效封鎖的查詢條件（永久或尚未到期）
+func activeBanFilter(now int64) bson.M {
+	return bson.M{
+		"$or": bson.A{
+			bson.M{"expires_at": 0},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		},
+	}
+}
@@ -0,0 +1,147 @@
+package repositories
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type roleRepository struct {
+	odm providers.ODM
+}
+
+func NewRoleRepository(odm providers.ODM) *roleRepository {
+	return &roleRepository{
+		odm: odm,
+	}
+}
+
+// CreateRole 創建伺服器角色
+func (r *roleRepository) CreateRole(ctx context.Context, role *models.ServerRole) error {
+	err := r.odm.Create(ctx, role)
+	if err != nil {
+		return fmt.Errorf("創建角色失敗: %v", err)
+	}
+	return nil
+}
+
+// GetRolesByServerID 根據伺服器ID獲取角色列表（依位階由高到低，同位階依創建順序）
+func (r *roleRepository) GetRolesByServerID(ctx context.Context, serverID string) ([]models.ServerRole, error) {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	qb := providers.NewQueryBuilder()
+	qb.Where("server_id", serverObjectID).SortDesc("position").SortAsc("_id")
+
+	var roles []models.ServerRole
+	err = r.odm.FindWithOptions(ctx, qb.GetFilter(), &roles, qb.GetQueryOptions())
+	if err != nil {
+		return nil, fmt.Errorf("查詢角色失敗: %v", err)
+	}
+
+	return roles, nil
+}
+
+// GetRoleByID 根據角色ID獲取角色
+func (r *roleRepository) GetRoleByID(ctx context.Context, roleID string) (*models.ServerRole, error) {
+	var role models.ServerRole
+
+	err := r.odm.FindByID(ctx, roleID, &role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// GetRolesByIDs 根據角色ID陣列獲取角色
+func (r *roleRepository) GetRolesByIDs(ctx context.Context, roleIDs []string) ([]models.ServerRole, error) {
+	roleObjectIDs := make([]primitive.ObjectID, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+		if err != nil {
+			return nil, fmt.Errorf("無效的角色ID: %v", err)
+		}
+		roleObjectIDs = append(roleObjectIDs, roleObjectID)
+	}
+
+	if len(roleObjectIDs) == 0 {
+		return []models.ServerRole{}, nil
+	}
+
+	var roles []models.ServerRole
+	err := r.odm.Find(ctx, bson.M{"_id": bson.M{"$in": roleObjectIDs}}, &roles)
+	if err != nil {
+		return nil, fmt.Errorf("查詢角色失敗: %v", err)
+	}
+
+	return roles, nil
+}
+
+// UpdateRole 更新角色
+func (r *roleRepository) UpdateRole(ctx context.Context, roleID string, updates map[string]any) error {
+	roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+	if err != nil {
+		return fmt.Errorf("無效的角色ID: %v", err)
+	}
+
+	filter := bson.M{"_id": roleObjectID}
+	updateDoc := bson.M{"$set": updates}
+
+	err = r.odm.UpdateMany(ctx, &models.ServerRole{}, filter, updateDoc)
+	if err != nil {
+		return fmt.Errorf("更新角色失敗: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteRole 刪除角色並移除所有成員的指派與頻道覆寫
+func (r *roleRepository) DeleteRole(ctx context.Context, roleID string) error {
+	roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+	if err != nil {
+		return fmt.Errorf("無效的角色ID: %v", err)
+	}
+
+	// 先移除成員上的指派，避免留下指向不存在角色的 ID
+	err = r.odm.UpdateMany(ctx, &models.ServerMember{},
+		bson.M{"role_ids": roleObjectID},
+		bson.M{"$pull": bson.M{"role_ids": roleObjectID}},
+	)
+	if err != nil {
+		return fmt.Errorf("移除成員角色失敗: %v", err)
+	}
+
+	// 一併移除頻道上針對該角色的權限覆寫
+	overwriteFilter := bson.M{"target_type": models.OverwriteTargetRole, "target_id": roleObjectID}
+	err = r.odm.UpdateMany(ctx, &models.Channel{},
+		bson.M{"permission_overwrites": bson.M{"$elemMatch": overwriteFilter}},
+		bson.M{"$pull": bson.M{"permission_overwrites": overwriteFilter}},
+	)
+	if err != nil {
+		return fmt.Errorf("移除頻道角色覆寫失敗: %v", err)
+	}
+
+	err = r.odm.DeleteByID(ctx, roleID, &models.ServerRole{})
+	if err != nil {
+		return fmt.Errorf("刪除角色失敗: %v", err)
+	}
+
+	return nil
+}
+
+// CountRolesByServerID 獲取伺服器角色數量
+func (r *roleRepository) CountRolesByServerID(ctx context.Context, serverID string) (int64, error) {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return 0, fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	return r.odm.Count(ctx, bson.M{"server_id": serverObjectID}, &models.ServerRole{})
+}
@@ -197,3 +197,68 @@ func (smr *serverMemberRepository) GetMemberCount(serverID string) (int64, error
 
 	return smr.odm.Count(ctx, filter, &models.ServerMember{})
 }
+
+// GetServerMember 獲取用戶在伺服器中的成員資料
+func (smr *serverMemberRepository) GetServerMember(ctx context.Context, serverID, userID string) (*models.ServerMember, error) {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("無效的用戶ID: %v", err)
+	}
+
+	filter := bson.M{
+		"server_id": serverObjectID,
+		"user_id":   userObjectID,
+	}
+
+	var member models.ServerMember
+	if err := smr.odm.FindOne(ctx, filter, &member); err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+// AddRoleToMember 為成員指派角色
+func (smr *serverMemberRepository) AddRoleToMember(ctx context.Context, serverID, userID, roleID string) error {
+	return smr.updateMemberRoles(ctx, serverID, userID, roleID, "$addToSet")
+}
+
+// RemoveRoleFromMember 移除成員的角色
+func (smr *serverMemberRepository) RemoveRoleFromMember(ctx context.Context, serverID, userID, roleID string) error {
+	return smr.updateMemberRoles(ctx, serverID, userID, roleID, "$pull")
+}
+
+// updateMemberRoles 以指定的陣列運算子更新成員的角色列表
+func (smr *serverMemberRepository) updateMemberRoles(ctx context.Context, serverID, userID, roleID, operator string) error {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return fmt.Errorf("無效的伺服器ID: %v", err)
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("無效的用戶ID: %v", err)
+	}
+
+	roleObjectID, err := primitive.ObjectIDFromHex(roleID)
+	if err != nil {
+		return fmt.Errorf("無效的角色ID: %v", err)
+	}
+
+	filter := bson.M{
+		"server_id": serverObjectID,
+		"user_id":   userObjectID,
+	}
+
+	update := bson.M{
+		operator: bson.M{"role_ids": roleObjectID},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+
+	return smr.odm.UpdateMany(ctx, &models.ServerMember{}, filter, update)
+}
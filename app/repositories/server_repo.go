@@ -150,8 +150,7 @@ func (sr *serverRepository) CheckUserInServer(userID, serverID string) (bool, er
 }
 
 // GetServerByID 根據ID獲取伺服器
-func (sr *serverRepository) GetServerByID(serverID string) (*models.Server, error) {
-	ctx := context.Background()
+func (sr *serverRepository) GetServerByID(ctx context.Context, serverID string) (*models.Server, error) {
 	var server models.Server
 
 	err := sr.odm.FindByID(ctx, serverID, &server)
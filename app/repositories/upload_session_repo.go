@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type uploadSessionRepository struct {
+	odm providers.ODM
+}
+
+func NewUploadSessionRepository(odm providers.ODM) *uploadSessionRepository {
+	return &uploadSessionRepository{
+		odm: odm,
+	}
+}
+
+// CreateUploadSession 創建分段上傳紀錄
+func (r *uploadSessionRepository) CreateUploadSession(session *models.UploadSession) error {
+	ctx := context.Background()
+	if err := r.odm.Create(ctx, session); err != nil {
+		return fmt.Errorf("創建分段上傳紀錄失敗: %v", err)
+	}
+	return nil
+}
+
+// GetUploadSessionByID 根據ID獲取分段上傳紀錄
+func (r *uploadSessionRepository) GetUploadSessionByID(sessionID string) (*models.UploadSession, error) {
+	ctx := context.Background()
+	var session models.UploadSession
+
+	err := r.odm.FindByID(ctx, sessionID, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// SetUploadPart 記錄已接收的分段，只在上傳仍進行中時寫入，返回是否寫入成功
+// 每個分段寫入獨立的欄位，並行上傳不同分段時不會互相覆蓋
+func (r *uploadSessionRepository) SetUploadPart(sessionID string, part models.UploadPart) (bool, error) {
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false, fmt.Errorf("無效的上傳ID: %v", err)
+	}
+
+	ctx := context.Background()
+	filter := bson.M{"_id": sessionObjectID, "status": models.UploadSessionUploading}
+	update := bson.M{"$set": bson.M{
+		"parts." + strconv.Itoa(part.Number): part,
+		"updated_at":                         time.Now(),
+	}}
+
+	result, err := r.odm.Collection(&models.UploadSession{}).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("記錄分段失敗: %v", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// TransitionUploadSession 在狀態符合 fromStatus 時更新狀態與其他欄位，返回是否更新成功
+func (r *uploadSessionRepository) TransitionUploadSession(sessionID string, fromStatus string, toStatus string, fields map[string]any) (bool, error) {
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return false, fmt.Errorf("無效的上傳ID: %v", err)
+	}
+
+	set := bson.M{"status": toStatus, "updated_at": time.Now()}
+	for key, value := range fields {
+		set[key] = value
+	}
+
+	ctx := context.Background()
+	filter := bson.M{"_id": sessionObjectID, "status": fromStatus}
+	result, err := r.odm.Collection(&models.UploadSession{}).UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, fmt.Errorf("更新分段上傳狀態失敗: %v", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// GetExpiredUploadSessions 獲取已過期的分段上傳紀錄
+func (r *uploadSessionRepository) GetExpiredUploadSessions(now time.Time) ([]models.UploadSession, error) {
+	ctx := context.Background()
+	var sessions []models.UploadSession
+
+	err := r.odm.Find(ctx, bson.M{"expires_at": bson.M{"$lt": now}}, &sessions)
+	if err != nil {
+		return nil, fmt.Errorf("查詢過期分段上傳失敗: %v", err)
+	}
+
+	return sessions, nil
+}
+
+// DeleteUploadSession 刪除分段上傳紀錄
+func (r *uploadSessionRepository) DeleteUploadSession(sessionID string) error {
+	ctx := context.Background()
+	if err := r.odm.DeleteByID(ctx, sessionID, &models.UploadSession{}); err != nil {
+		return fmt.Errorf("刪除分段上傳紀錄失敗: %v", err)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"chat_app_backend/utils"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"time"
 
@@ -239,3 +240,125 @@ func (ur *userRepository) DeleteUser(userID string) error {
 	}()
 	return ur.odm.DeleteByID(context.Background(), userID, &models.User{})
 }
+
+// GetUserCredentials 獲取包含兩步驟驗證密鑰等敏感欄位的用戶資料
+// 快取中的用戶資料經過 JSON 序列化，不含 json:"-" 欄位，因此直接查詢資料庫
+func (ur *userRepository) GetUserCredentials(userID string) (*models.User, error) {
+	var user models.User
+	if err := ur.odm.FindByID(context.Background(), userID, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ConsumeTOTPCounter 以條件更新記錄已使用的 TOTP 時間步，同一驗證碼只能使用一次
+func (ur *userRepository) ConsumeTOTPCounter(userID string, counter int64) (bool, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{
+		"_id": userObjectID,
+		"$or": bson.A{
+			bson.M{"two_factor_last_counter": bson.M{"$lt": counter}},
+			bson.M{"two_factor_last_counter": bson.M{"$exists": false}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"two_factor_last_counter": counter}}
+
+	result, err := ur.odm.Collection(&models.User{}).UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("更新 TOTP 時間步失敗: %v", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// ConsumeRecoveryCode 以條件更新移除復原碼雜湊，同一復原碼只能使用一次
+func (ur *userRepository) ConsumeRecoveryCode(userID string, codeHash string) (bool, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": userObjectID, "recovery_codes": codeHash}
+	update := bson.M{
+		"$pull": bson.M{"recovery_codes": codeHash},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := ur.odm.Collection(&models.User{}).UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("移除復原碼失敗: %v", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// MarkRefreshTokenRotated 以條件更新將 refresh token 標記為已輪替，並發刷新時只有一個請求能完成輪替
+func (ur *userRepository) MarkRefreshTokenRotated(tokenID string, replacedByID string) (bool, error) {
+	tokenObjectID, err := primitive.ObjectIDFromHex(tokenID)
+	if err != nil {
+		return false, err
+	}
+	replacedByObjectID, err := primitive.ObjectIDFromHex(replacedByID)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	filter := bson.M{"_id": tokenObjectID, "revoked": false}
+	update := bson.M{"$set": bson.M{
+		"revoked":        true,
+		"replaced_by_id": replacedByObjectID,
+		"rotated_at":     now.Unix(),
+		"updated_at":     now,
+	}}
+
+	result, err := ur.odm.Collection(&models.RefreshToken{}).UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("輪替 refresh token 失敗: %v", err)
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// GetUserByEmail 根據電子郵件獲取用戶
+func (ur *userRepository) GetUserByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := ur.odm.FindOne(context.Background(), bson.M{"email": email}, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ConsumeUserToken 以條件更新清除 token ID 並套用更新，同一 token 只能使用一次
+func (ur *userRepository) ConsumeUserToken(userID string, field string, tokenID string, updates map[string]any) (bool, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+	if tokenID == "" {
+		return false, nil
+	}
+
+	filter := bson.M{"_id": userObjectID, field: tokenID}
+	update := bson.M{"$unset": bson.M{field: ""}}
+	if len(updates) > 0 {
+		update["$set"] = updates
+	}
+
+	result, err := ur.odm.Collection(&models.User{}).UpdateOne(context.Background(), filter, update)
+	if err != nil {
+		return false, fmt.Errorf("使用一次性 token 失敗: %v", err)
+	}
+
+	if result.MatchedCount > 0 {
+		if err := ur.cache.Delete(utils.UserProfileCacheKey(userID)); err != nil {
+			slog.Warn("無法清理用戶資料快取", "user_id", userID, "error", err)
+		}
+	}
+
+	return result.MatchedCount > 0, nil
+}
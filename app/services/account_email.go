@@ -0,0 +1,290 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"chat_app_backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 用戶記錄中保存一次性 token ID 的欄位
+const (
+	emailVerificationTokenField = "email_verification_token_id"
+	passwordResetTokenField     = "password_reset_token_id"
+)
+
+// passwordResetMailTimeout 背景寄送重設密碼信的逾時時間
+const passwordResetMailTimeout = 30 * time.Second
+
+// frontendLink 組成信件中指向前端頁面的連結
+func (us *userService) frontendLink(path string, token string) string {
+	baseURL := ""
+	if us.config != nil {
+		baseURL = strings.TrimRight(us.config.Server.FrontendURL, "/")
+	}
+	return fmt.Sprintf("%s%s?token=%s", baseURL, path, url.QueryEscape(token))
+}
+
+// sendMail 透過寄信服務寄出信件
+func (us *userService) sendMail(ctx context.Context, message providers.MailMessage) error {
+	if us.mailer == nil {
+		return errors.New("未設定寄信服務")
+	}
+	return us.mailer.Send(ctx, message)
+}
+
+// sendEmailVerificationMail 寄送電子郵件驗證信
+func (us *userService) sendEmailVerificationMail(user *models.User, token string) error {
+	body := fmt.Sprintf("%s 您好：\n\n請點擊以下連結驗證您的電子郵件，連結將於 %d 小時後失效：\n%s\n\n如果您沒有註冊帳號，請忽略此信件。\n",
+		user.Username, int(utils.EmailVerificationTokenExpiry.Hours()), us.frontendLink("/verify-email", token))
+
+	return us.sendMail(context.Background(), providers.MailMessage{
+		To:      user.Email,
+		Subject: "驗證您的電子郵件",
+		Body:    body,
+	})
+}
+
+// sendPasswordResetMail 寄送重設密碼信
+func (us *userService) sendPasswordResetMail(ctx context.Context, user *models.User, token string) error {
+	body := fmt.Sprintf("%s 您好：\n\n我們收到了重設密碼的申請，請點擊以下連結設定新密碼，連結將於 %d 分鐘後失效：\n%s\n\n如果您沒有申請重設密碼，請忽略此信件，您的密碼不會變更。\n",
+		user.Username, int(utils.PasswordResetTokenExpiry.Minutes()), us.frontendLink("/reset-password", token))
+
+	return us.sendMail(ctx, providers.MailMessage{
+		To:      user.Email,
+		Subject: "重設您的密碼",
+		Body:    body,
+	})
+}
+
+// SendEmailVerification 重新寄送電子郵件驗證信，先前寄出的驗證連結隨即失效
+func (us *userService) SendEmailVerification(userID string) *models.MessageOptions {
+	user, msgOpt := us.getUserCredentials(userID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	if user.EmailVerified {
+		return &models.MessageOptions{
+			Code:    models.ErrEmailAlreadyVerified,
+			Message: "電子郵件已驗證",
+		}
+	}
+	if user.Email == "" {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "尚未設定電子郵件",
+		}
+	}
+
+	token, err := utils.GenEmailToken(utils.EmailTokenPurposeVerification, userID, user.Email)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "產生驗證連結失敗",
+			Details: err.Error(),
+		}
+	}
+
+	updates := map[string]any{
+		emailVerificationTokenField: token.ID,
+		"updated_at":                time.Now(),
+	}
+	if err = us.userRepo.UpdateUser(userID, updates); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "產生驗證連結失敗",
+			Details: err.Error(),
+		}
+	}
+
+	if err = us.sendEmailVerificationMail(user, token.Token); err != nil {
+		slog.Error("寄送電子郵件驗證信失敗", "user_id", userID, "error", err)
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "寄送驗證信失敗，請稍後再試",
+		}
+	}
+
+	return nil
+}
+
+// VerifyEmail 以驗證信中的 token 完成電子郵件驗證，token 只能使用一次
+func (us *userService) VerifyEmail(token string) *models.MessageOptions {
+	claims, err := utils.ParseEmailToken(utils.EmailTokenPurposeVerification, token)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "驗證連結無效或已過期",
+		}
+	}
+
+	user, msgOpt := us.getEmailTokenUser(claims)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	updates := map[string]any{
+		"email_verified": true,
+		"updated_at":     time.Now(),
+	}
+	consumed, err := us.userRepo.ConsumeUserToken(claims.UserID, emailVerificationTokenField, claims.ID, updates)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "電子郵件驗證失敗",
+			Details: err.Error(),
+		}
+	}
+	if !consumed {
+		if user.EmailVerified {
+			return &models.MessageOptions{
+				Code:    models.ErrEmailAlreadyVerified,
+				Message: "電子郵件已驗證",
+			}
+		}
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "驗證連結已使用或已失效，請重新寄送驗證信",
+		}
+	}
+
+	return nil
+}
+
+// RequestPasswordReset 寄送重設密碼信，先前寄出的重設連結隨即失效
+// 電子郵件未註冊時同樣返回成功，避免被用來探測帳號是否存在
+func (us *userService) RequestPasswordReset(email string) *models.MessageOptions {
+	user, err := us.userRepo.GetUserByEmail(email)
+	if err != nil {
+		if errors.Is(err, providers.ErrDocumentNotFound) {
+			return nil
+		}
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "申請重設密碼失敗",
+			Details: err.Error(),
+		}
+	}
+
+	userID := user.GetID().Hex()
+	token, err := utils.GenEmailToken(utils.EmailTokenPurposePasswordReset, userID, user.Email)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "申請重設密碼失敗",
+			Details: err.Error(),
+		}
+	}
+
+	updates := map[string]any{
+		passwordResetTokenField: token.ID,
+		"updated_at":            time.Now(),
+	}
+	if err = us.userRepo.UpdateUser(userID, updates); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "申請重設密碼失敗",
+			Details: err.Error(),
+		}
+	}
+
+	// 於背景寄信，回應時間不受寄信耗時影響，避免與帳號不存在時的差異洩漏帳號是否存在
+	// 寄送失敗時只記錄錯誤，返回結果與帳號不存在時一致
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetMailTimeout)
+		defer cancel()
+		if err := us.sendPasswordResetMail(ctx, user, token.Token); err != nil {
+			slog.Error("寄送重設密碼信失敗", "user_id", userID, "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// ResetPassword 以重設密碼信中的 token 設定新密碼，token 只能使用一次，完成後所有裝置需重新登入
+func (us *userService) ResetPassword(token string, newPassword string) *models.MessageOptions {
+	claims, err := utils.ParseEmailToken(utils.EmailTokenPurposePasswordReset, token)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "重設密碼連結無效或已過期",
+		}
+	}
+
+	user, msgOpt := us.getEmailTokenUser(claims)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "密碼雜湊失敗",
+			Details: err.Error(),
+		}
+	}
+
+	// 能收到重設密碼信即代表擁有該信箱，一併視為完成電子郵件驗證
+	updates := map[string]any{
+		"password":       string(hashedPassword),
+		"email_verified": true,
+		"updated_at":     time.Now(),
+	}
+	consumed, err := us.userRepo.ConsumeUserToken(claims.UserID, passwordResetTokenField, claims.ID, updates)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "重設密碼失敗",
+			Details: err.Error(),
+		}
+	}
+	if !consumed {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "重設密碼連結已使用或已失效，請重新申請",
+		}
+	}
+
+	// 密碼可能已外洩，撤銷所有裝置的登入狀態
+	if err = us.revokeAllUserTokens(user.GetID()); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "密碼已重設，但登出其他裝置失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// getEmailTokenUser 獲取電子郵件 token 所屬的用戶，信箱已變更時 token 視為無效
+func (us *userService) getEmailTokenUser(claims *utils.EmailTokenClaims) (*models.User, *models.MessageOptions) {
+	user, msgOpt := us.getUserCredentials(claims.UserID)
+	if msgOpt != nil {
+		if msgOpt.Code == models.ErrUserNotFound {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidToken,
+				Message: "連結無效或已過期",
+			}
+		}
+		return nil, msgOpt
+	}
+
+	if user.Email != claims.Email {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "電子郵件已變更，連結已失效",
+		}
+	}
+
+	return user, nil
+}
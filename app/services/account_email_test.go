@@ -0,0 +1,348 @@
+package services
+
+import (
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"chat_app_backend/config"
+	"chat_app_backend/utils"
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setupAccountEmailConfig 設置電子郵件相關測試用的 config
+func setupAccountEmailConfig(t *testing.T) *config.Config {
+	cfg := setupTwoFactorConfig(t)
+	cfg.Server.FrontendURL = "https://chat.example.com/"
+	return cfg
+}
+
+// newTestOutboxMailer 建立將信件寫入暫存目錄的 log 寄信服務
+func newTestOutboxMailer(t *testing.T) (providers.Mailer, string) {
+	outboxDir := t.TempDir()
+	mailer, err := providers.NewLogMailer("Chat App <no-reply@example.com>", outboxDir)
+	assert.NoError(t, err)
+	return mailer, outboxDir
+}
+
+// readTestOutbox 依寄出順序讀取輸出目錄中的信件
+func readTestOutbox(t *testing.T, outboxDir string) []providers.MailMessage {
+	files, err := filepath.Glob(filepath.Join(outboxDir, "*.eml"))
+	assert.NoError(t, err)
+	sort.Strings(files)
+
+	messages := make([]providers.MailMessage, 0, len(files))
+	for _, file := range files {
+		reader, err := os.Open(file)
+		assert.NoError(t, err)
+		message, err := providers.ParseMailMessage(reader)
+		_ = reader.Close()
+		assert.NoError(t, err)
+		messages = append(messages, *message)
+	}
+	return messages
+}
+
+// notifyingMailer 於信件寄送完成後發出通知，供測試等待背景寄信
+type notifyingMailer struct {
+	providers.Mailer
+	sent chan struct{}
+}
+
+func (m *notifyingMailer) Send(ctx context.Context, message providers.MailMessage) error {
+	err := m.Mailer.Send(ctx, message)
+	m.sent <- struct{}{}
+	return err
+}
+
+var mailLinkPattern = regexp.MustCompile(`https://\S+`)
+
+// mailLinkToken 取出信件連結中的 token
+func mailLinkToken(t *testing.T, message providers.MailMessage, path string) string {
+	link := mailLinkPattern.FindString(message.Body)
+	parsed, err := url.Parse(link)
+	assert.NoError(t, err)
+	assert.Equal(t, "chat.example.com", parsed.Host)
+	assert.Equal(t, path, parsed.Path)
+	return parsed.Query().Get("token")
+}
+
+func TestRegisterUser_SendsVerificationEmail(t *testing.T) {
+	cfg := setupAccountEmailConfig(t)
+	mailer, outboxDir := newTestOutboxMailer(t)
+
+	var created models.User
+	mockRepo := &testUserRepository{
+		createUserFunc: func(user models.User) error {
+			created = user
+			return nil
+		},
+	}
+	service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, mailer)
+
+	msgOpt := service.RegisterUser(models.User{
+		Username:      "alice",
+		Email:         "alice@example.com",
+		Password:      "password123",
+		EmailVerified: true,
+	})
+
+	assert.Nil(t, msgOpt)
+	assert.False(t, created.EmailVerified, "註冊時不接受指定已驗證")
+	assert.False(t, created.ID.IsZero())
+	assert.NotEmpty(t, created.EmailVerificationTokenID)
+
+	messages := readTestOutbox(t, outboxDir)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "alice@example.com", messages[0].To)
+
+	claims, err := utils.ParseEmailToken(utils.EmailTokenPurposeVerification, mailLinkToken(t, messages[0], "/verify-email"))
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID.Hex(), claims.UserID)
+	assert.Equal(t, created.EmailVerificationTokenID, claims.ID)
+}
+
+func TestSendEmailVerification(t *testing.T) {
+	t.Run("寄出新的驗證信", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		mailer, outboxDir := newTestOutboxMailer(t)
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Username: "alice", Email: "alice@example.com"}
+
+		var storedTokenID string
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			updateUserFunc: func(id string, updates map[string]any) error {
+				storedTokenID = updates["email_verification_token_id"].(string)
+				return nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, mailer)
+
+		msgOpt := service.SendEmailVerification(user.ID.Hex())
+
+		assert.Nil(t, msgOpt)
+		messages := readTestOutbox(t, outboxDir)
+		assert.Len(t, messages, 1)
+		claims, err := utils.ParseEmailToken(utils.EmailTokenPurposeVerification, mailLinkToken(t, messages[0], "/verify-email"))
+		assert.NoError(t, err)
+		assert.Equal(t, storedTokenID, claims.ID, "只有最後寄出的連結有效")
+	})
+
+	t.Run("電子郵件已驗證", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		mailer, outboxDir := newTestOutboxMailer(t)
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Email: "alice@example.com", EmailVerified: true}
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, mailer)
+
+		msgOpt := service.SendEmailVerification(user.ID.Hex())
+
+		assert.Equal(t, models.ErrEmailAlreadyVerified, msgOpt.Code)
+		assert.Empty(t, readTestOutbox(t, outboxDir))
+	})
+
+	t.Run("寄信失敗", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Email: "alice@example.com"}
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			updateUserFunc:         func(id string, updates map[string]any) error { return nil },
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		msgOpt := service.SendEmailVerification(user.ID.Hex())
+
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+	})
+}
+
+func TestVerifyEmail(t *testing.T) {
+	t.Run("驗證成功", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Email: "alice@example.com"}
+		token, _ := utils.GenEmailToken(utils.EmailTokenPurposeVerification, user.ID.Hex(), user.Email)
+
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeUserTokenFunc: func(id string, field string, tokenID string, updates map[string]any) (bool, error) {
+				assert.Equal(t, user.ID.Hex(), id)
+				assert.Equal(t, "email_verification_token_id", field)
+				assert.Equal(t, token.ID, tokenID)
+				assert.Equal(t, true, updates["email_verified"])
+				return true, nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		assert.Nil(t, service.VerifyEmail(token.Token))
+	})
+
+	t.Run("連結已使用", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Email: "alice@example.com"}
+		token, _ := utils.GenEmailToken(utils.EmailTokenPurposeVerification, user.ID.Hex(), user.Email)
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeUserTokenFunc: func(id string, field string, tokenID string, updates map[string]any) (bool, error) {
+				return false, nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		msgOpt := service.VerifyEmail(token.Token)
+
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+	})
+
+	t.Run("信箱已變更時連結失效", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Email: "new@example.com"}
+		token, _ := utils.GenEmailToken(utils.EmailTokenPurposeVerification, user.ID.Hex(), "old@example.com")
+		consumed := false
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeUserTokenFunc: func(id string, field string, tokenID string, updates map[string]any) (bool, error) {
+				consumed = true
+				return true, nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		msgOpt := service.VerifyEmail(token.Token)
+
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+		assert.False(t, consumed)
+	})
+
+	t.Run("重設密碼 token 不能用於驗證信箱", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		token, _ := utils.GenEmailToken(utils.EmailTokenPurposePasswordReset, primitive.NewObjectID().Hex(), "alice@example.com")
+		service := NewUserService(cfg, nil, &testUserRepository{}, nil, nil, nil, nil)
+
+		msgOpt := service.VerifyEmail(token.Token)
+
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+	})
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	t.Run("寄出重設密碼信", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		outboxMailer, outboxDir := newTestOutboxMailer(t)
+		mailer := &notifyingMailer{Mailer: outboxMailer, sent: make(chan struct{}, 1)}
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Username: "alice", Email: "alice@example.com"}
+
+		var storedTokenID string
+		mockRepo := &testUserRepository{
+			getUserByEmailFunc: func(email string) (*models.User, error) {
+				assert.Equal(t, "alice@example.com", email)
+				return user, nil
+			},
+			updateUserFunc: func(id string, updates map[string]any) error {
+				storedTokenID = updates["password_reset_token_id"].(string)
+				return nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, mailer)
+
+		msgOpt := service.RequestPasswordReset("alice@example.com")
+
+		assert.Nil(t, msgOpt)
+		// 重設密碼信於背景寄出，等待寄送完成
+		select {
+		case <-mailer.sent:
+		case <-time.After(time.Second):
+			t.Fatal("未寄出重設密碼信")
+		}
+		messages := readTestOutbox(t, outboxDir)
+		assert.Len(t, messages, 1)
+		assert.Equal(t, "alice@example.com", messages[0].To)
+		claims, err := utils.ParseEmailToken(utils.EmailTokenPurposePasswordReset, mailLinkToken(t, messages[0], "/reset-password"))
+		assert.NoError(t, err)
+		assert.Equal(t, storedTokenID, claims.ID)
+	})
+
+	t.Run("未註冊的電子郵件同樣返回成功", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		mailer, outboxDir := newTestOutboxMailer(t)
+		service := NewUserService(cfg, nil, &testUserRepository{}, nil, nil, nil, mailer)
+
+		msgOpt := service.RequestPasswordReset("nobody@example.com")
+
+		assert.Nil(t, msgOpt)
+		assert.Empty(t, readTestOutbox(t, outboxDir))
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	t.Run("重設密碼並撤銷所有登入狀態", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Email: "alice@example.com"}
+		token, _ := utils.GenEmailToken(utils.EmailTokenPurposePasswordReset, user.ID.Hex(), user.Email)
+
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeUserTokenFunc: func(id string, field string, tokenID string, updates map[string]any) (bool, error) {
+				assert.Equal(t, "password_reset_token_id", field)
+				assert.Equal(t, token.ID, tokenID)
+				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updates["password"].(string)), []byte("new-password")))
+				return true, nil
+			},
+		}
+		mockODM := new(mocks.ODM)
+		mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.RefreshToken"), mock.MatchedBy(func(filter bson.M) bool {
+			return filter["user_id"] == user.ID
+		}), mock.Anything).Return(nil).Once()
+		tokenDenylist := providers.NewCacheTokenDenylist(nil, time.Hour)
+		issuedAt := time.Now().Add(-time.Minute)
+		service := NewUserService(cfg, mockODM, mockRepo, nil, nil, tokenDenylist, nil)
+
+		msgOpt := service.ResetPassword(token.Token, "new-password")
+
+		assert.Nil(t, msgOpt)
+		assert.True(t, tokenDenylist.IsRevoked("", "", user.ID.Hex(), issuedAt), "重設密碼後既有的 access token 應失效")
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("連結只能使用一次", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Email: "alice@example.com"}
+		token, _ := utils.GenEmailToken(utils.EmailTokenPurposePasswordReset, user.ID.Hex(), user.Email)
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeUserTokenFunc: func(id string, field string, tokenID string, updates map[string]any) (bool, error) {
+				return false, nil
+			},
+		}
+		mockODM := new(mocks.ODM)
+		service := NewUserService(cfg, mockODM, mockRepo, nil, nil, nil, nil)
+
+		msgOpt := service.ResetPassword(token.Token, "new-password")
+
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+		mockODM.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("無效的 token", func(t *testing.T) {
+		cfg := setupAccountEmailConfig(t)
+		service := NewUserService(cfg, nil, &testUserRepository{}, nil, nil, nil, nil)
+
+		msgOpt := service.ResetPassword("invalid", "new-password")
+
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+	})
+}
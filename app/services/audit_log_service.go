@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bytes"
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/repositories"
+	"chat_app_backend/config"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultAuditLogLimit = 50  // 稽核紀錄預設每頁數量
+	MaxAuditLogLimit     = 100 // 稽核紀錄每頁數量上限
+)
+
+// auditIgnoredFields 比較變更時忽略的欄位（識別資訊或由系統維護的欄位）
+var auditIgnoredFields = []string{"id", "created_at", "updated_at", "last_message_at", "member_count"}
+
+type auditLogService struct {
+	config            *config.Config
+	auditLogRepo      repositories.AuditLogRepository
+	userRepo          repositories.UserRepository
+	permissionService PermissionService
+}
+
+func NewAuditLogService(cfg *config.Config,
+	auditLogRepo repositories.AuditLogRepository,
+	userRepo repositories.UserRepository,
+	permissionService PermissionService,
+) *auditLogService {
+	return &auditLogService{
+		config:            cfg,
+		auditLogRepo:      auditLogRepo,
+		userRepo:          userRepo,
+		permissionService: permissionService,
+	}
+}
+
+// GetServerAuditLogs 獲取伺服器稽核紀錄，需要查看稽核紀錄權限
+func (as *auditLogService) GetServerAuditLogs(ctx context.Context, userID string, serverID string, query models.AuditLogQuery) (*models.AuditLogResults, *models.MessageOptions) {
+	if query.Action != "" && !models.IsValidAuditAction(query.Action) {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的動作類型",
+		}
+	}
+
+	for _, id := range []struct {
+		value   string
+		message string
+	}{
+		{query.ActorID, "無效的操作者ID格式"},
+		{query.Before, "無效的before參數格式"},
+	} {
+		if id.value == "" {
+			continue
+		}
+		if _, err := primitive.ObjectIDFromHex(id.value); err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: id.message,
+				Details: err.Error(),
+			}
+		}
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = DefaultAuditLogLimit
+	}
+	if query.Limit > MaxAuditLogLimit {
+		query.Limit = MaxAuditLogLimit
+	}
+
+	if msgOpt := as.permissionService.CheckPermission(ctx, serverID, userID, models.PermissionViewAuditLog); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	// 多取一筆用於判斷是否還有下一頁
+	entries, err := as.auditLogRepo.GetAuditLogs(serverID, query, query.Limit+1)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取稽核紀錄失敗",
+			Details: err.Error(),
+		}
+	}
+
+	results := &models.AuditLogResults{
+		Entries: make([]models.AuditLogResponse, 0, len(entries)),
+	}
+	if int64(len(entries)) > query.Limit {
+		entries = entries[:query.Limit]
+		results.NextCursor = entries[len(entries)-1].ID.Hex()
+	}
+
+	actorIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !slices.Contains(actorIDs, entry.ActorID.Hex()) {
+			actorIDs = append(actorIDs, entry.ActorID.Hex())
+		}
+	}
+
+	usernames := make(map[string]string, len(actorIDs))
+	if len(actorIDs) > 0 {
+		users, err := as.userRepo.GetUserListByIds(actorIDs)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "獲取用戶資訊失敗",
+				Details: err.Error(),
+			}
+		}
+		for _, user := range users {
+			usernames[user.ID.Hex()] = user.Username
+		}
+	}
+
+	for _, entry := range entries {
+		results.Entries = append(results.Entries, toAuditLogResponse(entry, usernames[entry.ActorID.Hex()]))
+	}
+
+	return results, nil
+}
+
+// toAuditLogResponse 轉換稽核紀錄為響應格式
+func toAuditLogResponse(entry models.AuditLog, actorUsername string) models.AuditLogResponse {
+	changes := make([]models.AuditLogChangeResponse, 0, len(entry.Changes))
+	for _, change := range entry.Changes {
+		response := models.AuditLogChangeResponse{Key: change.Key}
+		if change.Before != "" {
+			response.Before = json.RawMessage(change.Before)
+		}
+		if change.After != "" {
+			response.After = json.RawMessage(change.After)
+		}
+		changes = append(changes, response)
+	}
+
+	return models.AuditLogResponse{
+		ID:            entry.ID.Hex(),
+		Action:        entry.Action,
+		ActorID:       entry.ActorID.Hex(),
+		ActorUsername: actorUsername,
+		TargetType:    entry.TargetType,
+		TargetID:      entry.TargetID.Hex(),
+		Changes:       changes,
+		Reason:        entry.Reason,
+		CreatedAt:     entry.CreatedAt.Unix(),
+	}
+}
+
+// recordAuditLog 寫入稽核紀錄，操作本身已完成，寫入失敗只記錄日誌；未設定儲存庫時略過
+func recordAuditLog(auditLogRepo repositories.AuditLogRepository, entry *models.AuditLog) {
+	if auditLogRepo == nil {
+		return
+	}
+
+	if err := auditLogRepo.CreateAuditLog(entry); err != nil {
+		slog.Error("寫入稽核紀錄失敗", "server_id", entry.ServerID.Hex(), "action", entry.Action, "error", err)
+	}
+}
+
+// recordMemberAuditLog 寫入以成員為目標的稽核紀錄（加入、離開、角色與管理動作）
+func recordMemberAuditLog(auditLogRepo repositories.AuditLogRepository, serverID string, actorID string, targetUserID string, action string, reason string, changes []models.AuditLogChange) {
+	serverObjectID, _ := primitive.ObjectIDFromHex(serverID)
+	actorObjectID, _ := primitive.ObjectIDFromHex(actorID)
+	targetObjectID, _ := primitive.ObjectIDFromHex(targetUserID)
+
+	recordAuditLog(auditLogRepo, &models.AuditLog{
+		ServerID:   serverObjectID,
+		ActorID:    actorObjectID,
+		Action:     action,
+		TargetType: models.AuditTargetMember,
+		TargetID:   targetObjectID,
+		Changes:    changes,
+		Reason:     reason,
+	})
+}
+
+// auditChanges 比較變更前後的資料，返回有差異的欄位（依欄位名稱排序）
+// 以 JSON 欄位名稱與編碼結果比較，before 或 after 為 nil 時分別代表創建與刪除
+func auditChanges(before any, after any) []models.AuditLogChange {
+	beforeFields := auditFields(before)
+	afterFields := auditFields(after)
+
+	keys := make([]string, 0, len(beforeFields)+len(afterFields))
+	for key := range beforeFields {
+		keys = append(keys, key)
+	}
+	for key := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	changes := make([]models.AuditLogChange, 0, len(keys))
+	for _, key := range keys {
+		if slices.Contains(auditIgnoredFields, key) {
+			continue
+		}
+		beforeValue, afterValue := beforeFields[key], afterFields[key]
+		if bytes.Equal(beforeValue, afterValue) {
+			continue
+		}
+		changes = append(changes, models.AuditLogChange{
+			Key:    key,
+			Before: string(beforeValue),
+			After:  string(afterValue),
+		})
+	}
+
+	return changes
+}
+
+// auditFields 將資料編碼為 JSON 欄位對應，nil 或無法編碼時返回空集合
+func auditFields(value any) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if value == nil {
+		return fields
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return make(map[string]json.RawMessage)
+	}
+
+	// JSON null 視為欄位不存在
+	for key, raw := range fields {
+		if bytes.Equal(raw, []byte("null")) {
+			delete(fields, key)
+		}
+	}
+
+	return fields
+}
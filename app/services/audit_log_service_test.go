@@ -0,0 +1,132 @@
+package services
+
+import (
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mockAuditLogRepository 模擬 AuditLogRepository
+type mockAuditLogRepository struct {
+	mock.Mock
+}
+
+func (m *mockAuditLogRepository) CreateAuditLog(entry *models.AuditLog) error {
+	args := m.Called(entry)
+	return args.Error(0)
+}
+
+func (m *mockAuditLogRepository) GetAuditLogs(serverID string, query models.AuditLogQuery, limit int64) ([]models.AuditLog, error) {
+	args := m.Called(serverID, query, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.AuditLog), args.Error(1)
+}
+
+// TestAuditChanges 測試稽核紀錄的變更比較
+func TestAuditChanges(t *testing.T) {
+	serverID := primitive.NewObjectID()
+
+	t.Run("只記錄有差異的欄位", func(t *testing.T) {
+		before := models.Server{BaseModel: providers.BaseModel{ID: serverID}, Name: "old", Description: "same", MemberCount: 1}
+		after := models.Server{BaseModel: providers.BaseModel{ID: serverID}, Name: "new", Description: "same", MemberCount: 2, IsPublic: true}
+
+		changes := auditChanges(before, after)
+
+		assert.Equal(t, []models.AuditLogChange{
+			{Key: "is_public", Before: "false", After: "true"},
+			{Key: "name", Before: `"old"`, After: `"new"`},
+		}, changes)
+	})
+
+	t.Run("創建與刪除只有單側的值", func(t *testing.T) {
+		role := models.ServerRoleResponse{ID: "role123", Name: "Moderator", Permissions: []string{"kick_members"}}
+
+		created := auditChanges(nil, role)
+		deleted := auditChanges(role, nil)
+
+		assert.Contains(t, created, models.AuditLogChange{Key: "name", After: `"Moderator"`})
+		assert.Contains(t, deleted, models.AuditLogChange{Key: "permissions", Before: `["kick_members"]`})
+		for _, change := range created {
+			assert.NotEqual(t, "id", change.Key)
+		}
+	})
+
+	t.Run("沒有變更時返回空集合", func(t *testing.T) {
+		channel := &models.Channel{Name: "general"}
+
+		assert.Empty(t, auditChanges(channel, channel))
+	})
+}
+
+// TestGetServerAuditLogs 測試稽核紀錄查詢
+func TestGetServerAuditLogs(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	serverID := primitive.NewObjectID()
+
+	t.Run("分頁並附上操作者名稱", func(t *testing.T) {
+		mockAuditLogRepo := new(mockAuditLogRepository)
+		mockUserRepo := new(mocks.UserRepository)
+		mockPS := new(mocks.PermissionService)
+		service := NewAuditLogService(nil, mockAuditLogRepo, mockUserRepo, mockPS)
+
+		query := models.AuditLogQuery{Action: models.AuditActionChannelUpdate, Limit: 2}
+		entries := []models.AuditLog{
+			{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, ActorID: userID, Action: models.AuditActionChannelUpdate,
+				Changes: []models.AuditLogChange{{Key: "name", Before: `"old"`, After: `"new"`}}},
+			{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, ActorID: userID, Action: models.AuditActionChannelUpdate},
+			{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, ActorID: userID, Action: models.AuditActionChannelUpdate},
+		}
+
+		mockPS.On("CheckPermission", ctx, serverID.Hex(), userID.Hex(), models.PermissionViewAuditLog).Return(nil).Once()
+		mockAuditLogRepo.On("GetAuditLogs", serverID.Hex(), query, int64(3)).Return(entries, nil).Once()
+		mockUserRepo.On("GetUserListByIds", []string{userID.Hex()}).
+			Return([]models.User{{BaseModel: providers.BaseModel{ID: userID}, Username: "alice"}}, nil).Once()
+
+		results, msgOpt := service.GetServerAuditLogs(ctx, userID.Hex(), serverID.Hex(), query)
+
+		assert.Nil(t, msgOpt)
+		assert.Len(t, results.Entries, 2)
+		assert.Equal(t, entries[1].ID.Hex(), results.NextCursor)
+		assert.Equal(t, "alice", results.Entries[0].ActorUsername)
+		assert.Equal(t, json.RawMessage(`"new"`), results.Entries[0].Changes[0].After)
+
+		mockAuditLogRepo.AssertExpectations(t)
+		mockUserRepo.AssertExpectations(t)
+	})
+
+	t.Run("無效的動作類型", func(t *testing.T) {
+		mockAuditLogRepo := new(mockAuditLogRepository)
+		service := NewAuditLogService(nil, mockAuditLogRepo, nil, nil)
+
+		results, msgOpt := service.GetServerAuditLogs(ctx, userID.Hex(), serverID.Hex(), models.AuditLogQuery{Action: "fly"})
+
+		assert.Nil(t, results)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+		mockAuditLogRepo.AssertNotCalled(t, "GetAuditLogs", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("沒有查看稽核紀錄權限", func(t *testing.T) {
+		mockAuditLogRepo := new(mockAuditLogRepository)
+		mockPS := new(mocks.PermissionService)
+		service := NewAuditLogService(nil, mockAuditLogRepo, nil, mockPS)
+
+		mockPS.On("CheckPermission", ctx, serverID.Hex(), userID.Hex(), models.PermissionViewAuditLog).
+			Return(&models.MessageOptions{Code: models.ErrNoServerPermission}).Once()
+
+		results, msgOpt := service.GetServerAuditLogs(ctx, userID.Hex(), serverID.Hex(), models.AuditLogQuery{})
+
+		assert.Nil(t, results)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		mockAuditLogRepo.AssertNotCalled(t, "GetAuditLogs", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -9,13 +9,15 @@ import (
 
 // BackgroundTasks 管理後台任務
 type BackgroundTasks struct {
-	userService UserService
+	userService       UserService
+	fileUploadService FileUploadService
 }
 
 // NewBackgroundTasks 創建後台任務管理器
-func NewBackgroundTasks(userService UserService) *BackgroundTasks {
+func NewBackgroundTasks(userService UserService, fileUploadService FileUploadService) *BackgroundTasks {
 	return &BackgroundTasks{
-		userService: userService,
+		userService:       userService,
+		fileUploadService: fileUploadService,
 	}
 }
 
@@ -48,6 +50,11 @@ func (bt *BackgroundTasks) StartAllBackgroundTasks(ctx context.Context) {
 	// 啟動過期令牌清理任務 - 每10分鐘檢查一次
 	go bt.StartExpiredTokenCleaner(ctx, 10)
 
+	// 啟動過期檔案清理任務（含已刪除訊息的附件與未完成的分段上傳）- 每30分鐘檢查一次
+	if bt.fileUploadService != nil {
+		go bt.StartExpiredFileCleaner(ctx, 30)
+	}
+
 	log.Println("所有後台任務已啟動")
 }
 
@@ -70,3 +77,26 @@ func (bt *BackgroundTasks) StartExpiredTokenCleaner(ctx context.Context, interva
 		}
 	}
 }
+
+// StartExpiredFileCleaner 啟動過期檔案清理任務
+func (bt *BackgroundTasks) StartExpiredFileCleaner(ctx context.Context, intervalMinutes int) {
+	ticker := time.NewTicker(time.Duration(intervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	slog.Info("過期檔案清理任務已啟動", "interval_minutes", intervalMinutes)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("收到關閉信號，停止過期檔案清理任務")
+			return
+		case <-ticker.C:
+			if msgOpt := bt.fileUploadService.CleanupExpiredFiles(); msgOpt != nil {
+				slog.Error("清理過期檔案失敗", "error", msgOpt.Details)
+			}
+			if msgOpt := bt.fileUploadService.CleanupExpiredChunkedUploads(); msgOpt != nil {
+				slog.Error("清理過期分段上傳失敗", "error", msgOpt.Details)
+			}
+		}
+	}
+}
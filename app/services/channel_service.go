@@ -6,42 +6,55 @@ import (
 	"chat_app_backend/app/repositories"
 	"chat_app_backend/config"
 	"chat_app_backend/utils"
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type channelService struct {
-	config           *config.Config
-	odm              providers.ODM
-	channelRepo      repositories.ChannelRepository
-	serverRepo       repositories.ServerRepository
-	serverMemberRepo repositories.ServerMemberRepository
-	userRepo         repositories.UserRepository
-	chatRepo         repositories.ChatRepository
-	cache            providers.CacheProvider // 伺服器成員權限快取
+	config              *config.Config
+	odm                 providers.ODM
+	channelRepo         repositories.ChannelRepository
+	channelCategoryRepo repositories.ChannelCategoryRepository
+	serverRepo          repositories.ServerRepository
+	serverMemberRepo    repositories.ServerMemberRepository
+	userRepo            repositories.UserRepository
+	chatRepo            repositories.ChatRepository
+	cache               providers.CacheProvider // 伺服器成員權限快取
+	permissionService   PermissionService
+	auditLogRepo        repositories.AuditLogRepository
 }
 
 func NewChannelService(cfg *config.Config,
 	odm providers.ODM,
 	channelRepo repositories.ChannelRepository,
+	channelCategoryRepo repositories.ChannelCategoryRepository,
 	serverRepo repositories.ServerRepository,
 	serverMemberRepo repositories.ServerMemberRepository,
 	userRepo repositories.UserRepository,
 	chatRepo repositories.ChatRepository,
-	cache providers.CacheProvider) *channelService {
+	cache providers.CacheProvider,
+	permissionService PermissionService,
+	auditLogRepo repositories.AuditLogRepository) *channelService {
 	return &channelService{
-		config:           cfg,
-		odm:              odm,
-		channelRepo:      channelRepo,
-		serverRepo:       serverRepo,
-		serverMemberRepo: serverMemberRepo,
-		userRepo:         userRepo,
-		chatRepo:         chatRepo,
-		cache:            cache,
+		config:              cfg,
+		odm:                 odm,
+		channelRepo:         channelRepo,
+		channelCategoryRepo: channelCategoryRepo,
+		serverRepo:          serverRepo,
+		serverMemberRepo:    serverMemberRepo,
+		userRepo:            userRepo,
+		chatRepo:            chatRepo,
+		cache:               cache,
+		permissionService:   permissionService,
+		auditLogRepo:        auditLogRepo,
 	}
 }
 
@@ -76,8 +89,27 @@ func (cs *channelService) getCachedUserServers(userID string) ([]models.ServerMe
 	return members, nil
 }
 
-// GetChannelsByServerID 根據伺服器ID獲取頻道列表
-func (cs *channelService) GetChannelsByServerID(userID string, serverID string) ([]models.ChannelResponse, *models.MessageOptions) {
+// checkManageChannels 透過集中權限檢查確認用戶具有管理頻道權限
+// 無權限或非成員時以 deniedMessage 回傳未授權錯誤，其餘錯誤原樣返回
+func (cs *channelService) checkManageChannels(serverID string, userID string, deniedMessage string) *models.MessageOptions {
+	msgOpt := cs.permissionService.CheckPermission(context.TODO(), serverID, userID, models.PermissionManageChannels)
+	if msgOpt == nil {
+		return nil
+	}
+
+	switch msgOpt.Code {
+	case models.ErrNoServerPermission, models.ErrNotServerMember:
+		return &models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: deniedMessage,
+		}
+	default:
+		return msgOpt
+	}
+}
+
+// GetChannelsByServerID 根據伺服器ID獲取頻道樹（類別與類別內的頻道皆依排序位置排列）
+func (cs *channelService) GetChannelsByServerID(userID string, serverID string) (*models.ChannelTreeResponse, *models.MessageOptions) {
 	// 檢查用戶是否有權限訪問該伺服器
 	serverMembers, err := cs.getCachedUserServers(userID)
 	if err != nil {
@@ -127,24 +159,45 @@ func (cs *channelService) GetChannelsByServerID(userID string, serverID string)
 		}
 	}
 
-	// 轉換為響應格式
-	var channelResponses []models.ChannelResponse
+	// 依頻道權限覆寫過濾用戶無法查看的頻道（私人頻道等）
+	channels, msgOpt := cs.permissionService.FilterViewableChannels(context.TODO(), serverID, userID, channels)
+	if msgOpt != nil {
+		if msgOpt.Code == models.ErrNotServerMember {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrUnauthorized,
+				Message: "用戶沒有權限訪問該伺服器",
+			}
+		}
+		return nil, msgOpt
+	}
+
+	// 取得各頻道的已讀狀態，失敗時不影響列表回傳
+	channelIDs := make([]string, 0, len(channels))
 	for _, channel := range channels {
-		channelResponses = append(channelResponses, models.ChannelResponse{
-			ID:       channel.ID,
-			ServerID: channel.ServerID,
-			Name:     channel.Name,
-			Type:     channel.Type,
-		})
+		channelIDs = append(channelIDs, channel.ID.Hex())
+	}
+	readStates, err := cs.chatRepo.GetRoomReadStates(context.TODO(), userID, channelIDs)
+	if err != nil {
+		slog.Warn("獲取頻道已讀狀態失敗", "user_id", userID, "server_id", serverID, "error", err)
+	}
+
+	// 獲取類別列表
+	categories, err := cs.channelCategoryRepo.GetChannelCategoriesByServerID(serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取頻道類別失敗",
+			Details: err.Error(),
+		}
 	}
 
-	return channelResponses, nil
+	return buildChannelTree(categories, channels, readStates), nil
 }
 
 // GetChannelByID 根據頻道ID獲取頻道詳細信息
 func (cs *channelService) GetChannelByID(userID string, channelID string) (*models.ChannelResponse, *models.MessageOptions) {
 	// 獲取頻道信息
-	channel, err := cs.channelRepo.GetChannelByID(channelID)
+	channel, err := cs.channelRepo.GetChannelByID(context.TODO(), channelID)
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
@@ -178,43 +231,47 @@ func (cs *channelService) GetChannelByID(userID string, channelID string) (*mode
 		}
 	}
 
-	// 轉換為響應格式
-	channelResponse := &models.ChannelResponse{
-		ID:       channel.ID,
-		ServerID: channel.ServerID,
-		Name:     channel.Name,
-		Type:     channel.Type,
+	// 套用頻道權限覆寫（私人頻道等）
+	if msgOpt := cs.permissionService.CheckChannelPermission(context.TODO(), channelID, userID, models.PermissionViewChannel); msgOpt != nil {
+		if msgOpt.Code == models.ErrInternalServer {
+			return nil, msgOpt
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "用戶沒有權限訪問該頻道",
+		}
 	}
 
-	return channelResponse, nil
+	// 轉換為響應格式
+	channelResponse := toChannelResponse(channel)
+
+	return &channelResponse, nil
 }
 
 // CreateChannel 創建新頻道
 func (cs *channelService) CreateChannel(userID string, channel *models.Channel) (*models.ChannelResponse, *models.MessageOptions) {
-	// 檢查用戶是否有權限創建頻道（需要是伺服器成員）
-	serverMembers, err := cs.serverMemberRepo.GetUserServers(userID)
-	if err != nil {
-		return nil, &models.MessageOptions{
-			Code:    models.ErrInternalServer,
-			Message: "獲取用戶伺服器列表失敗",
-			Details: err.Error(),
-		}
+	// 檢查用戶是否有權限創建頻道（需要具有管理頻道權限）
+	if msgOpt := cs.checkManageChannels(channel.ServerID.Hex(), userID, "用戶沒有權限在該伺服器創建頻道"); msgOpt != nil {
+		return nil, msgOpt
 	}
 
-	hasAccess := false
-	for _, member := range serverMembers {
-		if member.ServerID == channel.ServerID {
-			hasAccess = true
-			break
+	// 指定類別時確認類別屬於同一伺服器
+	if !channel.CategoryID.IsZero() {
+		if _, msgOpt := cs.getServerCategory(channel.ServerID, channel.CategoryID.Hex()); msgOpt != nil {
+			return nil, msgOpt
 		}
 	}
 
-	if !hasAccess {
+	// 新頻道排在所屬類別的最後
+	channels, err := cs.channelRepo.GetChannelsByServerID(channel.ServerID.Hex())
+	if err != nil {
 		return nil, &models.MessageOptions{
-			Code:    models.ErrUnauthorized,
-			Message: "用戶沒有權限在該伺服器創建頻道",
+			Code:    models.ErrInternalServer,
+			Message: "獲取頻道列表失敗",
+			Details: err.Error(),
 		}
 	}
+	channel.Position = nextChannelPosition(channels, channel.CategoryID)
 
 	// 設置頻道ID
 	if channel.ID.IsZero() {
@@ -231,21 +288,18 @@ func (cs *channelService) CreateChannel(userID string, channel *models.Channel)
 		}
 	}
 
+	cs.recordChannelAuditLog(userID, models.AuditActionChannelCreate, channel, auditChanges(nil, channel))
+
 	// 返回創建的頻道響應
-	channelResponse := &models.ChannelResponse{
-		ID:       channel.ID,
-		ServerID: channel.ServerID,
-		Name:     channel.Name,
-		Type:     channel.Type,
-	}
+	channelResponse := toChannelResponse(channel)
 
-	return channelResponse, nil
+	return &channelResponse, nil
 }
 
 // UpdateChannel 更新頻道信息
 func (cs *channelService) UpdateChannel(userID string, channelID string, updates map[string]any) (*models.ChannelResponse, *models.MessageOptions) {
 	// 獲取頻道信息以檢查權限
-	channel, err := cs.channelRepo.GetChannelByID(channelID)
+	channel, err := cs.channelRepo.GetChannelByID(context.TODO(), channelID)
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
@@ -254,29 +308,26 @@ func (cs *channelService) UpdateChannel(userID string, channelID string, updates
 		}
 	}
 
-	// 檢查用戶是否有權限更新該頻道
-	serverMembers, err := cs.serverMemberRepo.GetUserServers(userID)
-	if err != nil {
-		return nil, &models.MessageOptions{
-			Code:    models.ErrInternalServer,
-			Message: "獲取用戶伺服器列表失敗",
-			Details: err.Error(),
-		}
+	// 檢查用戶是否有權限更新該頻道（需要具有管理頻道權限）
+	if msgOpt := cs.checkManageChannels(channel.ServerID.Hex(), userID, "用戶沒有權限更新該頻道"); msgOpt != nil {
+		return nil, msgOpt
 	}
 
-	hasAccess := false
-	for _, member := range serverMembers {
-		if member.ServerID == channel.ServerID {
-			hasAccess = true
-			break
+	// 移動到其他類別時確認類別屬於同一伺服器，並排在新類別的最後
+	if categoryID, ok := updates["category_id"].(primitive.ObjectID); ok && categoryID != channel.CategoryID {
+		if _, msgOpt := cs.getServerCategory(channel.ServerID, categoryID.Hex()); msgOpt != nil {
+			return nil, msgOpt
 		}
-	}
 
-	if !hasAccess {
-		return nil, &models.MessageOptions{
-			Code:    models.ErrUnauthorized,
-			Message: "用戶沒有權限更新該頻道",
+		channels, err := cs.channelRepo.GetChannelsByServerID(channel.ServerID.Hex())
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "獲取頻道列表失敗",
+				Details: err.Error(),
+			}
 		}
+		updates["position"] = nextChannelPosition(channels, categoryID)
 	}
 
 	// 更新頻道
@@ -290,7 +341,7 @@ func (cs *channelService) UpdateChannel(userID string, channelID string, updates
 	}
 
 	// 重新獲取更新後的頻道信息
-	updatedChannel, err := cs.channelRepo.GetChannelByID(channelID)
+	updatedChannel, err := cs.channelRepo.GetChannelByID(context.TODO(), channelID)
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
@@ -299,21 +350,20 @@ func (cs *channelService) UpdateChannel(userID string, channelID string, updates
 		}
 	}
 
-	// 返回更新後的頻道響應
-	channelResponse := &models.ChannelResponse{
-		ID:       updatedChannel.ID,
-		ServerID: updatedChannel.ServerID,
-		Name:     updatedChannel.Name,
-		Type:     updatedChannel.Type,
+	if changes := auditChanges(channel, updatedChannel); len(changes) > 0 {
+		cs.recordChannelAuditLog(userID, models.AuditActionChannelUpdate, channel, changes)
 	}
 
-	return channelResponse, nil
+	// 返回更新後的頻道響應
+	channelResponse := toChannelResponse(updatedChannel)
+
+	return &channelResponse, nil
 }
 
 // DeleteChannel 刪除頻道
 func (cs *channelService) DeleteChannel(userID string, channelID string) *models.MessageOptions {
 	// 獲取頻道信息以檢查權限
-	channel, err := cs.channelRepo.GetChannelByID(channelID)
+	channel, err := cs.channelRepo.GetChannelByID(context.TODO(), channelID)
 	if err != nil {
 		return &models.MessageOptions{
 			Code:    models.ErrInternalServer,
@@ -322,47 +372,521 @@ func (cs *channelService) DeleteChannel(userID string, channelID string) *models
 		}
 	}
 
-	// 檢查用戶是否有權限刪除該頻道
-	serverMembers, err := cs.serverMemberRepo.GetUserServers(userID)
+	// 檢查用戶是否有權限刪除該頻道（需要具有管理頻道權限）
+	if msgOpt := cs.checkManageChannels(channel.ServerID.Hex(), userID, "用戶沒有權限刪除該頻道"); msgOpt != nil {
+		return msgOpt
+	}
+
+	// 先刪除該頻道的所有訊息
+	err = cs.chatRepo.DeleteMessagesByRoomID(context.TODO(), channelID)
+	if err != nil {
+		// 記錄錯誤但不阻止頻道刪除
+		slog.Error("刪除頻道訊息失敗", "channel_id", channelID, "error", err)
+	}
+
+	// 然後刪除頻道本身
+	err = cs.channelRepo.DeleteChannel(channelID)
 	if err != nil {
 		return &models.MessageOptions{
 			Code:    models.ErrInternalServer,
-			Message: "獲取用戶伺服器列表失敗",
+			Message: "刪除頻道失敗",
 			Details: err.Error(),
 		}
 	}
 
-	hasAccess := false
-	for _, member := range serverMembers {
-		if member.ServerID == channel.ServerID {
-			hasAccess = true
-			break
+	cs.recordChannelAuditLog(userID, models.AuditActionChannelDelete, channel, auditChanges(channel, nil))
+
+	return nil
+}
+
+// recordChannelAuditLog 寫入以頻道為目標的稽核紀錄
+func (cs *channelService) recordChannelAuditLog(userID string, action string, channel *models.Channel, changes []models.AuditLogChange) {
+	actorObjectID, _ := primitive.ObjectIDFromHex(userID)
+	recordAuditLog(cs.auditLogRepo, &models.AuditLog{
+		ServerID:   channel.ServerID,
+		ActorID:    actorObjectID,
+		Action:     action,
+		TargetType: models.AuditTargetChannel,
+		TargetID:   channel.ID,
+		Changes:    changes,
+	})
+}
+
+// CreateCategory 創建頻道類別，新類別排在最後
+func (cs *channelService) CreateCategory(userID string, serverID string, request models.CreateChannelCategoryRequest) (*models.ChannelCategoryResponse, *models.MessageOptions) {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的伺服器ID格式",
+			Details: err.Error(),
 		}
 	}
 
-	if !hasAccess {
-		return &models.MessageOptions{
-			Code:    models.ErrUnauthorized,
-			Message: "用戶沒有權限刪除該頻道",
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "類別名稱不能為空",
 		}
 	}
 
-	// 先刪除該頻道的所有訊息
-	err = cs.chatRepo.DeleteMessagesByRoomID(context.TODO(), channelID)
+	categoryType := request.CategoryType
+	if categoryType == "" {
+		categoryType = models.CategoryTypeCustom
+	}
+	if !slices.Contains([]string{models.CategoryTypeText, models.CategoryTypeVoice, models.CategoryTypeCustom}, categoryType) {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的類別類型",
+		}
+	}
+
+	if msgOpt := cs.checkManageChannels(serverID, userID, "用戶沒有權限在該伺服器創建頻道類別"); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	categories, err := cs.channelCategoryRepo.GetChannelCategoriesByServerID(serverID)
 	if err != nil {
-		// 記錄錯誤但不阻止頻道刪除
-		slog.Error("刪除頻道訊息失敗", "channel_id", channelID, "error", err)
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取頻道類別失敗",
+			Details: err.Error(),
+		}
 	}
 
-	// 然後刪除頻道本身
-	err = cs.channelRepo.DeleteChannel(channelID)
+	position := 0
+	for _, category := range categories {
+		position = max(position, category.Position+1)
+	}
+
+	category := &models.ChannelCategory{
+		Name:         name,
+		ServerID:     serverObjectID,
+		CategoryType: categoryType,
+		Position:     position,
+	}
+	if err := cs.channelCategoryRepo.CreateChannelCategory(category); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "創建頻道類別失敗",
+			Details: err.Error(),
+		}
+	}
+
+	cs.recordCategoryAuditLog(userID, models.AuditActionCategoryCreate, category, auditChanges(nil, category))
+
+	response := toChannelCategoryResponse(category)
+	return &response, nil
+}
+
+// UpdateCategory 重新命名頻道類別
+func (cs *channelService) UpdateCategory(userID string, categoryID string, request models.UpdateChannelCategoryRequest) (*models.ChannelCategoryResponse, *models.MessageOptions) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "類別名稱不能為空",
+		}
+	}
+
+	category, msgOpt := cs.getCategory(categoryID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if msgOpt := cs.checkManageChannels(category.ServerID.Hex(), userID, "用戶沒有權限更新該頻道類別"); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if err := cs.channelCategoryRepo.UpdateChannelCategory(categoryID, map[string]any{"name": name}); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "更新頻道類別失敗",
+			Details: err.Error(),
+		}
+	}
+
+	updatedCategory := *category
+	updatedCategory.Name = name
+	if changes := auditChanges(category, &updatedCategory); len(changes) > 0 {
+		cs.recordCategoryAuditLog(userID, models.AuditActionCategoryUpdate, category, changes)
+	}
+
+	response := toChannelCategoryResponse(&updatedCategory)
+	return &response, nil
+}
+
+// DeleteCategory 刪除頻道類別，類別內的頻道依原順序移到未分類頻道之後
+func (cs *channelService) DeleteCategory(userID string, categoryID string) *models.MessageOptions {
+	category, msgOpt := cs.getCategory(categoryID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	serverID := category.ServerID.Hex()
+	if msgOpt := cs.checkManageChannels(serverID, userID, "用戶沒有權限刪除該頻道類別"); msgOpt != nil {
+		return msgOpt
+	}
+
+	channels, err := cs.channelRepo.GetChannelsByServerID(serverID)
 	if err != nil {
 		return &models.MessageOptions{
 			Code:    models.ErrInternalServer,
-			Message: "刪除頻道失敗",
+			Message: "獲取頻道列表失敗",
+			Details: err.Error(),
+		}
+	}
+
+	var categoryChannels []models.Channel
+	for _, channel := range channels {
+		if channel.CategoryID == category.ID {
+			categoryChannels = append(categoryChannels, channel)
+		}
+	}
+	slices.SortStableFunc(categoryChannels, compareChannels)
+
+	nextPosition := nextChannelPosition(channels, primitive.NilObjectID)
+	positions := make([]models.ChannelPosition, 0, len(categoryChannels))
+	for i, channel := range categoryChannels {
+		positions = append(positions, models.ChannelPosition{
+			ChannelID:  channel.ID,
+			CategoryID: primitive.NilObjectID,
+			Position:   nextPosition + i,
+		})
+	}
+	if err := cs.channelRepo.UpdateChannelPositions(serverID, positions); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "移出類別內的頻道失敗",
+			Details: err.Error(),
+		}
+	}
+
+	if err := cs.channelCategoryRepo.DeleteChannelCategory(categoryID); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "刪除頻道類別失敗",
 			Details: err.Error(),
 		}
 	}
 
+	cs.recordCategoryAuditLog(userID, models.AuditActionCategoryDelete, category, auditChanges(category, nil))
+
 	return nil
 }
+
+// ReorderChannels 批量調整類別排序與頻道的類別和排序，返回調整後的頻道樹
+// 所有項目都必須屬於該伺服器，驗證全部通過後才寫入；寫入中途失敗時還原已寫入的項目，整批全部套用或全部不套用
+func (cs *channelService) ReorderChannels(userID string, serverID string, request models.ReorderChannelsRequest) (*models.ChannelTreeResponse, *models.MessageOptions) {
+	if len(request.Categories) == 0 && len(request.Channels) == 0 {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "沒有提供要調整的排序",
+		}
+	}
+
+	if _, err := primitive.ObjectIDFromHex(serverID); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的伺服器ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	if msgOpt := cs.checkManageChannels(serverID, userID, "用戶沒有權限調整該伺服器的頻道排序"); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	categories, err := cs.channelCategoryRepo.GetChannelCategoriesByServerID(serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取頻道類別失敗",
+			Details: err.Error(),
+		}
+	}
+	channels, err := cs.channelRepo.GetChannelsByServerID(serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取頻道列表失敗",
+			Details: err.Error(),
+		}
+	}
+
+	categoryByID := make(map[string]*models.ChannelCategory, len(categories))
+	for i := range categories {
+		categoryByID[categories[i].ID.Hex()] = &categories[i]
+	}
+	channelByID := make(map[string]*models.Channel, len(channels))
+	for i := range channels {
+		channelByID[channels[i].ID.Hex()] = &channels[i]
+	}
+
+	// 驗證類別排序，只寫入位置有變動的類別並保留原位置以便還原
+	var categoryPositions, previousCategoryPositions []models.CategoryPosition
+	seen := make(map[string]bool, len(request.Categories)+len(request.Channels))
+	for _, item := range request.Categories {
+		category, ok := categoryByID[item.ID]
+		if !ok {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrCategoryNotFound,
+				Message: "頻道類別不存在",
+				Details: item.ID,
+			}
+		}
+		if msgOpt := validatePositionItem(seen, item.ID, item.Position); msgOpt != nil {
+			return nil, msgOpt
+		}
+		if category.Position == item.Position {
+			continue
+		}
+
+		categoryPositions = append(categoryPositions, models.CategoryPosition{CategoryID: category.ID, Position: item.Position})
+		previousCategoryPositions = append(previousCategoryPositions, models.CategoryPosition{CategoryID: category.ID, Position: category.Position})
+	}
+
+	// 驗證頻道的目標類別與排序，只寫入有變動的頻道並保留原類別與位置以便還原
+	var channelPositions, previousChannelPositions []models.ChannelPosition
+	var movedChannels []*models.Channel
+	for _, item := range request.Channels {
+		channel, ok := channelByID[item.ID]
+		if !ok {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrChannelNotFound,
+				Message: "頻道不存在",
+				Details: item.ID,
+			}
+		}
+		if msgOpt := validatePositionItem(seen, item.ID, item.Position); msgOpt != nil {
+			return nil, msgOpt
+		}
+
+		categoryID := primitive.NilObjectID
+		if item.CategoryID != "" {
+			category, ok := categoryByID[item.CategoryID]
+			if !ok {
+				return nil, &models.MessageOptions{
+					Code:    models.ErrCategoryNotFound,
+					Message: "頻道類別不存在",
+					Details: item.CategoryID,
+				}
+			}
+			categoryID = category.ID
+		}
+		if channel.CategoryID == categoryID && channel.Position == item.Position {
+			continue
+		}
+
+		channelPositions = append(channelPositions, models.ChannelPosition{ChannelID: channel.ID, CategoryID: categoryID, Position: item.Position})
+		previousChannelPositions = append(previousChannelPositions, models.ChannelPosition{ChannelID: channel.ID, CategoryID: channel.CategoryID, Position: channel.Position})
+		if channel.CategoryID != categoryID {
+			movedChannels = append(movedChannels, channel)
+		}
+	}
+
+	if err := cs.applyPositions(serverID, categoryPositions, channelPositions, previousCategoryPositions, previousChannelPositions); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "調整頻道排序失敗",
+			Details: err.Error(),
+		}
+	}
+
+	// 僅記錄跨類別移動的頻道，單純調整順序不寫入稽核紀錄
+	for _, channel := range movedChannels {
+		movedChannel := *channel
+		for _, position := range channelPositions {
+			if position.ChannelID == channel.ID {
+				movedChannel.CategoryID = position.CategoryID
+				movedChannel.Position = position.Position
+				break
+			}
+		}
+		cs.recordChannelAuditLog(userID, models.AuditActionChannelUpdate, channel, auditChanges(channel, &movedChannel))
+	}
+
+	return cs.GetChannelsByServerID(userID, serverID)
+}
+
+// applyPositions 依序寫入類別與頻道的排序位置
+// 專案未使用 MongoDB 交易，任一批量寫入失敗時以原位置補償寫回，避免留下只套用一半的排序
+func (cs *channelService) applyPositions(serverID string,
+	categoryPositions []models.CategoryPosition,
+	channelPositions []models.ChannelPosition,
+	previousCategoryPositions []models.CategoryPosition,
+	previousChannelPositions []models.ChannelPosition,
+) error {
+	if err := cs.channelCategoryRepo.UpdateChannelCategoryPositions(serverID, categoryPositions); err != nil {
+		cs.restorePositions(serverID, previousCategoryPositions, nil)
+		return err
+	}
+
+	if err := cs.channelRepo.UpdateChannelPositions(serverID, channelPositions); err != nil {
+		cs.restorePositions(serverID, previousCategoryPositions, previousChannelPositions)
+		return err
+	}
+
+	return nil
+}
+
+// restorePositions 將類別與頻道寫回原本的排序位置，失敗只記錄日誌
+func (cs *channelService) restorePositions(serverID string, categoryPositions []models.CategoryPosition, channelPositions []models.ChannelPosition) {
+	if err := cs.channelCategoryRepo.UpdateChannelCategoryPositions(serverID, categoryPositions); err != nil {
+		slog.Error("還原頻道類別排序失敗", "server_id", serverID, "error", err)
+	}
+	if err := cs.channelRepo.UpdateChannelPositions(serverID, channelPositions); err != nil {
+		slog.Error("還原頻道排序失敗", "server_id", serverID, "error", err)
+	}
+}
+
+// validatePositionItem 檢查排序項目未重複出現且位置不為負數
+func validatePositionItem(seen map[string]bool, id string, position int) *models.MessageOptions {
+	if seen[id] {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "排序項目重複",
+			Details: id,
+		}
+	}
+	seen[id] = true
+
+	if position < 0 {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "排序位置不能為負數",
+			Details: id,
+		}
+	}
+
+	return nil
+}
+
+// getCategory 根據類別ID獲取頻道類別，不存在時返回類別不存在錯誤
+func (cs *channelService) getCategory(categoryID string) (*models.ChannelCategory, *models.MessageOptions) {
+	category, err := cs.channelCategoryRepo.GetChannelCategoryByID(categoryID)
+	if err != nil {
+		if errors.Is(err, providers.ErrDocumentNotFound) || errors.Is(err, providers.ErrInvalidID) {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrCategoryNotFound,
+				Message: "頻道類別不存在",
+			}
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取頻道類別失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return category, nil
+}
+
+// getServerCategory 獲取屬於指定伺服器的頻道類別，其他伺服器的類別視為不存在
+func (cs *channelService) getServerCategory(serverID primitive.ObjectID, categoryID string) (*models.ChannelCategory, *models.MessageOptions) {
+	category, msgOpt := cs.getCategory(categoryID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if category.ServerID != serverID {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrCategoryNotFound,
+			Message: "頻道類別不存在",
+		}
+	}
+
+	return category, nil
+}
+
+// recordCategoryAuditLog 寫入以頻道類別為目標的稽核紀錄
+func (cs *channelService) recordCategoryAuditLog(userID string, action string, category *models.ChannelCategory, changes []models.AuditLogChange) {
+	actorObjectID, _ := primitive.ObjectIDFromHex(userID)
+	recordAuditLog(cs.auditLogRepo, &models.AuditLog{
+		ServerID:   category.ServerID,
+		ActorID:    actorObjectID,
+		Action:     action,
+		TargetType: models.AuditTargetCategory,
+		TargetID:   category.ID,
+		Changes:    changes,
+	})
+}
+
+// nextChannelPosition 返回類別內下一個排序位置（排在現有頻道之後）
+func nextChannelPosition(channels []models.Channel, categoryID primitive.ObjectID) int {
+	position := 0
+	for _, channel := range channels {
+		if channel.CategoryID == categoryID {
+			position = max(position, channel.Position+1)
+		}
+	}
+	return position
+}
+
+// compareChannels 依排序位置排列頻道，位置相同時依創建順序（ID）排列
+func compareChannels(a, b models.Channel) int {
+	return cmp.Or(cmp.Compare(a.Position, b.Position), strings.Compare(a.ID.Hex(), b.ID.Hex()))
+}
+
+// buildChannelTree 依排序位置組成類別→頻道樹，所屬類別不存在的頻道歸入未分類
+func buildChannelTree(categories []models.ChannelCategory, channels []models.Channel, readStates map[string]models.RoomReadState) *models.ChannelTreeResponse {
+	categories = slices.Clone(categories)
+	slices.SortStableFunc(categories, func(a, b models.ChannelCategory) int {
+		return cmp.Or(cmp.Compare(a.Position, b.Position), strings.Compare(a.ID.Hex(), b.ID.Hex()))
+	})
+	channels = slices.Clone(channels)
+	slices.SortStableFunc(channels, compareChannels)
+
+	tree := &models.ChannelTreeResponse{
+		Uncategorized: make([]models.ChannelResponse, 0),
+		Categories:    make([]models.ChannelCategoryResponse, 0, len(categories)),
+	}
+	categoryIndex := make(map[primitive.ObjectID]int, len(categories))
+	for i := range categories {
+		categoryIndex[categories[i].ID] = i
+		tree.Categories = append(tree.Categories, toChannelCategoryResponse(&categories[i]))
+	}
+
+	for i := range channels {
+		response := toChannelResponse(&channels[i])
+		readState := readStates[channels[i].ID.Hex()]
+		response.UnreadCount = readState.UnreadCount
+		response.LastReadMessageID = readState.LastReadMessageID
+
+		if index, ok := categoryIndex[channels[i].CategoryID]; ok {
+			tree.Categories[index].Channels = append(tree.Categories[index].Channels, response)
+		} else {
+			tree.Uncategorized = append(tree.Uncategorized, response)
+		}
+	}
+
+	return tree
+}
+
+// toChannelResponse 轉換頻道為響應格式（不含已讀狀態）
+func toChannelResponse(channel *models.Channel) models.ChannelResponse {
+	response := models.ChannelResponse{
+		ID:       channel.ID,
+		ServerID: channel.ServerID,
+		Name:     channel.Name,
+		Type:     channel.Type,
+		Position: channel.Position,
+	}
+	if !channel.CategoryID.IsZero() {
+		response.CategoryID = channel.CategoryID.Hex()
+	}
+	return response
+}
+
+// toChannelCategoryResponse 轉換頻道類別為響應格式（頻道列表為空）
+func toChannelCategoryResponse(category *models.ChannelCategory) models.ChannelCategoryResponse {
+	return models.ChannelCategoryResponse{
+		ID:           category.ID.Hex(),
+		ServerID:     category.ServerID.Hex(),
+		Name:         category.Name,
+		CategoryType: category.CategoryType,
+		Position:     category.Position,
+		Channels:     make([]models.ChannelResponse, 0),
+	}
+}
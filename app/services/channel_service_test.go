@@ -4,7 +4,9 @@ import (
 	"chat_app_backend/app/mocks"
 	"chat_app_backend/app/models"
 	"chat_app_backend/app/providers"
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -27,8 +29,8 @@ func (m *mockChannelServiceChannelRepository) GetChannelsByServerID(serverID str
 	return args.Get(0).([]models.Channel), args.Error(1)
 }
 
-func (m *mockChannelServiceChannelRepository) GetChannelByID(channelID string) (*models.Channel, error) {
-	args := m.Called(channelID)
+func (m *mockChannelServiceChannelRepository) GetChannelByID(ctx context.Context, channelID string) (*models.Channel, error) {
+	args := m.Called(ctx, channelID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -55,6 +57,11 @@ func (m *mockChannelServiceChannelRepository) CheckChannelExists(channelID strin
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockChannelServiceChannelRepository) UpdateChannelPositions(serverID string, positions []models.ChannelPosition) error {
+	args := m.Called(serverID, positions)
+	return args.Error(0)
+}
+
 // mockChannelServiceServerMemberRepository 模擬 ServerMemberRepository
 type mockChannelServiceServerMemberRepository struct {
 	mock.Mock
@@ -101,10 +108,29 @@ func (m *mockChannelServiceServerMemberRepository) GetMemberCount(serverID strin
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *mockChannelServiceServerMemberRepository) GetServerMember(ctx context.Context, serverID, userID string) (*models.ServerMember, error) {
+	args := m.Called(ctx, serverID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServerMember), args.Error(1)
+}
+
+func (m *mockChannelServiceServerMemberRepository) AddRoleToMember(ctx context.Context, serverID, userID, roleID string) error {
+	args := m.Called(ctx, serverID, userID, roleID)
+	return args.Error(0)
+}
+
+func (m *mockChannelServiceServerMemberRepository) RemoveRoleFromMember(ctx context.Context, serverID, userID, roleID string) error {
+	args := m.Called(ctx, serverID, userID, roleID)
+	return args.Error(0)
+}
+
 // --- Tests ---
 
 func TestNewChannelService(t *testing.T) {
 	mockChannelRepo := new(mockChannelServiceChannelRepository)
+	mockCategoryRepo := new(mockChannelCategoryRepository)
 	mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
 	mockChatRepo := new(mocks.ChatRepository)
 
@@ -112,32 +138,48 @@ func TestNewChannelService(t *testing.T) {
 		nil,
 		nil,
 		mockChannelRepo,
+		mockCategoryRepo,
 		nil,
 		mockServerMemberRepo,
 		nil,
 		mockChatRepo,
 		nil,
+		nil,
+		nil,
 	)
 
 	assert.NotNil(t, service)
 	assert.Equal(t, mockChannelRepo, service.channelRepo)
+	assert.Equal(t, mockCategoryRepo, service.channelCategoryRepo)
 	assert.Equal(t, mockServerMemberRepo, service.serverMemberRepo)
 	assert.Equal(t, mockChatRepo, service.chatRepo)
 }
 
 func TestGetChannelsByServerID(t *testing.T) {
-	t.Run("成功獲取頻道列表", func(t *testing.T) {
+	t.Run("成功獲取頻道樹", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
 		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockChatRepo := new(mocks.ChatRepository)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
 		channelID1 := primitive.NewObjectID()
 		channelID2 := primitive.NewObjectID()
+		channelID3 := primitive.NewObjectID()
+		channelID4 := primitive.NewObjectID()
+		textCategoryID := primitive.NewObjectID()
+		voiceCategoryID := primitive.NewObjectID()
+		lastReadID := primitive.NewObjectID()
+
+		mockPS := new(mocks.PermissionService)
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			serverMemberRepo:    mockServerMemberRepo,
+			chatRepo:            mockChatRepo,
+			permissionService:   mockPS,
 		}
 
 		serverMembers := []models.ServerMember{
@@ -148,36 +190,123 @@ func TestGetChannelsByServerID(t *testing.T) {
 			},
 		}
 
+		categories := []models.ChannelCategory{
+			{BaseModel: providers.BaseModel{ID: voiceCategoryID}, ServerID: serverID, Name: "語音頻道", CategoryType: "voice", Position: 2},
+			{BaseModel: providers.BaseModel{ID: textCategoryID}, ServerID: serverID, Name: "文字頻道", CategoryType: "text", Position: 1},
+		}
+
 		channels := []models.Channel{
 			{
-				BaseModel: providers.BaseModel{ID: channelID1},
-				ServerID:  serverID,
-				Name:      "general",
-				Type:      "text",
+				BaseModel:  providers.BaseModel{ID: channelID1},
+				ServerID:   serverID,
+				CategoryID: textCategoryID,
+				Position:   1,
+				Name:       "general",
+				Type:       "text",
 			},
 			{
-				BaseModel: providers.BaseModel{ID: channelID2},
+				BaseModel:  providers.BaseModel{ID: channelID2},
+				ServerID:   serverID,
+				CategoryID: voiceCategoryID,
+				Name:       "voice-channel",
+				Type:       "voice",
+			},
+			{
+				BaseModel:  providers.BaseModel{ID: channelID3},
+				ServerID:   serverID,
+				CategoryID: textCategoryID,
+				Position:   0,
+				Name:       "rules",
+				Type:       "text",
+			},
+			{
+				BaseModel: providers.BaseModel{ID: channelID4},
 				ServerID:  serverID,
-				Name:      "voice-channel",
-				Type:      "voice",
+				Name:      "lobby",
+				Type:      "text",
 			},
 		}
 
 		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
 		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return(channels, nil).Once()
+		mockPS.On("FilterViewableChannels", mock.Anything, serverID.Hex(), userID.Hex(), channels).Return(channels, nil).Once()
+		mockChatRepo.On("GetRoomReadStates", mock.Anything, userID.Hex(), []string{channelID1.Hex(), channelID2.Hex(), channelID3.Hex(), channelID4.Hex()}).Return(map[string]models.RoomReadState{
+			channelID1.Hex(): {LastReadMessageID: lastReadID.Hex(), UnreadCount: 3},
+		}, nil).Once()
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return(categories, nil).Once()
 
 		result, msgOpt := service.GetChannelsByServerID(userID.Hex(), serverID.Hex())
 
 		assert.Nil(t, msgOpt)
 		assert.NotNil(t, result)
-		assert.Len(t, result, 2)
-		assert.Equal(t, "general", result[0].Name)
-		assert.Equal(t, "text", result[0].Type)
-		assert.Equal(t, "voice-channel", result[1].Name)
-		assert.Equal(t, "voice", result[1].Type)
+
+		assert.Len(t, result.Uncategorized, 1)
+		assert.Equal(t, "lobby", result.Uncategorized[0].Name)
+		assert.Empty(t, result.Uncategorized[0].CategoryID)
+
+		assert.Len(t, result.Categories, 2)
+		assert.Equal(t, "文字頻道", result.Categories[0].Name)
+		assert.Equal(t, "語音頻道", result.Categories[1].Name)
+
+		textChannels := result.Categories[0].Channels
+		assert.Len(t, textChannels, 2)
+		assert.Equal(t, "rules", textChannels[0].Name)
+		assert.Equal(t, "general", textChannels[1].Name)
+		assert.Equal(t, textCategoryID.Hex(), textChannels[1].CategoryID)
+		assert.Equal(t, 1, textChannels[1].Position)
+		assert.Equal(t, int64(3), textChannels[1].UnreadCount)
+		assert.Equal(t, lastReadID.Hex(), textChannels[1].LastReadMessageID)
+		assert.Equal(t, int64(0), textChannels[0].UnreadCount)
+
+		assert.Len(t, result.Categories[1].Channels, 1)
+		assert.Equal(t, "voice-channel", result.Categories[1].Channels[0].Name)
+		assert.Equal(t, "voice", result.Categories[1].Channels[0].Type)
 
 		mockServerMemberRepo.AssertExpectations(t)
 		mockChannelRepo.AssertExpectations(t)
+		mockCategoryRepo.AssertExpectations(t)
+		mockChatRepo.AssertExpectations(t)
+	})
+
+	t.Run("過濾無法查看的私人頻道", func(t *testing.T) {
+		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockChatRepo := new(mocks.ChatRepository)
+		mockPS := new(mocks.PermissionService)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+		categoryID := primitive.NewObjectID()
+		publicChannel := models.Channel{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, CategoryID: categoryID, Name: "general"}
+		privateChannel := models.Channel{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, CategoryID: categoryID, Name: "staff"}
+		channels := []models.Channel{publicChannel, privateChannel}
+
+		service := &channelService{
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			serverMemberRepo:    mockServerMemberRepo,
+			chatRepo:            mockChatRepo,
+			permissionService:   mockPS,
+		}
+
+		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return([]models.ServerMember{{ServerID: serverID, UserID: userID}}, nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return(channels, nil).Once()
+		mockPS.On("FilterViewableChannels", mock.Anything, serverID.Hex(), userID.Hex(), channels).Return([]models.Channel{publicChannel}, nil).Once()
+		mockChatRepo.On("GetRoomReadStates", mock.Anything, userID.Hex(), []string{publicChannel.ID.Hex()}).Return(map[string]models.RoomReadState{}, nil).Once()
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return([]models.ChannelCategory{
+			{BaseModel: providers.BaseModel{ID: categoryID}, ServerID: serverID, Name: "文字頻道"},
+		}, nil).Once()
+
+		result, msgOpt := service.GetChannelsByServerID(userID.Hex(), serverID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Empty(t, result.Uncategorized)
+		assert.Len(t, result.Categories, 1)
+		assert.Len(t, result.Categories[0].Channels, 1)
+		assert.Equal(t, "general", result.Categories[0].Channels[0].Name)
+		mockPS.AssertExpectations(t)
+		mockChatRepo.AssertExpectations(t)
 	})
 
 	t.Run("獲取用戶伺服器列表失敗", func(t *testing.T) {
@@ -298,9 +427,12 @@ func TestGetChannelByID(t *testing.T) {
 		serverID := primitive.NewObjectID()
 		channelID := primitive.NewObjectID()
 
+		mockPS := new(mocks.PermissionService)
+
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
+			channelRepo:       mockChannelRepo,
+			serverMemberRepo:  mockServerMemberRepo,
+			permissionService: mockPS,
 		}
 
 		channel := &models.Channel{
@@ -318,8 +450,9 @@ func TestGetChannelByID(t *testing.T) {
 			},
 		}
 
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(channel, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(channel, nil).Once()
 		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockPS.On("CheckChannelPermission", mock.Anything, channelID.Hex(), userID.Hex(), models.PermissionViewChannel).Return(nil).Once()
 
 		result, msgOpt := service.GetChannelByID(userID.Hex(), channelID.Hex())
 
@@ -343,7 +476,7 @@ func TestGetChannelByID(t *testing.T) {
 			channelRepo: mockChannelRepo,
 		}
 
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(nil, errors.New("channel not found")).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(nil, errors.New("channel not found")).Once()
 
 		result, msgOpt := service.GetChannelByID(userID.Hex(), channelID.Hex())
 
@@ -384,7 +517,7 @@ func TestGetChannelByID(t *testing.T) {
 			},
 		}
 
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(channel, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(channel, nil).Once()
 		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
 
 		result, msgOpt := service.GetChannelByID(userID.Hex(), channelID.Hex())
@@ -402,14 +535,14 @@ func TestGetChannelByID(t *testing.T) {
 func TestCreateChannel(t *testing.T) {
 	t.Run("成功創建頻道", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
 		}
 
 		channel := &models.Channel{
@@ -418,15 +551,8 @@ func TestCreateChannel(t *testing.T) {
 			Type:     "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  serverID,
-				UserID:    userID,
-			},
-		}
-
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return([]models.Channel{}, nil).Once()
 		mockChannelRepo.On("CreateChannel", mock.AnythingOfType("*models.Channel")).Return(nil).Once()
 
 		result, msgOpt := service.CreateChannel(userID.Hex(), channel)
@@ -437,19 +563,18 @@ func TestCreateChannel(t *testing.T) {
 		assert.Equal(t, "text", result.Type)
 		assert.False(t, result.ID.IsZero())
 
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
 		mockChannelRepo.AssertExpectations(t)
 	})
 
 	t.Run("用戶沒有權限創建頻道", func(t *testing.T) {
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
-		otherServerID := primitive.NewObjectID()
 
 		service := &channelService{
-			serverMemberRepo: mockServerMemberRepo,
+			permissionService: mockPS,
 		}
 
 		channel := &models.Channel{
@@ -458,15 +583,7 @@ func TestCreateChannel(t *testing.T) {
 			Type:     "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  otherServerID, // 不同的伺服器
-				UserID:    userID,
-			},
-		}
-
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(&models.MessageOptions{Code: models.ErrNoServerPermission, Message: "權限不足"}).Once()
 
 		result, msgOpt := service.CreateChannel(userID.Hex(), channel)
 
@@ -475,19 +592,19 @@ func TestCreateChannel(t *testing.T) {
 		assert.Equal(t, models.ErrUnauthorized, msgOpt.Code)
 		assert.Contains(t, msgOpt.Message, "用戶沒有權限在該伺服器創建頻道")
 
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
 	})
 
 	t.Run("創建頻道失敗", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
 		}
 
 		channel := &models.Channel{
@@ -496,15 +613,8 @@ func TestCreateChannel(t *testing.T) {
 			Type:     "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  serverID,
-				UserID:    userID,
-			},
-		}
-
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return([]models.Channel{}, nil).Once()
 		mockChannelRepo.On("CreateChannel", mock.AnythingOfType("*models.Channel")).Return(errors.New("database error")).Once()
 
 		result, msgOpt := service.CreateChannel(userID.Hex(), channel)
@@ -514,21 +624,21 @@ func TestCreateChannel(t *testing.T) {
 		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
 		assert.Contains(t, msgOpt.Message, "創建頻道失敗")
 
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
 		mockChannelRepo.AssertExpectations(t)
 	})
 
 	t.Run("頻道已有ID則使用該ID", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
 		channelID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
 		}
 
 		channel := &models.Channel{
@@ -538,15 +648,8 @@ func TestCreateChannel(t *testing.T) {
 			Type:      "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  serverID,
-				UserID:    userID,
-			},
-		}
-
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return([]models.Channel{}, nil).Once()
 		mockChannelRepo.On("CreateChannel", mock.AnythingOfType("*models.Channel")).Return(nil).Once()
 
 		result, msgOpt := service.CreateChannel(userID.Hex(), channel)
@@ -555,23 +658,99 @@ func TestCreateChannel(t *testing.T) {
 		assert.NotNil(t, result)
 		assert.Equal(t, channelID, result.ID)
 
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
+		mockChannelRepo.AssertExpectations(t)
+	})
+
+	t.Run("新頻道排在所屬類別的最後", func(t *testing.T) {
+		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockPS := new(mocks.PermissionService)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+		categoryID := primitive.NewObjectID()
+
+		service := &channelService{
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			permissionService:   mockPS,
+		}
+
+		channel := &models.Channel{
+			ServerID:   serverID,
+			CategoryID: categoryID,
+			Name:       "new-channel",
+			Type:       "text",
+		}
+
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockCategoryRepo.On("GetChannelCategoryByID", categoryID.Hex()).Return(&models.ChannelCategory{BaseModel: providers.BaseModel{ID: categoryID}, ServerID: serverID}, nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return([]models.Channel{
+			{ServerID: serverID, CategoryID: categoryID, Position: 0},
+			{ServerID: serverID, CategoryID: categoryID, Position: 4},
+			{ServerID: serverID, Position: 9},
+		}, nil).Once()
+		mockChannelRepo.On("CreateChannel", mock.MatchedBy(func(c *models.Channel) bool {
+			return c.Position == 5 && c.CategoryID == categoryID
+		})).Return(nil).Once()
+
+		result, msgOpt := service.CreateChannel(userID.Hex(), channel)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, 5, result.Position)
+		assert.Equal(t, categoryID.Hex(), result.CategoryID)
+
 		mockChannelRepo.AssertExpectations(t)
+		mockCategoryRepo.AssertExpectations(t)
+	})
+
+	t.Run("類別不屬於該伺服器", func(t *testing.T) {
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockPS := new(mocks.PermissionService)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+		categoryID := primitive.NewObjectID()
+
+		service := &channelService{
+			channelCategoryRepo: mockCategoryRepo,
+			permissionService:   mockPS,
+		}
+
+		channel := &models.Channel{
+			ServerID:   serverID,
+			CategoryID: categoryID,
+			Name:       "new-channel",
+		}
+
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockCategoryRepo.On("GetChannelCategoryByID", categoryID.Hex()).Return(&models.ChannelCategory{BaseModel: providers.BaseModel{ID: categoryID}, ServerID: primitive.NewObjectID()}, nil).Once()
+
+		result, msgOpt := service.CreateChannel(userID.Hex(), channel)
+
+		assert.Nil(t, result)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrCategoryNotFound, msgOpt.Code)
+
+		mockCategoryRepo.AssertExpectations(t)
 	})
 }
 
 func TestUpdateChannel(t *testing.T) {
 	t.Run("成功更新頻道", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
+		mockAuditLogRepo := new(mockAuditLogRepository)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
 		channelID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
+			auditLogRepo:      mockAuditLogRepo,
 		}
 
 		channel := &models.Channel{
@@ -588,20 +767,19 @@ func TestUpdateChannel(t *testing.T) {
 			Type:      "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  serverID,
-				UserID:    userID,
-			},
-		}
-
 		updates := map[string]any{"name": "new-name"}
 
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(channel, nil).Once()
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(channel, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
 		mockChannelRepo.On("UpdateChannel", channelID.Hex(), updates).Return(nil).Once()
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(updatedChannel, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(updatedChannel, nil).Once()
+		mockAuditLogRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *models.AuditLog) bool {
+			return entry.Action == models.AuditActionChannelUpdate &&
+				entry.ServerID == serverID &&
+				entry.ActorID == userID &&
+				entry.TargetID == channelID &&
+				assert.ObjectsAreEqual([]models.AuditLogChange{{Key: "name", Before: `"old-name"`, After: `"new-name"`}}, entry.Changes)
+		})).Return(nil).Once()
 
 		result, msgOpt := service.UpdateChannel(userID.Hex(), channelID.Hex(), updates)
 
@@ -610,7 +788,8 @@ func TestUpdateChannel(t *testing.T) {
 		assert.Equal(t, "new-name", result.Name)
 
 		mockChannelRepo.AssertExpectations(t)
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
+		mockAuditLogRepo.AssertExpectations(t)
 	})
 
 	t.Run("獲取頻道信息失敗", func(t *testing.T) {
@@ -625,7 +804,7 @@ func TestUpdateChannel(t *testing.T) {
 
 		updates := map[string]any{"name": "new-name"}
 
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(nil, errors.New("channel not found")).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(nil, errors.New("channel not found")).Once()
 
 		result, msgOpt := service.UpdateChannel(userID.Hex(), channelID.Hex(), updates)
 
@@ -638,16 +817,15 @@ func TestUpdateChannel(t *testing.T) {
 
 	t.Run("用戶沒有權限更新頻道", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
-		otherServerID := primitive.NewObjectID()
 		channelID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
 		}
 
 		channel := &models.Channel{
@@ -657,18 +835,10 @@ func TestUpdateChannel(t *testing.T) {
 			Type:      "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  otherServerID, // 不同的伺服器
-				UserID:    userID,
-			},
-		}
-
 		updates := map[string]any{"name": "new-name"}
 
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(channel, nil).Once()
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(channel, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(&models.MessageOptions{Code: models.ErrNoServerPermission, Message: "權限不足"}).Once()
 
 		result, msgOpt := service.UpdateChannel(userID.Hex(), channelID.Hex(), updates)
 
@@ -677,20 +847,20 @@ func TestUpdateChannel(t *testing.T) {
 		assert.Equal(t, models.ErrUnauthorized, msgOpt.Code)
 
 		mockChannelRepo.AssertExpectations(t)
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
 	})
 
 	t.Run("更新頻道失敗", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
 		channelID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
 		}
 
 		channel := &models.Channel{
@@ -700,18 +870,10 @@ func TestUpdateChannel(t *testing.T) {
 			Type:      "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  serverID,
-				UserID:    userID,
-			},
-		}
-
 		updates := map[string]any{"name": "new-name"}
 
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(channel, nil).Once()
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(channel, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
 		mockChannelRepo.On("UpdateChannel", channelID.Hex(), updates).Return(errors.New("database error")).Once()
 
 		result, msgOpt := service.UpdateChannel(userID.Hex(), channelID.Hex(), updates)
@@ -722,14 +884,14 @@ func TestUpdateChannel(t *testing.T) {
 		assert.Contains(t, msgOpt.Message, "更新頻道失敗")
 
 		mockChannelRepo.AssertExpectations(t)
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
 	})
 }
 
 func TestDeleteChannel(t *testing.T) {
 	t.Run("成功刪除頻道", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 		mockChatRepo := new(mocks.ChatRepository)
 
 		userID := primitive.NewObjectID()
@@ -737,9 +899,9 @@ func TestDeleteChannel(t *testing.T) {
 		channelID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
-			chatRepo:         mockChatRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
+			chatRepo:          mockChatRepo,
 		}
 
 		channel := &models.Channel{
@@ -749,16 +911,8 @@ func TestDeleteChannel(t *testing.T) {
 			Type:      "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  serverID,
-				UserID:    userID,
-			},
-		}
-
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(channel, nil).Once()
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(channel, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
 		mockChatRepo.On("DeleteMessagesByRoomID", mock.Anything, channelID.Hex()).Return(nil).Once()
 		mockChannelRepo.On("DeleteChannel", channelID.Hex()).Return(nil).Once()
 
@@ -767,7 +921,7 @@ func TestDeleteChannel(t *testing.T) {
 		assert.Nil(t, msgOpt)
 
 		mockChannelRepo.AssertExpectations(t)
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
 		mockChatRepo.AssertExpectations(t)
 	})
 
@@ -781,7 +935,7 @@ func TestDeleteChannel(t *testing.T) {
 			channelRepo: mockChannelRepo,
 		}
 
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(nil, errors.New("channel not found")).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(nil, errors.New("channel not found")).Once()
 
 		msgOpt := service.DeleteChannel(userID.Hex(), channelID.Hex())
 
@@ -793,16 +947,15 @@ func TestDeleteChannel(t *testing.T) {
 
 	t.Run("用戶沒有權限刪除頻道", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
-		otherServerID := primitive.NewObjectID()
 		channelID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
 		}
 
 		channel := &models.Channel{
@@ -812,16 +965,8 @@ func TestDeleteChannel(t *testing.T) {
 			Type:      "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  otherServerID, // 不同的伺服器
-				UserID:    userID,
-			},
-		}
-
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(channel, nil).Once()
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(channel, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(&models.MessageOptions{Code: models.ErrNoServerPermission, Message: "權限不足"}).Once()
 
 		msgOpt := service.DeleteChannel(userID.Hex(), channelID.Hex())
 
@@ -829,12 +974,12 @@ func TestDeleteChannel(t *testing.T) {
 		assert.Equal(t, models.ErrUnauthorized, msgOpt.Code)
 
 		mockChannelRepo.AssertExpectations(t)
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
 	})
 
 	t.Run("刪除訊息失敗但繼續刪除頻道", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 		mockChatRepo := new(mocks.ChatRepository)
 
 		userID := primitive.NewObjectID()
@@ -842,9 +987,9 @@ func TestDeleteChannel(t *testing.T) {
 		channelID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
-			chatRepo:         mockChatRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
+			chatRepo:          mockChatRepo,
 		}
 
 		channel := &models.Channel{
@@ -854,16 +999,8 @@ func TestDeleteChannel(t *testing.T) {
 			Type:      "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  serverID,
-				UserID:    userID,
-			},
-		}
-
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(channel, nil).Once()
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(channel, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
 		mockChatRepo.On("DeleteMessagesByRoomID", mock.Anything, channelID.Hex()).Return(errors.New("delete messages error")).Once()
 		mockChannelRepo.On("DeleteChannel", channelID.Hex()).Return(nil).Once()
 
@@ -873,13 +1010,13 @@ func TestDeleteChannel(t *testing.T) {
 		assert.Nil(t, msgOpt)
 
 		mockChannelRepo.AssertExpectations(t)
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
 		mockChatRepo.AssertExpectations(t)
 	})
 
 	t.Run("刪除頻道失敗", func(t *testing.T) {
 		mockChannelRepo := new(mockChannelServiceChannelRepository)
-		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
 		mockChatRepo := new(mocks.ChatRepository)
 
 		userID := primitive.NewObjectID()
@@ -887,9 +1024,9 @@ func TestDeleteChannel(t *testing.T) {
 		channelID := primitive.NewObjectID()
 
 		service := &channelService{
-			channelRepo:      mockChannelRepo,
-			serverMemberRepo: mockServerMemberRepo,
-			chatRepo:         mockChatRepo,
+			channelRepo:       mockChannelRepo,
+			permissionService: mockPS,
+			chatRepo:          mockChatRepo,
 		}
 
 		channel := &models.Channel{
@@ -899,16 +1036,8 @@ func TestDeleteChannel(t *testing.T) {
 			Type:      "text",
 		}
 
-		serverMembers := []models.ServerMember{
-			{
-				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
-				ServerID:  serverID,
-				UserID:    userID,
-			},
-		}
-
-		mockChannelRepo.On("GetChannelByID", channelID.Hex()).Return(channel, nil).Once()
-		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return(serverMembers, nil).Once()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(channel, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
 		mockChatRepo.On("DeleteMessagesByRoomID", mock.Anything, channelID.Hex()).Return(nil).Once()
 		mockChannelRepo.On("DeleteChannel", channelID.Hex()).Return(errors.New("database error")).Once()
 
@@ -919,7 +1048,426 @@ func TestDeleteChannel(t *testing.T) {
 		assert.Contains(t, msgOpt.Message, "刪除頻道失敗")
 
 		mockChannelRepo.AssertExpectations(t)
-		mockServerMemberRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
 		mockChatRepo.AssertExpectations(t)
 	})
 }
+
+func TestCreateCategory(t *testing.T) {
+	t.Run("成功創建類別並排在最後", func(t *testing.T) {
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockPS := new(mocks.PermissionService)
+		mockAuditLogRepo := new(mockAuditLogRepository)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+
+		service := &channelService{
+			channelCategoryRepo: mockCategoryRepo,
+			permissionService:   mockPS,
+			auditLogRepo:        mockAuditLogRepo,
+		}
+
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return([]models.ChannelCategory{
+			{ServerID: serverID, Position: 1},
+			{ServerID: serverID, Position: 2},
+		}, nil).Once()
+		mockCategoryRepo.On("CreateChannelCategory", mock.MatchedBy(func(category *models.ChannelCategory) bool {
+			return category.Name == "公告" && category.CategoryType == models.CategoryTypeCustom && category.Position == 3 && category.ServerID == serverID
+		})).Return(nil).Once()
+		mockAuditLogRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *models.AuditLog) bool {
+			return entry.Action == models.AuditActionCategoryCreate && entry.TargetType == models.AuditTargetCategory
+		})).Return(nil).Once()
+
+		result, msgOpt := service.CreateCategory(userID.Hex(), serverID.Hex(), models.CreateChannelCategoryRequest{Name: " 公告 "})
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "公告", result.Name)
+		assert.Equal(t, 3, result.Position)
+		assert.Empty(t, result.Channels)
+
+		mockCategoryRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
+		mockAuditLogRepo.AssertExpectations(t)
+	})
+
+	t.Run("無效的類別類型", func(t *testing.T) {
+		service := &channelService{}
+
+		result, msgOpt := service.CreateCategory(primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex(), models.CreateChannelCategoryRequest{Name: "公告", CategoryType: "forum"})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+
+	t.Run("用戶沒有管理頻道權限", func(t *testing.T) {
+		mockPS := new(mocks.PermissionService)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+
+		service := &channelService{permissionService: mockPS}
+
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(&models.MessageOptions{Code: models.ErrNoServerPermission}).Once()
+
+		result, msgOpt := service.CreateCategory(userID.Hex(), serverID.Hex(), models.CreateChannelCategoryRequest{Name: "公告"})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrUnauthorized, msgOpt.Code)
+		mockPS.AssertExpectations(t)
+	})
+}
+
+func TestUpdateCategory(t *testing.T) {
+	t.Run("成功重新命名類別", func(t *testing.T) {
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockPS := new(mocks.PermissionService)
+		mockAuditLogRepo := new(mockAuditLogRepository)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+		categoryID := primitive.NewObjectID()
+
+		service := &channelService{
+			channelCategoryRepo: mockCategoryRepo,
+			permissionService:   mockPS,
+			auditLogRepo:        mockAuditLogRepo,
+		}
+
+		category := &models.ChannelCategory{BaseModel: providers.BaseModel{ID: categoryID}, ServerID: serverID, Name: "舊名稱", Position: 1}
+
+		mockCategoryRepo.On("GetChannelCategoryByID", categoryID.Hex()).Return(category, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockCategoryRepo.On("UpdateChannelCategory", categoryID.Hex(), map[string]any{"name": "新名稱"}).Return(nil).Once()
+		mockAuditLogRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *models.AuditLog) bool {
+			return entry.Action == models.AuditActionCategoryUpdate &&
+				entry.TargetID == categoryID &&
+				assert.ObjectsAreEqual([]models.AuditLogChange{{Key: "name", Before: `"舊名稱"`, After: `"新名稱"`}}, entry.Changes)
+		})).Return(nil).Once()
+
+		result, msgOpt := service.UpdateCategory(userID.Hex(), categoryID.Hex(), models.UpdateChannelCategoryRequest{Name: "新名稱"})
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "新名稱", result.Name)
+		assert.Equal(t, 1, result.Position)
+
+		mockCategoryRepo.AssertExpectations(t)
+		mockAuditLogRepo.AssertExpectations(t)
+	})
+
+	t.Run("類別不存在", func(t *testing.T) {
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		categoryID := primitive.NewObjectID()
+
+		service := &channelService{channelCategoryRepo: mockCategoryRepo}
+
+		mockCategoryRepo.On("GetChannelCategoryByID", categoryID.Hex()).Return(nil, fmt.Errorf("查詢頻道類別失敗: %w", providers.ErrDocumentNotFound)).Once()
+
+		result, msgOpt := service.UpdateCategory(primitive.NewObjectID().Hex(), categoryID.Hex(), models.UpdateChannelCategoryRequest{Name: "新名稱"})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrCategoryNotFound, msgOpt.Code)
+		mockCategoryRepo.AssertExpectations(t)
+	})
+}
+
+func TestDeleteCategory(t *testing.T) {
+	t.Run("刪除類別並將頻道依原順序移到未分類之後", func(t *testing.T) {
+		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockPS := new(mocks.PermissionService)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+		categoryID := primitive.NewObjectID()
+		channelA := primitive.NewObjectID()
+		channelB := primitive.NewObjectID()
+
+		service := &channelService{
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			permissionService:   mockPS,
+		}
+
+		category := &models.ChannelCategory{BaseModel: providers.BaseModel{ID: categoryID}, ServerID: serverID, Name: "待刪除"}
+
+		mockCategoryRepo.On("GetChannelCategoryByID", categoryID.Hex()).Return(category, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return([]models.Channel{
+			{BaseModel: providers.BaseModel{ID: channelA}, ServerID: serverID, CategoryID: categoryID, Position: 3},
+			{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, Position: 1},
+			{BaseModel: providers.BaseModel{ID: channelB}, ServerID: serverID, CategoryID: categoryID, Position: 0},
+		}, nil).Once()
+		mockChannelRepo.On("UpdateChannelPositions", serverID.Hex(), []models.ChannelPosition{
+			{ChannelID: channelB, CategoryID: primitive.NilObjectID, Position: 2},
+			{ChannelID: channelA, CategoryID: primitive.NilObjectID, Position: 3},
+		}).Return(nil).Once()
+		mockCategoryRepo.On("DeleteChannelCategory", categoryID.Hex()).Return(nil).Once()
+
+		msgOpt := service.DeleteCategory(userID.Hex(), categoryID.Hex())
+
+		assert.Nil(t, msgOpt)
+		mockChannelRepo.AssertExpectations(t)
+		mockCategoryRepo.AssertExpectations(t)
+	})
+
+	t.Run("移出頻道失敗時不刪除類別", func(t *testing.T) {
+		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockPS := new(mocks.PermissionService)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+		categoryID := primitive.NewObjectID()
+
+		service := &channelService{
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			permissionService:   mockPS,
+		}
+
+		mockCategoryRepo.On("GetChannelCategoryByID", categoryID.Hex()).Return(&models.ChannelCategory{BaseModel: providers.BaseModel{ID: categoryID}, ServerID: serverID}, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return([]models.Channel{
+			{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, CategoryID: categoryID},
+		}, nil).Once()
+		mockChannelRepo.On("UpdateChannelPositions", serverID.Hex(), mock.Anything).Return(errors.New("database error")).Once()
+
+		msgOpt := service.DeleteCategory(userID.Hex(), categoryID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+		mockCategoryRepo.AssertNotCalled(t, "DeleteChannelCategory", mock.Anything)
+	})
+}
+
+func TestReorderChannels(t *testing.T) {
+	userID := primitive.NewObjectID()
+	serverID := primitive.NewObjectID()
+	textCategoryID := primitive.NewObjectID()
+	voiceCategoryID := primitive.NewObjectID()
+	generalID := primitive.NewObjectID()
+	rulesID := primitive.NewObjectID()
+
+	newFixture := func() ([]models.ChannelCategory, []models.Channel) {
+		return []models.ChannelCategory{
+			{BaseModel: providers.BaseModel{ID: textCategoryID}, ServerID: serverID, Name: "文字頻道", Position: 1},
+			{BaseModel: providers.BaseModel{ID: voiceCategoryID}, ServerID: serverID, Name: "語音頻道", Position: 2},
+		}, []models.Channel{
+			{BaseModel: providers.BaseModel{ID: generalID}, ServerID: serverID, CategoryID: textCategoryID, Name: "general", Position: 0},
+			{BaseModel: providers.BaseModel{ID: rulesID}, ServerID: serverID, CategoryID: textCategoryID, Name: "rules", Position: 1},
+		}
+	}
+
+	t.Run("成功調整排序並跨類別移動頻道", func(t *testing.T) {
+		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockChatRepo := new(mocks.ChatRepository)
+		mockPS := new(mocks.PermissionService)
+		mockAuditLogRepo := new(mockAuditLogRepository)
+
+		service := &channelService{
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			serverMemberRepo:    mockServerMemberRepo,
+			chatRepo:            mockChatRepo,
+			permissionService:   mockPS,
+			auditLogRepo:        mockAuditLogRepo,
+		}
+
+		categories, channels := newFixture()
+		reorderedCategories := []models.ChannelCategory{
+			{BaseModel: providers.BaseModel{ID: textCategoryID}, ServerID: serverID, Name: "文字頻道", Position: 1},
+			{BaseModel: providers.BaseModel{ID: voiceCategoryID}, ServerID: serverID, Name: "語音頻道", Position: 0},
+		}
+		reorderedChannels := []models.Channel{
+			{BaseModel: providers.BaseModel{ID: generalID}, ServerID: serverID, CategoryID: voiceCategoryID, Name: "general", Position: 0},
+			{BaseModel: providers.BaseModel{ID: rulesID}, ServerID: serverID, CategoryID: textCategoryID, Name: "rules", Position: 1},
+		}
+
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return(categories, nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return(channels, nil).Once()
+		mockCategoryRepo.On("UpdateChannelCategoryPositions", serverID.Hex(), []models.CategoryPosition{
+			{CategoryID: voiceCategoryID, Position: 0},
+		}).Return(nil).Once()
+		// rules 位置未變動，不會寫入
+		mockChannelRepo.On("UpdateChannelPositions", serverID.Hex(), []models.ChannelPosition{
+			{ChannelID: generalID, CategoryID: voiceCategoryID, Position: 0},
+		}).Return(nil).Once()
+		mockAuditLogRepo.On("CreateAuditLog", mock.MatchedBy(func(entry *models.AuditLog) bool {
+			return entry.Action == models.AuditActionChannelUpdate &&
+				entry.TargetID == generalID &&
+				len(entry.Changes) == 1 && entry.Changes[0].Key == "category_id"
+		})).Return(nil).Once()
+
+		// 返回調整後的頻道樹
+		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return([]models.ServerMember{{ServerID: serverID, UserID: userID}}, nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return(reorderedChannels, nil).Once()
+		mockPS.On("FilterViewableChannels", mock.Anything, serverID.Hex(), userID.Hex(), reorderedChannels).Return(reorderedChannels, nil).Once()
+		mockChatRepo.On("GetRoomReadStates", mock.Anything, userID.Hex(), mock.Anything).Return(map[string]models.RoomReadState{}, nil).Once()
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return(reorderedCategories, nil).Once()
+
+		result, msgOpt := service.ReorderChannels(userID.Hex(), serverID.Hex(), models.ReorderChannelsRequest{
+			Categories: []models.CategoryPositionRequest{
+				{ID: voiceCategoryID.Hex(), Position: 0},
+				{ID: textCategoryID.Hex(), Position: 1},
+			},
+			Channels: []models.ChannelPositionRequest{
+				{ID: generalID.Hex(), CategoryID: voiceCategoryID.Hex(), Position: 0},
+				{ID: rulesID.Hex(), CategoryID: textCategoryID.Hex(), Position: 1},
+			},
+		})
+
+		assert.Nil(t, msgOpt)
+		assert.Len(t, result.Categories, 2)
+		assert.Equal(t, "語音頻道", result.Categories[0].Name)
+		assert.Equal(t, "general", result.Categories[0].Channels[0].Name)
+		assert.Equal(t, "rules", result.Categories[1].Channels[0].Name)
+
+		mockChannelRepo.AssertExpectations(t)
+		mockCategoryRepo.AssertExpectations(t)
+		mockAuditLogRepo.AssertExpectations(t)
+	})
+
+	t.Run("移出類別成為未分類頻道", func(t *testing.T) {
+		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockServerMemberRepo := new(mockChannelServiceServerMemberRepository)
+		mockChatRepo := new(mocks.ChatRepository)
+		mockPS := new(mocks.PermissionService)
+
+		service := &channelService{
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			serverMemberRepo:    mockServerMemberRepo,
+			chatRepo:            mockChatRepo,
+			permissionService:   mockPS,
+		}
+
+		categories, channels := newFixture()
+
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return(categories, nil)
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return(channels, nil)
+		mockCategoryRepo.On("UpdateChannelCategoryPositions", serverID.Hex(), []models.CategoryPosition(nil)).Return(nil).Once()
+		mockChannelRepo.On("UpdateChannelPositions", serverID.Hex(), []models.ChannelPosition{
+			{ChannelID: rulesID, CategoryID: primitive.NilObjectID, Position: 0},
+		}).Return(nil).Once()
+		mockServerMemberRepo.On("GetUserServers", userID.Hex()).Return([]models.ServerMember{{ServerID: serverID, UserID: userID}}, nil).Once()
+		mockPS.On("FilterViewableChannels", mock.Anything, serverID.Hex(), userID.Hex(), channels).Return(channels, nil).Once()
+		mockChatRepo.On("GetRoomReadStates", mock.Anything, userID.Hex(), mock.Anything).Return(map[string]models.RoomReadState{}, nil).Once()
+
+		_, msgOpt := service.ReorderChannels(userID.Hex(), serverID.Hex(), models.ReorderChannelsRequest{
+			Channels: []models.ChannelPositionRequest{{ID: rulesID.Hex(), Position: 0}},
+		})
+
+		assert.Nil(t, msgOpt)
+		mockChannelRepo.AssertExpectations(t)
+		mockCategoryRepo.AssertExpectations(t)
+	})
+
+	t.Run("其他伺服器的頻道時整批不套用", func(t *testing.T) {
+		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockPS := new(mocks.PermissionService)
+
+		service := &channelService{
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			permissionService:   mockPS,
+		}
+
+		categories, channels := newFixture()
+
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return(categories, nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return(channels, nil).Once()
+
+		result, msgOpt := service.ReorderChannels(userID.Hex(), serverID.Hex(), models.ReorderChannelsRequest{
+			Categories: []models.CategoryPositionRequest{{ID: voiceCategoryID.Hex(), Position: 0}},
+			Channels: []models.ChannelPositionRequest{
+				{ID: generalID.Hex(), Position: 3},
+				{ID: primitive.NewObjectID().Hex(), Position: 0},
+			},
+		})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrChannelNotFound, msgOpt.Code)
+		mockCategoryRepo.AssertNotCalled(t, "UpdateChannelCategoryPositions", mock.Anything, mock.Anything)
+		mockChannelRepo.AssertNotCalled(t, "UpdateChannelPositions", mock.Anything, mock.Anything)
+	})
+
+	t.Run("排序項目重複", func(t *testing.T) {
+		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockPS := new(mocks.PermissionService)
+
+		service := &channelService{
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			permissionService:   mockPS,
+		}
+
+		categories, channels := newFixture()
+
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return(categories, nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return(channels, nil).Once()
+
+		_, msgOpt := service.ReorderChannels(userID.Hex(), serverID.Hex(), models.ReorderChannelsRequest{
+			Channels: []models.ChannelPositionRequest{
+				{ID: generalID.Hex(), Position: 1},
+				{ID: generalID.Hex(), Position: 2},
+			},
+		})
+
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+		mockChannelRepo.AssertNotCalled(t, "UpdateChannelPositions", mock.Anything, mock.Anything)
+	})
+
+	t.Run("頻道寫入失敗時還原類別排序", func(t *testing.T) {
+		mockChannelRepo := new(mockChannelServiceChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockPS := new(mocks.PermissionService)
+
+		service := &channelService{
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			permissionService:   mockPS,
+		}
+
+		categories, channels := newFixture()
+		newChannelPositions := []models.ChannelPosition{{ChannelID: generalID, CategoryID: voiceCategoryID, Position: 0}}
+		previousChannelPositions := []models.ChannelPosition{{ChannelID: generalID, CategoryID: textCategoryID, Position: 0}}
+
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageChannels).Return(nil).Once()
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return(categories, nil).Once()
+		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return(channels, nil).Once()
+		mockCategoryRepo.On("UpdateChannelCategoryPositions", serverID.Hex(), []models.CategoryPosition{{CategoryID: voiceCategoryID, Position: 0}}).Return(nil).Once()
+		mockChannelRepo.On("UpdateChannelPositions", serverID.Hex(), newChannelPositions).Return(errors.New("database error")).Once()
+		mockCategoryRepo.On("UpdateChannelCategoryPositions", serverID.Hex(), []models.CategoryPosition{{CategoryID: voiceCategoryID, Position: 2}}).Return(nil).Once()
+		mockChannelRepo.On("UpdateChannelPositions", serverID.Hex(), previousChannelPositions).Return(nil).Once()
+
+		result, msgOpt := service.ReorderChannels(userID.Hex(), serverID.Hex(), models.ReorderChannelsRequest{
+			Categories: []models.CategoryPositionRequest{{ID: voiceCategoryID.Hex(), Position: 0}},
+			Channels:   []models.ChannelPositionRequest{{ID: generalID.Hex(), CategoryID: voiceCategoryID.Hex(), Position: 0}},
+		})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+		mockChannelRepo.AssertExpectations(t)
+		mockCategoryRepo.AssertExpectations(t)
+	})
+
+	t.Run("沒有提供要調整的排序", func(t *testing.T) {
+		service := &channelService{}
+
+		result, msgOpt := service.ReorderChannels(userID.Hex(), serverID.Hex(), models.ReorderChannelsRequest{})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
@@ -6,9 +6,12 @@ import (
 	"chat_app_backend/app/repositories"
 	"chat_app_backend/config"
 	"context"
+	"errors"
 	"log/slog"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
@@ -16,6 +19,9 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// MaxSearchQueryLength 訊息搜尋關鍵字長度上限（字元）
+const MaxSearchQueryLength = 100
+
 // chatService 管理所有的聊天功能
 type chatService struct {
 	config            *config.Config
@@ -27,6 +33,7 @@ type chatService struct {
 	odm               providers.ODM
 	userService       UserService
 	fileUploadService FileUploadService // 添加 FileUploadService 依賴
+	permissionService PermissionService
 
 	// 新增的模組化組件
 	clientManager    ClientManager
@@ -40,18 +47,19 @@ func NewChatService(cfg *config.Config,
 	odm providers.ODM,
 	redisClient *redis.Client,
 	cache providers.CacheProvider,
+	clientManager ClientManager,
 	chatRepo repositories.ChatRepository,
 	serverRepo repositories.ServerRepository,
 	serverMemberRepo repositories.ServerMemberRepository,
 	userRepo repositories.UserRepository,
 	userService UserService,
-	fileUploadService FileUploadService) ChatService {
+	fileUploadService FileUploadService,
+	permissionService PermissionService) ChatService {
 
-	// 創建模組化組件
-	clientManager := NewClientManager(cache)
-	roomManager := NewRoomManager(odm, redisClient, serverMemberRepo)
-	messageHandler := NewMessageHandler(odm, roomManager, redisClient)
-	websocketHandler := NewWebSocketHandler(odm, clientManager, roomManager, messageHandler, userService, cache)
+	// 創建模組化組件（ClientManager 由外部注入，與伺服器、好友服務共用同一份連線狀態）
+	roomManager := NewRoomManager(odm, redisClient, serverMemberRepo, permissionService)
+	messageHandler := NewMessageHandler(odm, roomManager, redisClient, permissionService, fileUploadService)
+	websocketHandler := NewWebSocketHandler(odm, clientManager, roomManager, messageHandler, userService, cache, permissionService)
 
 	cs := &chatService{
 		config:            cfg,
@@ -63,12 +71,15 @@ func NewChatService(cfg *config.Config,
 		odm:               odm,
 		userService:       userService,
 		fileUploadService: fileUploadService,
+		permissionService: permissionService,
 		clientManager:     clientManager,
 		roomManager:       roomManager,
 		messageHandler:    messageHandler,
 		websocketHandler:  websocketHandler,
 	}
 
+	clientManager.OnClientEvent(clientEventLeaveServerRooms, cs.leaveLocalServerRooms)
+
 	return cs
 }
 
@@ -86,8 +97,8 @@ func (cs *chatService) getUserPictureURL(user *models.User) string {
 }
 
 // HandleWebSocket 處理 WebSocket 連線
-func (cs *chatService) HandleWebSocket(ws *websocket.Conn, userID string) {
-	cs.websocketHandler.HandleWebSocket(ws, userID)
+func (cs *chatService) HandleWebSocket(ws *websocket.Conn, userID string, sessionID string) {
+	cs.websocketHandler.HandleWebSocket(ws, userID, sessionID)
 }
 
 // GetClientManager 獲取客戶端管理器
@@ -126,6 +137,16 @@ func (cs *chatService) GetDMRoomResponseList(ctx context.Context, userID string,
 		userListById[user.ID.Hex()] = user
 	}
 
+	// 取得各房間的已讀狀態，失敗時不影響列表回傳
+	roomIDs := make([]string, 0, len(chatList))
+	for _, chat := range chatList {
+		roomIDs = append(roomIDs, chat.RoomID.Hex())
+	}
+	readStates, err := cs.chatRepo.GetRoomReadStates(ctx, userID, roomIDs)
+	if err != nil {
+		slog.Warn("獲取私聊已讀狀態失敗", "user_id", userID, "error", err)
+	}
+
 	// 轉換為 ChatResponse 格式
 	chatResponseList := []models.DMRoomResponse{}
 	for _, chat := range chatList {
@@ -140,12 +161,17 @@ func (cs *chatService) GetDMRoomResponseList(ctx context.Context, userID string,
 			isOnline = cs.clientManager.IsUserOnline(chat.ChatWithUserID.Hex())
 		}
 
+		readState := readStates[chat.RoomID.Hex()]
+
 		chatResponseList = append(chatResponseList, models.DMRoomResponse{
 			RoomID:     chat.RoomID,
 			Nickname:   user.Nickname,
 			PictureURL: cs.getUserPictureURL(&user),
 			Timestamp:  chat.UpdatedAt.UnixMilli(),
 			IsOnline:   isOnline,
+			// 已讀狀態
+			UnreadCount:       readState.UnreadCount,
+			LastReadMessageID: readState.LastReadMessageID,
 		})
 	}
 
@@ -451,21 +477,15 @@ func (cs *chatService) GetDMMessages(ctx context.Context, userID string, roomID
 
 	var messageResponse []models.MessageResponse
 	for _, message := range messageList {
-		messageResponse = append(messageResponse, models.MessageResponse{
-			ID:        message.ID,
-			RoomType:  models.RoomType(message.RoomType),
-			RoomID:    message.RoomID.Hex(),
-			SenderID:  message.SenderID.Hex(),
-			Content:   message.Content,
-			Timestamp: message.UpdatedAt.UnixMilli(),
-		})
+		messageResponse = append(messageResponse, cs.toMessageResponse(&message, userID))
 	}
 
 	return messageResponse, nil
 }
 
 // GetChannelMessages 獲取頻道訊息
-func (cs *chatService) GetChannelMessages(ctx context.Context, userID string, channelID string, before string, after string, limit string) ([]models.MessageResponse, *models.MessageOptions) {
+// topLevelOnly 為 true 時只回傳頂層訊息（不含討論串回覆）
+func (cs *chatService) GetChannelMessages(ctx context.Context, userID string, channelID string, before string, after string, limit string, topLevelOnly bool) ([]models.MessageResponse, *models.MessageOptions) {
 	channelObjectID, err := primitive.ObjectIDFromHex(channelID)
 	if err != nil {
 		return nil, &models.MessageOptions{
@@ -503,9 +523,23 @@ func (cs *chatService) GetChannelMessages(ctx context.Context, userID string, ch
 		}
 	}
 
+	// 套用頻道權限覆寫（私人頻道等）
+	if msgOpt := cs.permissionService.CheckChannelPermission(ctx, channelID, userID, models.PermissionViewChannel); msgOpt != nil {
+		if msgOpt.Code == models.ErrInternalServer {
+			return nil, msgOpt
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrUnauthorized,
+			Message: "您沒有權限訪問此頻道",
+		}
+	}
+
 	// 構建訊息查詢
 	messageQb := providers.NewQueryBuilder()
 	messageQb.Where("room_id", channelObjectID).Where("room_type", string(models.RoomTypeChannel))
+	if topLevelOnly {
+		messageQb.WhereNotExists("thread_id")
+	}
 
 	if before != "" {
 		var beforeObjectID primitive.ObjectID
@@ -563,19 +597,601 @@ func (cs *chatService) GetChannelMessages(ctx context.Context, userID string, ch
 	// 轉換為響應格式
 	var messageResponse []models.MessageResponse
 	for _, message := range messageList {
-		messageResponse = append(messageResponse, models.MessageResponse{
-			ID:        message.ID,
-			RoomType:  models.RoomType(message.RoomType),
-			RoomID:    message.RoomID.Hex(),
-			SenderID:  message.SenderID.Hex(),
-			Content:   message.Content,
-			Timestamp: message.UpdatedAt.UnixMilli(),
-		})
+		messageResponse = append(messageResponse, cs.toMessageResponse(&message, userID))
 	}
 
 	return messageResponse, nil
 }
 
+// GetThreadMessages 獲取討論串回覆，使用與訊息列表相同的 before/after 游標
+func (cs *chatService) GetThreadMessages(ctx context.Context, userID string, roomType models.RoomType, roomID string, threadID string, before string, after string, limit string) ([]models.MessageResponse, *models.MessageOptions) {
+	roomObjectID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Details: err,
+			Message: "無效的房間ID格式",
+		}
+	}
+
+	threadObjectID, err := primitive.ObjectIDFromHex(threadID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Details: err,
+			Message: "無效的訊息ID格式",
+		}
+	}
+
+	// 檢查用戶是否可存取該房間
+	allowed, err := cs.roomManager.CheckUserAllowedJoinRoom(ctx, userID, roomID, roomType)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+			Message: "檢查房間權限失敗",
+		}
+	}
+	if !allowed {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNoPermission,
+			Message: "您沒有權限存取此房間",
+		}
+	}
+
+	// 父訊息必須屬於該房間
+	var parent models.Message
+	err = cs.odm.FindByID(ctx, threadID, &parent)
+	if err != nil && !errors.Is(err, providers.ErrDocumentNotFound) {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+			Message: "獲取訊息失敗",
+		}
+	}
+	if err != nil || parent.RoomID != roomObjectID || parent.RoomType != roomType {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrMessageNotFound,
+			Message: "訊息不存在",
+		}
+	}
+
+	// 構建討論串查詢
+	messageQb := providers.NewQueryBuilder()
+	messageQb.Where("room_id", roomObjectID).Where("thread_id", threadObjectID)
+
+	if before != "" {
+		var beforeObjectID primitive.ObjectID
+		beforeObjectID, err = primitive.ObjectIDFromHex(before)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Details: err,
+				Message: "無效的before參數格式",
+			}
+		}
+		messageQb.WhereLt("_id", beforeObjectID)
+	}
+
+	if after != "" {
+		var afterObjectID primitive.ObjectID
+		afterObjectID, err = primitive.ObjectIDFromHex(after)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Details: err,
+				Message: "無效的after參數格式",
+			}
+		}
+		messageQb.WhereGt("_id", afterObjectID)
+	}
+
+	messageQb.SortDesc("_id")
+
+	if limit != "" {
+		var limitVal int64
+		limitVal, err = strconv.ParseInt(limit, 10, 64)
+		if err == nil && limitVal > 0 {
+			// 限制最大獲取數量為100
+			if limitVal > 100 {
+				limitVal = 100
+			}
+			messageQb.Limit(limitVal)
+		}
+	} else {
+		// 如果沒有指定 limit，默認返回最近 50 條回覆
+		messageQb.Limit(50)
+	}
+
+	var messageList []models.Message
+	err = cs.odm.FindWithOptions(ctx, messageQb.GetFilter(), &messageList, messageQb.GetQueryOptions())
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+			Message: "獲取討論串訊息失敗",
+		}
+	}
+
+	messageResponse := []models.MessageResponse{}
+	for _, message := range messageList {
+		messageResponse = append(messageResponse, cs.toMessageResponse(&message, userID))
+	}
+
+	return messageResponse, nil
+}
+
+// EditMessage 編輯訊息（僅限發送者），並透過房間頻道廣播
+func (cs *chatService) EditMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, content string) (*models.MessageResponse, *models.MessageOptions) {
+	message, msgOpt := cs.messageHandler.EditMessage(ctx, userID, roomType, roomID, messageID, content)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	return fromHandlerMessage(message), nil
+}
+
+// DeleteMessage 刪除訊息（發送者或伺服器管理員），並透過房間頻道廣播
+func (cs *chatService) DeleteMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) *models.MessageOptions {
+	_, msgOpt := cs.messageHandler.DeleteMessage(ctx, userID, roomType, roomID, messageID)
+	return msgOpt
+}
+
+// AddReaction 新增訊息表情回應，並透過房間頻道廣播
+func (cs *chatService) AddReaction(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, emoji string) (*models.MessageResponse, *models.MessageOptions) {
+	message, msgOpt := cs.messageHandler.UpdateReaction(ctx, userID, roomType, roomID, messageID, emoji, true)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	return fromHandlerMessage(message), nil
+}
+
+// RemoveReaction 移除訊息表情回應，並透過房間頻道廣播
+func (cs *chatService) RemoveReaction(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, emoji string) (*models.MessageResponse, *models.MessageOptions) {
+	message, msgOpt := cs.messageHandler.UpdateReaction(ctx, userID, roomType, roomID, messageID, emoji, false)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	return fromHandlerMessage(message), nil
+}
+
+// SearchMessages 全文搜尋用戶可讀取房間內的訊息
+// 未指定範圍時搜尋用戶所有私聊房間與已加入伺服器的頻道
+func (cs *chatService) SearchMessages(ctx context.Context, userID string, request models.MessageSearchRequest) (*models.MessageSearchResults, *models.MessageOptions) {
+	request.Query = strings.TrimSpace(request.Query)
+	if request.Query == "" {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "搜尋關鍵字不能為空",
+		}
+	}
+	if utf8.RuneCountInString(request.Query) > MaxSearchQueryLength {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "搜尋關鍵字過長",
+		}
+	}
+
+	for _, id := range []struct {
+		value   string
+		message string
+	}{
+		{request.SenderID, "無效的發送者ID格式"},
+		{request.Before, "無效的before參數格式"},
+	} {
+		if id.value == "" {
+			continue
+		}
+		if _, err := primitive.ObjectIDFromHex(id.value); err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Details: err,
+				Message: id.message,
+			}
+		}
+	}
+
+	if request.From > 0 && request.To > 0 && request.From > request.To {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "起始時間不能晚於結束時間",
+		}
+	}
+
+	// 預設返回 20 筆，最多 100 筆
+	if request.Limit <= 0 {
+		request.Limit = 20
+	}
+	if request.Limit > 100 {
+		request.Limit = 100
+	}
+
+	roomIDs, msgOpt := cs.getSearchableRoomIDs(ctx, userID, request)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	// 多取一筆用於判斷是否還有下一頁
+	messages, err := cs.chatRepo.SearchMessages(ctx, roomIDs, request, request.Limit+1)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+			Message: "搜尋訊息失敗",
+		}
+	}
+
+	results := &models.MessageSearchResults{
+		Messages: make([]models.MessageResponse, 0, len(messages)),
+	}
+	if int64(len(messages)) > request.Limit {
+		messages = messages[:request.Limit]
+		results.NextCursor = messages[len(messages)-1].ID.Hex()
+	}
+	for i := range messages {
+		results.Messages = append(results.Messages, cs.toMessageResponse(&messages[i], userID))
+	}
+
+	return results, nil
+}
+
+// getSearchableRoomIDs 依搜尋範圍取得用戶可讀取的房間ID
+// 私聊以 dm_rooms 紀錄判斷，頻道以伺服器成員身份判斷
+func (cs *chatService) getSearchableRoomIDs(ctx context.Context, userID string, request models.MessageSearchRequest) ([]string, *models.MessageOptions) {
+	noPermission := &models.MessageOptions{
+		Code:    models.ErrNoPermission,
+		Message: "您沒有權限存取此房間",
+	}
+
+	// 限定單一房間
+	if request.RoomID != "" {
+		roomObjectID, err := primitive.ObjectIDFromHex(request.RoomID)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Details: err,
+				Message: "無效的房間ID格式",
+			}
+		}
+
+		switch request.RoomType {
+		case models.RoomTypeDM:
+			userObjectID, err := primitive.ObjectIDFromHex(userID)
+			if err != nil {
+				return nil, &models.MessageOptions{
+					Code:    models.ErrInvalidParams,
+					Details: err,
+					Message: "無效的用戶ID格式",
+				}
+			}
+			exists, err := cs.odm.Exists(ctx, bson.M{"room_id": roomObjectID, "user_id": userObjectID}, &models.DMRoom{})
+			if err != nil {
+				return nil, &models.MessageOptions{
+					Code:    models.ErrInternalServer,
+					Details: err,
+					Message: "檢查房間權限失敗",
+				}
+			}
+			if !exists {
+				return nil, noPermission
+			}
+		case models.RoomTypeChannel:
+			var channel models.Channel
+			if err := cs.odm.FindByID(ctx, request.RoomID, &channel); err != nil {
+				return nil, noPermission
+			}
+			if request.ServerID != "" && channel.ServerID.Hex() != request.ServerID {
+				return nil, &models.MessageOptions{
+					Code:    models.ErrInvalidParams,
+					Message: "頻道不屬於指定的伺服器",
+				}
+			}
+			isMember, err := cs.serverMemberRepo.IsMemberOfServer(channel.ServerID.Hex(), userID)
+			if err != nil {
+				return nil, &models.MessageOptions{
+					Code:    models.ErrInternalServer,
+					Details: err,
+					Message: "檢查伺服器成員身份失敗",
+				}
+			}
+			if !isMember {
+				return nil, noPermission
+			}
+			// 套用頻道權限覆寫（私人頻道等）
+			if msgOpt := cs.permissionService.CheckChannelPermission(ctx, request.RoomID, userID, models.PermissionViewChannel); msgOpt != nil {
+				if msgOpt.Code == models.ErrInternalServer {
+					return nil, msgOpt
+				}
+				return nil, noPermission
+			}
+		default:
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "無效的房間類型",
+			}
+		}
+
+		return []string{request.RoomID}, nil
+	}
+
+	// 限定伺服器內所有頻道
+	if request.ServerID != "" {
+		serverObjectID, err := primitive.ObjectIDFromHex(request.ServerID)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Details: err,
+				Message: "無效的伺服器ID格式",
+			}
+		}
+		isMember, err := cs.serverMemberRepo.IsMemberOfServer(request.ServerID, userID)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Details: err,
+				Message: "檢查伺服器成員身份失敗",
+			}
+		}
+		if !isMember {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrNoPermission,
+				Message: "您不是該伺服器的成員",
+			}
+		}
+		return cs.getViewableChannelIDs(ctx, userID, []primitive.ObjectID{serverObjectID})
+	}
+
+	// 未指定範圍：所有私聊房間（含已隱藏）與已加入伺服器的頻道
+	roomIDs := make([]string, 0)
+	dmRooms, err := cs.chatRepo.GetDMRoomListByUserID(ctx, userID, true)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+			Message: "獲取聊天列表失敗",
+		}
+	}
+	for _, room := range dmRooms {
+		roomIDs = append(roomIDs, room.RoomID.Hex())
+	}
+
+	memberships, err := cs.serverMemberRepo.GetUserServers(userID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+			Message: "獲取用戶伺服器列表失敗",
+		}
+	}
+	if len(memberships) > 0 {
+		serverObjectIDs := make([]primitive.ObjectID, 0, len(memberships))
+		for _, membership := range memberships {
+			serverObjectIDs = append(serverObjectIDs, membership.ServerID)
+		}
+		channelIDs, msgOpt := cs.getViewableChannelIDs(ctx, userID, serverObjectIDs)
+		if msgOpt != nil {
+			return nil, msgOpt
+		}
+		roomIDs = append(roomIDs, channelIDs...)
+	}
+
+	return roomIDs, nil
+}
+
+// getViewableChannelIDs 取得多個伺服器下用戶可查看的頻道ID（套用頻道權限覆寫）
+func (cs *chatService) getViewableChannelIDs(ctx context.Context, userID string, serverObjectIDs []primitive.ObjectID) ([]string, *models.MessageOptions) {
+	var channels []models.Channel
+	err := cs.odm.Find(ctx, bson.M{"server_id": bson.M{"$in": serverObjectIDs}}, &channels)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+			Message: "獲取頻道列表失敗",
+		}
+	}
+
+	channelsByServer := make(map[primitive.ObjectID][]models.Channel, len(serverObjectIDs))
+	for _, channel := range channels {
+		channelsByServer[channel.ServerID] = append(channelsByServer[channel.ServerID], channel)
+	}
+
+	channelIDs := make([]string, 0, len(channels))
+	for _, serverObjectID := range serverObjectIDs {
+		serverChannels := channelsByServer[serverObjectID]
+		if len(serverChannels) == 0 {
+			continue
+		}
+		viewable, msgOpt := cs.permissionService.FilterViewableChannels(ctx, serverObjectID.Hex(), userID, serverChannels)
+		if msgOpt != nil {
+			if msgOpt.Code == models.ErrInternalServer {
+				return nil, msgOpt
+			}
+			// 成員資格已變動的伺服器直接略過
+			continue
+		}
+		for _, channel := range viewable {
+			channelIDs = append(channelIDs, channel.ID.Hex())
+		}
+	}
+	return channelIDs, nil
+}
+
+// MarkRoomRead 標記房間已讀，並同步到用戶的其他裝置
+func (cs *chatService) MarkRoomRead(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*models.ReadStateResponse, *models.MessageOptions) {
+	return cs.messageHandler.MarkRead(ctx, userID, roomType, roomID, messageID)
+}
+
+// GetAttachment 獲取訊息附件，需能存取引用該附件的任一房間，返回新的短效下載連結
+func (cs *chatService) GetAttachment(ctx context.Context, userID string, fileID string) (*models.MessageAttachment, *models.MessageOptions) {
+	fileObjectID, err := primitive.ObjectIDFromHex(fileID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的檔案ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	var messages []models.Message
+	if err := cs.odm.Find(ctx, bson.M{"attachments.file_id": fileObjectID, "is_deleted": false}, &messages); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "查詢附件失敗",
+			Details: err.Error(),
+		}
+	}
+	if len(messages) == 0 {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "附件不存在",
+		}
+	}
+
+	for _, message := range messages {
+		allowed, err := cs.roomManager.CheckUserAllowedJoinRoom(ctx, userID, message.RoomID.Hex(), message.RoomType)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "檢查房間權限失敗",
+				Details: err.Error(),
+			}
+		}
+		if !allowed {
+			continue
+		}
+
+		for _, attachment := range message.Attachments {
+			if attachment.FileID != fileObjectID {
+				continue
+			}
+			if cs.fileUploadService != nil {
+				url, msgOpt := cs.fileUploadService.GetFileDownloadURL(fileID)
+				if msgOpt != nil {
+					return nil, &models.MessageOptions{
+						Code:    models.ErrNotFound,
+						Message: "附件不存在或已被刪除",
+						Details: msgOpt.Message,
+					}
+				}
+				attachment.URL = url
+			}
+			return &attachment, nil
+		}
+	}
+
+	return nil, &models.MessageOptions{
+		Code:    models.ErrNoPermission,
+		Message: "您沒有權限存取此附件",
+	}
+}
+
+// CanAccessAttachment 檢查用戶能否存取引用任一指定檔案的訊息所屬房間（用於讀取附件內容）
+func (cs *chatService) CanAccessAttachment(ctx context.Context, userID string, fileIDs []string) (bool, *models.MessageOptions) {
+	fileObjectIDs := make([]primitive.ObjectID, 0, len(fileIDs))
+	for _, fileID := range fileIDs {
+		fileObjectID, err := primitive.ObjectIDFromHex(fileID)
+		if err != nil {
+			return false, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "無效的檔案ID格式",
+				Details: err.Error(),
+			}
+		}
+		fileObjectIDs = append(fileObjectIDs, fileObjectID)
+	}
+	if len(fileObjectIDs) == 0 {
+		return false, nil
+	}
+
+	var messages []models.Message
+	if err := cs.odm.Find(ctx, bson.M{"attachments.file_id": bson.M{"$in": fileObjectIDs}, "is_deleted": false}, &messages); err != nil {
+		return false, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "查詢附件失敗",
+			Details: err.Error(),
+		}
+	}
+
+	// 同一房間可能有多則訊息引用相同檔案，每個房間只檢查一次
+	checked := make(map[string]bool)
+	for _, message := range messages {
+		roomKey := string(message.RoomType) + ":" + message.RoomID.Hex()
+		if checked[roomKey] {
+			continue
+		}
+		checked[roomKey] = true
+
+		allowed, err := cs.roomManager.CheckUserAllowedJoinRoom(ctx, userID, message.RoomID.Hex(), message.RoomType)
+		if err != nil {
+			return false, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "檢查房間權限失敗",
+				Details: err.Error(),
+			}
+		}
+		if allowed {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// fromHandlerMessage 將 WebSocket 訊息格式轉換為 API 回應格式
+func fromHandlerMessage(message *MessageResponse) *models.MessageResponse {
+	messageObjectID, _ := primitive.ObjectIDFromHex(message.ID)
+	return &models.MessageResponse{
+		ID:        messageObjectID,
+		RoomType:  message.RoomType,
+		RoomID:    message.RoomID,
+		SenderID:  message.SenderID,
+		Content:   message.Content,
+		Timestamp: message.Timestamp,
+		EditedAt:  message.EditedAt,
+		IsDeleted: message.IsDeleted,
+		// 附件
+		Attachments: message.Attachments,
+		// 引用與討論串
+		ReplyToMessageID: message.ReplyToMessageID,
+		ThreadID:         message.ThreadID,
+		ReplyCount:       message.ReplyCount,
+		LastReplyAt:      message.LastReplyAt,
+		// 表情回應
+		Reactions: message.Reactions,
+	}
+}
+
+// toMessageResponse 將資料庫訊息轉換為 API 回應格式，userID 用於標記目前用戶是否已回應表情
+func (cs *chatService) toMessageResponse(message *models.Message, userID string) models.MessageResponse {
+	response := models.MessageResponse{
+		ID:          message.ID,
+		RoomType:    message.RoomType,
+		RoomID:      message.RoomID.Hex(),
+		SenderID:    message.SenderID.Hex(),
+		Content:     message.Content,
+		Timestamp:   message.CreatedAt.UnixMilli(),
+		IsDeleted:   message.IsDeleted,
+		Attachments: attachmentsWithURLs(cs.fileUploadService, message.Attachments),
+	}
+	if message.EditedAt != nil {
+		response.EditedAt = message.EditedAt.UnixMilli()
+	}
+	if !message.ReplyToMessageID.IsZero() {
+		response.ReplyToMessageID = message.ReplyToMessageID.Hex()
+	}
+	if !message.ThreadID.IsZero() {
+		response.ThreadID = message.ThreadID.Hex()
+	}
+	response.ReplyCount = message.ReplyCount
+	if message.LastReplyAt != nil {
+		response.LastReplyAt = message.LastReplyAt.UnixMilli()
+	}
+	response.Reactions = summarizeReactions(message.Reactions, userID)
+	return response
+}
+
 // checkUserServerMembership 檢查用戶是否為伺服器成員
 func (cs *chatService) checkUserServerMembership(ctx context.Context, userID, serverID string) (bool, error) {
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
@@ -596,3 +1212,64 @@ func (cs *chatService) checkUserServerMembership(ctx context.Context, userID, se
 
 	return cs.odm.Exists(ctx, filter, &models.ServerMember{})
 }
+
+// LeaveServerRooms 將用戶在所有實例的連線移出伺服器所有頻道房間，並通知客戶端已失去存取權
+func (cs *chatService) LeaveServerRooms(ctx context.Context, userID string, serverID string) *models.MessageOptions {
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的伺服器ID",
+		}
+	}
+
+	var channels []models.Channel
+	err = cs.odm.Find(ctx, bson.M{"server_id": serverObjectID}, &channels)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+			Message: "獲取頻道列表失敗",
+		}
+	}
+
+	channelIDs := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		channelIDs = append(channelIDs, channel.ID.Hex())
+	}
+
+	// 用戶可能連線到其他實例，或在同一實例有多個連線，交由各實例處理本機連線
+	cs.clientManager.PublishClientEvent(ClientEvent{
+		Action:     clientEventLeaveServerRooms,
+		UserID:     userID,
+		ServerID:   serverID,
+		ChannelIDs: channelIDs,
+	})
+
+	return nil
+}
+
+// leaveLocalServerRooms 將用戶在本實例的所有連線移出指定頻道房間，並發送存取撤銷通知
+func (cs *chatService) leaveLocalServerRooms(event ClientEvent) {
+	channelIDs := event.ChannelIDs
+	if channelIDs == nil {
+		channelIDs = []string{}
+	}
+
+	for _, client := range cs.clientManager.GetUserClients(event.UserID) {
+		for _, channelID := range channelIDs {
+			cs.roomManager.LeaveRoom(client, models.RoomTypeChannel, channelID)
+		}
+
+		msg := &WsMessage[ServerAccessRevokedEvent]{
+			Action: "server_access_revoked",
+			Data: ServerAccessRevokedEvent{
+				ServerID:   event.ServerID,
+				ChannelIDs: channelIDs,
+			},
+		}
+		if err := client.SendMessage(msg); err != nil {
+			slog.Debug("發送伺服器存取撤銷通知失敗", "user_id", event.UserID, "error", err)
+		}
+	}
+}
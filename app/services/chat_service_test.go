@@ -5,12 +5,15 @@ import (
 	"chat_app_backend/app/models"
 	"chat_app_backend/app/providers"
 	"context"
+	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -24,12 +27,14 @@ func TestNewChatService(t *testing.T) {
 		nil, // odm
 		nil, // redis
 		nil, // cache
+		NewClientManager(nil, nil),
 		nil, // chatRepo
 		nil, // serverRepo
 		nil, // serverMemberRepo
 		nil, // userRepo
 		nil, // userService
 		nil, // fileService
+		nil, // permissionService
 	)
 
 	assert.NotNil(t, service, "服務應該被成功創建")
@@ -44,7 +49,7 @@ func TestNewChatService(t *testing.T) {
 
 // TestChatService_Structure 測試 ChatService 結構
 func TestChatService_Structure(t *testing.T) {
-	service := NewChatService(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	service := NewChatService(nil, nil, nil, nil, NewClientManager(nil, nil), nil, nil, nil, nil, nil, nil, nil)
 
 	cs, ok := service.(*chatService)
 	assert.True(t, ok, "服務應該可以轉換為 chatService 類型")
@@ -93,6 +98,10 @@ func TestGetDMRoomResponseList(t *testing.T) {
 		mockChatRepo.On("GetDMRoomListByUserID", mock.Anything, userID.Hex(), false).Return(dmRooms, nil).Once()
 		mockUserRepo.On("GetUserListByIds", []string{chatWithUserID.Hex()}).Return(users, nil).Once()
 		mockClientManager.On("IsUserOnline", chatWithUserID.Hex()).Return(true).Once()
+		lastReadID := primitive.NewObjectID()
+		mockChatRepo.On("GetRoomReadStates", mock.Anything, userID.Hex(), []string{roomID.Hex()}).Return(map[string]models.RoomReadState{
+			roomID.Hex(): {LastReadMessageID: lastReadID.Hex(), UnreadCount: 2},
+		}, nil).Once()
 
 		result, msgOpt := service.GetDMRoomResponseList(context.Background(), userID.Hex(), false)
 
@@ -102,6 +111,8 @@ func TestGetDMRoomResponseList(t *testing.T) {
 		assert.Equal(t, roomID, result[0].RoomID)
 		assert.Equal(t, "TestUser", result[0].Nickname)
 		assert.True(t, result[0].IsOnline)
+		assert.Equal(t, int64(2), result[0].UnreadCount)
+		assert.Equal(t, lastReadID.Hex(), result[0].LastReadMessageID)
 
 		mockChatRepo.AssertExpectations(t)
 		mockUserRepo.AssertExpectations(t)
@@ -413,8 +424,11 @@ func TestGetChannelMessages(t *testing.T) {
 		userID := primitive.NewObjectID()
 		channelID := primitive.NewObjectID()
 
+		mockPS := new(mocks.PermissionService)
+
 		service := &chatService{
-			odm: mockODM,
+			odm:               mockODM,
+			permissionService: mockPS,
 		}
 
 		messages := []models.Message{
@@ -432,12 +446,13 @@ func TestGetChannelMessages(t *testing.T) {
 
 		mockODM.On("FindByID", mock.Anything, channelID.Hex(), mock.AnythingOfType("*models.Channel")).Return(nil).Once()
 		mockODM.On("Exists", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ServerMember")).Return(true, nil).Once()
+		mockPS.On("CheckChannelPermission", mock.Anything, channelID.Hex(), userID.Hex(), models.PermissionViewChannel).Return(nil).Once()
 		mockODM.On("FindWithOptions", mock.Anything, mock.Anything, mock.AnythingOfType("*[]models.Message"), mock.Anything).Run(func(args mock.Arguments) {
 			arg := args.Get(2).(*[]models.Message)
 			*arg = messages
 		}).Return(nil).Once()
 
-		result, msgOpt := service.GetChannelMessages(context.Background(), userID.Hex(), channelID.Hex(), "", "", "")
+		result, msgOpt := service.GetChannelMessages(context.Background(), userID.Hex(), channelID.Hex(), "", "", "", false)
 
 		assert.Nil(t, msgOpt)
 		assert.NotNil(t, result)
@@ -458,7 +473,7 @@ func TestGetChannelMessages(t *testing.T) {
 
 		mockODM.On("FindByID", mock.Anything, channelID.Hex(), mock.AnythingOfType("*models.Channel")).Return(providers.ErrDocumentNotFound).Once()
 
-		result, msgOpt := service.GetChannelMessages(context.Background(), userID.Hex(), channelID.Hex(), "", "", "")
+		result, msgOpt := service.GetChannelMessages(context.Background(), userID.Hex(), channelID.Hex(), "", "", "", false)
 
 		assert.Nil(t, result)
 		assert.NotNil(t, msgOpt)
@@ -479,13 +494,377 @@ func TestGetChannelMessages(t *testing.T) {
 		mockODM.On("FindByID", mock.Anything, channelID.Hex(), mock.AnythingOfType("*models.Channel")).Return(nil).Once()
 		mockODM.On("Exists", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ServerMember")).Return(false, nil).Once()
 
-		result, msgOpt := service.GetChannelMessages(context.Background(), userID.Hex(), channelID.Hex(), "", "", "")
+		result, msgOpt := service.GetChannelMessages(context.Background(), userID.Hex(), channelID.Hex(), "", "", "", false)
+
+		assert.Nil(t, result)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrUnauthorized, msgOpt.Code)
+
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("私人頻道拒絕無查看權限的成員", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockPS := new(mocks.PermissionService)
+		userID := primitive.NewObjectID()
+		channelID := primitive.NewObjectID()
+
+		service := &chatService{
+			odm:               mockODM,
+			permissionService: mockPS,
+		}
+
+		mockODM.On("FindByID", mock.Anything, channelID.Hex(), mock.AnythingOfType("*models.Channel")).Return(nil).Once()
+		mockODM.On("Exists", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ServerMember")).Return(true, nil).Once()
+		mockPS.On("CheckChannelPermission", mock.Anything, channelID.Hex(), userID.Hex(), models.PermissionViewChannel).
+			Return(&models.MessageOptions{Code: models.ErrNoServerPermission, Message: "權限不足"}).Once()
+
+		result, msgOpt := service.GetChannelMessages(context.Background(), userID.Hex(), channelID.Hex(), "", "", "", false)
 
 		assert.Nil(t, result)
 		assert.NotNil(t, msgOpt)
 		assert.Equal(t, models.ErrUnauthorized, msgOpt.Code)
+		mockODM.AssertNotCalled(t, "FindWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("只回傳頂層訊息", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		userID := primitive.NewObjectID()
+		channelID := primitive.NewObjectID()
+
+		mockPS := new(mocks.PermissionService)
+
+		service := &chatService{
+			odm:               mockODM,
+			permissionService: mockPS,
+		}
+
+		mockODM.On("FindByID", mock.Anything, channelID.Hex(), mock.AnythingOfType("*models.Channel")).Return(nil).Once()
+		mockODM.On("Exists", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ServerMember")).Return(true, nil).Once()
+		mockPS.On("CheckChannelPermission", mock.Anything, channelID.Hex(), userID.Hex(), models.PermissionViewChannel).Return(nil).Once()
+		mockODM.On("FindWithOptions", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+			return reflect.DeepEqual(filter["thread_id"], bson.M{"$exists": false})
+		}), mock.AnythingOfType("*[]models.Message"), mock.Anything).Return(nil).Once()
+
+		_, msgOpt := service.GetChannelMessages(context.Background(), userID.Hex(), channelID.Hex(), "", "", "", true)
+
+		assert.Nil(t, msgOpt)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("預簽名網址過期後仍回傳新的附件連結", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockPS := new(mocks.PermissionService)
+		mockFS := new(mocks.FileUploadService)
+		userID := primitive.NewObjectID()
+		channelID := primitive.NewObjectID()
+		fileID := primitive.NewObjectID()
+
+		service := &chatService{
+			odm:               mockODM,
+			permissionService: mockPS,
+			fileUploadService: mockFS,
+		}
+
+		// 舊資料可能仍保存發送當下已過期的網址，讀取時不應沿用
+		raw, err := bson.Marshal(bson.M{
+			"_id":       primitive.NewObjectID(),
+			"room_type": models.RoomTypeChannel,
+			"room_id":   channelID,
+			"sender_id": userID,
+			"content":   "photo",
+			"attachments": []bson.M{{
+				"file_id":   fileID,
+				"file_name": "photo.png",
+				"url":       "http://minio/photo.png?X-Amz-Expires=300&X-Amz-Date=20200101T000000Z",
+			}},
+		})
+		assert.NoError(t, err)
+		var stored models.Message
+		assert.NoError(t, bson.Unmarshal(raw, &stored))
+
+		mockODM.On("FindByID", mock.Anything, channelID.Hex(), mock.AnythingOfType("*models.Channel")).Return(nil).Once()
+		mockODM.On("Exists", mock.Anything, mock.Anything, mock.AnythingOfType("*models.ServerMember")).Return(true, nil).Once()
+		mockPS.On("CheckChannelPermission", mock.Anything, channelID.Hex(), userID.Hex(), models.PermissionViewChannel).Return(nil).Once()
+		mockODM.On("FindWithOptions", mock.Anything, mock.Anything, mock.AnythingOfType("*[]models.Message"), mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.Message) = []models.Message{stored}
+		}).Return(nil).Once()
+		mockFS.On("GetFileDownloadURL", fileID.Hex()).Return("http://minio/photo.png?X-Amz-Expires=300&X-Amz-Date=fresh", nil).Once()
+
+		result, msgOpt := service.GetChannelMessages(context.Background(), userID.Hex(), channelID.Hex(), "", "", "", false)
+
+		assert.Nil(t, msgOpt)
+		assert.Len(t, result, 1)
+		assert.Len(t, result[0].Attachments, 1)
+		assert.Equal(t, "photo.png", result[0].Attachments[0].FileName)
+		assert.Equal(t, "http://minio/photo.png?X-Amz-Expires=300&X-Amz-Date=fresh", result[0].Attachments[0].URL)
+		mockODM.AssertExpectations(t)
+		mockFS.AssertExpectations(t)
+	})
+}
+
+func TestGetThreadMessages(t *testing.T) {
+	userID := primitive.NewObjectID()
+	channelID := primitive.NewObjectID()
+	threadID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	t.Run("成功獲取討論串回覆", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		service := &chatService{
+			odm:         mockODM,
+			roomManager: mockRM,
+		}
+
+		replies := []models.Message{
+			{
+				BaseModel: providers.BaseModel{ID: primitive.NewObjectID(), CreatedAt: time.Now()},
+				RoomType:  models.RoomTypeChannel,
+				RoomID:    channelID,
+				SenderID:  userID,
+				Content:   "reply",
+				ThreadID:  threadID,
+			},
+		}
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), channelID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, threadID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			parent := args.Get(2).(*models.Message)
+			parent.RoomID = channelID
+			parent.RoomType = models.RoomTypeChannel
+		}).Return(nil).Once()
+		mockODM.On("FindWithOptions", ctx, mock.MatchedBy(func(filter bson.M) bool {
+			return filter["thread_id"] == threadID && filter["room_id"] == channelID
+		}), mock.AnythingOfType("*[]models.Message"), mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.Message) = replies
+		}).Return(nil).Once()
+
+		result, msgOpt := service.GetThreadMessages(ctx, userID.Hex(), models.RoomTypeChannel, channelID.Hex(), threadID.Hex(), "", "", "")
 
+		assert.Nil(t, msgOpt)
+		assert.Len(t, result, 1)
+		assert.Equal(t, threadID.Hex(), result[0].ThreadID)
 		mockODM.AssertExpectations(t)
+		mockRM.AssertExpectations(t)
+	})
+
+	t.Run("父訊息不屬於該房間", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		service := &chatService{
+			odm:         mockODM,
+			roomManager: mockRM,
+		}
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), channelID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, threadID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			args.Get(2).(*models.Message).RoomID = primitive.NewObjectID()
+		}).Return(nil).Once()
+
+		result, msgOpt := service.GetThreadMessages(ctx, userID.Hex(), models.RoomTypeChannel, channelID.Hex(), threadID.Hex(), "", "", "")
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrMessageNotFound, msgOpt.Code)
+		mockODM.AssertNotCalled(t, "FindWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("父訊息不存在", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		service := &chatService{
+			odm:         mockODM,
+			roomManager: mockRM,
+		}
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), channelID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, threadID.Hex(), mock.AnythingOfType("*models.Message")).Return(providers.ErrDocumentNotFound).Once()
+
+		result, msgOpt := service.GetThreadMessages(ctx, userID.Hex(), models.RoomTypeChannel, channelID.Hex(), threadID.Hex(), "", "", "")
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrMessageNotFound, msgOpt.Code)
+	})
+
+	t.Run("資料庫錯誤不視為訊息不存在", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		service := &chatService{
+			odm:         mockODM,
+			roomManager: mockRM,
+		}
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), channelID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, threadID.Hex(), mock.AnythingOfType("*models.Message")).Return(context.DeadlineExceeded).Once()
+
+		result, msgOpt := service.GetThreadMessages(ctx, userID.Hex(), models.RoomTypeChannel, channelID.Hex(), threadID.Hex(), "", "", "")
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+		mockODM.AssertNotCalled(t, "FindWithOptions", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("無權限存取房間", func(t *testing.T) {
+		mockRM := new(mockRoomManager)
+		service := &chatService{
+			roomManager: mockRM,
+		}
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), channelID.Hex(), models.RoomTypeChannel).Return(false, nil).Once()
+
+		result, msgOpt := service.GetThreadMessages(ctx, userID.Hex(), models.RoomTypeChannel, channelID.Hex(), threadID.Hex(), "", "", "")
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrNoPermission, msgOpt.Code)
+	})
+}
+
+func TestGetAttachment(t *testing.T) {
+	userID := primitive.NewObjectID()
+	fileID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	newMessage := func(roomID primitive.ObjectID) models.Message {
+		return models.Message{
+			BaseModel: providers.BaseModel{ID: primitive.NewObjectID(), CreatedAt: time.Now()},
+			RoomType:  models.RoomTypeChannel,
+			RoomID:    roomID,
+			SenderID:  primitive.NewObjectID(),
+			Attachments: []models.MessageAttachment{
+				{FileID: fileID, FileName: "photo.png", FileSize: 1024, MimeType: "image/png", URL: "/uploads/old.png"},
+			},
+		}
+	}
+
+	t.Run("可存取任一引用房間時返回最新連結", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		mockFS := new(mocks.FileUploadService)
+		service := &chatService{
+			odm:               mockODM,
+			roomManager:       mockRM,
+			fileUploadService: mockFS,
+		}
+
+		deniedRoom := primitive.NewObjectID()
+		allowedRoom := primitive.NewObjectID()
+		mockODM.On("Find", ctx, mock.MatchedBy(func(filter bson.M) bool {
+			return filter["attachments.file_id"] == fileID && filter["is_deleted"] == false
+		}), mock.AnythingOfType("*[]models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.Message) = []models.Message{newMessage(deniedRoom), newMessage(allowedRoom)}
+		}).Return(nil).Once()
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), deniedRoom.Hex(), models.RoomTypeChannel).Return(false, nil).Once()
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), allowedRoom.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockFS.On("GetFileDownloadURL", fileID.Hex()).Return("/uploads/photo.png", nil).Once()
+
+		attachment, msgOpt := service.GetAttachment(ctx, userID.Hex(), fileID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "photo.png", attachment.FileName)
+		assert.Equal(t, "/uploads/photo.png", attachment.URL)
+		mockODM.AssertExpectations(t)
+		mockRM.AssertExpectations(t)
+	})
+
+	t.Run("無權限存取房間", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		service := &chatService{
+			odm:         mockODM,
+			roomManager: mockRM,
+		}
+
+		roomID := primitive.NewObjectID()
+		mockODM.On("Find", ctx, mock.Anything, mock.AnythingOfType("*[]models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.Message) = []models.Message{newMessage(roomID)}
+		}).Return(nil).Once()
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(false, nil).Once()
+
+		attachment, msgOpt := service.GetAttachment(ctx, userID.Hex(), fileID.Hex())
+
+		assert.Nil(t, attachment)
+		assert.Equal(t, models.ErrNoPermission, msgOpt.Code)
+	})
+
+	t.Run("附件未被任何訊息引用", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		service := &chatService{odm: mockODM}
+
+		mockODM.On("Find", ctx, mock.Anything, mock.AnythingOfType("*[]models.Message")).Return(nil).Once()
+
+		attachment, msgOpt := service.GetAttachment(ctx, userID.Hex(), fileID.Hex())
+
+		assert.Nil(t, attachment)
+		assert.Equal(t, models.ErrNotFound, msgOpt.Code)
+	})
+
+	t.Run("無效的檔案ID", func(t *testing.T) {
+		service := &chatService{}
+
+		attachment, msgOpt := service.GetAttachment(ctx, userID.Hex(), "invalid")
+
+		assert.Nil(t, attachment)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestCanAccessAttachment(t *testing.T) {
+	userID := primitive.NewObjectID()
+	fileID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	newMessage := func(roomID primitive.ObjectID) models.Message {
+		return models.Message{
+			BaseModel:   providers.BaseModel{ID: primitive.NewObjectID()},
+			RoomType:    models.RoomTypeDM,
+			RoomID:      roomID,
+			Attachments: []models.MessageAttachment{{FileID: fileID}},
+		}
+	}
+
+	t.Run("同一房間只檢查一次", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		service := &chatService{odm: mockODM, roomManager: mockRM}
+
+		deniedRoom := primitive.NewObjectID()
+		allowedRoom := primitive.NewObjectID()
+		mockODM.On("Find", ctx, mock.MatchedBy(func(filter bson.M) bool {
+			ids, ok := filter["attachments.file_id"].(bson.M)["$in"].([]primitive.ObjectID)
+			return ok && len(ids) == 1 && ids[0] == fileID && filter["is_deleted"] == false
+		}), mock.AnythingOfType("*[]models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.Message) = []models.Message{newMessage(deniedRoom), newMessage(deniedRoom), newMessage(allowedRoom)}
+		}).Return(nil).Once()
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), deniedRoom.Hex(), models.RoomTypeDM).Return(false, nil).Once()
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), allowedRoom.Hex(), models.RoomTypeDM).Return(true, nil).Once()
+
+		allowed, msgOpt := service.CanAccessAttachment(ctx, userID.Hex(), []string{fileID.Hex()})
+
+		assert.Nil(t, msgOpt)
+		assert.True(t, allowed)
+		mockODM.AssertExpectations(t)
+		mockRM.AssertExpectations(t)
+	})
+
+	t.Run("未被任何訊息引用", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		service := &chatService{odm: mockODM}
+
+		mockODM.On("Find", ctx, mock.Anything, mock.AnythingOfType("*[]models.Message")).Return(nil).Once()
+
+		allowed, msgOpt := service.CanAccessAttachment(ctx, userID.Hex(), []string{fileID.Hex()})
+
+		assert.Nil(t, msgOpt)
+		assert.False(t, allowed)
+	})
+
+	t.Run("無效的檔案ID", func(t *testing.T) {
+		service := &chatService{}
+
+		allowed, msgOpt := service.CanAccessAttachment(ctx, userID.Hex(), []string{"invalid"})
+
+		assert.False(t, allowed)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
 	})
 }
 
@@ -585,3 +964,163 @@ func TestChatService_GetUserPictureURL(t *testing.T) {
 		assert.Empty(t, url)
 	})
 }
+
+func TestSearchMessages(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	dmRoomID := primitive.NewObjectID()
+	serverID := primitive.NewObjectID()
+	channelID := primitive.NewObjectID()
+
+	t.Run("未指定範圍時搜尋所有可讀取房間並回傳下一頁游標", func(t *testing.T) {
+		mockChatRepo := new(mocks.ChatRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockODM := new(mocks.ODM)
+		mockPS := new(mocks.PermissionService)
+		service := &chatService{chatRepo: mockChatRepo, serverMemberRepo: mockMemberRepo, odm: mockODM, permissionService: mockPS}
+
+		channels := []models.Channel{
+			{BaseModel: providers.BaseModel{ID: channelID}, ServerID: serverID},
+			{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID}, // 無法查看的私人頻道
+		}
+		mockChatRepo.On("GetDMRoomListByUserID", ctx, userID.Hex(), true).Return([]models.DMRoom{{RoomID: dmRoomID, UserID: userID}}, nil).Once()
+		mockMemberRepo.On("GetUserServers", userID.Hex()).Return([]models.ServerMember{{ServerID: serverID, UserID: userID}}, nil).Once()
+		mockODM.On("Find", ctx, bson.M{"server_id": bson.M{"$in": []primitive.ObjectID{serverID}}}, mock.AnythingOfType("*[]models.Channel")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.Channel) = channels
+		}).Return(nil).Once()
+		mockPS.On("FilterViewableChannels", ctx, serverID.Hex(), userID.Hex(), channels).Return(channels[:1], nil).Once()
+
+		newer := models.Message{BaseModel: providers.BaseModel{ID: primitive.NewObjectID(), CreatedAt: time.Now()}, RoomID: channelID, RoomType: models.RoomTypeChannel, SenderID: userID, Content: "hello world"}
+		older := models.Message{BaseModel: providers.BaseModel{ID: primitive.NewObjectID(), CreatedAt: time.Now()}, RoomID: dmRoomID, RoomType: models.RoomTypeDM, SenderID: userID, Content: "hello"}
+		oldest := models.Message{BaseModel: providers.BaseModel{ID: primitive.NewObjectID(), CreatedAt: time.Now()}, RoomID: dmRoomID, RoomType: models.RoomTypeDM, SenderID: userID, Content: "hello again"}
+
+		expectedRequest := models.MessageSearchRequest{Query: "hello", Limit: 2}
+		mockChatRepo.On("SearchMessages", ctx, []string{dmRoomID.Hex(), channelID.Hex()}, expectedRequest, int64(3)).
+			Return([]models.Message{newer, older, oldest}, nil).Once()
+
+		results, msgOpt := service.SearchMessages(ctx, userID.Hex(), models.MessageSearchRequest{Query: "  hello ", Limit: 2})
+
+		assert.Nil(t, msgOpt)
+		assert.Len(t, results.Messages, 2)
+		assert.Equal(t, newer.ID, results.Messages[0].ID)
+		assert.Equal(t, older.ID.Hex(), results.NextCursor)
+		mockChatRepo.AssertExpectations(t)
+		mockMemberRepo.AssertExpectations(t)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("限定伺服器但不是成員", func(t *testing.T) {
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		service := &chatService{serverMemberRepo: mockMemberRepo}
+
+		mockMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(false, nil).Once()
+
+		results, msgOpt := service.SearchMessages(ctx, userID.Hex(), models.MessageSearchRequest{Query: "hello", ServerID: serverID.Hex()})
+
+		assert.Nil(t, results)
+		assert.Equal(t, models.ErrNoPermission, msgOpt.Code)
+		mockMemberRepo.AssertExpectations(t)
+	})
+
+	t.Run("限定私聊房間但不是房間成員", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		service := &chatService{odm: mockODM}
+
+		mockODM.On("Exists", ctx, bson.M{"room_id": dmRoomID, "user_id": userID}, mock.AnythingOfType("*models.DMRoom")).Return(false, nil).Once()
+
+		results, msgOpt := service.SearchMessages(ctx, userID.Hex(), models.MessageSearchRequest{Query: "hello", RoomType: models.RoomTypeDM, RoomID: dmRoomID.Hex()})
+
+		assert.Nil(t, results)
+		assert.Equal(t, models.ErrNoPermission, msgOpt.Code)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("限定頻道時只搜尋該頻道", func(t *testing.T) {
+		mockChatRepo := new(mocks.ChatRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockODM := new(mocks.ODM)
+		mockPS := new(mocks.PermissionService)
+		service := &chatService{chatRepo: mockChatRepo, serverMemberRepo: mockMemberRepo, odm: mockODM, permissionService: mockPS}
+
+		mockODM.On("FindByID", ctx, channelID.Hex(), mock.AnythingOfType("*models.Channel")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Channel) = models.Channel{BaseModel: providers.BaseModel{ID: channelID}, ServerID: serverID}
+		}).Return(nil).Once()
+		mockMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(true, nil).Once()
+		mockPS.On("CheckChannelPermission", ctx, channelID.Hex(), userID.Hex(), models.PermissionViewChannel).Return(nil).Once()
+		mockChatRepo.On("SearchMessages", ctx, []string{channelID.Hex()}, mock.AnythingOfType("models.MessageSearchRequest"), int64(21)).Return([]models.Message{}, nil).Once()
+
+		results, msgOpt := service.SearchMessages(ctx, userID.Hex(), models.MessageSearchRequest{Query: "hello", RoomType: models.RoomTypeChannel, RoomID: channelID.Hex()})
+
+		assert.Nil(t, msgOpt)
+		assert.Empty(t, results.Messages)
+		assert.Empty(t, results.NextCursor)
+		mockChatRepo.AssertExpectations(t)
+	})
+
+	t.Run("無效的參數", func(t *testing.T) {
+		service := &chatService{}
+
+		for name, request := range map[string]models.MessageSearchRequest{
+			"空白關鍵字":  {Query: "   "},
+			"無效發送者":  {Query: "hello", SenderID: "invalid"},
+			"無效游標":   {Query: "hello", Before: "invalid"},
+			"時間範圍顛倒": {Query: "hello", From: 2000, To: 1000},
+			"無效房間類型": {Query: "hello", RoomID: primitive.NewObjectID().Hex(), RoomType: "user"},
+		} {
+			results, msgOpt := service.SearchMessages(ctx, userID.Hex(), request)
+			assert.Nil(t, results, name)
+			assert.Equal(t, models.ErrInvalidParams, msgOpt.Code, name)
+		}
+	})
+}
+
+// TestLeaveServerRooms 測試用戶的所有連線都會離開伺服器頻道房間
+func TestLeaveServerRooms(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID().Hex()
+	otherUserID := primitive.NewObjectID().Hex()
+	serverID := primitive.NewObjectID()
+	channelID := primitive.NewObjectID()
+
+	mockODM := new(mocks.ODM)
+	clientManager := NewClientManager(nil, nil)
+	cs := NewChatService(nil, mockODM, nil, nil, clientManager, nil, nil, nil, nil, nil, nil, nil).(*chatService)
+
+	// 同一用戶在兩個裝置上連線，另有一位其他用戶
+	desktop := clientManager.NewClient(userID, nil)
+	mobile := clientManager.NewClient(userID, nil)
+	other := clientManager.NewClient(otherUserID, nil)
+	room := cs.roomManager.InitRoom(models.RoomTypeChannel, channelID.Hex())
+	for _, client := range []*Client{desktop, mobile, other} {
+		clientManager.Register(client)
+		cs.roomManager.JoinRoom(client, models.RoomTypeChannel, channelID.Hex())
+	}
+
+	mockODM.On("Find", ctx, bson.M{"server_id": serverID}, mock.AnythingOfType("*[]models.Channel")).Run(func(args mock.Arguments) {
+		*args.Get(2).(*[]models.Channel) = []models.Channel{{BaseModel: providers.BaseModel{ID: channelID}}}
+	}).Return(nil).Once()
+
+	msgOpt := cs.LeaveServerRooms(ctx, userID, serverID.Hex())
+
+	assert.Nil(t, msgOpt)
+	room.Mutex.RLock()
+	assert.NotContains(t, room.Clients, desktop)
+	assert.NotContains(t, room.Clients, mobile)
+	assert.Contains(t, room.Clients, other)
+	room.Mutex.RUnlock()
+
+	for _, client := range []*Client{desktop, mobile} {
+		select {
+		case payload := <-client.Send:
+			var event WsMessage[ServerAccessRevokedEvent]
+			assert.NoError(t, json.Unmarshal(payload, &event))
+			assert.Equal(t, "server_access_revoked", event.Action)
+			assert.Equal(t, serverID.Hex(), event.Data.ServerID)
+			assert.Equal(t, []string{channelID.Hex()}, event.Data.ChannelIDs)
+		default:
+			t.Fatal("每個連線都應收到伺服器存取撤銷通知")
+		}
+	}
+	assert.Empty(t, other.Send)
+	mockODM.AssertExpectations(t)
+}
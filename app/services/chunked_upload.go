@@ -0,0 +1,622 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MinChunkSize        = 5 * 1024 * 1024  // 分段大小下限（S3 multipart 除最後一段外的最小分段）
+	DefaultChunkSize    = 8 * 1024 * 1024  // 預設分段大小
+	MaxChunkSize        = 32 * 1024 * 1024 // 分段大小上限，亦為單次分段請求的內容上限
+	MaxChunks           = 10000            // 分段數量上限（S3 multipart 限制）
+	ChunkedUploadExpiry = 24 * time.Hour   // 分段上傳保留時間，逾期未完成即清除
+)
+
+// chunkedUploadConfigs 可使用分段上傳的檔案類型，頭像等小檔案仍使用一般上傳
+var chunkedUploadConfigs = map[string]func() *models.FileUploadConfig{
+	"general":  models.GetGeneralUploadConfig,
+	"document": models.GetDocumentUploadConfig,
+	"image":    models.GetImageUploadConfig,
+}
+
+// InitiateChunkedUpload 開始分段上傳，檢查檔案資訊後在儲存端建立上傳並記錄工作階段
+func (fs *fileUploadService) InitiateChunkedUpload(userID string, request models.InitiateChunkedUploadRequest) (*models.ChunkedUploadResponse, *models.MessageOptions) {
+	session, msgOpt := fs.newUploadSession(userID, request.FileType, request.FileName, request.FileSize, request.MimeType, request.SHA256, request.ServerID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	chunkSize := request.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < MinChunkSize || chunkSize > MaxChunkSize {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "分段大小超出允許範圍",
+			Details: fmt.Sprintf("分段大小: %d bytes, 範圍: %d - %d bytes", chunkSize, MinChunkSize, MaxChunkSize),
+		}
+	}
+
+	totalChunks := int((request.FileSize + chunkSize - 1) / chunkSize)
+	if totalChunks > MaxChunks {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "分段數量超過上限，請加大分段大小",
+			Details: fmt.Sprintf("分段數量: %d, 上限: %d", totalChunks, MaxChunks),
+		}
+	}
+
+	relativePath := session.FilePath
+	uploadID, err := fs.fileProvider.InitiateChunkedUpload(relativePath, request.MimeType)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "建立分段上傳失敗",
+			Details: err.Error(),
+		}
+	}
+
+	session.UploadID = uploadID
+	session.ChunkSize = chunkSize
+	session.TotalChunks = totalChunks
+	session.ExpiresAt = time.Now().Add(ChunkedUploadExpiry)
+
+	if err := fs.uploadSessionRepo.CreateUploadSession(session); err != nil {
+		if abortErr := fs.fileProvider.AbortChunkedUpload(relativePath, uploadID); abortErr != nil {
+			slog.Warn("無法在紀錄建立失敗後放棄分段上傳", "path", relativePath, "error", abortErr)
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "建立分段上傳紀錄失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return toChunkedUploadResponse(session), nil
+}
+
+// newUploadSession 檢查檔案資訊、伺服器成員與儲存配額，建立尚未寫入資料庫的上傳工作階段
+func (fs *fileUploadService) newUploadSession(userID string, fileType string, fileName string, fileSize int64, mimeType string, sha256Hex string, serverID string) (*models.UploadSession, *models.MessageOptions) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的用戶ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	if fileType == "" {
+		fileType = "general"
+	}
+	getConfig, ok := chunkedUploadConfigs[fileType]
+	if !ok {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "不支援分段上傳的檔案類型",
+			Details: fmt.Sprintf("檔案類型: %s", fileType),
+		}
+	}
+	config := getConfig()
+
+	if fileName == "" || fileSize <= 0 {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "檔案名稱與大小不能為空",
+		}
+	}
+	if msgOpt := checkUploadConfig(fileName, fileSize, mimeType, config); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	serverObjectID, msgOpt := fs.resolveUploadServer(userID, serverID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+	if msgOpt := fs.checkStorageQuota(userID, serverObjectID, fileSize); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	hash := strings.ToLower(sha256Hex)
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的 SHA256 雜湊值",
+		}
+	}
+
+	secureFileName := providers.GenerateSecureFileName(fileName, userID)
+
+	return &models.UploadSession{
+		UserID:       userObjectID,
+		FileType:     config.FileType,
+		OriginalName: fileName,
+		FileName:     secureFileName,
+		FilePath:     filepath.Join(config.FileType, secureFileName),
+		FileSize:     fileSize,
+		MimeType:     mimeType,
+		Hash:         hash,
+		Parts:        make(map[string]models.UploadPart),
+		Status:       models.UploadSessionUploading,
+		ServerID:     serverObjectID,
+	}, nil
+}
+
+// UploadChunk 上傳單一分段（編號從 1 開始），除最後一段外大小必須等於分段大小；重複上傳同一分段會覆蓋
+func (fs *fileUploadService) UploadChunk(userID string, uploadID string, chunkNumber int, reader io.Reader, size int64) (*models.ChunkedUploadResponse, *models.MessageOptions) {
+	session, msgOpt := fs.getUploadingSession(userID, uploadID, false)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if chunkNumber < 1 || chunkNumber > session.TotalChunks {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的分段編號",
+			Details: fmt.Sprintf("分段編號: %d, 範圍: 1 - %d", chunkNumber, session.TotalChunks),
+		}
+	}
+
+	chunkRange := chunkByteRange(session, chunkNumber)
+	if expected := chunkRange.End - chunkRange.Start + 1; size != expected {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "分段大小不符",
+			Details: fmt.Sprintf("分段 %d 預期 %d bytes, 實際 %d bytes", chunkNumber, expected, size),
+		}
+	}
+
+	etag, err := fs.fileProvider.UploadChunk(session.FilePath, session.UploadID, chunkNumber, reader, size)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "分段儲存失敗",
+			Details: err.Error(),
+		}
+	}
+
+	part := models.UploadPart{
+		Number:     chunkNumber,
+		Size:       size,
+		ETag:       etag,
+		UploadedAt: time.Now(),
+	}
+	ok, err := fs.uploadSessionRepo.SetUploadPart(uploadID, part)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "記錄分段失敗",
+			Details: err.Error(),
+		}
+	}
+	if !ok {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "分段上傳已結束",
+		}
+	}
+
+	session.Parts[strconv.Itoa(chunkNumber)] = part
+	return toChunkedUploadResponse(session), nil
+}
+
+// GetChunkedUpload 查詢分段上傳狀態與已接收的範圍，用於續傳
+func (fs *fileUploadService) GetChunkedUpload(userID string, uploadID string) (*models.ChunkedUploadResponse, *models.MessageOptions) {
+	session, msgOpt := fs.getUserUploadSession(userID, uploadID, false)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	return toChunkedUploadResponse(session), nil
+}
+
+// CompleteChunkedUpload 合併所有分段，比對 SHA256 與內容後建立檔案記錄
+// 驗證失敗時刪除合併後的檔案並將工作階段標記為 failed，需重新開始上傳
+func (fs *fileUploadService) CompleteChunkedUpload(userID string, uploadID string) (*models.FileResult, *models.MessageOptions) {
+	session, msgOpt := fs.getUploadingSession(userID, uploadID, false)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if missing := missingChunks(session); len(missing) > 0 {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrUploadIncomplete,
+			Message: "尚有分段未上傳",
+			Details: fmt.Sprintf("缺少分段: %v", missing),
+		}
+	}
+
+	// 先切換為合併中，避免重複完成或在合併期間繼續接收分段
+	ok, err := fs.uploadSessionRepo.TransitionUploadSession(uploadID, models.UploadSessionUploading, models.UploadSessionCompleting, nil)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "更新分段上傳狀態失敗",
+			Details: err.Error(),
+		}
+	}
+	if !ok {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "分段上傳已結束",
+		}
+	}
+
+	parts := make([]providers.ChunkPart, 0, session.TotalChunks)
+	for number := 1; number <= session.TotalChunks; number++ {
+		parts = append(parts, providers.ChunkPart{
+			Number: number,
+			ETag:   session.Parts[strconv.Itoa(number)].ETag,
+		})
+	}
+
+	filePath, err := fs.fileProvider.CompleteChunkedUpload(session.FilePath, session.UploadID, parts)
+	if err != nil {
+		// 合併失敗時分段仍保留，恢復為上傳中讓客戶端可重試
+		fs.transitionUploadSession(uploadID, models.UploadSessionCompleting, models.UploadSessionUploading, nil)
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "合併分段失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return fs.finalizeUploadSession(userID, session, filePath)
+}
+
+// finalizeUploadSession 驗證已完整上傳到儲存端的檔案並建立檔案記錄，分段與預簽名上傳共用
+// 驗證失敗時刪除檔案並將工作階段標記為 failed，需重新開始上傳
+func (fs *fileUploadService) finalizeUploadSession(userID string, session *models.UploadSession, filePath string) (*models.FileResult, *models.MessageOptions) {
+	uploadID := session.ID.Hex()
+
+	// 上傳期間用量可能已增加，建立記錄前再次檢查配額
+	config := chunkedUploadConfigs[session.FileType]()
+	msgOpt := fs.checkStorageQuota(userID, session.ServerID, session.FileSize)
+	if msgOpt == nil {
+		msgOpt = fs.verifyUploadSession(session, filePath)
+	}
+	if msgOpt != nil {
+		if err := fs.fileProvider.DeleteFile(filePath); err != nil {
+			slog.Warn("無法刪除驗證失敗的檔案", "path", filePath, "error", err)
+		}
+		fs.transitionUploadSession(uploadID, models.UploadSessionCompleting, models.UploadSessionFailed, nil)
+		return nil, msgOpt
+	}
+
+	// 相同內容已儲存時改用既有的儲存物件
+	blob, msgOpt := fs.dedupeAssembledFile(session, filePath)
+	if msgOpt != nil {
+		fs.transitionUploadSession(uploadID, models.UploadSessionCompleting, models.UploadSessionFailed, nil)
+		return nil, msgOpt
+	}
+	filePath = blob.FilePath
+
+	uploadedFile := &models.UploadedFile{
+		UserID:       session.UserID,
+		OriginalName: session.OriginalName,
+		FileName:     session.FileName,
+		FilePath:     filePath,
+		FileSize:     session.FileSize,
+		MimeType:     session.MimeType,
+		FileType:     session.FileType,
+		Hash:         session.Hash,
+		ServerID:     session.ServerID,
+	}
+
+	// 惡意軟體掃描與縮圖在背景處理，完成前檔案維持 processing 不會被提供
+	plan := fs.planFileProcessing(uploadedFile, config, blob.RefCount > 1)
+	status := uploadedFile.Status
+
+	if err := fs.fileRepo.CreateFile(uploadedFile); err != nil {
+		if cleanupErr := fs.releaseStoredFile(uploadedFile); cleanupErr != nil {
+			slog.Warn("無法在資料庫寫入失敗後清理檔案", "path", filePath, "error", cleanupErr)
+		}
+		fs.transitionUploadSession(uploadID, models.UploadSessionCompleting, models.UploadSessionFailed, nil)
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "資料庫記錄創建失敗",
+			Details: err.Error(),
+		}
+	}
+
+	fileID := uploadedFile.GetID()
+	fs.transitionUploadSession(uploadID, models.UploadSessionCompleting, models.UploadSessionCompleted, map[string]any{"file_id": fileID})
+
+	if status == "processing" {
+		go fs.processUploadedFile(*uploadedFile, plan)
+	}
+
+	return &models.FileResult{
+		ID:         fileID,
+		FileName:   session.FileName,
+		FilePath:   filePath,
+		FileURL:    fs.verifiedFileURL(uploadedFile),
+		FileSize:   session.FileSize,
+		MimeType:   session.MimeType,
+		UploadedAt: time.Now().UnixMilli(),
+		UserID:     userID,
+		Status:     status,
+	}, nil
+}
+
+// dedupeAssembledFile 合併完成的檔案已有相同內容時刪除合併結果並沿用既有內容，否則記錄為新內容
+func (fs *fileUploadService) dedupeAssembledFile(session *models.UploadSession, filePath string) (*models.FileBlob, *models.MessageOptions) {
+	blob, err := fs.fileRepo.AcquireBlob(session.Hash)
+	if err != nil {
+		if cleanupErr := fs.fileProvider.DeleteFile(filePath); cleanupErr != nil {
+			slog.Warn("無法在記錄檔案內容失敗後清理檔案", "path", filePath, "error", cleanupErr)
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "檔案儲存失敗",
+			Details: err.Error(),
+		}
+	}
+	if blob == nil {
+		return fs.registerFileBlob(&models.FileBlob{
+			Hash:     session.Hash,
+			FilePath: filePath,
+			FileSize: session.FileSize,
+			MimeType: session.MimeType,
+		})
+	}
+
+	if err := fs.fileProvider.DeleteFile(filePath); err != nil {
+		slog.Warn("無法刪除重複儲存的檔案", "path", filePath, "error", err)
+	}
+	return blob, nil
+}
+
+// AbortChunkedUpload 放棄分段上傳並清除已上傳的分段
+func (fs *fileUploadService) AbortChunkedUpload(userID string, uploadID string) *models.MessageOptions {
+	session, msgOpt := fs.getUserUploadSession(userID, uploadID, false)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	ok, err := fs.uploadSessionRepo.TransitionUploadSession(uploadID, models.UploadSessionUploading, models.UploadSessionAborted, nil)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "更新分段上傳狀態失敗",
+			Details: err.Error(),
+		}
+	}
+	if !ok {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "分段上傳已結束",
+		}
+	}
+
+	if err := fs.fileProvider.AbortChunkedUpload(session.FilePath, session.UploadID); err != nil {
+		slog.Warn("無法清除已放棄的分段", "upload_id", uploadID, "error", err)
+	}
+
+	return nil
+}
+
+// CleanupExpiredChunkedUploads 清除過期的分段與預簽名上傳，未完成的上傳會一併清除儲存端的分段或暫存物件
+func (fs *fileUploadService) CleanupExpiredChunkedUploads() *models.MessageOptions {
+	sessions, err := fs.uploadSessionRepo.GetExpiredUploadSessions(time.Now())
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取過期分段上傳失敗",
+			Details: err.Error(),
+		}
+	}
+
+	for _, session := range sessions {
+		// 預簽名上傳的網址在工作階段過期前已失效，不論狀態都清除確認後才重新上傳的暫存物件
+		if session.Presigned || session.Status == models.UploadSessionUploading || session.Status == models.UploadSessionCompleting {
+			if err := fs.discardPendingUpload(&session); err != nil {
+				slog.Warn("無法清除過期的分段", "upload_id", session.ID.Hex(), "error", err)
+				continue
+			}
+		}
+
+		if err := fs.uploadSessionRepo.DeleteUploadSession(session.ID.Hex()); err != nil {
+			slog.Warn("無法刪除過期的分段上傳紀錄", "upload_id", session.ID.Hex(), "error", err)
+		}
+	}
+
+	return nil
+}
+
+// discardPendingUpload 清除未完成上傳在儲存端留下的分段或暫存物件
+func (fs *fileUploadService) discardPendingUpload(session *models.UploadSession) error {
+	if session.Presigned {
+		return fs.fileProvider.DeleteFile(session.UploadID)
+	}
+	return fs.fileProvider.AbortChunkedUpload(session.FilePath, session.UploadID)
+}
+
+// getUserUploadSession 獲取用戶自己的上傳工作階段，不屬於該用戶或上傳方式不符時視為不存在
+func (fs *fileUploadService) getUserUploadSession(userID string, uploadID string, presigned bool) (*models.UploadSession, *models.MessageOptions) {
+	if _, err := primitive.ObjectIDFromHex(uploadID); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的上傳ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	session, err := fs.uploadSessionRepo.GetUploadSessionByID(uploadID)
+	if err != nil || session.UserID.Hex() != userID || session.Presigned != presigned {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "分段上傳不存在",
+		}
+	}
+
+	if session.Parts == nil {
+		session.Parts = make(map[string]models.UploadPart)
+	}
+
+	return session, nil
+}
+
+// getUploadingSession 獲取仍在上傳中且未過期的上傳工作階段
+func (fs *fileUploadService) getUploadingSession(userID string, uploadID string, presigned bool) (*models.UploadSession, *models.MessageOptions) {
+	session, msgOpt := fs.getUserUploadSession(userID, uploadID, presigned)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if session.Status != models.UploadSessionUploading {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "分段上傳已結束",
+			Details: fmt.Sprintf("狀態: %s", session.Status),
+		}
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "分段上傳已過期",
+		}
+	}
+
+	return session, nil
+}
+
+// transitionUploadSession 更新分段上傳狀態，操作本身已完成，失敗只記錄日誌
+func (fs *fileUploadService) transitionUploadSession(uploadID string, fromStatus string, toStatus string, fields map[string]any) {
+	if _, err := fs.uploadSessionRepo.TransitionUploadSession(uploadID, fromStatus, toStatus, fields); err != nil {
+		slog.Error("更新分段上傳狀態失敗", "upload_id", uploadID, "status", toStatus, "error", err)
+	}
+}
+
+// verifyUploadSession 讀取上傳完成的檔案，比對大小、SHA256 與內容類型（惡意軟體掃描於建立記錄後在背景進行）
+func (fs *fileUploadService) verifyUploadSession(session *models.UploadSession, filePath string) *models.MessageOptions {
+	reader, err := fs.fileProvider.GetFile(filePath)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "無法開啟合併後的檔案",
+			Details: err.Error(),
+		}
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			slog.Warn("無法關閉檔案 (verifyUploadSession)", "path", filePath, "error", err)
+		}
+	}()
+
+	// 保留開頭內容供類型檢測，其餘內容只計算雜湊
+	head := make([]byte, 512)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "無法讀取上傳的檔案",
+			Details: err.Error(),
+		}
+	}
+	head = head[:n]
+
+	h := sha256.New()
+	_, _ = h.Write(head)
+	rest, err := io.Copy(h, reader)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "檔案雜湊計算失敗",
+			Details: err.Error(),
+		}
+	}
+
+	if size := int64(n) + rest; size != session.FileSize {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "上傳的檔案大小不符",
+			Details: fmt.Sprintf("預期 %d bytes, 實際 %d bytes", session.FileSize, size),
+		}
+	}
+
+	if hash := hex.EncodeToString(h.Sum(nil)); hash != session.Hash {
+		return &models.MessageOptions{
+			Code:    models.ErrHashMismatch,
+			Message: "檔案雜湊與宣告值不符",
+			Details: fmt.Sprintf("宣告: %s, 實際: %s", session.Hash, hash),
+		}
+	}
+
+	return fs.checkContentHead(head, session.MimeType)
+}
+
+// chunkByteRange 計算分段涵蓋的位元組範圍，最後一段可能小於分段大小
+func chunkByteRange(session *models.UploadSession, chunkNumber int) models.ByteRange {
+	start := int64(chunkNumber-1) * session.ChunkSize
+	end := min(start+session.ChunkSize, session.FileSize) - 1
+	return models.ByteRange{Start: start, End: end}
+}
+
+// receivedChunkNumbers 已接收的分段編號（遞增排序）
+func receivedChunkNumbers(session *models.UploadSession) []int {
+	numbers := make([]int, 0, len(session.Parts))
+	for _, part := range session.Parts {
+		numbers = append(numbers, part.Number)
+	}
+	slices.Sort(numbers)
+	return numbers
+}
+
+// missingChunks 尚未接收的分段編號
+func missingChunks(session *models.UploadSession) []int {
+	var missing []int
+	for number := 1; number <= session.TotalChunks; number++ {
+		if _, ok := session.Parts[strconv.Itoa(number)]; !ok {
+			missing = append(missing, number)
+		}
+	}
+	return missing
+}
+
+// toChunkedUploadResponse 轉換分段上傳為響應格式，相鄰的分段合併為同一個範圍
+func toChunkedUploadResponse(session *models.UploadSession) *models.ChunkedUploadResponse {
+	response := &models.ChunkedUploadResponse{
+		UploadID:       session.ID.Hex(),
+		FileName:       session.OriginalName,
+		FileSize:       session.FileSize,
+		MimeType:       session.MimeType,
+		ChunkSize:      session.ChunkSize,
+		TotalChunks:    session.TotalChunks,
+		ReceivedChunks: receivedChunkNumbers(session),
+		ReceivedRanges: []models.ByteRange{},
+		Status:         session.Status,
+		ExpiresAt:      session.ExpiresAt.Unix(),
+	}
+	if session.FileID != nil {
+		response.FileID = session.FileID.Hex()
+	}
+
+	for _, number := range response.ReceivedChunks {
+		chunkRange := chunkByteRange(session, number)
+		response.ReceivedBytes += chunkRange.End - chunkRange.Start + 1
+
+		last := len(response.ReceivedRanges) - 1
+		if last >= 0 && response.ReceivedRanges[last].End+1 == chunkRange.Start {
+			response.ReceivedRanges[last].End = chunkRange.End
+			continue
+		}
+		response.ReceivedRanges = append(response.ReceivedRanges, chunkRange)
+	}
+
+	return response
+}
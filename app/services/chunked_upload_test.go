@@ -0,0 +1,532 @@
+package services
+
+import (
+	"bytes"
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mockUploadSessionRepository 模擬 UploadSessionRepository
+type mockUploadSessionRepository struct {
+	mock.Mock
+}
+
+func (m *mockUploadSessionRepository) CreateUploadSession(session *models.UploadSession) error {
+	args := m.Called(session)
+	return args.Error(0)
+}
+
+func (m *mockUploadSessionRepository) GetUploadSessionByID(sessionID string) (*models.UploadSession, error) {
+	args := m.Called(sessionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UploadSession), args.Error(1)
+}
+
+func (m *mockUploadSessionRepository) SetUploadPart(sessionID string, part models.UploadPart) (bool, error) {
+	args := m.Called(sessionID, part)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockUploadSessionRepository) TransitionUploadSession(sessionID string, fromStatus string, toStatus string, fields map[string]any) (bool, error) {
+	args := m.Called(sessionID, fromStatus, toStatus, fields)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockUploadSessionRepository) GetExpiredUploadSessions(now time.Time) ([]models.UploadSession, error) {
+	args := m.Called(now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UploadSession), args.Error(1)
+}
+
+func (m *mockUploadSessionRepository) DeleteUploadSession(sessionID string) error {
+	args := m.Called(sessionID)
+	return args.Error(0)
+}
+
+// newTestUploadSession 建立測試用的分段上傳，content 依分段大小切分後由 parts 指定已接收的分段
+func newTestUploadSession(userID primitive.ObjectID, content []byte, chunkSize int64, parts ...int) *models.UploadSession {
+	sum := sha256.Sum256(content)
+	session := &models.UploadSession{
+		BaseModel:    providers.BaseModel{ID: primitive.NewObjectID()},
+		UserID:       userID,
+		UploadID:     "provider-upload-id",
+		FileType:     "document",
+		OriginalName: "notes.pdf",
+		FileName:     "123_abc.pdf",
+		FilePath:     "document/123_abc.pdf",
+		FileSize:     int64(len(content)),
+		MimeType:     "application/pdf",
+		ChunkSize:    chunkSize,
+		TotalChunks:  int((int64(len(content)) + chunkSize - 1) / chunkSize),
+		Hash:         hex.EncodeToString(sum[:]),
+		Parts:        make(map[string]models.UploadPart),
+		Status:       models.UploadSessionUploading,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	for _, number := range parts {
+		chunkRange := chunkByteRange(session, number)
+		session.Parts[strconv.Itoa(number)] = models.UploadPart{
+			Number: number,
+			Size:   chunkRange.End - chunkRange.Start + 1,
+			ETag:   "etag",
+		}
+	}
+	return session
+}
+
+func TestInitiateChunkedUpload(t *testing.T) {
+	userID := primitive.NewObjectID()
+	validHash := hex.EncodeToString(make([]byte, sha256.Size))
+
+	t.Run("成功建立分段上傳", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockFileProvider.On("InitiateChunkedUpload", mock.MatchedBy(func(path string) bool {
+			return len(path) > len("document/") && path[:len("document/")] == "document/"
+		}), "application/pdf").Return("provider-upload-id", nil).Once()
+		mockSessionRepo.On("CreateUploadSession", mock.MatchedBy(func(session *models.UploadSession) bool {
+			return session.UploadID == "provider-upload-id" &&
+				session.TotalChunks == 3 &&
+				session.ChunkSize == DefaultChunkSize &&
+				session.Status == models.UploadSessionUploading
+		})).Return(nil).Once()
+
+		response, msgOpt := service.InitiateChunkedUpload(userID.Hex(), models.InitiateChunkedUploadRequest{
+			FileName: "report.pdf",
+			FileSize: 2*DefaultChunkSize + 1,
+			MimeType: "application/pdf",
+			SHA256:   validHash,
+			FileType: "document",
+		})
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, 3, response.TotalChunks)
+		assert.Empty(t, response.ReceivedChunks)
+		mockFileProvider.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("紀錄建立失敗時放棄上傳", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockFileProvider.On("InitiateChunkedUpload", mock.Anything, "application/pdf").Return("provider-upload-id", nil).Once()
+		mockSessionRepo.On("CreateUploadSession", mock.Anything).Return(errors.New("db error")).Once()
+		mockFileProvider.On("AbortChunkedUpload", mock.Anything, "provider-upload-id").Return(nil).Once()
+
+		response, msgOpt := service.InitiateChunkedUpload(userID.Hex(), models.InitiateChunkedUploadRequest{
+			FileName: "report.pdf",
+			FileSize: 1024,
+			MimeType: "application/pdf",
+			SHA256:   validHash,
+		})
+
+		assert.Nil(t, response)
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+		mockFileProvider.AssertExpectations(t)
+	})
+
+	tests := []struct {
+		name    string
+		request models.InitiateChunkedUploadRequest
+	}{
+		{"不支援的檔案類型", models.InitiateChunkedUploadRequest{FileName: "a.png", FileSize: 1024, MimeType: "image/png", SHA256: validHash, FileType: "avatar"}},
+		{"不支援的副檔名", models.InitiateChunkedUploadRequest{FileName: "a.exe", FileSize: 1024, MimeType: "application/pdf", SHA256: validHash}},
+		{"超過檔案大小上限", models.InitiateChunkedUploadRequest{FileName: "a.pdf", FileSize: 200 * 1024 * 1024, MimeType: "application/pdf", SHA256: validHash}},
+		{"無效的雜湊值", models.InitiateChunkedUploadRequest{FileName: "a.pdf", FileSize: 1024, MimeType: "application/pdf", SHA256: "not-a-hash"}},
+		{"分段大小過小", models.InitiateChunkedUploadRequest{FileName: "a.pdf", FileSize: 1024, MimeType: "application/pdf", SHA256: validHash, ChunkSize: 1024}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockFileProvider := new(mockFileProvider)
+			service := &fileUploadService{
+				fileProvider: mockFileProvider,
+			}
+
+			response, msgOpt := service.InitiateChunkedUpload(userID.Hex(), tt.request)
+
+			assert.Nil(t, response)
+			assert.NotNil(t, msgOpt)
+			assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+			mockFileProvider.AssertNotCalled(t, "InitiateChunkedUpload", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestUploadChunk(t *testing.T) {
+	userID := primitive.NewObjectID()
+	content := []byte("0123456789")
+
+	t.Run("成功上傳分段", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, 4, 1)
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		reader := bytes.NewReader(content[4:8])
+		mockSessionRepo.On("GetUploadSessionByID", session.ID.Hex()).Return(session, nil).Once()
+		mockFileProvider.On("UploadChunk", session.FilePath, session.UploadID, 2, reader, int64(4)).Return("etag-2", nil).Once()
+		mockSessionRepo.On("SetUploadPart", session.ID.Hex(), mock.MatchedBy(func(part models.UploadPart) bool {
+			return part.Number == 2 && part.Size == 4 && part.ETag == "etag-2"
+		})).Return(true, nil).Once()
+
+		response, msgOpt := service.UploadChunk(userID.Hex(), session.ID.Hex(), 2, reader, 4)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, []int{1, 2}, response.ReceivedChunks)
+		assert.Equal(t, []models.ByteRange{{Start: 0, End: 7}}, response.ReceivedRanges)
+		assert.Equal(t, int64(8), response.ReceivedBytes)
+		mockFileProvider.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("最後一段大小不符", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, 4)
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", session.ID.Hex()).Return(session, nil).Once()
+
+		response, msgOpt := service.UploadChunk(userID.Hex(), session.ID.Hex(), 3, bytes.NewReader(content[8:]), 4)
+
+		assert.Nil(t, response)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+		mockFileProvider.AssertNotCalled(t, "UploadChunk", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("分段編號超出範圍", func(t *testing.T) {
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, 4)
+
+		service := &fileUploadService{
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", session.ID.Hex()).Return(session, nil).Once()
+
+		response, msgOpt := service.UploadChunk(userID.Hex(), session.ID.Hex(), 4, bytes.NewReader(nil), 0)
+
+		assert.Nil(t, response)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+
+	t.Run("其他用戶的上傳視為不存在", func(t *testing.T) {
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(primitive.NewObjectID(), content, 4)
+
+		service := &fileUploadService{
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", session.ID.Hex()).Return(session, nil).Once()
+
+		response, msgOpt := service.UploadChunk(userID.Hex(), session.ID.Hex(), 1, bytes.NewReader(content[:4]), 4)
+
+		assert.Nil(t, response)
+		assert.Equal(t, models.ErrNotFound, msgOpt.Code)
+	})
+
+	t.Run("上傳已結束", func(t *testing.T) {
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, 4)
+		session.Status = models.UploadSessionCompleted
+
+		service := &fileUploadService{
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", session.ID.Hex()).Return(session, nil).Once()
+
+		response, msgOpt := service.UploadChunk(userID.Hex(), session.ID.Hex(), 1, bytes.NewReader(content[:4]), 4)
+
+		assert.Nil(t, response)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestCompleteChunkedUpload(t *testing.T) {
+	userID := primitive.NewObjectID()
+	content := []byte("%PDF-1.4 notes for upload")
+
+	t.Run("成功合併並建立檔案", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, 10, 1, 2, 3)
+		sessionID := session.ID.Hex()
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			fileRepo:          mockFileRepo,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", sessionID).Return(session, nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionUploading, models.UploadSessionCompleting, map[string]any(nil)).Return(true, nil).Once()
+		mockFileProvider.On("CompleteChunkedUpload", session.FilePath, session.UploadID, []providers.ChunkPart{
+			{Number: 1, ETag: "etag"}, {Number: 2, ETag: "etag"}, {Number: 3, ETag: "etag"},
+		}).Return(session.FilePath, nil).Once()
+		// 驗證雜湊與背景惡意軟體掃描各讀取一次
+		mockFileProvider.On("GetFile", session.FilePath).Return(io.NopCloser(bytes.NewReader(content)), nil).Twice()
+		mockFileRepo.On("AcquireBlob", session.Hash).Return(nil, nil).Once()
+		mockFileRepo.On("RegisterBlob", mock.MatchedBy(func(blob *models.FileBlob) bool {
+			return blob.Hash == session.Hash && blob.FilePath == session.FilePath
+		})).Return(&models.FileBlob{Hash: session.Hash, FilePath: session.FilePath, RefCount: 1}, nil).Once()
+		mockFileRepo.On("CreateFile", mock.MatchedBy(func(file *models.UploadedFile) bool {
+			return file.Hash == session.Hash && file.Status == "processing" && file.FileSize == int64(len(content))
+		})).Return(nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionCompleting, models.UploadSessionCompleted, mock.Anything).Return(true, nil).Once()
+		scanned := make(chan struct{})
+		mockFileRepo.On("UpdateFileStatus", mock.Anything, "verified").Return(nil).Once().Run(func(args mock.Arguments) {
+			close(scanned)
+		})
+
+		result, msgOpt := service.CompleteChunkedUpload(userID.Hex(), sessionID)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "processing", result.Status)
+		assert.Empty(t, result.FileURL, "掃描完成前不提供檔案連結")
+
+		select {
+		case <-scanned:
+		case <-time.After(time.Second):
+			t.Fatal("背景掃描未完成")
+		}
+		mockFileProvider.AssertExpectations(t)
+		mockFileRepo.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("相同內容已存在時沿用既有檔案", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, 10, 1, 2, 3)
+		sessionID := session.ID.Hex()
+		existingPath := "document/100_existing.pdf"
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			fileRepo:          mockFileRepo,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", sessionID).Return(session, nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionUploading, models.UploadSessionCompleting, map[string]any(nil)).Return(true, nil).Once()
+		mockFileProvider.On("CompleteChunkedUpload", session.FilePath, session.UploadID, mock.Anything).Return(session.FilePath, nil).Once()
+		mockFileProvider.On("GetFile", session.FilePath).Return(io.NopCloser(bytes.NewReader(content)), nil).Once()
+		mockFileRepo.On("AcquireBlob", session.Hash).Return(&models.FileBlob{Hash: session.Hash, FilePath: existingPath, RefCount: 2}, nil).Once()
+		// 既有內容已通過掃描，不再重複掃描
+		mockFileRepo.On("GetVerifiedFileByHash", session.Hash).Return(&models.UploadedFile{FilePath: existingPath, Status: "verified"}, nil).Once()
+		// 合併出的重複內容被刪除，記錄指向既有檔案
+		mockFileProvider.On("DeleteFile", session.FilePath).Return(nil).Once()
+		mockFileRepo.On("CreateFile", mock.MatchedBy(func(file *models.UploadedFile) bool {
+			return file.FilePath == existingPath && file.FileName == session.FileName && file.Hash == session.Hash
+		})).Return(nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionCompleting, models.UploadSessionCompleted, mock.Anything).Return(true, nil).Once()
+		mockFileProvider.On("GetFileURL", existingPath).Return("http://localhost/uploads/document/100_existing.pdf").Once()
+
+		result, msgOpt := service.CompleteChunkedUpload(userID.Hex(), sessionID)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, existingPath, result.FilePath)
+		assert.Equal(t, "verified", result.Status)
+		mockFileProvider.AssertExpectations(t)
+		mockFileRepo.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("雜湊不符時刪除檔案並標記失敗", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, 10, 1, 2, 3)
+		sessionID := session.ID.Hex()
+		tampered := bytes.ToUpper(content)
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			fileRepo:          mockFileRepo,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", sessionID).Return(session, nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionUploading, models.UploadSessionCompleting, map[string]any(nil)).Return(true, nil).Once()
+		mockFileProvider.On("CompleteChunkedUpload", session.FilePath, session.UploadID, mock.Anything).Return(session.FilePath, nil).Once()
+		mockFileProvider.On("GetFile", session.FilePath).Return(io.NopCloser(bytes.NewReader(tampered)), nil).Once()
+		mockFileProvider.On("DeleteFile", session.FilePath).Return(nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionCompleting, models.UploadSessionFailed, map[string]any(nil)).Return(true, nil).Once()
+
+		result, msgOpt := service.CompleteChunkedUpload(userID.Hex(), sessionID)
+
+		assert.Nil(t, result)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrHashMismatch, msgOpt.Code)
+		mockFileRepo.AssertNotCalled(t, "CreateFile", mock.Anything)
+		mockFileProvider.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("合併失敗時恢復為上傳中", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, 10, 1, 2, 3)
+		sessionID := session.ID.Hex()
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", sessionID).Return(session, nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionUploading, models.UploadSessionCompleting, map[string]any(nil)).Return(true, nil).Once()
+		mockFileProvider.On("CompleteChunkedUpload", session.FilePath, session.UploadID, mock.Anything).Return("", errors.New("minio error")).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionCompleting, models.UploadSessionUploading, map[string]any(nil)).Return(true, nil).Once()
+
+		result, msgOpt := service.CompleteChunkedUpload(userID.Hex(), sessionID)
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("尚有分段未上傳", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, 10, 1, 3)
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", session.ID.Hex()).Return(session, nil).Once()
+
+		result, msgOpt := service.CompleteChunkedUpload(userID.Hex(), session.ID.Hex())
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrUploadIncomplete, msgOpt.Code)
+		assert.Contains(t, msgOpt.Details, "[2]")
+		mockFileProvider.AssertNotCalled(t, "CompleteChunkedUpload", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAbortChunkedUpload(t *testing.T) {
+	userID := primitive.NewObjectID()
+
+	t.Run("成功放棄上傳", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, []byte("content"), 4, 1)
+		sessionID := session.ID.Hex()
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", sessionID).Return(session, nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionUploading, models.UploadSessionAborted, map[string]any(nil)).Return(true, nil).Once()
+		mockFileProvider.On("AbortChunkedUpload", session.FilePath, session.UploadID).Return(nil).Once()
+
+		msgOpt := service.AbortChunkedUpload(userID.Hex(), sessionID)
+
+		assert.Nil(t, msgOpt)
+		mockFileProvider.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("已完成的上傳無法放棄", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, []byte("content"), 4, 1, 2)
+		session.Status = models.UploadSessionCompleted
+		sessionID := session.ID.Hex()
+
+		service := &fileUploadService{
+			fileProvider:      mockFileProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", sessionID).Return(session, nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionUploading, models.UploadSessionAborted, map[string]any(nil)).Return(false, nil).Once()
+
+		msgOpt := service.AbortChunkedUpload(userID.Hex(), sessionID)
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+		mockFileProvider.AssertNotCalled(t, "AbortChunkedUpload", mock.Anything, mock.Anything)
+	})
+}
+
+func TestCleanupExpiredChunkedUploads(t *testing.T) {
+	mockFileProvider := new(mockFileProvider)
+	mockSessionRepo := new(mockUploadSessionRepository)
+	uploading := newTestUploadSession(primitive.NewObjectID(), []byte("content"), 4, 1)
+	completed := newTestUploadSession(primitive.NewObjectID(), []byte("content"), 4, 1, 2)
+	completed.Status = models.UploadSessionCompleted
+
+	service := &fileUploadService{
+		fileProvider:      mockFileProvider,
+		uploadSessionRepo: mockSessionRepo,
+	}
+
+	mockSessionRepo.On("GetExpiredUploadSessions", mock.Anything).Return([]models.UploadSession{*uploading, *completed}, nil).Once()
+	mockFileProvider.On("AbortChunkedUpload", uploading.FilePath, uploading.UploadID).Return(nil).Once()
+	mockSessionRepo.On("DeleteUploadSession", uploading.ID.Hex()).Return(nil).Once()
+	mockSessionRepo.On("DeleteUploadSession", completed.ID.Hex()).Return(nil).Once()
+
+	msgOpt := service.CleanupExpiredChunkedUploads()
+
+	assert.Nil(t, msgOpt)
+	mockFileProvider.AssertExpectations(t)
+	mockSessionRepo.AssertExpectations(t)
+}
+
+func TestToChunkedUploadResponse(t *testing.T) {
+	// 10 bytes、分段大小 3：分段範圍為 0-2、3-5、6-8、9-9
+	session := newTestUploadSession(primitive.NewObjectID(), []byte("0123456789"), 3, 1, 2, 4)
+
+	response := toChunkedUploadResponse(session)
+
+	assert.Equal(t, 4, response.TotalChunks)
+	assert.Equal(t, []int{1, 2, 4}, response.ReceivedChunks)
+	assert.Equal(t, []models.ByteRange{{Start: 0, End: 5}, {Start: 9, End: 9}}, response.ReceivedRanges)
+	assert.Equal(t, int64(7), response.ReceivedBytes)
+}
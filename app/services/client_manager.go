@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"sync"
 	"time"
@@ -10,6 +11,16 @@ import (
 	"chat_app_backend/utils"
 
 	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+)
+
+// clientEventsChannel 跨實例連線管理事件的 Redis Pub/Sub 頻道
+const clientEventsChannel = "client_events"
+
+// 連線管理事件類型
+const (
+	clientEventLeaveServerRooms  = "leave_server_rooms" // 用戶被踢出或封鎖，離開伺服器頻道房間
+	clientEventDisconnectSession = "disconnect_session" // 登入工作階段被撤銷，斷開其連線
 )
 
 // clientManager 管理客戶端的註冊和註銷
@@ -18,15 +29,25 @@ type clientManager struct {
 	clientsByUserID map[string]*Client
 	mutex           sync.RWMutex
 	cache           providers.CacheProvider // 用於跨實例在線狀態查詢
+	redisClient     *redis.Client           // 用於跨實例廣播連線管理事件
+
+	eventHandlers map[string]func(ClientEvent)
+	handlersMutex sync.RWMutex
 }
 
 // NewClientManager 創建新的客戶端管理器
-func NewClientManager(cache providers.CacheProvider) *clientManager {
-	return &clientManager{
+func NewClientManager(cache providers.CacheProvider, redisClient *redis.Client) *clientManager {
+	cm := &clientManager{
 		clients:         make(map[*Client]bool, 1000),
 		clientsByUserID: make(map[string]*Client, 1000),
 		cache:           cache,
+		redisClient:     redisClient,
+		eventHandlers:   make(map[string]func(ClientEvent)),
 	}
+	cm.OnClientEvent(clientEventDisconnectSession, func(event ClientEvent) {
+		cm.disconnectLocalSession(event)
+	})
+	return cm
 }
 
 // NewClient 創建新的客戶端
@@ -72,6 +93,19 @@ func (cm *clientManager) GetClient(userID string) (*Client, bool) {
 	return client, exists
 }
 
+// GetUserClients 獲取用戶在本實例的所有連線（同一用戶可能有多個裝置或分頁）
+func (cm *clientManager) GetUserClients(userID string) []*Client {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+	var result []*Client
+	for client := range cm.clients {
+		if client.UserID == userID {
+			result = append(result, client)
+		}
+	}
+	return result
+}
+
 // GetAllClients 獲取所有客戶端
 func (cm *clientManager) GetAllClients() map[*Client]bool {
 	cm.mutex.RLock()
@@ -88,6 +122,11 @@ func (cm *clientManager) Unregister(client *Client) {
 	cm.mutex.Lock()
 	defer cm.mutex.Unlock()
 
+	// 連線可能已被主動斷開（例如工作階段撤銷），避免讀取協程結束時重複註銷
+	if _, exists := cm.clients[client]; !exists {
+		return
+	}
+
 	// 標記為非活躍並取消所有相關協程
 	client.IsActive = false
 	if client.Cancel != nil {
@@ -98,7 +137,10 @@ func (cm *clientManager) Unregister(client *Client) {
 	// 依賴 clientWritePump 監聽 Cancel() 訊號後自然退出
 
 	delete(cm.clients, client)
-	delete(cm.clientsByUserID, client.UserID)
+	// 同一用戶可能已有較新的連線，只移除指向此客戶端的索引
+	if cm.clientsByUserID[client.UserID] == client {
+		delete(cm.clientsByUserID, client.UserID)
+	}
 
 	WsActiveConnections.Dec()
 	// 關閉 WebSocket 連線 (必須由 Hub 負責清理)
@@ -111,6 +153,40 @@ func (cm *clientManager) Unregister(client *Client) {
 	slog.Info("客戶端已註銷", "user_id", client.UserID, "total_connections", len(cm.clients))
 }
 
+// DisconnectSession 通知所有實例斷開指定登入工作階段的 WebSocket 連線
+func (cm *clientManager) DisconnectSession(userID string, sessionID string) {
+	if sessionID == "" {
+		return
+	}
+	cm.PublishClientEvent(ClientEvent{
+		Action:    clientEventDisconnectSession,
+		UserID:    userID,
+		SessionID: sessionID,
+	})
+}
+
+// disconnectLocalSession 斷開指定登入工作階段在本實例的 WebSocket 連線，返回斷開的連線數
+func (cm *clientManager) disconnectLocalSession(event ClientEvent) int {
+	if event.SessionID == "" {
+		return 0
+	}
+
+	cm.mutex.RLock()
+	var sessionClients []*Client
+	for client := range cm.clients {
+		if client.UserID == event.UserID && client.SessionID == event.SessionID {
+			sessionClients = append(sessionClients, client)
+		}
+	}
+	cm.mutex.RUnlock()
+
+	for _, client := range sessionClients {
+		slog.Info("工作階段已撤銷，斷開 WebSocket 連線", "user_id", event.UserID, "session_id", event.SessionID)
+		cm.Unregister(client)
+	}
+	return len(sessionClients)
+}
+
 // CheckClientsHealth 檢查所有客戶端的健康狀態
 func (cm *clientManager) CheckClientsHealth() {
 	cm.mutex.RLock()
@@ -158,3 +234,74 @@ func (cm *clientManager) IsUserOnline(userID string) bool {
 	}
 	return false
 }
+
+// OnClientEvent 註冊連線管理事件的處理函式，每個實例收到事件後對本機連線執行
+func (cm *clientManager) OnClientEvent(action string, handler func(ClientEvent)) {
+	cm.handlersMutex.Lock()
+	defer cm.handlersMutex.Unlock()
+	cm.eventHandlers[action] = handler
+}
+
+// PublishClientEvent 透過 Redis 將連線管理事件廣播給所有實例（包含本實例）
+func (cm *clientManager) PublishClientEvent(event ClientEvent) {
+	if cm.redisClient == nil {
+		cm.dispatchClientEvent(event)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		slog.Error("序列化連線管理事件失敗", "action", event.Action, "error", err)
+		return
+	}
+	if err := cm.redisClient.Publish(context.Background(), clientEventsChannel, payload).Err(); err != nil {
+		slog.Error("Redis Publish 連線管理事件失敗", "action", event.Action, "error", err)
+		// 如果 Redis 失敗，至少處理本實例的連線
+		cm.dispatchClientEvent(event)
+	}
+}
+
+// StartEventSubscriber 訂閱跨實例連線管理事件，直到 ctx 結束
+func (cm *clientManager) StartEventSubscriber(ctx context.Context) {
+	if cm.redisClient == nil {
+		slog.Warn("Redis 未配置，跳過連線管理事件訂閱")
+		return
+	}
+
+	pubsub := cm.redisClient.Subscribe(ctx, clientEventsChannel)
+	defer func() {
+		if err := pubsub.Close(); err != nil {
+			slog.Warn("無法關閉 Redis PubSub 訂閱", "error", err)
+		}
+	}()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var event ClientEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				slog.Error("解析連線管理事件失敗", "error", err)
+				continue
+			}
+			cm.dispatchClientEvent(event)
+		}
+	}
+}
+
+// dispatchClientEvent 將事件交給對應的處理函式
+func (cm *clientManager) dispatchClientEvent(event ClientEvent) {
+	cm.handlersMutex.RLock()
+	handler, exists := cm.eventHandlers[event.Action]
+	cm.handlersMutex.RUnlock()
+	if !exists {
+		slog.Debug("未註冊的連線管理事件", "action", event.Action)
+		return
+	}
+	handler(event)
+}
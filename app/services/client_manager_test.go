@@ -2,9 +2,11 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
+	"github.com/go-redis/redismock/v9"
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -22,7 +24,7 @@ func (m *mockWebSocketConn) Close() error {
 }
 
 func TestNewClientManager(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 
 	assert.NotNil(t, cm)
 	assert.NotNil(t, cm.clients)
@@ -32,7 +34,7 @@ func TestNewClientManager(t *testing.T) {
 }
 
 func TestNewClient(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 	userID := primitive.NewObjectID().Hex()
 	mockConn := &mockWebSocketConn{}
 
@@ -50,7 +52,7 @@ func TestNewClient(t *testing.T) {
 }
 
 func TestRegisterClient(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 	userID := primitive.NewObjectID().Hex()
 	mockConn := &mockWebSocketConn{}
 	client := cm.NewClient(userID, mockConn.Conn)
@@ -70,7 +72,7 @@ func TestRegisterClient(t *testing.T) {
 }
 
 func TestUnregisterClient(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 	userID := primitive.NewObjectID().Hex()
 	mockConn := &mockWebSocketConn{}
 	client := cm.NewClient(userID, mockConn.Conn)
@@ -97,8 +99,44 @@ func TestUnregisterClient(t *testing.T) {
 	assert.False(t, client.IsActive)
 }
 
+func TestDisconnectSession(t *testing.T) {
+	cm := NewClientManager(nil, nil)
+	userID := primitive.NewObjectID().Hex()
+	otherUserID := primitive.NewObjectID().Hex()
+	mockConn := &mockWebSocketConn{}
+
+	revoked := cm.NewClient(userID, mockConn.Conn)
+	revoked.SessionID = "session_revoked"
+	kept := cm.NewClient(userID, mockConn.Conn)
+	kept.SessionID = "session_kept"
+	otherUser := cm.NewClient(otherUserID, mockConn.Conn)
+	otherUser.SessionID = "session_revoked"
+
+	cm.Register(revoked)
+	cm.Register(kept)
+	cm.Register(otherUser)
+
+	// 只斷開該用戶指定工作階段的連線
+	cm.DisconnectSession(userID, "session_revoked")
+	assert.False(t, revoked.IsActive)
+	assert.True(t, kept.IsActive)
+	assert.True(t, otherUser.IsActive)
+
+	// 較新的連線仍為該用戶的主要連線
+	retrievedClient, exists := cm.GetClient(userID)
+	assert.True(t, exists)
+	assert.Equal(t, kept, retrievedClient)
+	assert.Equal(t, 2, len(cm.GetAllClients()))
+
+	// 重複斷開不應有任何影響
+	cm.DisconnectSession(userID, "session_revoked")
+	cm.DisconnectSession(userID, "")
+	cm.Unregister(revoked)
+	assert.Equal(t, 2, len(cm.GetAllClients()))
+}
+
 func TestGetClient(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 	userID1 := primitive.NewObjectID().Hex()
 	userID2 := primitive.NewObjectID().Hex()
 	mockConn := &mockWebSocketConn{}
@@ -119,7 +157,7 @@ func TestGetClient(t *testing.T) {
 }
 
 func TestGetAllClients(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 	mockConn := &mockWebSocketConn{}
 
 	userID1 := primitive.NewObjectID().Hex()
@@ -144,7 +182,7 @@ func TestGetAllClients(t *testing.T) {
 }
 
 func TestCheckClientsHealth(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 	mockConn := &mockWebSocketConn{}
 
 	healthyUserID := primitive.NewObjectID().Hex()
@@ -175,7 +213,7 @@ func TestCheckClientsHealth(t *testing.T) {
 }
 
 func TestIsUserOnline(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 	mockConn := &mockWebSocketConn{}
 
 	onlineUserID := primitive.NewObjectID().Hex()
@@ -196,7 +234,7 @@ func TestIsUserOnline(t *testing.T) {
 }
 
 func TestStartHealthChecker(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 	mockConn := &mockWebSocketConn{}
 
 	unhealthyUserID := primitive.NewObjectID().Hex()
@@ -228,8 +266,39 @@ func TestStartHealthChecker(t *testing.T) {
 	assert.False(t, exists)
 }
 
+func TestDisconnectSession_PublishesToAllInstances(t *testing.T) {
+	redisClient, redisMock := redismock.NewClientMock()
+	cm := NewClientManager(nil, redisClient)
+	userID := primitive.NewObjectID().Hex()
+
+	client := cm.NewClient(userID, nil)
+	client.SessionID = "session_revoked"
+	cm.Register(client)
+
+	payload, _ := json.Marshal(ClientEvent{
+		Action:    clientEventDisconnectSession,
+		UserID:    userID,
+		SessionID: "session_revoked",
+	})
+	redisMock.ExpectPublish(clientEventsChannel, payload).SetVal(1)
+
+	// 已配置 Redis 時由訂閱端處理，發布本身不直接斷開連線
+	cm.DisconnectSession(userID, "session_revoked")
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+	assert.True(t, client.IsActive)
+
+	// 模擬從 Redis 收到其他實例發布的事件
+	cm.dispatchClientEvent(ClientEvent{
+		Action:    clientEventDisconnectSession,
+		UserID:    userID,
+		SessionID: "session_revoked",
+	})
+	assert.False(t, client.IsActive)
+	assert.Empty(t, cm.GetAllClients())
+}
+
 func TestMultipleClientsRegistrationAndUnregistration(t *testing.T) {
-	cm := NewClientManager(nil)
+	cm := NewClientManager(nil, nil)
 	mockConn := &mockWebSocketConn{}
 
 	// 創建多個客戶端
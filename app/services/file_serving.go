@@ -0,0 +1,101 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ResolveServedFile 依儲存路徑（原檔或縮圖）找出引用該內容的已驗證檔案，返回傳送內容所需的資訊
+// 相同內容的檔案共用儲存路徑，任一記錄為公開資源即可公開讀取，存取權限由呼叫端依 OwnerIDs 與 FileIDs 檢查
+func (fs *fileUploadService) ResolveServedFile(filePath string) (*models.ServedFile, *models.MessageOptions) {
+	// 以根目錄為基準清理路徑，避免 ".." 跳出上傳目錄
+	cleanPath := strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	if cleanPath == "" {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "檔案路徑不能為空",
+		}
+	}
+
+	files, err := fs.fileRepo.GetVerifiedFilesByStoragePath(cleanPath)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "查詢檔案失敗",
+			Details: err.Error(),
+		}
+	}
+	// 已過期或待清理的記錄（訊息已刪除、頭像已更換等）不再提供下載
+	files = slices.DeleteFunc(files, func(file models.UploadedFile) bool {
+		return file.Status != "verified" || file.ExpiresAt != nil
+	})
+	if len(files) == 0 {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "檔案不存在",
+		}
+	}
+
+	served := &models.ServedFile{FilePath: cleanPath}
+	for i := range files {
+		file := &files[i]
+		served.FileIDs = append(served.FileIDs, file.ID.Hex())
+		if !slices.Contains(served.OwnerIDs, file.UserID.Hex()) {
+			served.OwnerIDs = append(served.OwnerIDs, file.UserID.Hex())
+		}
+		if slices.Contains(models.PublicFileTypes, file.FileType) {
+			served.Public = true
+		}
+
+		// 以最早上傳的記錄作為檔名與修改時間的來源
+		if served.MimeType != "" && !file.CreatedAt.Before(served.ModTime) {
+			continue
+		}
+		served.FileName = file.OriginalName
+		served.ModTime = file.CreatedAt
+		if file.FilePath == cleanPath {
+			served.MimeType = file.MimeType
+			served.FileSize = file.FileSize
+			served.ETag = contentETag(file.Hash, "")
+			continue
+		}
+		for _, variant := range file.Variants {
+			if variant.FilePath == cleanPath {
+				served.MimeType = variant.MimeType
+				served.FileSize = variant.FileSize
+				served.ETag = contentETag(file.Hash, variant.Size)
+				break
+			}
+		}
+	}
+
+	return served, nil
+}
+
+// OpenFileContent 開啟檔案內容，呼叫端負責關閉
+func (fs *fileUploadService) OpenFileContent(filePath string) (io.ReadCloser, *models.MessageOptions) {
+	content, err := fs.fileProvider.GetFile(filePath)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "檔案不存在或已被刪除",
+			Details: err.Error(),
+		}
+	}
+	return content, nil
+}
+
+// contentETag 以內容雜湊產生強 ETag，縮圖加上尺寸名稱區分
+func contentETag(hash string, size string) string {
+	if hash == "" {
+		return ""
+	}
+	if size != "" {
+		return fmt.Sprintf(`"%s-%s"`, hash, size)
+	}
+	return fmt.Sprintf(`"%s"`, hash)
+}
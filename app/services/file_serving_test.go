@@ -0,0 +1,137 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolveServedFile(t *testing.T) {
+	uploadedAt := time.Unix(1700000000, 0)
+	newFile := func(fileType string, originalName string, createdAt time.Time) models.UploadedFile {
+		return models.UploadedFile{
+			BaseModel:    providers.BaseModel{ID: primitive.NewObjectID(), CreatedAt: createdAt},
+			UserID:       primitive.NewObjectID(),
+			OriginalName: originalName,
+			FilePath:     "image/123_abc.png",
+			FileSize:     4096,
+			MimeType:     "image/png",
+			FileType:     fileType,
+			Status:       "verified",
+			Hash:         "abc123",
+			Variants: []models.FileVariant{
+				{Size: "small", FilePath: "image/123_abc_small.png", FileSize: 512, MimeType: "image/png"},
+			},
+		}
+	}
+
+	t.Run("共用內容的檔案合併上傳者與公開狀態", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileRepo: mockFileRepo}
+
+		later := newFile("avatar", "avatar.png", uploadedAt.Add(time.Hour))
+		first := newFile("image", "photo.png", uploadedAt)
+		mockFileRepo.On("GetVerifiedFilesByStoragePath", "image/123_abc.png").Return([]models.UploadedFile{later, first}, nil).Once()
+
+		served, msgOpt := service.ResolveServedFile("/image/123_abc.png")
+
+		assert.Nil(t, msgOpt)
+		assert.True(t, served.Public)
+		assert.Equal(t, "photo.png", served.FileName, "使用最早上傳的檔名")
+		assert.Equal(t, uploadedAt, served.ModTime)
+		assert.Equal(t, `"abc123"`, served.ETag)
+		assert.Equal(t, int64(4096), served.FileSize)
+		assert.ElementsMatch(t, []string{later.UserID.Hex(), first.UserID.Hex()}, served.OwnerIDs)
+		assert.ElementsMatch(t, []string{later.ID.Hex(), first.ID.Hex()}, served.FileIDs)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("縮圖使用縮圖的大小與 ETag", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileRepo: mockFileRepo}
+
+		file := newFile("image", "photo.png", uploadedAt)
+		mockFileRepo.On("GetVerifiedFilesByStoragePath", "image/123_abc_small.png").Return([]models.UploadedFile{file}, nil).Once()
+
+		served, msgOpt := service.ResolveServedFile("/image/123_abc_small.png")
+
+		assert.Nil(t, msgOpt)
+		assert.False(t, served.Public)
+		assert.Equal(t, int64(512), served.FileSize)
+		assert.Equal(t, `"abc123-small"`, served.ETag)
+	})
+
+	t.Run("略過已過期或待清理的記錄", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileRepo: mockFileRepo}
+
+		expiresAt := uploadedAt.Add(time.Hour)
+		pending := newFile("avatar", "avatar.png", uploadedAt)
+		pending.ExpiresAt = &expiresAt
+		kept := newFile("image", "photo.png", uploadedAt.Add(time.Minute))
+		mockFileRepo.On("GetVerifiedFilesByStoragePath", "image/123_abc.png").Return([]models.UploadedFile{pending, kept}, nil).Once()
+
+		served, msgOpt := service.ResolveServedFile("/image/123_abc.png")
+
+		assert.Nil(t, msgOpt)
+		assert.False(t, served.Public, "待清理的頭像不應讓內容公開")
+		assert.Equal(t, "photo.png", served.FileName)
+		assert.Equal(t, []string{kept.ID.Hex()}, served.FileIDs)
+		assert.Equal(t, []string{kept.UserID.Hex()}, served.OwnerIDs)
+	})
+
+	t.Run("所有記錄皆待清理", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileRepo: mockFileRepo}
+
+		expiresAt := uploadedAt
+		pending := newFile("image", "photo.png", uploadedAt)
+		pending.ExpiresAt = &expiresAt
+		mockFileRepo.On("GetVerifiedFilesByStoragePath", "image/123_abc.png").Return([]models.UploadedFile{pending}, nil).Once()
+
+		served, msgOpt := service.ResolveServedFile("/image/123_abc.png")
+
+		assert.Nil(t, served)
+		assert.Equal(t, models.ErrNotFound, msgOpt.Code)
+	})
+
+	t.Run("清理跳出上傳目錄的路徑", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileRepo: mockFileRepo}
+
+		mockFileRepo.On("GetVerifiedFilesByStoragePath", "etc/passwd").Return([]models.UploadedFile{}, nil).Once()
+
+		served, msgOpt := service.ResolveServedFile("/../../etc/passwd")
+
+		assert.Nil(t, served)
+		assert.Equal(t, models.ErrNotFound, msgOpt.Code)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("空路徑", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileRepo: mockFileRepo}
+
+		_, msgOpt := service.ResolveServedFile("/")
+
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+		mockFileRepo.AssertNotCalled(t, "GetVerifiedFilesByStoragePath", mock.Anything)
+	})
+
+	t.Run("查詢失敗", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileRepo: mockFileRepo}
+
+		mockFileRepo.On("GetVerifiedFilesByStoragePath", "image/123_abc.png").Return(nil, errors.New("db error")).Once()
+
+		_, msgOpt := service.ResolveServedFile("/image/123_abc.png")
+
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+	})
+}
@@ -1,11 +1,14 @@
 package services
 
 import (
+	"bytes"
 	"chat_app_backend/app/models"
 	"chat_app_backend/app/providers"
 	"chat_app_backend/app/repositories"
 	"chat_app_backend/config"
+	"context"
 	"fmt"
+	"image"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -20,56 +23,53 @@ import (
 
 // fileUploadService 檔案上傳服務實現
 type fileUploadService struct {
-	config       *config.Config
-	fileProvider providers.FileProvider
-	odm          providers.ODM
-	fileRepo     repositories.FileRepository
+	config            *config.Config
+	fileProvider      providers.FileProvider
+	odm               providers.ODM
+	fileRepo          repositories.FileRepository
+	uploadSessionRepo repositories.UploadSessionRepository
+	serverMemberRepo  repositories.ServerMemberRepository
+	scanner           providers.MalwareScanner
 }
 
 // NewFileUploadService 創建新的檔案上傳服務
-func NewFileUploadService(cfg *config.Config, fileProvider providers.FileProvider, odm providers.ODM, fileRepo repositories.FileRepository) *fileUploadService {
+func NewFileUploadService(cfg *config.Config, fileProvider providers.FileProvider, odm providers.ODM, fileRepo repositories.FileRepository, uploadSessionRepo repositories.UploadSessionRepository, serverMemberRepo repositories.ServerMemberRepository, scanner providers.MalwareScanner) *fileUploadService {
 	return &fileUploadService{
-		config:       cfg,
-		fileProvider: fileProvider,
-		odm:          odm,
-		fileRepo:     fileRepo,
+		config:            cfg,
+		fileProvider:      fileProvider,
+		odm:               odm,
+		fileRepo:          fileRepo,
+		uploadSessionRepo: uploadSessionRepo,
+		serverMemberRepo:  serverMemberRepo,
+		scanner:           scanner,
 	}
 }
 
 // UploadFileWithConfig 統一檔案上傳函數，使用配置參數
 func (fs *fileUploadService) UploadFileWithConfig(file multipart.File, header *multipart.FileHeader, userID string, config *models.FileUploadConfig) (*models.FileResult, *models.MessageOptions) {
+	return fs.UploadServerFile(file, header, userID, "", config)
+}
+
+// UploadServerFile 上傳檔案並同時計入伺服器的儲存配額（例如伺服器頻道的附件），serverID 為空時只計入上傳者
+func (fs *fileUploadService) UploadServerFile(file multipart.File, header *multipart.FileHeader, userID string, serverID string, config *models.FileUploadConfig) (*models.FileResult, *models.MessageOptions) {
 	// 基本驗證
 	if msgOpt := fs.ValidateFile(header); msgOpt != nil {
 		return nil, msgOpt
 	}
 
-	// 檔案大小檢查
-	if header.Size > config.MaxFileSize {
-		return nil, &models.MessageOptions{
-			Code:    models.ErrInvalidParams,
-			Message: "檔案大小超過限制",
-			Details: fmt.Sprintf("檔案大小: %d bytes, 限制: %d bytes", header.Size, config.MaxFileSize),
-		}
+	// 檔案大小、副檔名與MIME類型檢查
+	mimeType := header.Header.Get("Content-Type")
+	if msgOpt := checkUploadConfig(header.Filename, header.Size, mimeType, config); msgOpt != nil {
+		return nil, msgOpt
 	}
 
-	// 檢查副檔名
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if !slices.Contains(config.AllowedExtensions, ext) {
-		return nil, &models.MessageOptions{
-			Code:    models.ErrInvalidParams,
-			Message: "不支援的檔案格式",
-			Details: fmt.Sprintf("檔案格式: %s", ext),
-		}
+	// 儲存配額檢查（以邏輯檔案計算，內容重複的檔案同樣計入）
+	serverObjID, msgOpt := fs.resolveUploadServer(userID, serverID)
+	if msgOpt != nil {
+		return nil, msgOpt
 	}
-
-	// 檢查MIME類型
-	mimeType := header.Header.Get("Content-Type")
-	if !slices.Contains(config.AllowedMimeTypes, mimeType) {
-		return nil, &models.MessageOptions{
-			Code:    models.ErrInvalidParams,
-			Message: "不支援的檔案類型",
-			Details: fmt.Sprintf("檔案類型: %s", mimeType),
-		}
+	if msgOpt := fs.checkStorageQuota(userID, serverObjID, header.Size); msgOpt != nil {
+		return nil, msgOpt
 	}
 
 	// 內容安全檢查
@@ -77,28 +77,9 @@ func (fs *fileUploadService) UploadFileWithConfig(file multipart.File, header *m
 		return nil, msgOpt
 	}
 
-	// 生成安全的檔案名稱
-	secureFileName := providers.GenerateSecureFileName(header.Filename, userID)
-
-	relativePath := filepath.Join(config.FileType, secureFileName)
-
-	// 儲存檔案
-	fullPath, err := fs.fileProvider.SaveFile(file, relativePath)
-	if err != nil {
-		return nil, &models.MessageOptions{
-			Code:    models.ErrInternalServer,
-			Message: "檔案儲存失敗",
-			Details: err.Error(),
-		}
-	}
-
-	// 生成檔案雜湊
+	// 生成檔案雜湊，相同內容的上傳共用同一個儲存物件
 	fileHash, err := providers.GenerateFileHash(file)
 	if err != nil {
-		// 清理已儲存的檔案
-		if cleanupErr := fs.fileProvider.DeleteFile(fullPath); cleanupErr != nil {
-			slog.Warn("無法在雜湊計算失敗後清理檔案", "path", fullPath, "error", cleanupErr)
-		}
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
 			Message: "檔案雜湊計算失敗",
@@ -106,17 +87,18 @@ func (fs *fileUploadService) UploadFileWithConfig(file multipart.File, header *m
 		}
 	}
 
-	// 惡意軟體掃描（如果配置要求）
-	if config.ScanMalware {
-		if msgOpt := fs.ScanFileForMalware(fullPath); msgOpt != nil {
-			if err := fs.fileProvider.DeleteFile(fullPath); err != nil {
-				slog.Warn("無法在掃描到惡意軟體後清理檔案", "path", fullPath, "error", err)
-			}
-			return nil, msgOpt
-		}
+	// 生成安全的檔案名稱
+	secureFileName := providers.GenerateSecureFileName(header.Filename, userID)
+
+	relativePath := filepath.Join(config.FileType, secureFileName)
+
+	// 儲存檔案（內容已存在時只增加引用數）
+	blob, msgOpt := fs.storeFileBlob(file, relativePath, fileHash, header.Size, mimeType)
+	if msgOpt != nil {
+		return nil, msgOpt
 	}
+	fullPath := blob.FilePath
 
-	// 創建資料庫記錄
 	userObjID, _ := primitive.ObjectIDFromHex(userID)
 	uploadedFile := &models.UploadedFile{
 		UserID:       userObjID,
@@ -126,12 +108,17 @@ func (fs *fileUploadService) UploadFileWithConfig(file multipart.File, header *m
 		FileSize:     header.Size,
 		MimeType:     mimeType,
 		FileType:     config.FileType,
-		Status:       "verified",
 		Hash:         fileHash,
+		ServerID:     serverObjID,
 	}
 
+	// 惡意軟體掃描與縮圖在背景處理，完成前檔案維持 processing 不會被提供
+	plan := fs.planFileProcessing(uploadedFile, config, blob.RefCount > 1)
+	status := uploadedFile.Status
+
+	// 創建資料庫記錄
 	if err := fs.fileRepo.CreateFile(uploadedFile); err != nil {
-		if cleanupErr := fs.fileProvider.DeleteFile(fullPath); cleanupErr != nil {
+		if cleanupErr := fs.releaseStoredFile(uploadedFile); cleanupErr != nil {
 			slog.Warn("無法在資料庫寫入失敗後清理檔案", "path", fullPath, "error", cleanupErr)
 		}
 		return nil, &models.MessageOptions{
@@ -141,18 +128,368 @@ func (fs *fileUploadService) UploadFileWithConfig(file multipart.File, header *m
 		}
 	}
 
+	if status == "processing" {
+		go fs.processUploadedFile(*uploadedFile, plan)
+	}
+
 	return &models.FileResult{
 		ID:         uploadedFile.GetID(),
 		FileName:   secureFileName,
 		FilePath:   fullPath,
-		FileURL:    fs.fileProvider.GetFileURL(fullPath),
+		FileURL:    fs.verifiedFileURL(uploadedFile),
 		FileSize:   header.Size,
 		MimeType:   mimeType,
 		UploadedAt: time.Now().UnixMilli(),
 		UserID:     userID,
+		Status:     status,
 	}, nil
 }
 
+// storeFileBlob 以內容雜湊儲存檔案並增加引用數
+// 相同內容已儲存時直接沿用既有的儲存物件，否則儲存到 relativePath
+func (fs *fileUploadService) storeFileBlob(file multipart.File, relativePath string, hash string, fileSize int64, mimeType string) (*models.FileBlob, *models.MessageOptions) {
+	blob, err := fs.fileRepo.AcquireBlob(hash)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "檔案儲存失敗",
+			Details: err.Error(),
+		}
+	}
+	if blob != nil {
+		return blob, nil
+	}
+
+	fullPath, err := fs.fileProvider.SaveFile(file, relativePath)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "檔案儲存失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return fs.registerFileBlob(&models.FileBlob{
+		Hash:     hash,
+		FilePath: fullPath,
+		FileSize: fileSize,
+		MimeType: mimeType,
+	})
+}
+
+// registerFileBlob 記錄剛儲存的檔案內容
+// 並發上傳相同內容時以先記錄者為準，刪除自己多儲存的一份
+func (fs *fileUploadService) registerFileBlob(stored *models.FileBlob) (*models.FileBlob, *models.MessageOptions) {
+	blob, err := fs.fileRepo.RegisterBlob(stored)
+	if err != nil {
+		if cleanupErr := fs.fileProvider.DeleteFile(stored.FilePath); cleanupErr != nil {
+			slog.Warn("無法在記錄檔案內容失敗後清理檔案", "path", stored.FilePath, "error", cleanupErr)
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "檔案儲存失敗",
+			Details: err.Error(),
+		}
+	}
+
+	if blob.FilePath != stored.FilePath {
+		if err := fs.fileProvider.DeleteFile(stored.FilePath); err != nil {
+			slog.Warn("無法刪除重複儲存的檔案", "path", stored.FilePath, "error", err)
+		}
+	}
+
+	return blob, nil
+}
+
+// fileProcessing 上傳後需在背景進行的處理
+type fileProcessing struct {
+	scan     bool // 惡意軟體掃描
+	variants bool // 產生縮圖
+}
+
+// planFileProcessing 決定檔案的背景處理項目並設定初始狀態，需要背景處理時為 processing
+// shared 表示內容已被其他檔案引用，相同內容已有驗證完成的檔案時沿用其掃描與縮圖結果
+func (fs *fileUploadService) planFileProcessing(file *models.UploadedFile, config *models.FileUploadConfig, shared bool) fileProcessing {
+	plan := fileProcessing{
+		scan:     config.ScanMalware,
+		variants: config.GenerateVariants && canGenerateVariants(file.MimeType),
+	}
+
+	if shared && (plan.scan || plan.variants) {
+		processed, err := fs.fileRepo.GetVerifiedFileByHash(file.Hash)
+		if err == nil && processed.FilePath == file.FilePath {
+			// 已驗證的內容已通過掃描
+			plan.scan = false
+			if plan.variants && processed.Width > 0 {
+				file.Width = processed.Width
+				file.Height = processed.Height
+				file.Variants = processed.Variants
+				plan.variants = false
+			}
+		}
+	}
+
+	file.Status = "verified"
+	if plan.scan || plan.variants {
+		file.Status = "processing"
+	}
+	return plan
+}
+
+// processUploadedFile 背景掃描並產生縮圖，全部完成後才將檔案標記為 verified
+// 掃描發現惡意內容或無法完成掃描時隔離檔案（標記為 failed），之後不會被提供
+func (fs *fileUploadService) processUploadedFile(file models.UploadedFile, plan fileProcessing) {
+	if plan.scan {
+		if msgOpt := fs.ScanFileForMalware(file.FilePath); msgOpt != nil {
+			fs.quarantineFile(file, msgOpt)
+			return
+		}
+	}
+
+	if plan.variants {
+		fs.processImageVariants(file)
+		return
+	}
+
+	if err := fs.fileRepo.UpdateFileStatus(file.ID.Hex(), "verified"); err != nil {
+		slog.Error("更新檔案狀態失敗", "file_id", file.ID.Hex(), "error", err)
+	}
+}
+
+// quarantineFile 隔離未通過掃描的檔案，保留實際檔案以供管理員檢查
+func (fs *fileUploadService) quarantineFile(file models.UploadedFile, msgOpt *models.MessageOptions) {
+	scanResult := msgOpt.Message
+	if msgOpt.Details != nil {
+		scanResult = fmt.Sprintf("%s: %v", msgOpt.Message, msgOpt.Details)
+	}
+	slog.Warn("檔案未通過惡意軟體掃描，已隔離", "file_id", file.ID.Hex(), "path", file.FilePath, "result", scanResult)
+
+	updates := map[string]any{
+		"status":      "failed",
+		"scan_result": scanResult,
+	}
+	if err := fs.fileRepo.UpdateFile(file.ID.Hex(), updates); err != nil {
+		slog.Error("更新檔案隔離狀態失敗", "file_id", file.ID.Hex(), "error", err)
+	}
+}
+
+// verifiedFileURL 只有已驗證的檔案才返回連結
+func (fs *fileUploadService) verifiedFileURL(file *models.UploadedFile) string {
+	if file.Status != "verified" {
+		return ""
+	}
+	fileURL, msgOpt := fs.fileLinkURL(file, file.FilePath)
+	if msgOpt != nil {
+		slog.Warn("無法產生檔案連結", "file_id", file.ID.Hex(), "error", msgOpt.Details)
+	}
+	return fileURL
+}
+
+// malwareScanner 返回設定的掃描引擎，未設定時使用啟發式掃描
+func (fs *fileUploadService) malwareScanner() providers.MalwareScanner {
+	if fs.scanner == nil {
+		return providers.NewHeuristicScanner()
+	}
+	return fs.scanner
+}
+
+// releaseStoredFile 減少檔案內容的引用數，最後一個引用移除時才刪除實際檔案與縮圖
+// 去重前上傳的檔案沒有內容記錄，直接刪除
+func (fs *fileUploadService) releaseStoredFile(file *models.UploadedFile) error {
+	if file.Hash == "" {
+		return fs.deleteStoredFile(file)
+	}
+
+	blob, err := fs.fileRepo.ReleaseBlob(file.Hash, file.FilePath)
+	if err != nil {
+		return err
+	}
+	if blob == nil {
+		return fs.deleteStoredFile(file)
+	}
+	if blob.RefCount > 0 {
+		return nil
+	}
+
+	deleted, err := fs.fileRepo.DeleteBlobIfUnreferenced(file.Hash)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		// 刪除前又被新的上傳引用
+		return nil
+	}
+
+	return fs.deleteStoredFile(file)
+}
+
+// deleteStoredFile 刪除實際檔案與縮圖
+func (fs *fileUploadService) deleteStoredFile(file *models.UploadedFile) error {
+	if err := fs.fileProvider.DeleteFile(file.FilePath); err != nil {
+		return err
+	}
+	fs.deleteFileVariants(file)
+	return nil
+}
+
+// deleteFileRecord 刪除檔案記錄並釋放其內容
+func (fs *fileUploadService) deleteFileRecord(file *models.UploadedFile) *models.MessageOptions {
+	if err := fs.fileRepo.DeleteFileByID(file.ID.Hex()); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "刪除檔案記錄失敗",
+			Details: err.Error(),
+		}
+	}
+
+	if err := fs.releaseStoredFile(file); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "刪除檔案失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// checkUploadConfig 依上傳配置檢查檔案大小、副檔名與MIME類型
+func checkUploadConfig(fileName string, fileSize int64, mimeType string, config *models.FileUploadConfig) *models.MessageOptions {
+	if fileSize > config.MaxFileSize {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "檔案大小超過限制",
+			Details: fmt.Sprintf("檔案大小: %d bytes, 限制: %d bytes", fileSize, config.MaxFileSize),
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if !slices.Contains(config.AllowedExtensions, ext) {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "不支援的檔案格式",
+			Details: fmt.Sprintf("檔案格式: %s", ext),
+		}
+	}
+
+	if !slices.Contains(config.AllowedMimeTypes, mimeType) {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "不支援的檔案類型",
+			Details: fmt.Sprintf("檔案類型: %s", mimeType),
+		}
+	}
+
+	return nil
+}
+
+// processImageVariants 背景解析圖片尺寸並產生縮圖，完成後將狀態改為 verified
+// 圖片無法解析時視為損壞並標記為 failed；單一縮圖儲存失敗只略過該尺寸
+func (fs *fileUploadService) processImageVariants(file models.UploadedFile) {
+	updates, err := fs.generateImageVariants(&file)
+	updates["status"] = "verified"
+	if err != nil {
+		slog.Warn("圖片處理失敗", "file_id", file.ID.Hex(), "error", err)
+		updates["status"] = "failed"
+	}
+
+	if err := fs.fileRepo.UpdateFile(file.ID.Hex(), updates); err != nil {
+		slog.Error("更新圖片處理結果失敗", "file_id", file.ID.Hex(), "error", err)
+	}
+}
+
+// generateImageVariants 解析圖片尺寸並產生比原圖小的標準縮圖，返回要更新的檔案欄位
+func (fs *fileUploadService) generateImageVariants(file *models.UploadedFile) (map[string]any, error) {
+	updates := make(map[string]any)
+
+	reader, err := fs.fileProvider.GetFile(file.FilePath)
+	if err != nil {
+		return updates, fmt.Errorf("無法開啟檔案: %w", err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			slog.Warn("無法關閉檔案 (generateImageVariants)", "path", file.FilePath, "error", err)
+		}
+	}()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return updates, fmt.Errorf("無法讀取檔案: %w", err)
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return updates, fmt.Errorf("無法解析圖片: %w", err)
+	}
+	updates["width"] = imageConfig.Width
+	updates["height"] = imageConfig.Height
+
+	if imageConfig.Width*imageConfig.Height > MaxImagePixels {
+		slog.Warn("圖片像素超過上限，略過縮圖", "file_id", file.ID.Hex(), "width", imageConfig.Width, "height", imageConfig.Height)
+		return updates, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return updates, fmt.Errorf("無法解碼圖片: %w", err)
+	}
+
+	// 由大到小產生，較小的尺寸以上一個縮圖為來源以減少運算量
+	variants := make([]models.FileVariant, 0, len(models.ImageVariantSizes))
+	source := img
+	for _, size := range slices.Backward(models.ImageVariantSizes) {
+		width, height, ok := variantDimensions(imageConfig.Width, imageConfig.Height, size.MaxEdge)
+		if !ok {
+			continue
+		}
+
+		resized := resizeImage(source, width, height)
+		source = resized
+
+		variant, err := fs.saveImageVariant(file, size.Name, resized)
+		if err != nil {
+			slog.Warn("儲存縮圖失敗", "file_id", file.ID.Hex(), "size", size.Name, "error", err)
+			continue
+		}
+		variants = append(variants, *variant)
+	}
+	slices.Reverse(variants)
+	updates["variants"] = variants
+
+	return updates, nil
+}
+
+// saveImageVariant 編碼並儲存單一尺寸的縮圖
+func (fs *fileUploadService) saveImageVariant(file *models.UploadedFile, size string, img image.Image) (*models.FileVariant, error) {
+	data, mimeType, ext, err := encodeVariant(img, file.MimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := fs.fileProvider.SaveReader(bytes.NewReader(data), int64(len(data)), variantFilePath(file.FilePath, size, ext), mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FileVariant{
+		Size:     size,
+		FilePath: path,
+		Width:    img.Bounds().Dx(),
+		Height:   img.Bounds().Dy(),
+		FileSize: int64(len(data)),
+		MimeType: mimeType,
+	}, nil
+}
+
+// deleteFileVariants 刪除檔案的所有縮圖，原始檔案已刪除，失敗只記錄日誌
+func (fs *fileUploadService) deleteFileVariants(file *models.UploadedFile) {
+	for _, variant := range file.Variants {
+		if err := fs.fileProvider.DeleteFile(variant.FilePath); err != nil {
+			slog.Warn("刪除縮圖失敗", "file_id", file.ID.Hex(), "path", variant.FilePath, "error", err)
+		}
+	}
+}
+
 // UploadFile 通用檔案上傳 - 使用統一函數
 func (fs *fileUploadService) UploadFile(file multipart.File, header *multipart.FileHeader, userID string) (*models.FileResult, *models.MessageOptions) {
 	config := models.GetGeneralUploadConfig()
@@ -354,9 +691,13 @@ func (fs *fileUploadService) CheckFileContent(file multipart.File, header *multi
 		}
 	}
 
+	return fs.checkContentHead(buffer[:n], header.Header.Get("Content-Type"))
+}
+
+// checkContentHead 以檔案開頭內容檢測真實類型並檢查惡意內容標誌
+func (fs *fileUploadService) checkContentHead(head []byte, declaredMimeType string) *models.MessageOptions {
 	// 檢測真實的MIME類型
-	detectedMimeType := http.DetectContentType(buffer[:n])
-	declaredMimeType := header.Header.Get("Content-Type")
+	detectedMimeType := http.DetectContentType(head)
 
 	// 驗證MIME類型一致性（允許一些變化）
 	if !fs.isMimeTypeCompatible(detectedMimeType, declaredMimeType) {
@@ -368,7 +709,7 @@ func (fs *fileUploadService) CheckFileContent(file multipart.File, header *multi
 	}
 
 	// 檢查是否包含惡意內容標誌
-	if fs.containsMaliciousContent(buffer[:n]) {
+	if fs.containsMaliciousContent(head) {
 		return &models.MessageOptions{
 			Code:    models.ErrInvalidParams,
 			Message: "檔案包含可疑內容",
@@ -404,67 +745,12 @@ func (fs *fileUploadService) isMimeTypeCompatible(detected, declared string) boo
 
 // containsMaliciousContent 檢查是否包含惡意內容
 func (fs *fileUploadService) containsMaliciousContent(content []byte) bool {
-	// 檢查常見的惡意軟體標誌
-	maliciousSignatures := [][]byte{
-		[]byte("MZ"),             // PE執行檔標誌
-		[]byte("<!DOCTYPE html"), // HTML檔案
-		[]byte("<script"),        // JavaScript
-		[]byte("<?php"),          // PHP腳本
-		[]byte("<%"),             // ASP/JSP
-		[]byte("#!/bin/sh"),      // Shell腳本
-		[]byte("#!/bin/bash"),    // Bash腳本
-		[]byte("\x7fELF"),        // ELF執行檔（Linux）
-		[]byte("PK\x03\x04"),     // ZIP檔案（可能包含惡意軟體）
-	}
-
-	contentStr := strings.ToLower(string(content))
-
-	// 檢查二進位標誌
-	for _, signature := range maliciousSignatures {
-		if len(content) >= len(signature) {
-			for i := 0; i <= len(content)-len(signature); i++ {
-				match := true
-				for j := 0; j < len(signature); j++ {
-					if content[i+j] != signature[j] {
-						match = false
-						break
-					}
-				}
-				if match {
-					return true
-				}
-			}
-		}
-	}
-
-	// 檢查可疑的文字內容
-	suspiciousStrings := []string{
-		"eval(",
-		"base64_decode",
-		"shell_exec",
-		"system(",
-		"exec(",
-		"passthru(",
-		"file_get_contents",
-		"fopen(",
-		"javascript:",
-		"vbscript:",
-	}
-
-	for _, suspicious := range suspiciousStrings {
-		if strings.Contains(contentStr, suspicious) {
-			return true
-		}
-	}
-
-	return false
+	_, found := providers.DetectMaliciousContent(content)
+	return found
 }
 
-// ScanFileForMalware 惡意軟體掃描（簡化實現）
+// ScanFileForMalware 以設定的掃描引擎（啟發式或 ClamAV）掃描已儲存的檔案
 func (fs *fileUploadService) ScanFileForMalware(filePath string) *models.MessageOptions {
-	// 這裡可以整合第三方防毒引擎，如 ClamAV
-	// 目前實現基本的檔案檢查
-
 	file, err := fs.fileProvider.GetFile(filePath)
 	if err != nil {
 		return &models.MessageOptions{
@@ -479,32 +765,31 @@ func (fs *fileUploadService) ScanFileForMalware(filePath string) *models.Message
 		}
 	}()
 
-	// 讀取檔案開頭進行基本檢查
-	buffer := make([]byte, 1024)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
+	result, err := fs.malwareScanner().Scan(context.Background(), file)
+	if err != nil {
 		return &models.MessageOptions{
 			Code:    models.ErrInternalServer,
-			Message: "無法讀取檔案進行掃描",
+			Message: "惡意軟體掃描失敗",
 			Details: err.Error(),
 		}
 	}
 
-	// 檢查是否包含惡意內容
-	if fs.containsMaliciousContent(buffer[:n]) {
+	if result.Infected {
 		return &models.MessageOptions{
 			Code:    models.ErrInvalidParams,
 			Message: "檔案掃描發現可疑內容",
+			Details: result.Signature,
 		}
 	}
 
 	return nil
 }
 
-// DeleteFile 刪除檔案
+// DeleteFile 根據路徑刪除檔案
+// 相同內容的檔案共用同一路徑時只會刪除其中一筆記錄，應優先使用 DeleteFileByID
 func (fs *fileUploadService) DeleteFile(filePath string) *models.MessageOptions {
-	// 從資料庫刪除記錄
-	if err := fs.fileRepo.DeleteFileByPath(filePath); err != nil {
+	file, err := fs.fileRepo.GetFileByPath(filePath)
+	if err != nil {
 		return &models.MessageOptions{
 			Code:    models.ErrInternalServer,
 			Message: "刪除檔案記錄失敗",
@@ -512,16 +797,7 @@ func (fs *fileUploadService) DeleteFile(filePath string) *models.MessageOptions
 		}
 	}
 
-	// 從檔案系統刪除檔案
-	if err := fs.fileProvider.DeleteFile(filePath); err != nil {
-		return &models.MessageOptions{
-			Code:    models.ErrInternalServer,
-			Message: "刪除檔案失敗",
-			Details: err.Error(),
-		}
-	}
-
-	return nil
+	return fs.deleteFileRecord(file)
 }
 
 // GetFileInfo 取得檔案資訊
@@ -571,7 +847,7 @@ func (fs *fileUploadService) CleanupExpiredFiles() *models.MessageOptions {
 
 	// 刪除過期檔案
 	for _, file := range expiredFiles {
-		if msgOpt := fs.DeleteFile(file.FilePath); msgOpt != nil {
+		if msgOpt := fs.deleteFileRecord(&file); msgOpt != nil {
 			// 記錄錯誤但繼續處理其他檔案
 			fmt.Printf("刪除過期檔案失敗 %s: %v\n", file.FilePath, msgOpt.Details)
 		}
@@ -580,6 +856,27 @@ func (fs *fileUploadService) CleanupExpiredFiles() *models.MessageOptions {
 	return nil
 }
 
+// MarkFileForCleanup 將檔案標記為立即過期，由過期檔案清理任務刪除
+// 用於不再被引用的檔案（例如已刪除訊息的附件），不需要檢查擁有者
+func (fs *fileUploadService) MarkFileForCleanup(fileID string) *models.MessageOptions {
+	if fileID == "" {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "檔案ID不能為空",
+		}
+	}
+
+	if err := fs.fileRepo.SetFileExpiry(fileID, time.Now()); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "標記檔案待清理失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
+
 // GetUserFiles 獲取用戶的檔案列表
 func (fs *fileUploadService) GetUserFiles(userID string) ([]*models.UploadedFile, *models.MessageOptions) {
 	if userID == "" {
@@ -640,31 +937,25 @@ func (fs *fileUploadService) DeleteFileByID(fileID string, userID string) *model
 		}
 	}
 
-	// 從檔案系統刪除檔案
-	if err := fs.fileProvider.DeleteFile(file.FilePath); err != nil {
-		return &models.MessageOptions{
-			Code:    models.ErrInternalServer,
-			Message: "刪除檔案失敗",
-			Details: err.Error(),
-		}
-	}
+	// 刪除記錄，最後一個引用相同內容的記錄刪除時才刪除實際檔案
+	return fs.deleteFileRecord(file)
+}
 
-	// 從資料庫刪除記錄
-	if err := fs.fileRepo.DeleteFileByID(fileID); err != nil {
-		return &models.MessageOptions{
-			Code:    models.ErrInternalServer,
-			Message: "刪除檔案記錄失敗",
-			Details: err.Error(),
-		}
+// GetFileURLByID 根據檔案ID獲取檔案連結
+func (fs *fileUploadService) GetFileURLByID(fileID string) (string, *models.MessageOptions) {
+	file, msgOpt := fs.getServableFile(fileID)
+	if msgOpt != nil {
+		return "", msgOpt
 	}
 
-	return nil
+	// 返回檔案URL，非公開檔案為短效預簽名網址
+	return fs.fileLinkURL(file, file.FilePath)
 }
 
-// GetFileURLByID 根據檔案ID獲取檔案連結
-func (fs *fileUploadService) GetFileURLByID(fileID string) (string, *models.MessageOptions) {
+// getServableFile 獲取可提供下載的檔案記錄，需已驗證且實際檔案仍存在
+func (fs *fileUploadService) getServableFile(fileID string) (*models.UploadedFile, *models.MessageOptions) {
 	if fileID == "" {
-		return "", &models.MessageOptions{
+		return nil, &models.MessageOptions{
 			Code:    models.ErrInvalidParams,
 			Message: "檔案ID不能為空",
 		}
@@ -673,7 +964,7 @@ func (fs *fileUploadService) GetFileURLByID(fileID string) (string, *models.Mess
 	// 從資料庫獲取檔案記錄
 	file, err := fs.fileRepo.GetFileByID(fileID)
 	if err != nil {
-		return "", &models.MessageOptions{
+		return nil, &models.MessageOptions{
 			Code:    models.ErrNotFound,
 			Message: "檔案不存在",
 			Details: err.Error(),
@@ -682,7 +973,7 @@ func (fs *fileUploadService) GetFileURLByID(fileID string) (string, *models.Mess
 
 	// 檢查檔案狀態
 	if file.Status != "verified" {
-		return "", &models.MessageOptions{
+		return nil, &models.MessageOptions{
 			Code:    models.ErrInvalidParams,
 			Message: "檔案尚未驗證或已損壞",
 		}
@@ -690,15 +981,100 @@ func (fs *fileUploadService) GetFileURLByID(fileID string) (string, *models.Mess
 
 	// 檢查檔案是否存在
 	if _, err := fs.fileProvider.GetFileInfo(file.FilePath); err != nil {
-		return "", &models.MessageOptions{
+		return nil, &models.MessageOptions{
 			Code:    models.ErrNotFound,
 			Message: "檔案不存在或已被刪除",
 			Details: err.Error(),
 		}
 	}
 
-	// 返回檔案URL
-	return fs.fileProvider.GetFileURL(file.FilePath), nil
+	return file, nil
+}
+
+// GetFileVariant 根據檔案ID獲取指定尺寸的連結，圖片小於該尺寸時返回原圖
+// 非公開檔案的存取權限由呼叫端依返回的 OwnerID 與 FileID 檢查
+func (fs *fileUploadService) GetFileVariant(fileID string, size string) (*models.FileVariantResponse, *models.MessageOptions) {
+	if fileID == "" {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "檔案ID不能為空",
+		}
+	}
+
+	if _, ok := findImageVariantSize(size); !ok && size != models.ImageVariantOriginal {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "不支援的縮圖尺寸",
+			Details: fmt.Sprintf("尺寸: %s", size),
+		}
+	}
+
+	file, err := fs.fileRepo.GetFileByID(fileID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "檔案不存在",
+			Details: err.Error(),
+		}
+	}
+
+	switch file.Status {
+	case "verified":
+	case "processing":
+		return nil, &models.MessageOptions{
+			Code:    models.ErrFileProcessing,
+			Message: "檔案處理中，請稍後再試",
+		}
+	default:
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "檔案尚未驗證或已損壞",
+		}
+	}
+
+	response := &models.FileVariantResponse{
+		FileID:   file.ID.Hex(),
+		Size:     models.ImageVariantOriginal,
+		Width:    file.Width,
+		Height:   file.Height,
+		FileSize: file.FileSize,
+		MimeType: file.MimeType,
+		Public:   slices.Contains(models.PublicFileTypes, file.FileType),
+		OwnerID:  file.UserID.Hex(),
+	}
+	filePath := file.FilePath
+
+	if size != models.ImageVariantOriginal {
+		// 非圖片或無法產生縮圖的格式沒有尺寸資訊
+		if file.Width == 0 {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrNotFound,
+				Message: "此檔案沒有縮圖",
+			}
+		}
+
+		for _, variant := range file.Variants {
+			if variant.Size != size {
+				continue
+			}
+			filePath = variant.FilePath
+			response.Size = variant.Size
+			response.Width = variant.Width
+			response.Height = variant.Height
+			response.FileSize = variant.FileSize
+			response.MimeType = variant.MimeType
+			break
+		}
+	}
+
+	// 非公開檔案（訊息附件等）使用短效預簽名網址
+	fileURL, msgOpt := fs.fileLinkURL(file, filePath)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+	response.URL = fileURL
+
+	return response, nil
 }
 
 // GetFileInfoByID 根據檔案ID獲取完整檔案資訊
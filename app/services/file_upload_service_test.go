@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"chat_app_backend/app/models"
 	"chat_app_backend/app/providers"
+	"context"
 	"errors"
+	"image"
+	"image/png"
 	"io"
 	"mime/multipart"
 	"net/textproto"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -29,6 +33,11 @@ func (m *mockFileProvider) SaveFile(file multipart.File, filename string) (strin
 	return args.String(0), args.Error(1)
 }
 
+func (m *mockFileProvider) SaveReader(reader io.Reader, size int64, filename string, contentType string) (string, error) {
+	args := m.Called(reader, size, filename, contentType)
+	return args.String(0), args.Error(1)
+}
+
 func (m *mockFileProvider) DeleteFile(filepath string) error {
 	args := m.Called(filepath)
 	return args.Error(0)
@@ -55,6 +64,26 @@ func (m *mockFileProvider) GetFile(filepath string) (io.ReadCloser, error) {
 	return args.Get(0).(io.ReadCloser), args.Error(1)
 }
 
+func (m *mockFileProvider) InitiateChunkedUpload(filename string, contentType string) (string, error) {
+	args := m.Called(filename, contentType)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockFileProvider) UploadChunk(filename string, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	args := m.Called(filename, uploadID, partNumber, reader, size)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockFileProvider) CompleteChunkedUpload(filename string, uploadID string, parts []providers.ChunkPart) (string, error) {
+	args := m.Called(filename, uploadID, parts)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockFileProvider) AbortChunkedUpload(filename string, uploadID string) error {
+	args := m.Called(filename, uploadID)
+	return args.Error(0)
+}
+
 // mockFileRepository 模擬 FileRepository
 type mockFileRepository struct {
 	mock.Mock
@@ -81,6 +110,14 @@ func (m *mockFileRepository) GetFileByPath(filePath string) (*models.UploadedFil
 	return args.Get(0).(*models.UploadedFile), args.Error(1)
 }
 
+func (m *mockFileRepository) GetVerifiedFilesByStoragePath(filePath string) ([]models.UploadedFile, error) {
+	args := m.Called(filePath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.UploadedFile), args.Error(1)
+}
+
 func (m *mockFileRepository) GetFilesByUserID(userID string) ([]models.UploadedFile, error) {
 	args := m.Called(userID)
 	if args.Get(0) == nil {
@@ -94,6 +131,16 @@ func (m *mockFileRepository) UpdateFileStatus(fileID string, status string) erro
 	return args.Error(0)
 }
 
+func (m *mockFileRepository) UpdateFile(fileID string, updates map[string]any) error {
+	args := m.Called(fileID, updates)
+	return args.Error(0)
+}
+
+func (m *mockFileRepository) SetFileExpiry(fileID string, expiresAt time.Time) error {
+	args := m.Called(fileID, expiresAt)
+	return args.Error(0)
+}
+
 func (m *mockFileRepository) DeleteFileByID(fileID string) error {
 	args := m.Called(fileID)
 	return args.Error(0)
@@ -104,6 +151,51 @@ func (m *mockFileRepository) DeleteFileByPath(filePath string) error {
 	return args.Error(0)
 }
 
+func (m *mockFileRepository) GetVerifiedFileByHash(hash string) (*models.UploadedFile, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UploadedFile), args.Error(1)
+}
+
+func (m *mockFileRepository) AcquireBlob(hash string) (*models.FileBlob, error) {
+	args := m.Called(hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileBlob), args.Error(1)
+}
+
+func (m *mockFileRepository) RegisterBlob(blob *models.FileBlob) (*models.FileBlob, error) {
+	args := m.Called(blob)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileBlob), args.Error(1)
+}
+
+func (m *mockFileRepository) ReleaseBlob(hash string, filePath string) (*models.FileBlob, error) {
+	args := m.Called(hash, filePath)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FileBlob), args.Error(1)
+}
+
+func (m *mockFileRepository) DeleteBlobIfUnreferenced(hash string) (bool, error) {
+	args := m.Called(hash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockFileRepository) GetStorageUsage(ownerType string, ownerID string) (*models.StorageUsage, error) {
+	args := m.Called(ownerType, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.StorageUsage), args.Error(1)
+}
+
 func (m *mockFileRepository) GetExpiredFiles() ([]models.UploadedFile, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -175,7 +267,7 @@ func TestNewFileUploadService(t *testing.T) {
 	mockFileProvider := new(mockFileProvider)
 	mockFileRepo := new(mockFileRepository)
 
-	service := NewFileUploadService(nil, mockFileProvider, nil, mockFileRepo)
+	service := NewFileUploadService(nil, mockFileProvider, nil, mockFileRepo, nil, nil, nil)
 
 	assert.NotNil(t, service)
 	assert.Equal(t, mockFileProvider, service.fileProvider)
@@ -402,6 +494,125 @@ func TestScanFileForMalware(t *testing.T) {
 		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
 		assert.Contains(t, msgOpt.Message, "無法開啟檔案")
 	})
+
+	t.Run("發現惡意內容", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileProvider.On("GetFile", "general/evil.pdf").Return(io.NopCloser(bytes.NewReader([]byte("%PDF-1.4"))), nil).Once()
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			scanner:      &stubMalwareScanner{result: &providers.ScanResult{Infected: true, Signature: "Eicar-Signature"}},
+		}
+		msgOpt := service.ScanFileForMalware("general/evil.pdf")
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+		assert.Equal(t, "Eicar-Signature", msgOpt.Details)
+	})
+
+	t.Run("掃描引擎錯誤", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileProvider.On("GetFile", "general/report.pdf").Return(io.NopCloser(bytes.NewReader([]byte("%PDF-1.4"))), nil).Once()
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			scanner:      &stubMalwareScanner{err: errors.New("connection refused")},
+		}
+		msgOpt := service.ScanFileForMalware("general/report.pdf")
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+	})
+
+	t.Run("未設定掃描引擎時使用啟發式掃描", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileProvider.On("GetFile", "general/script.pdf").Return(io.NopCloser(bytes.NewReader([]byte("<?php system($_GET['c']); ?>"))), nil).Once()
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+		}
+		msgOpt := service.ScanFileForMalware("general/script.pdf")
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, "Heuristic.PHP", msgOpt.Details)
+	})
+}
+
+// stubMalwareScanner 返回固定結果的掃描引擎
+type stubMalwareScanner struct {
+	result *providers.ScanResult
+	err    error
+}
+
+func (s *stubMalwareScanner) Scan(ctx context.Context, reader io.Reader) (*providers.ScanResult, error) {
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return nil, err
+	}
+	return s.result, s.err
+}
+
+func TestProcessUploadedFile(t *testing.T) {
+	file := models.UploadedFile{
+		BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+		FilePath:  "general/report.pdf",
+		MimeType:  "application/pdf",
+		Status:    "processing",
+	}
+
+	t.Run("掃描通過後標記為已驗證", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+			scanner:      &stubMalwareScanner{result: &providers.ScanResult{}},
+		}
+
+		mockFileProvider.On("GetFile", file.FilePath).Return(io.NopCloser(bytes.NewReader([]byte("%PDF-1.4"))), nil).Once()
+		mockFileRepo.On("UpdateFileStatus", file.ID.Hex(), "verified").Return(nil).Once()
+
+		service.processUploadedFile(file, fileProcessing{scan: true})
+
+		mockFileProvider.AssertExpectations(t)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("發現惡意內容時隔離檔案", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+			scanner:      &stubMalwareScanner{result: &providers.ScanResult{Infected: true, Signature: "Eicar-Signature"}},
+		}
+
+		mockFileProvider.On("GetFile", file.FilePath).Return(io.NopCloser(bytes.NewReader([]byte("%PDF-1.4"))), nil).Once()
+		mockFileRepo.On("UpdateFile", file.ID.Hex(), mock.MatchedBy(func(updates map[string]any) bool {
+			result, _ := updates["scan_result"].(string)
+			return updates["status"] == "failed" && strings.Contains(result, "Eicar-Signature")
+		})).Return(nil).Once()
+
+		service.processUploadedFile(file, fileProcessing{scan: true, variants: true})
+
+		mockFileRepo.AssertNotCalled(t, "UpdateFileStatus", mock.Anything, mock.Anything)
+		mockFileProvider.AssertNotCalled(t, "SaveFile", mock.Anything, mock.Anything)
+		mockFileProvider.AssertNotCalled(t, "DeleteFile", mock.Anything)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("無法完成掃描時同樣隔離", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+			scanner:      &stubMalwareScanner{err: errors.New("connection refused")},
+		}
+
+		mockFileProvider.On("GetFile", file.FilePath).Return(io.NopCloser(bytes.NewReader([]byte("%PDF-1.4"))), nil).Once()
+		mockFileRepo.On("UpdateFile", file.ID.Hex(), mock.MatchedBy(func(updates map[string]any) bool {
+			return updates["status"] == "failed"
+		})).Return(nil).Once()
+
+		service.processUploadedFile(file, fileProcessing{scan: true})
+
+		mockFileRepo.AssertNotCalled(t, "UpdateFileStatus", mock.Anything, mock.Anything)
+		mockFileRepo.AssertExpectations(t)
+	})
 }
 
 func TestGetUserFiles(t *testing.T) {
@@ -589,6 +800,7 @@ func TestDeleteFileByID(t *testing.T) {
 		}
 
 		mockFileRepo.On("GetFileByID", fileID.Hex()).Return(file, nil).Once()
+		mockFileRepo.On("DeleteFileByID", fileID.Hex()).Return(nil).Once()
 		mockFileProvider.On("DeleteFile", "/uploads/test.jpg").Return(errors.New("delete error")).Once()
 
 		msgOpt := service.DeleteFileByID(fileID.Hex(), userID.Hex())
@@ -600,6 +812,133 @@ func TestDeleteFileByID(t *testing.T) {
 		mockFileRepo.AssertExpectations(t)
 		mockFileProvider.AssertExpectations(t)
 	})
+
+	t.Run("內容仍被其他檔案引用時只刪除記錄", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+
+		userID := primitive.NewObjectID()
+		fileID := primitive.NewObjectID()
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		file := &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: fileID},
+			UserID:    userID,
+			FilePath:  "image/123_abc.png",
+			Hash:      "abc123",
+			Variants:  []models.FileVariant{{Size: "small", FilePath: "image/123_abc_small.png"}},
+		}
+
+		mockFileRepo.On("GetFileByID", fileID.Hex()).Return(file, nil).Once()
+		mockFileRepo.On("DeleteFileByID", fileID.Hex()).Return(nil).Once()
+		mockFileRepo.On("ReleaseBlob", "abc123", "image/123_abc.png").Return(&models.FileBlob{Hash: "abc123", FilePath: "image/123_abc.png", RefCount: 1}, nil).Once()
+
+		msgOpt := service.DeleteFileByID(fileID.Hex(), userID.Hex())
+
+		assert.Nil(t, msgOpt)
+		mockFileRepo.AssertExpectations(t)
+		mockFileProvider.AssertNotCalled(t, "DeleteFile", mock.Anything)
+	})
+
+	t.Run("最後一個引用刪除時刪除實際檔案與縮圖", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+
+		userID := primitive.NewObjectID()
+		fileID := primitive.NewObjectID()
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		file := &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: fileID},
+			UserID:    userID,
+			FilePath:  "image/123_abc.png",
+			Hash:      "abc123",
+			Variants:  []models.FileVariant{{Size: "small", FilePath: "image/123_abc_small.png"}},
+		}
+
+		mockFileRepo.On("GetFileByID", fileID.Hex()).Return(file, nil).Once()
+		mockFileRepo.On("DeleteFileByID", fileID.Hex()).Return(nil).Once()
+		mockFileRepo.On("ReleaseBlob", "abc123", "image/123_abc.png").Return(&models.FileBlob{Hash: "abc123", FilePath: "image/123_abc.png", RefCount: 0}, nil).Once()
+		mockFileRepo.On("DeleteBlobIfUnreferenced", "abc123").Return(true, nil).Once()
+		mockFileProvider.On("DeleteFile", "image/123_abc.png").Return(nil).Once()
+		mockFileProvider.On("DeleteFile", "image/123_abc_small.png").Return(nil).Once()
+
+		msgOpt := service.DeleteFileByID(fileID.Hex(), userID.Hex())
+
+		assert.Nil(t, msgOpt)
+		mockFileRepo.AssertExpectations(t)
+		mockFileProvider.AssertExpectations(t)
+	})
+
+	t.Run("刪除期間內容被重新引用時保留實際檔案", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+
+		userID := primitive.NewObjectID()
+		fileID := primitive.NewObjectID()
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		file := &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: fileID},
+			UserID:    userID,
+			FilePath:  "image/123_abc.png",
+			Hash:      "abc123",
+		}
+
+		mockFileRepo.On("GetFileByID", fileID.Hex()).Return(file, nil).Once()
+		mockFileRepo.On("DeleteFileByID", fileID.Hex()).Return(nil).Once()
+		mockFileRepo.On("ReleaseBlob", "abc123", "image/123_abc.png").Return(&models.FileBlob{Hash: "abc123", FilePath: "image/123_abc.png", RefCount: 0}, nil).Once()
+		mockFileRepo.On("DeleteBlobIfUnreferenced", "abc123").Return(false, nil).Once()
+
+		msgOpt := service.DeleteFileByID(fileID.Hex(), userID.Hex())
+
+		assert.Nil(t, msgOpt)
+		mockFileRepo.AssertExpectations(t)
+		mockFileProvider.AssertNotCalled(t, "DeleteFile", mock.Anything)
+	})
+
+	t.Run("沒有內容記錄的舊檔案直接刪除", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+
+		userID := primitive.NewObjectID()
+		fileID := primitive.NewObjectID()
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		file := &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: fileID},
+			UserID:    userID,
+			FilePath:  "general/123_abc.txt",
+			Hash:      "abc123",
+		}
+
+		mockFileRepo.On("GetFileByID", fileID.Hex()).Return(file, nil).Once()
+		mockFileRepo.On("DeleteFileByID", fileID.Hex()).Return(nil).Once()
+		mockFileRepo.On("ReleaseBlob", "abc123", "general/123_abc.txt").Return(nil, nil).Once()
+		mockFileProvider.On("DeleteFile", "general/123_abc.txt").Return(nil).Once()
+
+		msgOpt := service.DeleteFileByID(fileID.Hex(), userID.Hex())
+
+		assert.Nil(t, msgOpt)
+		mockFileRepo.AssertExpectations(t)
+		mockFileProvider.AssertExpectations(t)
+	})
 }
 
 func TestGetFileURLByID(t *testing.T) {
@@ -638,6 +977,33 @@ func TestGetFileURLByID(t *testing.T) {
 		mockFileProvider.AssertExpectations(t)
 	})
 
+	t.Run("附件返回短效預簽名網址", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{
+			fileProvider: mockProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		file := &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+			FileType:  "image",
+			FilePath:  "image/123_abc.jpg",
+			Status:    "verified",
+		}
+
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(file, nil).Once()
+		mockProvider.On("GetFileInfo", file.FilePath).Return(&mockFileInfo{}, nil).Once()
+		mockProvider.On("PresignGetURL", file.FilePath, PresignedDownloadExpiry, "").Return("https://minio.example.com/image?sig", nil).Once()
+
+		url, msgOpt := service.GetFileURLByID(file.ID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "https://minio.example.com/image?sig", url)
+		mockProvider.AssertNotCalled(t, "GetFileURL", mock.Anything)
+		mockProvider.AssertExpectations(t)
+	})
+
 	t.Run("檔案ID為空", func(t *testing.T) {
 		service := &fileUploadService{}
 
@@ -782,6 +1148,310 @@ func TestGetFileInfoByID(t *testing.T) {
 	})
 }
 
+func TestMarkFileForCleanup(t *testing.T) {
+	t.Run("設定為立即過期", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		fileID := primitive.NewObjectID()
+
+		service := &fileUploadService{
+			fileRepo: mockFileRepo,
+		}
+
+		mockFileRepo.On("SetFileExpiry", fileID.Hex(), mock.MatchedBy(func(expiresAt time.Time) bool {
+			return !expiresAt.After(time.Now())
+		})).Return(nil).Once()
+
+		msgOpt := service.MarkFileForCleanup(fileID.Hex())
+
+		assert.Nil(t, msgOpt)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("更新失敗", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		fileID := primitive.NewObjectID()
+
+		service := &fileUploadService{
+			fileRepo: mockFileRepo,
+		}
+
+		mockFileRepo.On("SetFileExpiry", fileID.Hex(), mock.Anything).Return(errors.New("db error")).Once()
+
+		msgOpt := service.MarkFileForCleanup(fileID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+	})
+
+	t.Run("檔案ID為空", func(t *testing.T) {
+		service := &fileUploadService{}
+
+		msgOpt := service.MarkFileForCleanup("")
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+// createTestPNG 建立指定尺寸的 PNG 圖片
+func createTestPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height)))
+	assert.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestProcessImageVariants(t *testing.T) {
+	t.Run("產生縮圖並標記為已驗證", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		fileID := primitive.NewObjectID()
+		file := models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: fileID},
+			FilePath:  "avatar/123_abc.png",
+			MimeType:  "image/png",
+		}
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		// 300x200 只會產生 small（128）縮圖
+		mockFileProvider.On("GetFile", file.FilePath).Return(io.NopCloser(bytes.NewReader(createTestPNG(t, 300, 200))), nil).Once()
+		mockFileProvider.On("SaveReader", mock.Anything, mock.Anything, "avatar/123_abc_small.png", "image/png").Return("avatar/123_abc_small.png", nil).Once()
+		mockFileRepo.On("UpdateFile", fileID.Hex(), mock.MatchedBy(func(updates map[string]any) bool {
+			variants, ok := updates["variants"].([]models.FileVariant)
+			return updates["status"] == "verified" &&
+				updates["width"] == 300 && updates["height"] == 200 &&
+				ok && len(variants) == 1 &&
+				variants[0].Size == "small" && variants[0].Width == 128 && variants[0].Height == 85
+		})).Return(nil).Once()
+
+		service.processImageVariants(file)
+
+		mockFileProvider.AssertExpectations(t)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("原圖小於所有尺寸時只記錄寬高", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		fileID := primitive.NewObjectID()
+		file := models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: fileID},
+			FilePath:  "image/123_abc.png",
+			MimeType:  "image/png",
+		}
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		mockFileProvider.On("GetFile", file.FilePath).Return(io.NopCloser(bytes.NewReader(createTestPNG(t, 64, 32))), nil).Once()
+		mockFileRepo.On("UpdateFile", fileID.Hex(), mock.MatchedBy(func(updates map[string]any) bool {
+			variants, _ := updates["variants"].([]models.FileVariant)
+			return updates["status"] == "verified" && updates["width"] == 64 && updates["height"] == 32 && len(variants) == 0
+		})).Return(nil).Once()
+
+		service.processImageVariants(file)
+
+		mockFileProvider.AssertNotCalled(t, "SaveReader", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("圖片損壞時標記為失敗", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		fileID := primitive.NewObjectID()
+		file := models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: fileID},
+			FilePath:  "image/123_abc.png",
+			MimeType:  "image/png",
+		}
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		mockFileProvider.On("GetFile", file.FilePath).Return(io.NopCloser(bytes.NewReader([]byte("not an image"))), nil).Once()
+		mockFileRepo.On("UpdateFile", fileID.Hex(), mock.MatchedBy(func(updates map[string]any) bool {
+			return updates["status"] == "failed"
+		})).Return(nil).Once()
+
+		service.processImageVariants(file)
+
+		mockFileRepo.AssertExpectations(t)
+	})
+}
+
+func TestGetFileVariant(t *testing.T) {
+	newImageFile := func(status string) *models.UploadedFile {
+		return &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+			UserID:    primitive.NewObjectID(),
+			FileType:  "image",
+			FilePath:  "image/123_abc.png",
+			FileSize:  4096,
+			MimeType:  "image/png",
+			Status:    status,
+			Width:     300,
+			Height:    200,
+			Variants: []models.FileVariant{
+				{Size: "small", FilePath: "image/123_abc_small.png", Width: 128, Height: 85, FileSize: 512, MimeType: "image/png"},
+			},
+		}
+	}
+
+	t.Run("返回指定尺寸的縮圖", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		file := newImageFile("verified")
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(file, nil).Once()
+		mockFileProvider.On("GetFileURL", "image/123_abc_small.png").Return("http://localhost/uploads/image/123_abc_small.png").Once()
+
+		variant, msgOpt := service.GetFileVariant(file.ID.Hex(), "small")
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "small", variant.Size)
+		assert.Equal(t, "http://localhost/uploads/image/123_abc_small.png", variant.URL)
+		assert.Equal(t, 128, variant.Width)
+		assert.Equal(t, int64(512), variant.FileSize)
+		assert.False(t, variant.Public, "訊息圖片需由呼叫端檢查存取權限")
+		assert.Equal(t, file.UserID.Hex(), variant.OwnerID)
+		mockFileProvider.AssertExpectations(t)
+	})
+
+	t.Run("附件縮圖使用短效預簽名網址", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		file := newImageFile("verified")
+
+		service := &fileUploadService{
+			fileProvider: mockProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(file, nil).Times(2)
+		mockProvider.On("PresignGetURL", "image/123_abc_small.png", PresignedDownloadExpiry, "").Return("https://minio.example.com/small?sig", nil).Once()
+		mockProvider.On("PresignGetURL", file.FilePath, PresignedDownloadExpiry, "").Return("https://minio.example.com/original?sig", nil).Once()
+
+		variant, msgOpt := service.GetFileVariant(file.ID.Hex(), "small")
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "https://minio.example.com/small?sig", variant.URL)
+
+		original, msgOpt := service.GetFileVariant(file.ID.Hex(), models.ImageVariantOriginal)
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "https://minio.example.com/original?sig", original.URL)
+
+		mockProvider.AssertNotCalled(t, "GetFileURL", mock.Anything)
+		mockProvider.AssertExpectations(t)
+	})
+
+	t.Run("公開資源返回一般連結", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		file := newImageFile("verified")
+		file.FileType = "avatar"
+
+		service := &fileUploadService{
+			fileProvider: mockProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(file, nil).Once()
+		mockProvider.On("GetFileURL", "image/123_abc_small.png").Return("https://minio.example.com/bucket/image/123_abc_small.png").Once()
+
+		variant, msgOpt := service.GetFileVariant(file.ID.Hex(), "small")
+
+		assert.Nil(t, msgOpt)
+		assert.True(t, variant.Public)
+		assert.Equal(t, "https://minio.example.com/bucket/image/123_abc_small.png", variant.URL)
+		mockProvider.AssertNotCalled(t, "PresignGetURL", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("原圖小於指定尺寸時返回原圖", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		file := newImageFile("verified")
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(file, nil).Once()
+		mockFileProvider.On("GetFileURL", file.FilePath).Return("http://localhost/uploads/image/123_abc.png").Once()
+
+		variant, msgOpt := service.GetFileVariant(file.ID.Hex(), "large")
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, models.ImageVariantOriginal, variant.Size)
+		assert.Equal(t, "http://localhost/uploads/image/123_abc.png", variant.URL)
+		assert.Equal(t, 300, variant.Width)
+	})
+
+	t.Run("檔案處理中", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		file := newImageFile("processing")
+
+		service := &fileUploadService{
+			fileRepo: mockFileRepo,
+		}
+
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(file, nil).Once()
+
+		variant, msgOpt := service.GetFileVariant(file.ID.Hex(), "small")
+
+		assert.Nil(t, variant)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrFileProcessing, msgOpt.Code)
+	})
+
+	t.Run("非圖片檔案沒有縮圖", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		file := &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+			FilePath:  "document/123_abc.pdf",
+			MimeType:  "application/pdf",
+			Status:    "verified",
+		}
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(file, nil).Once()
+
+		variant, msgOpt := service.GetFileVariant(file.ID.Hex(), "small")
+
+		assert.Nil(t, variant)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNotFound, msgOpt.Code)
+		mockFileProvider.AssertNotCalled(t, "GetFileURL", mock.Anything)
+	})
+
+	t.Run("不支援的尺寸", func(t *testing.T) {
+		service := &fileUploadService{}
+
+		variant, msgOpt := service.GetFileVariant(primitive.NewObjectID().Hex(), "huge")
+
+		assert.Nil(t, variant)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
 func TestDeleteFile(t *testing.T) {
 	t.Run("成功刪除檔案", func(t *testing.T) {
 		mockFileProvider := new(mockFileProvider)
@@ -793,8 +1463,13 @@ func TestDeleteFile(t *testing.T) {
 		}
 
 		filePath := "/uploads/test.jpg"
+		file := &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+			FilePath:  filePath,
+		}
 
-		mockFileRepo.On("DeleteFileByPath", filePath).Return(nil).Once()
+		mockFileRepo.On("GetFileByPath", filePath).Return(file, nil).Once()
+		mockFileRepo.On("DeleteFileByID", file.ID.Hex()).Return(nil).Once()
 		mockFileProvider.On("DeleteFile", filePath).Return(nil).Once()
 
 		msgOpt := service.DeleteFile(filePath)
@@ -813,8 +1488,13 @@ func TestDeleteFile(t *testing.T) {
 		}
 
 		filePath := "/uploads/test.jpg"
+		file := &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+			FilePath:  filePath,
+		}
 
-		mockFileRepo.On("DeleteFileByPath", filePath).Return(errors.New("database error")).Once()
+		mockFileRepo.On("GetFileByPath", filePath).Return(file, nil).Once()
+		mockFileRepo.On("DeleteFileByID", file.ID.Hex()).Return(errors.New("database error")).Once()
 
 		msgOpt := service.DeleteFile(filePath)
 
@@ -835,8 +1515,13 @@ func TestDeleteFile(t *testing.T) {
 		}
 
 		filePath := "/uploads/test.jpg"
+		file := &models.UploadedFile{
+			BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+			FilePath:  filePath,
+		}
 
-		mockFileRepo.On("DeleteFileByPath", filePath).Return(nil).Once()
+		mockFileRepo.On("GetFileByPath", filePath).Return(file, nil).Once()
+		mockFileRepo.On("DeleteFileByID", file.ID.Hex()).Return(nil).Once()
 		mockFileProvider.On("DeleteFile", filePath).Return(errors.New("delete error")).Once()
 
 		msgOpt := service.DeleteFile(filePath)
@@ -964,9 +1649,9 @@ func TestCleanupExpiredFiles(t *testing.T) {
 		}
 
 		mockFileRepo.On("GetExpiredFiles").Return(expiredFiles, nil).Once()
-		mockFileRepo.On("DeleteFileByPath", "/uploads/expired1.jpg").Return(nil).Once()
+		mockFileRepo.On("DeleteFileByID", expiredFiles[0].ID.Hex()).Return(nil).Once()
 		mockFileProvider.On("DeleteFile", "/uploads/expired1.jpg").Return(nil).Once()
-		mockFileRepo.On("DeleteFileByPath", "/uploads/expired2.jpg").Return(nil).Once()
+		mockFileRepo.On("DeleteFileByID", expiredFiles[1].ID.Hex()).Return(nil).Once()
 		mockFileProvider.On("DeleteFile", "/uploads/expired2.jpg").Return(nil).Once()
 
 		msgOpt := service.CleanupExpiredFiles()
@@ -1016,9 +1701,9 @@ func TestCleanupExpiredFiles(t *testing.T) {
 
 		mockFileRepo.On("GetExpiredFiles").Return(expiredFiles, nil).Once()
 		// 第一個檔案刪除失敗
-		mockFileRepo.On("DeleteFileByPath", "/uploads/expired1.jpg").Return(errors.New("delete error")).Once()
+		mockFileRepo.On("DeleteFileByID", expiredFiles[0].ID.Hex()).Return(errors.New("delete error")).Once()
 		// 第二個檔案成功刪除
-		mockFileRepo.On("DeleteFileByPath", "/uploads/expired2.jpg").Return(nil).Once()
+		mockFileRepo.On("DeleteFileByID", expiredFiles[1].ID.Hex()).Return(nil).Once()
 		mockFileProvider.On("DeleteFile", "/uploads/expired2.jpg").Return(nil).Once()
 
 		msgOpt := service.CleanupExpiredFiles()
@@ -1029,6 +1714,44 @@ func TestCleanupExpiredFiles(t *testing.T) {
 		mockFileRepo.AssertExpectations(t)
 		mockFileProvider.AssertExpectations(t)
 	})
+
+	t.Run("共用內容的過期檔案只在最後一個引用時刪除實際檔案", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		expiredFiles := []models.UploadedFile{
+			{
+				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+				FilePath:  "general/123_abc.txt",
+				Hash:      "abc123",
+			},
+			{
+				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+				FilePath:  "general/123_abc.txt",
+				Hash:      "abc123",
+			},
+		}
+
+		mockFileRepo.On("GetExpiredFiles").Return(expiredFiles, nil).Once()
+		mockFileRepo.On("DeleteFileByID", expiredFiles[0].ID.Hex()).Return(nil).Once()
+		mockFileRepo.On("DeleteFileByID", expiredFiles[1].ID.Hex()).Return(nil).Once()
+		mockFileRepo.On("ReleaseBlob", "abc123", "general/123_abc.txt").Return(&models.FileBlob{Hash: "abc123", FilePath: "general/123_abc.txt", RefCount: 1}, nil).Once()
+		mockFileRepo.On("ReleaseBlob", "abc123", "general/123_abc.txt").Return(&models.FileBlob{Hash: "abc123", FilePath: "general/123_abc.txt", RefCount: 0}, nil).Once()
+		mockFileRepo.On("DeleteBlobIfUnreferenced", "abc123").Return(true, nil).Once()
+		mockFileProvider.On("DeleteFile", "general/123_abc.txt").Return(nil).Once()
+
+		msgOpt := service.CleanupExpiredFiles()
+
+		assert.Nil(t, msgOpt)
+
+		mockFileRepo.AssertExpectations(t)
+		mockFileProvider.AssertExpectations(t)
+	})
 }
 
 func TestContainsMaliciousContent(t *testing.T) {
@@ -1095,3 +1818,94 @@ func TestIsMimeTypeCompatible(t *testing.T) {
 		assert.True(t, result)
 	})
 }
+
+func TestUploadFileWithConfigDeduplication(t *testing.T) {
+	userID := primitive.NewObjectID()
+	config := models.GetGeneralUploadConfig()
+
+	t.Run("新內容儲存並記錄引用", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		content := []byte("%PDF-1.4 quarterly report")
+		hash, err := providers.GenerateFileHash(createTestFile(content))
+		assert.NoError(t, err)
+		header := createTestFileHeader("report.pdf", int64(len(content)), "application/pdf")
+
+		mockFileRepo.On("AcquireBlob", hash).Return(nil, nil).Once()
+		mockFileProvider.On("SaveFile", mock.Anything, mock.MatchedBy(func(path string) bool {
+			return strings.HasPrefix(path, "general/")
+		})).Return("general/100_new.pdf", nil).Once()
+		mockFileRepo.On("RegisterBlob", mock.MatchedBy(func(blob *models.FileBlob) bool {
+			return blob.Hash == hash && blob.FilePath == "general/100_new.pdf" && blob.FileSize == int64(len(content))
+		})).Return(&models.FileBlob{Hash: hash, FilePath: "general/100_new.pdf", RefCount: 1}, nil).Once()
+		mockFileRepo.On("CreateFile", mock.MatchedBy(func(file *models.UploadedFile) bool {
+			return file.FilePath == "general/100_new.pdf" && file.Hash == hash && file.Status == "processing"
+		})).Return(nil).Once()
+		// 新內容在背景掃描
+		mockFileProvider.On("GetFile", "general/100_new.pdf").Return(io.NopCloser(bytes.NewReader(content)), nil).Once()
+		scanned := make(chan struct{})
+		mockFileRepo.On("UpdateFileStatus", mock.Anything, "verified").Return(nil).Once().Run(func(args mock.Arguments) {
+			close(scanned)
+		})
+
+		result, msgOpt := service.UploadFileWithConfig(createTestFile(content), header, userID.Hex(), config)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "general/100_new.pdf", result.FilePath)
+		assert.Equal(t, "processing", result.Status)
+
+		select {
+		case <-scanned:
+		case <-time.After(time.Second):
+			t.Fatal("背景掃描未完成")
+		}
+		mockFileProvider.AssertExpectations(t)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("相同內容共用既有檔案並沿用縮圖", func(t *testing.T) {
+		mockFileProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{
+			fileProvider: mockFileProvider,
+			fileRepo:     mockFileRepo,
+		}
+
+		content := createTestPNG(t, 300, 200)
+		hash, err := providers.GenerateFileHash(createTestFile(content))
+		assert.NoError(t, err)
+		header := createTestFileHeader("photo.png", int64(len(content)), "image/png")
+		existingPath := "general/100_existing.png"
+		variants := []models.FileVariant{{Size: "small", FilePath: "general/100_existing_small.png", Width: 128, Height: 85}}
+
+		mockFileRepo.On("AcquireBlob", hash).Return(&models.FileBlob{Hash: hash, FilePath: existingPath, RefCount: 2}, nil).Once()
+		mockFileRepo.On("GetVerifiedFileByHash", hash).Return(&models.UploadedFile{
+			FilePath: existingPath,
+			Hash:     hash,
+			Width:    300,
+			Height:   200,
+			Variants: variants,
+			Status:   "verified",
+		}, nil).Once()
+		mockFileRepo.On("CreateFile", mock.MatchedBy(func(file *models.UploadedFile) bool {
+			return file.FilePath == existingPath && file.OriginalName == "photo.png" &&
+				file.Status == "verified" && file.Width == 300 && len(file.Variants) == 1
+		})).Return(nil).Once()
+		mockFileProvider.On("GetFileURL", existingPath).Return("http://localhost/uploads/general/100_existing.png").Once()
+
+		result, msgOpt := service.UploadFileWithConfig(createTestFile(content), header, userID.Hex(), config)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "verified", result.Status)
+		assert.Equal(t, existingPath, result.FilePath)
+		mockFileProvider.AssertNotCalled(t, "SaveFile", mock.Anything, mock.Anything)
+		mockFileProvider.AssertNotCalled(t, "GetFile", mock.Anything)
+		mockFileProvider.AssertExpectations(t)
+		mockFileRepo.AssertExpectations(t)
+	})
+}
@@ -46,10 +46,17 @@ func (m *mockFriendClientManager) Register(client *Client) {
 func (m *mockFriendClientManager) Unregister(client *Client) {
 }
 
+func (m *mockFriendClientManager) DisconnectSession(userID string, sessionID string) {
+}
+
 func (m *mockFriendClientManager) GetClient(userID string) (*Client, bool) {
 	return nil, false
 }
 
+func (m *mockFriendClientManager) GetUserClients(userID string) []*Client {
+	return nil
+}
+
 func (m *mockFriendClientManager) GetAllClients() map[*Client]bool {
 	return nil
 }
@@ -63,6 +70,15 @@ func (m *mockFriendClientManager) StartHealthChecker(ctx context.Context) {
 	m.Called(ctx)
 }
 
+func (m *mockFriendClientManager) OnClientEvent(action string, handler func(ClientEvent)) {
+}
+
+func (m *mockFriendClientManager) PublishClientEvent(event ClientEvent) {
+}
+
+func (m *mockFriendClientManager) StartEventSubscriber(ctx context.Context) {
+}
+
 // --- Tests ---
 
 func TestNewFriendService(t *testing.T) {
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"bytes"
+	"chat_app_backend/app/models"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // 註冊 GIF 解碼器，JPEG 與 PNG 由編碼時的匯入註冊
+	"image/jpeg"
+	"image/png"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	MaxImagePixels     = 40_000_000 // 產生縮圖的圖片像素上限，避免解壓縮炸彈耗盡記憶體
+	VariantJPEGQuality = 85         // JPEG 縮圖品質
+)
+
+// variantImageTypes 可解析並產生縮圖的圖片類型，其他格式（webp、bmp 等）只保留原圖
+var variantImageTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// canGenerateVariants 判斷 MIME 類型是否可產生縮圖
+func canGenerateVariants(mimeType string) bool {
+	return slices.Contains(variantImageTypes, mimeType)
+}
+
+// variantDimensions 依最長邊等比例計算縮圖尺寸，原圖不大於該尺寸時返回 false
+func variantDimensions(width, height, maxEdge int) (int, int, bool) {
+	if width <= maxEdge && height <= maxEdge {
+		return 0, 0, false
+	}
+
+	if width >= height {
+		return maxEdge, max(1, height*maxEdge/width), true
+	}
+	return max(1, width*maxEdge/height), maxEdge, true
+}
+
+// resizeImage 以區域平均縮小圖片，每個目標像素取其涵蓋的來源像素平均值
+func resizeImage(src image.Image, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := range height {
+		y0 := bounds.Min.Y + y*srcHeight/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcHeight/height)
+		for x := range width {
+			x0 := bounds.Min.X + x*srcWidth/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcWidth/width)
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					count++
+				}
+			}
+
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / count), //nolint:gosec // 平均值不會超過 255
+				G: uint8(g / count), //nolint:gosec // 平均值不會超過 255
+				B: uint8(b / count), //nolint:gosec // 平均值不會超過 255
+				A: uint8(a / count), //nolint:gosec // 平均值不會超過 255
+			})
+		}
+	}
+
+	return dst
+}
+
+// encodeVariant 編碼縮圖，PNG 與 GIF 輸出 PNG 以保留透明度，其餘輸出 JPEG
+// 返回編碼結果、MIME 類型與副檔名
+func encodeVariant(img image.Image, mimeType string) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+	switch mimeType {
+	case "image/png", "image/gif":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", "", fmt.Errorf("PNG 編碼失敗: %w", err)
+		}
+		return buf.Bytes(), "image/png", ".png", nil
+	default:
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: VariantJPEGQuality}); err != nil {
+			return nil, "", "", fmt.Errorf("JPEG 編碼失敗: %w", err)
+		}
+		return buf.Bytes(), "image/jpeg", ".jpg", nil
+	}
+}
+
+// variantFilePath 縮圖路徑，與原始檔案放在同一目錄，例如 avatar/123_abc.png -> avatar/123_abc_small.png
+func variantFilePath(filePath string, size string, ext string) string {
+	base := strings.TrimSuffix(filePath, filepath.Ext(filePath))
+	return fmt.Sprintf("%s_%s%s", base, size, ext)
+}
+
+// findImageVariantSize 依名稱查找標準縮圖尺寸
+func findImageVariantSize(name string) (models.ImageVariantSize, bool) {
+	for _, size := range models.ImageVariantSizes {
+		if size.Name == name {
+			return size, true
+		}
+	}
+	return models.ImageVariantSize{}, false
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariantDimensions(t *testing.T) {
+	tests := []struct {
+		name           string
+		width, height  int
+		maxEdge        int
+		expectedWidth  int
+		expectedHeight int
+		expectedOK     bool
+	}{
+		{"橫向圖片依寬度縮放", 1000, 500, 128, 128, 64, true},
+		{"直向圖片依高度縮放", 300, 600, 128, 64, 128, true},
+		{"極窄圖片至少保留 1 像素", 4000, 10, 128, 128, 1, true},
+		{"原圖不大於尺寸時不產生", 128, 100, 128, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			width, height, ok := variantDimensions(tt.width, tt.height, tt.maxEdge)
+			assert.Equal(t, tt.expectedOK, ok)
+			assert.Equal(t, tt.expectedWidth, width)
+			assert.Equal(t, tt.expectedHeight, height)
+		})
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	// 左半邊黑色、右半邊白色，縮小為 2x1 後應保留左右顏色
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.NRGBA{A: 255}
+			if x >= 2 {
+				c = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			src.SetNRGBA(x, y, c)
+		}
+	}
+
+	dst := resizeImage(src, 2, 1)
+
+	assert.Equal(t, image.Rect(0, 0, 2, 1), dst.Bounds())
+	assert.Equal(t, color.NRGBA{A: 255}, dst.NRGBAAt(0, 0))
+	assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, dst.NRGBAAt(1, 0))
+
+	t.Run("取區域平均值", func(t *testing.T) {
+		dst := resizeImage(src, 1, 1)
+		assert.Equal(t, uint8(127), dst.NRGBAAt(0, 0).R)
+	})
+}
+
+func TestEncodeVariant(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+
+	_, mimeType, ext, err := encodeVariant(img, "image/png")
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", mimeType)
+	assert.Equal(t, ".png", ext)
+
+	_, mimeType, ext, err = encodeVariant(img, "image/jpeg")
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", mimeType)
+	assert.Equal(t, ".jpg", ext)
+}
+
+func TestVariantFilePath(t *testing.T) {
+	assert.Equal(t, "avatar/123_abc_small.png", variantFilePath("avatar/123_abc.png", "small", ".png"))
+	assert.Equal(t, "image/123_abc_large.jpg", variantFilePath("image/123_abc.jpeg", "large", ".jpg"))
+}
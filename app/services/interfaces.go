@@ -4,6 +4,7 @@ import (
 	"chat_app_backend/app/models"
 	"chat_app_backend/app/providers"
 	"context"
+	"io"
 	"mime/multipart"
 
 	"github.com/gin-gonic/gin"
@@ -23,13 +24,13 @@ type UserService interface {
 	RegisterUser(user models.User) *models.MessageOptions
 
 	// Login 處理用戶登入
-	Login(loginUser models.User) (*models.LoginResponse, *models.MessageOptions)
+	Login(loginUser models.User, device models.DeviceInfo) (*models.LoginResponse, *models.MessageOptions)
 
 	// Logout 處理用戶登出
 	Logout(c *gin.Context) *models.MessageOptions
 
 	// RefreshToken 刷新令牌
-	RefreshToken(refreshToken string) (*models.RefreshTokenResponse, *models.MessageOptions)
+	RefreshToken(refreshToken string, device models.DeviceInfo) (*models.RefreshTokenResponse, *models.MessageOptions)
 
 	// SetUserOnline 設置用戶為在線狀態
 	SetUserOnline(userID string) error
@@ -67,8 +68,38 @@ type UserService interface {
 	// GetTwoFactorStatus 獲取兩步驟驗證狀態
 	GetTwoFactorStatus(userID string) (*models.TwoFactorStatusResponse, error)
 
-	// UpdateTwoFactorStatus 啟用/停用兩步驟驗證
-	UpdateTwoFactorStatus(userID string, enabled bool) error
+	// SetupTwoFactor 產生待確認的 TOTP 密鑰與 otpauth URI
+	SetupTwoFactor(userID string) (*models.TwoFactorSetupResponse, *models.MessageOptions)
+
+	// EnableTwoFactor 以驗證碼確認密鑰並啟用兩步驟驗證，返回復原碼
+	EnableTwoFactor(userID string, code string) (*models.TwoFactorRecoveryCodesResponse, *models.MessageOptions)
+
+	// DisableTwoFactor 以驗證碼或復原碼停用兩步驟驗證
+	DisableTwoFactor(userID string, code string) *models.MessageOptions
+
+	// VerifyTwoFactorLogin 以挑戰 token 與驗證碼完成兩步驟登入
+	VerifyTwoFactorLogin(challengeToken string, code string, device models.DeviceInfo) (*models.LoginResponse, *models.MessageOptions)
+
+	// GetSessions 獲取用戶目前有效的登入工作階段
+	GetSessions(userID string, currentSessionID string) ([]models.SessionResponse, *models.MessageOptions)
+
+	// RevokeSession 撤銷指定的登入工作階段
+	RevokeSession(userID string, sessionID string) *models.MessageOptions
+
+	// RevokeOtherSessions 撤銷目前工作階段以外的所有工作階段，返回被撤銷的工作階段 ID
+	RevokeOtherSessions(userID string, currentSessionID string) ([]string, *models.MessageOptions)
+
+	// SendEmailVerification 重新寄送電子郵件驗證信
+	SendEmailVerification(userID string) *models.MessageOptions
+
+	// VerifyEmail 以驗證信中的 token 完成電子郵件驗證
+	VerifyEmail(token string) *models.MessageOptions
+
+	// RequestPasswordReset 寄送重設密碼信，電子郵件未註冊時同樣返回成功
+	RequestPasswordReset(email string) *models.MessageOptions
+
+	// ResetPassword 以重設密碼信中的 token 設定新密碼，並撤銷所有登入狀態
+	ResetPassword(token string, newPassword string) *models.MessageOptions
 
 	// DeactivateAccount 停用帳號
 	DeactivateAccount(userID string) error
@@ -81,7 +112,7 @@ type UserService interface {
 // 所有與聊天相關的業務邏輯方法都應該在這裡声明
 type ChatService interface {
 	// HandleWebSocket 處理 WebSocket 連接
-	HandleWebSocket(ws *websocket.Conn, userID string)
+	HandleWebSocket(ws *websocket.Conn, userID string, sessionID string)
 
 	// GetDMRoomResponseList 獲取聊天列表response
 	GetDMRoomResponseList(ctx context.Context, userID string, includeNotVisible bool) ([]models.DMRoomResponse, *models.MessageOptions)
@@ -95,8 +126,38 @@ type ChatService interface {
 	// GetDMMessages 獲取私聊訊息
 	GetDMMessages(ctx context.Context, userID string, roomID string, before string, after string, limit string) ([]models.MessageResponse, *models.MessageOptions)
 
-	// GetChannelMessages 獲取頻道訊息
-	GetChannelMessages(ctx context.Context, userID string, channelID string, before string, after string, limit string) ([]models.MessageResponse, *models.MessageOptions)
+	// GetChannelMessages 獲取頻道訊息（topLevelOnly 時不含討論串回覆）
+	GetChannelMessages(ctx context.Context, userID string, channelID string, before string, after string, limit string, topLevelOnly bool) ([]models.MessageResponse, *models.MessageOptions)
+
+	// GetThreadMessages 獲取討論串回覆
+	GetThreadMessages(ctx context.Context, userID string, roomType models.RoomType, roomID string, threadID string, before string, after string, limit string) ([]models.MessageResponse, *models.MessageOptions)
+
+	// EditMessage 編輯訊息（僅限發送者）
+	EditMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, content string) (*models.MessageResponse, *models.MessageOptions)
+
+	// DeleteMessage 刪除訊息（發送者或伺服器管理員）
+	DeleteMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) *models.MessageOptions
+
+	// AddReaction 新增訊息表情回應
+	AddReaction(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, emoji string) (*models.MessageResponse, *models.MessageOptions)
+
+	// RemoveReaction 移除訊息表情回應
+	RemoveReaction(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, emoji string) (*models.MessageResponse, *models.MessageOptions)
+
+	// SearchMessages 全文搜尋用戶可讀取房間內的訊息
+	SearchMessages(ctx context.Context, userID string, request models.MessageSearchRequest) (*models.MessageSearchResults, *models.MessageOptions)
+
+	// MarkRoomRead 標記房間已讀（messageID 為空時標記至最新訊息）
+	MarkRoomRead(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*models.ReadStateResponse, *models.MessageOptions)
+
+	// GetAttachment 獲取訊息附件（需具備附件所屬房間的存取權限）
+	GetAttachment(ctx context.Context, userID string, fileID string) (*models.MessageAttachment, *models.MessageOptions)
+
+	// CanAccessAttachment 檢查用戶能否存取引用任一指定檔案的訊息所屬房間
+	CanAccessAttachment(ctx context.Context, userID string, fileIDs []string) (bool, *models.MessageOptions)
+
+	// LeaveServerRooms 將用戶的連線移出伺服器所有頻道房間（被踢出或封鎖時使用）
+	LeaveServerRooms(ctx context.Context, userID string, serverID string) *models.MessageOptions
 }
 
 // ServerService 定義了伺服器服務的接口
@@ -131,6 +192,45 @@ type ServerService interface {
 
 	// LeaveServer 離開伺服器
 	LeaveServer(userID string, serverID string) *models.MessageOptions
+
+	// CreateInvite 創建伺服器邀請碼
+	CreateInvite(userID string, serverID string, request models.CreateServerInviteRequest) (*models.ServerInviteResponse, *models.MessageOptions)
+
+	// GetServerInvites 獲取伺服器仍有效的邀請列表
+	GetServerInvites(userID string, serverID string) ([]models.ServerInviteResponse, *models.MessageOptions)
+
+	// RevokeInvite 撤銷邀請碼
+	RevokeInvite(userID string, serverID string, inviteID string) *models.MessageOptions
+
+	// AcceptInvite 透過邀請碼加入伺服器
+	AcceptInvite(userID string, code string) (*models.ServerResponse, *models.MessageOptions)
+}
+
+// ModerationService 定義伺服器成員管理（踢出、封鎖、禁言）的接口
+type ModerationService interface {
+	// KickMember 將成員踢出伺服器
+	KickMember(ctx context.Context, userID string, serverID string, targetUserID string, request models.KickMemberRequest) *models.MessageOptions
+
+	// BanMember 封鎖用戶（成員會一併被移出伺服器）
+	BanMember(ctx context.Context, userID string, serverID string, targetUserID string, request models.BanMemberRequest) *models.MessageOptions
+
+	// UnbanMember 解除封鎖
+	UnbanMember(ctx context.Context, userID string, serverID string, targetUserID string) *models.MessageOptions
+
+	// GetServerBans 獲取伺服器仍有效的封鎖列表
+	GetServerBans(ctx context.Context, userID string, serverID string) ([]models.ServerBanResponse, *models.MessageOptions)
+
+	// TimeoutMember 禁言成員，期間無法發送訊息
+	TimeoutMember(ctx context.Context, userID string, serverID string, targetUserID string, request models.TimeoutMemberRequest) *models.MessageOptions
+
+	// RemoveTimeout 解除成員禁言
+	RemoveTimeout(ctx context.Context, userID string, serverID string, targetUserID string) *models.MessageOptions
+}
+
+// AuditLogService 定義伺服器稽核紀錄查詢的接口（紀錄由各服務透過 AuditLogRepository 寫入）
+type AuditLogService interface {
+	// GetServerAuditLogs 依動作類型與操作者篩選伺服器稽核紀錄，以游標分頁
+	GetServerAuditLogs(ctx context.Context, userID string, serverID string, query models.AuditLogQuery) (*models.AuditLogResults, *models.MessageOptions)
 }
 
 type FriendService interface {
@@ -166,8 +266,8 @@ type FriendService interface {
 }
 
 type ChannelService interface {
-	// GetChannelsByServerID 根據伺服器ID獲取頻道列表
-	GetChannelsByServerID(userID string, serverID string) ([]models.ChannelResponse, *models.MessageOptions)
+	// GetChannelsByServerID 根據伺服器ID獲取頻道樹（類別與類別內的頻道皆依排序位置排列）
+	GetChannelsByServerID(userID string, serverID string) (*models.ChannelTreeResponse, *models.MessageOptions)
 
 	// GetChannelByID 根據頻道ID獲取頻道詳細信息
 	GetChannelByID(userID string, channelID string) (*models.ChannelResponse, *models.MessageOptions)
@@ -180,6 +280,69 @@ type ChannelService interface {
 
 	// DeleteChannel 刪除頻道
 	DeleteChannel(userID string, channelID string) *models.MessageOptions
+
+	// CreateCategory 創建頻道類別，新類別排在最後
+	CreateCategory(userID string, serverID string, request models.CreateChannelCategoryRequest) (*models.ChannelCategoryResponse, *models.MessageOptions)
+
+	// UpdateCategory 重新命名頻道類別
+	UpdateCategory(userID string, categoryID string, request models.UpdateChannelCategoryRequest) (*models.ChannelCategoryResponse, *models.MessageOptions)
+
+	// DeleteCategory 刪除頻道類別，類別內的頻道移到未分類
+	DeleteCategory(userID string, categoryID string) *models.MessageOptions
+
+	// ReorderChannels 批量調整類別與頻道排序（可跨類別移動頻道），整批全部套用或全部不套用
+	ReorderChannels(userID string, serverID string, request models.ReorderChannelsRequest) (*models.ChannelTreeResponse, *models.MessageOptions)
+}
+
+// PermissionService 伺服器權限的集中檢查與角色管理
+type PermissionService interface {
+	// GetMemberPermissions 計算用戶在伺服器中的有效權限（擁有者擁有全部權限）
+	GetMemberPermissions(ctx context.Context, serverID string, userID string) (models.Permission, *models.MessageOptions)
+
+	// CheckPermission 檢查用戶在伺服器中是否具有指定權限
+	CheckPermission(ctx context.Context, serverID string, userID string, required models.Permission) *models.MessageOptions
+
+	// CheckMemberPosition 檢查用戶的最高角色位階是否高於目標成員（擁有者不受限制，且無法被其他成員管理）
+	CheckMemberPosition(ctx context.Context, serverID string, userID string, targetUserID string) *models.MessageOptions
+
+	// GetChannelPermissions 計算用戶在頻道中的有效權限（伺服器權限套用頻道覆寫後的結果）
+	GetChannelPermissions(ctx context.Context, channelID string, userID string) (models.Permission, *models.MessageOptions)
+
+	// CheckChannelPermission 檢查用戶在頻道中是否具有指定權限（套用頻道覆寫）
+	CheckChannelPermission(ctx context.Context, channelID string, userID string, required models.Permission) *models.MessageOptions
+
+	// FilterViewableChannels 從同一伺服器的頻道中篩選出用戶可查看的頻道
+	FilterViewableChannels(ctx context.Context, serverID string, userID string, channels []models.Channel) ([]models.Channel, *models.MessageOptions)
+
+	// GetMyPermissions 獲取用戶在伺服器中的有效權限名稱
+	GetMyPermissions(ctx context.Context, userID string, serverID string) (*models.MemberPermissionsResponse, *models.MessageOptions)
+
+	// GetServerRoles 獲取伺服器角色列表
+	GetServerRoles(ctx context.Context, userID string, serverID string) ([]models.ServerRoleResponse, *models.MessageOptions)
+
+	// CreateRole 創建伺服器角色
+	CreateRole(ctx context.Context, userID string, serverID string, request models.CreateServerRoleRequest) (*models.ServerRoleResponse, *models.MessageOptions)
+
+	// UpdateRole 更新伺服器角色
+	UpdateRole(ctx context.Context, userID string, serverID string, roleID string, request models.UpdateServerRoleRequest) (*models.ServerRoleResponse, *models.MessageOptions)
+
+	// DeleteRole 刪除伺服器角色
+	DeleteRole(ctx context.Context, userID string, serverID string, roleID string) *models.MessageOptions
+
+	// AssignRole 將角色指派給成員
+	AssignRole(ctx context.Context, userID string, serverID string, roleID string, targetUserID string) *models.MessageOptions
+
+	// UnassignRole 移除成員的角色
+	UnassignRole(ctx context.Context, userID string, serverID string, roleID string, targetUserID string) *models.MessageOptions
+
+	// GetChannelOverwrites 獲取頻道權限覆寫列表
+	GetChannelOverwrites(ctx context.Context, userID string, channelID string) ([]models.ChannelOverwriteResponse, *models.MessageOptions)
+
+	// SetChannelOverwrite 新增或取代頻道權限覆寫，返回更新後的覆寫列表
+	SetChannelOverwrite(ctx context.Context, userID string, channelID string, request models.ChannelOverwriteRequest) ([]models.ChannelOverwriteResponse, *models.MessageOptions)
+
+	// DeleteChannelOverwrite 刪除頻道權限覆寫
+	DeleteChannelOverwrite(ctx context.Context, userID string, channelID string, targetType string, targetID string) *models.MessageOptions
 }
 
 // FileUploadService - 負責業務邏輯和安全驗證
@@ -189,6 +352,7 @@ type FileUploadService interface {
 	UploadAvatar(file multipart.File, header *multipart.FileHeader, userID string) (*models.FileResult, *models.MessageOptions)
 	UploadDocument(file multipart.File, header *multipart.FileHeader, userID string) (*models.FileResult, *models.MessageOptions)
 	UploadFileWithConfig(file multipart.File, header *multipart.FileHeader, userID string, config *models.FileUploadConfig) (*models.FileResult, *models.MessageOptions)
+	UploadServerFile(file multipart.File, header *multipart.FileHeader, userID string, serverID string, config *models.FileUploadConfig) (*models.FileResult, *models.MessageOptions)
 
 	// 驗證方法
 	ValidateFile(header *multipart.FileHeader) *models.MessageOptions
@@ -204,14 +368,34 @@ type FileUploadService interface {
 	DeleteFileByID(fileID string, userID string) *models.MessageOptions
 	GetFileInfo(filePath string) (*models.FileInfo, *models.MessageOptions)
 	GetFileURLByID(fileID string) (string, *models.MessageOptions)
+	GetFileDownloadURL(fileID string) (string, *models.MessageOptions)
 	GetFileInfoByID(fileID string) (*models.UploadedFile, *models.MessageOptions)
+	GetFileVariant(fileID string, size string) (*models.FileVariantResponse, *models.MessageOptions)
 	GetUserFiles(userID string) ([]*models.UploadedFile, *models.MessageOptions)
+	ResolveServedFile(filePath string) (*models.ServedFile, *models.MessageOptions)
+	OpenFileContent(filePath string) (io.ReadCloser, *models.MessageOptions)
+	MarkFileForCleanup(fileID string) *models.MessageOptions
 	CleanupExpiredFiles() *models.MessageOptions
+
+	// 儲存配額方法
+	GetStorageUsage(userID string, serverID string) (*models.StorageUsageResponse, *models.MessageOptions)
+
+	// 分段上傳（可續傳）方法
+	InitiateChunkedUpload(userID string, request models.InitiateChunkedUploadRequest) (*models.ChunkedUploadResponse, *models.MessageOptions)
+	UploadChunk(userID string, uploadID string, chunkNumber int, reader io.Reader, size int64) (*models.ChunkedUploadResponse, *models.MessageOptions)
+	GetChunkedUpload(userID string, uploadID string) (*models.ChunkedUploadResponse, *models.MessageOptions)
+	CompleteChunkedUpload(userID string, uploadID string) (*models.FileResult, *models.MessageOptions)
+	AbortChunkedUpload(userID string, uploadID string) *models.MessageOptions
+	CleanupExpiredChunkedUploads() *models.MessageOptions
+
+	// 預簽名直接上傳方法
+	InitiatePresignedUpload(userID string, request models.PresignedUploadRequest) (*models.PresignedUploadResponse, *models.MessageOptions)
+	ConfirmPresignedUpload(userID string, uploadID string) (*models.FileResult, *models.MessageOptions)
 }
 
 type WebSocketHandler interface {
 	// HandleWebSocket 處理 WebSocket 連接
-	HandleWebSocket(ws *websocket.Conn, userID string)
+	HandleWebSocket(ws *websocket.Conn, userID string, sessionID string)
 }
 
 // --- WebSocket Handler Dependencies ---
@@ -221,9 +405,16 @@ type ClientManager interface {
 	Register(client *Client)
 	Unregister(client *Client)
 	GetClient(userID string) (*Client, bool)
+	GetUserClients(userID string) []*Client
 	GetAllClients() map[*Client]bool
 	IsUserOnline(userID string) bool
+	DisconnectSession(userID string, sessionID string)
 	StartHealthChecker(ctx context.Context)
+
+	// 跨實例連線管理事件
+	OnClientEvent(action string, handler func(ClientEvent))
+	PublishClientEvent(event ClientEvent)
+	StartEventSubscriber(ctx context.Context)
 }
 
 // RoomManager defines the interface for room management.
@@ -239,6 +430,13 @@ type RoomManager interface {
 // MessageHandler defines the interface for handling messages.
 type MessageHandler interface {
 	HandleMessage(message *MessageResponse)
+	EditMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, content string) (*MessageResponse, *models.MessageOptions)
+	DeleteMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*MessageResponse, *models.MessageOptions)
+	MarkRead(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*models.ReadStateResponse, *models.MessageOptions)
+	HandleTyping(ctx context.Context, userID string, roomType models.RoomType, roomID string, isTyping bool) *models.MessageOptions
+	ResolveMessageReference(ctx context.Context, message *MessageResponse) *models.MessageOptions
+	ResolveAttachments(ctx context.Context, message *MessageResponse, fileIDs []string) *models.MessageOptions
+	UpdateReaction(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, emoji string, add bool) (*MessageResponse, *models.MessageOptions)
 }
 
 // WebSocketODM defines the interface for WebSocket-related database operations.
@@ -5,27 +5,44 @@ import (
 	"chat_app_backend/app/providers"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // messageHandler 處理消息相關邏輯
 type messageHandler struct {
-	odm         providers.ODM
-	roomManager RoomManager
-	redisClient *redis.Client
+	odm               providers.ODM
+	roomManager       RoomManager
+	redisClient       *redis.Client
+	permissionService PermissionService
+	fileUploadService FileUploadService
+	// 輸入中狀態的過期計時器，key 為 房間:用戶
+	typingTimers  map[string]*time.Timer
+	typingTimeout time.Duration
+	typingMutex   sync.Mutex
 }
 
 // NewMessageHandler 創建新的消息處理器
-func NewMessageHandler(odm providers.ODM, roomManager RoomManager, redisClient *redis.Client) *messageHandler {
+func NewMessageHandler(odm providers.ODM, roomManager RoomManager, redisClient *redis.Client, permissionService PermissionService, fileUploadService FileUploadService) *messageHandler {
 	return &messageHandler{
-		odm:         odm,
-		roomManager: roomManager,
-		redisClient: redisClient,
+		odm:               odm,
+		roomManager:       roomManager,
+		redisClient:       redisClient,
+		permissionService: permissionService,
+		fileUploadService: fileUploadService,
+		typingTimers:      make(map[string]*time.Timer),
+		typingTimeout:     TypingTimeout,
 	}
 }
 
@@ -37,10 +54,715 @@ func (mh *messageHandler) HandleMessage(message *MessageResponse) {
 		return
 	}
 
+	// 訊息送出即結束輸入中狀態，客戶端收到新訊息時會自行清除提示
+	mh.clearTyping(RoomKey{Type: message.RoomType, RoomID: message.RoomID}, message.SenderID)
+
+	// 所有實例收到後會根據 senderID 決定是 message_sent 還是 new_message
+	mh.publishToRoom("new_message", message)
+}
+
+// ResolveMessageReference 驗證新訊息的引用與討論串，並補齊所屬討論串
+// 引用討論串內的訊息時，新訊息會自動歸入同一個討論串
+func (mh *messageHandler) ResolveMessageReference(ctx context.Context, message *MessageResponse) *models.MessageOptions {
+	if message.ReplyToMessageID != "" {
+		replyTo, msgOpt := mh.findRoomMessage(ctx, message.SenderID, message.RoomType, message.RoomID, message.ReplyToMessageID)
+		if msgOpt != nil {
+			return msgOpt
+		}
+
+		// 引用的訊息所屬的討論串（父訊息本身則為自己的討論串）
+		replyThreadID := replyTo.ThreadID
+		if replyThreadID.IsZero() && message.ThreadID == replyTo.ID.Hex() {
+			replyThreadID = replyTo.ID
+		}
+
+		switch {
+		case message.ThreadID == "" && !replyTo.ThreadID.IsZero():
+			message.ThreadID = replyTo.ThreadID.Hex()
+		case message.ThreadID != "" && !replyThreadID.IsZero() && replyThreadID.Hex() != message.ThreadID:
+			return &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "引用的訊息不在此討論串中",
+			}
+		}
+	}
+
+	if message.ThreadID == "" {
+		return nil
+	}
+
+	parent, msgOpt := mh.findRoomMessage(ctx, message.SenderID, message.RoomType, message.RoomID, message.ThreadID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	if !parent.ThreadID.IsZero() {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無法在討論串回覆中建立討論串",
+		}
+	}
+
+	if parent.IsDeleted {
+		return &models.MessageOptions{
+			Code:    models.ErrMessageDeleted,
+			Message: "訊息已被刪除",
+		}
+	}
+
+	return nil
+}
+
+// ResolveAttachments 驗證附件皆為發送者上傳且已驗證的檔案，並填入附件中繼資料
+func (mh *messageHandler) ResolveAttachments(ctx context.Context, message *MessageResponse, fileIDs []string) *models.MessageOptions {
+	if len(fileIDs) == 0 {
+		return nil
+	}
+
+	if len(fileIDs) > MaxAttachments {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: fmt.Sprintf("附件數量不能超過 %d 個", MaxAttachments),
+		}
+	}
+
+	if mh.fileUploadService == nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "檔案服務未初始化",
+		}
+	}
+
+	attachments := make([]models.MessageAttachment, 0, len(fileIDs))
+	seen := make(map[string]bool, len(fileIDs))
+	for _, fileID := range fileIDs {
+		if _, err := primitive.ObjectIDFromHex(fileID); err != nil {
+			return &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "無效的附件ID格式",
+				Details: err.Error(),
+			}
+		}
+		if seen[fileID] {
+			return &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "附件不能重複",
+			}
+		}
+		seen[fileID] = true
+
+		file, msgOpt := mh.fileUploadService.GetFileInfoByID(fileID)
+		if msgOpt != nil {
+			if msgOpt.Code == models.ErrNotFound {
+				return &models.MessageOptions{
+					Code:    models.ErrInvalidAttachment,
+					Message: "附件不存在",
+				}
+			}
+			return msgOpt
+		}
+
+		// 只能附加自己上傳且尚未標記清理的檔案
+		if file.UserID.Hex() != message.SenderID || file.ExpiresAt != nil {
+			return &models.MessageOptions{
+				Code:    models.ErrInvalidAttachment,
+				Message: "附件不存在",
+			}
+		}
+
+		url, msgOpt := mh.fileUploadService.GetFileDownloadURL(fileID)
+		if msgOpt != nil {
+			return &models.MessageOptions{
+				Code:    models.ErrInvalidAttachment,
+				Message: "附件尚未驗證或已被刪除",
+				Details: msgOpt.Message,
+			}
+		}
+
+		attachments = append(attachments, models.MessageAttachment{
+			FileID:   file.ID,
+			FileName: file.OriginalName,
+			FileSize: file.FileSize,
+			MimeType: file.MimeType,
+			URL:      url,
+			Width:    file.Width,
+			Height:   file.Height,
+		})
+	}
+
+	message.Attachments = attachments
+	return nil
+}
+
+// attachmentsWithURLs 為附件產生新的下載連結，避免回傳已過期的預簽名網址
+// 檔案已被刪除或無法產生連結時保留空網址，客戶端可透過附件端點重新取得
+func attachmentsWithURLs(fileUploadService FileUploadService, attachments []models.MessageAttachment) []models.MessageAttachment {
+	if len(attachments) == 0 || fileUploadService == nil {
+		return attachments
+	}
+
+	result := make([]models.MessageAttachment, len(attachments))
+	for i, attachment := range attachments {
+		if url, msgOpt := fileUploadService.GetFileDownloadURL(attachment.FileID.Hex()); msgOpt == nil {
+			attachment.URL = url
+		}
+		result[i] = attachment
+	}
+	return result
+}
+
+// HandleTyping 處理輸入中狀態，僅廣播不儲存
+// 開始後若在過期時間內沒有再次刷新，伺服器會自動廣播 typing_stopped
+func (mh *messageHandler) HandleTyping(ctx context.Context, userID string, roomType models.RoomType, roomID string, isTyping bool) *models.MessageOptions {
+	if roomType != models.RoomTypeChannel && roomType != models.RoomTypeDM {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的房間類型",
+		}
+	}
+
+	if _, err := primitive.ObjectIDFromHex(roomID); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的房間ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	if msgOpt := mh.checkRoomAccess(ctx, userID, roomType, roomID); msgOpt != nil {
+		return msgOpt
+	}
+
+	roomKey := RoomKey{Type: roomType, RoomID: roomID}
+	if !isTyping {
+		if mh.clearTyping(roomKey, userID) {
+			mh.publishTypingStopped(roomKey, userID)
+		}
+		return nil
+	}
+
+	// 已在輸入中時只重設計時器，避免每次刷新都廣播
+	if mh.refreshTyping(roomKey, userID) {
+		mh.publishRoomEvent(roomKey, "typing_started", &TypingResponse{
+			RoomType:  roomType,
+			RoomID:    roomID,
+			SenderID:  userID,
+			ExpiresIn: mh.typingTimeout.Milliseconds(),
+		})
+	}
+	return nil
+}
+
+// refreshTyping 啟動或重設輸入中計時器，回傳是否為新的輸入中狀態
+func (mh *messageHandler) refreshTyping(roomKey RoomKey, userID string) bool {
+	key := roomKey.String() + ":" + userID
+
+	mh.typingMutex.Lock()
+	defer mh.typingMutex.Unlock()
+
+	if timer, exists := mh.typingTimers[key]; exists {
+		timer.Reset(mh.typingTimeout)
+		return false
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(mh.typingTimeout, func() {
+		mh.typingMutex.Lock()
+		// 計時器可能已被清除或替換
+		if mh.typingTimers[key] != timer {
+			mh.typingMutex.Unlock()
+			return
+		}
+		delete(mh.typingTimers, key)
+		mh.typingMutex.Unlock()
+
+		mh.publishTypingStopped(roomKey, userID)
+	})
+	mh.typingTimers[key] = timer
+	return true
+}
+
+// clearTyping 清除輸入中計時器，回傳原本是否處於輸入中
+func (mh *messageHandler) clearTyping(roomKey RoomKey, userID string) bool {
+	key := roomKey.String() + ":" + userID
+
+	mh.typingMutex.Lock()
+	defer mh.typingMutex.Unlock()
+
+	timer, exists := mh.typingTimers[key]
+	if !exists {
+		return false
+	}
+	timer.Stop()
+	delete(mh.typingTimers, key)
+	return true
+}
+
+// publishTypingStopped 廣播輸入結束事件
+func (mh *messageHandler) publishTypingStopped(roomKey RoomKey, userID string) {
+	mh.publishRoomEvent(roomKey, "typing_stopped", &TypingResponse{
+		RoomType: roomKey.Type,
+		RoomID:   roomKey.RoomID,
+		SenderID: userID,
+	})
+}
+
+// EditMessage 編輯訊息（僅限發送者），成功後廣播 message_edited 事件
+func (mh *messageHandler) EditMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, content string) (*MessageResponse, *models.MessageOptions) {
+	if strings.TrimSpace(content) == "" {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "訊息內容不能為空",
+		}
+	}
+
+	message, msgOpt := mh.findRoomMessage(ctx, userID, roomType, roomID, messageID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if message.IsDeleted {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrMessageDeleted,
+			Message: "訊息已被刪除",
+		}
+	}
+
+	if message.SenderID.Hex() != userID {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNoPermission,
+			Message: "只有發送者可以編輯訊息",
+		}
+	}
+
+	// 編輯後的內容使用 @everyone / @here 同樣需要提及所有人權限
+	if message.RoomType == models.RoomTypeChannel && mentionsEveryone(content) {
+		if msgOpt := mh.permissionService.CheckChannelPermission(ctx, roomID, userID, models.PermissionMentionEveryone); msgOpt != nil {
+			if msgOpt.Code == models.ErrInternalServer {
+				return nil, msgOpt
+			}
+			return nil, &models.MessageOptions{
+				Code:    models.ErrNoPermission,
+				Message: "沒有權限使用 @everyone 或 @here",
+			}
+		}
+	}
+
+	now := time.Now()
+	if err := mh.odm.UpdateFields(ctx, message, bson.M{
+		"content":   content,
+		"edited_at": now,
+	}); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "編輯訊息失敗",
+			Details: err.Error(),
+		}
+	}
+
+	message.Content = content
+	message.EditedAt = &now
+
+	response := mh.newMessageResponse(message)
+	mh.publishToRoom("message_edited", response)
+	return response, nil
+}
+
+// DeleteMessage 刪除訊息（發送者或伺服器管理員），以墓碑保留並廣播 message_deleted 事件
+func (mh *messageHandler) DeleteMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*MessageResponse, *models.MessageOptions) {
+	message, msgOpt := mh.findRoomMessage(ctx, userID, roomType, roomID, messageID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if message.IsDeleted {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrMessageDeleted,
+			Message: "訊息已被刪除",
+		}
+	}
+
+	if message.SenderID.Hex() != userID {
+		canManage, err := mh.canManageRoomMessages(ctx, userID, message)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "檢查刪除權限失敗",
+				Details: err.Error(),
+			}
+		}
+		if !canManage {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrNoPermission,
+				Message: "沒有權限刪除此訊息",
+			}
+		}
+	}
+
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+	now := time.Now()
+	if err := mh.odm.UpdateFields(ctx, message, bson.M{
+		"content":     "",
+		"attachments": nil,
+		"is_deleted":  true,
+		"deleted_at":  now,
+		"deleted_by":  userObjectID,
+	}); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "刪除訊息失敗",
+			Details: err.Error(),
+		}
+	}
+
+	attachments := message.Attachments
+	message.Content = ""
+	message.Attachments = nil
+	message.IsDeleted = true
+	message.DeletedAt = &now
+	message.DeletedBy = userObjectID
+
+	mh.releaseAttachments(ctx, message.ID, attachments)
+
+	response := mh.newMessageResponse(message)
+	mh.publishToRoom("message_deleted", response)
+	return response, nil
+}
+
+// releaseAttachments 將已刪除訊息的附件標記為待清理，仍被其他訊息引用的檔案會保留
+// 訊息已刪除，標記失敗只記錄日誌
+func (mh *messageHandler) releaseAttachments(ctx context.Context, messageID primitive.ObjectID, attachments []models.MessageAttachment) {
+	if len(attachments) == 0 || mh.fileUploadService == nil {
+		return
+	}
+
+	for _, attachment := range attachments {
+		inUse, err := mh.odm.Exists(ctx, bson.M{
+			"_id":                 bson.M{"$ne": messageID},
+			"is_deleted":          false,
+			"attachments.file_id": attachment.FileID,
+		}, &models.Message{})
+		if err != nil {
+			slog.Warn("檢查附件引用失敗", "file_id", attachment.FileID.Hex(), "error", err)
+			continue
+		}
+		if inUse {
+			continue
+		}
+
+		if msgOpt := mh.fileUploadService.MarkFileForCleanup(attachment.FileID.Hex()); msgOpt != nil {
+			slog.Warn("標記附件待清理失敗", "file_id", attachment.FileID.Hex(), "error", msgOpt.Details)
+		}
+	}
+}
+
+// MarkRead 記錄用戶在房間的最後已讀訊息，並同步到該用戶的其他連線裝置
+// messageID 為空時標記為房間最新一則訊息；已讀位置只會往前推進
+func (mh *messageHandler) MarkRead(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*models.ReadStateResponse, *models.MessageOptions) {
+	if roomType != models.RoomTypeChannel && roomType != models.RoomTypeDM {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的房間類型",
+		}
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的用戶ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	var message *models.Message
+	if messageID != "" {
+		var msgOpt *models.MessageOptions
+		message, msgOpt = mh.findRoomMessage(ctx, userID, roomType, roomID, messageID)
+		if msgOpt != nil {
+			return nil, msgOpt
+		}
+	} else {
+		var msgOpt *models.MessageOptions
+		message, msgOpt = mh.findLatestRoomMessage(ctx, userID, roomType, roomID)
+		if msgOpt != nil {
+			return nil, msgOpt
+		}
+	}
+
+	roomObjectID, _ := primitive.ObjectIDFromHex(roomID)
+	now := time.Now()
+
+	roomRead := &models.RoomReads{}
+	err = mh.odm.FindOne(ctx, bson.M{"user_id": userObjectID, "room_id": roomObjectID}, roomRead)
+	switch {
+	case err == nil:
+		// 已讀位置不倒退（ObjectID 依時間遞增）
+		if message == nil || (!roomRead.LastReadMessageID.IsZero() && roomRead.LastReadMessageID.Hex() >= message.ID.Hex()) {
+			return newReadStateResponse(roomRead), nil
+		}
+		if err := mh.odm.UpdateFields(ctx, roomRead, bson.M{
+			"last_read_message_id": message.ID,
+			"last_read_at":         now,
+		}); err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "更新已讀狀態失敗",
+				Details: err.Error(),
+			}
+		}
+		roomRead.LastReadMessageID = message.ID
+		roomRead.LastReadAt = now
+	case errors.Is(err, providers.ErrDocumentNotFound):
+		roomRead = &models.RoomReads{
+			UserID:     userObjectID,
+			RoomID:     roomObjectID,
+			RoomType:   roomType,
+			LastReadAt: now,
+		}
+		if message != nil {
+			roomRead.LastReadMessageID = message.ID
+		}
+		if err := mh.odm.Create(ctx, roomRead); err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "建立已讀狀態失敗",
+				Details: err.Error(),
+			}
+		}
+	default:
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "查詢已讀狀態失敗",
+			Details: err.Error(),
+		}
+	}
+
+	response := newReadStateResponse(roomRead)
+	mh.publishRoomEvent(RoomKey{Type: userRoomType, RoomID: userID}, "read_state_updated", response)
+	return response, nil
+}
+
+// UpdateReaction 新增或移除訊息的表情回應，並透過房間頻道廣播 reaction_added / reaction_removed
+func (mh *messageHandler) UpdateReaction(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, emoji string, add bool) (*MessageResponse, *models.MessageOptions) {
+	if msgOpt := validateReactionEmoji(emoji); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	message, msgOpt := mh.findRoomMessage(ctx, userID, roomType, roomID, messageID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if message.IsDeleted {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrMessageDeleted,
+			Message: "訊息已被刪除",
+		}
+	}
+
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+	reacted := containsObjectID(message.Reactions[emoji], userObjectID)
+
+	// 狀態未變更時直接回傳目前的彙總
+	if add == reacted {
+		response := mh.newMessageResponse(message)
+		response.Reactions = summarizeReactions(message.Reactions, userID)
+		return response, nil
+	}
+
+	if add && len(message.Reactions[emoji]) == 0 && countReactionTypes(message.Reactions) >= MaxReactionTypes {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrTooManyReactions,
+			Message: "訊息表情回應種類已達上限",
+		}
+	}
+
+	// 以 $addToSet / $pull 原子更新，避免並發回應互相覆蓋
+	field := "reactions." + emoji
+	update := bson.M{"$pull": bson.M{field: userObjectID}}
+	if add {
+		update = bson.M{"$addToSet": bson.M{field: userObjectID}}
+	}
+	if err := mh.odm.UpdateMany(ctx, message, bson.M{"_id": message.ID}, update); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "更新表情回應失敗",
+			Details: err.Error(),
+		}
+	}
+
+	// 重新讀取以取得包含其他用戶並發變更的最新數量
+	updated := &models.Message{}
+	if err := mh.odm.FindByID(ctx, messageID, updated); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "查詢訊息失敗",
+			Details: err.Error(),
+		}
+	}
+
+	action := "reaction_removed"
+	if add {
+		action = "reaction_added"
+	}
+	mh.publishRoomEvent(RoomKey{Type: roomType, RoomID: roomID}, action, &ReactionEvent{
+		RoomType:  roomType,
+		RoomID:    roomID,
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+		Count:     int64(len(updated.Reactions[emoji])),
+	})
+
+	response := mh.newMessageResponse(updated)
+	response.Reactions = summarizeReactions(updated.Reactions, userID)
+	return response, nil
+}
+
+// findLatestRoomMessage 取得房間最新一則訊息，房間沒有訊息時回傳 nil
+func (mh *messageHandler) findLatestRoomMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string) (*models.Message, *models.MessageOptions) {
+	roomObjectID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的房間ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	if msgOpt := mh.checkRoomAccess(ctx, userID, roomType, roomID); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	limit := int64(1)
+	var messages []models.Message
+	err = mh.odm.FindWithOptions(ctx, bson.M{"room_id": roomObjectID, "room_type": roomType}, &messages, &providers.QueryOptions{
+		Sort:  bson.D{{Key: "_id", Value: -1}},
+		Limit: &limit,
+	})
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "查詢訊息失敗",
+			Details: err.Error(),
+		}
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	return &messages[0], nil
+}
+
+// newReadStateResponse 將已讀紀錄轉換為回應格式
+func newReadStateResponse(roomRead *models.RoomReads) *models.ReadStateResponse {
+	response := &models.ReadStateResponse{
+		RoomType:   roomRead.RoomType,
+		RoomID:     roomRead.RoomID.Hex(),
+		LastReadAt: roomRead.LastReadAt.UnixMilli(),
+	}
+	if !roomRead.LastReadMessageID.IsZero() {
+		response.LastReadMessageID = roomRead.LastReadMessageID.Hex()
+	}
+	return response
+}
+
+// findRoomMessage 取得指定房間內的訊息，並確認用戶仍可存取該房間
+func (mh *messageHandler) findRoomMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*models.Message, *models.MessageOptions) {
+	roomObjectID, err := primitive.ObjectIDFromHex(roomID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的房間ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	if _, err := primitive.ObjectIDFromHex(messageID); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的訊息ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	if msgOpt := mh.checkRoomAccess(ctx, userID, roomType, roomID); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	message := &models.Message{}
+	if err := mh.odm.FindByID(ctx, messageID, message); err != nil {
+		if errors.Is(err, providers.ErrDocumentNotFound) {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrMessageNotFound,
+				Message: "訊息不存在",
+			}
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "查詢訊息失敗",
+			Details: err.Error(),
+		}
+	}
+
+	// 訊息必須屬於路徑上的房間，避免跨房間操作
+	if message.RoomID != roomObjectID || message.RoomType != roomType {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrMessageNotFound,
+			Message: "訊息不存在",
+		}
+	}
+
+	return message, nil
+}
+
+// checkRoomAccess 確認用戶可存取指定房間
+func (mh *messageHandler) checkRoomAccess(ctx context.Context, userID string, roomType models.RoomType, roomID string) *models.MessageOptions {
+	allowed, err := mh.roomManager.CheckUserAllowedJoinRoom(ctx, userID, roomID, roomType)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "檢查房間權限失敗",
+			Details: err.Error(),
+		}
+	}
+	if !allowed {
+		return &models.MessageOptions{
+			Code:    models.ErrNoPermission,
+			Message: "您沒有權限存取此房間",
+		}
+	}
+	return nil
+}
+
+// canManageRoomMessages 檢查用戶是否可管理房間內他人的訊息（具有頻道所屬伺服器的管理訊息權限）
+func (mh *messageHandler) canManageRoomMessages(ctx context.Context, userID string, message *models.Message) (bool, error) {
+	// 私聊沒有管理員，只有發送者能刪除
+	if message.RoomType != models.RoomTypeChannel {
+		return false, nil
+	}
+
+	msgOpt := mh.permissionService.CheckChannelPermission(ctx, message.RoomID.Hex(), userID, models.PermissionManageMessages)
+	if msgOpt == nil {
+		return true, nil
+	}
+	if msgOpt.Code == models.ErrInternalServer {
+		return false, fmt.Errorf("%s: %v", msgOpt.Message, msgOpt.Details)
+	}
+	return false, nil
+}
+
+// publishToRoom 透過 Redis Pub/Sub 將訊息事件發送給所有訂閱的實例
+func (mh *messageHandler) publishToRoom(action string, message *MessageResponse) {
+	mh.publishRoomEvent(RoomKey{Type: message.RoomType, RoomID: message.RoomID}, action, message)
+}
+
+// publishRoomEvent 透過 Redis Pub/Sub 將任意房間事件發送給所有訂閱的實例
+func (mh *messageHandler) publishRoomEvent(roomKey RoomKey, action string, data any) {
 	// 構建要發送的訊息結構
-	wsMsg := &WsMessage[*MessageResponse]{
-		Action: "new_message", // 所有實例收到後會根據 senderID 決定是 message_sent 還是 new_message
-		Data:   message,
+	wsMsg := &WsMessage[any]{
+		Action: action,
+		Data:   data,
 	}
 
 	// 序列化訊息
@@ -50,33 +772,40 @@ func (mh *messageHandler) HandleMessage(message *MessageResponse) {
 		return
 	}
 
-	// 構建 room key
-	roomKey := RoomKey{Type: message.RoomType, RoomID: message.RoomID}
 	channel := "room:" + roomKey.String()
 
 	// 透過 Redis Publish 發送訊息給所有訂閱的實例
 	ctx := context.Background()
 	if mh.redisClient == nil {
-		mh.localBroadcast(message)
+		mh.localBroadcast(roomKey, action, data)
 		return
 	}
 	if err := mh.redisClient.Publish(ctx, channel, msgJSON).Err(); err != nil {
 		slog.Error("Redis Publish 失敗", "error", err)
 		// 如果 Redis 失敗，回退到本地廣播
-		mh.localBroadcast(message)
+		mh.localBroadcast(roomKey, action, data)
 		return
 	}
 	//nolint:gosec // channel 與 HOSTNAME 為內部受控變數，無日誌注入風險
-	slog.Info("[跨實例廣播] Publish", "channel", channel, "instance", os.Getenv("HOSTNAME"))
+	slog.Info("[跨實例廣播] Publish", "channel", channel, "instance", os.Getenv("HOSTNAME"), "action", action)
 }
 
 // localBroadcast 本地廣播（Redis 失敗時的回退方案）
-func (mh *messageHandler) localBroadcast(message *MessageResponse) {
-	room, exists := mh.roomManager.GetRoom(message.RoomType, message.RoomID)
+func (mh *messageHandler) localBroadcast(roomKey RoomKey, action string, data any) {
+	room, exists := mh.roomManager.GetRoom(roomKey.Type, roomKey.RoomID)
 	if !exists {
 		return
 	}
 
+	// 訊息與輸入中事件需要依發送者區分動作
+	senderID := ""
+	switch event := data.(type) {
+	case *MessageResponse:
+		senderID = event.SenderID
+	case *TypingResponse:
+		senderID = event.SenderID
+	}
+
 	room.Mutex.RLock()
 	clients := make([]*Client, 0, len(room.Clients))
 	for client := range room.Clients {
@@ -85,31 +814,141 @@ func (mh *messageHandler) localBroadcast(message *MessageResponse) {
 	room.Mutex.RUnlock()
 
 	for _, client := range clients {
+		if !shouldDeliverToClient(action, senderID, client.UserID) {
+			continue
+		}
 		go func(c *Client) {
 			if !mh.isClientConnectionValid(c) {
-				go mh.roomManager.LeaveRoom(c, message.RoomType, message.RoomID)
+				go mh.roomManager.LeaveRoom(c, roomKey.Type, roomKey.RoomID)
 				return
 			}
 
-			var action string
-			if c.UserID == message.SenderID {
-				action = "message_sent"
-			} else {
-				action = "new_message"
-			}
-
-			outMsg := &WsMessage[*MessageResponse]{
-				Action: action,
-				Data:   message,
+			outMsg := &WsMessage[any]{
+				Action: resolveClientAction(action, senderID, c.UserID),
+				Data:   data,
 			}
 
 			if err := c.SendMessage(outMsg); err != nil {
-				go mh.roomManager.LeaveRoom(c, message.RoomType, message.RoomID)
+				go mh.roomManager.LeaveRoom(c, roomKey.Type, roomKey.RoomID)
 			}
 		}(client)
 	}
 }
 
+// resolveClientAction 決定送往各客戶端的動作名稱
+// 新訊息對發送者回報 message_sent，其餘事件（編輯、刪除等）維持原動作
+func resolveClientAction(action string, senderID string, userID string) string {
+	if action != "new_message" {
+		return action
+	}
+	if userID == senderID {
+		return "message_sent"
+	}
+	return "new_message"
+}
+
+// shouldDeliverToClient 決定事件是否送往該客戶端
+// 輸入中狀態只通知房間內的其他成員，不回送給發送者
+func shouldDeliverToClient(action string, senderID string, userID string) bool {
+	switch action {
+	case "typing_started", "typing_stopped":
+		return userID != senderID
+	default:
+		return true
+	}
+}
+
+// validateReactionEmoji 檢查表情回應格式，emoji 會作為文件欄位名稱，不可包含 . 或以 $ 開頭
+func validateReactionEmoji(emoji string) *models.MessageOptions {
+	invalid := emoji == "" ||
+		len(emoji) > MaxEmojiLength ||
+		!utf8.ValidString(emoji) ||
+		strings.HasPrefix(emoji, "$") ||
+		strings.ContainsAny(emoji, ". \t\r\n")
+	if invalid {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的表情回應",
+		}
+	}
+	return nil
+}
+
+// summarizeReactions 將表情回應彙總為數量，並標記指定用戶是否已回應
+// 依數量由多到少排序，數量相同時依表情排序，確保回傳順序穩定
+func summarizeReactions(reactions map[string][]primitive.ObjectID, userID string) []models.ReactionSummary {
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+
+	summaries := make([]models.ReactionSummary, 0, len(reactions))
+	for emoji, userIDs := range reactions {
+		if len(userIDs) == 0 {
+			continue
+		}
+		summaries = append(summaries, models.ReactionSummary{
+			Emoji:   emoji,
+			Count:   int64(len(userIDs)),
+			Reacted: !userObjectID.IsZero() && containsObjectID(userIDs, userObjectID),
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Count != summaries[j].Count {
+			return summaries[i].Count > summaries[j].Count
+		}
+		return summaries[i].Emoji < summaries[j].Emoji
+	})
+	return summaries
+}
+
+// countReactionTypes 計算仍有回應的表情種類數
+func countReactionTypes(reactions map[string][]primitive.ObjectID) int {
+	count := 0
+	for _, userIDs := range reactions {
+		if len(userIDs) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// containsObjectID 檢查 ID 是否在列表中
+func containsObjectID(ids []primitive.ObjectID, target primitive.ObjectID) bool {
+	for _, id := range ids {
+		if id == target {
+			return true
+		}
+	}
+	return false
+}
+
+// newMessageResponse 將資料庫訊息轉換為 WebSocket 訊息格式
+func (mh *messageHandler) newMessageResponse(message *models.Message) *MessageResponse {
+	response := &MessageResponse{
+		ID:          message.ID.Hex(),
+		RoomType:    message.RoomType,
+		RoomID:      message.RoomID.Hex(),
+		SenderID:    message.SenderID.Hex(),
+		Content:     message.Content,
+		Timestamp:   message.CreatedAt.UnixMilli(),
+		IsDeleted:   message.IsDeleted,
+		Attachments: attachmentsWithURLs(mh.fileUploadService, message.Attachments),
+	}
+	if message.EditedAt != nil {
+		response.EditedAt = message.EditedAt.UnixMilli()
+	}
+	if !message.ReplyToMessageID.IsZero() {
+		response.ReplyToMessageID = message.ReplyToMessageID.Hex()
+	}
+	if !message.ThreadID.IsZero() {
+		response.ThreadID = message.ThreadID.Hex()
+	}
+	response.ReplyCount = message.ReplyCount
+	if message.LastReplyAt != nil {
+		response.LastReplyAt = message.LastReplyAt.UnixMilli()
+	}
+	return response
+}
+
 // saveMessageToDB 儲存消息到資料庫
 func (mh *messageHandler) saveMessageToDB(data *MessageResponse) error {
 	roomObjectID, err := primitive.ObjectIDFromHex(data.RoomID)
@@ -127,6 +966,20 @@ func (mh *messageHandler) saveMessageToDB(data *MessageResponse) error {
 		SenderID: senderObjectID,
 		Content:  data.Content,
 		RoomType: data.RoomType,
+		// 附件已由 ResolveAttachments 驗證
+		Attachments: data.Attachments,
+	}
+
+	// 引用與討論串已由 ResolveMessageReference 驗證
+	if data.ReplyToMessageID != "" {
+		if message.ReplyToMessageID, err = primitive.ObjectIDFromHex(data.ReplyToMessageID); err != nil {
+			return err
+		}
+	}
+	if data.ThreadID != "" {
+		if message.ThreadID, err = primitive.ObjectIDFromHex(data.ThreadID); err != nil {
+			return err
+		}
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -136,12 +989,47 @@ func (mh *messageHandler) saveMessageToDB(data *MessageResponse) error {
 	if err != nil {
 		return err
 	}
+	data.ID = message.ID.Hex()
+
+	if !message.ThreadID.IsZero() {
+		mh.updateThreadParent(ctx, message, data)
+	}
 
 	MessagesSavedTotal.WithLabelValues(string(data.RoomType)).Inc()
 	mh.updateRoomLastMessage(data.RoomID, data.RoomType)
 	return nil
 }
 
+// updateThreadParent 更新父訊息的回覆數與最後回覆時間，並推送給討論串參與者
+func (mh *messageHandler) updateThreadParent(ctx context.Context, message *models.Message, data *MessageResponse) {
+	parent := &models.Message{}
+	err := mh.odm.UpdateMany(ctx, parent, bson.M{"_id": message.ThreadID}, bson.M{
+		"$inc":      bson.M{"reply_count": 1},
+		"$set":      bson.M{"last_reply_at": message.CreatedAt},
+		"$addToSet": bson.M{"thread_participants": message.SenderID},
+	})
+	if err != nil {
+		slog.Warn("更新討論串父訊息失敗", "thread_id", message.ThreadID.Hex(), "error", err)
+		return
+	}
+
+	if err := mh.odm.FindByID(ctx, message.ThreadID.Hex(), parent); err != nil {
+		slog.Warn("查詢討論串父訊息失敗", "thread_id", message.ThreadID.Hex(), "error", err)
+		return
+	}
+
+	// 參與者包含父訊息發送者與所有回覆者，透過個人房間推送到各自的所有裝置
+	participants := map[primitive.ObjectID]bool{parent.SenderID: true}
+	for _, participantID := range parent.ThreadParticipants {
+		participants[participantID] = true
+	}
+	delete(participants, message.SenderID)
+
+	for participantID := range participants {
+		mh.publishRoomEvent(RoomKey{Type: userRoomType, RoomID: participantID.Hex()}, "thread_reply", data)
+	}
+}
+
 // updateRoomLastMessage 更新房間的最後訊息時間
 func (mh *messageHandler) updateRoomLastMessage(roomID string, roomType models.RoomType) {
 	roomObjectID, err := primitive.ObjectIDFromHex(roomID)
@@ -1,18 +1,25 @@
 package services
 
 import (
+	"chat_app_backend/app/mocks"
 	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"context"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // TestNewMessageHandler 測試創建消息處理器
 func TestNewMessageHandler(t *testing.T) {
 	// 由於 NewMessageHandler 需要 ODM 和 RoomManager，我們傳入 nil 進行基本測試
-	handler := NewMessageHandler(nil, nil, nil)
+	handler := NewMessageHandler(nil, nil, nil, nil, nil)
 
 	assert.NotNil(t, handler)
 }
@@ -20,7 +27,7 @@ func TestNewMessageHandler(t *testing.T) {
 // TestIsClientConnectionValid 測試檢查客戶端連線有效性
 func TestIsClientConnectionValid(t *testing.T) {
 	mockConn := &websocket.Conn{}
-	handler := NewMessageHandler(nil, nil, nil)
+	handler := NewMessageHandler(nil, nil, nil, nil, nil)
 
 	tests := []struct {
 		name     string
@@ -107,7 +114,7 @@ func TestIsClientConnectionValid(t *testing.T) {
 // TestIsClientConnectionValid_WithRealTime 測試實際時間場景
 func TestIsClientConnectionValid_WithRealTime(t *testing.T) {
 	mockConn := &websocket.Conn{}
-	handler := NewMessageHandler(nil, nil, nil)
+	handler := NewMessageHandler(nil, nil, nil, nil, nil)
 
 	t.Run("剛連線的客戶端應該有效", func(t *testing.T) {
 		client := &Client{
@@ -183,3 +190,762 @@ func TestMessageResponse_Structure(t *testing.T) {
 		assert.Equal(t, models.RoomTypeDM, dmMsg.RoomType)
 	})
 }
+
+// TestResolveClientAction 測試房間事件送往客戶端時的動作名稱
+func TestResolveClientAction(t *testing.T) {
+	assert.Equal(t, "message_sent", resolveClientAction("new_message", "user1", "user1"))
+	assert.Equal(t, "new_message", resolveClientAction("new_message", "user1", "user2"))
+	assert.Equal(t, "message_edited", resolveClientAction("message_edited", "user1", "user1"))
+	assert.Equal(t, "message_deleted", resolveClientAction("message_deleted", "user1", "user2"))
+}
+
+// TestEditMessage 測試編輯訊息
+func TestEditMessage(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	senderID := primitive.NewObjectID()
+	messageID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	newStoredMessage := func() models.Message {
+		return models.Message{
+			BaseModel: providers.BaseModel{ID: messageID, CreatedAt: time.Now()},
+			RoomType:  models.RoomTypeChannel,
+			RoomID:    roomID,
+			SenderID:  senderID,
+			Content:   "original",
+		}
+	}
+
+	t.Run("發送者成功編輯並廣播", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, senderID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = newStoredMessage()
+		}).Return(nil).Once()
+		mockODM.On("UpdateFields", ctx, mock.AnythingOfType("*models.Message"), mock.MatchedBy(func(fields bson.M) bool {
+			return fields["content"] == "edited" && fields["edited_at"] != nil
+		})).Return(nil).Once()
+		// 沒有 Redis 時回退到本地廣播
+		mockRM.On("GetRoom", models.RoomTypeChannel, roomID.Hex()).Return(nil, false).Once()
+
+		result, msgOpt := handler.EditMessage(ctx, senderID.Hex(), models.RoomTypeChannel, roomID.Hex(), messageID.Hex(), "edited")
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, messageID.Hex(), result.ID)
+		assert.Equal(t, "edited", result.Content)
+		assert.NotZero(t, result.EditedAt)
+		mockODM.AssertExpectations(t)
+		mockRM.AssertExpectations(t)
+	})
+
+	t.Run("非發送者無法編輯", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+		otherUserID := primitive.NewObjectID().Hex()
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, otherUserID, roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = newStoredMessage()
+		}).Return(nil).Once()
+
+		result, msgOpt := handler.EditMessage(ctx, otherUserID, models.RoomTypeChannel, roomID.Hex(), messageID.Hex(), "edited")
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrNoPermission, msgOpt.Code)
+		mockODM.AssertNotCalled(t, "UpdateFields", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("訊息不屬於該房間", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+		otherRoomID := primitive.NewObjectID().Hex()
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, senderID.Hex(), otherRoomID, models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = newStoredMessage()
+		}).Return(nil).Once()
+
+		result, msgOpt := handler.EditMessage(ctx, senderID.Hex(), models.RoomTypeChannel, otherRoomID, messageID.Hex(), "edited")
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrMessageNotFound, msgOpt.Code)
+	})
+
+	t.Run("內容為空", func(t *testing.T) {
+		handler := NewMessageHandler(nil, nil, nil, nil, nil)
+
+		result, msgOpt := handler.EditMessage(ctx, senderID.Hex(), models.RoomTypeChannel, roomID.Hex(), messageID.Hex(), "   ")
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+// TestDeleteMessage 測試刪除訊息
+func TestDeleteMessage(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	senderID := primitive.NewObjectID()
+	messageID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	newStoredMessage := func() models.Message {
+		return models.Message{
+			BaseModel: providers.BaseModel{ID: messageID, CreatedAt: time.Now()},
+			RoomType:  models.RoomTypeChannel,
+			RoomID:    roomID,
+			SenderID:  senderID,
+			Content:   "to be deleted",
+		}
+	}
+
+	t.Run("發送者刪除後保留墓碑", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, senderID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = newStoredMessage()
+		}).Return(nil).Once()
+		mockODM.On("UpdateFields", ctx, mock.AnythingOfType("*models.Message"), mock.MatchedBy(func(fields bson.M) bool {
+			return fields["is_deleted"] == true && fields["content"] == ""
+		})).Return(nil).Once()
+		mockRM.On("GetRoom", models.RoomTypeChannel, roomID.Hex()).Return(nil, false).Once()
+
+		result, msgOpt := handler.DeleteMessage(ctx, senderID.Hex(), models.RoomTypeChannel, roomID.Hex(), messageID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.True(t, result.IsDeleted)
+		assert.Empty(t, result.Content)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("刪除後將未被其他訊息引用的附件標記待清理", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		mockFS := new(mocks.FileUploadService)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, mockFS)
+
+		releasedID := primitive.NewObjectID()
+		sharedID := primitive.NewObjectID()
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, senderID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			message := newStoredMessage()
+			message.Attachments = []models.MessageAttachment{{FileID: releasedID}, {FileID: sharedID}}
+			*args.Get(2).(*models.Message) = message
+		}).Return(nil).Once()
+		mockODM.On("UpdateFields", ctx, mock.AnythingOfType("*models.Message"), mock.MatchedBy(func(fields bson.M) bool {
+			value, exists := fields["attachments"]
+			return exists && value == nil && fields["is_deleted"] == true
+		})).Return(nil).Once()
+		mockODM.On("Exists", ctx, mock.MatchedBy(func(filter bson.M) bool {
+			return filter["attachments.file_id"] == releasedID
+		}), mock.AnythingOfType("*models.Message")).Return(false, nil).Once()
+		mockODM.On("Exists", ctx, mock.MatchedBy(func(filter bson.M) bool {
+			return filter["attachments.file_id"] == sharedID
+		}), mock.AnythingOfType("*models.Message")).Return(true, nil).Once()
+		mockFS.On("MarkFileForCleanup", releasedID.Hex()).Return(nil).Once()
+		mockRM.On("GetRoom", models.RoomTypeChannel, roomID.Hex()).Return(nil, false).Once()
+
+		result, msgOpt := handler.DeleteMessage(ctx, senderID.Hex(), models.RoomTypeChannel, roomID.Hex(), messageID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Empty(t, result.Attachments)
+		mockODM.AssertExpectations(t)
+		mockFS.AssertExpectations(t)
+		mockFS.AssertNotCalled(t, "MarkFileForCleanup", sharedID.Hex())
+	})
+
+	t.Run("伺服器管理員可刪除他人訊息", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		mockPS := new(mocks.PermissionService)
+		handler := NewMessageHandler(mockODM, mockRM, nil, mockPS, nil)
+		adminID := primitive.NewObjectID().Hex()
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, adminID, roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = newStoredMessage()
+		}).Return(nil).Once()
+		mockPS.On("CheckChannelPermission", ctx, roomID.Hex(), adminID, models.PermissionManageMessages).Return(nil).Once()
+		mockODM.On("UpdateFields", ctx, mock.AnythingOfType("*models.Message"), mock.Anything).Return(nil).Once()
+		mockRM.On("GetRoom", models.RoomTypeChannel, roomID.Hex()).Return(nil, false).Once()
+
+		result, msgOpt := handler.DeleteMessage(ctx, adminID, models.RoomTypeChannel, roomID.Hex(), messageID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.True(t, result.IsDeleted)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("一般成員無法刪除他人訊息", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		mockPS := new(mocks.PermissionService)
+		handler := NewMessageHandler(mockODM, mockRM, nil, mockPS, nil)
+		memberID := primitive.NewObjectID().Hex()
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, memberID, roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = newStoredMessage()
+		}).Return(nil).Once()
+		mockPS.On("CheckChannelPermission", ctx, roomID.Hex(), memberID, models.PermissionManageMessages).Return(&models.MessageOptions{Code: models.ErrNoServerPermission, Message: "權限不足"}).Once()
+
+		result, msgOpt := handler.DeleteMessage(ctx, memberID, models.RoomTypeChannel, roomID.Hex(), messageID.Hex())
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrNoPermission, msgOpt.Code)
+		mockODM.AssertNotCalled(t, "UpdateFields", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("無權限存取房間", func(t *testing.T) {
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(nil, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, senderID.Hex(), roomID.Hex(), models.RoomTypeDM).Return(false, nil).Once()
+
+		result, msgOpt := handler.DeleteMessage(ctx, senderID.Hex(), models.RoomTypeDM, roomID.Hex(), messageID.Hex())
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrNoPermission, msgOpt.Code)
+	})
+}
+
+func TestMarkRead(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	newRoomMessage := func(id primitive.ObjectID) models.Message {
+		return models.Message{
+			BaseModel: providers.BaseModel{ID: id, CreatedAt: time.Now()},
+			RoomType:  models.RoomTypeDM,
+			RoomID:    roomID,
+			SenderID:  primitive.NewObjectID(),
+			Content:   "hello",
+		}
+	}
+
+	t.Run("首次標記建立已讀紀錄並推送到個人房間", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+		messageID := primitive.NewObjectID()
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), roomID.Hex(), models.RoomTypeDM).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = newRoomMessage(messageID)
+		}).Return(nil).Once()
+		mockODM.On("FindOne", ctx, bson.M{"user_id": userID, "room_id": roomID}, mock.AnythingOfType("*models.RoomReads")).Return(providers.ErrDocumentNotFound).Once()
+		mockODM.On("Create", ctx, mock.MatchedBy(func(read *models.RoomReads) bool {
+			return read.LastReadMessageID == messageID && read.RoomType == models.RoomTypeDM
+		})).Return(nil).Once()
+		// 沒有 Redis 時回退到本地廣播至個人房間
+		mockRM.On("GetRoom", userRoomType, userID.Hex()).Return(nil, false).Once()
+
+		result, msgOpt := handler.MarkRead(ctx, userID.Hex(), models.RoomTypeDM, roomID.Hex(), messageID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, roomID.Hex(), result.RoomID)
+		assert.Equal(t, messageID.Hex(), result.LastReadMessageID)
+		mockODM.AssertExpectations(t)
+		mockRM.AssertExpectations(t)
+	})
+
+	t.Run("已讀位置不倒退", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+		olderID := primitive.NewObjectIDFromTimestamp(time.Now().Add(-time.Hour))
+		newerID := primitive.NewObjectID()
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), roomID.Hex(), models.RoomTypeDM).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, olderID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = newRoomMessage(olderID)
+		}).Return(nil).Once()
+		mockODM.On("FindOne", ctx, mock.Anything, mock.AnythingOfType("*models.RoomReads")).Run(func(args mock.Arguments) {
+			read := args.Get(2).(*models.RoomReads)
+			read.RoomID = roomID
+			read.RoomType = models.RoomTypeDM
+			read.LastReadMessageID = newerID
+		}).Return(nil).Once()
+
+		result, msgOpt := handler.MarkRead(ctx, userID.Hex(), models.RoomTypeDM, roomID.Hex(), olderID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, newerID.Hex(), result.LastReadMessageID)
+		mockODM.AssertNotCalled(t, "UpdateFields", mock.Anything, mock.Anything, mock.Anything)
+		mockRM.AssertNotCalled(t, "GetRoom", mock.Anything, mock.Anything)
+	})
+
+	t.Run("未指定訊息時標記至最新訊息", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+		olderID := primitive.NewObjectIDFromTimestamp(time.Now().Add(-time.Hour))
+		latestID := primitive.NewObjectID()
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), roomID.Hex(), models.RoomTypeDM).Return(true, nil).Once()
+		mockODM.On("FindWithOptions", ctx, mock.Anything, mock.AnythingOfType("*[]models.Message"), mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.Message) = []models.Message{newRoomMessage(latestID)}
+		}).Return(nil).Once()
+		mockODM.On("FindOne", ctx, mock.Anything, mock.AnythingOfType("*models.RoomReads")).Run(func(args mock.Arguments) {
+			read := args.Get(2).(*models.RoomReads)
+			read.RoomID = roomID
+			read.RoomType = models.RoomTypeDM
+			read.LastReadMessageID = olderID
+		}).Return(nil).Once()
+		mockODM.On("UpdateFields", ctx, mock.AnythingOfType("*models.RoomReads"), mock.MatchedBy(func(fields bson.M) bool {
+			return fields["last_read_message_id"] == latestID
+		})).Return(nil).Once()
+		mockRM.On("GetRoom", userRoomType, userID.Hex()).Return(nil, false).Once()
+
+		result, msgOpt := handler.MarkRead(ctx, userID.Hex(), models.RoomTypeDM, roomID.Hex(), "")
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, latestID.Hex(), result.LastReadMessageID)
+		mockODM.AssertExpectations(t)
+		mockRM.AssertExpectations(t)
+	})
+
+	t.Run("無效的房間類型", func(t *testing.T) {
+		handler := NewMessageHandler(nil, nil, nil, nil, nil)
+
+		result, msgOpt := handler.MarkRead(ctx, userID.Hex(), userRoomType, userID.Hex(), "")
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestShouldDeliverToClient(t *testing.T) {
+	assert.False(t, shouldDeliverToClient("typing_started", "user1", "user1"))
+	assert.True(t, shouldDeliverToClient("typing_started", "user1", "user2"))
+	assert.False(t, shouldDeliverToClient("typing_stopped", "user1", "user1"))
+	assert.True(t, shouldDeliverToClient("new_message", "user1", "user1"))
+}
+
+func TestMessageHandlerTyping(t *testing.T) {
+	roomID := primitive.NewObjectID().Hex()
+	userID := primitive.NewObjectID().Hex()
+	ctx := context.Background()
+
+	t.Run("開始輸入只廣播一次，刷新不重複廣播，且不寫入資料庫", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID, roomID, models.RoomTypeChannel).Return(true, nil).Times(3)
+		// 沒有 Redis 時回退到本地廣播：typing_started 與 typing_stopped 各一次
+		mockRM.On("GetRoom", models.RoomTypeChannel, roomID).Return(nil, false).Twice()
+
+		assert.Nil(t, handler.HandleTyping(ctx, userID, models.RoomTypeChannel, roomID, true))
+		assert.Nil(t, handler.HandleTyping(ctx, userID, models.RoomTypeChannel, roomID, true))
+		assert.Nil(t, handler.HandleTyping(ctx, userID, models.RoomTypeChannel, roomID, false))
+
+		assert.Empty(t, handler.typingTimers)
+		mockRM.AssertExpectations(t)
+		mockODM.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("未刷新時自動過期", func(t *testing.T) {
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(nil, mockRM, nil, nil, nil)
+		handler.typingTimeout = 20 * time.Millisecond
+
+		stopped := make(chan struct{}, 1)
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID, roomID, models.RoomTypeDM).Return(true, nil).Once()
+		mockRM.On("GetRoom", models.RoomTypeDM, roomID).Return(nil, false).Once()
+		mockRM.On("GetRoom", models.RoomTypeDM, roomID).Run(func(args mock.Arguments) {
+			stopped <- struct{}{}
+		}).Return(nil, false).Once()
+
+		assert.Nil(t, handler.HandleTyping(ctx, userID, models.RoomTypeDM, roomID, true))
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("輸入中狀態未自動過期")
+		}
+
+		handler.typingMutex.Lock()
+		assert.Empty(t, handler.typingTimers)
+		handler.typingMutex.Unlock()
+	})
+
+	t.Run("未輸入時停止不廣播", func(t *testing.T) {
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(nil, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID, roomID, models.RoomTypeDM).Return(true, nil).Once()
+
+		assert.Nil(t, handler.HandleTyping(ctx, userID, models.RoomTypeDM, roomID, false))
+		mockRM.AssertNotCalled(t, "GetRoom", mock.Anything, mock.Anything)
+	})
+
+	t.Run("無權限存取房間", func(t *testing.T) {
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(nil, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID, roomID, models.RoomTypeChannel).Return(false, nil).Once()
+
+		msgOpt := handler.HandleTyping(ctx, userID, models.RoomTypeChannel, roomID, true)
+
+		assert.Equal(t, models.ErrNoPermission, msgOpt.Code)
+		assert.Empty(t, handler.typingTimers)
+	})
+}
+
+func TestResolveMessageReference(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	senderID := primitive.NewObjectID()
+	parentID := primitive.NewObjectID()
+	replyID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	storedMessage := func(id primitive.ObjectID, threadID primitive.ObjectID) models.Message {
+		return models.Message{
+			BaseModel: providers.BaseModel{ID: id, CreatedAt: time.Now()},
+			RoomType:  models.RoomTypeChannel,
+			RoomID:    roomID,
+			SenderID:  primitive.NewObjectID(),
+			Content:   "hello",
+			ThreadID:  threadID,
+		}
+	}
+
+	t.Run("引用討論串內的訊息時歸入同一討論串", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, senderID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Twice()
+		mockODM.On("FindByID", ctx, replyID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = storedMessage(replyID, parentID)
+		}).Return(nil).Once()
+		mockODM.On("FindByID", ctx, parentID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = storedMessage(parentID, primitive.NilObjectID)
+		}).Return(nil).Once()
+
+		message := &MessageResponse{
+			RoomType:         models.RoomTypeChannel,
+			RoomID:           roomID.Hex(),
+			SenderID:         senderID.Hex(),
+			Content:          "quote",
+			ReplyToMessageID: replyID.Hex(),
+		}
+
+		msgOpt := handler.ResolveMessageReference(ctx, message)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, parentID.Hex(), message.ThreadID)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("不允許巢狀討論串", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, senderID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, replyID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = storedMessage(replyID, parentID)
+		}).Return(nil).Once()
+
+		msgOpt := handler.ResolveMessageReference(ctx, &MessageResponse{
+			RoomType: models.RoomTypeChannel,
+			RoomID:   roomID.Hex(),
+			SenderID: senderID.Hex(),
+			ThreadID: replyID.Hex(),
+		})
+
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+
+	t.Run("引用的訊息不在指定討論串", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, senderID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, replyID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = storedMessage(replyID, primitive.NewObjectID())
+		}).Return(nil).Once()
+
+		msgOpt := handler.ResolveMessageReference(ctx, &MessageResponse{
+			RoomType:         models.RoomTypeChannel,
+			RoomID:           roomID.Hex(),
+			SenderID:         senderID.Hex(),
+			ReplyToMessageID: replyID.Hex(),
+			ThreadID:         parentID.Hex(),
+		})
+
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestResolveAttachments(t *testing.T) {
+	ctx := context.Background()
+	senderID := primitive.NewObjectID()
+	fileID := primitive.NewObjectID()
+
+	newFile := func() *models.UploadedFile {
+		return &models.UploadedFile{
+			BaseModel:    providers.BaseModel{ID: fileID},
+			UserID:       senderID,
+			OriginalName: "report.pdf",
+			FileSize:     2048,
+			MimeType:     "application/pdf",
+			Status:       "verified",
+		}
+	}
+	newMessage := func() *MessageResponse {
+		return &MessageResponse{RoomType: models.RoomTypeChannel, RoomID: primitive.NewObjectID().Hex(), SenderID: senderID.Hex()}
+	}
+
+	t.Run("填入附件中繼資料", func(t *testing.T) {
+		mockFS := new(mocks.FileUploadService)
+		handler := NewMessageHandler(nil, nil, nil, nil, mockFS)
+		mockFS.On("GetFileInfoByID", fileID.Hex()).Return(newFile(), (*models.MessageOptions)(nil)).Once()
+		mockFS.On("GetFileDownloadURL", fileID.Hex()).Return("/uploads/files/report.pdf", nil).Once()
+
+		message := newMessage()
+		msgOpt := handler.ResolveAttachments(ctx, message, []string{fileID.Hex()})
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, []models.MessageAttachment{{
+			FileID:   fileID,
+			FileName: "report.pdf",
+			FileSize: 2048,
+			MimeType: "application/pdf",
+			URL:      "/uploads/files/report.pdf",
+		}}, message.Attachments)
+		mockFS.AssertExpectations(t)
+	})
+
+	t.Run("不能附加他人上傳的檔案", func(t *testing.T) {
+		mockFS := new(mocks.FileUploadService)
+		handler := NewMessageHandler(nil, nil, nil, nil, mockFS)
+		file := newFile()
+		file.UserID = primitive.NewObjectID()
+		mockFS.On("GetFileInfoByID", fileID.Hex()).Return(file, (*models.MessageOptions)(nil)).Once()
+
+		message := newMessage()
+		msgOpt := handler.ResolveAttachments(ctx, message, []string{fileID.Hex()})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidAttachment, msgOpt.Code)
+		assert.Empty(t, message.Attachments)
+		mockFS.AssertNotCalled(t, "GetFileDownloadURL", mock.Anything)
+	})
+
+	t.Run("檔案尚未驗證", func(t *testing.T) {
+		mockFS := new(mocks.FileUploadService)
+		handler := NewMessageHandler(nil, nil, nil, nil, mockFS)
+		mockFS.On("GetFileInfoByID", fileID.Hex()).Return(newFile(), (*models.MessageOptions)(nil)).Once()
+		mockFS.On("GetFileDownloadURL", fileID.Hex()).Return("", &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "檔案尚未驗證或已損壞",
+		}).Once()
+
+		msgOpt := handler.ResolveAttachments(ctx, newMessage(), []string{fileID.Hex()})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidAttachment, msgOpt.Code)
+	})
+
+	t.Run("附件不存在", func(t *testing.T) {
+		mockFS := new(mocks.FileUploadService)
+		handler := NewMessageHandler(nil, nil, nil, nil, mockFS)
+		mockFS.On("GetFileInfoByID", fileID.Hex()).Return(nil, &models.MessageOptions{Code: models.ErrNotFound}).Once()
+
+		msgOpt := handler.ResolveAttachments(ctx, newMessage(), []string{fileID.Hex()})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidAttachment, msgOpt.Code)
+	})
+
+	t.Run("格式錯誤、重複或超過數量上限", func(t *testing.T) {
+		mockFS := new(mocks.FileUploadService)
+		handler := NewMessageHandler(nil, nil, nil, nil, mockFS)
+
+		msgOpt := handler.ResolveAttachments(ctx, newMessage(), []string{"invalid"})
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+
+		mockFS.On("GetFileInfoByID", fileID.Hex()).Return(newFile(), (*models.MessageOptions)(nil)).Once()
+		mockFS.On("GetFileDownloadURL", fileID.Hex()).Return("/uploads/files/report.pdf", nil).Once()
+		msgOpt = handler.ResolveAttachments(ctx, newMessage(), []string{fileID.Hex(), fileID.Hex()})
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+
+		tooMany := make([]string, MaxAttachments+1)
+		for i := range tooMany {
+			tooMany[i] = primitive.NewObjectID().Hex()
+		}
+		msgOpt = handler.ResolveAttachments(ctx, newMessage(), tooMany)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestHandleMessage_ThreadReply(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	senderID := primitive.NewObjectID()
+	parentID := primitive.NewObjectID()
+	parentSenderID := primitive.NewObjectID()
+	otherReplierID := primitive.NewObjectID()
+
+	mockODM := new(mocks.ODM)
+	mockRM := new(mockRoomManager)
+	handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+
+	mockODM.On("Create", mock.Anything, mock.MatchedBy(func(message *models.Message) bool {
+		return message.ThreadID == parentID
+	})).Run(func(args mock.Arguments) {
+		message := args.Get(1).(*models.Message)
+		message.ID = primitive.NewObjectID()
+		message.CreatedAt = time.Now()
+	}).Return(nil).Once()
+	mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.Message"), bson.M{"_id": parentID}, mock.MatchedBy(func(update bson.M) bool {
+		return reflect.DeepEqual(update["$inc"], bson.M{"reply_count": 1})
+	})).Return(nil).Once()
+	mockODM.On("FindByID", mock.Anything, parentID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+		parent := args.Get(2).(*models.Message)
+		parent.SenderID = parentSenderID
+		parent.ThreadParticipants = []primitive.ObjectID{senderID, otherReplierID}
+	}).Return(nil).Once()
+	mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.Channel"), mock.Anything, mock.Anything).Return(nil).Once()
+
+	// 推送給父訊息發送者與其他回覆者的個人房間，不推送給自己
+	mockRM.On("GetRoom", userRoomType, parentSenderID.Hex()).Return(nil, false).Once()
+	mockRM.On("GetRoom", userRoomType, otherReplierID.Hex()).Return(nil, false).Once()
+	mockRM.On("GetRoom", models.RoomTypeChannel, roomID.Hex()).Return(nil, false).Once()
+
+	handler.HandleMessage(&MessageResponse{
+		RoomType: models.RoomTypeChannel,
+		RoomID:   roomID.Hex(),
+		SenderID: senderID.Hex(),
+		Content:  "reply",
+		ThreadID: parentID.Hex(),
+	})
+
+	mockODM.AssertExpectations(t)
+	mockRM.AssertExpectations(t)
+	mockRM.AssertNotCalled(t, "GetRoom", userRoomType, senderID.Hex())
+}
+
+func TestSummarizeReactions(t *testing.T) {
+	userID := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+
+	summaries := summarizeReactions(map[string][]primitive.ObjectID{
+		"🎉":  {otherID},
+		"👍":  {userID, otherID},
+		"😢":  {},
+		"❤️": {otherID},
+	}, userID.Hex())
+
+	assert.Equal(t, []models.ReactionSummary{
+		{Emoji: "👍", Count: 2, Reacted: true},
+		{Emoji: "❤️", Count: 1, Reacted: false},
+		{Emoji: "🎉", Count: 1, Reacted: false},
+	}, summaries)
+}
+
+func TestUpdateReaction(t *testing.T) {
+	roomID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+	messageID := primitive.NewObjectID()
+	ctx := context.Background()
+
+	storedMessage := func(reactions map[string][]primitive.ObjectID) models.Message {
+		return models.Message{
+			BaseModel: providers.BaseModel{ID: messageID, CreatedAt: time.Now()},
+			RoomType:  models.RoomTypeChannel,
+			RoomID:    roomID,
+			SenderID:  primitive.NewObjectID(),
+			Content:   "hello",
+			Reactions: reactions,
+		}
+	}
+
+	t.Run("新增回應並廣播", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+		otherID := primitive.NewObjectID()
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = storedMessage(map[string][]primitive.ObjectID{"👍": {otherID}})
+		}).Return(nil).Once()
+		mockODM.On("UpdateMany", ctx, mock.AnythingOfType("*models.Message"), bson.M{"_id": messageID}, bson.M{
+			"$addToSet": bson.M{"reactions.👍": userID},
+		}).Return(nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = storedMessage(map[string][]primitive.ObjectID{"👍": {otherID, userID}})
+		}).Return(nil).Once()
+		// 沒有 Redis 時回退到本地廣播
+		mockRM.On("GetRoom", models.RoomTypeChannel, roomID.Hex()).Return(nil, false).Once()
+
+		result, msgOpt := handler.UpdateReaction(ctx, userID.Hex(), models.RoomTypeChannel, roomID.Hex(), messageID.Hex(), "👍", true)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, []models.ReactionSummary{{Emoji: "👍", Count: 2, Reacted: true}}, result.Reactions)
+		mockODM.AssertExpectations(t)
+		mockRM.AssertExpectations(t)
+	})
+
+	t.Run("已回應時不重複更新", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = storedMessage(map[string][]primitive.ObjectID{"👍": {userID}})
+		}).Return(nil).Once()
+
+		result, msgOpt := handler.UpdateReaction(ctx, userID.Hex(), models.RoomTypeChannel, roomID.Hex(), messageID.Hex(), "👍", true)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, int64(1), result.Reactions[0].Count)
+		mockODM.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockRM.AssertNotCalled(t, "GetRoom", mock.Anything, mock.Anything)
+	})
+
+	t.Run("表情種類已達上限", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRM := new(mockRoomManager)
+		handler := NewMessageHandler(mockODM, mockRM, nil, nil, nil)
+
+		reactions := make(map[string][]primitive.ObjectID, MaxReactionTypes)
+		for i := 0; i < MaxReactionTypes; i++ {
+			reactions[string(rune('a'+i))] = []primitive.ObjectID{primitive.NewObjectID()}
+		}
+
+		mockRM.On("CheckUserAllowedJoinRoom", ctx, userID.Hex(), roomID.Hex(), models.RoomTypeChannel).Return(true, nil).Once()
+		mockODM.On("FindByID", ctx, messageID.Hex(), mock.AnythingOfType("*models.Message")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.Message) = storedMessage(reactions)
+		}).Return(nil).Once()
+
+		result, msgOpt := handler.UpdateReaction(ctx, userID.Hex(), models.RoomTypeChannel, roomID.Hex(), messageID.Hex(), "🔥", true)
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrTooManyReactions, msgOpt.Code)
+	})
+
+	t.Run("無效的表情", func(t *testing.T) {
+		handler := NewMessageHandler(nil, nil, nil, nil, nil)
+
+		for _, emoji := range []string{"", "$set", "a.b", "has space"} {
+			result, msgOpt := handler.UpdateReaction(ctx, userID.Hex(), models.RoomTypeChannel, roomID.Hex(), messageID.Hex(), emoji, true)
+			assert.Nil(t, result)
+			assert.Equal(t, models.ErrInvalidParams, msgOpt.Code, emoji)
+		}
+	})
+}
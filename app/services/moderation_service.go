@@ -0,0 +1,400 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"chat_app_backend/app/repositories"
+	"chat_app_backend/config"
+	"chat_app_backend/utils"
+	"context"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MaxModerationReasonLength = 512               // 管理動作原因長度上限（字元）
+	MaxTimeoutDuration        = 28 * 24 * 60 * 60 // 禁言時間上限（秒）
+)
+
+type moderationService struct {
+	config            *config.Config
+	serverRepo        repositories.ServerRepository
+	serverMemberRepo  repositories.ServerMemberRepository
+	userRepo          repositories.UserRepository
+	moderationRepo    repositories.ModerationRepository
+	permissionService PermissionService
+	chatService       ChatService             // 用於關閉目標用戶的 WebSocket 房間
+	cache             providers.CacheProvider // 用於清除成員伺服器列表快取
+	auditLogRepo      repositories.AuditLogRepository
+}
+
+func NewModerationService(cfg *config.Config,
+	serverRepo repositories.ServerRepository,
+	serverMemberRepo repositories.ServerMemberRepository,
+	userRepo repositories.UserRepository,
+	moderationRepo repositories.ModerationRepository,
+	permissionService PermissionService,
+	chatService ChatService,
+	cache providers.CacheProvider,
+	auditLogRepo repositories.AuditLogRepository,
+) *moderationService {
+	return &moderationService{
+		config:            cfg,
+		serverRepo:        serverRepo,
+		serverMemberRepo:  serverMemberRepo,
+		userRepo:          userRepo,
+		moderationRepo:    moderationRepo,
+		permissionService: permissionService,
+		chatService:       chatService,
+		cache:             cache,
+		auditLogRepo:      auditLogRepo,
+	}
+}
+
+// moderationTarget 管理動作的目標資訊
+type moderationTarget struct {
+	server   *models.Server
+	isMember bool
+}
+
+// KickMember 將成員踢出伺服器，需要踢出成員權限
+func (ms *moderationService) KickMember(ctx context.Context, userID string, serverID string, targetUserID string, request models.KickMemberRequest) *models.MessageOptions {
+	if msgOpt := validateModerationReason(request.Reason); msgOpt != nil {
+		return msgOpt
+	}
+
+	target, msgOpt := ms.authorize(ctx, userID, serverID, targetUserID, models.PermissionKickMembers)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	if !target.isMember {
+		return notServerMemberError()
+	}
+
+	if msgOpt := ms.removeMember(ctx, serverID, targetUserID); msgOpt != nil {
+		return msgOpt
+	}
+
+	ms.recordAction(serverID, userID, targetUserID, models.ModerationActionKick, request.Reason, 0)
+	return nil
+}
+
+// BanMember 封鎖用戶，需要封鎖成員權限；非成員也可預先封鎖
+func (ms *moderationService) BanMember(ctx context.Context, userID string, serverID string, targetUserID string, request models.BanMemberRequest) *models.MessageOptions {
+	if msgOpt := validateModerationReason(request.Reason); msgOpt != nil {
+		return msgOpt
+	}
+
+	target, msgOpt := ms.authorize(ctx, userID, serverID, targetUserID, models.PermissionBanMembers)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	actorObjectID, _ := primitive.ObjectIDFromHex(userID)
+	targetObjectID, err := primitive.ObjectIDFromHex(targetUserID)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的用戶ID",
+		}
+	}
+
+	var expiresAt int64
+	if request.Duration > 0 {
+		expiresAt = time.Now().Unix() + request.Duration
+	}
+
+	ban := &models.ServerBan{
+		ServerID:  target.server.ID,
+		UserID:    targetObjectID,
+		BannedBy:  actorObjectID,
+		Reason:    request.Reason,
+		ExpiresAt: expiresAt,
+	}
+	if err := ms.moderationRepo.SaveBan(ban); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "封鎖用戶失敗",
+			Details: err.Error(),
+		}
+	}
+
+	if target.isMember {
+		if msgOpt := ms.removeMember(ctx, serverID, targetUserID); msgOpt != nil {
+			return msgOpt
+		}
+	}
+
+	ms.recordAction(serverID, userID, targetUserID, models.ModerationActionBan, request.Reason, expiresAt)
+	return nil
+}
+
+// UnbanMember 解除封鎖，需要封鎖成員權限
+func (ms *moderationService) UnbanMember(ctx context.Context, userID string, serverID string, targetUserID string) *models.MessageOptions {
+	if msgOpt := ms.permissionService.CheckPermission(ctx, serverID, userID, models.PermissionBanMembers); msgOpt != nil {
+		return msgOpt
+	}
+
+	deleted, err := ms.moderationRepo.DeleteBan(serverID, targetUserID)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "解除封鎖失敗",
+			Details: err.Error(),
+		}
+	}
+	if !deleted {
+		return &models.MessageOptions{
+			Code:    models.ErrBanNotFound,
+			Message: "該用戶未被封鎖",
+		}
+	}
+
+	ms.recordAction(serverID, userID, targetUserID, models.ModerationActionUnban, "", 0)
+	return nil
+}
+
+// GetServerBans 獲取伺服器仍有效的封鎖列表，需要封鎖成員權限
+func (ms *moderationService) GetServerBans(ctx context.Context, userID string, serverID string) ([]models.ServerBanResponse, *models.MessageOptions) {
+	if msgOpt := ms.permissionService.CheckPermission(ctx, serverID, userID, models.PermissionBanMembers); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	bans, err := ms.moderationRepo.GetBansByServerID(serverID, time.Now().Unix())
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取封鎖列表失敗",
+			Details: err.Error(),
+		}
+	}
+
+	userIDs := make([]string, 0, len(bans))
+	for _, ban := range bans {
+		userIDs = append(userIDs, ban.UserID.Hex())
+	}
+
+	usernames := make(map[string]string, len(bans))
+	if len(userIDs) > 0 {
+		users, err := ms.userRepo.GetUserListByIds(userIDs)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "獲取用戶資訊失敗",
+				Details: err.Error(),
+			}
+		}
+		for _, user := range users {
+			usernames[user.ID.Hex()] = user.Username
+		}
+	}
+
+	responses := make([]models.ServerBanResponse, 0, len(bans))
+	for _, ban := range bans {
+		responses = append(responses, models.ServerBanResponse{
+			UserID:    ban.UserID.Hex(),
+			Username:  usernames[ban.UserID.Hex()],
+			BannedBy:  ban.BannedBy.Hex(),
+			Reason:    ban.Reason,
+			ExpiresAt: ban.ExpiresAt,
+			CreatedAt: ban.CreatedAt.Unix(),
+		})
+	}
+
+	return responses, nil
+}
+
+// TimeoutMember 禁言成員，需要禁言成員權限；重複禁言時以新的期限為準
+func (ms *moderationService) TimeoutMember(ctx context.Context, userID string, serverID string, targetUserID string, request models.TimeoutMemberRequest) *models.MessageOptions {
+	if msgOpt := validateModerationReason(request.Reason); msgOpt != nil {
+		return msgOpt
+	}
+	if request.Duration <= 0 || request.Duration > MaxTimeoutDuration {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "禁言時間最長為 28 天",
+		}
+	}
+
+	target, msgOpt := ms.authorize(ctx, userID, serverID, targetUserID, models.PermissionTimeoutMembers)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	if !target.isMember {
+		return notServerMemberError()
+	}
+
+	until := time.Now().Unix() + request.Duration
+	if err := ms.moderationRepo.SetMemberTimeout(serverID, targetUserID, until); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "禁言成員失敗",
+			Details: err.Error(),
+		}
+	}
+
+	ms.recordAction(serverID, userID, targetUserID, models.ModerationActionTimeout, request.Reason, until)
+	return nil
+}
+
+// RemoveTimeout 解除成員禁言，需要禁言成員權限
+func (ms *moderationService) RemoveTimeout(ctx context.Context, userID string, serverID string, targetUserID string) *models.MessageOptions {
+	target, msgOpt := ms.authorize(ctx, userID, serverID, targetUserID, models.PermissionTimeoutMembers)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	if !target.isMember {
+		return notServerMemberError()
+	}
+
+	if err := ms.moderationRepo.SetMemberTimeout(serverID, targetUserID, 0); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "解除禁言失敗",
+			Details: err.Error(),
+		}
+	}
+
+	ms.recordAction(serverID, userID, targetUserID, models.ModerationActionRemoveTimeout, "", 0)
+	return nil
+}
+
+// authorize 檢查操作者是否可對目標執行管理動作
+// 不可對自己或擁有者操作；非擁有者只能管理最高角色位階低於自己的成員
+func (ms *moderationService) authorize(ctx context.Context, userID string, serverID string, targetUserID string, required models.Permission) (*moderationTarget, *models.MessageOptions) {
+	if userID == targetUserID {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無法對自己執行此操作",
+		}
+	}
+
+	actorPermissions, msgOpt := ms.permissionService.GetMemberPermissions(ctx, serverID, userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+	if msgOpt := requirePermission(actorPermissions, required); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	server, err := ms.serverRepo.GetServerByID(ctx, serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrServerNotFound,
+			Message: "伺服器不存在",
+			Details: err.Error(),
+		}
+	}
+	if server.OwnerID.Hex() == targetUserID {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNoServerPermission,
+			Message: "無法對伺服器擁有者執行此操作",
+		}
+	}
+
+	// 非成員可預先封鎖，不需比較位階
+	if _, msgOpt := ms.permissionService.GetMemberPermissions(ctx, serverID, targetUserID); msgOpt != nil {
+		if msgOpt.Code == models.ErrNotServerMember {
+			return &moderationTarget{server: server}, nil
+		}
+		return nil, msgOpt
+	}
+
+	// 與角色管理相同以角色位階判斷上下關係，權限相同時位階較低者也無法管理位階較高的成員
+	if msgOpt := ms.permissionService.CheckMemberPosition(ctx, serverID, userID, targetUserID); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	return &moderationTarget{server: server, isMember: true}, nil
+}
+
+// removeMember 將成員移出伺服器，並關閉其在此伺服器的 WebSocket 房間
+func (ms *moderationService) removeMember(ctx context.Context, serverID string, targetUserID string) *models.MessageOptions {
+	err := ms.serverMemberRepo.RemoveMemberFromServer(serverID, targetUserID)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "移除成員失敗",
+			Details: err.Error(),
+		}
+	}
+
+	// 清除用戶的伺服器成員快取（已被移出伺服器）
+	if ms.cache != nil {
+		if cacheErr := ms.cache.Delete(utils.UserServersCacheKey(targetUserID)); cacheErr != nil {
+			slog.Warn("無法清理用戶伺服器列表快取", "user_id", targetUserID, "error", cacheErr)
+		}
+	}
+
+	// 更新伺服器成員數量快取
+	memberCount, err := ms.serverMemberRepo.GetMemberCount(serverID)
+	if err == nil {
+		if err := ms.serverRepo.UpdateMemberCount(serverID, int(memberCount)); err != nil {
+			slog.Warn("更新成員數量快取失敗", "server_id", serverID, "error", err)
+		}
+	}
+
+	if ms.chatService != nil {
+		if msgOpt := ms.chatService.LeaveServerRooms(ctx, targetUserID, serverID); msgOpt != nil {
+			slog.Warn("無法關閉成員的伺服器房間", "user_id", targetUserID, "server_id", serverID, "error", msgOpt.Message)
+		}
+	}
+
+	return nil
+}
+
+// moderationAuditActions 管理動作對應的稽核紀錄動作類型
+var moderationAuditActions = map[string]string{
+	models.ModerationActionKick:          models.AuditActionMemberKick,
+	models.ModerationActionBan:           models.AuditActionMemberBan,
+	models.ModerationActionUnban:         models.AuditActionMemberUnban,
+	models.ModerationActionTimeout:       models.AuditActionMemberTimeout,
+	models.ModerationActionRemoveTimeout: models.AuditActionMemberRemoveTimeout,
+}
+
+// recordAction 記錄管理動作並寫入稽核紀錄，動作本身已完成，記錄失敗只寫入日誌
+func (ms *moderationService) recordAction(serverID string, actorID string, targetUserID string, action string, reason string, expiresAt int64) {
+	serverObjectID, _ := primitive.ObjectIDFromHex(serverID)
+	actorObjectID, _ := primitive.ObjectIDFromHex(actorID)
+	targetObjectID, _ := primitive.ObjectIDFromHex(targetUserID)
+
+	err := ms.moderationRepo.CreateAction(&models.ModerationAction{
+		ServerID:  serverObjectID,
+		ActorID:   actorObjectID,
+		TargetID:  targetObjectID,
+		Action:    action,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		slog.Error("記錄管理動作失敗", "server_id", serverID, "action", action, "error", err)
+	}
+
+	var changes []models.AuditLogChange
+	if expiresAt > 0 {
+		changes = auditChanges(nil, map[string]any{"expires_at": expiresAt})
+	}
+	recordMemberAuditLog(ms.auditLogRepo, serverID, actorID, targetUserID, moderationAuditActions[action], reason, changes)
+}
+
+// validateModerationReason 檢查管理動作原因長度
+func validateModerationReason(reason string) *models.MessageOptions {
+	if utf8.RuneCountInString(reason) > MaxModerationReasonLength {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "原因長度不能超過 512 個字元",
+		}
+	}
+	return nil
+}
+
+// notServerMemberError 目標用戶不是伺服器成員
+func notServerMemberError() *models.MessageOptions {
+	return &models.MessageOptions{
+		Code:    models.ErrNotServerMember,
+		Message: "該用戶不是伺服器成員",
+	}
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mockModerationRepository 模擬 ModerationRepository
+type mockModerationRepository struct {
+	mock.Mock
+}
+
+func (m *mockModerationRepository) SaveBan(ban *models.ServerBan) error {
+	args := m.Called(ban)
+	return args.Error(0)
+}
+
+func (m *mockModerationRepository) GetActiveBan(serverID, userID string, now int64) (*models.ServerBan, error) {
+	args := m.Called(serverID, userID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServerBan), args.Error(1)
+}
+
+func (m *mockModerationRepository) GetBansByServerID(serverID string, now int64) ([]models.ServerBan, error) {
+	args := m.Called(serverID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ServerBan), args.Error(1)
+}
+
+func (m *mockModerationRepository) DeleteBan(serverID, userID string) (bool, error) {
+	args := m.Called(serverID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockModerationRepository) SetMemberTimeout(serverID, userID string, until int64) error {
+	args := m.Called(serverID, userID, until)
+	return args.Error(0)
+}
+
+func (m *mockModerationRepository) CreateAction(action *models.ModerationAction) error {
+	args := m.Called(action)
+	return args.Error(0)
+}
+
+// moderationTestFixture 管理動作測試共用的資料與 mock
+type moderationTestFixture struct {
+	service           *moderationService
+	serverRepo        *mockServerRepository
+	serverMemberRepo  *mocks.ServerMemberRepository
+	moderationRepo    *mockModerationRepository
+	permissionService *mocks.PermissionService
+	chatService       *mocks.ChatService
+	ownerID           primitive.ObjectID
+	actorID           primitive.ObjectID
+	targetID          primitive.ObjectID
+	serverID          primitive.ObjectID
+}
+
+func newModerationTestFixture() *moderationTestFixture {
+	f := &moderationTestFixture{
+		serverRepo:        new(mockServerRepository),
+		serverMemberRepo:  new(mocks.ServerMemberRepository),
+		moderationRepo:    new(mockModerationRepository),
+		permissionService: new(mocks.PermissionService),
+		chatService:       new(mocks.ChatService),
+		ownerID:           primitive.NewObjectID(),
+		actorID:           primitive.NewObjectID(),
+		targetID:          primitive.NewObjectID(),
+		serverID:          primitive.NewObjectID(),
+	}
+	f.service = NewModerationService(nil, f.serverRepo, f.serverMemberRepo, nil, f.moderationRepo, f.permissionService, f.chatService, nil, nil)
+	f.serverRepo.On("GetServerByID", mock.Anything, f.serverID.Hex()).
+		Return(&models.Server{BaseModel: providers.BaseModel{ID: f.serverID}, OwnerID: f.ownerID}, nil).Maybe()
+	return f
+}
+
+// givenPermissions 設定操作者與目標的伺服器權限，操作者位階高於目標
+func (f *moderationTestFixture) givenPermissions(actor models.Permission, target models.Permission) {
+	f.permissionService.On("GetMemberPermissions", mock.Anything, f.serverID.Hex(), f.actorID.Hex()).Return(actor, nil)
+	f.permissionService.On("GetMemberPermissions", mock.Anything, f.serverID.Hex(), f.targetID.Hex()).Return(target, nil)
+	f.permissionService.On("CheckMemberPosition", mock.Anything, f.serverID.Hex(), f.actorID.Hex(), f.targetID.Hex()).Return(nil).Maybe()
+}
+
+// useRolePositions 改用實際的權限服務，以成員資料與角色計算權限與位階
+func (f *moderationTestFixture) useRolePositions(actor *models.ServerMember, target *models.ServerMember, roles []models.ServerRole) {
+	roleRepo := new(mockRoleRepository)
+	f.service.permissionService = NewPermissionService(nil, f.serverRepo, f.serverMemberRepo, roleRepo, nil, nil)
+	for _, member := range []*models.ServerMember{actor, target} {
+		f.serverMemberRepo.On("GetServerMember", mock.Anything, f.serverID.Hex(), member.UserID.Hex()).Return(member, nil)
+		if len(member.RoleIDs) == 0 {
+			continue
+		}
+		var memberRoles []models.ServerRole
+		for _, role := range roles {
+			if slices.Contains(member.RoleIDs, role.ID) {
+				memberRoles = append(memberRoles, role)
+			}
+		}
+		roleRepo.On("GetRolesByIDs", mock.Anything, roleIDsToHex(member.RoleIDs)).Return(memberRoles, nil)
+	}
+}
+
+// expectRemoval 預期目標被移出伺服器並關閉房間
+func (f *moderationTestFixture) expectRemoval() {
+	f.serverMemberRepo.On("RemoveMemberFromServer", f.serverID.Hex(), f.targetID.Hex()).Return(nil).Once()
+	f.serverMemberRepo.On("GetMemberCount", f.serverID.Hex()).Return(int64(9), nil).Once()
+	f.serverRepo.On("UpdateMemberCount", f.serverID.Hex(), 9).Return(nil).Once()
+	f.chatService.On("LeaveServerRooms", mock.Anything, f.targetID.Hex(), f.serverID.Hex()).Return(nil).Once()
+}
+
+// expectAction 預期記錄指定的管理動作
+func (f *moderationTestFixture) expectAction(action string) {
+	f.moderationRepo.On("CreateAction", mock.MatchedBy(func(record *models.ModerationAction) bool {
+		return record.Action == action && record.ActorID == f.actorID && record.TargetID == f.targetID && record.ServerID == f.serverID
+	})).Return(nil).Once()
+}
+
+func (f *moderationTestFixture) assertExpectations(t *testing.T) {
+	f.serverRepo.AssertExpectations(t)
+	f.serverMemberRepo.AssertExpectations(t)
+	f.moderationRepo.AssertExpectations(t)
+	f.permissionService.AssertExpectations(t)
+	f.chatService.AssertExpectations(t)
+}
+
+func TestKickMember(t *testing.T) {
+	t.Run("成功踢出成員並關閉房間", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.givenPermissions(models.DefaultMemberPermissions|models.PermissionKickMembers, models.DefaultMemberPermissions)
+		f.expectRemoval()
+		f.expectAction(models.ModerationActionKick)
+
+		msgOpt := f.service.KickMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.KickMemberRequest{Reason: "spam"})
+
+		assert.Nil(t, msgOpt)
+		f.assertExpectations(t)
+	})
+
+	t.Run("缺少踢出成員權限", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.permissionService.On("GetMemberPermissions", mock.Anything, f.serverID.Hex(), f.actorID.Hex()).Return(models.DefaultMemberPermissions, nil)
+
+		msgOpt := f.service.KickMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.KickMemberRequest{})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		f.serverMemberRepo.AssertNotCalled(t, "RemoveMemberFromServer", mock.Anything, mock.Anything)
+	})
+
+	t.Run("無法踢出位階不低於自身的成員", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.permissionService.On("GetMemberPermissions", mock.Anything, f.serverID.Hex(), f.actorID.Hex()).Return(models.DefaultMemberPermissions|models.PermissionKickMembers, nil)
+		f.permissionService.On("GetMemberPermissions", mock.Anything, f.serverID.Hex(), f.targetID.Hex()).Return(models.DefaultMemberPermissions, nil)
+		f.permissionService.On("CheckMemberPosition", mock.Anything, f.serverID.Hex(), f.actorID.Hex(), f.targetID.Hex()).
+			Return(&models.MessageOptions{Code: models.ErrNoServerPermission}).Once()
+
+		msgOpt := f.service.KickMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.KickMemberRequest{})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		f.serverMemberRepo.AssertNotCalled(t, "RemoveMemberFromServer", mock.Anything, mock.Anything)
+	})
+
+	t.Run("權限相同但位階較低時無法踢出", func(t *testing.T) {
+		f := newModerationTestFixture()
+		lowerRole := models.ServerRole{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: f.serverID, Permissions: models.PermissionKickMembers, Position: 2}
+		higherRole := models.ServerRole{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: f.serverID, Permissions: models.PermissionKickMembers, Position: 5}
+		f.useRolePositions(
+			&models.ServerMember{ServerID: f.serverID, UserID: f.actorID, Role: "member", RoleIDs: []primitive.ObjectID{lowerRole.ID}},
+			&models.ServerMember{ServerID: f.serverID, UserID: f.targetID, Role: "member", RoleIDs: []primitive.ObjectID{higherRole.ID}},
+			[]models.ServerRole{lowerRole, higherRole},
+		)
+
+		msgOpt := f.service.KickMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.KickMemberRequest{})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		f.serverMemberRepo.AssertNotCalled(t, "RemoveMemberFromServer", mock.Anything, mock.Anything)
+	})
+
+	t.Run("位階較高時可踢出權限相同的成員", func(t *testing.T) {
+		f := newModerationTestFixture()
+		lowerRole := models.ServerRole{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: f.serverID, Permissions: models.PermissionKickMembers, Position: 2}
+		higherRole := models.ServerRole{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: f.serverID, Permissions: models.PermissionKickMembers, Position: 5}
+		f.useRolePositions(
+			&models.ServerMember{ServerID: f.serverID, UserID: f.actorID, Role: "member", RoleIDs: []primitive.ObjectID{higherRole.ID}},
+			&models.ServerMember{ServerID: f.serverID, UserID: f.targetID, Role: "member", RoleIDs: []primitive.ObjectID{lowerRole.ID}},
+			[]models.ServerRole{lowerRole, higherRole},
+		)
+		f.expectRemoval()
+		f.expectAction(models.ModerationActionKick)
+
+		msgOpt := f.service.KickMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.KickMemberRequest{})
+
+		assert.Nil(t, msgOpt)
+		f.serverMemberRepo.AssertExpectations(t)
+	})
+
+	t.Run("無法踢出伺服器擁有者", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.permissionService.On("GetMemberPermissions", mock.Anything, f.serverID.Hex(), f.actorID.Hex()).Return(models.PermissionAll, nil)
+
+		msgOpt := f.service.KickMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.ownerID.Hex(), models.KickMemberRequest{})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+	})
+
+	t.Run("原因過長", func(t *testing.T) {
+		f := newModerationTestFixture()
+
+		msgOpt := f.service.KickMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(),
+			models.KickMemberRequest{Reason: strings.Repeat("a", MaxModerationReasonLength+1)})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestBanMember(t *testing.T) {
+	t.Run("封鎖成員並設定期限", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.givenPermissions(models.PermissionAll, models.DefaultMemberPermissions)
+		f.moderationRepo.On("SaveBan", mock.MatchedBy(func(ban *models.ServerBan) bool {
+			return ban.UserID == f.targetID && ban.BannedBy == f.actorID && ban.Reason == "raid" && ban.ExpiresAt > time.Now().Unix()
+		})).Return(nil).Once()
+		f.expectRemoval()
+		f.expectAction(models.ModerationActionBan)
+
+		msgOpt := f.service.BanMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.BanMemberRequest{Reason: "raid", Duration: 3600})
+
+		assert.Nil(t, msgOpt)
+		f.assertExpectations(t)
+	})
+
+	t.Run("舊版管理員之間無法互相封鎖", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.useRolePositions(
+			&models.ServerMember{ServerID: f.serverID, UserID: f.actorID, Role: "admin"},
+			&models.ServerMember{ServerID: f.serverID, UserID: f.targetID, Role: "admin"},
+			nil,
+		)
+
+		msgOpt := f.service.BanMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.BanMemberRequest{})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		f.moderationRepo.AssertNotCalled(t, "SaveBan", mock.Anything)
+		f.serverMemberRepo.AssertNotCalled(t, "RemoveMemberFromServer", mock.Anything, mock.Anything)
+	})
+
+	t.Run("預先封鎖非成員", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.permissionService.On("GetMemberPermissions", mock.Anything, f.serverID.Hex(), f.actorID.Hex()).Return(models.DefaultMemberPermissions|models.PermissionBanMembers, nil)
+		f.permissionService.On("GetMemberPermissions", mock.Anything, f.serverID.Hex(), f.targetID.Hex()).
+			Return(models.Permission(0), &models.MessageOptions{Code: models.ErrNotServerMember})
+		f.moderationRepo.On("SaveBan", mock.MatchedBy(func(ban *models.ServerBan) bool {
+			return ban.UserID == f.targetID && ban.ExpiresAt == 0
+		})).Return(nil).Once()
+		f.expectAction(models.ModerationActionBan)
+
+		msgOpt := f.service.BanMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.BanMemberRequest{})
+
+		assert.Nil(t, msgOpt)
+		f.serverMemberRepo.AssertNotCalled(t, "RemoveMemberFromServer", mock.Anything, mock.Anything)
+		f.chatService.AssertNotCalled(t, "LeaveServerRooms", mock.Anything, mock.Anything, mock.Anything)
+		f.moderationRepo.AssertExpectations(t)
+	})
+}
+
+func TestUnbanMember(t *testing.T) {
+	t.Run("成功解除封鎖", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.permissionService.On("CheckPermission", mock.Anything, f.serverID.Hex(), f.actorID.Hex(), models.PermissionBanMembers).Return(nil).Once()
+		f.moderationRepo.On("DeleteBan", f.serverID.Hex(), f.targetID.Hex()).Return(true, nil).Once()
+		f.expectAction(models.ModerationActionUnban)
+
+		msgOpt := f.service.UnbanMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex())
+
+		assert.Nil(t, msgOpt)
+		f.moderationRepo.AssertExpectations(t)
+	})
+
+	t.Run("用戶未被封鎖", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.permissionService.On("CheckPermission", mock.Anything, f.serverID.Hex(), f.actorID.Hex(), models.PermissionBanMembers).Return(nil).Once()
+		f.moderationRepo.On("DeleteBan", f.serverID.Hex(), f.targetID.Hex()).Return(false, nil).Once()
+
+		msgOpt := f.service.UnbanMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrBanNotFound, msgOpt.Code)
+		f.moderationRepo.AssertNotCalled(t, "CreateAction", mock.Anything)
+	})
+}
+
+func TestTimeoutMember(t *testing.T) {
+	t.Run("成功禁言成員", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.givenPermissions(models.DefaultMemberPermissions|models.PermissionTimeoutMembers, models.DefaultMemberPermissions)
+		f.moderationRepo.On("SetMemberTimeout", f.serverID.Hex(), f.targetID.Hex(), mock.MatchedBy(func(until int64) bool {
+			return until > time.Now().Unix()+500
+		})).Return(nil).Once()
+		f.expectAction(models.ModerationActionTimeout)
+
+		msgOpt := f.service.TimeoutMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.TimeoutMemberRequest{Duration: 600})
+
+		assert.Nil(t, msgOpt)
+		f.assertExpectations(t)
+	})
+
+	t.Run("禁言時間超過上限", func(t *testing.T) {
+		f := newModerationTestFixture()
+
+		msgOpt := f.service.TimeoutMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex(), models.TimeoutMemberRequest{Duration: MaxTimeoutDuration + 1})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+
+	t.Run("無法禁言自己", func(t *testing.T) {
+		f := newModerationTestFixture()
+
+		msgOpt := f.service.TimeoutMember(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.actorID.Hex(), models.TimeoutMemberRequest{Duration: 60})
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+
+	t.Run("解除禁言", func(t *testing.T) {
+		f := newModerationTestFixture()
+		f.givenPermissions(models.DefaultMemberPermissions|models.PermissionTimeoutMembers, models.PermissionViewChannel)
+		f.moderationRepo.On("SetMemberTimeout", f.serverID.Hex(), f.targetID.Hex(), int64(0)).Return(nil).Once()
+		f.expectAction(models.ModerationActionRemoveTimeout)
+
+		msgOpt := f.service.RemoveTimeout(context.Background(), f.actorID.Hex(), f.serverID.Hex(), f.targetID.Hex())
+
+		assert.Nil(t, msgOpt)
+		f.moderationRepo.AssertExpectations(t)
+	})
+}
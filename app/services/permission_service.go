@@ -0,0 +1,981 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"chat_app_backend/app/repositories"
+	"chat_app_backend/config"
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MaxServerRoles       = 50  // 每個伺服器的角色數量上限
+	MaxRoleNameLength    = 32  // 角色名稱長度上限（字元）
+	MaxChannelOverwrites = 100 // 每個頻道的權限覆寫數量上限
+)
+
+// 角色位階的特殊值，用於比較成員之間的位階
+const (
+	noRolePosition          = -1            // 沒有任何角色的成員
+	legacyAdminRolePosition = math.MaxInt32 // 舊資料中的 owner / admin 角色字串，高於所有自訂角色
+)
+
+type permissionService struct {
+	config           *config.Config
+	serverRepo       repositories.ServerRepository
+	serverMemberRepo repositories.ServerMemberRepository
+	roleRepo         repositories.RoleRepository
+	channelRepo      repositories.ChannelRepository
+	auditLogRepo     repositories.AuditLogRepository
+}
+
+func NewPermissionService(cfg *config.Config,
+	serverRepo repositories.ServerRepository,
+	serverMemberRepo repositories.ServerMemberRepository,
+	roleRepo repositories.RoleRepository,
+	channelRepo repositories.ChannelRepository,
+	auditLogRepo repositories.AuditLogRepository) *permissionService {
+	return &permissionService{
+		config:           cfg,
+		serverRepo:       serverRepo,
+		serverMemberRepo: serverMemberRepo,
+		roleRepo:         roleRepo,
+		channelRepo:      channelRepo,
+		auditLogRepo:     auditLogRepo,
+	}
+}
+
+// memberPermissionContext 計算頻道權限所需的成員資訊
+type memberPermissionContext struct {
+	userID       primitive.ObjectID
+	roleIDs      []primitive.ObjectID
+	permissions  models.Permission // 伺服器層級的有效權限
+	timedOut     bool              // 是否處於禁言期間
+	isOwner      bool              // 是否為伺服器擁有者
+	rolePosition int               // 最高角色的位階，沒有角色時為 noRolePosition
+}
+
+// GetMemberPermissions 計算用戶在伺服器中的有效權限
+// 擁有者擁有全部權限，其餘成員為預設權限、所有角色權限與個人特殊權限的聯集
+func (ps *permissionService) GetMemberPermissions(ctx context.Context, serverID string, userID string) (models.Permission, *models.MessageOptions) {
+	memberContext, msgOpt := ps.loadMemberContext(ctx, serverID, userID)
+	if msgOpt != nil {
+		return 0, msgOpt
+	}
+	return memberContext.permissions, nil
+}
+
+// loadMemberContext 載入用戶在伺服器中的角色與伺服器層級權限
+func (ps *permissionService) loadMemberContext(ctx context.Context, serverID string, userID string) (*memberPermissionContext, *models.MessageOptions) {
+	server, err := ps.serverRepo.GetServerByID(ctx, serverID)
+	if err != nil {
+		if errors.Is(err, providers.ErrDocumentNotFound) || errors.Is(err, providers.ErrInvalidID) {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrServerNotFound,
+				Message: "伺服器不存在",
+			}
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取伺服器信息失敗",
+			Details: err.Error(),
+		}
+	}
+
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+	if server.OwnerID.Hex() == userID {
+		return &memberPermissionContext{userID: userObjectID, permissions: models.PermissionAll, isOwner: true}, nil
+	}
+
+	member, err := ps.serverMemberRepo.GetServerMember(ctx, serverID, userID)
+	if err != nil {
+		if errors.Is(err, providers.ErrDocumentNotFound) {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrNotServerMember,
+				Message: "您不是該伺服器的成員",
+			}
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取成員資料失敗",
+			Details: err.Error(),
+		}
+	}
+
+	var roles []models.ServerRole
+	if len(member.RoleIDs) > 0 {
+		roleIDs := make([]string, 0, len(member.RoleIDs))
+		for _, roleID := range member.RoleIDs {
+			roleIDs = append(roleIDs, roleID.Hex())
+		}
+		roles, err = ps.roleRepo.GetRolesByIDs(ctx, roleIDs)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "獲取成員角色失敗",
+				Details: err.Error(),
+			}
+		}
+	}
+
+	// 禁言期間無法發送訊息
+	permissions := resolveMemberPermissions(member, roles)
+	timedOut := member.TimeoutUntil > time.Now().Unix()
+	if timedOut {
+		permissions &^= models.PermissionSendMessages
+	}
+
+	return &memberPermissionContext{
+		userID:       userObjectID,
+		roleIDs:      member.RoleIDs,
+		permissions:  permissions,
+		timedOut:     timedOut,
+		rolePosition: highestRolePosition(member, roles),
+	}, nil
+}
+
+// resolveMemberPermissions 合併成員的預設權限、角色權限與個人特殊權限
+// 舊資料中的 owner / admin 角色字串視為擁有全部權限
+func resolveMemberPermissions(member *models.ServerMember, roles []models.ServerRole) models.Permission {
+	if member.Role == "owner" || member.Role == "admin" {
+		return models.PermissionAll
+	}
+
+	permissions := models.DefaultMemberPermissions
+	for _, name := range member.Permissions {
+		// 忽略無法辨識的舊權限名稱
+		if permission, err := models.ParsePermissions([]string{name}); err == nil {
+			permissions |= permission
+		}
+	}
+
+	for _, role := range roles {
+		// 只採用屬於同一伺服器的角色
+		if role.ServerID == member.ServerID {
+			permissions |= role.Permissions
+		}
+	}
+
+	return permissions
+}
+
+// highestRolePosition 返回成員在伺服器中最高角色的位階
+func highestRolePosition(member *models.ServerMember, roles []models.ServerRole) int {
+	if member.Role == "owner" || member.Role == "admin" {
+		return legacyAdminRolePosition
+	}
+
+	position := noRolePosition
+	for _, role := range roles {
+		if role.ServerID == member.ServerID && role.Position > position {
+			position = role.Position
+		}
+	}
+	return position
+}
+
+// computeChannelPermissions 將頻道覆寫套用到成員的伺服器權限上
+// 禁言中的成員無法透過頻道覆寫恢復發言權限
+func computeChannelPermissions(memberContext *memberPermissionContext, channel *models.Channel) models.Permission {
+	permissions := applyChannelOverwrites(memberContext, channel)
+	if memberContext.timedOut {
+		permissions &^= models.PermissionSendMessages
+	}
+	return permissions
+}
+
+// applyChannelOverwrites 依序套用 @everyone、角色與個人覆寫
+// 擁有全部權限者不受覆寫限制；無法查看頻道時其餘頻道權限一併失效
+func applyChannelOverwrites(memberContext *memberPermissionContext, channel *models.Channel) models.Permission {
+	if memberContext.permissions.Has(models.PermissionAll) {
+		return models.PermissionAll
+	}
+
+	var everyoneAllow, everyoneDeny, roleAllow, roleDeny, userAllow, userDeny models.Permission
+	for _, overwrite := range channel.PermissionOverwrites {
+		switch overwrite.TargetType {
+		case models.OverwriteTargetEveryone:
+			everyoneAllow |= overwrite.Allow
+			everyoneDeny |= overwrite.Deny
+		case models.OverwriteTargetRole:
+			if slices.Contains(memberContext.roleIDs, overwrite.TargetID) {
+				roleAllow |= overwrite.Allow
+				roleDeny |= overwrite.Deny
+			}
+		case models.OverwriteTargetUser:
+			if overwrite.TargetID == memberContext.userID {
+				userAllow |= overwrite.Allow
+				userDeny |= overwrite.Deny
+			}
+		}
+	}
+
+	permissions := memberContext.permissions
+	permissions = permissions&^everyoneDeny | everyoneAllow
+	permissions = permissions&^roleDeny | roleAllow
+	permissions = permissions&^userDeny | userAllow
+
+	if !permissions.Has(models.PermissionViewChannel) {
+		return 0
+	}
+	return permissions
+}
+
+// CheckPermission 檢查用戶在伺服器中是否具有指定權限
+func (ps *permissionService) CheckPermission(ctx context.Context, serverID string, userID string, required models.Permission) *models.MessageOptions {
+	permissions, msgOpt := ps.GetMemberPermissions(ctx, serverID, userID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	return requirePermission(permissions, required)
+}
+
+// CheckMemberPosition 檢查用戶的最高角色位階是否高於目標成員，與角色管理使用相同的位階規則
+// 擁有者不受限制且無法被其他成員管理，舊資料中的 owner / admin 成員彼此位階相同
+func (ps *permissionService) CheckMemberPosition(ctx context.Context, serverID string, userID string, targetUserID string) *models.MessageOptions {
+	actor, msgOpt := ps.loadMemberContext(ctx, serverID, userID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	return ps.checkMemberPosition(ctx, actor, serverID, targetUserID)
+}
+
+// GetChannelPermissions 計算用戶在頻道中的有效權限
+func (ps *permissionService) GetChannelPermissions(ctx context.Context, channelID string, userID string) (models.Permission, *models.MessageOptions) {
+	channel, msgOpt := ps.getChannel(ctx, channelID)
+	if msgOpt != nil {
+		return 0, msgOpt
+	}
+
+	memberContext, msgOpt := ps.loadMemberContext(ctx, channel.ServerID.Hex(), userID)
+	if msgOpt != nil {
+		return 0, msgOpt
+	}
+
+	return computeChannelPermissions(memberContext, channel), nil
+}
+
+// CheckChannelPermission 檢查用戶在頻道中是否具有指定權限（套用頻道覆寫）
+func (ps *permissionService) CheckChannelPermission(ctx context.Context, channelID string, userID string, required models.Permission) *models.MessageOptions {
+	permissions, msgOpt := ps.GetChannelPermissions(ctx, channelID, userID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	return requirePermission(permissions, required)
+}
+
+// FilterViewableChannels 從同一伺服器的頻道中篩選出用戶可查看的頻道
+func (ps *permissionService) FilterViewableChannels(ctx context.Context, serverID string, userID string, channels []models.Channel) ([]models.Channel, *models.MessageOptions) {
+	memberContext, msgOpt := ps.loadMemberContext(ctx, serverID, userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	viewable := make([]models.Channel, 0, len(channels))
+	for i := range channels {
+		if channels[i].ServerID.Hex() != serverID {
+			continue
+		}
+		if computeChannelPermissions(memberContext, &channels[i]).Has(models.PermissionViewChannel) {
+			viewable = append(viewable, channels[i])
+		}
+	}
+
+	return viewable, nil
+}
+
+// requirePermission 確認權限集合包含指定權限
+func requirePermission(permissions models.Permission, required models.Permission) *models.MessageOptions {
+	if !permissions.Has(required) {
+		return &models.MessageOptions{
+			Code:    models.ErrNoServerPermission,
+			Message: "權限不足",
+			Details: required.Names(),
+		}
+	}
+	return nil
+}
+
+// getChannel 獲取頻道，不存在時返回頻道不存在錯誤
+func (ps *permissionService) getChannel(ctx context.Context, channelID string) (*models.Channel, *models.MessageOptions) {
+	channel, err := ps.channelRepo.GetChannelByID(ctx, channelID)
+	if err != nil {
+		if errors.Is(err, providers.ErrDocumentNotFound) || errors.Is(err, providers.ErrInvalidID) {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrChannelNotFound,
+				Message: "頻道不存在",
+			}
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取頻道信息失敗",
+			Details: err.Error(),
+		}
+	}
+	return channel, nil
+}
+
+// GetMyPermissions 獲取用戶在伺服器中的有效權限名稱
+func (ps *permissionService) GetMyPermissions(ctx context.Context, userID string, serverID string) (*models.MemberPermissionsResponse, *models.MessageOptions) {
+	permissions, msgOpt := ps.GetMemberPermissions(ctx, serverID, userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	server, err := ps.serverRepo.GetServerByID(ctx, serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取伺服器信息失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return &models.MemberPermissionsResponse{
+		ServerID:    serverID,
+		UserID:      userID,
+		IsOwner:     server.OwnerID.Hex() == userID,
+		Permissions: permissions.Names(),
+	}, nil
+}
+
+// GetServerRoles 獲取伺服器角色列表（伺服器成員皆可查看）
+func (ps *permissionService) GetServerRoles(ctx context.Context, userID string, serverID string) ([]models.ServerRoleResponse, *models.MessageOptions) {
+	if _, msgOpt := ps.GetMemberPermissions(ctx, serverID, userID); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	roles, err := ps.roleRepo.GetRolesByServerID(ctx, serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取角色列表失敗",
+			Details: err.Error(),
+		}
+	}
+
+	responses := make([]models.ServerRoleResponse, 0, len(roles))
+	for i := range roles {
+		responses = append(responses, toServerRoleResponse(&roles[i]))
+	}
+
+	return responses, nil
+}
+
+// CreateRole 創建伺服器角色，非擁有者只能授予自己已擁有的權限
+func (ps *permissionService) CreateRole(ctx context.Context, userID string, serverID string, request models.CreateServerRoleRequest) (*models.ServerRoleResponse, *models.MessageOptions) {
+	actor, msgOpt := ps.authorizeRoleManagement(ctx, serverID, userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	name, msgOpt := validateRoleName(request.Name)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	permissions, err := models.ParsePermissions(request.Permissions)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的權限名稱",
+			Details: err.Error(),
+		}
+	}
+	if msgOpt := checkGrantable(actor.permissions, permissions); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	position := 0
+	if request.Position != nil {
+		position = *request.Position
+	}
+	if msgOpt := checkRolePosition(actor, position); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	count, err := ps.roleRepo.CountRolesByServerID(ctx, serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取角色數量失敗",
+			Details: err.Error(),
+		}
+	}
+	if count >= MaxServerRoles {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrTooManyRoles,
+			Message: "伺服器角色數量已達上限",
+		}
+	}
+
+	serverObjectID, _ := primitive.ObjectIDFromHex(serverID)
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+	role := &models.ServerRole{
+		ServerID:    serverObjectID,
+		Name:        name,
+		Permissions: permissions,
+		Position:    position,
+		CreatedBy:   userObjectID,
+	}
+	if err := ps.roleRepo.CreateRole(ctx, role); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "創建角色失敗",
+			Details: err.Error(),
+		}
+	}
+
+	response := toServerRoleResponse(role)
+	ps.recordRoleAuditLog(userID, models.AuditActionRoleCreate, role, auditChanges(nil, response))
+	return &response, nil
+}
+
+// UpdateRole 更新伺服器角色名稱或權限
+func (ps *permissionService) UpdateRole(ctx context.Context, userID string, serverID string, roleID string, request models.UpdateServerRoleRequest) (*models.ServerRoleResponse, *models.MessageOptions) {
+	actor, msgOpt := ps.authorizeRoleManagement(ctx, serverID, userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	role, msgOpt := ps.getServerRole(ctx, serverID, roleID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+	if msgOpt := checkRolePosition(actor, role.Position); msgOpt != nil {
+		return nil, msgOpt
+	}
+	if msgOpt := checkGrantable(actor.permissions, role.Permissions); msgOpt != nil {
+		return nil, msgOpt
+	}
+	before := toServerRoleResponse(role)
+
+	updates := make(map[string]any)
+	if request.Name != nil {
+		name, msgOpt := validateRoleName(*request.Name)
+		if msgOpt != nil {
+			return nil, msgOpt
+		}
+		updates["name"] = name
+		role.Name = name
+	}
+	if request.Permissions != nil {
+		permissions, err := models.ParsePermissions(*request.Permissions)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "無效的權限名稱",
+				Details: err.Error(),
+			}
+		}
+		if msgOpt := checkGrantable(actor.permissions, permissions); msgOpt != nil {
+			return nil, msgOpt
+		}
+		updates["permissions"] = permissions
+		role.Permissions = permissions
+	}
+	if request.Position != nil {
+		if msgOpt := checkRolePosition(actor, *request.Position); msgOpt != nil {
+			return nil, msgOpt
+		}
+		updates["position"] = *request.Position
+		role.Position = *request.Position
+	}
+
+	if len(updates) == 0 {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "沒有有效的更新欄位",
+		}
+	}
+
+	if err := ps.roleRepo.UpdateRole(ctx, roleID, updates); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "更新角色失敗",
+			Details: err.Error(),
+		}
+	}
+
+	response := toServerRoleResponse(role)
+	if changes := auditChanges(before, response); len(changes) > 0 {
+		ps.recordRoleAuditLog(userID, models.AuditActionRoleUpdate, role, changes)
+	}
+	return &response, nil
+}
+
+// DeleteRole 刪除伺服器角色
+func (ps *permissionService) DeleteRole(ctx context.Context, userID string, serverID string, roleID string) *models.MessageOptions {
+	actor, msgOpt := ps.authorizeRoleManagement(ctx, serverID, userID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	role, msgOpt := ps.getServerRole(ctx, serverID, roleID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	if msgOpt := checkRolePosition(actor, role.Position); msgOpt != nil {
+		return msgOpt
+	}
+	if msgOpt := checkGrantable(actor.permissions, role.Permissions); msgOpt != nil {
+		return msgOpt
+	}
+
+	if err := ps.roleRepo.DeleteRole(ctx, roleID); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "刪除角色失敗",
+			Details: err.Error(),
+		}
+	}
+
+	ps.recordRoleAuditLog(userID, models.AuditActionRoleDelete, role, auditChanges(toServerRoleResponse(role), nil))
+
+	return nil
+}
+
+// AssignRole 將角色指派給成員
+func (ps *permissionService) AssignRole(ctx context.Context, userID string, serverID string, roleID string, targetUserID string) *models.MessageOptions {
+	return ps.updateMemberRole(ctx, userID, serverID, roleID, targetUserID, true)
+}
+
+// UnassignRole 移除成員的角色
+func (ps *permissionService) UnassignRole(ctx context.Context, userID string, serverID string, roleID string, targetUserID string) *models.MessageOptions {
+	return ps.updateMemberRole(ctx, userID, serverID, roleID, targetUserID, false)
+}
+
+// updateMemberRole 指派或移除成員角色
+// 非擁有者只能處理權限不超過自身、且位階低於自身最高角色的角色，目標成員的位階也必須低於自身
+func (ps *permissionService) updateMemberRole(ctx context.Context, userID string, serverID string, roleID string, targetUserID string, assign bool) *models.MessageOptions {
+	actor, msgOpt := ps.authorizeRoleManagement(ctx, serverID, userID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	role, msgOpt := ps.getServerRole(ctx, serverID, roleID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	if msgOpt := checkRolePosition(actor, role.Position); msgOpt != nil {
+		return msgOpt
+	}
+	if msgOpt := checkGrantable(actor.permissions, role.Permissions); msgOpt != nil {
+		return msgOpt
+	}
+
+	if _, err := primitive.ObjectIDFromHex(targetUserID); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的用戶ID格式",
+		}
+	}
+	member, err := ps.serverMemberRepo.GetServerMember(ctx, serverID, targetUserID)
+	if err != nil {
+		if errors.Is(err, providers.ErrDocumentNotFound) {
+			return &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "目標用戶不是伺服器成員",
+			}
+		}
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取成員資料失敗",
+			Details: err.Error(),
+		}
+	}
+	if msgOpt := ps.checkMemberPosition(ctx, actor, serverID, targetUserID); msgOpt != nil {
+		return msgOpt
+	}
+
+	if assign {
+		err = ps.serverMemberRepo.AddRoleToMember(ctx, serverID, targetUserID, roleID)
+	} else {
+		err = ps.serverMemberRepo.RemoveRoleFromMember(ctx, serverID, targetUserID, roleID)
+	}
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "更新成員角色失敗",
+			Details: err.Error(),
+		}
+	}
+
+	beforeRoleIDs := roleIDsToHex(member.RoleIDs)
+	afterRoleIDs := slices.DeleteFunc(slices.Clone(beforeRoleIDs), func(id string) bool { return id == role.ID.Hex() })
+	if assign {
+		afterRoleIDs = append(afterRoleIDs, role.ID.Hex())
+	}
+	changes := auditChanges(map[string]any{"role_ids": beforeRoleIDs}, map[string]any{"role_ids": afterRoleIDs})
+	if len(changes) > 0 {
+		recordMemberAuditLog(ps.auditLogRepo, serverID, userID, targetUserID, models.AuditActionMemberRoleUpdate, "", changes)
+	}
+
+	return nil
+}
+
+// GetChannelOverwrites 獲取頻道權限覆寫列表（需具有管理頻道權限）
+func (ps *permissionService) GetChannelOverwrites(ctx context.Context, userID string, channelID string) ([]models.ChannelOverwriteResponse, *models.MessageOptions) {
+	channel, _, msgOpt := ps.authorizeOverwriteManagement(ctx, channelID, userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	return toChannelOverwriteResponses(channel.PermissionOverwrites), nil
+}
+
+// SetChannelOverwrite 新增或取代頻道權限覆寫，非擁有者只能覆寫自身已擁有的權限
+func (ps *permissionService) SetChannelOverwrite(ctx context.Context, userID string, channelID string, request models.ChannelOverwriteRequest) ([]models.ChannelOverwriteResponse, *models.MessageOptions) {
+	channel, actorPermissions, msgOpt := ps.authorizeOverwriteManagement(ctx, channelID, userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	targetID, msgOpt := ps.resolveOverwriteTarget(ctx, channel.ServerID.Hex(), request.TargetType, request.TargetID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	allow, err := models.ParsePermissions(request.Allow)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的權限名稱",
+			Details: err.Error(),
+		}
+	}
+	deny, err := models.ParsePermissions(request.Deny)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的權限名稱",
+			Details: err.Error(),
+		}
+	}
+	if !models.ChannelScopedPermissions.Has(allow | deny) {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "只能覆寫頻道層級的權限",
+			Details: models.ChannelScopedPermissions.Names(),
+		}
+	}
+	if allow&deny != 0 {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "同一權限不能同時允許與拒絕",
+		}
+	}
+	if msgOpt := checkGrantable(actorPermissions, allow|deny); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	overwrite := models.PermissionOverwrite{
+		TargetType: request.TargetType,
+		TargetID:   targetID,
+		Allow:      allow,
+		Deny:       deny,
+	}
+	overwrites := slices.Clone(channel.PermissionOverwrites)
+	if index := findOverwrite(overwrites, request.TargetType, targetID); index >= 0 {
+		overwrites[index] = overwrite
+	} else {
+		if len(overwrites) >= MaxChannelOverwrites {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "頻道權限覆寫數量已達上限",
+			}
+		}
+		overwrites = append(overwrites, overwrite)
+	}
+
+	if err := ps.channelRepo.UpdateChannel(channelID, map[string]any{"permission_overwrites": overwrites}); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "更新頻道權限覆寫失敗",
+			Details: err.Error(),
+		}
+	}
+
+	responses := toChannelOverwriteResponses(overwrites)
+	ps.recordOverwriteAuditLog(userID, models.AuditActionChannelOverwriteUpdate, channel, responses)
+	return responses, nil
+}
+
+// DeleteChannelOverwrite 刪除頻道權限覆寫
+func (ps *permissionService) DeleteChannelOverwrite(ctx context.Context, userID string, channelID string, targetType string, targetID string) *models.MessageOptions {
+	channel, actorPermissions, msgOpt := ps.authorizeOverwriteManagement(ctx, channelID, userID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	// 刪除時不檢查對象是否仍存在，以便清理已退出成員的覆寫
+	targetObjectID, msgOpt := parseOverwriteTarget(targetType, targetID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	index := findOverwrite(channel.PermissionOverwrites, targetType, targetObjectID)
+	if index < 0 {
+		return &models.MessageOptions{
+			Code:    models.ErrOverwriteNotFound,
+			Message: "頻道權限覆寫不存在",
+		}
+	}
+	existing := channel.PermissionOverwrites[index]
+	if msgOpt := checkGrantable(actorPermissions, existing.Allow|existing.Deny); msgOpt != nil {
+		return msgOpt
+	}
+
+	overwrites := slices.Delete(slices.Clone(channel.PermissionOverwrites), index, index+1)
+	if err := ps.channelRepo.UpdateChannel(channelID, map[string]any{"permission_overwrites": overwrites}); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "刪除頻道權限覆寫失敗",
+			Details: err.Error(),
+		}
+	}
+
+	ps.recordOverwriteAuditLog(userID, models.AuditActionChannelOverwriteDelete, channel, toChannelOverwriteResponses(overwrites))
+
+	return nil
+}
+
+// recordRoleAuditLog 寫入以角色為目標的稽核紀錄
+func (ps *permissionService) recordRoleAuditLog(userID string, action string, role *models.ServerRole, changes []models.AuditLogChange) {
+	actorObjectID, _ := primitive.ObjectIDFromHex(userID)
+	recordAuditLog(ps.auditLogRepo, &models.AuditLog{
+		ServerID:   role.ServerID,
+		ActorID:    actorObjectID,
+		Action:     action,
+		TargetType: models.AuditTargetRole,
+		TargetID:   role.ID,
+		Changes:    changes,
+	})
+}
+
+// recordOverwriteAuditLog 寫入頻道權限覆寫變更的稽核紀錄，以覆寫前後的完整列表比較
+func (ps *permissionService) recordOverwriteAuditLog(userID string, action string, channel *models.Channel, after []models.ChannelOverwriteResponse) {
+	changes := auditChanges(
+		map[string]any{"permission_overwrites": toChannelOverwriteResponses(channel.PermissionOverwrites)},
+		map[string]any{"permission_overwrites": after},
+	)
+	if len(changes) == 0 {
+		return
+	}
+
+	actorObjectID, _ := primitive.ObjectIDFromHex(userID)
+	recordAuditLog(ps.auditLogRepo, &models.AuditLog{
+		ServerID:   channel.ServerID,
+		ActorID:    actorObjectID,
+		Action:     action,
+		TargetType: models.AuditTargetChannel,
+		TargetID:   channel.ID,
+		Changes:    changes,
+	})
+}
+
+// authorizeOverwriteManagement 確認用戶具有管理頻道權限，並返回頻道與其伺服器權限
+func (ps *permissionService) authorizeOverwriteManagement(ctx context.Context, channelID string, userID string) (*models.Channel, models.Permission, *models.MessageOptions) {
+	channel, msgOpt := ps.getChannel(ctx, channelID)
+	if msgOpt != nil {
+		return nil, 0, msgOpt
+	}
+
+	permissions, msgOpt := ps.GetMemberPermissions(ctx, channel.ServerID.Hex(), userID)
+	if msgOpt != nil {
+		return nil, 0, msgOpt
+	}
+	if !permissions.Has(models.PermissionManageChannels) {
+		return nil, 0, &models.MessageOptions{
+			Code:    models.ErrNoServerPermission,
+			Message: "沒有管理頻道的權限",
+		}
+	}
+
+	return channel, permissions, nil
+}
+
+// resolveOverwriteTarget 驗證覆寫對象存在於伺服器中，並返回對象ID
+func (ps *permissionService) resolveOverwriteTarget(ctx context.Context, serverID string, targetType string, targetID string) (primitive.ObjectID, *models.MessageOptions) {
+	targetObjectID, msgOpt := parseOverwriteTarget(targetType, targetID)
+	if msgOpt != nil {
+		return primitive.NilObjectID, msgOpt
+	}
+
+	switch targetType {
+	case models.OverwriteTargetRole:
+		if _, msgOpt := ps.getServerRole(ctx, serverID, targetID); msgOpt != nil {
+			return primitive.NilObjectID, msgOpt
+		}
+	case models.OverwriteTargetUser:
+		if _, err := ps.serverMemberRepo.GetServerMember(ctx, serverID, targetID); err != nil {
+			if errors.Is(err, providers.ErrDocumentNotFound) {
+				return primitive.NilObjectID, &models.MessageOptions{
+					Code:    models.ErrInvalidParams,
+					Message: "目標用戶不是伺服器成員",
+				}
+			}
+			return primitive.NilObjectID, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "獲取成員資料失敗",
+				Details: err.Error(),
+			}
+		}
+	}
+
+	return targetObjectID, nil
+}
+
+// parseOverwriteTarget 驗證覆寫對象類型與ID格式，對象為所有成員時返回零值ID
+func parseOverwriteTarget(targetType string, targetID string) (primitive.ObjectID, *models.MessageOptions) {
+	switch targetType {
+	case models.OverwriteTargetEveryone:
+		return primitive.NilObjectID, nil
+	case models.OverwriteTargetRole, models.OverwriteTargetUser:
+		targetObjectID, err := primitive.ObjectIDFromHex(targetID)
+		if err != nil {
+			return primitive.NilObjectID, &models.MessageOptions{
+				Code:    models.ErrInvalidParams,
+				Message: "無效的覆寫對象ID格式",
+			}
+		}
+		return targetObjectID, nil
+	default:
+		return primitive.NilObjectID, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的覆寫對象類型",
+		}
+	}
+}
+
+// findOverwrite 返回指定對象的覆寫索引，不存在時返回 -1
+func findOverwrite(overwrites []models.PermissionOverwrite, targetType string, targetID primitive.ObjectID) int {
+	return slices.IndexFunc(overwrites, func(overwrite models.PermissionOverwrite) bool {
+		return overwrite.TargetType == targetType && overwrite.TargetID == targetID
+	})
+}
+
+// toChannelOverwriteResponses 將頻道覆寫轉換為 API 回應格式
+func toChannelOverwriteResponses(overwrites []models.PermissionOverwrite) []models.ChannelOverwriteResponse {
+	responses := make([]models.ChannelOverwriteResponse, 0, len(overwrites))
+	for _, overwrite := range overwrites {
+		response := models.ChannelOverwriteResponse{
+			TargetType: overwrite.TargetType,
+			Allow:      overwrite.Allow.Names(),
+			Deny:       overwrite.Deny.Names(),
+		}
+		if !overwrite.TargetID.IsZero() {
+			response.TargetID = overwrite.TargetID.Hex()
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+// authorizeRoleManagement 確認用戶具有管理角色權限，並返回其權限與角色位階
+func (ps *permissionService) authorizeRoleManagement(ctx context.Context, serverID string, userID string) (*memberPermissionContext, *models.MessageOptions) {
+	actor, msgOpt := ps.loadMemberContext(ctx, serverID, userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+	if !actor.permissions.Has(models.PermissionManageRoles) {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNoServerPermission,
+			Message: "沒有管理角色的權限",
+		}
+	}
+	return actor, nil
+}
+
+// checkRolePosition 確認角色位階低於操作者的最高角色，擁有者不受限制
+func checkRolePosition(actor *memberPermissionContext, position int) *models.MessageOptions {
+	if actor.isOwner || position < actor.rolePosition {
+		return nil
+	}
+	return &models.MessageOptions{
+		Code:    models.ErrNoServerPermission,
+		Message: "無法管理位階不低於自身最高角色的角色",
+	}
+}
+
+// checkMemberPosition 確認目標成員的最高角色位階低於操作者，擁有者不受限制且無法被其他成員管理
+func (ps *permissionService) checkMemberPosition(ctx context.Context, actor *memberPermissionContext, serverID string, targetUserID string) *models.MessageOptions {
+	if actor.isOwner {
+		return nil
+	}
+
+	target, msgOpt := ps.loadMemberContext(ctx, serverID, targetUserID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	if target.isOwner || target.rolePosition >= actor.rolePosition {
+		return &models.MessageOptions{
+			Code:    models.ErrNoServerPermission,
+			Message: "無法管理位階不低於自身的成員",
+		}
+	}
+	return nil
+}
+
+// getServerRole 獲取屬於指定伺服器的角色
+func (ps *permissionService) getServerRole(ctx context.Context, serverID string, roleID string) (*models.ServerRole, *models.MessageOptions) {
+	role, err := ps.roleRepo.GetRoleByID(ctx, roleID)
+	if err != nil || role.ServerID.Hex() != serverID {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrRoleNotFound,
+			Message: "角色不存在",
+		}
+	}
+	return role, nil
+}
+
+// checkGrantable 確認角色權限不超出操作者自身的權限，避免權限提升
+func checkGrantable(actorPermissions models.Permission, permissions models.Permission) *models.MessageOptions {
+	if !actorPermissions.Has(permissions) {
+		return &models.MessageOptions{
+			Code:    models.ErrNoServerPermission,
+			Message: "無法管理超出自身權限的角色",
+		}
+	}
+	return nil
+}
+
+// validateRoleName 驗證並整理角色名稱
+func validateRoleName(name string) (string, *models.MessageOptions) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "角色名稱不能為空",
+		}
+	}
+	if utf8.RuneCountInString(name) > MaxRoleNameLength {
+		return "", &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "角色名稱過長",
+		}
+	}
+	return name, nil
+}
+
+// toServerRoleResponse 將角色轉換為 API 回應格式
+func toServerRoleResponse(role *models.ServerRole) models.ServerRoleResponse {
+	return models.ServerRoleResponse{
+		ID:          role.ID.Hex(),
+		ServerID:    role.ServerID.Hex(),
+		Name:        role.Name,
+		Permissions: role.Permissions.Names(),
+		Position:    role.Position,
+		CreatedAt:   role.CreatedAt.UnixMilli(),
+	}
+}
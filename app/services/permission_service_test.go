@@ -0,0 +1,601 @@
+package services
+
+import (
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mockRoleRepository 模擬 RoleRepository
+type mockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *mockRoleRepository) CreateRole(ctx context.Context, role *models.ServerRole) error {
+	args := m.Called(ctx, role)
+	return args.Error(0)
+}
+
+func (m *mockRoleRepository) GetRolesByServerID(ctx context.Context, serverID string) ([]models.ServerRole, error) {
+	args := m.Called(ctx, serverID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ServerRole), args.Error(1)
+}
+
+func (m *mockRoleRepository) GetRoleByID(ctx context.Context, roleID string) (*models.ServerRole, error) {
+	args := m.Called(ctx, roleID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServerRole), args.Error(1)
+}
+
+func (m *mockRoleRepository) GetRolesByIDs(ctx context.Context, roleIDs []string) ([]models.ServerRole, error) {
+	args := m.Called(ctx, roleIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ServerRole), args.Error(1)
+}
+
+func (m *mockRoleRepository) UpdateRole(ctx context.Context, roleID string, updates map[string]any) error {
+	args := m.Called(ctx, roleID, updates)
+	return args.Error(0)
+}
+
+func (m *mockRoleRepository) DeleteRole(ctx context.Context, roleID string) error {
+	args := m.Called(ctx, roleID)
+	return args.Error(0)
+}
+
+func (m *mockRoleRepository) CountRolesByServerID(ctx context.Context, serverID string) (int64, error) {
+	args := m.Called(ctx, serverID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// TestPermissionNames 測試權限名稱與位元集合的轉換
+func TestPermissionNames(t *testing.T) {
+	permissions, err := models.ParsePermissions([]string{"manage_roles", "kick_members"})
+	assert.NoError(t, err)
+	assert.True(t, permissions.Has(models.PermissionKickMembers|models.PermissionManageRoles))
+	assert.False(t, permissions.Has(models.PermissionBanMembers))
+	assert.Equal(t, []string{"kick_members", "manage_roles"}, permissions.Names())
+
+	_, err = models.ParsePermissions([]string{"fly"})
+	assert.Error(t, err)
+
+	assert.Len(t, models.PermissionAll.Names(), 12)
+}
+
+// TestResolveMemberPermissions 測試成員有效權限的合併規則
+func TestResolveMemberPermissions(t *testing.T) {
+	serverID := primitive.NewObjectID()
+
+	t.Run("合併角色與個人權限並忽略其他伺服器角色", func(t *testing.T) {
+		member := &models.ServerMember{
+			ServerID:    serverID,
+			Role:        "member",
+			Permissions: []string{"mention_everyone", "legacy_unknown"},
+		}
+		roles := []models.ServerRole{
+			{ServerID: serverID, Permissions: models.PermissionKickMembers},
+			{ServerID: primitive.NewObjectID(), Permissions: models.PermissionManageRoles},
+		}
+
+		permissions := resolveMemberPermissions(member, roles)
+
+		assert.Equal(t, models.DefaultMemberPermissions|models.PermissionMentionEveryone|models.PermissionKickMembers, permissions)
+	})
+
+	t.Run("舊版管理員角色擁有全部權限", func(t *testing.T) {
+		member := &models.ServerMember{ServerID: serverID, Role: "admin"}
+
+		assert.Equal(t, models.PermissionAll, resolveMemberPermissions(member, nil))
+	})
+}
+
+// TestCheckPermission 測試伺服器權限檢查
+func TestCheckPermission(t *testing.T) {
+	ctx := context.Background()
+	ownerID := primitive.NewObjectID()
+	serverID := primitive.NewObjectID()
+	server := &models.Server{BaseModel: providers.BaseModel{ID: serverID}, OwnerID: ownerID}
+
+	t.Run("擁有者擁有全部權限", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		service := &permissionService{serverRepo: mockServerRepo}
+
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+
+		msgOpt := service.CheckPermission(ctx, serverID.Hex(), ownerID.Hex(), models.PermissionManageRoles)
+
+		assert.Nil(t, msgOpt)
+		mockServerRepo.AssertExpectations(t)
+	})
+
+	t.Run("角色授予的權限", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo, roleRepo: mockRoleRepo}
+
+		userID := primitive.NewObjectID()
+		roleID := primitive.NewObjectID()
+		member := &models.ServerMember{ServerID: serverID, UserID: userID, Role: "member", RoleIDs: []primitive.ObjectID{roleID}}
+		roles := []models.ServerRole{{BaseModel: providers.BaseModel{ID: roleID}, ServerID: serverID, Permissions: models.PermissionManageChannels}}
+
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Twice()
+		mockMemberRepo.On("GetServerMember", mock.Anything, serverID.Hex(), userID.Hex()).Return(member, nil).Twice()
+		mockRoleRepo.On("GetRolesByIDs", mock.Anything, []string{roleID.Hex()}).Return(roles, nil).Twice()
+
+		assert.Nil(t, service.CheckPermission(ctx, serverID.Hex(), userID.Hex(), models.PermissionManageChannels))
+
+		msgOpt := service.CheckPermission(ctx, serverID.Hex(), userID.Hex(), models.PermissionBanMembers)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		mockRoleRepo.AssertExpectations(t)
+	})
+
+	t.Run("非成員", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo}
+
+		userID := primitive.NewObjectID()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockMemberRepo.On("GetServerMember", mock.Anything, serverID.Hex(), userID.Hex()).Return(nil, providers.ErrDocumentNotFound).Once()
+
+		msgOpt := service.CheckPermission(ctx, serverID.Hex(), userID.Hex(), models.PermissionManageChannels)
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNotServerMember, msgOpt.Code)
+	})
+
+	t.Run("伺服器不存在", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		service := &permissionService{serverRepo: mockServerRepo}
+
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(nil, providers.ErrDocumentNotFound).Once()
+
+		msgOpt := service.CheckPermission(ctx, serverID.Hex(), ownerID.Hex(), models.PermissionManageChannels)
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrServerNotFound, msgOpt.Code)
+	})
+}
+
+// TestRoleManagement 測試角色的建立與指派
+func TestRoleManagement(t *testing.T) {
+	ctx := context.Background()
+	ownerID := primitive.NewObjectID()
+	managerID := primitive.NewObjectID()
+	serverID := primitive.NewObjectID()
+	server := &models.Server{BaseModel: providers.BaseModel{ID: serverID}, OwnerID: ownerID}
+
+	// managerRoleSetup 設定具有管理角色與踢出成員權限、最高角色位階為 managerPosition 的操作者
+	const managerPosition = 5
+	managerRoleSetup := func(mockServerRepo *mockServerRepository, mockMemberRepo *mocks.ServerMemberRepository, mockRoleRepo *mockRoleRepository) {
+		roleID := primitive.NewObjectID()
+		member := &models.ServerMember{ServerID: serverID, UserID: managerID, Role: "member", RoleIDs: []primitive.ObjectID{roleID}}
+		roles := []models.ServerRole{{ServerID: serverID, Permissions: models.PermissionManageRoles | models.PermissionKickMembers, Position: managerPosition}}
+
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockMemberRepo.On("GetServerMember", mock.Anything, serverID.Hex(), managerID.Hex()).Return(member, nil).Once()
+		mockRoleRepo.On("GetRolesByIDs", mock.Anything, []string{roleID.Hex()}).Return(roles, nil).Once()
+	}
+
+	t.Run("擁有者創建角色", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, roleRepo: mockRoleRepo}
+
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockRoleRepo.On("CountRolesByServerID", mock.Anything, serverID.Hex()).Return(int64(0), nil).Once()
+		mockRoleRepo.On("CreateRole", mock.Anything, mock.MatchedBy(func(role *models.ServerRole) bool {
+			return role.Name == "Moderator" && role.Permissions == models.PermissionKickMembers|models.PermissionBanMembers
+		})).Return(nil).Once()
+
+		result, msgOpt := service.CreateRole(ctx, ownerID.Hex(), serverID.Hex(), models.CreateServerRoleRequest{
+			Name:        "  Moderator ",
+			Permissions: []string{"kick_members", "ban_members"},
+		})
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "Moderator", result.Name)
+		assert.Equal(t, []string{"kick_members", "ban_members"}, result.Permissions)
+		mockRoleRepo.AssertExpectations(t)
+	})
+
+	t.Run("無法授予超出自身的權限", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo, roleRepo: mockRoleRepo}
+		managerRoleSetup(mockServerRepo, mockMemberRepo, mockRoleRepo)
+
+		result, msgOpt := service.CreateRole(ctx, managerID.Hex(), serverID.Hex(), models.CreateServerRoleRequest{
+			Name:        "Admin",
+			Permissions: []string{"ban_members"},
+		})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		mockRoleRepo.AssertNotCalled(t, "CreateRole", mock.Anything, mock.Anything)
+	})
+
+	t.Run("未知的權限名稱", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		service := &permissionService{serverRepo: mockServerRepo}
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+
+		result, msgOpt := service.CreateRole(ctx, ownerID.Hex(), serverID.Hex(), models.CreateServerRoleRequest{
+			Name:        "Role",
+			Permissions: []string{"fly"},
+		})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+
+	t.Run("角色數量達上限", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, roleRepo: mockRoleRepo}
+
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockRoleRepo.On("CountRolesByServerID", mock.Anything, serverID.Hex()).Return(int64(MaxServerRoles), nil).Once()
+
+		result, msgOpt := service.CreateRole(ctx, ownerID.Hex(), serverID.Hex(), models.CreateServerRoleRequest{Name: "Role"})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrTooManyRoles, msgOpt.Code)
+	})
+
+	t.Run("指派角色給成員", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo, roleRepo: mockRoleRepo}
+		managerRoleSetup(mockServerRepo, mockMemberRepo, mockRoleRepo)
+
+		targetID := primitive.NewObjectID()
+		target := &models.ServerMember{ServerID: serverID, UserID: targetID, Role: "member"}
+		roleID := primitive.NewObjectID()
+		role := &models.ServerRole{BaseModel: providers.BaseModel{ID: roleID}, ServerID: serverID, Permissions: models.PermissionKickMembers, Position: managerPosition - 1}
+
+		mockRoleRepo.On("GetRoleByID", mock.Anything, roleID.Hex()).Return(role, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockMemberRepo.On("GetServerMember", mock.Anything, serverID.Hex(), targetID.Hex()).Return(target, nil).Twice()
+		mockMemberRepo.On("AddRoleToMember", mock.Anything, serverID.Hex(), targetID.Hex(), roleID.Hex()).Return(nil).Once()
+
+		msgOpt := service.AssignRole(ctx, managerID.Hex(), serverID.Hex(), roleID.Hex(), targetID.Hex())
+
+		assert.Nil(t, msgOpt)
+		mockMemberRepo.AssertExpectations(t)
+	})
+
+	t.Run("無法指派位階不低於自身的角色", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo, roleRepo: mockRoleRepo}
+		managerRoleSetup(mockServerRepo, mockMemberRepo, mockRoleRepo)
+
+		targetID := primitive.NewObjectID().Hex()
+		roleID := primitive.NewObjectID()
+		role := &models.ServerRole{BaseModel: providers.BaseModel{ID: roleID}, ServerID: serverID, Permissions: models.PermissionKickMembers, Position: managerPosition}
+
+		mockRoleRepo.On("GetRoleByID", mock.Anything, roleID.Hex()).Return(role, nil).Once()
+
+		msgOpt := service.AssignRole(ctx, managerID.Hex(), serverID.Hex(), roleID.Hex(), targetID)
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		mockMemberRepo.AssertNotCalled(t, "AddRoleToMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("無法移除位階相同成員的角色", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo, roleRepo: mockRoleRepo}
+		managerRoleSetup(mockServerRepo, mockMemberRepo, mockRoleRepo)
+
+		// 目標成員擁有與操作者同位階的角色，即使要移除的角色位階較低也不允許
+		targetID := primitive.NewObjectID()
+		peerRoleID := primitive.NewObjectID()
+		roleID := primitive.NewObjectID()
+		target := &models.ServerMember{ServerID: serverID, UserID: targetID, Role: "member", RoleIDs: []primitive.ObjectID{peerRoleID, roleID}}
+		role := &models.ServerRole{BaseModel: providers.BaseModel{ID: roleID}, ServerID: serverID, Permissions: models.PermissionKickMembers, Position: 1}
+		targetRoles := []models.ServerRole{
+			{BaseModel: providers.BaseModel{ID: peerRoleID}, ServerID: serverID, Permissions: models.PermissionKickMembers, Position: managerPosition},
+			*role,
+		}
+
+		mockRoleRepo.On("GetRoleByID", mock.Anything, roleID.Hex()).Return(role, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockMemberRepo.On("GetServerMember", mock.Anything, serverID.Hex(), targetID.Hex()).Return(target, nil).Twice()
+		mockRoleRepo.On("GetRolesByIDs", mock.Anything, []string{peerRoleID.Hex(), roleID.Hex()}).Return(targetRoles, nil).Once()
+
+		msgOpt := service.UnassignRole(ctx, managerID.Hex(), serverID.Hex(), roleID.Hex(), targetID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		mockMemberRepo.AssertNotCalled(t, "RemoveRoleFromMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("無法將角色位階調整到不低於自身", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo, roleRepo: mockRoleRepo}
+		managerRoleSetup(mockServerRepo, mockMemberRepo, mockRoleRepo)
+
+		roleID := primitive.NewObjectID()
+		role := &models.ServerRole{BaseModel: providers.BaseModel{ID: roleID}, ServerID: serverID, Permissions: models.PermissionKickMembers, Position: 1}
+		position := managerPosition
+
+		mockRoleRepo.On("GetRoleByID", mock.Anything, roleID.Hex()).Return(role, nil).Once()
+
+		result, msgOpt := service.UpdateRole(ctx, managerID.Hex(), serverID.Hex(), roleID.Hex(), models.UpdateServerRoleRequest{Position: &position})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		mockRoleRepo.AssertNotCalled(t, "UpdateRole", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("目標不是伺服器成員", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo, roleRepo: mockRoleRepo}
+
+		targetID := primitive.NewObjectID().Hex()
+		roleID := primitive.NewObjectID()
+		role := &models.ServerRole{BaseModel: providers.BaseModel{ID: roleID}, ServerID: serverID}
+
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockRoleRepo.On("GetRoleByID", mock.Anything, roleID.Hex()).Return(role, nil).Once()
+		mockMemberRepo.On("GetServerMember", mock.Anything, serverID.Hex(), targetID).Return(nil, providers.ErrDocumentNotFound).Once()
+
+		msgOpt := service.AssignRole(ctx, ownerID.Hex(), serverID.Hex(), roleID.Hex(), targetID)
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+		mockMemberRepo.AssertNotCalled(t, "AddRoleToMember", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("其他伺服器的角色視為不存在", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, roleRepo: mockRoleRepo}
+
+		roleID := primitive.NewObjectID()
+		role := &models.ServerRole{BaseModel: providers.BaseModel{ID: roleID}, ServerID: primitive.NewObjectID()}
+
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockRoleRepo.On("GetRoleByID", mock.Anything, roleID.Hex()).Return(role, nil).Once()
+
+		msgOpt := service.DeleteRole(ctx, ownerID.Hex(), serverID.Hex(), roleID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrRoleNotFound, msgOpt.Code)
+		mockRoleRepo.AssertNotCalled(t, "DeleteRole", mock.Anything, mock.Anything)
+	})
+}
+
+// TestComputeChannelPermissions 測試頻道權限覆寫的套用順序
+func TestComputeChannelPermissions(t *testing.T) {
+	userID := primitive.NewObjectID()
+	roleID := primitive.NewObjectID()
+	memberContext := &memberPermissionContext{
+		userID:      userID,
+		roleIDs:     []primitive.ObjectID{roleID},
+		permissions: models.DefaultMemberPermissions,
+	}
+
+	t.Run("無覆寫時繼承伺服器權限", func(t *testing.T) {
+		permissions := computeChannelPermissions(memberContext, &models.Channel{})
+
+		assert.Equal(t, models.DefaultMemberPermissions, permissions)
+	})
+
+	t.Run("私人頻道只允許指定角色查看", func(t *testing.T) {
+		channel := &models.Channel{PermissionOverwrites: []models.PermissionOverwrite{
+			{TargetType: models.OverwriteTargetEveryone, Deny: models.PermissionViewChannel},
+			{TargetType: models.OverwriteTargetRole, TargetID: roleID, Allow: models.PermissionViewChannel},
+		}}
+
+		assert.True(t, computeChannelPermissions(memberContext, channel).Has(models.PermissionViewChannel))
+
+		outsider := &memberPermissionContext{userID: primitive.NewObjectID(), permissions: models.DefaultMemberPermissions}
+		assert.Equal(t, models.Permission(0), computeChannelPermissions(outsider, channel))
+	})
+
+	t.Run("個別用戶覆寫優先於角色", func(t *testing.T) {
+		channel := &models.Channel{PermissionOverwrites: []models.PermissionOverwrite{
+			{TargetType: models.OverwriteTargetEveryone, Deny: models.PermissionSendMessages},
+			{TargetType: models.OverwriteTargetRole, TargetID: roleID, Allow: models.PermissionSendMessages},
+			{TargetType: models.OverwriteTargetUser, TargetID: userID, Deny: models.PermissionSendMessages},
+		}}
+
+		permissions := computeChannelPermissions(memberContext, channel)
+
+		assert.True(t, permissions.Has(models.PermissionViewChannel))
+		assert.False(t, permissions.Has(models.PermissionSendMessages))
+	})
+
+	t.Run("擁有全部權限者不受覆寫限制", func(t *testing.T) {
+		owner := &memberPermissionContext{userID: primitive.NewObjectID(), permissions: models.PermissionAll}
+		channel := &models.Channel{PermissionOverwrites: []models.PermissionOverwrite{
+			{TargetType: models.OverwriteTargetEveryone, Deny: models.PermissionViewChannel},
+		}}
+
+		assert.Equal(t, models.PermissionAll, computeChannelPermissions(owner, channel))
+	})
+
+	t.Run("禁言中的成員無法透過覆寫取得發言權限", func(t *testing.T) {
+		timedOut := &memberPermissionContext{userID: userID, permissions: models.DefaultMemberPermissions, timedOut: true}
+		channel := &models.Channel{PermissionOverwrites: []models.PermissionOverwrite{
+			{TargetType: models.OverwriteTargetUser, TargetID: userID, Allow: models.PermissionSendMessages},
+		}}
+
+		permissions := computeChannelPermissions(timedOut, channel)
+
+		assert.True(t, permissions.Has(models.PermissionViewChannel))
+		assert.False(t, permissions.Has(models.PermissionSendMessages))
+	})
+}
+
+// TestChannelOverwrites 測試頻道權限覆寫的管理
+func TestChannelOverwrites(t *testing.T) {
+	ctx := context.Background()
+	ownerID := primitive.NewObjectID()
+	serverID := primitive.NewObjectID()
+	channelID := primitive.NewObjectID()
+	server := &models.Server{BaseModel: providers.BaseModel{ID: serverID}, OwnerID: ownerID}
+
+	newChannel := func(overwrites ...models.PermissionOverwrite) *models.Channel {
+		return &models.Channel{BaseModel: providers.BaseModel{ID: channelID}, ServerID: serverID, PermissionOverwrites: overwrites}
+	}
+
+	t.Run("設定角色覆寫", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockChannelRepo := new(mockChannelRepository)
+		mockRoleRepo := new(mockRoleRepository)
+		service := &permissionService{serverRepo: mockServerRepo, channelRepo: mockChannelRepo, roleRepo: mockRoleRepo}
+
+		roleID := primitive.NewObjectID()
+		everyone := models.PermissionOverwrite{TargetType: models.OverwriteTargetEveryone, Deny: models.PermissionViewChannel}
+
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(newChannel(everyone), nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockRoleRepo.On("GetRoleByID", mock.Anything, roleID.Hex()).Return(&models.ServerRole{BaseModel: providers.BaseModel{ID: roleID}, ServerID: serverID}, nil).Once()
+		mockChannelRepo.On("UpdateChannel", channelID.Hex(), map[string]any{"permission_overwrites": []models.PermissionOverwrite{
+			everyone,
+			{TargetType: models.OverwriteTargetRole, TargetID: roleID, Allow: models.PermissionViewChannel},
+		}}).Return(nil).Once()
+
+		result, msgOpt := service.SetChannelOverwrite(ctx, ownerID.Hex(), channelID.Hex(), models.ChannelOverwriteRequest{
+			TargetType: models.OverwriteTargetRole,
+			TargetID:   roleID.Hex(),
+			Allow:      []string{"view_channel"},
+		})
+
+		assert.Nil(t, msgOpt)
+		assert.Len(t, result, 2)
+		assert.Empty(t, result[0].TargetID)
+		assert.Equal(t, []string{"view_channel"}, result[0].Deny)
+		assert.Equal(t, roleID.Hex(), result[1].TargetID)
+		mockChannelRepo.AssertExpectations(t)
+	})
+
+	t.Run("拒絕非頻道層級或重疊的權限", func(t *testing.T) {
+		for name, request := range map[string]models.ChannelOverwriteRequest{
+			"伺服器層級權限": {TargetType: models.OverwriteTargetEveryone, Allow: []string{"ban_members"}},
+			"同時允許與拒絕": {TargetType: models.OverwriteTargetEveryone, Allow: []string{"send_messages"}, Deny: []string{"send_messages"}},
+			"無效對象類型":  {TargetType: "group"},
+		} {
+			mockServerRepo := new(mockServerRepository)
+			mockChannelRepo := new(mockChannelRepository)
+			service := &permissionService{serverRepo: mockServerRepo, channelRepo: mockChannelRepo}
+
+			mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(newChannel(), nil).Once()
+			mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+
+			result, msgOpt := service.SetChannelOverwrite(ctx, ownerID.Hex(), channelID.Hex(), request)
+
+			assert.Nil(t, result, name)
+			assert.Equal(t, models.ErrInvalidParams, msgOpt.Code, name)
+			mockChannelRepo.AssertNotCalled(t, "UpdateChannel", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("沒有管理頻道權限", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		mockChannelRepo := new(mockChannelRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo, channelRepo: mockChannelRepo}
+
+		userID := primitive.NewObjectID()
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(newChannel(), nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockMemberRepo.On("GetServerMember", mock.Anything, serverID.Hex(), userID.Hex()).Return(&models.ServerMember{ServerID: serverID, UserID: userID}, nil).Once()
+
+		result, msgOpt := service.SetChannelOverwrite(ctx, userID.Hex(), channelID.Hex(), models.ChannelOverwriteRequest{
+			TargetType: models.OverwriteTargetEveryone,
+			Deny:       []string{"view_channel"},
+		})
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+	})
+
+	t.Run("刪除不存在的覆寫", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockChannelRepo := new(mockChannelRepository)
+		service := &permissionService{serverRepo: mockServerRepo, channelRepo: mockChannelRepo}
+
+		mockChannelRepo.On("GetChannelByID", mock.Anything, channelID.Hex()).Return(newChannel(), nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+
+		msgOpt := service.DeleteChannelOverwrite(ctx, ownerID.Hex(), channelID.Hex(), models.OverwriteTargetEveryone, "")
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrOverwriteNotFound, msgOpt.Code)
+	})
+
+	t.Run("私人頻道從列表中過濾", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo}
+
+		userID := primitive.NewObjectID()
+		publicChannel := models.Channel{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID}
+		privateChannel := *newChannel(models.PermissionOverwrite{TargetType: models.OverwriteTargetEveryone, Deny: models.PermissionViewChannel})
+
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockMemberRepo.On("GetServerMember", mock.Anything, serverID.Hex(), userID.Hex()).Return(&models.ServerMember{ServerID: serverID, UserID: userID}, nil).Once()
+
+		viewable, msgOpt := service.FilterViewableChannels(ctx, serverID.Hex(), userID.Hex(), []models.Channel{publicChannel, privateChannel})
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, []models.Channel{publicChannel}, viewable)
+	})
+}
+
+// TestLoadMemberContext_PassesContext 測試權限查詢沿用呼叫端的 context
+func TestLoadMemberContext_PassesContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serverID := primitive.NewObjectID()
+	userID := primitive.NewObjectID()
+	roleID := primitive.NewObjectID()
+	server := &models.Server{BaseModel: providers.BaseModel{ID: serverID}, OwnerID: primitive.NewObjectID()}
+	member := &models.ServerMember{ServerID: serverID, UserID: userID, Role: "member", RoleIDs: []primitive.ObjectID{roleID}}
+
+	mockServerRepo := new(mockServerRepository)
+	mockMemberRepo := new(mocks.ServerMemberRepository)
+	mockRoleRepo := new(mockRoleRepository)
+	service := &permissionService{serverRepo: mockServerRepo, serverMemberRepo: mockMemberRepo, roleRepo: mockRoleRepo}
+
+	mockServerRepo.On("GetServerByID", ctx, serverID.Hex()).Return(server, nil).Once()
+	mockMemberRepo.On("GetServerMember", ctx, serverID.Hex(), userID.Hex()).Return(member, nil).Once()
+	mockRoleRepo.On("GetRolesByIDs", ctx, []string{roleID.Hex()}).Return([]models.ServerRole{}, nil).Once()
+
+	_, msgOpt := service.GetMemberPermissions(ctx, serverID.Hex(), userID.Hex())
+
+	assert.Nil(t, msgOpt)
+	mockServerRepo.AssertExpectations(t)
+	mockMemberRepo.AssertExpectations(t)
+	mockRoleRepo.AssertExpectations(t)
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+)
+
+const (
+	PresignedStagingDir      = "tmp/presigned"  // 預簽名上傳的暫存目錄，確認後才複製到正式路徑
+	PresignedUploadURLExpiry = 15 * time.Minute // 預簽名上傳網址有效時間
+	PresignedUploadExpiry    = time.Hour        // 預簽名上傳保留時間，須晚於網址到期，逾期未確認即清除暫存物件
+	PresignedDownloadExpiry  = 5 * time.Minute  // 私人檔案下載網址有效時間
+)
+
+// InitiatePresignedUpload 檢查檔案資訊後產生預簽名上傳網址，客戶端直接上傳到儲存端後呼叫 ConfirmPresignedUpload
+func (fs *fileUploadService) InitiatePresignedUpload(userID string, request models.PresignedUploadRequest) (*models.PresignedUploadResponse, *models.MessageOptions) {
+	presigner, msgOpt := fs.presignProvider()
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	session, msgOpt := fs.newUploadSession(userID, request.FileType, request.FileName, request.FileSize, request.MimeType, request.SHA256, request.ServerID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	// 網址只能寫入暫存物件，確認時才複製到正式路徑，確認後網址仍有效也無法覆寫已驗證的檔案
+	stagingPath := filepath.Join(PresignedStagingDir, session.FileName)
+	uploadURL, err := presigner.PresignPutURL(stagingPath, session.MimeType, session.FileSize, PresignedUploadURLExpiry)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "產生上傳網址失敗",
+			Details: err.Error(),
+		}
+	}
+
+	now := time.Now()
+	session.UploadID = stagingPath
+	session.Presigned = true
+	session.ExpiresAt = now.Add(PresignedUploadExpiry)
+
+	if err := fs.uploadSessionRepo.CreateUploadSession(session); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "建立上傳紀錄失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return &models.PresignedUploadResponse{
+		UploadID:  session.ID.Hex(),
+		UploadURL: uploadURL,
+		Method:    http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":   session.MimeType,
+			"Content-Length": strconv.FormatInt(session.FileSize, 10),
+		},
+		ExpiresAt: now.Add(PresignedUploadURLExpiry).Unix(),
+	}, nil
+}
+
+// ConfirmPresignedUpload 確認客戶端已上傳，複製到正式路徑後比對 SHA256 與內容並建立檔案記錄
+// 驗證失敗時刪除檔案並將上傳標記為 failed，需重新取得上傳網址
+func (fs *fileUploadService) ConfirmPresignedUpload(userID string, uploadID string) (*models.FileResult, *models.MessageOptions) {
+	presigner, msgOpt := fs.presignProvider()
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	session, msgOpt := fs.getUploadingSession(userID, uploadID, true)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	if _, err := fs.fileProvider.GetFileInfo(session.UploadID); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrUploadIncomplete,
+			Message: "檔案尚未上傳",
+			Details: err.Error(),
+		}
+	}
+
+	// 先切換為確認中，避免重複確認
+	ok, err := fs.uploadSessionRepo.TransitionUploadSession(uploadID, models.UploadSessionUploading, models.UploadSessionCompleting, nil)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "更新上傳狀態失敗",
+			Details: err.Error(),
+		}
+	}
+	if !ok {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "上傳已結束",
+		}
+	}
+
+	filePath, err := presigner.CopyFile(session.UploadID, session.FilePath)
+	if err != nil {
+		// 暫存物件仍保留，恢復為上傳中讓客戶端可重試
+		fs.transitionUploadSession(uploadID, models.UploadSessionCompleting, models.UploadSessionUploading, nil)
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "複製上傳檔案失敗",
+			Details: err.Error(),
+		}
+	}
+	if err := fs.fileProvider.DeleteFile(session.UploadID); err != nil {
+		slog.Warn("無法刪除預簽名上傳的暫存檔案", "path", session.UploadID, "error", err)
+	}
+
+	return fs.finalizeUploadSession(userID, session, filePath)
+}
+
+// GetFileDownloadURL 獲取檔案下載連結，用於不公開的檔案（例如訊息附件）
+// 儲存服務支援時返回短效預簽名網址並以原始檔名下載，否則返回一般連結
+func (fs *fileUploadService) GetFileDownloadURL(fileID string) (string, *models.MessageOptions) {
+	file, msgOpt := fs.getServableFile(fileID)
+	if msgOpt != nil {
+		return "", msgOpt
+	}
+
+	return fs.presignedFileURL(file.FilePath, file.OriginalName)
+}
+
+// fileLinkURL 獲取檔案原檔或縮圖的連結，公開資源返回一般連結，其他檔案與附件下載相同使用短效預簽名網址
+func (fs *fileUploadService) fileLinkURL(file *models.UploadedFile, filePath string) (string, *models.MessageOptions) {
+	if slices.Contains(models.PublicFileTypes, file.FileType) {
+		return fs.fileProvider.GetFileURL(filePath), nil
+	}
+	return fs.presignedFileURL(filePath, "")
+}
+
+// presignedFileURL 儲存服務支援時返回短效預簽名網址，downloadName 非空時以附件方式下載，否則返回一般連結
+func (fs *fileUploadService) presignedFileURL(filePath string, downloadName string) (string, *models.MessageOptions) {
+	presigner, ok := fs.fileProvider.(providers.PresignProvider)
+	if !ok {
+		return fs.fileProvider.GetFileURL(filePath), nil
+	}
+
+	downloadURL, err := presigner.PresignGetURL(filePath, PresignedDownloadExpiry, downloadName)
+	if err != nil {
+		return "", &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "產生下載網址失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return downloadURL, nil
+}
+
+// presignProvider 取得支援預簽名網址的儲存服務
+func (fs *fileUploadService) presignProvider() (providers.PresignProvider, *models.MessageOptions) {
+	presigner, ok := fs.fileProvider.(providers.PresignProvider)
+	if !ok {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrPresignUnsupported,
+			Message: "目前的儲存服務不支援直接上傳",
+		}
+	}
+	return presigner, nil
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"bytes"
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mockPresignFileProvider 模擬支援預簽名網址的 FileProvider（MinIO）
+type mockPresignFileProvider struct {
+	mockFileProvider
+}
+
+func (m *mockPresignFileProvider) PresignPutURL(filename string, contentType string, size int64, expires time.Duration) (string, error) {
+	args := m.Called(filename, contentType, size, expires)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockPresignFileProvider) PresignGetURL(filePath string, expires time.Duration, downloadName string) (string, error) {
+	args := m.Called(filePath, expires, downloadName)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockPresignFileProvider) CopyFile(srcPath string, dstPath string) (string, error) {
+	args := m.Called(srcPath, dstPath)
+	return args.String(0), args.Error(1)
+}
+
+func newTestPresignedSession(userID primitive.ObjectID, content []byte) *models.UploadSession {
+	session := newTestUploadSession(userID, content, int64(len(content)))
+	session.UploadID = "tmp/presigned/123_abc.pdf"
+	session.Presigned = true
+	return session
+}
+
+func TestInitiatePresignedUpload(t *testing.T) {
+	userID := primitive.NewObjectID()
+	validHash := hex.EncodeToString(make([]byte, sha256.Size))
+	request := models.PresignedUploadRequest{
+		FileName: "report.pdf",
+		FileSize: 20 * 1024 * 1024,
+		MimeType: "application/pdf",
+		SHA256:   validHash,
+		FileType: "document",
+	}
+
+	t.Run("成功產生上傳網址", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		service := &fileUploadService{
+			fileProvider:      mockProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockProvider.On("PresignPutURL", mock.MatchedBy(func(path string) bool {
+			return strings.HasPrefix(path, PresignedStagingDir+"/")
+		}), "application/pdf", request.FileSize, PresignedUploadURLExpiry).Return("https://minio.example.com/upload?sig", nil).Once()
+		mockSessionRepo.On("CreateUploadSession", mock.MatchedBy(func(session *models.UploadSession) bool {
+			return session.Presigned &&
+				strings.HasPrefix(session.UploadID, PresignedStagingDir+"/") &&
+				strings.HasPrefix(session.FilePath, "document/") &&
+				session.Status == models.UploadSessionUploading
+		})).Return(nil).Once()
+
+		response, msgOpt := service.InitiatePresignedUpload(userID.Hex(), request)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "https://minio.example.com/upload?sig", response.UploadURL)
+		assert.Equal(t, "PUT", response.Method)
+		assert.Equal(t, "application/pdf", response.Headers["Content-Type"])
+		assert.Equal(t, "20971520", response.Headers["Content-Length"])
+		mockProvider.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("本地儲存不支援", func(t *testing.T) {
+		service := &fileUploadService{fileProvider: new(mockFileProvider)}
+
+		response, msgOpt := service.InitiatePresignedUpload(userID.Hex(), request)
+
+		assert.Nil(t, response)
+		assert.Equal(t, models.ErrPresignUnsupported, msgOpt.Code)
+	})
+
+	t.Run("檔案類型不允許時不產生網址", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		service := &fileUploadService{fileProvider: mockProvider}
+
+		invalid := request
+		invalid.FileName = "script.exe"
+		invalid.MimeType = "application/x-msdownload"
+		_, msgOpt := service.InitiatePresignedUpload(userID.Hex(), invalid)
+
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+		mockProvider.AssertNotCalled(t, "PresignPutURL", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestConfirmPresignedUpload(t *testing.T) {
+	userID := primitive.NewObjectID()
+	content := []byte("%PDF-1.4 presigned upload")
+
+	t.Run("複製到正式路徑並建立檔案", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestPresignedSession(userID, content)
+		sessionID := session.ID.Hex()
+		service := &fileUploadService{
+			fileProvider:      mockProvider,
+			fileRepo:          mockFileRepo,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", sessionID).Return(session, nil).Once()
+		mockProvider.On("GetFileInfo", session.UploadID).Return(&mockFileInfo{size: session.FileSize}, nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionUploading, models.UploadSessionCompleting, map[string]any(nil)).Return(true, nil).Once()
+		mockProvider.On("CopyFile", session.UploadID, session.FilePath).Return(session.FilePath, nil).Once()
+		mockProvider.On("DeleteFile", session.UploadID).Return(nil).Once()
+		// 驗證雜湊與背景惡意軟體掃描各讀取一次
+		mockProvider.On("GetFile", session.FilePath).Return(io.NopCloser(bytes.NewReader(content)), nil).Twice()
+		mockFileRepo.On("AcquireBlob", session.Hash).Return(nil, nil).Once()
+		mockFileRepo.On("RegisterBlob", mock.Anything).Return(&models.FileBlob{Hash: session.Hash, FilePath: session.FilePath, RefCount: 1}, nil).Once()
+		mockFileRepo.On("CreateFile", mock.MatchedBy(func(file *models.UploadedFile) bool {
+			return file.FilePath == session.FilePath && file.Hash == session.Hash && file.Status == "processing"
+		})).Return(nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionCompleting, models.UploadSessionCompleted, mock.Anything).Return(true, nil).Once()
+		scanned := make(chan struct{})
+		mockFileRepo.On("UpdateFileStatus", mock.Anything, "verified").Return(nil).Once().Run(func(args mock.Arguments) {
+			close(scanned)
+		})
+
+		result, msgOpt := service.ConfirmPresignedUpload(userID.Hex(), sessionID)
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, session.FilePath, result.FilePath)
+		assert.Equal(t, "processing", result.Status)
+
+		select {
+		case <-scanned:
+		case <-time.After(time.Second):
+			t.Fatal("背景掃描未完成")
+		}
+		mockProvider.AssertExpectations(t)
+		mockFileRepo.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("尚未上傳", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestPresignedSession(userID, content)
+		service := &fileUploadService{
+			fileProvider:      mockProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", session.ID.Hex()).Return(session, nil).Once()
+		mockProvider.On("GetFileInfo", session.UploadID).Return(nil, errors.New("object not found")).Once()
+
+		result, msgOpt := service.ConfirmPresignedUpload(userID.Hex(), session.ID.Hex())
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrUploadIncomplete, msgOpt.Code)
+		mockSessionRepo.AssertNotCalled(t, "TransitionUploadSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("雜湊不符時刪除檔案並標記失敗", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestPresignedSession(userID, content)
+		sessionID := session.ID.Hex()
+		service := &fileUploadService{
+			fileProvider:      mockProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+		tampered := bytes.Replace(content, []byte("presigned"), []byte("tampered!"), 1)
+
+		mockSessionRepo.On("GetUploadSessionByID", sessionID).Return(session, nil).Once()
+		mockProvider.On("GetFileInfo", session.UploadID).Return(&mockFileInfo{size: session.FileSize}, nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionUploading, models.UploadSessionCompleting, map[string]any(nil)).Return(true, nil).Once()
+		mockProvider.On("CopyFile", session.UploadID, session.FilePath).Return(session.FilePath, nil).Once()
+		mockProvider.On("DeleteFile", session.UploadID).Return(nil).Once()
+		mockProvider.On("GetFile", session.FilePath).Return(io.NopCloser(bytes.NewReader(tampered)), nil).Once()
+		mockProvider.On("DeleteFile", session.FilePath).Return(nil).Once()
+		mockSessionRepo.On("TransitionUploadSession", sessionID, models.UploadSessionCompleting, models.UploadSessionFailed, map[string]any(nil)).Return(true, nil).Once()
+
+		result, msgOpt := service.ConfirmPresignedUpload(userID.Hex(), sessionID)
+
+		assert.Nil(t, result)
+		assert.Equal(t, models.ErrHashMismatch, msgOpt.Code)
+		mockProvider.AssertExpectations(t)
+		mockSessionRepo.AssertExpectations(t)
+	})
+
+	t.Run("分段上傳無法以預簽名方式確認", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		mockSessionRepo := new(mockUploadSessionRepository)
+		session := newTestUploadSession(userID, content, int64(len(content)), 1)
+		service := &fileUploadService{
+			fileProvider:      mockProvider,
+			uploadSessionRepo: mockSessionRepo,
+		}
+
+		mockSessionRepo.On("GetUploadSessionByID", session.ID.Hex()).Return(session, nil).Once()
+
+		_, msgOpt := service.ConfirmPresignedUpload(userID.Hex(), session.ID.Hex())
+
+		assert.Equal(t, models.ErrNotFound, msgOpt.Code)
+		mockProvider.AssertNotCalled(t, "CopyFile", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGetFileDownloadURL(t *testing.T) {
+	file := &models.UploadedFile{
+		BaseModel:    providers.BaseModel{ID: primitive.NewObjectID()},
+		OriginalName: "季報.pdf",
+		FilePath:     "document/123_abc.pdf",
+		Status:       "verified",
+	}
+
+	t.Run("返回短效預簽名網址", func(t *testing.T) {
+		mockProvider := new(mockPresignFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileProvider: mockProvider, fileRepo: mockFileRepo}
+
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(file, nil).Once()
+		mockProvider.On("GetFileInfo", file.FilePath).Return(&mockFileInfo{}, nil).Once()
+		mockProvider.On("PresignGetURL", file.FilePath, PresignedDownloadExpiry, "季報.pdf").Return("https://minio.example.com/doc?sig", nil).Once()
+
+		url, msgOpt := service.GetFileDownloadURL(file.ID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "https://minio.example.com/doc?sig", url)
+		mockProvider.AssertNotCalled(t, "GetFileURL", mock.Anything)
+		mockProvider.AssertExpectations(t)
+	})
+
+	t.Run("不支援預簽名時返回一般連結", func(t *testing.T) {
+		mockProvider := new(mockFileProvider)
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileProvider: mockProvider, fileRepo: mockFileRepo}
+
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(file, nil).Once()
+		mockProvider.On("GetFileInfo", file.FilePath).Return(&mockFileInfo{}, nil).Once()
+		mockProvider.On("GetFileURL", file.FilePath).Return("/uploads/document/123_abc.pdf").Once()
+
+		url, msgOpt := service.GetFileDownloadURL(file.ID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, "/uploads/document/123_abc.pdf", url)
+	})
+
+	t.Run("未驗證的檔案", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{fileProvider: new(mockPresignFileProvider), fileRepo: mockFileRepo}
+
+		quarantined := *file
+		quarantined.Status = "failed"
+		mockFileRepo.On("GetFileByID", file.ID.Hex()).Return(&quarantined, nil).Once()
+
+		_, msgOpt := service.GetFileDownloadURL(file.ID.Hex())
+
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestCleanupExpiredPresignedUploads(t *testing.T) {
+	mockProvider := new(mockPresignFileProvider)
+	mockSessionRepo := new(mockUploadSessionRepository)
+	pending := newTestPresignedSession(primitive.NewObjectID(), []byte("content"))
+	// 確認後網址仍有效期間可能再次上傳到暫存物件，過期時同樣清除
+	completed := newTestPresignedSession(primitive.NewObjectID(), []byte("content"))
+	completed.UploadID = "tmp/presigned/456_def.pdf"
+	completed.Status = models.UploadSessionCompleted
+
+	service := &fileUploadService{
+		fileProvider:      mockProvider,
+		uploadSessionRepo: mockSessionRepo,
+	}
+
+	mockSessionRepo.On("GetExpiredUploadSessions", mock.Anything).Return([]models.UploadSession{*pending, *completed}, nil).Once()
+	mockProvider.On("DeleteFile", pending.UploadID).Return(nil).Once()
+	mockProvider.On("DeleteFile", completed.UploadID).Return(nil).Once()
+	mockSessionRepo.On("DeleteUploadSession", pending.ID.Hex()).Return(nil).Once()
+	mockSessionRepo.On("DeleteUploadSession", completed.ID.Hex()).Return(nil).Once()
+
+	msgOpt := service.CleanupExpiredChunkedUploads()
+
+	assert.Nil(t, msgOpt)
+	mockProvider.AssertNotCalled(t, "AbortChunkedUpload", mock.Anything, mock.Anything)
+	mockProvider.AssertExpectations(t)
+	mockSessionRepo.AssertExpectations(t)
+}
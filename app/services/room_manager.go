@@ -6,6 +6,7 @@ import (
 	"chat_app_backend/app/repositories"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
 	"sync"
@@ -18,23 +19,25 @@ import (
 
 // roomManager 管理房間的創建、加入、離開等操作
 type roomManager struct {
-	odm              providers.ODM
-	rooms            map[string]*Room
-	roomPubSubs      map[string]*redis.PubSub
-	redisClient      *redis.Client
-	serverMemberRepo repositories.ServerMemberRepository
-	mutex            sync.RWMutex
-	pubSubMutex      sync.RWMutex
+	odm               providers.ODM
+	rooms             map[string]*Room
+	roomPubSubs       map[string]*redis.PubSub
+	redisClient       *redis.Client
+	serverMemberRepo  repositories.ServerMemberRepository
+	permissionService PermissionService
+	mutex             sync.RWMutex
+	pubSubMutex       sync.RWMutex
 }
 
 // NewRoomManager 創建新的房間管理器
-func NewRoomManager(odm providers.ODM, redisClient *redis.Client, serverMemberRepo repositories.ServerMemberRepository) *roomManager {
+func NewRoomManager(odm providers.ODM, redisClient *redis.Client, serverMemberRepo repositories.ServerMemberRepository, permissionService PermissionService) *roomManager {
 	return &roomManager{
-		odm:              odm,
-		rooms:            make(map[string]*Room, 1000),
-		roomPubSubs:      make(map[string]*redis.PubSub),
-		redisClient:      redisClient,
-		serverMemberRepo: serverMemberRepo,
+		odm:               odm,
+		rooms:             make(map[string]*Room, 1000),
+		roomPubSubs:       make(map[string]*redis.PubSub),
+		redisClient:       redisClient,
+		serverMemberRepo:  serverMemberRepo,
+		permissionService: permissionService,
 	}
 }
 
@@ -109,26 +112,28 @@ func (rm *roomManager) InitRoom(roomType models.RoomType, roomID string) *Room {
 		}()
 
 		for msg := range pubsub.Channel() {
-			var message *WsMessage[MessageResponse]
+			// Data 保持原始 JSON 轉發，讓訊息以外的事件（如已讀狀態）也能共用此通道
+			var message *WsMessage[json.RawMessage]
 			if err := json.Unmarshal([]byte(msg.Payload), &message); err != nil {
 				slog.Error("解析消息失敗", "error", err)
 				continue
 			}
+			var sender roomEventSender
+			_ = json.Unmarshal(message.Data, &sender)
 			room.Mutex.RLock()
 			instanceID := os.Getenv("HOSTNAME")
 			//nolint:gosec // channel 與 HOSTNAME 為內部受控變數，無日誌注入風險
 			slog.Info("[跨實例廣播] Subscribe 收到", "channel", "room:"+key.String(), "instance", instanceID, "local_clients", len(room.Clients))
 			for client := range room.Clients {
+				if !shouldDeliverToClient(message.Action, sender.SenderID, client.UserID) {
+					continue
+				}
 				go func(c *Client) {
-					outMsg := &WsMessage[MessageResponse]{
-						Data: message.Data,
+					outMsg := &WsMessage[json.RawMessage]{
+						Action: resolveClientAction(message.Action, sender.SenderID, c.UserID),
+						Data:   message.Data,
 					}
-					if c.UserID == message.Data.SenderID {
-						outMsg.Action = "message_sent"
-					} else {
-						outMsg.Action = "new_message"
-					}
-					rm.safelyBroadcastToClient(c, outMsg)
+					rm.safelyBroadcastToClient(c, key, outMsg)
 				}(client)
 			}
 			room.Mutex.RUnlock()
@@ -246,8 +251,19 @@ func (rm *roomManager) CheckUserAllowedJoinRoom(ctx context.Context, userID stri
 
 		if !isMember {
 			slog.Debug("用戶非伺服器成員", "server_id", channel.ServerID.Hex(), "user_id", userID)
+			return false, nil
+		}
+
+		// 套用頻道權限覆寫（私人頻道等）
+		msgOpt := rm.permissionService.CheckChannelPermission(ctx, roomID, userID, models.PermissionViewChannel)
+		if msgOpt != nil {
+			if msgOpt.Code == models.ErrInternalServer {
+				return false, fmt.Errorf("%s: %v", msgOpt.Message, msgOpt.Details)
+			}
+			slog.Debug("用戶無權查看頻道", "channel_id", roomID, "user_id", userID)
+			return false, nil
 		}
-		return isMember, nil
+		return true, nil
 	}
 	return false, nil
 }
@@ -279,14 +295,14 @@ func (rm *roomManager) broadcastWorker(room *Room) {
 	for msg := range room.Broadcast {
 		room.Mutex.RLock()
 		for client := range room.Clients {
-			go rm.safelyBroadcastToClient(client, msg)
+			go rm.safelyBroadcastToClient(client, room.Key, msg)
 		}
 		room.Mutex.RUnlock()
 	}
 }
 
 // safelyBroadcastToClient 安全發送消息
-func (rm *roomManager) safelyBroadcastToClient(client *Client, message *WsMessage[MessageResponse]) {
+func (rm *roomManager) safelyBroadcastToClient(client *Client, key RoomKey, message any) {
 	// 使用統一的發送機制，而不是直接寫入 WebSocket
 	if err := client.SendMessage(message); err != nil {
 		slog.Debug("發送消息失敗", "user_id", client.UserID, "error", err)
@@ -297,7 +313,7 @@ func (rm *roomManager) safelyBroadcastToClient(client *Client, message *WsMessag
 
 	// 更新房間活躍時間
 	client.ActivityMutex.Lock()
-	client.RoomActivity[message.Data.RoomID] = time.Now()
+	client.RoomActivity[key.String()] = time.Now()
 	client.ActivityMutex.Unlock()
-	// rm.redisClient.Set(context.Background(), "user:"+client.UserID+":room:"+message.Data.RoomID+":last_active", time.Now().UnixMilli(), 24*time.Hour)
+	// rm.redisClient.Set(context.Background(), "user:"+client.UserID+":room:"+key.String()+":last_active", time.Now().UnixMilli(), 24*time.Hour)
 }
@@ -63,7 +63,7 @@ func TestNewRoomManager(t *testing.T) {
 	redisClient, _ := redismock.NewClientMock()
 	mockRepo := new(mocks.ServerMemberRepository)
 	mockODM := new(mocks.ODM)
-	rm := NewRoomManager(mockODM, redisClient, mockRepo)
+	rm := NewRoomManager(mockODM, redisClient, mockRepo, nil)
 
 	assert.NotNil(t, rm, "RoomManager 不應為 nil")
 	assert.NotNil(t, rm.rooms, "Rooms map 應該被初始化")
@@ -71,7 +71,7 @@ func TestNewRoomManager(t *testing.T) {
 
 func TestRoomManager_InitRoom(t *testing.T) {
 	redisClient, _ := redismock.NewClientMock()
-	rm := NewRoomManager(nil, redisClient, new(mocks.ServerMemberRepository))
+	rm := NewRoomManager(nil, redisClient, new(mocks.ServerMemberRepository), nil)
 	roomID := primitive.NewObjectID().Hex()
 
 	t.Run("應該在房間不存在時創建新房間", func(t *testing.T) {
@@ -95,7 +95,7 @@ func TestRoomManager_InitRoom(t *testing.T) {
 
 func TestRoomManager_JoinAndLeaveRoom(t *testing.T) {
 	redisClient, _ := redismock.NewClientMock()
-	rm := NewRoomManager(nil, redisClient, new(mocks.ServerMemberRepository))
+	rm := NewRoomManager(nil, redisClient, new(mocks.ServerMemberRepository), nil)
 	roomID := primitive.NewObjectID().Hex()
 	client1 := newTestClient(primitive.NewObjectID().Hex())
 	client2 := newTestClient(primitive.NewObjectID().Hex())
@@ -160,7 +160,7 @@ func TestRoomManager_JoinAndLeaveRoom(t *testing.T) {
 
 func TestRoomManager_Broadcast(t *testing.T) {
 	redisClient, _ := redismock.NewClientMock()
-	rm := NewRoomManager(nil, redisClient, new(mocks.ServerMemberRepository))
+	rm := NewRoomManager(nil, redisClient, new(mocks.ServerMemberRepository), nil)
 	roomID := primitive.NewObjectID().Hex()
 
 	client1 := newTestClient(primitive.NewObjectID().Hex())
@@ -240,7 +240,7 @@ func TestRoomManager_Broadcast(t *testing.T) {
 
 func TestRoomManager_Concurrency(t *testing.T) {
 	redisClient, _ := redismock.NewClientMock()
-	rm := NewRoomManager(nil, redisClient, new(mocks.ServerMemberRepository))
+	rm := NewRoomManager(nil, redisClient, new(mocks.ServerMemberRepository), nil)
 	numGoroutines := 100
 	numRooms := 10
 
@@ -287,7 +287,7 @@ func TestCheckUserAllowedJoinRoom(t *testing.T) {
 
 	t.Run("DM 房間 - 允許", func(t *testing.T) {
 		mockODM := new(mocks.ODM)
-		rm := NewRoomManager(mockODM, nil, nil)
+		rm := NewRoomManager(mockODM, nil, nil, nil)
 
 		// 期望 FindOne 被調用並返回非錯誤結果
 		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.DMRoom")).Return(nil).Run(func(args mock.Arguments) {
@@ -304,7 +304,7 @@ func TestCheckUserAllowedJoinRoom(t *testing.T) {
 
 	t.Run("DM 房間 - 未找到", func(t *testing.T) {
 		mockODM := new(mocks.ODM)
-		rm := NewRoomManager(mockODM, nil, nil)
+		rm := NewRoomManager(mockODM, nil, nil, nil)
 
 		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.DMRoom")).Return(providers.ErrDocumentNotFound).Once()
 
@@ -318,7 +318,8 @@ func TestCheckUserAllowedJoinRoom(t *testing.T) {
 	t.Run("頻道房間 - 作為成員允許", func(t *testing.T) {
 		mockODM := new(mocks.ODM)
 		mockRepo := new(mocks.ServerMemberRepository)
-		rm := NewRoomManager(mockODM, nil, mockRepo)
+		mockPS := new(mocks.PermissionService)
+		rm := NewRoomManager(mockODM, nil, mockRepo, mockPS)
 
 		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Channel")).Return(nil).Run(func(args mock.Arguments) {
 			arg := args.Get(2).(*models.Channel)
@@ -331,6 +332,7 @@ func TestCheckUserAllowedJoinRoom(t *testing.T) {
 		}).Once()
 
 		mockRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(true, nil).Once()
+		mockPS.On("CheckChannelPermission", mock.Anything, roomID.Hex(), userID.Hex(), models.PermissionViewChannel).Return(nil).Once()
 
 		allowed, err := rm.CheckUserAllowedJoinRoom(context.Background(), userID.Hex(), roomID.Hex(), models.RoomTypeChannel)
 
@@ -338,12 +340,40 @@ func TestCheckUserAllowedJoinRoom(t *testing.T) {
 		assert.NoError(t, err)
 		mockODM.AssertExpectations(t)
 		mockRepo.AssertExpectations(t)
+		mockPS.AssertExpectations(t)
+	})
+
+	t.Run("頻道房間 - 私人頻道拒絕無查看權限的成員", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockRepo := new(mocks.ServerMemberRepository)
+		mockPS := new(mocks.PermissionService)
+		rm := NewRoomManager(mockODM, nil, mockRepo, mockPS)
+
+		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Channel")).Return(nil).Run(func(args mock.Arguments) {
+			arg := args.Get(2).(*models.Channel)
+			*arg = models.Channel{ServerID: serverID}
+		}).Once()
+
+		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Server")).Return(nil).Run(func(args mock.Arguments) {
+			arg := args.Get(2).(*models.Server)
+			*arg = models.Server{}
+		}).Once()
+
+		mockRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(true, nil).Once()
+		mockPS.On("CheckChannelPermission", mock.Anything, roomID.Hex(), userID.Hex(), models.PermissionViewChannel).
+			Return(&models.MessageOptions{Code: models.ErrNoServerPermission, Message: "權限不足"}).Once()
+
+		allowed, err := rm.CheckUserAllowedJoinRoom(context.Background(), userID.Hex(), roomID.Hex(), models.RoomTypeChannel)
+
+		assert.False(t, allowed)
+		assert.NoError(t, err)
+		mockPS.AssertExpectations(t)
 	})
 
 	t.Run("頻道房間 - 非成員被拒絕", func(t *testing.T) {
 		mockODM := new(mocks.ODM)
 		mockRepo := new(mocks.ServerMemberRepository)
-		rm := NewRoomManager(mockODM, nil, mockRepo)
+		rm := NewRoomManager(mockODM, nil, mockRepo, nil)
 
 		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Channel")).Return(nil).Run(func(args mock.Arguments) {
 			arg := args.Get(2).(*models.Channel)
@@ -367,7 +397,7 @@ func TestCheckUserAllowedJoinRoom(t *testing.T) {
 
 	t.Run("頻道房間 - 查找頻道時 ODM 錯誤", func(t *testing.T) {
 		mockODM := new(mocks.ODM)
-		rm := NewRoomManager(mockODM, nil, nil)
+		rm := NewRoomManager(mockODM, nil, nil, nil)
 		expectedErr := errors.New("db connection error")
 
 		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.Channel")).Return(expectedErr).Once()
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log/slog"
 	"mime/multipart"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -28,6 +29,10 @@ type serverService struct {
 	userService         UserService
 	clientManager       ClientManager
 	cache               providers.CacheProvider // 用於清除成員權限快取
+	permissionService   PermissionService
+	inviteRepo          repositories.InviteRepository
+	moderationRepo      repositories.ModerationRepository // 加入伺服器時檢查封鎖
+	auditLogRepo        repositories.AuditLogRepository
 }
 
 func NewServerService(cfg *config.Config,
@@ -42,6 +47,10 @@ func NewServerService(cfg *config.Config,
 	userService UserService,
 	clientManager ClientManager,
 	cache providers.CacheProvider,
+	permissionService PermissionService,
+	inviteRepo repositories.InviteRepository,
+	moderationRepo repositories.ModerationRepository,
+	auditLogRepo repositories.AuditLogRepository,
 ) *serverService {
 	return &serverService{
 		config:              cfg,
@@ -56,6 +65,10 @@ func NewServerService(cfg *config.Config,
 		userService:         userService,
 		clientManager:       clientManager,
 		cache:               cache,
+		permissionService:   permissionService,
+		inviteRepo:          inviteRepo,
+		moderationRepo:      moderationRepo,
+		auditLogRepo:        auditLogRepo,
 	}
 }
 
@@ -185,9 +198,11 @@ func (ss *serverService) CreateServer(userID string, name string, file multipart
 	// 保存到資料庫
 	createdServer, err := ss.serverRepo.CreateServer(server)
 	if err != nil {
-		// 如果保存失敗，嘗試刪除已上傳的檔案
-		if deleteErr := ss.fileUploadService.DeleteFile(uploadResult.FilePath); deleteErr != nil {
-			fmt.Printf("清理上傳檔案失敗: %v\n", deleteErr)
+		// 如果保存失敗，嘗試刪除已上傳的檔案（以ID刪除，相同內容的其他檔案共用同一路徑）
+		if !uploadResult.ID.IsZero() {
+			if deleteErr := ss.fileUploadService.DeleteFileByID(uploadResult.ID.Hex(), userID); deleteErr != nil {
+				fmt.Printf("清理上傳檔案失敗: %v\n", deleteErr)
+			}
 		}
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
@@ -217,20 +232,17 @@ func (ss *serverService) CreateServer(userID string, name string, file multipart
 	}
 
 	// 返回響應格式
-	var pictureURL string
-	// 如果有圖片ID，則取得圖片URL
-	if !createdServer.ImageID.IsZero() {
-		if url, err := ss.fileUploadService.GetFileURLByID(createdServer.ImageID.Hex()); err == nil {
-			pictureURL = url
-		}
-	}
-
+	// 圖片在背景掃描與產生縮圖完成前尚未驗證，此時連結為空，以圖片ID與處理狀態讓客戶端完成後重新取得
 	serverResponse := &models.ServerResponse{
 		ID:          createdServer.GetID(),
 		Name:        createdServer.Name,
-		PictureURL:  pictureURL,
+		PictureURL:  uploadResult.FileURL,
 		Description: createdServer.Description,
 	}
+	if !uploadResult.ID.IsZero() {
+		serverResponse.PictureID = uploadResult.ID.Hex()
+		serverResponse.PictureStatus = uploadResult.Status
+	}
 
 	return serverResponse, nil
 }
@@ -421,7 +433,7 @@ func (ss *serverService) UpdateServer(userID string, serverID string, updates ma
 	}
 
 	// 獲取伺服器信息
-	server, err := ss.serverRepo.GetServerByID(serverID)
+	server, err := ss.serverRepo.GetServerByID(context.TODO(), serverID)
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrNotFound,
@@ -430,12 +442,17 @@ func (ss *serverService) UpdateServer(userID string, serverID string, updates ma
 		}
 	}
 
-	// 檢查用戶是否有權限更新（擁有者或管理員）
+	// 檢查用戶是否有權限更新（擁有者或具有管理伺服器權限的成員）
 	if server.OwnerID.Hex() != userID {
-		// 可以在未來添加管理員權限檢查
-		return nil, &models.MessageOptions{
-			Code:    models.ErrUnauthorized,
-			Message: "無權限更新此伺服器",
+		msgOpt := ss.permissionService.CheckPermission(context.TODO(), serverID, userID, models.PermissionManageServer)
+		if msgOpt != nil {
+			if msgOpt.Code == models.ErrInternalServer {
+				return nil, msgOpt
+			}
+			return nil, &models.MessageOptions{
+				Code:    models.ErrUnauthorized,
+				Message: "無權限更新此伺服器",
+			}
 		}
 	}
 
@@ -450,7 +467,7 @@ func (ss *serverService) UpdateServer(userID string, serverID string, updates ma
 	}
 
 	// 獲取更新後的伺服器信息
-	updatedServer, err := ss.serverRepo.GetServerByID(serverID)
+	updatedServer, err := ss.serverRepo.GetServerByID(context.TODO(), serverID)
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
@@ -459,6 +476,18 @@ func (ss *serverService) UpdateServer(userID string, serverID string, updates ma
 		}
 	}
 
+	if changes := auditChanges(server, updatedServer); len(changes) > 0 {
+		actorObjectID, _ := primitive.ObjectIDFromHex(userID)
+		recordAuditLog(ss.auditLogRepo, &models.AuditLog{
+			ServerID:   server.ID,
+			ActorID:    actorObjectID,
+			Action:     models.AuditActionServerUpdate,
+			TargetType: models.AuditTargetServer,
+			TargetID:   server.ID,
+			Changes:    changes,
+		})
+	}
+
 	// 獲取圖片URL
 	var pictureURL string
 	if !updatedServer.ImageID.IsZero() {
@@ -488,7 +517,7 @@ func (ss *serverService) DeleteServer(userID string, serverID string) *models.Me
 	}
 
 	// 獲取伺服器信息
-	server, err := ss.serverRepo.GetServerByID(serverID)
+	server, err := ss.serverRepo.GetServerByID(context.TODO(), serverID)
 	if err != nil {
 		return &models.MessageOptions{
 			Code:    models.ErrNotFound,
@@ -565,7 +594,7 @@ func (ss *serverService) GetServerByID(userID string, serverID string) (*models.
 	}
 
 	// 獲取伺服器信息
-	server, err := ss.serverRepo.GetServerByID(serverID)
+	server, err := ss.serverRepo.GetServerByID(context.TODO(), serverID)
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrNotFound,
@@ -621,7 +650,7 @@ func (ss *serverService) GetServerDetailByID(userID string, serverID string) (*m
 	}
 
 	// 獲取伺服器信息
-	server, err := ss.serverRepo.GetServerByID(serverID)
+	server, err := ss.serverRepo.GetServerByID(context.TODO(), serverID)
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrNotFound,
@@ -703,6 +732,7 @@ func (ss *serverService) GetServerDetailByID(userID string, serverID string) (*m
 					IsOnline:     isOnline,
 					LastActiveAt: member.LastActiveAt.UnixMilli(),
 					JoinedAt:     member.JoinedAt.UnixMilli(),
+					RoleIDs:      roleIDsToHex(member.RoleIDs),
 				})
 			}
 		}
@@ -769,7 +799,7 @@ func (ss *serverService) JoinServer(userID string, serverID string) *models.Mess
 	}
 
 	// 獲取伺服器信息
-	server, err := ss.serverRepo.GetServerByID(serverID)
+	server, err := ss.serverRepo.GetServerByID(context.TODO(), serverID)
 	if err != nil {
 		return &models.MessageOptions{
 			Code:    models.ErrNotFound,
@@ -786,48 +816,83 @@ func (ss *serverService) JoinServer(userID string, serverID string) *models.Mess
 		}
 	}
 
+	memberCount, msgOpt := ss.checkCanJoinServer(server, userID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+
+	// 添加用戶到伺服器（默認角色為 member）
+	err = ss.serverMemberRepo.AddMemberToServer(serverID, userID, "member")
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "加入伺服器失敗",
+			Details: err.Error(),
+		}
+	}
+
+	ss.afterMemberJoined(serverID, userID, memberCount)
+
+	return nil
+}
+
+// checkCanJoinServer 檢查用戶是否可加入伺服器（尚非成員、未被封鎖且未達成員上限），返回目前成員數
+func (ss *serverService) checkCanJoinServer(server *models.Server, userID string) (int64, *models.MessageOptions) {
+	serverID := server.ID.Hex()
+
 	// 檢查用戶是否已經是成員
 	isMember, err := ss.serverMemberRepo.IsMemberOfServer(serverID, userID)
 	if err != nil {
-		return &models.MessageOptions{
+		return 0, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
 			Message: "檢查成員身份失敗",
 			Details: err.Error(),
 		}
 	}
 	if isMember {
-		return &models.MessageOptions{
+		return 0, &models.MessageOptions{
 			Code:    models.ErrOperationFailed,
 			Message: "您已經是此伺服器的成員",
 		}
 	}
 
+	// 檢查用戶是否被封鎖
+	ban, err := ss.moderationRepo.GetActiveBan(serverID, userID, time.Now().Unix())
+	if err != nil {
+		return 0, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "檢查封鎖狀態失敗",
+			Details: err.Error(),
+		}
+	}
+	if ban != nil {
+		return 0, &models.MessageOptions{
+			Code:    models.ErrUserBanned,
+			Message: "您已被此伺服器封鎖",
+		}
+	}
+
 	// 檢查伺服器是否已達到最大成員數限制
 	memberCount, err := ss.serverMemberRepo.GetMemberCount(serverID)
 	if err != nil {
-		return &models.MessageOptions{
+		return 0, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
 			Message: "獲取成員數量失敗",
 			Details: err.Error(),
 		}
 	}
 	if int(memberCount) >= server.MaxMembers {
-		return &models.MessageOptions{
+		return 0, &models.MessageOptions{
 			Code:    models.ErrForbidden,
 			Message: "伺服器已達到最大成員數限制",
 		}
 	}
 
-	// 添加用戶到伺服器（默認角色為 member）
-	err = ss.serverMemberRepo.AddMemberToServer(serverID, userID, "member")
-	if err != nil {
-		return &models.MessageOptions{
-			Code:    models.ErrInternalServer,
-			Message: "加入伺服器失敗",
-			Details: err.Error(),
-		}
-	}
+	return memberCount, nil
+}
 
+// afterMemberJoined 成員加入後清除快取並更新成員數量
+func (ss *serverService) afterMemberJoined(serverID string, userID string, memberCount int64) {
 	// 清除用戶的伺服器成員快取（已加入新伺服器）
 	if ss.cache != nil {
 		if cacheErr := ss.cache.Delete(utils.UserServersCacheKey(userID)); cacheErr != nil {
@@ -837,12 +902,12 @@ func (ss *serverService) JoinServer(userID string, serverID string) *models.Mess
 
 	// 更新伺服器成員數量快取
 	newMemberCount := int(memberCount) + 1
-	err = ss.serverRepo.UpdateMemberCount(serverID, newMemberCount)
+	err := ss.serverRepo.UpdateMemberCount(serverID, newMemberCount)
 	if err != nil {
 		fmt.Printf("更新成員數量快取失敗: %v\n", err)
 	}
 
-	return nil
+	recordMemberAuditLog(ss.auditLogRepo, serverID, userID, userID, models.AuditActionMemberJoin, "", nil)
 }
 
 // LeaveServer 離開伺服器
@@ -858,7 +923,7 @@ func (ss *serverService) LeaveServer(userID string, serverID string) *models.Mes
 	}
 
 	// 獲取伺服器信息
-	server, err := ss.serverRepo.GetServerByID(serverID)
+	server, err := ss.serverRepo.GetServerByID(context.TODO(), serverID)
 	if err != nil {
 		return &models.MessageOptions{
 			Code:    models.ErrNotFound,
@@ -917,6 +982,8 @@ func (ss *serverService) LeaveServer(userID string, serverID string) *models.Mes
 		}
 	}
 
+	recordMemberAuditLog(ss.auditLogRepo, serverID, userID, userID, models.AuditActionMemberLeave, "", nil)
+
 	return nil
 }
 
@@ -961,3 +1028,254 @@ func (ss *serverService) deleteServerChannelsAndCategories(serverID string) erro
 
 	return nil
 }
+
+// roleIDsToHex 將成員角色ID轉換為字串陣列
+func roleIDsToHex(roleIDs []primitive.ObjectID) []string {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		ids = append(ids, roleID.Hex())
+	}
+	return ids
+}
+
+// InviteCodeLength 邀請碼長度
+const InviteCodeLength = 10
+
+// CreateInvite 創建伺服器邀請碼，需要創建邀請權限
+func (ss *serverService) CreateInvite(userID string, serverID string, request models.CreateServerInviteRequest) (*models.ServerInviteResponse, *models.MessageOptions) {
+	if msgOpt := ss.permissionService.CheckPermission(context.TODO(), serverID, userID, models.PermissionCreateInvite); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	server, err := ss.serverRepo.GetServerByID(context.TODO(), serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrServerNotFound,
+			Message: "伺服器不存在",
+			Details: err.Error(),
+		}
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的用戶ID",
+			Details: err.Error(),
+		}
+	}
+
+	code, err := utils.GenerateSecureRandomString(InviteCodeLength)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "生成邀請碼失敗",
+			Details: err.Error(),
+		}
+	}
+
+	invite := &models.ServerInvite{
+		ServerID:  server.ID,
+		Code:      code,
+		CreatedBy: userObjectID,
+		MaxUses:   request.MaxUses,
+	}
+	if request.ExpiresIn > 0 {
+		invite.ExpiresAt = time.Now().Unix() + request.ExpiresIn
+	}
+
+	if err := ss.inviteRepo.CreateInvite(invite); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "創建邀請失敗",
+			Details: err.Error(),
+		}
+	}
+
+	response := toServerInviteResponse(*invite)
+	return &response, nil
+}
+
+// GetServerInvites 獲取伺服器仍有效的邀請列表，需要管理伺服器權限
+func (ss *serverService) GetServerInvites(userID string, serverID string) ([]models.ServerInviteResponse, *models.MessageOptions) {
+	if msgOpt := ss.permissionService.CheckPermission(context.TODO(), serverID, userID, models.PermissionManageServer); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	invites, err := ss.inviteRepo.GetInvitesByServerID(serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取邀請列表失敗",
+			Details: err.Error(),
+		}
+	}
+
+	// 已過期或用盡的邀請不再列出
+	now := time.Now().Unix()
+	responses := make([]models.ServerInviteResponse, 0, len(invites))
+	for _, invite := range invites {
+		if invite.IsExpired(now) || invite.IsExhausted() {
+			continue
+		}
+		responses = append(responses, toServerInviteResponse(invite))
+	}
+
+	return responses, nil
+}
+
+// RevokeInvite 撤銷邀請碼，創建者本人或具有管理伺服器權限的成員可撤銷
+func (ss *serverService) RevokeInvite(userID string, serverID string, inviteID string) *models.MessageOptions {
+	invite, err := ss.inviteRepo.GetInviteByID(inviteID)
+	if err != nil || invite.ServerID.Hex() != serverID || invite.Revoked {
+		return &models.MessageOptions{
+			Code:    models.ErrInviteNotFound,
+			Message: "邀請不存在",
+		}
+	}
+
+	if invite.CreatedBy.Hex() == userID {
+		// 創建者仍須是成員才能撤銷
+		isMember, err := ss.serverMemberRepo.IsMemberOfServer(serverID, userID)
+		if err != nil {
+			return &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "檢查成員身份失敗",
+				Details: err.Error(),
+			}
+		}
+		if !isMember {
+			return &models.MessageOptions{
+				Code:    models.ErrNotServerMember,
+				Message: "您不是此伺服器的成員",
+			}
+		}
+	} else if msgOpt := ss.permissionService.CheckPermission(context.TODO(), serverID, userID, models.PermissionManageServer); msgOpt != nil {
+		return msgOpt
+	}
+
+	if err := ss.inviteRepo.RevokeInvite(inviteID); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "撤銷邀請失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// AcceptInvite 透過邀請碼加入伺服器（私人伺服器亦可加入），返回加入的伺服器
+func (ss *serverService) AcceptInvite(userID string, code string) (*models.ServerResponse, *models.MessageOptions) {
+	// 驗證用戶是否存在
+	_, err := ss.userRepo.GetUserById(userID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "用戶不存在",
+			Details: err.Error(),
+		}
+	}
+
+	invite, err := ss.inviteRepo.GetInviteByCode(code)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInviteNotFound,
+			Message: "邀請不存在",
+		}
+	}
+
+	now := time.Now().Unix()
+	if msgOpt := checkInviteUsable(invite, now); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	serverID := invite.ServerID.Hex()
+	server, err := ss.serverRepo.GetServerByID(context.TODO(), serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "伺服器不存在",
+			Details: err.Error(),
+		}
+	}
+
+	memberCount, msgOpt := ss.checkCanJoinServer(server, userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	// 先佔用一次使用次數，並發接受時由資料庫條件保證不超過上限
+	claimed, err := ss.inviteRepo.ClaimInviteUse(invite.ID.Hex(), now)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "使用邀請失敗",
+			Details: err.Error(),
+		}
+	}
+	if !claimed {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInviteInvalid,
+			Message: "邀請已失效",
+		}
+	}
+
+	err = ss.serverMemberRepo.AddMemberToServer(serverID, userID, "member")
+	if err != nil {
+		if releaseErr := ss.inviteRepo.ReleaseInviteUse(invite.ID.Hex()); releaseErr != nil {
+			slog.Warn("無法歸還邀請使用次數", "invite_id", invite.ID.Hex(), "error", releaseErr)
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "加入伺服器失敗",
+			Details: err.Error(),
+		}
+	}
+
+	ss.afterMemberJoined(serverID, userID, memberCount)
+
+	var pictureURL string
+	if !server.ImageID.IsZero() && ss.fileUploadService != nil {
+		if url, err := ss.fileUploadService.GetFileURLByID(server.ImageID.Hex()); err == nil {
+			pictureURL = url
+		}
+	}
+
+	return &models.ServerResponse{
+		ID:          server.ID,
+		Name:        server.Name,
+		PictureURL:  pictureURL,
+		Description: server.Description,
+	}, nil
+}
+
+// checkInviteUsable 檢查邀請是否可使用（未撤銷、未過期、未用盡）
+func checkInviteUsable(invite *models.ServerInvite, now int64) *models.MessageOptions {
+	switch {
+	case invite.Revoked:
+		return &models.MessageOptions{Code: models.ErrInviteInvalid, Message: "邀請已被撤銷"}
+	case invite.IsExpired(now):
+		return &models.MessageOptions{Code: models.ErrInviteInvalid, Message: "邀請已過期"}
+	case invite.IsExhausted():
+		return &models.MessageOptions{Code: models.ErrInviteInvalid, Message: "邀請已達使用次數上限"}
+	}
+	return nil
+}
+
+// toServerInviteResponse 將邀請轉換為響應格式
+func toServerInviteResponse(invite models.ServerInvite) models.ServerInviteResponse {
+	return models.ServerInviteResponse{
+		ID:        invite.ID.Hex(),
+		Code:      invite.Code,
+		ServerID:  invite.ServerID.Hex(),
+		CreatedBy: invite.CreatedBy.Hex(),
+		MaxUses:   invite.MaxUses,
+		Uses:      invite.Uses,
+		ExpiresAt: invite.ExpiresAt,
+		CreatedAt: invite.CreatedAt.Unix(),
+	}
+}
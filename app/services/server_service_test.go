@@ -6,6 +6,7 @@ import (
 	"chat_app_backend/app/providers"
 	"context"
 	"errors"
+	"mime/multipart"
 	"testing"
 	"time"
 
@@ -58,8 +59,8 @@ func (m *mockServerRepository) DeleteServer(serverID string) error {
 	return args.Error(0)
 }
 
-func (m *mockServerRepository) GetServerByID(serverID string) (*models.Server, error) {
-	args := m.Called(serverID)
+func (m *mockServerRepository) GetServerByID(ctx context.Context, serverID string) (*models.Server, error) {
+	args := m.Called(ctx, serverID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -84,8 +85,8 @@ func (m *mockChannelRepository) GetChannelsByServerID(serverID string) ([]models
 	return args.Get(0).([]models.Channel), args.Error(1)
 }
 
-func (m *mockChannelRepository) GetChannelByID(channelID string) (*models.Channel, error) {
-	args := m.Called(channelID)
+func (m *mockChannelRepository) GetChannelByID(ctx context.Context, channelID string) (*models.Channel, error) {
+	args := m.Called(ctx, channelID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -112,6 +113,11 @@ func (m *mockChannelRepository) CheckChannelExists(channelID string) (bool, erro
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockChannelRepository) UpdateChannelPositions(serverID string, positions []models.ChannelPosition) error {
+	args := m.Called(serverID, positions)
+	return args.Error(0)
+}
+
 // mockChannelCategoryRepository 模擬 ChannelCategoryRepository
 type mockChannelCategoryRepository struct {
 	mock.Mock
@@ -153,6 +159,60 @@ func (m *mockChannelCategoryRepository) CheckChannelCategoryExists(categoryID st
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockChannelCategoryRepository) UpdateChannelCategoryPositions(serverID string, positions []models.CategoryPosition) error {
+	args := m.Called(serverID, positions)
+	return args.Error(0)
+}
+
+// mockInviteRepository 模擬 InviteRepository
+type mockInviteRepository struct {
+	mock.Mock
+}
+
+func (m *mockInviteRepository) CreateInvite(invite *models.ServerInvite) error {
+	args := m.Called(invite)
+	return args.Error(0)
+}
+
+func (m *mockInviteRepository) GetInviteByCode(code string) (*models.ServerInvite, error) {
+	args := m.Called(code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServerInvite), args.Error(1)
+}
+
+func (m *mockInviteRepository) GetInviteByID(inviteID string) (*models.ServerInvite, error) {
+	args := m.Called(inviteID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ServerInvite), args.Error(1)
+}
+
+func (m *mockInviteRepository) GetInvitesByServerID(serverID string) ([]models.ServerInvite, error) {
+	args := m.Called(serverID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ServerInvite), args.Error(1)
+}
+
+func (m *mockInviteRepository) RevokeInvite(inviteID string) error {
+	args := m.Called(inviteID)
+	return args.Error(0)
+}
+
+func (m *mockInviteRepository) ClaimInviteUse(inviteID string, now int64) (bool, error) {
+	args := m.Called(inviteID, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockInviteRepository) ReleaseInviteUse(inviteID string) error {
+	args := m.Called(inviteID)
+	return args.Error(0)
+}
+
 type mockServerClientManager struct {
 	mock.Mock
 }
@@ -167,10 +227,17 @@ func (m *mockServerClientManager) Register(client *Client) {
 func (m *mockServerClientManager) Unregister(client *Client) {
 }
 
+func (m *mockServerClientManager) DisconnectSession(userID string, sessionID string) {
+}
+
 func (m *mockServerClientManager) GetClient(userID string) (*Client, bool) {
 	return nil, false
 }
 
+func (m *mockServerClientManager) GetUserClients(userID string) []*Client {
+	return nil
+}
+
 func (m *mockServerClientManager) GetAllClients() map[*Client]bool {
 	return nil
 }
@@ -184,6 +251,15 @@ func (m *mockServerClientManager) StartHealthChecker(ctx context.Context) {
 	m.Called(ctx)
 }
 
+func (m *mockServerClientManager) OnClientEvent(action string, handler func(ClientEvent)) {
+}
+
+func (m *mockServerClientManager) PublishClientEvent(event ClientEvent) {
+}
+
+func (m *mockServerClientManager) StartEventSubscriber(ctx context.Context) {
+}
+
 // --- Tests ---
 
 func TestNewServerService(t *testing.T) {
@@ -211,6 +287,10 @@ func TestNewServerService(t *testing.T) {
 		nil,
 		mockClientMgr,
 		nil,
+		nil,
+		nil,
+		nil,
+		nil,
 	)
 
 	assert.NotNil(t, service)
@@ -433,6 +513,51 @@ func TestCreateServer(t *testing.T) {
 		mockChannelRepo.AssertExpectations(t)
 	})
 
+	t.Run("成功創建伺服器（圖片處理中）", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		mockServerRepo := new(mockServerRepository)
+		mockServerMemberRepo := new(mocks.ServerMemberRepository)
+		mockChannelRepo := new(mockChannelRepository)
+		mockCategoryRepo := new(mockChannelCategoryRepository)
+		mockFileService := new(mocks.FileUploadService)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+		imageID := primitive.NewObjectID()
+
+		service := &serverService{
+			userRepo:            mockUserRepo,
+			serverRepo:          mockServerRepo,
+			serverMemberRepo:    mockServerMemberRepo,
+			channelRepo:         mockChannelRepo,
+			channelCategoryRepo: mockCategoryRepo,
+			fileUploadService:   mockFileService,
+		}
+
+		mockUserRepo.On("GetUserById", userID.Hex()).Return(&models.User{BaseModel: providers.BaseModel{ID: userID}}, nil).Once()
+		mockFileService.On("UploadFileWithConfig", mock.Anything, mock.Anything, userID.Hex(), mock.AnythingOfType("*models.FileUploadConfig")).
+			Return(&models.FileResult{ID: imageID, Status: "processing"}, (*models.MessageOptions)(nil)).Once()
+		mockServerRepo.On("CreateServer", mock.MatchedBy(func(server *models.Server) bool {
+			return server.ImageID == imageID
+		})).Return(models.Server{BaseModel: providers.BaseModel{ID: serverID}, Name: "Test Server", OwnerID: userID}, nil).Once()
+		mockServerMemberRepo.On("AddMemberToServer", serverID.Hex(), userID.Hex(), "owner").Return(nil).Once()
+		mockCategoryRepo.On("CreateChannelCategory", mock.AnythingOfType("*models.ChannelCategory")).Return(nil).Times(2)
+		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return([]models.ChannelCategory{
+			{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, CategoryType: "text"},
+			{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, CategoryType: "voice"},
+		}, nil).Once()
+		mockChannelRepo.On("CreateChannel", mock.AnythingOfType("*models.Channel")).Return(nil).Times(2)
+
+		result, msgOpt := service.CreateServer(userID.Hex(), "Test Server", createTestFile([]byte("png")), &multipart.FileHeader{Filename: "icon.png"})
+
+		assert.Nil(t, msgOpt)
+		assert.Empty(t, result.PictureURL)
+		assert.Equal(t, imageID.Hex(), result.PictureID)
+		assert.Equal(t, "processing", result.PictureStatus)
+		mockFileService.AssertExpectations(t)
+		mockServerRepo.AssertExpectations(t)
+	})
+
 	t.Run("用戶不存在", func(t *testing.T) {
 		mockUserRepo := new(mocks.UserRepository)
 		userID := primitive.NewObjectID()
@@ -594,9 +719,9 @@ func TestUpdateServer(t *testing.T) {
 		updates := map[string]any{"name": "New Name"}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 		mockServerRepo.On("UpdateServer", serverID.Hex(), updates).Return(nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(updatedServer, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(updatedServer, nil).Once()
 		mockFileService.On("GetFileURLByID", mock.Anything).Return("", nil).Maybe()
 
 		result, msgOpt := service.UpdateServer(userID.Hex(), serverID.Hex(), updates)
@@ -609,17 +734,68 @@ func TestUpdateServer(t *testing.T) {
 		mockServerRepo.AssertExpectations(t)
 	})
 
+	t.Run("具管理伺服器權限的成員可更新", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		mockServerRepo := new(mockServerRepository)
+		mockPS := new(mocks.PermissionService)
+
+		userID := primitive.NewObjectID()
+		ownerID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+
+		service := &serverService{
+			userRepo:          mockUserRepo,
+			serverRepo:        mockServerRepo,
+			permissionService: mockPS,
+		}
+
+		user := &models.User{
+			BaseModel: providers.BaseModel{ID: userID},
+		}
+
+		server := &models.Server{
+			BaseModel: providers.BaseModel{ID: serverID},
+			Name:      "Old Name",
+			OwnerID:   ownerID,
+		}
+
+		updatedServer := &models.Server{
+			BaseModel: providers.BaseModel{ID: serverID},
+			Name:      "New Name",
+			OwnerID:   ownerID,
+		}
+
+		updates := map[string]any{"name": "New Name"}
+
+		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageServer).Return(nil).Once()
+		mockServerRepo.On("UpdateServer", serverID.Hex(), updates).Return(nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(updatedServer, nil).Once()
+
+		result, msgOpt := service.UpdateServer(userID.Hex(), serverID.Hex(), updates)
+
+		assert.Nil(t, msgOpt)
+		assert.NotNil(t, result)
+		assert.Equal(t, "New Name", result.Name)
+
+		mockPS.AssertExpectations(t)
+		mockServerRepo.AssertExpectations(t)
+	})
+
 	t.Run("無權限更新（非擁有者）", func(t *testing.T) {
 		mockUserRepo := new(mocks.UserRepository)
 		mockServerRepo := new(mockServerRepository)
+		mockPS := new(mocks.PermissionService)
 
 		userID := primitive.NewObjectID()
 		ownerID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
 
 		service := &serverService{
-			userRepo:   mockUserRepo,
-			serverRepo: mockServerRepo,
+			userRepo:          mockUserRepo,
+			serverRepo:        mockServerRepo,
+			permissionService: mockPS,
 		}
 
 		user := &models.User{
@@ -632,7 +808,9 @@ func TestUpdateServer(t *testing.T) {
 		}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockPS.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageServer).
+			Return(&models.MessageOptions{Code: models.ErrNoServerPermission, Message: "權限不足"}).Once()
 
 		result, msgOpt := service.UpdateServer(userID.Hex(), serverID.Hex(), map[string]any{})
 
@@ -678,7 +856,7 @@ func TestDeleteServer(t *testing.T) {
 		}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 		mockServerMemberRepo.On("GetServerMembers", serverID.Hex(), 1, 1000).Return([]models.ServerMember{}, int64(0), nil).Once()
 		mockChannelRepo.On("GetChannelsByServerID", serverID.Hex()).Return([]models.Channel{}, nil).Once()
 		mockCategoryRepo.On("GetChannelCategoriesByServerID", serverID.Hex()).Return([]models.ChannelCategory{}, nil).Once()
@@ -718,7 +896,7 @@ func TestDeleteServer(t *testing.T) {
 		}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 
 		msgOpt := service.DeleteServer(userID.Hex(), serverID.Hex())
 
@@ -735,6 +913,7 @@ func TestJoinServer(t *testing.T) {
 		mockUserRepo := new(mocks.UserRepository)
 		mockServerRepo := new(mockServerRepository)
 		mockServerMemberRepo := new(mocks.ServerMemberRepository)
+		mockModerationRepo := new(mockModerationRepository)
 
 		userID := primitive.NewObjectID()
 		serverID := primitive.NewObjectID()
@@ -743,6 +922,7 @@ func TestJoinServer(t *testing.T) {
 			userRepo:         mockUserRepo,
 			serverRepo:       mockServerRepo,
 			serverMemberRepo: mockServerMemberRepo,
+			moderationRepo:   mockModerationRepo,
 		}
 
 		user := &models.User{
@@ -756,8 +936,9 @@ func TestJoinServer(t *testing.T) {
 		}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(false, nil).Once()
+		mockModerationRepo.On("GetActiveBan", serverID.Hex(), userID.Hex(), mock.AnythingOfType("int64")).Return(nil, nil).Once()
 		mockServerMemberRepo.On("GetMemberCount", serverID.Hex()).Return(int64(10), nil).Once()
 		mockServerMemberRepo.On("AddMemberToServer", serverID.Hex(), userID.Hex(), "member").Return(nil).Once()
 		mockServerRepo.On("UpdateMemberCount", serverID.Hex(), 11).Return(nil).Once()
@@ -769,6 +950,48 @@ func TestJoinServer(t *testing.T) {
 		mockUserRepo.AssertExpectations(t)
 		mockServerRepo.AssertExpectations(t)
 		mockServerMemberRepo.AssertExpectations(t)
+		mockModerationRepo.AssertExpectations(t)
+	})
+
+	t.Run("被封鎖的用戶無法加入", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		mockServerRepo := new(mockServerRepository)
+		mockServerMemberRepo := new(mocks.ServerMemberRepository)
+		mockModerationRepo := new(mockModerationRepository)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+
+		service := &serverService{
+			userRepo:         mockUserRepo,
+			serverRepo:       mockServerRepo,
+			serverMemberRepo: mockServerMemberRepo,
+			moderationRepo:   mockModerationRepo,
+		}
+
+		user := &models.User{
+			BaseModel: providers.BaseModel{ID: userID},
+		}
+
+		server := &models.Server{
+			BaseModel:  providers.BaseModel{ID: serverID},
+			IsPublic:   true,
+			MaxMembers: 100,
+		}
+
+		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(false, nil).Once()
+		mockModerationRepo.On("GetActiveBan", serverID.Hex(), userID.Hex(), mock.AnythingOfType("int64")).
+			Return(&models.ServerBan{ServerID: serverID, UserID: userID}, nil).Once()
+
+		msgOpt := service.JoinServer(userID.Hex(), serverID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrUserBanned, msgOpt.Code)
+
+		mockServerMemberRepo.AssertNotCalled(t, "AddMemberToServer", mock.Anything, mock.Anything, mock.Anything)
+		mockModerationRepo.AssertExpectations(t)
 	})
 
 	t.Run("伺服器不開放加入", func(t *testing.T) {
@@ -793,7 +1016,7 @@ func TestJoinServer(t *testing.T) {
 		}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 
 		msgOpt := service.JoinServer(userID.Hex(), serverID.Hex())
 
@@ -828,7 +1051,7 @@ func TestJoinServer(t *testing.T) {
 		}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(true, nil).Once()
 
 		msgOpt := service.JoinServer(userID.Hex(), serverID.Hex())
@@ -868,7 +1091,7 @@ func TestLeaveServer(t *testing.T) {
 		}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(true, nil).Once()
 		mockServerMemberRepo.On("RemoveMemberFromServer", serverID.Hex(), userID.Hex()).Return(nil).Once()
 		mockServerMemberRepo.On("GetMemberCount", serverID.Hex()).Return(int64(10), nil).Once()
@@ -905,7 +1128,7 @@ func TestLeaveServer(t *testing.T) {
 		}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 
 		msgOpt := service.LeaveServer(userID.Hex(), serverID.Hex())
 
@@ -941,7 +1164,7 @@ func TestLeaveServer(t *testing.T) {
 		}
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(false, nil).Once()
 
 		msgOpt := service.LeaveServer(userID.Hex(), serverID.Hex())
@@ -985,7 +1208,7 @@ func TestGetServerByID(t *testing.T) {
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
 		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(true, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 		mockFileService.On("GetFileURLByID", mock.Anything).Return("", nil).Maybe()
 
 		result, msgOpt := service.GetServerByID(userID.Hex(), serverID.Hex())
@@ -1024,7 +1247,7 @@ func TestGetServerByID(t *testing.T) {
 
 		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
 		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(false, nil).Once()
-		mockServerRepo.On("GetServerByID", serverID.Hex()).Return(server, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
 
 		result, msgOpt := service.GetServerByID(userID.Hex(), serverID.Hex())
 
@@ -1086,3 +1309,383 @@ func TestGetServerChannels(t *testing.T) {
 		mockChannelRepo.AssertExpectations(t)
 	})
 }
+
+func TestCreateInvite(t *testing.T) {
+	t.Run("具有創建邀請權限的成員成功創建邀請", func(t *testing.T) {
+		mockServerRepo := new(mockServerRepository)
+		mockPermissionService := new(mocks.PermissionService)
+		mockInviteRepo := new(mockInviteRepository)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+
+		service := &serverService{
+			serverRepo:        mockServerRepo,
+			permissionService: mockPermissionService,
+			inviteRepo:        mockInviteRepo,
+		}
+
+		server := &models.Server{BaseModel: providers.BaseModel{ID: serverID}}
+
+		mockPermissionService.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionCreateInvite).Return(nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(server, nil).Once()
+		mockInviteRepo.On("CreateInvite", mock.MatchedBy(func(invite *models.ServerInvite) bool {
+			return invite.ServerID == serverID && invite.CreatedBy == userID &&
+				len(invite.Code) == InviteCodeLength && invite.MaxUses == 5 && invite.ExpiresAt > time.Now().Unix()
+		})).Return(nil).Once()
+
+		result, msgOpt := service.CreateInvite(userID.Hex(), serverID.Hex(), models.CreateServerInviteRequest{MaxUses: 5, ExpiresIn: 3600})
+
+		assert.Nil(t, msgOpt)
+		assert.NotNil(t, result)
+		assert.Len(t, result.Code, InviteCodeLength)
+		assert.Equal(t, 5, result.MaxUses)
+
+		mockServerRepo.AssertExpectations(t)
+		mockPermissionService.AssertExpectations(t)
+		mockInviteRepo.AssertExpectations(t)
+	})
+
+	t.Run("沒有創建邀請權限的成員無法創建邀請", func(t *testing.T) {
+		mockPermissionService := new(mocks.PermissionService)
+		mockInviteRepo := new(mockInviteRepository)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+
+		service := &serverService{
+			permissionService: mockPermissionService,
+			inviteRepo:        mockInviteRepo,
+		}
+
+		mockPermissionService.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionCreateInvite).Return(&models.MessageOptions{
+			Code:    models.ErrNoServerPermission,
+			Message: "權限不足",
+		}).Once()
+
+		result, msgOpt := service.CreateInvite(userID.Hex(), serverID.Hex(), models.CreateServerInviteRequest{})
+
+		assert.Nil(t, result)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		mockInviteRepo.AssertNotCalled(t, "CreateInvite", mock.Anything)
+	})
+
+	t.Run("非成員無法創建邀請", func(t *testing.T) {
+		mockPermissionService := new(mocks.PermissionService)
+		mockInviteRepo := new(mockInviteRepository)
+
+		userID := primitive.NewObjectID()
+		serverID := primitive.NewObjectID()
+
+		service := &serverService{
+			permissionService: mockPermissionService,
+			inviteRepo:        mockInviteRepo,
+		}
+
+		mockPermissionService.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionCreateInvite).Return(&models.MessageOptions{
+			Code:    models.ErrNotServerMember,
+			Message: "您不是該伺服器的成員",
+		}).Once()
+
+		result, msgOpt := service.CreateInvite(userID.Hex(), serverID.Hex(), models.CreateServerInviteRequest{})
+
+		assert.Nil(t, result)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNotServerMember, msgOpt.Code)
+		mockInviteRepo.AssertNotCalled(t, "CreateInvite", mock.Anything)
+	})
+}
+
+func TestGetServerInvites(t *testing.T) {
+	mockInviteRepo := new(mockInviteRepository)
+	mockPermissionService := new(mocks.PermissionService)
+
+	userID := primitive.NewObjectID()
+	serverID := primitive.NewObjectID()
+
+	service := &serverService{
+		inviteRepo:        mockInviteRepo,
+		permissionService: mockPermissionService,
+	}
+
+	now := time.Now().Unix()
+	invites := []models.ServerInvite{
+		{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, Code: "active"},
+		{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, Code: "expired", ExpiresAt: now - 10},
+		{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, ServerID: serverID, Code: "used-up", MaxUses: 1, Uses: 1},
+	}
+
+	mockPermissionService.On("CheckPermission", mock.Anything, serverID.Hex(), userID.Hex(), models.PermissionManageServer).Return(nil).Once()
+	mockInviteRepo.On("GetInvitesByServerID", serverID.Hex()).Return(invites, nil).Once()
+
+	result, msgOpt := service.GetServerInvites(userID.Hex(), serverID.Hex())
+
+	assert.Nil(t, msgOpt)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "active", result[0].Code)
+
+	mockPermissionService.AssertExpectations(t)
+	mockInviteRepo.AssertExpectations(t)
+}
+
+func TestRevokeInvite(t *testing.T) {
+	serverID := primitive.NewObjectID()
+	creatorID := primitive.NewObjectID()
+	inviteID := primitive.NewObjectID()
+
+	newInvite := func() *models.ServerInvite {
+		return &models.ServerInvite{
+			BaseModel: providers.BaseModel{ID: inviteID},
+			ServerID:  serverID,
+			CreatedBy: creatorID,
+			Code:      "abc",
+		}
+	}
+
+	t.Run("創建者可撤銷自己的邀請", func(t *testing.T) {
+		mockInviteRepo := new(mockInviteRepository)
+		mockServerMemberRepo := new(mocks.ServerMemberRepository)
+
+		service := &serverService{
+			inviteRepo:       mockInviteRepo,
+			serverMemberRepo: mockServerMemberRepo,
+		}
+
+		mockInviteRepo.On("GetInviteByID", inviteID.Hex()).Return(newInvite(), nil).Once()
+		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), creatorID.Hex()).Return(true, nil).Once()
+		mockInviteRepo.On("RevokeInvite", inviteID.Hex()).Return(nil).Once()
+
+		msgOpt := service.RevokeInvite(creatorID.Hex(), serverID.Hex(), inviteID.Hex())
+
+		assert.Nil(t, msgOpt)
+		mockInviteRepo.AssertExpectations(t)
+		mockServerMemberRepo.AssertExpectations(t)
+	})
+
+	t.Run("其他成員需要管理伺服器權限", func(t *testing.T) {
+		mockInviteRepo := new(mockInviteRepository)
+		mockPermissionService := new(mocks.PermissionService)
+		otherID := primitive.NewObjectID()
+
+		service := &serverService{
+			inviteRepo:        mockInviteRepo,
+			permissionService: mockPermissionService,
+		}
+
+		mockInviteRepo.On("GetInviteByID", inviteID.Hex()).Return(newInvite(), nil).Once()
+		mockPermissionService.On("CheckPermission", mock.Anything, serverID.Hex(), otherID.Hex(), models.PermissionManageServer).
+			Return(&models.MessageOptions{Code: models.ErrNoServerPermission, Message: "沒有權限"}).Once()
+
+		msgOpt := service.RevokeInvite(otherID.Hex(), serverID.Hex(), inviteID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNoServerPermission, msgOpt.Code)
+		mockInviteRepo.AssertNotCalled(t, "RevokeInvite", mock.Anything)
+	})
+
+	t.Run("邀請不屬於此伺服器", func(t *testing.T) {
+		mockInviteRepo := new(mockInviteRepository)
+
+		service := &serverService{
+			inviteRepo: mockInviteRepo,
+		}
+
+		mockInviteRepo.On("GetInviteByID", inviteID.Hex()).Return(newInvite(), nil).Once()
+
+		msgOpt := service.RevokeInvite(creatorID.Hex(), primitive.NewObjectID().Hex(), inviteID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInviteNotFound, msgOpt.Code)
+	})
+}
+
+func TestAcceptInvite(t *testing.T) {
+	userID := primitive.NewObjectID()
+	serverID := primitive.NewObjectID()
+	inviteID := primitive.NewObjectID()
+
+	user := &models.User{BaseModel: providers.BaseModel{ID: userID}}
+	privateServer := &models.Server{
+		BaseModel:  providers.BaseModel{ID: serverID},
+		Name:       "Private",
+		IsPublic:   false,
+		MaxMembers: 100,
+	}
+
+	t.Run("透過邀請加入私人伺服器", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		mockServerRepo := new(mockServerRepository)
+		mockServerMemberRepo := new(mocks.ServerMemberRepository)
+		mockInviteRepo := new(mockInviteRepository)
+		mockModerationRepo := new(mockModerationRepository)
+
+		service := &serverService{
+			userRepo:         mockUserRepo,
+			serverRepo:       mockServerRepo,
+			serverMemberRepo: mockServerMemberRepo,
+			inviteRepo:       mockInviteRepo,
+			moderationRepo:   mockModerationRepo,
+		}
+
+		invite := &models.ServerInvite{BaseModel: providers.BaseModel{ID: inviteID}, ServerID: serverID, Code: "abc", MaxUses: 2, Uses: 1}
+
+		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
+		mockInviteRepo.On("GetInviteByCode", "abc").Return(invite, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(privateServer, nil).Once()
+		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(false, nil).Once()
+		mockModerationRepo.On("GetActiveBan", serverID.Hex(), userID.Hex(), mock.AnythingOfType("int64")).Return(nil, nil).Once()
+		mockServerMemberRepo.On("GetMemberCount", serverID.Hex()).Return(int64(10), nil).Once()
+		mockInviteRepo.On("ClaimInviteUse", inviteID.Hex(), mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockServerMemberRepo.On("AddMemberToServer", serverID.Hex(), userID.Hex(), "member").Return(nil).Once()
+		mockServerRepo.On("UpdateMemberCount", serverID.Hex(), 11).Return(nil).Once()
+
+		result, msgOpt := service.AcceptInvite(userID.Hex(), "abc")
+
+		assert.Nil(t, msgOpt)
+		assert.NotNil(t, result)
+		assert.Equal(t, "Private", result.Name)
+
+		mockUserRepo.AssertExpectations(t)
+		mockServerRepo.AssertExpectations(t)
+		mockServerMemberRepo.AssertExpectations(t)
+		mockInviteRepo.AssertExpectations(t)
+	})
+
+	t.Run("邀請已失效", func(t *testing.T) {
+		now := time.Now().Unix()
+		tests := []struct {
+			name   string
+			invite *models.ServerInvite
+		}{
+			{"已撤銷", &models.ServerInvite{Revoked: true}},
+			{"已過期", &models.ServerInvite{ExpiresAt: now - 1}},
+			{"已用盡", &models.ServerInvite{MaxUses: 3, Uses: 3}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mockUserRepo := new(mocks.UserRepository)
+				mockInviteRepo := new(mockInviteRepository)
+
+				service := &serverService{
+					userRepo:   mockUserRepo,
+					inviteRepo: mockInviteRepo,
+				}
+
+				tt.invite.ID = inviteID
+				tt.invite.ServerID = serverID
+
+				mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
+				mockInviteRepo.On("GetInviteByCode", "abc").Return(tt.invite, nil).Once()
+
+				result, msgOpt := service.AcceptInvite(userID.Hex(), "abc")
+
+				assert.Nil(t, result)
+				assert.NotNil(t, msgOpt)
+				assert.Equal(t, models.ErrInviteInvalid, msgOpt.Code)
+				mockInviteRepo.AssertNotCalled(t, "ClaimInviteUse", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("伺服器已滿時不佔用邀請", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		mockServerRepo := new(mockServerRepository)
+		mockServerMemberRepo := new(mocks.ServerMemberRepository)
+		mockInviteRepo := new(mockInviteRepository)
+		mockModerationRepo := new(mockModerationRepository)
+
+		service := &serverService{
+			userRepo:         mockUserRepo,
+			serverRepo:       mockServerRepo,
+			serverMemberRepo: mockServerMemberRepo,
+			inviteRepo:       mockInviteRepo,
+			moderationRepo:   mockModerationRepo,
+		}
+
+		invite := &models.ServerInvite{BaseModel: providers.BaseModel{ID: inviteID}, ServerID: serverID, Code: "abc"}
+
+		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
+		mockInviteRepo.On("GetInviteByCode", "abc").Return(invite, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(privateServer, nil).Once()
+		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(false, nil).Once()
+		mockModerationRepo.On("GetActiveBan", serverID.Hex(), userID.Hex(), mock.AnythingOfType("int64")).Return(nil, nil).Once()
+		mockServerMemberRepo.On("GetMemberCount", serverID.Hex()).Return(int64(100), nil).Once()
+
+		result, msgOpt := service.AcceptInvite(userID.Hex(), "abc")
+
+		assert.Nil(t, result)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrForbidden, msgOpt.Code)
+		mockInviteRepo.AssertNotCalled(t, "ClaimInviteUse", mock.Anything, mock.Anything)
+	})
+
+	t.Run("並發使用導致佔用失敗", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		mockServerRepo := new(mockServerRepository)
+		mockServerMemberRepo := new(mocks.ServerMemberRepository)
+		mockInviteRepo := new(mockInviteRepository)
+		mockModerationRepo := new(mockModerationRepository)
+
+		service := &serverService{
+			userRepo:         mockUserRepo,
+			serverRepo:       mockServerRepo,
+			serverMemberRepo: mockServerMemberRepo,
+			inviteRepo:       mockInviteRepo,
+			moderationRepo:   mockModerationRepo,
+		}
+
+		invite := &models.ServerInvite{BaseModel: providers.BaseModel{ID: inviteID}, ServerID: serverID, Code: "abc", MaxUses: 1}
+
+		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
+		mockInviteRepo.On("GetInviteByCode", "abc").Return(invite, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(privateServer, nil).Once()
+		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(false, nil).Once()
+		mockModerationRepo.On("GetActiveBan", serverID.Hex(), userID.Hex(), mock.AnythingOfType("int64")).Return(nil, nil).Once()
+		mockServerMemberRepo.On("GetMemberCount", serverID.Hex()).Return(int64(10), nil).Once()
+		mockInviteRepo.On("ClaimInviteUse", inviteID.Hex(), mock.AnythingOfType("int64")).Return(false, nil).Once()
+
+		result, msgOpt := service.AcceptInvite(userID.Hex(), "abc")
+
+		assert.Nil(t, result)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInviteInvalid, msgOpt.Code)
+		mockServerMemberRepo.AssertNotCalled(t, "AddMemberToServer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("加入失敗時歸還使用次數", func(t *testing.T) {
+		mockUserRepo := new(mocks.UserRepository)
+		mockServerRepo := new(mockServerRepository)
+		mockServerMemberRepo := new(mocks.ServerMemberRepository)
+		mockInviteRepo := new(mockInviteRepository)
+		mockModerationRepo := new(mockModerationRepository)
+
+		service := &serverService{
+			userRepo:         mockUserRepo,
+			serverRepo:       mockServerRepo,
+			serverMemberRepo: mockServerMemberRepo,
+			inviteRepo:       mockInviteRepo,
+			moderationRepo:   mockModerationRepo,
+		}
+
+		invite := &models.ServerInvite{BaseModel: providers.BaseModel{ID: inviteID}, ServerID: serverID, Code: "abc"}
+
+		mockUserRepo.On("GetUserById", userID.Hex()).Return(user, nil).Once()
+		mockInviteRepo.On("GetInviteByCode", "abc").Return(invite, nil).Once()
+		mockServerRepo.On("GetServerByID", mock.Anything, serverID.Hex()).Return(privateServer, nil).Once()
+		mockServerMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID.Hex()).Return(false, nil).Once()
+		mockModerationRepo.On("GetActiveBan", serverID.Hex(), userID.Hex(), mock.AnythingOfType("int64")).Return(nil, nil).Once()
+		mockServerMemberRepo.On("GetMemberCount", serverID.Hex()).Return(int64(10), nil).Once()
+		mockInviteRepo.On("ClaimInviteUse", inviteID.Hex(), mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockServerMemberRepo.On("AddMemberToServer", serverID.Hex(), userID.Hex(), "member").Return(errors.New("database error")).Once()
+		mockInviteRepo.On("ReleaseInviteUse", inviteID.Hex()).Return(nil).Once()
+
+		result, msgOpt := service.AcceptInvite(userID.Hex(), "abc")
+
+		assert.Nil(t, result)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+		mockInviteRepo.AssertExpectations(t)
+	})
+}
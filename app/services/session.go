@@ -0,0 +1,221 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// activeSessionFilter 用戶尚未撤銷且未過期的登入工作階段
+func activeSessionFilter(userObjectID primitive.ObjectID) bson.M {
+	return bson.M{
+		"user_id":    userObjectID,
+		"revoked":    false,
+		"expires_at": bson.M{"$gt": time.Now().Unix()},
+	}
+}
+
+// sessionTokensFilter 屬於指定登入工作階段（refresh token family）的 token
+func sessionTokensFilter(sessionObjectID primitive.ObjectID) bson.A {
+	return bson.A{
+		bson.M{"family_id": sessionObjectID},
+		bson.M{"_id": sessionObjectID},
+	}
+}
+
+// revokeAllUserTokens 註銷用戶所有 refresh token，並將已發放的 access token 列入撤銷清單
+func (us *userService) revokeAllUserTokens(userObjectID primitive.ObjectID) error {
+	filter := bson.M{"user_id": userObjectID, "revoked": false}
+	update := bson.M{"$set": bson.M{"revoked": true, "updated_at": time.Now()}}
+	if err := us.odm.UpdateMany(context.Background(), &models.RefreshToken{}, filter, update); err != nil {
+		return err
+	}
+
+	if us.tokenDenylist == nil {
+		return nil
+	}
+	return us.tokenDenylist.RevokeUserTokens(userObjectID.Hex())
+}
+
+// revokeSessionAccessTokens 將登入工作階段已發放的 access token 列入撤銷清單
+func (us *userService) revokeSessionAccessTokens(sessionIDs ...string) error {
+	if us.tokenDenylist == nil {
+		return nil
+	}
+	for _, sessionID := range sessionIDs {
+		if err := us.tokenDenylist.RevokeSession(sessionID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSessions 獲取用戶目前有效的登入工作階段，依最後使用時間由新到舊排序
+func (us *userService) GetSessions(userID string, currentSessionID string) ([]models.SessionResponse, *models.MessageOptions) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的用戶ID",
+		}
+	}
+
+	var sessions []models.RefreshToken
+	if err = us.odm.Find(context.Background(), activeSessionFilter(userObjectID), &sessions); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取登入裝置失敗",
+			Details: err.Error(),
+		}
+	}
+
+	slices.SortFunc(sessions, func(a, b models.RefreshToken) int {
+		return cmp.Compare(b.LastUsedAt, a.LastUsedAt)
+	})
+
+	// 每個工作階段只保留最新的 token（並發輪替時同一 family 可能短暫存在多個有效 token）
+	response := make([]models.SessionResponse, 0, len(sessions))
+	seen := make(map[primitive.ObjectID]bool, len(sessions))
+	for _, session := range sessions {
+		sessionObjectID := session.SessionID()
+		if seen[sessionObjectID] {
+			continue
+		}
+		seen[sessionObjectID] = true
+
+		response = append(response, models.SessionResponse{
+			ID:         sessionObjectID.Hex(),
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.SessionCreatedAt(),
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    sessionObjectID.Hex() == currentSessionID,
+		})
+	}
+
+	return response, nil
+}
+
+// RevokeSession 撤銷指定登入工作階段的所有有效 token，該裝置之後無法再刷新 access token
+func (us *userService) RevokeSession(userID string, sessionID string) *models.MessageOptions {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的用戶ID",
+		}
+	}
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的工作階段ID",
+		}
+	}
+
+	filter := activeSessionFilter(userObjectID)
+	filter["$or"] = sessionTokensFilter(sessionObjectID)
+
+	exists, err := us.odm.Exists(context.Background(), filter, &models.RefreshToken{})
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "撤銷登入裝置失敗",
+			Details: err.Error(),
+		}
+	}
+	if !exists {
+		return &models.MessageOptions{
+			Code:    models.ErrNotFound,
+			Message: "登入裝置不存在或已登出",
+		}
+	}
+
+	update := bson.M{"$set": bson.M{"revoked": true, "updated_at": time.Now()}}
+	if err = us.odm.UpdateMany(context.Background(), &models.RefreshToken{}, filter, update); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "撤銷登入裝置失敗",
+			Details: err.Error(),
+		}
+	}
+
+	if err = us.revokeSessionAccessTokens(sessionID); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "撤銷登入裝置失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions 撤銷目前工作階段以外的所有工作階段（登出其他裝置），返回被撤銷的工作階段 ID
+func (us *userService) RevokeOtherSessions(userID string, currentSessionID string) ([]string, *models.MessageOptions) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的用戶ID",
+		}
+	}
+	// 無法辨識目前的工作階段時拒絕操作，避免連同目前裝置一起登出
+	currentObjectID, err := primitive.ObjectIDFromHex(currentSessionID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "無法辨識目前的登入裝置，請重新登入",
+		}
+	}
+
+	filter := activeSessionFilter(userObjectID)
+	filter["$nor"] = sessionTokensFilter(currentObjectID)
+
+	var sessions []models.RefreshToken
+	if err = us.odm.Find(context.Background(), filter, &sessions); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "登出其他裝置失敗",
+			Details: err.Error(),
+		}
+	}
+	if len(sessions) == 0 {
+		return []string{}, nil
+	}
+
+	sessionIDs := make([]string, 0, len(sessions))
+	tokenObjectIDs := make([]primitive.ObjectID, len(sessions))
+	for i, session := range sessions {
+		tokenObjectIDs[i] = session.ID
+		if sessionID := session.SessionID().Hex(); !slices.Contains(sessionIDs, sessionID) {
+			sessionIDs = append(sessionIDs, sessionID)
+		}
+	}
+
+	update := bson.M{"$set": bson.M{"revoked": true, "updated_at": time.Now()}}
+	revokeFilter := bson.M{"_id": bson.M{"$in": tokenObjectIDs}, "user_id": userObjectID}
+	if err = us.odm.UpdateMany(context.Background(), &models.RefreshToken{}, revokeFilter, update); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "登出其他裝置失敗",
+			Details: err.Error(),
+		}
+	}
+
+	if err = us.revokeSessionAccessTokens(sessionIDs...); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "登出其他裝置失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return sessionIDs, nil
+}
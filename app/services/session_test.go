@@ -0,0 +1,267 @@
+package services
+
+import (
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"chat_app_backend/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// newTestSession 建立測試用的登入工作階段
+func newTestSession(userObjectID primitive.ObjectID, userAgent string, lastUsedAt int64) models.RefreshToken {
+	return models.RefreshToken{
+		BaseModel:  providers.BaseModel{ID: primitive.NewObjectID(), CreatedAt: time.Unix(lastUsedAt-60, 0)},
+		UserID:     userObjectID,
+		UserAgent:  userAgent,
+		IP:         "203.0.113.1",
+		LastUsedAt: lastUsedAt,
+		ExpiresAt:  time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestGetSessions(t *testing.T) {
+	t.Run("依最後使用時間排序並標示目前裝置", func(t *testing.T) {
+		userObjectID := primitive.NewObjectID()
+		older := newTestSession(userObjectID, "Firefox", 1000)
+		newer := newTestSession(userObjectID, "Chrome", 2000)
+
+		mockODM := new(mocks.ODM)
+		mockODM.On("Find", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+			return filter["user_id"] == userObjectID && filter["revoked"] == false
+		}), mock.AnythingOfType("*[]models.RefreshToken")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.RefreshToken) = []models.RefreshToken{older, newer}
+		}).Return(nil).Once()
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		sessions, msgOpt := service.GetSessions(userObjectID.Hex(), older.ID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Len(t, sessions, 2)
+		assert.Equal(t, newer.ID.Hex(), sessions[0].ID)
+		assert.Equal(t, "Chrome", sessions[0].UserAgent)
+		assert.False(t, sessions[0].Current)
+		assert.Equal(t, older.ID.Hex(), sessions[1].ID)
+		assert.True(t, sessions[1].Current)
+		assert.Equal(t, int64(940), sessions[1].CreatedAt)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("同一 family 的 token 視為同一工作階段", func(t *testing.T) {
+		userObjectID := primitive.NewObjectID()
+		familyID := primitive.NewObjectID()
+		latest := newTestSession(userObjectID, "Chrome", 2000)
+		latest.FamilyID = familyID
+		latest.SessionStartedAt = 500
+		concurrent := newTestSession(userObjectID, "Chrome", 1990)
+		concurrent.FamilyID = familyID
+
+		mockODM := new(mocks.ODM)
+		mockODM.On("Find", mock.Anything, mock.Anything, mock.AnythingOfType("*[]models.RefreshToken")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.RefreshToken) = []models.RefreshToken{concurrent, latest}
+		}).Return(nil).Once()
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		sessions, msgOpt := service.GetSessions(userObjectID.Hex(), familyID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, familyID.Hex(), sessions[0].ID)
+		assert.Equal(t, int64(500), sessions[0].CreatedAt, "建立時間應為登入時間而非輪替時間")
+		assert.Equal(t, int64(2000), sessions[0].LastUsedAt)
+		assert.True(t, sessions[0].Current)
+	})
+
+	t.Run("無效的用戶ID", func(t *testing.T) {
+		service := NewUserService(nil, new(mocks.ODM), &testUserRepository{}, nil, nil, nil, nil)
+
+		_, msgOpt := service.GetSessions("invalid", "")
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestRevokeSession(t *testing.T) {
+	userObjectID := primitive.NewObjectID()
+	sessionObjectID := primitive.NewObjectID()
+
+	t.Run("撤銷指定工作階段", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		matchSession := mock.MatchedBy(func(filter bson.M) bool {
+			sessionTokens := filter["$or"].(bson.A)
+			return filter["user_id"] == userObjectID &&
+				sessionTokens[0].(bson.M)["family_id"] == sessionObjectID &&
+				sessionTokens[1].(bson.M)["_id"] == sessionObjectID
+		})
+		mockODM.On("Exists", mock.Anything, matchSession, mock.AnythingOfType("*models.RefreshToken")).Return(true, nil).Once()
+		mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.RefreshToken"), matchSession, mock.MatchedBy(func(update bson.M) bool {
+			return update["$set"].(bson.M)["revoked"] == true
+		})).Return(nil).Once()
+		tokenDenylist := providers.NewCacheTokenDenylist(nil, time.Hour)
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, tokenDenylist, nil)
+
+		msgOpt := service.RevokeSession(userObjectID.Hex(), sessionObjectID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.True(t, tokenDenylist.IsRevoked("", sessionObjectID.Hex(), "", time.Now()), "該工作階段的 access token 應失效")
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("工作階段不存在或屬於其他用戶", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockODM.On("Exists", mock.Anything, mock.Anything, mock.Anything).Return(false, nil).Once()
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		msgOpt := service.RevokeSession(userObjectID.Hex(), sessionObjectID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNotFound, msgOpt.Code)
+		mockODM.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("無效的工作階段ID", func(t *testing.T) {
+		service := NewUserService(nil, new(mocks.ODM), &testUserRepository{}, nil, nil, nil, nil)
+
+		msgOpt := service.RevokeSession(userObjectID.Hex(), "invalid")
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	userObjectID := primitive.NewObjectID()
+	currentID := primitive.NewObjectID()
+
+	t.Run("撤銷目前裝置以外的工作階段", func(t *testing.T) {
+		other1 := newTestSession(userObjectID, "Firefox", 1000)
+		other2 := newTestSession(userObjectID, "Safari", 2000)
+
+		mockODM := new(mocks.ODM)
+		mockODM.On("Find", mock.Anything, mock.MatchedBy(func(filter bson.M) bool {
+			currentTokens := filter["$nor"].(bson.A)
+			return currentTokens[0].(bson.M)["family_id"] == currentID
+		}), mock.AnythingOfType("*[]models.RefreshToken")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]models.RefreshToken) = []models.RefreshToken{other1, other2}
+		}).Return(nil).Once()
+		mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.RefreshToken"), mock.MatchedBy(func(filter bson.M) bool {
+			ids := filter["_id"].(bson.M)["$in"].([]primitive.ObjectID)
+			return len(ids) == 2 && ids[0] == other1.ID && ids[1] == other2.ID
+		}), mock.Anything).Return(nil).Once()
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		sessionIDs, msgOpt := service.RevokeOtherSessions(userObjectID.Hex(), currentID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, []string{other1.ID.Hex(), other2.ID.Hex()}, sessionIDs)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("沒有其他工作階段", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		mockODM.On("Find", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		sessionIDs, msgOpt := service.RevokeOtherSessions(userObjectID.Hex(), currentID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Empty(t, sessionIDs)
+		mockODM.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("無法辨識目前工作階段時拒絕操作", func(t *testing.T) {
+		mockODM := new(mocks.ODM)
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		_, msgOpt := service.RevokeOtherSessions(userObjectID.Hex(), "")
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+		mockODM.AssertNotCalled(t, "Find", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	userObjectID := primitive.NewObjectID()
+	sessionObjectID := primitive.NewObjectID()
+
+	newLogoutContext := func(accessToken string, refreshToken string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/logout", nil)
+		c.Request.Header.Set("Authorization", "Bearer "+accessToken)
+		if refreshToken != "" {
+			c.Request.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+		}
+		return c
+	}
+	matchSession := mock.MatchedBy(func(filter bson.M) bool {
+		sessionTokens, ok := filter["$or"].(bson.A)
+		return ok && filter["user_id"] == userObjectID && sessionTokens[0].(bson.M)["family_id"] == sessionObjectID
+	})
+
+	t.Run("只撤銷目前的工作階段", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		accessToken, _ := utils.GenAccessToken(userObjectID.Hex(), sessionObjectID.Hex())
+		issuedBefore := time.Now().Add(-time.Minute)
+
+		mockODM := new(mocks.ODM)
+		mockODM.On("Exists", mock.Anything, matchSession, mock.AnythingOfType("*models.RefreshToken")).Return(true, nil).Once()
+		mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.RefreshToken"), matchSession, mock.Anything).Return(nil).Once()
+		tokenDenylist := providers.NewCacheTokenDenylist(nil, time.Hour)
+		service := NewUserService(cfg, mockODM, &testUserRepository{}, nil, nil, tokenDenylist, nil)
+
+		msgOpt := service.Logout(newLogoutContext(accessToken.Token, ""))
+
+		assert.Nil(t, msgOpt)
+		assert.True(t, tokenDenylist.IsRevoked(accessToken.ID, "", "", time.Now()), "目前的 access token 應失效")
+		assert.True(t, tokenDenylist.IsRevoked("", sessionObjectID.Hex(), "", time.Now()), "目前工作階段的 access token 應失效")
+		assert.False(t, tokenDenylist.IsRevoked("other-jti", primitive.NewObjectID().Hex(), userObjectID.Hex(), issuedBefore), "其他裝置不應被登出")
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("舊版 token 由 refresh token cookie 找出工作階段", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		accessToken, _ := utils.GenAccessToken(userObjectID.Hex(), "")
+
+		mockODM := new(mocks.ODM)
+		mockODM.On("FindOne", mock.Anything, bson.M{"token": "refresh-token"}, mock.AnythingOfType("*models.RefreshToken")).Run(func(args mock.Arguments) {
+			*args.Get(2).(*models.RefreshToken) = models.RefreshToken{
+				BaseModel: providers.BaseModel{ID: primitive.NewObjectID()},
+				UserID:    userObjectID,
+				FamilyID:  sessionObjectID,
+			}
+		}).Return(nil).Once()
+		mockODM.On("Exists", mock.Anything, matchSession, mock.AnythingOfType("*models.RefreshToken")).Return(true, nil).Once()
+		mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.RefreshToken"), matchSession, mock.Anything).Return(nil).Once()
+		service := NewUserService(cfg, mockODM, &testUserRepository{}, nil, nil, providers.NewCacheTokenDenylist(nil, time.Hour), nil)
+
+		msgOpt := service.Logout(newLogoutContext(accessToken.Token, "refresh-token"))
+
+		assert.Nil(t, msgOpt)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("工作階段已登出", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		accessToken, _ := utils.GenAccessToken(userObjectID.Hex(), sessionObjectID.Hex())
+
+		mockODM := new(mocks.ODM)
+		mockODM.On("Exists", mock.Anything, matchSession, mock.AnythingOfType("*models.RefreshToken")).Return(false, nil).Once()
+		service := NewUserService(cfg, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		msgOpt := service.Logout(newLogoutContext(accessToken.Token, ""))
+
+		assert.Nil(t, msgOpt)
+		mockODM.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
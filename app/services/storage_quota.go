@@ -0,0 +1,154 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// storageQuotas 用戶與伺服器的儲存配額，0 表示不限制
+func (fs *fileUploadService) storageQuotas() (int64, int64) {
+	if fs.config == nil {
+		return 0, 0
+	}
+	return fs.config.Upload.UserQuota, fs.config.Upload.ServerQuota
+}
+
+// resolveUploadServer 驗證上傳指定的伺服器，只有成員可將檔案計入伺服器配額
+// 未指定伺服器時返回 nil
+func (fs *fileUploadService) resolveUploadServer(userID string, serverID string) (*primitive.ObjectID, *models.MessageOptions) {
+	if serverID == "" {
+		return nil, nil
+	}
+
+	serverObjectID, err := primitive.ObjectIDFromHex(serverID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "無效的伺服器ID格式",
+			Details: err.Error(),
+		}
+	}
+
+	isMember, err := fs.serverMemberRepo.IsMemberOfServer(serverID, userID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "檢查伺服器成員失敗",
+			Details: err.Error(),
+		}
+	}
+	if !isMember {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrNotServerMember,
+			Message: "您不是此伺服器的成員",
+		}
+	}
+
+	return &serverObjectID, nil
+}
+
+// checkStorageQuota 檢查加入 size 後是否超過上傳者與伺服器的儲存配額
+// 用量在檢查與建立記錄之間可能被並發上傳改變，配額只作為上限參考，允許少量超出
+func (fs *fileUploadService) checkStorageQuota(userID string, serverID *primitive.ObjectID, size int64) *models.MessageOptions {
+	userQuota, serverQuota := fs.storageQuotas()
+
+	if msgOpt := fs.checkOwnerQuota(models.StorageOwnerUser, userID, userQuota, size); msgOpt != nil {
+		return msgOpt
+	}
+	if serverID != nil {
+		if msgOpt := fs.checkOwnerQuota(models.StorageOwnerServer, serverID.Hex(), serverQuota, size); msgOpt != nil {
+			return msgOpt
+		}
+	}
+	return nil
+}
+
+// checkOwnerQuota 檢查單一擁有者的儲存配額
+func (fs *fileUploadService) checkOwnerQuota(ownerType string, ownerID string, quota int64, size int64) *models.MessageOptions {
+	if quota <= 0 {
+		return nil
+	}
+
+	usage, err := fs.fileRepo.GetStorageUsage(ownerType, ownerID)
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取儲存用量失敗",
+			Details: err.Error(),
+		}
+	}
+
+	if usage.TotalSize+size > quota {
+		message := "用戶儲存空間不足"
+		if ownerType == models.StorageOwnerServer {
+			message = "伺服器儲存空間不足"
+		}
+		return &models.MessageOptions{
+			Code:    models.ErrQuotaExceeded,
+			Message: message,
+			Details: fmt.Sprintf("已使用: %d bytes, 本次上傳: %d bytes, 上限: %d bytes", usage.TotalSize, size, quota),
+		}
+	}
+
+	return nil
+}
+
+// GetStorageUsage 獲取用戶的儲存用量，指定伺服器時一併返回伺服器用量（需為成員）
+func (fs *fileUploadService) GetStorageUsage(userID string, serverID string) (*models.StorageUsageResponse, *models.MessageOptions) {
+	if userID == "" {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "用戶ID不能為空",
+		}
+	}
+
+	serverObjectID, msgOpt := fs.resolveUploadServer(userID, serverID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	userQuota, serverQuota := fs.storageQuotas()
+
+	userUsage, msgOpt := fs.getQuotaUsage(models.StorageOwnerUser, userID, userQuota)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+	response := &models.StorageUsageResponse{User: *userUsage}
+
+	if serverObjectID != nil {
+		serverUsage, msgOpt := fs.getQuotaUsage(models.StorageOwnerServer, serverObjectID.Hex(), serverQuota)
+		if msgOpt != nil {
+			return nil, msgOpt
+		}
+		response.Server = serverUsage
+	}
+
+	return response, nil
+}
+
+// getQuotaUsage 組合單一擁有者的用量與配額
+func (fs *fileUploadService) getQuotaUsage(ownerType string, ownerID string, quota int64) (*models.StorageQuotaUsage, *models.MessageOptions) {
+	usage, err := fs.fileRepo.GetStorageUsage(ownerType, ownerID)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取儲存用量失敗",
+			Details: err.Error(),
+		}
+	}
+
+	remaining := int64(-1)
+	if quota > 0 {
+		remaining = max(0, quota-usage.TotalSize)
+	}
+
+	return &models.StorageQuotaUsage{
+		OwnerID:        ownerID,
+		UsedBytes:      usage.TotalSize,
+		FileCount:      usage.FileCount,
+		QuotaBytes:     quota,
+		RemainingBytes: remaining,
+	}, nil
+}
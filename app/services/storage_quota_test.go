@@ -0,0 +1,213 @@
+package services
+
+import (
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/config"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newQuotaTestConfig(userQuota, serverQuota int64) *config.Config {
+	return &config.Config{
+		Upload: config.UploadConfig{
+			UserQuota:   userQuota,
+			ServerQuota: serverQuota,
+		},
+	}
+}
+
+func TestCheckStorageQuota(t *testing.T) {
+	userID := primitive.NewObjectID().Hex()
+	serverID := primitive.NewObjectID()
+
+	t.Run("未設定配額時不查詢用量", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{config: newQuotaTestConfig(0, 0), fileRepo: mockFileRepo}
+
+		msgOpt := service.checkStorageQuota(userID, &serverID, 1<<40)
+
+		assert.Nil(t, msgOpt)
+		mockFileRepo.AssertNotCalled(t, "GetStorageUsage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("剛好達到配額仍允許", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{config: newQuotaTestConfig(1000, 0), fileRepo: mockFileRepo}
+
+		mockFileRepo.On("GetStorageUsage", models.StorageOwnerUser, userID).Return(&models.StorageUsage{TotalSize: 900}, nil).Once()
+
+		msgOpt := service.checkStorageQuota(userID, nil, 100)
+
+		assert.Nil(t, msgOpt)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("超過用戶配額", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{config: newQuotaTestConfig(1000, 0), fileRepo: mockFileRepo}
+
+		mockFileRepo.On("GetStorageUsage", models.StorageOwnerUser, userID).Return(&models.StorageUsage{TotalSize: 900}, nil).Once()
+
+		msgOpt := service.checkStorageQuota(userID, nil, 101)
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrQuotaExceeded, msgOpt.Code)
+		assert.Equal(t, "用戶儲存空間不足", msgOpt.Message)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("超過伺服器配額", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{config: newQuotaTestConfig(1000, 5000), fileRepo: mockFileRepo}
+
+		mockFileRepo.On("GetStorageUsage", models.StorageOwnerUser, userID).Return(&models.StorageUsage{TotalSize: 0}, nil).Once()
+		mockFileRepo.On("GetStorageUsage", models.StorageOwnerServer, serverID.Hex()).Return(&models.StorageUsage{TotalSize: 4500}, nil).Once()
+
+		msgOpt := service.checkStorageQuota(userID, &serverID, 600)
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrQuotaExceeded, msgOpt.Code)
+		assert.Equal(t, "伺服器儲存空間不足", msgOpt.Message)
+		mockFileRepo.AssertExpectations(t)
+	})
+
+	t.Run("獲取用量失敗", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{config: newQuotaTestConfig(1000, 0), fileRepo: mockFileRepo}
+
+		mockFileRepo.On("GetStorageUsage", models.StorageOwnerUser, userID).Return(nil, errors.New("db error")).Once()
+
+		msgOpt := service.checkStorageQuota(userID, nil, 1)
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+	})
+}
+
+func TestResolveUploadServer(t *testing.T) {
+	userID := primitive.NewObjectID().Hex()
+	serverID := primitive.NewObjectID()
+
+	t.Run("未指定伺服器", func(t *testing.T) {
+		service := &fileUploadService{}
+
+		result, msgOpt := service.resolveUploadServer(userID, "")
+
+		assert.Nil(t, msgOpt)
+		assert.Nil(t, result)
+	})
+
+	t.Run("無效的伺服器ID", func(t *testing.T) {
+		service := &fileUploadService{}
+
+		_, msgOpt := service.resolveUploadServer(userID, "invalid")
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+
+	t.Run("非伺服器成員", func(t *testing.T) {
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		service := &fileUploadService{serverMemberRepo: mockMemberRepo}
+
+		mockMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID).Return(false, nil).Once()
+
+		_, msgOpt := service.resolveUploadServer(userID, serverID.Hex())
+
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrNotServerMember, msgOpt.Code)
+		mockMemberRepo.AssertExpectations(t)
+	})
+
+	t.Run("伺服器成員", func(t *testing.T) {
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		service := &fileUploadService{serverMemberRepo: mockMemberRepo}
+
+		mockMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID).Return(true, nil).Once()
+
+		result, msgOpt := service.resolveUploadServer(userID, serverID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, serverID, *result)
+		mockMemberRepo.AssertExpectations(t)
+	})
+}
+
+func TestGetStorageUsage(t *testing.T) {
+	userID := primitive.NewObjectID().Hex()
+	serverID := primitive.NewObjectID()
+
+	t.Run("返回用戶與伺服器用量", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		mockMemberRepo := new(mocks.ServerMemberRepository)
+		service := &fileUploadService{
+			config:           newQuotaTestConfig(1000, 0),
+			fileRepo:         mockFileRepo,
+			serverMemberRepo: mockMemberRepo,
+		}
+
+		mockMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID).Return(true, nil).Once()
+		mockFileRepo.On("GetStorageUsage", models.StorageOwnerUser, userID).Return(&models.StorageUsage{TotalSize: 1200, FileCount: 3}, nil).Once()
+		mockFileRepo.On("GetStorageUsage", models.StorageOwnerServer, serverID.Hex()).Return(&models.StorageUsage{TotalSize: 300, FileCount: 1}, nil).Once()
+
+		usage, msgOpt := service.GetStorageUsage(userID, serverID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, int64(1200), usage.User.UsedBytes)
+		assert.Equal(t, int64(3), usage.User.FileCount)
+		assert.Equal(t, int64(1000), usage.User.QuotaBytes)
+		assert.Equal(t, int64(0), usage.User.RemainingBytes, "超出配額時剩餘空間為 0")
+		assert.NotNil(t, usage.Server)
+		assert.Equal(t, int64(300), usage.Server.UsedBytes)
+		assert.Equal(t, int64(-1), usage.Server.RemainingBytes, "不限制時剩餘空間為 -1")
+		mockFileRepo.AssertExpectations(t)
+		mockMemberRepo.AssertExpectations(t)
+	})
+
+	t.Run("只查詢用戶用量", func(t *testing.T) {
+		mockFileRepo := new(mockFileRepository)
+		service := &fileUploadService{config: newQuotaTestConfig(1000, 0), fileRepo: mockFileRepo}
+
+		mockFileRepo.On("GetStorageUsage", models.StorageOwnerUser, userID).Return(&models.StorageUsage{TotalSize: 400, FileCount: 2}, nil).Once()
+
+		usage, msgOpt := service.GetStorageUsage(userID, "")
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, int64(600), usage.User.RemainingBytes)
+		assert.Nil(t, usage.Server)
+		mockFileRepo.AssertExpectations(t)
+	})
+}
+
+func TestUploadServerFileQuotaExceeded(t *testing.T) {
+	mockFileProvider := new(mockFileProvider)
+	mockFileRepo := new(mockFileRepository)
+	mockMemberRepo := new(mocks.ServerMemberRepository)
+	service := &fileUploadService{
+		config:           newQuotaTestConfig(0, 1000),
+		fileProvider:     mockFileProvider,
+		fileRepo:         mockFileRepo,
+		serverMemberRepo: mockMemberRepo,
+	}
+
+	userID := primitive.NewObjectID().Hex()
+	serverID := primitive.NewObjectID()
+	content := []byte("%PDF-1.4 large report")
+	header := createTestFileHeader("report.pdf", 2000, "application/pdf")
+
+	mockMemberRepo.On("IsMemberOfServer", serverID.Hex(), userID).Return(true, nil).Once()
+	mockFileRepo.On("GetStorageUsage", models.StorageOwnerServer, serverID.Hex()).Return(&models.StorageUsage{}, nil).Once()
+
+	result, msgOpt := service.UploadServerFile(createTestFile(content), header, userID, serverID.Hex(), models.GetGeneralUploadConfig())
+
+	assert.Nil(t, result)
+	assert.NotNil(t, msgOpt)
+	assert.Equal(t, models.ErrQuotaExceeded, msgOpt.Code)
+	mockFileProvider.AssertNotCalled(t, "SaveFile", mock.Anything, mock.Anything)
+	mockFileRepo.AssertExpectations(t)
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"chat_app_backend/utils"
+	"crypto/sha256"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// TwoFactorRecoveryCodeCount 啟用兩步驟驗證時產生的復原碼數量
+const TwoFactorRecoveryCodeCount = 10
+
+// TwoFactorChallengeMaxAttempts 每個兩步驟登入挑戰允許的驗證失敗次數，達到後需重新以密碼登入
+const TwoFactorChallengeMaxAttempts = 5
+
+// twoFactorKey 由設定的加密金鑰衍生 AES-256 金鑰
+func (us *userService) twoFactorKey() ([]byte, error) {
+	if us.config == nil || us.config.TOTP.EncryptionKey == "" {
+		return nil, errors.New("TOTP_ENCRYPTION_KEY 未設定")
+	}
+	key := sha256.Sum256([]byte(us.config.TOTP.EncryptionKey))
+	return key[:], nil
+}
+
+// decryptTwoFactorSecret 解密儲存的 TOTP 密鑰
+func (us *userService) decryptTwoFactorSecret(encrypted string) (string, error) {
+	key, err := us.twoFactorKey()
+	if err != nil {
+		return "", err
+	}
+	secret, err := utils.AESDecrypt(encrypted, key)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+// getUserCredentials 獲取包含兩步驟驗證密鑰、一次性 token 等敏感欄位的用戶資料
+func (us *userService) getUserCredentials(userID string) (*models.User, *models.MessageOptions) {
+	user, err := us.userRepo.GetUserCredentials(userID)
+	if err != nil {
+		if errors.Is(err, providers.ErrDocumentNotFound) {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrUserNotFound,
+				Message: "用戶不存在",
+			}
+		}
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "獲取用戶資料失敗",
+			Details: err.Error(),
+		}
+	}
+	return user, nil
+}
+
+// SetupTwoFactor 產生新的 TOTP 密鑰，暫存為待確認狀態，需以 EnableTwoFactor 驗證後才會生效
+func (us *userService) SetupTwoFactor(userID string) (*models.TwoFactorSetupResponse, *models.MessageOptions) {
+	user, msgOpt := us.getUserCredentials(userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+	if user.TwoFactorEnabled {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrTwoFactorAlreadyEnabled,
+			Message: "已啟用兩步驟驗證，請先停用後再重新設定",
+		}
+	}
+
+	key, err := us.twoFactorKey()
+	if err != nil {
+		slog.Error("兩步驟驗證加密金鑰未設定", "error", err)
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "兩步驟驗證目前無法使用",
+		}
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "產生兩步驟驗證密鑰失敗",
+			Details: err.Error(),
+		}
+	}
+
+	encrypted, err := utils.AESEncrypt([]byte(secret), key)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "加密兩步驟驗證密鑰失敗",
+			Details: err.Error(),
+		}
+	}
+
+	updates := map[string]any{
+		"two_factor_pending_secret": encrypted,
+		"updated_at":                time.Now(),
+	}
+	if err = us.userRepo.UpdateUser(userID, updates); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "儲存兩步驟驗證密鑰失敗",
+			Details: err.Error(),
+		}
+	}
+
+	account := user.Email
+	if account == "" {
+		account = user.Username
+	}
+
+	return &models.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: utils.BuildOTPAuthURI(us.config.TOTP.Issuer, account, secret),
+	}, nil
+}
+
+// EnableTwoFactor 以驗證碼確認待啟用的密鑰並啟用兩步驟驗證，返回僅顯示一次的復原碼
+func (us *userService) EnableTwoFactor(userID string, code string) (*models.TwoFactorRecoveryCodesResponse, *models.MessageOptions) {
+	user, msgOpt := us.getUserCredentials(userID)
+	if msgOpt != nil {
+		return nil, msgOpt
+	}
+	if user.TwoFactorEnabled {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrTwoFactorAlreadyEnabled,
+			Message: "已啟用兩步驟驗證",
+		}
+	}
+	if user.TwoFactorPendingSecret == "" {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidParams,
+			Message: "請先設定兩步驟驗證",
+		}
+	}
+
+	secret, err := us.decryptTwoFactorSecret(user.TwoFactorPendingSecret)
+	if err != nil {
+		slog.Error("解密兩步驟驗證密鑰失敗", "user_id", userID, "error", err)
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "兩步驟驗證目前無法使用",
+		}
+	}
+
+	counter, ok := utils.ValidateTOTPCode(secret, code, time.Now())
+	if !ok {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidTwoFactorCode,
+			Message: "驗證碼錯誤",
+		}
+	}
+
+	recoveryCodes, err := utils.GenerateRecoveryCodes(TwoFactorRecoveryCodeCount)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "產生復原碼失敗",
+			Details: err.Error(),
+		}
+	}
+	hashes := make([]string, len(recoveryCodes))
+	for i, recoveryCode := range recoveryCodes {
+		hashes[i] = utils.HashRecoveryCode(recoveryCode)
+	}
+
+	updates := map[string]any{
+		"two_factor_enabled":        true,
+		"two_factor_secret":         user.TwoFactorPendingSecret,
+		"two_factor_pending_secret": "",
+		"two_factor_last_counter":   counter,
+		"recovery_codes":            hashes,
+		"updated_at":                time.Now(),
+	}
+	if err = us.userRepo.UpdateUser(userID, updates); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "啟用兩步驟驗證失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return &models.TwoFactorRecoveryCodesResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+// DisableTwoFactor 驗證 TOTP 驗證碼或復原碼後停用兩步驟驗證，並清除密鑰與復原碼
+func (us *userService) DisableTwoFactor(userID string, code string) *models.MessageOptions {
+	user, msgOpt := us.getUserCredentials(userID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	if !user.TwoFactorEnabled {
+		return &models.MessageOptions{
+			Code:    models.ErrTwoFactorNotEnabled,
+			Message: "尚未啟用兩步驟驗證",
+		}
+	}
+
+	if msgOpt = us.verifyTwoFactorCode(user, code); msgOpt != nil {
+		return msgOpt
+	}
+
+	updates := map[string]any{
+		"two_factor_enabled":      false,
+		"two_factor_secret":       "",
+		"two_factor_last_counter": int64(0),
+		"recovery_codes":          []string{},
+		"updated_at":              time.Now(),
+	}
+	if err := us.userRepo.UpdateUser(userID, updates); err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "停用兩步驟驗證失敗",
+			Details: err.Error(),
+		}
+	}
+
+	return nil
+}
+
+// VerifyTwoFactorLogin 以挑戰 token 與驗證碼（或復原碼）完成兩步驟登入
+// 每個挑戰只能成功使用一次，驗證失敗達上限後失效
+func (us *userService) VerifyTwoFactorLogin(challengeToken string, code string, device models.DeviceInfo) (*models.LoginResponse, *models.MessageOptions) {
+	claims, err := utils.ParseTwoFactorChallengeToken(challengeToken)
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "登入驗證已過期，請重新登入",
+		}
+	}
+	if msgOpt := us.checkTwoFactorChallenge(claims.ID); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	user, msgOpt := us.getUserCredentials(claims.UserID)
+	if msgOpt != nil {
+		if msgOpt.Code == models.ErrUserNotFound {
+			msgOpt.Code = models.ErrInvalidToken
+		}
+		return nil, msgOpt
+	}
+	if !user.TwoFactorEnabled {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "登入驗證已失效，請重新登入",
+		}
+	}
+
+	if msgOpt = us.verifyTwoFactorCode(user, code); msgOpt != nil {
+		if msgOpt.Code == models.ErrInvalidTwoFactorCode {
+			if failOpt := us.recordTwoFactorChallengeFailure(claims.ID, claims.UserID); failOpt != nil {
+				return nil, failOpt
+			}
+		}
+		return nil, msgOpt
+	}
+
+	if msgOpt = us.consumeTwoFactorChallenge(claims.ID); msgOpt != nil {
+		return nil, msgOpt
+	}
+
+	return us.issueLoginTokens(user, device)
+}
+
+// checkTwoFactorChallenge 檢查挑戰是否已使用或失敗次數已達上限
+// 快取無法使用時僅記錄警告並放行，驗證碼本身仍有 TOTP 時間步與復原碼的一次性限制
+func (us *userService) checkTwoFactorChallenge(challengeID string) *models.MessageOptions {
+	if us.cache == nil {
+		return nil
+	}
+
+	used, err := us.cache.Get(utils.TwoFactorChallengeUsedCacheKey(challengeID))
+	if err != nil {
+		slog.Warn("讀取兩步驟登入挑戰狀態失敗", "challenge_id", challengeID, "error", err)
+		return nil
+	}
+	if used != "" {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "登入驗證已失效，請重新登入",
+		}
+	}
+
+	attempts, err := us.cache.Get(utils.TwoFactorChallengeAttemptsCacheKey(challengeID))
+	if err != nil {
+		slog.Warn("讀取兩步驟登入挑戰狀態失敗", "challenge_id", challengeID, "error", err)
+		return nil
+	}
+	if count, _ := strconv.Atoi(attempts); count >= TwoFactorChallengeMaxAttempts {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "驗證失敗次數過多，請重新登入",
+		}
+	}
+	return nil
+}
+
+// recordTwoFactorChallengeFailure 記錄挑戰驗證失敗，達到上限時返回挑戰已失效的錯誤
+func (us *userService) recordTwoFactorChallengeFailure(challengeID string, userID string) *models.MessageOptions {
+	if us.cache == nil {
+		return nil
+	}
+
+	count, err := us.cache.Incr(utils.TwoFactorChallengeAttemptsCacheKey(challengeID), utils.TwoFactorChallengeExpiry)
+	if err != nil {
+		slog.Warn("記錄兩步驟登入失敗次數失敗", "challenge_id", challengeID, "error", err)
+		return nil
+	}
+	if count >= TwoFactorChallengeMaxAttempts {
+		slog.Warn("兩步驟登入驗證失敗次數過多，挑戰已失效", "user_id", userID, "challenge_id", challengeID)
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "驗證失敗次數過多，請重新登入",
+		}
+	}
+	return nil
+}
+
+// consumeTwoFactorChallenge 將挑戰標記為已使用，同一挑戰無法再換取第二組登入 token
+func (us *userService) consumeTwoFactorChallenge(challengeID string) *models.MessageOptions {
+	if us.cache == nil {
+		return nil
+	}
+
+	first, err := us.cache.SetNX(utils.TwoFactorChallengeUsedCacheKey(challengeID), "1", utils.TwoFactorChallengeExpiry)
+	if err != nil {
+		slog.Warn("標記兩步驟登入挑戰已使用失敗", "challenge_id", challengeID, "error", err)
+		return nil
+	}
+	if !first {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidToken,
+			Message: "登入驗證已失效，請重新登入",
+		}
+	}
+	return nil
+}
+
+// verifyTwoFactorCode 驗證 TOTP 驗證碼或復原碼，驗證成功後標記為已使用
+func (us *userService) verifyTwoFactorCode(user *models.User, code string) *models.MessageOptions {
+	userID := user.GetID().Hex()
+
+	secret, err := us.decryptTwoFactorSecret(user.TwoFactorSecret)
+	if err != nil {
+		slog.Error("解密兩步驟驗證密鑰失敗", "user_id", userID, "error", err)
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "兩步驟驗證目前無法使用",
+		}
+	}
+
+	if counter, ok := utils.ValidateTOTPCode(secret, code, time.Now()); ok {
+		consumed, err := us.userRepo.ConsumeTOTPCounter(userID, counter)
+		if err != nil {
+			return &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Message: "驗證兩步驟驗證碼失敗",
+				Details: err.Error(),
+			}
+		}
+		if !consumed {
+			return &models.MessageOptions{
+				Code:    models.ErrInvalidTwoFactorCode,
+				Message: "驗證碼已使用，請等待下一組驗證碼",
+			}
+		}
+		return nil
+	}
+
+	// 不是有效的 TOTP 驗證碼時，嘗試作為復原碼使用
+	consumed, err := us.userRepo.ConsumeRecoveryCode(userID, utils.HashRecoveryCode(code))
+	if err != nil {
+		return &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Message: "驗證復原碼失敗",
+			Details: err.Error(),
+		}
+	}
+	if !consumed {
+		return &models.MessageOptions{
+			Code:    models.ErrInvalidTwoFactorCode,
+			Message: "驗證碼錯誤",
+		}
+	}
+
+	slog.Info("用戶使用復原碼通過兩步驟驗證", "user_id", userID)
+	return nil
+}
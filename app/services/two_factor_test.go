@@ -0,0 +1,417 @@
+package services
+
+import (
+	"chat_app_backend/app/mocks"
+	"chat_app_backend/app/models"
+	"chat_app_backend/app/providers"
+	"chat_app_backend/config"
+	"chat_app_backend/utils"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// setupTwoFactorConfig 設置兩步驟驗證測試用的 config（token 產生依賴全域設定）
+func setupTwoFactorConfig(t *testing.T) *config.Config {
+	cfg := &config.Config{
+		JWT: config.JWTConfig{
+			AccessSecret:        "test_access_secret",
+			RefreshSecret:       "test_refresh_secret",
+			AccessExpireMinutes: 15,
+			RefreshExpireHours:  24,
+		},
+		TOTP: config.TOTPConfig{
+			Issuer:        "Chat App",
+			EncryptionKey: "test_totp_key",
+		},
+	}
+
+	original := config.AppConfig
+	config.AppConfig = cfg
+	t.Cleanup(func() { config.AppConfig = original })
+	return cfg
+}
+
+// encryptTestTOTPSecret 以測試金鑰加密 TOTP 密鑰
+func encryptTestTOTPSecret(t *testing.T, cfg *config.Config, secret string) string {
+	key := sha256.Sum256([]byte(cfg.TOTP.EncryptionKey))
+	encrypted, err := utils.AESEncrypt([]byte(secret), key[:])
+	assert.NoError(t, err)
+	return encrypted
+}
+
+// newTwoFactorUser 建立已啟用兩步驟驗證的測試用戶
+func newTwoFactorUser(t *testing.T, cfg *config.Config, secret string, recoveryCodes ...string) *models.User {
+	hashes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		hashes[i] = utils.HashRecoveryCode(code)
+	}
+	return &models.User{
+		BaseModel:        providers.BaseModel{ID: primitive.NewObjectID()},
+		Email:            "alice@example.com",
+		TwoFactorEnabled: true,
+		TwoFactorSecret:  encryptTestTOTPSecret(t, cfg, secret),
+		RecoveryCodes:    hashes,
+	}
+}
+
+// currentTOTPCode 產生目前時間步的驗證碼，同時返回時間步供比對
+func currentTOTPCode(t *testing.T, secret string) (string, int64) {
+	counter := utils.TOTPCounter(time.Now())
+	code, err := utils.GenerateTOTPCode(secret, counter)
+	assert.NoError(t, err)
+	return code, counter
+}
+
+func TestSetupTwoFactor(t *testing.T) {
+	t.Run("產生待確認的密鑰", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}, Email: "alice@example.com"}
+
+		var pending string
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			updateUserFunc: func(id string, updates map[string]any) error {
+				assert.NotContains(t, updates, "two_factor_enabled", "確認驗證碼前不應啟用")
+				pending = updates["two_factor_pending_secret"].(string)
+				return nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		setup, msgOpt := service.SetupTwoFactor(user.ID.Hex())
+
+		assert.Nil(t, msgOpt)
+		assert.Contains(t, setup.OTPAuthURI, "otpauth://totp/")
+		assert.Contains(t, setup.OTPAuthURI, "secret="+setup.Secret)
+		assert.NotEqual(t, setup.Secret, pending, "密鑰應加密儲存")
+
+		decrypted, err := service.decryptTwoFactorSecret(pending)
+		assert.NoError(t, err)
+		assert.Equal(t, setup.Secret, decrypted)
+	})
+
+	t.Run("已啟用時拒絕重新設定", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := newTwoFactorUser(t, cfg, "JBSWY3DPEHPK3PXP")
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		_, msgOpt := service.SetupTwoFactor(user.ID.Hex())
+
+		assert.Equal(t, models.ErrTwoFactorAlreadyEnabled, msgOpt.Code)
+	})
+
+	t.Run("未設定加密金鑰", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		cfg.TOTP.EncryptionKey = ""
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) {
+				return &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}}, nil
+			},
+			updateUserFunc: func(id string, updates map[string]any) error {
+				t.Fatal("未設定加密金鑰時不應儲存密鑰")
+				return nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		_, msgOpt := service.SetupTwoFactor(primitive.NewObjectID().Hex())
+
+		assert.Equal(t, models.ErrInternalServer, msgOpt.Code)
+	})
+}
+
+func TestEnableTwoFactor(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+
+	t.Run("驗證碼正確後啟用並返回復原碼", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := &models.User{
+			BaseModel:              providers.BaseModel{ID: primitive.NewObjectID()},
+			TwoFactorPendingSecret: encryptTestTOTPSecret(t, cfg, secret),
+		}
+
+		var saved map[string]any
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			updateUserFunc: func(id string, updates map[string]any) error {
+				saved = updates
+				return nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		code, counter := currentTOTPCode(t, secret)
+		result, msgOpt := service.EnableTwoFactor(user.ID.Hex(), code)
+
+		assert.Nil(t, msgOpt)
+		assert.Len(t, result.RecoveryCodes, TwoFactorRecoveryCodeCount)
+		assert.Equal(t, true, saved["two_factor_enabled"])
+		assert.Equal(t, user.TwoFactorPendingSecret, saved["two_factor_secret"])
+		assert.Equal(t, "", saved["two_factor_pending_secret"])
+		assert.Equal(t, counter, saved["two_factor_last_counter"])
+
+		// 僅儲存復原碼雜湊
+		hashes := saved["recovery_codes"].([]string)
+		assert.Len(t, hashes, TwoFactorRecoveryCodeCount)
+		assert.Equal(t, utils.HashRecoveryCode(result.RecoveryCodes[0]), hashes[0])
+		assert.NotContains(t, hashes, result.RecoveryCodes[0])
+	})
+
+	t.Run("驗證碼錯誤", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := &models.User{
+			BaseModel:              providers.BaseModel{ID: primitive.NewObjectID()},
+			TwoFactorPendingSecret: encryptTestTOTPSecret(t, cfg, secret),
+		}
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			updateUserFunc: func(id string, updates map[string]any) error {
+				t.Fatal("驗證碼錯誤時不應啟用")
+				return nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		code, _ := utils.GenerateTOTPCode(secret, utils.TOTPCounter(time.Now())+5)
+		_, msgOpt := service.EnableTwoFactor(user.ID.Hex(), code)
+
+		assert.Equal(t, models.ErrInvalidTwoFactorCode, msgOpt.Code)
+	})
+
+	t.Run("尚未設定密鑰", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) {
+				return &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}}, nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		_, msgOpt := service.EnableTwoFactor(primitive.NewObjectID().Hex(), "123456")
+
+		assert.Equal(t, models.ErrInvalidParams, msgOpt.Code)
+	})
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+
+	t.Run("使用復原碼停用", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := newTwoFactorUser(t, cfg, secret, "abcde-fghij")
+
+		var saved map[string]any
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeRecoveryCodeFunc: func(id string, codeHash string) (bool, error) {
+				return codeHash == utils.HashRecoveryCode("abcde-fghij"), nil
+			},
+			updateUserFunc: func(id string, updates map[string]any) error {
+				saved = updates
+				return nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		msgOpt := service.DisableTwoFactor(user.ID.Hex(), "ABCDE-FGHIJ")
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, false, saved["two_factor_enabled"])
+		assert.Equal(t, "", saved["two_factor_secret"])
+		assert.Empty(t, saved["recovery_codes"])
+	})
+
+	t.Run("尚未啟用", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) {
+				return &models.User{BaseModel: providers.BaseModel{ID: primitive.NewObjectID()}}, nil
+			},
+		}
+		service := NewUserService(cfg, nil, mockRepo, nil, nil, nil, nil)
+
+		msgOpt := service.DisableTwoFactor(primitive.NewObjectID().Hex(), "123456")
+
+		assert.Equal(t, models.ErrTwoFactorNotEnabled, msgOpt.Code)
+	})
+}
+
+func TestVerifyTwoFactorLogin(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+
+	t.Run("TOTP 驗證碼正確後發放登入 token", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := newTwoFactorUser(t, cfg, secret)
+
+		var consumedCounter int64
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeTOTPCounterFunc: func(id string, counter int64) (bool, error) {
+				consumedCounter = counter
+				return true, nil
+			},
+		}
+		mockODM := new(mocks.ODM)
+		mockODM.On("Create", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil).Once()
+		service := NewUserService(cfg, mockODM, mockRepo, nil, nil, nil, nil)
+
+		challenge, _ := utils.GenTwoFactorChallengeToken(user.ID.Hex())
+		code, counter := currentTOTPCode(t, secret)
+		response, msgOpt := service.VerifyTwoFactorLogin(challenge.Token, code, models.DeviceInfo{})
+
+		assert.Nil(t, msgOpt)
+		assert.NotEmpty(t, response.AccessToken)
+		assert.NotEmpty(t, response.RefreshToken)
+		assert.NotEmpty(t, response.CSRFToken)
+		assert.False(t, response.TwoFactorRequired)
+		assert.Equal(t, counter, consumedCounter)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("重複使用同一組驗證碼", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := newTwoFactorUser(t, cfg, secret)
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeTOTPCounterFunc: func(id string, counter int64) (bool, error) { return false, nil },
+		}
+		mockODM := new(mocks.ODM)
+		service := NewUserService(cfg, mockODM, mockRepo, nil, nil, nil, nil)
+
+		challenge, _ := utils.GenTwoFactorChallengeToken(user.ID.Hex())
+		code, _ := currentTOTPCode(t, secret)
+		_, msgOpt := service.VerifyTwoFactorLogin(challenge.Token, code, models.DeviceInfo{})
+
+		assert.Equal(t, models.ErrInvalidTwoFactorCode, msgOpt.Code)
+		mockODM.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("復原碼只能使用一次", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := newTwoFactorUser(t, cfg, secret, "abcde-fghij")
+
+		remaining := map[string]bool{utils.HashRecoveryCode("abcde-fghij"): true}
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeRecoveryCodeFunc: func(id string, codeHash string) (bool, error) {
+				if !remaining[codeHash] {
+					return false, nil
+				}
+				delete(remaining, codeHash)
+				return true, nil
+			},
+		}
+		mockODM := new(mocks.ODM)
+		mockODM.On("Create", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil).Once()
+		service := NewUserService(cfg, mockODM, mockRepo, nil, nil, nil, nil)
+
+		challenge, _ := utils.GenTwoFactorChallengeToken(user.ID.Hex())
+		response, msgOpt := service.VerifyTwoFactorLogin(challenge.Token, "abcde-fghij", models.DeviceInfo{})
+		assert.Nil(t, msgOpt)
+		assert.NotEmpty(t, response.AccessToken)
+
+		_, msgOpt = service.VerifyTwoFactorLogin(challenge.Token, "abcde-fghij", models.DeviceInfo{})
+		assert.Equal(t, models.ErrInvalidTwoFactorCode, msgOpt.Code)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("同一挑戰只能成功使用一次", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := newTwoFactorUser(t, cfg, secret, "abcde-fghij", "klmno-pqrst")
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc:  func(id string) (*models.User, error) { return user, nil },
+			consumeRecoveryCodeFunc: func(id string, codeHash string) (bool, error) { return true, nil },
+		}
+		mockODM := new(mocks.ODM)
+		mockODM.On("Create", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil).Once()
+		service := NewUserService(cfg, mockODM, mockRepo, nil, providers.NewInMemoryCacheProvider(), nil, nil)
+
+		challenge, _ := utils.GenTwoFactorChallengeToken(user.ID.Hex())
+		_, msgOpt := service.VerifyTwoFactorLogin(challenge.Token, "abcde-fghij", models.DeviceInfo{})
+		assert.Nil(t, msgOpt)
+
+		// 即使是另一組有效的復原碼也不能重複使用同一挑戰
+		_, msgOpt = service.VerifyTwoFactorLogin(challenge.Token, "klmno-pqrst", models.DeviceInfo{})
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("驗證失敗達上限後挑戰失效", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		user := newTwoFactorUser(t, cfg, secret)
+		totpChecked := false
+		mockRepo := &testUserRepository{
+			getUserCredentialsFunc: func(id string) (*models.User, error) { return user, nil },
+			consumeTOTPCounterFunc: func(id string, counter int64) (bool, error) {
+				totpChecked = true
+				return true, nil
+			},
+		}
+		service := NewUserService(cfg, new(mocks.ODM), mockRepo, nil, providers.NewInMemoryCacheProvider(), nil, nil)
+
+		challenge, _ := utils.GenTwoFactorChallengeToken(user.ID.Hex())
+		for i := 1; i < TwoFactorChallengeMaxAttempts; i++ {
+			_, msgOpt := service.VerifyTwoFactorLogin(challenge.Token, "000000-wrong", models.DeviceInfo{})
+			assert.Equal(t, models.ErrInvalidTwoFactorCode, msgOpt.Code)
+		}
+		_, msgOpt := service.VerifyTwoFactorLogin(challenge.Token, "000000-wrong", models.DeviceInfo{})
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+
+		// 挑戰失效後即使驗證碼正確也需重新登入
+		code, _ := currentTOTPCode(t, secret)
+		_, msgOpt = service.VerifyTwoFactorLogin(challenge.Token, code, models.DeviceInfo{})
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+		assert.False(t, totpChecked)
+
+		// 新的挑戰不受影響
+		newChallenge, _ := utils.GenTwoFactorChallengeToken(user.ID.Hex())
+		_, msgOpt = service.VerifyTwoFactorLogin(newChallenge.Token, "000000-wrong", models.DeviceInfo{})
+		assert.Equal(t, models.ErrInvalidTwoFactorCode, msgOpt.Code)
+	})
+
+	t.Run("access token 不能當作挑戰 token", func(t *testing.T) {
+		cfg := setupTwoFactorConfig(t)
+		service := NewUserService(cfg, nil, &testUserRepository{}, nil, nil, nil, nil)
+
+		accessToken, _ := utils.GenAccessToken(primitive.NewObjectID().Hex(), "")
+		_, msgOpt := service.VerifyTwoFactorLogin(accessToken.Token, "123456", models.DeviceInfo{})
+
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+	})
+}
+
+func TestLogin_TwoFactorRequired(t *testing.T) {
+	cfg := setupTwoFactorConfig(t)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := newTwoFactorUser(t, cfg, "JBSWY3DPEHPK3PXP")
+	user.Password = string(hashedPassword)
+
+	mockODM := new(mocks.ODM)
+	mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(2).(*models.User) = *user
+	}).Once()
+	service := NewUserService(cfg, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+	response, msgOpt := service.Login(models.User{Email: user.Email, Password: "password123"}, models.DeviceInfo{})
+
+	assert.Nil(t, msgOpt)
+	assert.True(t, response.TwoFactorRequired)
+	assert.Empty(t, response.AccessToken)
+	assert.Empty(t, response.RefreshToken)
+
+	claims, err := utils.ParseTwoFactorChallengeToken(response.ChallengeToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID.Hex(), claims.UserID)
+
+	// 尚未通過兩步驟驗證，不應建立 refresh token
+	mockODM.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
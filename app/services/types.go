@@ -19,6 +19,10 @@ const (
 	PongWait         = 60 * time.Second    // Pong 等待時間
 	PingPeriod       = (PongWait * 9) / 10 // Ping 週期
 	CloseGracePeriod = 10 * time.Second    // 優雅關閉等待時間
+	TypingTimeout    = 5 * time.Second     // 輸入中狀態未刷新時自動過期
+	MaxReactionTypes = 20                  // 每則訊息的表情回應種類上限
+	MaxEmojiLength   = 64                  // 表情回應長度上限（位元組）
+	MaxAttachments   = 10                  // 每則訊息的附件數量上限
 )
 
 // WebSocket 消息結構
@@ -35,13 +39,66 @@ type WsStatusResponse struct {
 
 // MessageResponse 定義聊天室消息
 type MessageResponse struct {
+	ID        string          `json:"id,omitempty"`
 	RoomType  models.RoomType `json:"room_type"`
 	RoomID    string          `json:"room_id"`
 	SenderID  string          `json:"sender_id"`
 	Content   string          `json:"content"`
 	Timestamp int64           `json:"timestamp"`
+	EditedAt  int64           `json:"edited_at,omitempty"`  // 最後編輯時間（毫秒）
+	IsDeleted bool            `json:"is_deleted,omitempty"` // 是否已刪除
+	// 附件
+	Attachments []models.MessageAttachment `json:"attachments,omitempty"`
+	// 引用與討論串
+	ReplyToMessageID string `json:"reply_to_message_id,omitempty"`
+	ThreadID         string `json:"thread_id,omitempty"`
+	ReplyCount       int64  `json:"reply_count,omitempty"`
+	LastReplyAt      int64  `json:"last_reply_at,omitempty"` // 最後回覆時間（毫秒）
+	// 表情回應
+	Reactions []models.ReactionSummary `json:"reactions,omitempty"`
+}
+
+// ReactionEvent 定義表情回應變更事件
+type ReactionEvent struct {
+	RoomType  models.RoomType `json:"room_type"`
+	RoomID    string          `json:"room_id"`
+	MessageID string          `json:"message_id"`
+	UserID    string          `json:"user_id"` // 新增或移除回應的用戶
+	Emoji     string          `json:"emoji"`
+	Count     int64           `json:"count"` // 變更後該表情的回應數
+}
+
+// TypingResponse 定義輸入中狀態事件
+type TypingResponse struct {
+	RoomType  models.RoomType `json:"room_type"`
+	RoomID    string          `json:"room_id"`
+	SenderID  string          `json:"sender_id"`
+	ExpiresIn int64           `json:"expires_in,omitempty"` // 未刷新時的過期時間（毫秒）
 }
 
+// ServerAccessRevokedEvent 定義用戶失去伺服器存取權的事件（被踢出或封鎖）
+type ServerAccessRevokedEvent struct {
+	ServerID   string   `json:"server_id"`
+	ChannelIDs []string `json:"channel_ids"` // 已離開的頻道房間
+}
+
+// ClientEvent 定義透過 Redis 廣播給所有實例的連線管理事件
+type ClientEvent struct {
+	Action     string   `json:"action"`
+	UserID     string   `json:"user_id"`
+	SessionID  string   `json:"session_id,omitempty"`
+	ServerID   string   `json:"server_id,omitempty"`
+	ChannelIDs []string `json:"channel_ids,omitempty"`
+}
+
+// roomEventSender 從房間事件中取出發送者，用於決定 message_sent / new_message
+type roomEventSender struct {
+	SenderID string `json:"sender_id"`
+}
+
+// userRoomType 用戶個人房間，用於推送給同一用戶的所有連線裝置（如已讀狀態同步）
+const userRoomType models.RoomType = "user"
+
 // ErrorResponse 定義錯誤回應結構
 type ErrorResponse struct {
 	OriginalAction string `json:"original_action"`
@@ -67,6 +124,8 @@ type Client struct {
 	Conn   *websocket.Conn
 	Send   chan []byte   // 發送訊息通道
 	Hub    ClientManager // 所屬的客戶端管理器
+	// 建立連線時使用的登入工作階段，撤銷工作階段時據此斷開連線
+	SessionID string
 	// Subscribed    map[string]bool
 	// SubscribedMux sync.RWMutex
 	// 房間活躍時間追蹤
@@ -16,6 +16,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -25,15 +26,19 @@ type userService struct {
 	odm               providers.ODM
 	fileUploadService FileUploadService       // 添加 FileUploadService 依賴
 	cache             providers.CacheProvider // 用戶資料快取
+	tokenDenylist     providers.TokenDenylist // access token 撤銷清單
+	mailer            providers.Mailer        // 寄送驗證信與重設密碼信
 }
 
-func NewUserService(cfg *config.Config, odm providers.ODM, userRepo repositories.UserRepository, fileUploadService FileUploadService, cache providers.CacheProvider) *userService {
+func NewUserService(cfg *config.Config, odm providers.ODM, userRepo repositories.UserRepository, fileUploadService FileUploadService, cache providers.CacheProvider, tokenDenylist providers.TokenDenylist, mailer providers.Mailer) *userService {
 	return &userService{
 		config:            cfg,
 		userRepo:          userRepo,
 		odm:               odm,
 		fileUploadService: fileUploadService,
 		cache:             cache,
+		tokenDenylist:     tokenDenylist,
+		mailer:            mailer,
 	}
 }
 
@@ -130,6 +135,20 @@ func (us *userService) RegisterUser(user models.User) *models.MessageOptions {
 	}
 	user.Password = string(hashedPassword)
 
+	// 兩步驟驗證需透過設定流程啟用，不接受註冊時指定
+	user.TwoFactorEnabled = false
+	// 電子郵件需透過驗證信確認，不接受註冊時指定
+	user.EmailVerified = false
+
+	// 預先產生用戶 ID，與驗證信 token 一併寫入；產生失敗不影響註冊，用戶可登入後重新寄送
+	user.ID = primitive.NewObjectID()
+	verificationToken, tokenErr := utils.GenEmailToken(utils.EmailTokenPurposeVerification, user.ID.Hex(), user.Email)
+	if tokenErr != nil {
+		slog.Error("產生電子郵件驗證 token 失敗", "error", tokenErr)
+	} else {
+		user.EmailVerificationTokenID = verificationToken.ID
+	}
+
 	// 設置創建時間和更新時間
 	now := time.Now()
 	user.CreatedAt = now
@@ -146,6 +165,13 @@ func (us *userService) RegisterUser(user models.User) *models.MessageOptions {
 		}
 	}
 
+	// 驗證信寄送失敗不影響註冊，用戶可登入後重新寄送
+	if tokenErr == nil {
+		if err = us.sendEmailVerificationMail(&user, verificationToken.Token); err != nil {
+			slog.Error("寄送電子郵件驗證信失敗", "user_id", user.ID.Hex(), "error", err)
+		}
+	}
+
 	return nil
 }
 
@@ -189,7 +215,7 @@ func (us *userService) GetUserResponseById(userID string) (*models.UserResponse,
 }
 
 // Login 處理用戶登入邏輯
-func (us *userService) Login(loginUser models.User) (*models.LoginResponse, *models.MessageOptions) {
+func (us *userService) Login(loginUser models.User, device models.DeviceInfo) (*models.LoginResponse, *models.MessageOptions) {
 	// 查找用戶
 	var user models.User
 	err := us.odm.FindOne(context.Background(), bson.M{"email": loginUser.Email}, &user)
@@ -216,6 +242,27 @@ func (us *userService) Login(loginUser models.User) (*models.LoginResponse, *mod
 		}
 	}
 
+	// 已啟用兩步驟驗證時先發放挑戰 token，待驗證碼確認後才發放登入 token
+	if user.TwoFactorEnabled {
+		challengeTokenResponse, err := utils.GenTwoFactorChallengeToken(user.GetID().Hex())
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Details: err,
+				Message: "生成兩步驟驗證挑戰失敗",
+			}
+		}
+		return &models.LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeTokenResponse.Token,
+		}, nil
+	}
+
+	return us.issueLoginTokens(&user, device)
+}
+
+// issueLoginTokens 建立登入工作階段並發放 refresh token、access token 與 CSRF token（登入驗證完成後呼叫）
+func (us *userService) issueLoginTokens(user *models.User, device models.DeviceInfo) (*models.LoginResponse, *models.MessageOptions) {
 	// 生成 Refresh Token
 	refreshTokenResponse, err := utils.GenRefreshToken(user.GetID().Hex())
 	if err != nil {
@@ -226,12 +273,20 @@ func (us *userService) Login(loginUser models.User) (*models.LoginResponse, *mod
 		}
 	}
 
-	// 將 refresh token 寫入資料庫
+	// 將 refresh token 寫入資料庫，第一個 token 的記錄 ID 即為 family（工作階段）ID
+	sessionID := primitive.NewObjectID()
+	now := time.Now().Unix()
 	var refreshTokenDoc = models.RefreshToken{
-		UserID:    user.GetID(),
-		Token:     refreshTokenResponse.Token,
-		ExpiresAt: refreshTokenResponse.ExpiresAt,
-		Revoked:   false,
+		BaseModel:        providers.BaseModel{ID: sessionID},
+		UserID:           user.GetID(),
+		Token:            refreshTokenResponse.Token,
+		ExpiresAt:        refreshTokenResponse.ExpiresAt,
+		Revoked:          false,
+		UserAgent:        device.UserAgent,
+		IP:               device.IP,
+		LastUsedAt:       now,
+		FamilyID:         sessionID,
+		SessionStartedAt: now,
 	}
 
 	err = us.odm.Create(context.Background(), &refreshTokenDoc)
@@ -245,7 +300,7 @@ func (us *userService) Login(loginUser models.User) (*models.LoginResponse, *mod
 	}
 
 	// 生成 Access Token
-	accessTokenResponse, err := utils.GenAccessToken(user.GetID().Hex())
+	accessTokenResponse, err := utils.GenAccessToken(user.GetID().Hex(), sessionID.Hex())
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
@@ -272,21 +327,30 @@ func (us *userService) Login(loginUser models.User) (*models.LoginResponse, *mod
 	}, nil
 }
 
-// 登出
+// 登出目前裝置，只撤銷目前的登入工作階段，其他裝置不受影響
 func (us *userService) Logout(c *gin.Context) *models.MessageOptions {
-	// 註銷 refresh token
-	_, userObjectID, err := utils.GetUserIDFromHeader(c)
+	userID, _, err := utils.GetUserIDFromHeader(c)
 	if err != nil {
 		return &models.MessageOptions{
 			Code: models.ErrUnauthorized,
 		}
 	}
 
-	// 使用 UpdateMany 直接更新所有符合條件的文檔
-	filter := bson.M{"user_id": userObjectID, "revoked": false}
-	update := bson.M{"$set": bson.M{"revoked": true}}
-	err = us.odm.UpdateMany(context.Background(), &models.RefreshToken{}, filter, update)
-	if err != nil {
+	sessionID, _ := utils.GetSessionIDFromHeader(c)
+	if sessionID == "" {
+		// 沒有工作階段 ID 的舊版 access token，改由 refresh token cookie 找出目前的工作階段
+		sessionID = us.sessionIDFromRefreshCookie(c, userID)
+	}
+
+	// 註銷目前工作階段的 refresh token，並撤銷其已發放的 access token
+	if sessionID != "" {
+		if msgOpt := us.RevokeSession(userID, sessionID); msgOpt != nil && msgOpt.Code != models.ErrNotFound {
+			return msgOpt
+		}
+	}
+
+	// 目前使用的 access token 立即失效
+	if err := us.revokeCurrentAccessToken(c); err != nil {
 		return &models.MessageOptions{
 			Code:    models.ErrInternalServer,
 			Details: err,
@@ -298,8 +362,45 @@ func (us *userService) Logout(c *gin.Context) *models.MessageOptions {
 	return nil
 }
 
-// RefreshToken 刷新令牌
-func (us *userService) RefreshToken(refreshToken string) (*models.RefreshTokenResponse, *models.MessageOptions) {
+// sessionIDFromRefreshCookie 由 refresh token cookie 找出用戶目前的登入工作階段，找不到時返回空字串
+func (us *userService) sessionIDFromRefreshCookie(c *gin.Context, userID string) string {
+	refreshToken, err := c.Cookie("refresh_token")
+	if err != nil || refreshToken == "" {
+		return ""
+	}
+
+	var refreshTokenDoc models.RefreshToken
+	if err := us.odm.FindOne(context.Background(), bson.M{"token": refreshToken}, &refreshTokenDoc); err != nil {
+		return ""
+	}
+	if refreshTokenDoc.UserID.Hex() != userID {
+		return ""
+	}
+	return refreshTokenDoc.SessionID().Hex()
+}
+
+// revokeCurrentAccessToken 將請求使用的 access token（以 jti 識別）列入撤銷清單
+func (us *userService) revokeCurrentAccessToken(c *gin.Context) error {
+	if us.tokenDenylist == nil {
+		return nil
+	}
+	accessToken, err := utils.GetAccessTokenByHeader(c)
+	if err != nil {
+		return nil
+	}
+	claims, err := utils.ParseAccessToken(accessToken)
+	if err != nil || claims.ExpiresAt == nil {
+		return nil
+	}
+	return us.tokenDenylist.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+}
+
+// RefreshTokenReuseGracePeriod 已輪替的 refresh token 仍可取得同一組新 token 的寬限時間，
+// 用於同一客戶端並發刷新（例如多個分頁同時刷新）時，晚到的請求不會被誤判為重複使用
+const RefreshTokenReuseGracePeriod = 30 * time.Second
+
+// RefreshToken 以 refresh token 換發新的 access token，並將 refresh token 輪替為同一 family 的新 token
+func (us *userService) RefreshToken(refreshToken string, device models.DeviceInfo) (*models.RefreshTokenResponse, *models.MessageOptions) {
 	// 查詢 refresh token
 	var refreshTokenDoc models.RefreshToken
 	err := us.odm.FindOne(context.Background(), bson.M{"token": refreshToken}, &refreshTokenDoc)
@@ -309,9 +410,8 @@ func (us *userService) RefreshToken(refreshToken string) (*models.RefreshTokenRe
 		}
 	}
 
-	// 檢查是否被註銷或過期
-	if refreshTokenDoc.Revoked || refreshTokenDoc.ExpiresAt < time.Now().Unix() {
-		// 移除 refresh token
+	// 已過期，移除 refresh token
+	if refreshTokenDoc.ExpiresAt < time.Now().Unix() {
 		err = us.odm.Delete(context.Background(), &refreshTokenDoc)
 		if err != nil {
 			return nil, &models.MessageOptions{
@@ -325,8 +425,32 @@ func (us *userService) RefreshToken(refreshToken string) (*models.RefreshTokenRe
 		}
 	}
 
-	// 生成新的 access token
-	accessTokenResponse, err := utils.GenAccessToken(refreshTokenDoc.UserID.Hex())
+	if refreshTokenDoc.Revoked {
+		// 已輪替的 token 需判斷是並發刷新還是重複使用
+		if !refreshTokenDoc.ReplacedByID.IsZero() {
+			return us.handleRotatedRefreshToken(&refreshTokenDoc)
+		}
+
+		// 已登出的 token，移除 refresh token
+		err = us.odm.Delete(context.Background(), &refreshTokenDoc)
+		if err != nil {
+			return nil, &models.MessageOptions{
+				Code:    models.ErrInternalServer,
+				Details: err,
+			}
+		}
+
+		return nil, &models.MessageOptions{
+			Code: models.ErrInvalidToken,
+		}
+	}
+
+	return us.rotateRefreshToken(&refreshTokenDoc, device)
+}
+
+// rotateRefreshToken 建立同一 family 的新 refresh token，並將舊 token 標記為已輪替
+func (us *userService) rotateRefreshToken(refreshTokenDoc *models.RefreshToken, device models.DeviceInfo) (*models.RefreshTokenResponse, *models.MessageOptions) {
+	refreshTokenResponse, err := utils.GenRefreshToken(refreshTokenDoc.UserID.Hex())
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
@@ -334,37 +458,127 @@ func (us *userService) RefreshToken(refreshToken string) (*models.RefreshTokenRe
 		}
 	}
 
-	// 生成 CSRF Token
-	csrfToken, err := utils.GenerateCSRFToken()
+	successor := models.RefreshToken{
+		BaseModel:        providers.BaseModel{ID: primitive.NewObjectID()},
+		UserID:           refreshTokenDoc.UserID,
+		Token:            refreshTokenResponse.Token,
+		ExpiresAt:        refreshTokenResponse.ExpiresAt,
+		Revoked:          false,
+		UserAgent:        refreshTokenDoc.UserAgent,
+		IP:               refreshTokenDoc.IP,
+		LastUsedAt:       time.Now().Unix(),
+		FamilyID:         refreshTokenDoc.SessionID(),
+		SessionStartedAt: refreshTokenDoc.SessionCreatedAt(),
+	}
+	if device.UserAgent != "" {
+		successor.UserAgent = device.UserAgent
+	}
+	if device.IP != "" {
+		successor.IP = device.IP
+	}
+
+	// 先建立新 token 再標記舊 token，確保並發請求看到已輪替的舊 token 時新 token 已存在
+	if err = us.odm.Create(context.Background(), &successor); err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+		}
+	}
+
+	rotated, err := us.userRepo.MarkRefreshTokenRotated(refreshTokenDoc.ID.Hex(), successor.ID.Hex())
+	if err != nil || !rotated {
+		if deleteErr := us.odm.Delete(context.Background(), &successor); deleteErr != nil {
+			slog.Warn("移除未使用的 refresh token 失敗", "token_id", successor.ID.Hex(), "error", deleteErr)
+		}
+	}
+	if err != nil {
+		return nil, &models.MessageOptions{
+			Code:    models.ErrInternalServer,
+			Details: err,
+		}
+	}
+
+	if !rotated {
+		// 其他並發請求已先完成輪替（或 token 已被撤銷），重新讀取後依輪替結果處理
+		if err = us.odm.FindByID(context.Background(), refreshTokenDoc.ID.Hex(), refreshTokenDoc); err != nil {
+			return nil, &models.MessageOptions{
+				Code: models.ErrInvalidToken,
+			}
+		}
+		return us.handleRotatedRefreshToken(refreshTokenDoc)
+	}
+
+	return us.buildRefreshTokenResponse(&successor)
+}
+
+// handleRotatedRefreshToken 處理已輪替的 refresh token：
+// 寬限時間內視為並發刷新，返回已輪替出的新 token；超過寬限時間則視為 token 遭竊取，撤銷整個 family
+func (us *userService) handleRotatedRefreshToken(refreshTokenDoc *models.RefreshToken) (*models.RefreshTokenResponse, *models.MessageOptions) {
+	if refreshTokenDoc.ReplacedByID.IsZero() {
+		return nil, &models.MessageOptions{
+			Code: models.ErrInvalidToken,
+		}
+	}
+
+	if time.Since(time.Unix(refreshTokenDoc.RotatedAt, 0)) <= RefreshTokenReuseGracePeriod {
+		var successor models.RefreshToken
+		err := us.odm.FindByID(context.Background(), refreshTokenDoc.ReplacedByID.Hex(), &successor)
+		if err != nil || successor.Revoked || successor.ExpiresAt < time.Now().Unix() {
+			return nil, &models.MessageOptions{
+				Code: models.ErrInvalidToken,
+			}
+		}
+		return us.buildRefreshTokenResponse(&successor)
+	}
+
+	userID := refreshTokenDoc.UserID.Hex()
+	sessionID := refreshTokenDoc.SessionID().Hex()
+	slog.Warn("偵測到已輪替的 refresh token 被重複使用，撤銷整個登入工作階段", "user_id", userID, "session_id", sessionID)
+
+	if msgOpt := us.RevokeSession(userID, sessionID); msgOpt != nil && msgOpt.Code != models.ErrNotFound {
+		return nil, msgOpt
+	}
+
+	return nil, &models.MessageOptions{
+		Code:    models.ErrInvalidToken,
+		Message: "登入憑證已被重複使用，請重新登入",
+	}
+}
+
+// buildRefreshTokenResponse 以 refresh token 記錄發放新的 access token 與 CSRF token
+func (us *userService) buildRefreshTokenResponse(refreshTokenDoc *models.RefreshToken) (*models.RefreshTokenResponse, *models.MessageOptions) {
+	// 生成新的 access token
+	accessTokenResponse, err := utils.GenAccessToken(refreshTokenDoc.UserID.Hex(), refreshTokenDoc.SessionID().Hex())
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
 			Details: err,
-			Message: "生成 CSRF 令牌失敗",
 		}
 	}
 
-	// 更新 refresh token 與過期時間
-	refreshTokenDoc.ExpiresAt = time.Now().Add(time.Hour * 24 * 7).Unix()
-	err = us.odm.Update(context.Background(), &refreshTokenDoc)
+	// 生成 CSRF Token
+	csrfToken, err := utils.GenerateCSRFToken()
 	if err != nil {
 		return nil, &models.MessageOptions{
 			Code:    models.ErrInternalServer,
 			Details: err,
+			Message: "生成 CSRF 令牌失敗",
 		}
 	}
 
 	return &models.RefreshTokenResponse{
-		AccessToken: accessTokenResponse.Token,
-		CSRFToken:   csrfToken,
+		AccessToken:  accessTokenResponse.Token,
+		RefreshToken: refreshTokenDoc.Token,
+		CSRFToken:    csrfToken,
 	}, nil
 }
 
 // 清除過期或被註銷的 refresh token
 func (us *userService) ClearExpiredRefreshTokens() error {
+	// 已輪替的 token 保留至過期，用於偵測重複使用
 	filter := bson.M{"$or": []bson.M{
 		{"expires_at": bson.M{"$lt": time.Now().Unix()}},
-		{"revoked": true},
+		{"revoked": true, "replaced_by_id": bson.M{"$exists": false}},
 	}}
 	err := us.odm.DeleteMany(context.Background(), &models.RefreshToken{}, filter)
 	return err
@@ -424,6 +638,8 @@ func (us *userService) GetUserProfile(userID string) (*models.UserProfileRespons
 		BannerURL:  us.getUserBannerURL(user),
 		Status:     user.Status,
 		Bio:        user.Bio,
+
+		EmailVerified: user.EmailVerified,
 	}
 
 	// 解析圖片 URL
@@ -502,16 +718,6 @@ func (us *userService) UploadUserImage(userID string, file multipart.File, heade
 		return nil, fmt.Errorf("圖片上傳失敗: %v", err)
 	}
 
-	// 獲取圖片URL
-	imageURL, err := us.fileUploadService.GetFileURLByID(uploadResult.ID.Hex())
-	if err != nil {
-		// 如果獲取URL失敗，嘗試刪除已上傳的檔案
-		if msgOpt := us.fileUploadService.DeleteFileByID(uploadResult.ID.Hex(), userID); msgOpt != nil {
-			fmt.Printf("清理上傳檔案失敗: %v\n", msgOpt.Details)
-		}
-		return nil, fmt.Errorf("獲取圖片URL失敗: %v", err)
-	}
-
 	// 更新用戶資料庫記錄（儲存檔案ID）
 	updates := map[string]any{
 		fieldName:    uploadResult.ID,
@@ -530,9 +736,12 @@ func (us *userService) UploadUserImage(userID string, file multipart.File, heade
 	// 清除用戶資料快取（頭像/橫幅 URL 已變更）
 	us.invalidateUserProfileCache(userID)
 
+	// 圖片在背景掃描與產生縮圖完成前尚未驗證，此時連結為空，以檔案ID與處理狀態讓客戶端完成後重新取得
 	return &models.UserImageResponse{
-		ImageURL: imageURL,
+		ImageURL: uploadResult.FileURL,
 		Type:     imageType,
+		FileID:   uploadResult.ID.Hex(),
+		Status:   uploadResult.Status,
 	}, nil
 }
 
@@ -614,31 +823,32 @@ func (us *userService) UpdateUserPassword(userID string, newPassword string) err
 		"updated_at": time.Now(),
 	}
 
-	return us.userRepo.UpdateUser(userID, updates)
+	if err = us.userRepo.UpdateUser(userID, updates); err != nil {
+		return err
+	}
+
+	// 變更密碼後所有裝置需重新登入
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return us.revokeAllUserTokens(userObjectID)
 }
 
 // GetTwoFactorStatus 獲取兩步驟驗證狀態
 func (us *userService) GetTwoFactorStatus(userID string) (*models.TwoFactorStatusResponse, error) {
-	user, err := us.userRepo.GetUserById(userID)
+	// 快取的用戶資料不含復原碼，需直接查詢
+	user, err := us.userRepo.GetUserCredentials(userID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.TwoFactorStatusResponse{
-		Enabled: user.TwoFactorEnabled,
+		Enabled:                user.TwoFactorEnabled,
+		RecoveryCodesRemaining: len(user.RecoveryCodes),
 	}, nil
 }
 
-// UpdateTwoFactorStatus 啟用/停用兩步驟驗證
-func (us *userService) UpdateTwoFactorStatus(userID string, enabled bool) error {
-	updates := map[string]any{
-		"two_factor_enabled": enabled,
-		"updated_at":         time.Now(),
-	}
-
-	return us.userRepo.UpdateUser(userID, updates)
-}
-
 // DeactivateAccount 停用帳號
 func (us *userService) DeactivateAccount(userID string) error {
 	updates := map[string]any{
@@ -646,7 +856,15 @@ func (us *userService) DeactivateAccount(userID string) error {
 		"updated_at": time.Now(),
 	}
 
-	return us.userRepo.UpdateUser(userID, updates)
+	if err := us.userRepo.UpdateUser(userID, updates); err != nil {
+		return err
+	}
+
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+	return us.revokeAllUserTokens(userObjectID)
 }
 
 // DeleteAccount 刪除帳號
@@ -4,17 +4,21 @@ import (
 	"chat_app_backend/app/mocks"
 	"chat_app_backend/app/models"
 	"chat_app_backend/app/providers"
+	"chat_app_backend/utils"
 	"fmt"
+	"mime/multipart"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // TestNewUserService 測試創建 UserService
 func TestNewUserService(t *testing.T) {
-	service := NewUserService(nil, nil, nil, nil, nil)
+	service := NewUserService(nil, nil, nil, nil, nil, nil, nil)
 
 	assert.NotNil(t, service, "服務應該被成功創建")
 	assert.IsType(t, &userService{}, service, "服務應該是 *userService 類型")
@@ -23,7 +27,7 @@ func TestNewUserService(t *testing.T) {
 // TestGetUserPictureURL 測試獲取用戶頭像 URL
 func TestGetUserPictureURL(t *testing.T) {
 	t.Run("用戶沒有頭像", func(t *testing.T) {
-		service := NewUserService(nil, nil, nil, nil, nil)
+		service := NewUserService(nil, nil, nil, nil, nil, nil, nil)
 
 		user := &models.User{
 			BaseModel: providers.BaseModel{
@@ -37,7 +41,7 @@ func TestGetUserPictureURL(t *testing.T) {
 	})
 
 	t.Run("FileUploadService 為 nil", func(t *testing.T) {
-		service := NewUserService(nil, nil, nil, nil, nil)
+		service := NewUserService(nil, nil, nil, nil, nil, nil, nil)
 
 		user := &models.User{
 			BaseModel: providers.BaseModel{
@@ -54,7 +58,7 @@ func TestGetUserPictureURL(t *testing.T) {
 // TestGetUserBannerURL 測試獲取用戶橫幅 URL
 func TestGetUserBannerURL(t *testing.T) {
 	t.Run("用戶沒有橫幅", func(t *testing.T) {
-		service := NewUserService(nil, nil, nil, nil, nil)
+		service := NewUserService(nil, nil, nil, nil, nil, nil, nil)
 
 		user := &models.User{
 			BaseModel: providers.BaseModel{
@@ -68,7 +72,7 @@ func TestGetUserBannerURL(t *testing.T) {
 	})
 
 	t.Run("FileUploadService 為 nil", func(t *testing.T) {
-		service := NewUserService(nil, nil, nil, nil, nil)
+		service := NewUserService(nil, nil, nil, nil, nil, nil, nil)
 
 		user := &models.User{
 			BaseModel: providers.BaseModel{
@@ -91,7 +95,7 @@ func TestGetUserPictureURL_WithFileService(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
 		fileService.On("GetFileURLByID", pictureID.Hex()).Return(expectedURL, (*models.MessageOptions)(nil))
 
-		service := NewUserService(nil, nil, nil, fileService, nil)
+		service := NewUserService(nil, nil, nil, fileService, nil, nil, nil)
 
 		user := &models.User{
 			BaseModel: providers.BaseModel{
@@ -108,7 +112,7 @@ func TestGetUserPictureURL_WithFileService(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
 		fileService.On("GetFileURLByID", mock.Anything).Return("", &models.MessageOptions{Code: models.ErrInternalServer})
 
-		service := NewUserService(nil, nil, nil, fileService, nil)
+		service := NewUserService(nil, nil, nil, fileService, nil, nil, nil)
 
 		user := &models.User{
 			BaseModel: providers.BaseModel{
@@ -131,7 +135,7 @@ func TestGetUserBannerURL_WithFileService(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
 		fileService.On("GetFileURLByID", bannerID.Hex()).Return(expectedURL, (*models.MessageOptions)(nil))
 
-		service := NewUserService(nil, nil, nil, fileService, nil)
+		service := NewUserService(nil, nil, nil, fileService, nil, nil, nil)
 
 		user := &models.User{
 			BaseModel: providers.BaseModel{
@@ -148,7 +152,7 @@ func TestGetUserBannerURL_WithFileService(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
 		fileService.On("GetFileURLByID", mock.Anything).Return("", &models.MessageOptions{Code: models.ErrInternalServer})
 
-		service := NewUserService(nil, nil, nil, fileService, nil)
+		service := NewUserService(nil, nil, nil, fileService, nil, nil, nil)
 
 		user := &models.User{
 			BaseModel: providers.BaseModel{
@@ -165,13 +169,13 @@ func TestGetUserBannerURL_WithFileService(t *testing.T) {
 // TestUserService_ServiceInitialization 測試服務初始化
 func TestUserService_ServiceInitialization(t *testing.T) {
 	t.Run("使用 nil 依賴初始化", func(t *testing.T) {
-		service := NewUserService(nil, nil, nil, nil, nil)
+		service := NewUserService(nil, nil, nil, nil, nil, nil, nil)
 		assert.NotNil(t, service)
 	})
 
 	t.Run("使用完整依賴初始化", func(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
-		service := NewUserService(nil, nil, nil, fileService, nil)
+		service := NewUserService(nil, nil, nil, fileService, nil, nil, nil)
 		assert.NotNil(t, service)
 	})
 }
@@ -179,7 +183,7 @@ func TestUserService_ServiceInitialization(t *testing.T) {
 // TestUserService_NilSafety 測試 nil 安全性
 func TestUserService_NilSafety(t *testing.T) {
 	t.Run("getUserPictureURL 處理 nil fileService", func(t *testing.T) {
-		service := NewUserService(nil, nil, nil, nil, nil)
+		service := NewUserService(nil, nil, nil, nil, nil, nil, nil)
 
 		user := &models.User{
 			PictureID: primitive.NewObjectID(),
@@ -193,7 +197,7 @@ func TestUserService_NilSafety(t *testing.T) {
 	})
 
 	t.Run("getUserBannerURL 處理 nil fileService", func(t *testing.T) {
-		service := NewUserService(nil, nil, nil, nil, nil)
+		service := NewUserService(nil, nil, nil, nil, nil, nil, nil)
 
 		user := &models.User{
 			BannerID: primitive.NewObjectID(),
@@ -208,7 +212,7 @@ func TestUserService_NilSafety(t *testing.T) {
 
 	t.Run("getUserPictureURL 處理零值 ObjectID", func(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
-		service := NewUserService(nil, nil, nil, fileService, nil)
+		service := NewUserService(nil, nil, nil, fileService, nil, nil, nil)
 
 		user := &models.User{
 			PictureID: primitive.NilObjectID,
@@ -220,7 +224,7 @@ func TestUserService_NilSafety(t *testing.T) {
 
 	t.Run("getUserBannerURL 處理零值 ObjectID", func(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
-		service := NewUserService(nil, nil, nil, fileService, nil)
+		service := NewUserService(nil, nil, nil, fileService, nil, nil, nil)
 
 		user := &models.User{
 			BannerID: primitive.NilObjectID,
@@ -243,6 +247,12 @@ type testUserRepository struct {
 	deleteUserFunc          func(userID string) error
 	updateOnlineStatusFunc  func(userID string, isOnline bool) error
 	updateLastActiveFunc    func(userID string, timestamp int64) error
+	getUserCredentialsFunc  func(userID string) (*models.User, error)
+	consumeTOTPCounterFunc  func(userID string, counter int64) (bool, error)
+	consumeRecoveryCodeFunc func(userID string, codeHash string) (bool, error)
+	rotateRefreshTokenFunc  func(tokenID string, replacedByID string) (bool, error)
+	getUserByEmailFunc      func(email string) (*models.User, error)
+	consumeUserTokenFunc    func(userID string, field string, tokenID string, updates map[string]any) (bool, error)
 }
 
 func (m *testUserRepository) GetUserById(userID string) (*models.User, error) {
@@ -309,6 +319,48 @@ func (m *testUserRepository) DeleteUser(userID string) error {
 	return nil
 }
 
+func (m *testUserRepository) GetUserCredentials(userID string) (*models.User, error) {
+	if m.getUserCredentialsFunc != nil {
+		return m.getUserCredentialsFunc(userID)
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (m *testUserRepository) ConsumeTOTPCounter(userID string, counter int64) (bool, error) {
+	if m.consumeTOTPCounterFunc != nil {
+		return m.consumeTOTPCounterFunc(userID, counter)
+	}
+	return true, nil
+}
+
+func (m *testUserRepository) ConsumeRecoveryCode(userID string, codeHash string) (bool, error) {
+	if m.consumeRecoveryCodeFunc != nil {
+		return m.consumeRecoveryCodeFunc(userID, codeHash)
+	}
+	return false, nil
+}
+
+func (m *testUserRepository) MarkRefreshTokenRotated(tokenID string, replacedByID string) (bool, error) {
+	if m.rotateRefreshTokenFunc != nil {
+		return m.rotateRefreshTokenFunc(tokenID, replacedByID)
+	}
+	return true, nil
+}
+
+func (m *testUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	if m.getUserByEmailFunc != nil {
+		return m.getUserByEmailFunc(email)
+	}
+	return nil, providers.ErrDocumentNotFound
+}
+
+func (m *testUserRepository) ConsumeUserToken(userID string, field string, tokenID string, updates map[string]any) (bool, error) {
+	if m.consumeUserTokenFunc != nil {
+		return m.consumeUserTokenFunc(userID, field, tokenID, updates)
+	}
+	return false, nil
+}
+
 // TestRegisterUser 測試用戶註冊
 func TestRegisterUser(t *testing.T) {
 	t.Run("成功註冊新用戶", func(t *testing.T) {
@@ -324,7 +376,7 @@ func TestRegisterUser(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		user := models.User{
 			Username: "testuser",
@@ -344,7 +396,7 @@ func TestRegisterUser(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		user := models.User{
 			Username: "existinguser",
@@ -368,7 +420,7 @@ func TestRegisterUser(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		user := models.User{
 			Username: "testuser",
@@ -389,7 +441,7 @@ func TestRegisterUser(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		user := models.User{
 			Username: "testuser",
@@ -419,7 +471,7 @@ func TestGetUserResponseById(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		response, err := service.GetUserResponseById(userID.Hex())
 
@@ -436,7 +488,7 @@ func TestGetUserResponseById(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		response, err := service.GetUserResponseById(primitive.NewObjectID().Hex())
 
@@ -460,7 +512,7 @@ func TestSetUserOnline(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		err := service.SetUserOnline(userID)
 
@@ -475,7 +527,7 @@ func TestSetUserOnline(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		err := service.SetUserOnline(primitive.NewObjectID().Hex())
 
@@ -498,7 +550,7 @@ func TestSetUserOffline(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		err := service.SetUserOffline(userID)
 
@@ -522,7 +574,7 @@ func TestUpdateUserActivity(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		err := service.UpdateUserActivity(userID)
 
@@ -554,7 +606,7 @@ func TestGetUserProfile(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
 		fileService.On("GetFileURLByID", pictureID.Hex()).Return("https://example.com/avatar.jpg", (*models.MessageOptions)(nil))
 
-		service := NewUserService(nil, nil, mockRepo, fileService, nil)
+		service := NewUserService(nil, nil, mockRepo, fileService, nil, nil, nil)
 
 		profile, err := service.GetUserProfile(userID.Hex())
 
@@ -572,7 +624,7 @@ func TestGetUserProfile(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		profile, err := service.GetUserProfile(primitive.NewObjectID().Hex())
 
@@ -597,7 +649,7 @@ func TestUpdateUserProfile(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		updates := map[string]any{
 			"nickname": "New Nickname",
@@ -622,7 +674,7 @@ func TestUpdateUserProfile(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		updates := map[string]any{
 			"nickname": "New Nickname",
@@ -643,7 +695,7 @@ func TestUpdateUserProfile(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		updates := map[string]any{
 			"invalid_field": "value",
@@ -655,6 +707,36 @@ func TestUpdateUserProfile(t *testing.T) {
 	})
 }
 
+// TestUploadUserImage 測試上傳用戶頭像或橫幅
+func TestUploadUserImage(t *testing.T) {
+	t.Run("圖片處理中時返回檔案ID與處理狀態", func(t *testing.T) {
+		userID := primitive.NewObjectID()
+		fileID := primitive.NewObjectID()
+
+		mockRepo := &testUserRepository{
+			updateUserFunc: func(id string, updates map[string]any) error {
+				assert.Equal(t, fileID, updates["picture_id"])
+				return nil
+			},
+		}
+
+		fileService := new(mocks.FileUploadService)
+		fileService.On("UploadFileWithConfig", mock.Anything, mock.Anything, userID.Hex(), mock.AnythingOfType("*models.FileUploadConfig")).
+			Return(&models.FileResult{ID: fileID, Status: "processing"}, (*models.MessageOptions)(nil)).Once()
+
+		service := NewUserService(nil, nil, mockRepo, fileService, nil, nil, nil)
+
+		result, err := service.UploadUserImage(userID.Hex(), nil, &multipart.FileHeader{Filename: "me.png"}, "avatar")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "avatar", result.Type)
+		assert.Equal(t, fileID.Hex(), result.FileID)
+		assert.Equal(t, "processing", result.Status)
+		assert.Empty(t, result.ImageURL)
+		fileService.AssertExpectations(t)
+	})
+}
+
 // TestDeleteUserAvatar 測試刪除用戶頭像
 func TestDeleteUserAvatar(t *testing.T) {
 	t.Run("成功刪除頭像", func(t *testing.T) {
@@ -677,7 +759,7 @@ func TestDeleteUserAvatar(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
 		fileService.On("DeleteFileByID", pictureID.Hex(), userID.Hex()).Return((*models.MessageOptions)(nil))
 
-		service := NewUserService(nil, nil, mockRepo, fileService, nil)
+		service := NewUserService(nil, nil, mockRepo, fileService, nil, nil, nil)
 
 		err := service.DeleteUserAvatar(userID.Hex())
 
@@ -699,7 +781,7 @@ func TestDeleteUserAvatar(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		err := service.DeleteUserAvatar(userID.Hex())
 
@@ -729,7 +811,7 @@ func TestDeleteUserBanner(t *testing.T) {
 		fileService := new(mocks.FileUploadService)
 		fileService.On("DeleteFileByID", bannerID.Hex(), userID.Hex()).Return((*models.MessageOptions)(nil))
 
-		service := NewUserService(nil, nil, mockRepo, fileService, nil)
+		service := NewUserService(nil, nil, mockRepo, fileService, nil, nil, nil)
 
 		err := service.DeleteUserBanner(userID.Hex())
 
@@ -756,12 +838,21 @@ func TestUpdateUserPassword(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		mockODM := new(mocks.ODM)
+		mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.RefreshToken"), mock.MatchedBy(func(filter bson.M) bool {
+			return filter["user_id"].(primitive.ObjectID).Hex() == userID
+		}), mock.Anything).Return(nil).Once()
+		tokenDenylist := providers.NewCacheTokenDenylist(nil, time.Hour)
+		issuedAt := time.Now().Add(-time.Minute)
+
+		service := NewUserService(nil, mockODM, mockRepo, nil, nil, tokenDenylist, nil)
 
 		err := service.UpdateUserPassword(userID, newPassword)
 
 		assert.NoError(t, err)
 		assert.True(t, called)
+		assert.True(t, tokenDenylist.IsRevoked("", "", userID, issuedAt), "變更密碼後既有的 access token 應失效")
+		mockODM.AssertExpectations(t)
 	})
 }
 
@@ -771,46 +862,23 @@ func TestGetTwoFactorStatus(t *testing.T) {
 		userID := primitive.NewObjectID()
 
 		mockRepo := &testUserRepository{
-			getUserByIdFunc: func(id string) (*models.User, error) {
+			getUserCredentialsFunc: func(id string) (*models.User, error) {
 				return &models.User{
 					BaseModel:        providers.BaseModel{ID: userID},
 					TwoFactorEnabled: true,
+					RecoveryCodes:    []string{"hash1", "hash2"},
 				}, nil
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		status, err := service.GetTwoFactorStatus(userID.Hex())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, status)
 		assert.True(t, status.Enabled)
-	})
-}
-
-// TestUpdateTwoFactorStatus 測試更新兩步驟驗證狀態
-func TestUpdateTwoFactorStatus(t *testing.T) {
-	t.Run("成功啟用兩步驟驗證", func(t *testing.T) {
-		userID := primitive.NewObjectID().Hex()
-		called := false
-
-		mockRepo := &testUserRepository{
-			updateUserFunc: func(id string, updates map[string]any) error {
-				called = true
-				assert.Equal(t, userID, id)
-				assert.Contains(t, updates, "two_factor_enabled")
-				assert.Equal(t, true, updates["two_factor_enabled"])
-				return nil
-			},
-		}
-
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
-
-		err := service.UpdateTwoFactorStatus(userID, true)
-
-		assert.NoError(t, err)
-		assert.True(t, called)
+		assert.Equal(t, 2, status.RecoveryCodesRemaining)
 	})
 }
 
@@ -830,12 +898,19 @@ func TestDeactivateAccount(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		mockODM := new(mocks.ODM)
+		mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.RefreshToken"), mock.Anything, mock.Anything).Return(nil).Once()
+		tokenDenylist := providers.NewCacheTokenDenylist(nil, time.Hour)
+		issuedAt := time.Now().Add(-time.Minute)
+
+		service := NewUserService(nil, mockODM, mockRepo, nil, nil, tokenDenylist, nil)
 
 		err := service.DeactivateAccount(userID)
 
 		assert.NoError(t, err)
 		assert.True(t, called)
+		assert.True(t, tokenDenylist.IsRevoked("", "", userID, issuedAt), "停用帳號後既有的 access token 應失效")
+		mockODM.AssertExpectations(t)
 	})
 }
 
@@ -853,7 +928,7 @@ func TestDeleteAccount(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		err := service.DeleteAccount(userID)
 
@@ -868,10 +943,204 @@ func TestDeleteAccount(t *testing.T) {
 			},
 		}
 
-		service := NewUserService(nil, nil, mockRepo, nil, nil)
+		service := NewUserService(nil, nil, mockRepo, nil, nil, nil, nil)
 
 		err := service.DeleteAccount(primitive.NewObjectID().Hex())
 
 		assert.Error(t, err)
 	})
 }
+
+// newTestRefreshToken 建立測試用的 refresh token 記錄
+func newTestRefreshToken(t *testing.T, userObjectID primitive.ObjectID, familyID primitive.ObjectID) *models.RefreshToken {
+	tokenResponse, err := utils.GenRefreshToken(userObjectID.Hex())
+	assert.NoError(t, err)
+	return &models.RefreshToken{
+		BaseModel:        providers.BaseModel{ID: primitive.NewObjectID()},
+		UserID:           userObjectID,
+		Token:            tokenResponse.Token,
+		ExpiresAt:        tokenResponse.ExpiresAt,
+		UserAgent:        "Chrome",
+		IP:               "203.0.113.1",
+		FamilyID:         familyID,
+		SessionStartedAt: 1000,
+	}
+}
+
+// returnRefreshToken 讓 ODM mock 的查詢結果填入指定的 refresh token
+func returnRefreshToken(doc *models.RefreshToken) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		*args.Get(2).(*models.RefreshToken) = *doc
+	}
+}
+
+// assertAccessTokenSession 驗證 access token 綁定的登入工作階段
+func assertAccessTokenSession(t *testing.T, accessToken string, sessionID primitive.ObjectID) {
+	claims, err := utils.ParseAccessToken(accessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, sessionID.Hex(), claims.SessionID)
+}
+
+// TestRefreshToken 測試 refresh token 輪替與重複使用偵測
+func TestRefreshToken(t *testing.T) {
+	userObjectID := primitive.NewObjectID()
+
+	t.Run("輪替為同一 family 的新 token", func(t *testing.T) {
+		setupTwoFactorConfig(t)
+		familyID := primitive.NewObjectID()
+		current := newTestRefreshToken(t, userObjectID, familyID)
+
+		var successor *models.RefreshToken
+		mockODM := new(mocks.ODM)
+		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(returnRefreshToken(current)).Return(nil).Once()
+		mockODM.On("Create", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(func(args mock.Arguments) {
+			successor = args.Get(1).(*models.RefreshToken)
+		}).Return(nil).Once()
+
+		rotated := false
+		mockRepo := &testUserRepository{
+			rotateRefreshTokenFunc: func(tokenID string, replacedByID string) (bool, error) {
+				rotated = true
+				assert.Equal(t, current.ID.Hex(), tokenID)
+				assert.Equal(t, successor.ID.Hex(), replacedByID)
+				return true, nil
+			},
+		}
+		service := NewUserService(nil, mockODM, mockRepo, nil, nil, nil, nil)
+
+		response, msgOpt := service.RefreshToken(current.Token, models.DeviceInfo{IP: "198.51.100.7"})
+
+		assert.Nil(t, msgOpt)
+		assert.True(t, rotated)
+		assert.Equal(t, familyID, successor.FamilyID)
+		assert.Equal(t, int64(1000), successor.SessionStartedAt)
+		assert.Equal(t, "Chrome", successor.UserAgent)
+		assert.Equal(t, "198.51.100.7", successor.IP)
+		assert.NotEqual(t, current.Token, successor.Token)
+		assert.Equal(t, successor.Token, response.RefreshToken)
+		assertAccessTokenSession(t, response.AccessToken, familyID)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("舊記錄沒有 family 時以自身 ID 作為工作階段", func(t *testing.T) {
+		setupTwoFactorConfig(t)
+		legacy := newTestRefreshToken(t, userObjectID, primitive.NilObjectID)
+
+		var successor *models.RefreshToken
+		mockODM := new(mocks.ODM)
+		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(returnRefreshToken(legacy)).Return(nil).Once()
+		mockODM.On("Create", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(func(args mock.Arguments) {
+			successor = args.Get(1).(*models.RefreshToken)
+		}).Return(nil).Once()
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		response, msgOpt := service.RefreshToken(legacy.Token, models.DeviceInfo{})
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, legacy.ID, successor.FamilyID)
+		assertAccessTokenSession(t, response.AccessToken, legacy.ID)
+	})
+
+	t.Run("寬限時間內重複使用返回相同的新 token", func(t *testing.T) {
+		setupTwoFactorConfig(t)
+		familyID := primitive.NewObjectID()
+		successor := newTestRefreshToken(t, userObjectID, familyID)
+		previous := newTestRefreshToken(t, userObjectID, familyID)
+		previous.Revoked = true
+		previous.ReplacedByID = successor.ID
+		previous.RotatedAt = time.Now().Add(-5 * time.Second).Unix()
+
+		mockODM := new(mocks.ODM)
+		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(returnRefreshToken(previous)).Return(nil).Once()
+		mockODM.On("FindByID", mock.Anything, successor.ID.Hex(), mock.AnythingOfType("*models.RefreshToken")).Run(returnRefreshToken(successor)).Return(nil).Once()
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		response, msgOpt := service.RefreshToken(previous.Token, models.DeviceInfo{})
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, successor.Token, response.RefreshToken)
+		assertAccessTokenSession(t, response.AccessToken, familyID)
+		mockODM.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		mockODM.AssertNotCalled(t, "UpdateMany", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("超過寬限時間重複使用撤銷整個 family", func(t *testing.T) {
+		setupTwoFactorConfig(t)
+		familyID := primitive.NewObjectID()
+		previous := newTestRefreshToken(t, userObjectID, familyID)
+		previous.Revoked = true
+		previous.ReplacedByID = primitive.NewObjectID()
+		previous.RotatedAt = time.Now().Add(-2 * RefreshTokenReuseGracePeriod).Unix()
+
+		matchFamily := mock.MatchedBy(func(filter bson.M) bool {
+			sessionTokens := filter["$or"].(bson.A)
+			return filter["user_id"] == userObjectID && sessionTokens[0].(bson.M)["family_id"] == familyID
+		})
+		mockODM := new(mocks.ODM)
+		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(returnRefreshToken(previous)).Return(nil).Once()
+		mockODM.On("Exists", mock.Anything, matchFamily, mock.AnythingOfType("*models.RefreshToken")).Return(true, nil).Once()
+		mockODM.On("UpdateMany", mock.Anything, mock.AnythingOfType("*models.RefreshToken"), matchFamily, mock.Anything).Return(nil).Once()
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		response, msgOpt := service.RefreshToken(previous.Token, models.DeviceInfo{})
+
+		assert.Nil(t, response)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+		mockODM.AssertExpectations(t)
+		mockODM.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("並發刷新未取得輪替時使用已輪替的新 token", func(t *testing.T) {
+		setupTwoFactorConfig(t)
+		familyID := primitive.NewObjectID()
+		current := newTestRefreshToken(t, userObjectID, familyID)
+		winner := newTestRefreshToken(t, userObjectID, familyID)
+		rotatedByWinner := *current
+		rotatedByWinner.Revoked = true
+		rotatedByWinner.ReplacedByID = winner.ID
+		rotatedByWinner.RotatedAt = time.Now().Unix()
+
+		var loser *models.RefreshToken
+		mockODM := new(mocks.ODM)
+		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(returnRefreshToken(current)).Return(nil).Once()
+		mockODM.On("Create", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(func(args mock.Arguments) {
+			loser = args.Get(1).(*models.RefreshToken)
+		}).Return(nil).Once()
+		mockODM.On("Delete", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(func(args mock.Arguments) {
+			assert.Equal(t, loser.ID, args.Get(1).(*models.RefreshToken).ID, "應移除未使用的新 token")
+		}).Return(nil).Once()
+		mockODM.On("FindByID", mock.Anything, current.ID.Hex(), mock.AnythingOfType("*models.RefreshToken")).Run(returnRefreshToken(&rotatedByWinner)).Return(nil).Once()
+		mockODM.On("FindByID", mock.Anything, winner.ID.Hex(), mock.AnythingOfType("*models.RefreshToken")).Run(returnRefreshToken(winner)).Return(nil).Once()
+		mockRepo := &testUserRepository{
+			rotateRefreshTokenFunc: func(tokenID string, replacedByID string) (bool, error) {
+				return false, nil
+			},
+		}
+		service := NewUserService(nil, mockODM, mockRepo, nil, nil, nil, nil)
+
+		response, msgOpt := service.RefreshToken(current.Token, models.DeviceInfo{})
+
+		assert.Nil(t, msgOpt)
+		assert.Equal(t, winner.Token, response.RefreshToken)
+		mockODM.AssertExpectations(t)
+	})
+
+	t.Run("已登出的 token", func(t *testing.T) {
+		setupTwoFactorConfig(t)
+		loggedOut := newTestRefreshToken(t, userObjectID, primitive.NewObjectID())
+		loggedOut.Revoked = true
+
+		mockODM := new(mocks.ODM)
+		mockODM.On("FindOne", mock.Anything, mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Run(returnRefreshToken(loggedOut)).Return(nil).Once()
+		mockODM.On("Delete", mock.Anything, mock.AnythingOfType("*models.RefreshToken")).Return(nil).Once()
+		service := NewUserService(nil, mockODM, &testUserRepository{}, nil, nil, nil, nil)
+
+		response, msgOpt := service.RefreshToken(loggedOut.Token, models.DeviceInfo{})
+
+		assert.Nil(t, response)
+		assert.NotNil(t, msgOpt)
+		assert.Equal(t, models.ErrInvalidToken, msgOpt.Code)
+		mockODM.AssertExpectations(t)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -15,28 +16,30 @@ import (
 
 // webSocketHandler 處理 WebSocket 相關操作
 type webSocketHandler struct {
-	odm            providers.ODM
-	clientManager  ClientManager
-	roomManager    RoomManager
-	messageHandler MessageHandler
-	userService    UserService
-	cache          providers.CacheProvider
+	odm               providers.ODM
+	clientManager     ClientManager
+	roomManager       RoomManager
+	messageHandler    MessageHandler
+	userService       UserService
+	cache             providers.CacheProvider
+	permissionService PermissionService
 }
 
 // NewWebSocketHandler 創建新的 WebSocket 處理器
-func NewWebSocketHandler(odm providers.ODM, clientManager ClientManager, roomManager RoomManager, messageHandler MessageHandler, userService UserService, cache providers.CacheProvider) *webSocketHandler {
+func NewWebSocketHandler(odm providers.ODM, clientManager ClientManager, roomManager RoomManager, messageHandler MessageHandler, userService UserService, cache providers.CacheProvider, permissionService PermissionService) *webSocketHandler {
 	return &webSocketHandler{
-		odm:            odm,
-		clientManager:  clientManager,
-		roomManager:    roomManager,
-		messageHandler: messageHandler,
-		userService:    userService,
-		cache:          cache,
+		odm:               odm,
+		clientManager:     clientManager,
+		roomManager:       roomManager,
+		messageHandler:    messageHandler,
+		userService:       userService,
+		cache:             cache,
+		permissionService: permissionService,
 	}
 }
 
 // HandleWebSocket 處理 WebSocket 連線
-func (wsh *webSocketHandler) HandleWebSocket(ws *websocket.Conn, userID string) {
+func (wsh *webSocketHandler) HandleWebSocket(ws *websocket.Conn, userID string, sessionID string) {
 	// 設置連接參數
 	ws.SetReadLimit(MaxMessageSize)
 	if err := ws.SetReadDeadline(time.Now().Add(PongWait)); err != nil {
@@ -45,6 +48,9 @@ func (wsh *webSocketHandler) HandleWebSocket(ws *websocket.Conn, userID string)
 
 	// 創建客戶端
 	client := wsh.clientManager.NewClient(userID, ws)
+	if client != nil {
+		client.SessionID = sessionID
+	}
 
 	// 設置 pong 處理器
 	ws.SetPongHandler(func(string) error {
@@ -73,6 +79,12 @@ func (wsh *webSocketHandler) HandleWebSocket(ws *websocket.Conn, userID string)
 		}
 	}
 
+	// 4. 加入用戶個人房間，接收同步到所有裝置的事件（如已讀狀態）
+	if client != nil {
+		wsh.roomManager.InitRoom(userRoomType, userID)
+		wsh.roomManager.JoinRoom(client, userRoomType, userID)
+	}
+
 	// 啟動讀寫協程
 	go wsh.clientWritePump(client)
 	go wsh.clientReadPump(client)
@@ -83,7 +95,10 @@ func (wsh *webSocketHandler) HandleWebSocket(ws *websocket.Conn, userID string)
 	}
 
 	// --- 連線關閉時的清理工作 ---
-	// 1. 從記憶體中註銷客戶端
+	// 1. 從記憶體中註銷客戶端，並離開用戶個人房間
+	if client != nil {
+		wsh.roomManager.LeaveRoom(client, userRoomType, userID)
+	}
 	wsh.clientManager.Unregister(client)
 
 	// 2. 更新資料庫狀態
@@ -332,6 +347,20 @@ func (wsh *webSocketHandler) handleClientMessage(client *Client, msg WsMessage[j
 		wsh.handleLeaveRoom(client, msg.Data)
 	case "send_message":
 		wsh.handleSendMessage(client, msg.Data)
+	case "edit_message":
+		wsh.handleEditMessage(client, msg.Data)
+	case "delete_message":
+		wsh.handleDeleteMessage(client, msg.Data)
+	case "add_reaction":
+		wsh.handleReaction(client, msg.Data, true)
+	case "remove_reaction":
+		wsh.handleReaction(client, msg.Data, false)
+	case "mark_read":
+		wsh.handleMarkRead(client, msg.Data)
+	case "typing_start":
+		wsh.handleTyping(client, msg.Data, true)
+	case "typing_stop":
+		wsh.handleTyping(client, msg.Data, false)
 	case "ping":
 		// 處理客戶端ping
 		wsh.handlePing(client)
@@ -417,9 +446,12 @@ func (wsh *webSocketHandler) handleSendMessage(client *Client, data json.RawMess
 
 	// 解析請求數據
 	var requestData struct {
-		RoomID   string          `json:"room_id"`
-		RoomType models.RoomType `json:"room_type"`
-		Content  string          `json:"content"`
+		RoomID           string          `json:"room_id"`
+		RoomType         models.RoomType `json:"room_type"`
+		Content          string          `json:"content"`
+		ReplyToMessageID string          `json:"reply_to_message_id"`
+		ThreadID         string          `json:"thread_id"`
+		AttachmentIDs    []string        `json:"attachment_ids"` // 發送者上傳的檔案ID
 	}
 	err := json.Unmarshal(data, &requestData)
 	if err != nil {
@@ -428,6 +460,45 @@ func (wsh *webSocketHandler) handleSendMessage(client *Client, data json.RawMess
 		return
 	}
 
+	// 只允許發送到頻道或私聊房間
+	if requestData.RoomType != models.RoomTypeChannel && requestData.RoomType != models.RoomTypeDM {
+		client.SendError(action, "無效的房間類型")
+		return
+	}
+
+	// 建立消息對象
+	message := &MessageResponse{
+		RoomID:           requestData.RoomID,
+		RoomType:         requestData.RoomType,
+		SenderID:         client.UserID,
+		Content:          requestData.Content,
+		Timestamp:        time.Now().UnixMilli(),
+		ReplyToMessageID: requestData.ReplyToMessageID,
+		ThreadID:         requestData.ThreadID,
+	}
+
+	// 頻道發言需具備發送訊息權限，使用 @everyone / @here 另需對應權限
+	if msgOpt := wsh.checkChannelSendPermission(client, requestData.RoomType, requestData.RoomID, requestData.Content); msgOpt != nil {
+		client.SendError(action, msgOpt.Message)
+		return
+	}
+
+	// 驗證引用與討論串
+	if message.ReplyToMessageID != "" || message.ThreadID != "" {
+		if msgOpt := wsh.messageHandler.ResolveMessageReference(client.Context, message); msgOpt != nil {
+			client.SendError(action, msgOpt.Message)
+			return
+		}
+	}
+
+	// 驗證附件並填入檔案資訊
+	if len(requestData.AttachmentIDs) > 0 {
+		if msgOpt := wsh.messageHandler.ResolveAttachments(client.Context, message, requestData.AttachmentIDs); msgOpt != nil {
+			client.SendError(action, msgOpt.Message)
+			return
+		}
+	}
+
 	// 確保房間存在
 	wsh.roomManager.InitRoom(requestData.RoomType, requestData.RoomID)
 
@@ -436,19 +507,171 @@ func (wsh *webSocketHandler) handleSendMessage(client *Client, data json.RawMess
 		wsh.handleDMRoomCreation(requestData.RoomID, client.UserID)
 	}
 
-	// 建立消息對象
-	message := &MessageResponse{
-		RoomID:    requestData.RoomID,
-		RoomType:  requestData.RoomType,
-		SenderID:  client.UserID,
-		Content:   requestData.Content,
-		Timestamp: time.Now().UnixMilli(),
-	}
-
 	// 使用MessageHandler處理消息
 	wsh.messageHandler.HandleMessage(message)
 }
 
+// checkChannelSendPermission 檢查頻道發言權限（唯讀頻道等），訊息含 @everyone / @here 時另需提及所有人權限
+func (wsh *webSocketHandler) checkChannelSendPermission(client *Client, roomType models.RoomType, roomID string, content string) *models.MessageOptions {
+	if roomType != models.RoomTypeChannel {
+		return nil
+	}
+
+	permissions, msgOpt := wsh.permissionService.GetChannelPermissions(client.Context, roomID, client.UserID)
+	if msgOpt != nil {
+		return msgOpt
+	}
+	if !permissions.Has(models.PermissionSendMessages) {
+		return &models.MessageOptions{
+			Code:    models.ErrNoServerPermission,
+			Message: "沒有權限在此頻道發送訊息",
+		}
+	}
+	if mentionsEveryone(content) && !permissions.Has(models.PermissionMentionEveryone) {
+		return &models.MessageOptions{
+			Code:    models.ErrNoServerPermission,
+			Message: "沒有權限使用 @everyone 或 @here",
+		}
+	}
+	return nil
+}
+
+// mentionsEveryone 判斷訊息內容是否提及所有人
+func mentionsEveryone(content string) bool {
+	return strings.Contains(content, "@everyone") || strings.Contains(content, "@here")
+}
+
+// handleEditMessage 處理編輯訊息請求
+func (wsh *webSocketHandler) handleEditMessage(client *Client, data json.RawMessage) {
+	// 用於錯誤回應的原始動作
+	action := "edit_message"
+
+	// 解析請求數據
+	var requestData struct {
+		RoomID    string          `json:"room_id"`
+		RoomType  models.RoomType `json:"room_type"`
+		MessageID string          `json:"message_id"`
+		Content   string          `json:"content"`
+	}
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		slog.Error("無法解析編輯訊息數據", "error", err)
+		client.SendError(action, "無法解析編輯訊息數據")
+		return
+	}
+
+	// 成功後由 message_edited 廣播通知房間內所有客戶端（包含自己）
+	_, msgOpt := wsh.messageHandler.EditMessage(client.Context, client.UserID, requestData.RoomType, requestData.RoomID, requestData.MessageID, requestData.Content)
+	if msgOpt != nil {
+		client.SendError(action, msgOpt.Message)
+	}
+}
+
+// handleDeleteMessage 處理刪除訊息請求
+func (wsh *webSocketHandler) handleDeleteMessage(client *Client, data json.RawMessage) {
+	// 用於錯誤回應的原始動作
+	action := "delete_message"
+
+	// 解析請求數據
+	var requestData struct {
+		RoomID    string          `json:"room_id"`
+		RoomType  models.RoomType `json:"room_type"`
+		MessageID string          `json:"message_id"`
+	}
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		slog.Error("無法解析刪除訊息數據", "error", err)
+		client.SendError(action, "無法解析刪除訊息數據")
+		return
+	}
+
+	// 成功後由 message_deleted 廣播通知房間內所有客戶端（包含自己）
+	_, msgOpt := wsh.messageHandler.DeleteMessage(client.Context, client.UserID, requestData.RoomType, requestData.RoomID, requestData.MessageID)
+	if msgOpt != nil {
+		client.SendError(action, msgOpt.Message)
+	}
+}
+
+// handleReaction 處理表情回應請求（add_reaction / remove_reaction）
+func (wsh *webSocketHandler) handleReaction(client *Client, data json.RawMessage, add bool) {
+	// 用於錯誤回應的原始動作
+	action := "remove_reaction"
+	if add {
+		action = "add_reaction"
+	}
+
+	// 解析請求數據
+	var requestData struct {
+		RoomID    string          `json:"room_id"`
+		RoomType  models.RoomType `json:"room_type"`
+		MessageID string          `json:"message_id"`
+		Emoji     string          `json:"emoji"`
+	}
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		slog.Error("無法解析表情回應數據", "error", err)
+		client.SendError(action, "無法解析表情回應數據")
+		return
+	}
+
+	// 成功後由 reaction_added / reaction_removed 廣播通知房間內所有客戶端（包含自己）
+	_, msgOpt := wsh.messageHandler.UpdateReaction(client.Context, client.UserID, requestData.RoomType, requestData.RoomID, requestData.MessageID, requestData.Emoji, add)
+	if msgOpt != nil {
+		client.SendError(action, msgOpt.Message)
+	}
+}
+
+// handleMarkRead 處理標記已讀請求
+func (wsh *webSocketHandler) handleMarkRead(client *Client, data json.RawMessage) {
+	// 用於錯誤回應的原始動作
+	action := "mark_read"
+
+	// 解析請求數據
+	var requestData struct {
+		RoomID    string          `json:"room_id"`
+		RoomType  models.RoomType `json:"room_type"`
+		MessageID string          `json:"message_id"`
+	}
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		slog.Error("無法解析標記已讀數據", "error", err)
+		client.SendError(action, "無法解析標記已讀數據")
+		return
+	}
+
+	// 成功後由 read_state_updated 推送到該用戶的所有裝置（包含自己）
+	_, msgOpt := wsh.messageHandler.MarkRead(client.Context, client.UserID, requestData.RoomType, requestData.RoomID, requestData.MessageID)
+	if msgOpt != nil {
+		client.SendError(action, msgOpt.Message)
+	}
+}
+
+// handleTyping 處理輸入中狀態請求（typing_start / typing_stop）
+func (wsh *webSocketHandler) handleTyping(client *Client, data json.RawMessage, isTyping bool) {
+	// 用於錯誤回應的原始動作
+	action := "typing_stop"
+	if isTyping {
+		action = "typing_start"
+	}
+
+	// 解析請求數據
+	var requestData struct {
+		RoomID   string          `json:"room_id"`
+		RoomType models.RoomType `json:"room_type"`
+	}
+	err := json.Unmarshal(data, &requestData)
+	if err != nil {
+		slog.Error("無法解析輸入中狀態數據", "error", err)
+		client.SendError(action, "無法解析輸入中狀態數據")
+		return
+	}
+
+	// 輸入中狀態只廣播給房間內其他成員，不寫入資料庫
+	if msgOpt := wsh.messageHandler.HandleTyping(client.Context, client.UserID, requestData.RoomType, requestData.RoomID, isTyping); msgOpt != nil {
+		client.SendError(action, msgOpt.Message)
+	}
+}
+
 // handlePing 處理ping請求
 func (wsh *webSocketHandler) handlePing(client *Client) {
 	pongMsg := &WsMessage[PingResponse]{
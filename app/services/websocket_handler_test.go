@@ -41,6 +41,10 @@ func (m *mockClientManager) Unregister(client *Client) {
 	m.Called(client)
 }
 
+func (m *mockClientManager) DisconnectSession(userID string, sessionID string) {
+	m.Called(userID, sessionID)
+}
+
 func (m *mockClientManager) GetClient(userID string) (*Client, bool) {
 	args := m.Called(userID)
 	if args.Get(0) == nil {
@@ -49,6 +53,14 @@ func (m *mockClientManager) GetClient(userID string) (*Client, bool) {
 	return args.Get(0).(*Client), args.Bool(1)
 }
 
+func (m *mockClientManager) GetUserClients(userID string) []*Client {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).([]*Client)
+}
+
 func (m *mockClientManager) GetAllClients() map[*Client]bool {
 	args := m.Called()
 	if args.Get(0) == nil {
@@ -66,6 +78,18 @@ func (m *mockClientManager) StartHealthChecker(ctx context.Context) {
 	m.Called(ctx)
 }
 
+func (m *mockClientManager) OnClientEvent(action string, handler func(ClientEvent)) {
+	m.Called(action, handler)
+}
+
+func (m *mockClientManager) PublishClientEvent(event ClientEvent) {
+	m.Called(event)
+}
+
+func (m *mockClientManager) StartEventSubscriber(ctx context.Context) {
+	m.Called(ctx)
+}
+
 // mockRoomManager 模擬 RoomManager
 type mockRoomManager struct {
 	mock.Mock
@@ -114,6 +138,82 @@ func (m *mockMessageHandler) HandleMessage(message *MessageResponse) {
 	m.Called(message)
 }
 
+func (m *mockMessageHandler) EditMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, content string) (*MessageResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, roomType, roomID, messageID, content)
+	var message *MessageResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		message = args.Get(0).(*MessageResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return message, msgOpt
+}
+
+func (m *mockMessageHandler) DeleteMessage(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*MessageResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, roomType, roomID, messageID)
+	var message *MessageResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		message = args.Get(0).(*MessageResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return message, msgOpt
+}
+
+func (m *mockMessageHandler) MarkRead(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string) (*models.ReadStateResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, roomType, roomID, messageID)
+	var state *models.ReadStateResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		state = args.Get(0).(*models.ReadStateResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return state, msgOpt
+}
+
+func (m *mockMessageHandler) HandleTyping(ctx context.Context, userID string, roomType models.RoomType, roomID string, isTyping bool) *models.MessageOptions {
+	args := m.Called(ctx, userID, roomType, roomID, isTyping)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+func (m *mockMessageHandler) ResolveMessageReference(ctx context.Context, message *MessageResponse) *models.MessageOptions {
+	args := m.Called(ctx, message)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+func (m *mockMessageHandler) ResolveAttachments(ctx context.Context, message *MessageResponse, fileIDs []string) *models.MessageOptions {
+	args := m.Called(ctx, message, fileIDs)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*models.MessageOptions)
+}
+
+func (m *mockMessageHandler) UpdateReaction(ctx context.Context, userID string, roomType models.RoomType, roomID string, messageID string, emoji string, add bool) (*MessageResponse, *models.MessageOptions) {
+	args := m.Called(ctx, userID, roomType, roomID, messageID, emoji, add)
+	var message *MessageResponse
+	var msgOpt *models.MessageOptions
+	if args.Get(0) != nil {
+		message = args.Get(0).(*MessageResponse)
+	}
+	if args.Get(1) != nil {
+		msgOpt = args.Get(1).(*models.MessageOptions)
+	}
+	return message, msgOpt
+}
+
 // mockUserService 模擬 UserService
 type mockUserService struct {
 	mock.Mock
@@ -131,7 +231,7 @@ func (m *mockUserService) RegisterUser(user models.User) *models.MessageOptions
 	return nil
 }
 
-func (m *mockUserService) Login(loginUser models.User) (*models.LoginResponse, *models.MessageOptions) {
+func (m *mockUserService) Login(loginUser models.User, device models.DeviceInfo) (*models.LoginResponse, *models.MessageOptions) {
 	return nil, nil
 }
 
@@ -139,7 +239,7 @@ func (m *mockUserService) Logout(c *gin.Context) *models.MessageOptions {
 	return nil
 }
 
-func (m *mockUserService) RefreshToken(refreshToken string) (*models.RefreshTokenResponse, *models.MessageOptions) {
+func (m *mockUserService) RefreshToken(refreshToken string, device models.DeviceInfo) (*models.RefreshTokenResponse, *models.MessageOptions) {
 	return nil, nil
 }
 
@@ -194,7 +294,47 @@ func (m *mockUserService) GetTwoFactorStatus(userID string) (*models.TwoFactorSt
 	return nil, errors.New("not found")
 }
 
-func (m *mockUserService) UpdateTwoFactorStatus(userID string, enabled bool) error {
+func (m *mockUserService) SetupTwoFactor(userID string) (*models.TwoFactorSetupResponse, *models.MessageOptions) {
+	return nil, nil
+}
+
+func (m *mockUserService) EnableTwoFactor(userID string, code string) (*models.TwoFactorRecoveryCodesResponse, *models.MessageOptions) {
+	return nil, nil
+}
+
+func (m *mockUserService) DisableTwoFactor(userID string, code string) *models.MessageOptions {
+	return nil
+}
+
+func (m *mockUserService) VerifyTwoFactorLogin(challengeToken string, code string, device models.DeviceInfo) (*models.LoginResponse, *models.MessageOptions) {
+	return nil, nil
+}
+
+func (m *mockUserService) GetSessions(userID string, currentSessionID string) ([]models.SessionResponse, *models.MessageOptions) {
+	return nil, nil
+}
+
+func (m *mockUserService) RevokeSession(userID string, sessionID string) *models.MessageOptions {
+	return nil
+}
+
+func (m *mockUserService) RevokeOtherSessions(userID string, currentSessionID string) ([]string, *models.MessageOptions) {
+	return nil, nil
+}
+
+func (m *mockUserService) SendEmailVerification(userID string) *models.MessageOptions {
+	return nil
+}
+
+func (m *mockUserService) VerifyEmail(token string) *models.MessageOptions {
+	return nil
+}
+
+func (m *mockUserService) RequestPasswordReset(email string) *models.MessageOptions {
+	return nil
+}
+
+func (m *mockUserService) ResetPassword(token string, newPassword string) *models.MessageOptions {
 	return nil
 }
 
@@ -231,6 +371,11 @@ func (m *mockCacheProvider) SetNX(key string, value string, expiration time.Dura
 	return args.Bool(0), args.Error(1)
 }
 
+func (m *mockCacheProvider) Incr(key string, expiration time.Duration) (int64, error) {
+	args := m.Called(key, expiration)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *mockCacheProvider) Exists(key string) (bool, error) {
 	args := m.Called(key)
 	return args.Bool(0), args.Error(1)
@@ -257,8 +402,9 @@ func TestNewWebSocketHandler(t *testing.T) {
 	mockMH := new(mockMessageHandler)
 	mockUS := new(mockUserService)
 	mockCache := new(mockCacheProvider)
+	mockPS := new(mocks.PermissionService)
 
-	handler := NewWebSocketHandler(mockODM, mockCM, mockRM, mockMH, mockUS, mockCache)
+	handler := NewWebSocketHandler(mockODM, mockCM, mockRM, mockMH, mockUS, mockCache, mockPS)
 
 	assert.NotNil(t, handler)
 	assert.Equal(t, mockODM, handler.odm)
@@ -543,10 +689,13 @@ func TestHandleSendMessage(t *testing.T) {
 		mockMH := new(mockMessageHandler)
 		mockODM := new(mocks.ODM)
 
+		mockPS := new(mocks.PermissionService)
+
 		handler := &webSocketHandler{
-			roomManager:    mockRM,
-			messageHandler: mockMH,
-			odm:            mockODM,
+			roomManager:       mockRM,
+			messageHandler:    mockMH,
+			odm:               mockODM,
+			permissionService: mockPS,
 		}
 
 		ctx, cancel := context.WithCancel(context.Background())
@@ -573,6 +722,7 @@ func TestHandleSendMessage(t *testing.T) {
 		data, _ := json.Marshal(requestData)
 
 		// 設定 mock
+		mockPS.On("GetChannelPermissions", ctx, roomID, userID).Return(models.DefaultMemberPermissions, nil).Once()
 		mockRM.On("InitRoom", models.RoomTypeChannel, roomID).Return(&Room{}).Once()
 		mockMH.On("HandleMessage", mock.AnythingOfType("*services.MessageResponse")).Once()
 
@@ -673,6 +823,507 @@ func TestHandleSendMessage(t *testing.T) {
 			t.Fatal("未收到錯誤訊息")
 		}
 	})
+
+	t.Run("引用的訊息無效時不發送", func(t *testing.T) {
+		mockRM := new(mockRoomManager)
+		mockMH := new(mockMessageHandler)
+		mockPS := new(mocks.PermissionService)
+		handler := &webSocketHandler{
+			roomManager:       mockRM,
+			messageHandler:    mockMH,
+			permissionService: mockPS,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sendCh := make(chan []byte, 5)
+		client := &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}
+
+		replyToID := primitive.NewObjectID().Hex()
+		data, _ := json.Marshal(map[string]any{
+			"room_id":             roomID,
+			"room_type":           models.RoomTypeChannel,
+			"content":             "quote",
+			"reply_to_message_id": replyToID,
+		})
+
+		mockPS.On("GetChannelPermissions", client.Context, roomID, userID).Return(models.DefaultMemberPermissions, nil).Once()
+		mockMH.On("ResolveMessageReference", client.Context, mock.MatchedBy(func(message *MessageResponse) bool {
+			return message.ReplyToMessageID == replyToID
+		})).Return(&models.MessageOptions{Code: models.ErrMessageNotFound, Message: "訊息不存在"}).Once()
+
+		handler.handleSendMessage(client, data)
+
+		select {
+		case msg := <-sendCh:
+			var response WsMessage[ErrorResponse]
+			err := json.Unmarshal(msg, &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "send_message", response.Data.OriginalAction)
+			assert.Equal(t, "訊息不存在", response.Data.Message)
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("未收到錯誤訊息")
+		}
+		mockMH.AssertExpectations(t)
+		mockMH.AssertNotCalled(t, "HandleMessage", mock.Anything)
+		mockRM.AssertNotCalled(t, "InitRoom", mock.Anything, mock.Anything)
+	})
+
+	t.Run("附件無效時不發送", func(t *testing.T) {
+		mockRM := new(mockRoomManager)
+		mockMH := new(mockMessageHandler)
+		mockPS := new(mocks.PermissionService)
+		handler := &webSocketHandler{
+			roomManager:       mockRM,
+			messageHandler:    mockMH,
+			permissionService: mockPS,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sendCh := make(chan []byte, 5)
+		client := &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}
+
+		attachmentIDs := []string{primitive.NewObjectID().Hex()}
+		data, _ := json.Marshal(map[string]any{
+			"room_id":        roomID,
+			"room_type":      models.RoomTypeChannel,
+			"content":        "see attached",
+			"attachment_ids": attachmentIDs,
+		})
+
+		mockPS.On("GetChannelPermissions", client.Context, roomID, userID).Return(models.DefaultMemberPermissions, nil).Once()
+		mockMH.On("ResolveAttachments", client.Context, mock.AnythingOfType("*services.MessageResponse"), attachmentIDs).
+			Return(&models.MessageOptions{Code: models.ErrInvalidAttachment, Message: "附件不存在"}).Once()
+
+		handler.handleSendMessage(client, data)
+
+		select {
+		case msg := <-sendCh:
+			var response WsMessage[ErrorResponse]
+			err := json.Unmarshal(msg, &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "send_message", response.Data.OriginalAction)
+			assert.Equal(t, "附件不存在", response.Data.Message)
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("未收到錯誤訊息")
+		}
+		mockMH.AssertExpectations(t)
+		mockMH.AssertNotCalled(t, "HandleMessage", mock.Anything)
+		mockRM.AssertNotCalled(t, "InitRoom", mock.Anything, mock.Anything)
+	})
+
+	t.Run("不允許發送到個人房間", func(t *testing.T) {
+		mockRM := new(mockRoomManager)
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{
+			roomManager:    mockRM,
+			messageHandler: mockMH,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sendCh := make(chan []byte, 5)
+		client := &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}
+
+		data, _ := json.Marshal(map[string]any{
+			"room_id":   primitive.NewObjectID().Hex(),
+			"room_type": userRoomType,
+			"content":   "hi",
+		})
+
+		handler.handleSendMessage(client, data)
+
+		select {
+		case msg := <-sendCh:
+			var response WsMessage[ErrorResponse]
+			err := json.Unmarshal(msg, &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "send_message", response.Data.OriginalAction)
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("未收到錯誤訊息")
+		}
+		mockRM.AssertNotCalled(t, "InitRoom", mock.Anything, mock.Anything)
+		mockMH.AssertNotCalled(t, "HandleMessage", mock.Anything)
+	})
+}
+
+func TestHandleEditMessage(t *testing.T) {
+	roomID := primitive.NewObjectID().Hex()
+	userID := primitive.NewObjectID().Hex()
+	messageID := primitive.NewObjectID().Hex()
+
+	t.Run("成功編輯訊息", func(t *testing.T) {
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{
+			messageHandler: mockMH,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sendCh := make(chan []byte, 5)
+		client := &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}
+
+		data, _ := json.Marshal(map[string]any{
+			"room_id":    roomID,
+			"room_type":  models.RoomTypeChannel,
+			"message_id": messageID,
+			"content":    "edited",
+		})
+
+		mockMH.On("EditMessage", client.Context, userID, models.RoomTypeChannel, roomID, messageID, "edited").Return(&MessageResponse{ID: messageID}, nil).Once()
+
+		handler.handleEditMessage(client, data)
+
+		// 成功時不直接回覆，由房間廣播通知
+		assert.Len(t, sendCh, 0)
+		mockMH.AssertExpectations(t)
+	})
+
+	t.Run("非發送者編輯回傳錯誤", func(t *testing.T) {
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{
+			messageHandler: mockMH,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sendCh := make(chan []byte, 5)
+		client := &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}
+
+		data, _ := json.Marshal(map[string]any{
+			"room_id":    roomID,
+			"room_type":  models.RoomTypeChannel,
+			"message_id": messageID,
+			"content":    "edited",
+		})
+
+		mockMH.On("EditMessage", client.Context, userID, models.RoomTypeChannel, roomID, messageID, "edited").Return(nil, &models.MessageOptions{
+			Code:    models.ErrNoPermission,
+			Message: "只有發送者可以編輯訊息",
+		}).Once()
+
+		handler.handleEditMessage(client, data)
+
+		select {
+		case msg := <-sendCh:
+			var response WsMessage[ErrorResponse]
+			err := json.Unmarshal(msg, &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "error", response.Action)
+			assert.Equal(t, "edit_message", response.Data.OriginalAction)
+			assert.Equal(t, "只有發送者可以編輯訊息", response.Data.Message)
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("未收到錯誤訊息")
+		}
+
+		mockMH.AssertExpectations(t)
+	})
+}
+
+func TestHandleDeleteMessage(t *testing.T) {
+	roomID := primitive.NewObjectID().Hex()
+	userID := primitive.NewObjectID().Hex()
+	messageID := primitive.NewObjectID().Hex()
+
+	t.Run("成功刪除訊息", func(t *testing.T) {
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{
+			messageHandler: mockMH,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sendCh := make(chan []byte, 5)
+		client := &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}
+
+		data, _ := json.Marshal(map[string]any{
+			"room_id":    roomID,
+			"room_type":  models.RoomTypeDM,
+			"message_id": messageID,
+		})
+
+		mockMH.On("DeleteMessage", client.Context, userID, models.RoomTypeDM, roomID, messageID).Return(&MessageResponse{ID: messageID, IsDeleted: true}, nil).Once()
+
+		handler.handleDeleteMessage(client, data)
+
+		assert.Len(t, sendCh, 0)
+		mockMH.AssertExpectations(t)
+	})
+
+	t.Run("無效的請求數據", func(t *testing.T) {
+		handler := &webSocketHandler{}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sendCh := make(chan []byte, 5)
+		client := &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}
+
+		handler.handleDeleteMessage(client, json.RawMessage(`{invalid`))
+
+		select {
+		case msg := <-sendCh:
+			var response WsMessage[ErrorResponse]
+			err := json.Unmarshal(msg, &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "delete_message", response.Data.OriginalAction)
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("未收到錯誤訊息")
+		}
+	})
+}
+
+func TestHandleReaction(t *testing.T) {
+	roomID := primitive.NewObjectID().Hex()
+	userID := primitive.NewObjectID().Hex()
+	messageID := primitive.NewObjectID().Hex()
+
+	newClient := func() (*Client, chan []byte, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sendCh := make(chan []byte, 5)
+		return &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}, sendCh, cancel
+	}
+
+	data, _ := json.Marshal(map[string]any{
+		"room_id":    roomID,
+		"room_type":  models.RoomTypeChannel,
+		"message_id": messageID,
+		"emoji":      "👍",
+	})
+
+	t.Run("新增與移除回應", func(t *testing.T) {
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{messageHandler: mockMH}
+		client, sendCh, cancel := newClient()
+		defer cancel()
+
+		mockMH.On("UpdateReaction", client.Context, userID, models.RoomTypeChannel, roomID, messageID, "👍", true).Return(&MessageResponse{ID: messageID}, nil).Once()
+		mockMH.On("UpdateReaction", client.Context, userID, models.RoomTypeChannel, roomID, messageID, "👍", false).Return(&MessageResponse{ID: messageID}, nil).Once()
+
+		handler.handleClientMessage(client, WsMessage[json.RawMessage]{Action: "add_reaction", Data: data})
+		handler.handleClientMessage(client, WsMessage[json.RawMessage]{Action: "remove_reaction", Data: data})
+
+		// 成功時由房間廣播通知，不直接回覆
+		assert.Len(t, sendCh, 0)
+		mockMH.AssertExpectations(t)
+	})
+
+	t.Run("訊息已刪除時回傳錯誤", func(t *testing.T) {
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{messageHandler: mockMH}
+		client, sendCh, cancel := newClient()
+		defer cancel()
+
+		mockMH.On("UpdateReaction", client.Context, userID, models.RoomTypeChannel, roomID, messageID, "👍", true).
+			Return(nil, &models.MessageOptions{Code: models.ErrMessageDeleted, Message: "訊息已被刪除"}).Once()
+
+		handler.handleReaction(client, data, true)
+
+		select {
+		case msg := <-sendCh:
+			var response WsMessage[ErrorResponse]
+			err := json.Unmarshal(msg, &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "add_reaction", response.Data.OriginalAction)
+			assert.Equal(t, "訊息已被刪除", response.Data.Message)
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("未收到錯誤訊息")
+		}
+		mockMH.AssertExpectations(t)
+	})
+}
+
+func TestHandleMarkRead(t *testing.T) {
+	roomID := primitive.NewObjectID().Hex()
+	userID := primitive.NewObjectID().Hex()
+	messageID := primitive.NewObjectID().Hex()
+
+	newClient := func() (*Client, chan []byte, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sendCh := make(chan []byte, 5)
+		return &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}, sendCh, cancel
+	}
+
+	t.Run("成功標記已讀", func(t *testing.T) {
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{messageHandler: mockMH}
+		client, sendCh, cancel := newClient()
+		defer cancel()
+
+		data, _ := json.Marshal(map[string]any{
+			"room_id":    roomID,
+			"room_type":  models.RoomTypeChannel,
+			"message_id": messageID,
+		})
+
+		mockMH.On("MarkRead", client.Context, userID, models.RoomTypeChannel, roomID, messageID).
+			Return(&models.ReadStateResponse{RoomType: models.RoomTypeChannel, RoomID: roomID, LastReadMessageID: messageID}, nil).Once()
+
+		handler.handleMarkRead(client, data)
+
+		// 成功時由個人房間推送 read_state_updated，不直接回覆
+		assert.Len(t, sendCh, 0)
+		mockMH.AssertExpectations(t)
+	})
+
+	t.Run("沒有權限時回傳錯誤", func(t *testing.T) {
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{messageHandler: mockMH}
+		client, sendCh, cancel := newClient()
+		defer cancel()
+
+		data, _ := json.Marshal(map[string]any{
+			"room_id":   roomID,
+			"room_type": models.RoomTypeDM,
+		})
+
+		mockMH.On("MarkRead", client.Context, userID, models.RoomTypeDM, roomID, "").
+			Return(nil, &models.MessageOptions{Code: models.ErrNoPermission, Message: "您沒有權限存取此房間"}).Once()
+
+		handler.handleMarkRead(client, data)
+
+		select {
+		case msg := <-sendCh:
+			var response WsMessage[ErrorResponse]
+			err := json.Unmarshal(msg, &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "mark_read", response.Data.OriginalAction)
+			assert.Equal(t, "您沒有權限存取此房間", response.Data.Message)
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("未收到錯誤訊息")
+		}
+		mockMH.AssertExpectations(t)
+	})
+}
+
+func TestHandleTypingAction(t *testing.T) {
+	roomID := primitive.NewObjectID().Hex()
+	userID := primitive.NewObjectID().Hex()
+
+	newClient := func() (*Client, chan []byte, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sendCh := make(chan []byte, 5)
+		return &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}, sendCh, cancel
+	}
+
+	t.Run("開始與停止輸入", func(t *testing.T) {
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{messageHandler: mockMH}
+		client, sendCh, cancel := newClient()
+		defer cancel()
+
+		data, _ := json.Marshal(map[string]any{
+			"room_id":   roomID,
+			"room_type": models.RoomTypeChannel,
+		})
+
+		mockMH.On("HandleTyping", client.Context, userID, models.RoomTypeChannel, roomID, true).Return(nil).Once()
+		mockMH.On("HandleTyping", client.Context, userID, models.RoomTypeChannel, roomID, false).Return(nil).Once()
+
+		handler.handleClientMessage(client, WsMessage[json.RawMessage]{Action: "typing_start", Data: data})
+		handler.handleClientMessage(client, WsMessage[json.RawMessage]{Action: "typing_stop", Data: data})
+
+		assert.Len(t, sendCh, 0)
+		mockMH.AssertExpectations(t)
+	})
+
+	t.Run("沒有權限時回傳錯誤", func(t *testing.T) {
+		mockMH := new(mockMessageHandler)
+		handler := &webSocketHandler{messageHandler: mockMH}
+		client, sendCh, cancel := newClient()
+		defer cancel()
+
+		data, _ := json.Marshal(map[string]any{
+			"room_id":   roomID,
+			"room_type": models.RoomTypeDM,
+		})
+
+		mockMH.On("HandleTyping", client.Context, userID, models.RoomTypeDM, roomID, true).
+			Return(&models.MessageOptions{Code: models.ErrNoPermission, Message: "您沒有權限存取此房間"}).Once()
+
+		handler.handleTyping(client, data, true)
+
+		select {
+		case msg := <-sendCh:
+			var response WsMessage[ErrorResponse]
+			err := json.Unmarshal(msg, &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "typing_start", response.Data.OriginalAction)
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("未收到錯誤訊息")
+		}
+		mockMH.AssertExpectations(t)
+	})
 }
 
 func TestHandlePing(t *testing.T) {
@@ -982,3 +1633,65 @@ func TestUpdateActivityWithThrottle(t *testing.T) {
 		// mockUS 不應該被調用
 	})
 }
+
+// TestHandleSendMessageChannelPermissions 測試頻道發言與提及所有人的權限檢查
+func TestHandleSendMessageChannelPermissions(t *testing.T) {
+	roomID := primitive.NewObjectID().Hex()
+	userID := primitive.NewObjectID().Hex()
+
+	newClient := func() (*Client, chan []byte, context.CancelFunc) {
+		ctx, cancel := context.WithCancel(context.Background())
+		sendCh := make(chan []byte, 5)
+		return &Client{
+			UserID:       userID,
+			IsActive:     true,
+			Send:         sendCh,
+			RoomActivity: make(map[string]time.Time),
+			Context:      ctx,
+			Cancel:       cancel,
+		}, sendCh, cancel
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		permissions models.Permission
+		expected    string
+	}{
+		{"唯讀頻道無法發言", "hello", models.PermissionViewChannel, "沒有權限在此頻道發送訊息"},
+		{"無權限使用 @everyone", "@everyone 開會了", models.DefaultMemberPermissions, "沒有權限使用 @everyone 或 @here"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockMH := new(mockMessageHandler)
+			mockPS := new(mocks.PermissionService)
+			handler := &webSocketHandler{messageHandler: mockMH, permissionService: mockPS}
+			client, sendCh, cancel := newClient()
+			defer cancel()
+
+			mockPS.On("GetChannelPermissions", client.Context, roomID, userID).Return(tt.permissions, nil).Once()
+
+			data, _ := json.Marshal(map[string]any{
+				"room_id":   roomID,
+				"room_type": models.RoomTypeChannel,
+				"content":   tt.content,
+			})
+			handler.handleSendMessage(client, data)
+
+			select {
+			case msg := <-sendCh:
+				var response WsMessage[ErrorResponse]
+				err := json.Unmarshal(msg, &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "send_message", response.Data.OriginalAction)
+				assert.Equal(t, tt.expected, response.Data.Message)
+			case <-time.After(100 * time.Millisecond):
+				t.Fatal("預期收到錯誤訊息")
+			}
+
+			mockPS.AssertExpectations(t)
+			mockMH.AssertNotCalled(t, "HandleMessage", mock.Anything)
+		})
+	}
+}
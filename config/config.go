@@ -18,6 +18,9 @@ type Config struct {
 	Upload   UploadConfig
 	MinIO    MinIOConfig
 	Cache    CacheConfig
+	Scanner  ScannerConfig
+	TOTP     TOTPConfig
+	Mail     MailConfig
 }
 type ModeConfig string
 
@@ -31,6 +34,7 @@ type ServerConfig struct {
 	MainDomain       string
 	Port             string
 	BaseURL          string
+	FrontendURL      string // 前端網址，用於信件中的驗證與重設密碼連結
 	Mode             ModeConfig
 	Timezone         string
 	AllowedOrigins   []string
@@ -73,6 +77,8 @@ type JWTConfig struct {
 type UploadConfig struct {
 	MaxSize      int64
 	AllowedTypes []string
+	UserQuota    int64 // 每位用戶的儲存空間上限（bytes），0 表示不限制
+	ServerQuota  int64 // 每個伺服器的儲存空間上限（bytes），0 表示不限制
 }
 
 type MinIOConfig struct {
@@ -82,6 +88,44 @@ type MinIOConfig struct {
 	UseSSL          bool
 	BucketName      string
 	PublicURL       string
+	PresignEndpoint string // 預簽名網址使用的位址（簽名包含主機名稱，需為客戶端可連線的位址），空值表示與 Endpoint 相同
+	Region          string
+}
+
+type ScannerType string
+
+const (
+	ScannerTypeHeuristic ScannerType = "heuristic"
+	ScannerTypeClamd     ScannerType = "clamd"
+)
+
+type ScannerConfig struct {
+	Type           ScannerType
+	ClamdAddress   string // tcp://host:3310 或 unix:///var/run/clamav/clamd.ctl
+	TimeoutSeconds int
+}
+
+type TOTPConfig struct {
+	Issuer        string // 顯示在驗證器 App 中的服務名稱
+	EncryptionKey string // 加密儲存 TOTP 密鑰用的金鑰
+}
+
+type MailDriver string
+
+const (
+	MailDriverSMTP MailDriver = "smtp"
+	MailDriverLog  MailDriver = "log"
+)
+
+type MailConfig struct {
+	Driver         MailDriver
+	From           string // 寄件者，例如 "Chat App <no-reply@example.com>"
+	SMTPHost       string
+	SMTPPort       int // 465 使用 TLS 連線，其他連接埠在伺服器支援時使用 STARTTLS
+	SMTPUsername   string
+	SMTPPassword   string
+	OutboxDir      string // log 模式下將信件寫成 .eml 檔案的目錄，空值表示只寫入日誌
+	TimeoutSeconds int
 }
 
 var AppConfig *Config
@@ -103,6 +147,7 @@ func LoadConfig() {
 			MainDomain:       getEnv("SERVER_MAIN_DOMAIN", "localhost"),
 			Port:             getEnv("SERVER_PORT", "8080"),
 			BaseURL:          getEnv("SERVER_BASE_URL", "http://localhost"),
+			FrontendURL:      getEnv("FRONTEND_URL", "http://localhost:3000"),
 			Mode:             ModeConfig(getEnv("ENV", "development")),
 			Timezone:         getEnv("TIMEZONE", "Asia/Taipei"),
 			AllowedOrigins:   strings.Split(getEnv("ALLOWED_ORIGINS", "http://localhost:3000"), ","),
@@ -129,6 +174,8 @@ func LoadConfig() {
 		Upload: UploadConfig{
 			MaxSize:      getEnvAsInt64("UPLOAD_MAX_SIZE", 10485760),
 			AllowedTypes: strings.Split(getEnv("UPLOAD_ALLOWED_TYPES", "image/jpeg,image/png"), ","),
+			UserQuota:    getEnvAsInt64("UPLOAD_USER_QUOTA", 1073741824),
+			ServerQuota:  getEnvAsInt64("UPLOAD_SERVER_QUOTA", 5368709120),
 		},
 		MinIO: MinIOConfig{
 			Endpoint:        getEnv("MINIO_ENDPOINT", "localhost:9000"),
@@ -137,10 +184,31 @@ func LoadConfig() {
 			UseSSL:          getEnv("MINIO_USE_SSL", "false") == "true",
 			BucketName:      getEnv("MINIO_BUCKET_NAME", "chat-app-uploads"),
 			PublicURL:       getEnv("MINIO_PUBLIC_URL", "http://localhost:9000"),
+			PresignEndpoint: getEnv("MINIO_PRESIGN_ENDPOINT", ""),
+			Region:          getEnv("MINIO_REGION", "us-east-1"),
 		},
 		Cache: CacheConfig{
 			Type: CacheType(getEnv("CACHE_TYPE", "redis")),
 		},
+		Scanner: ScannerConfig{
+			Type:           ScannerType(getEnv("MALWARE_SCANNER", "heuristic")),
+			ClamdAddress:   getEnv("CLAMD_ADDRESS", "tcp://localhost:3310"),
+			TimeoutSeconds: getEnvAsInt("CLAMD_TIMEOUT_SECONDS", 60),
+		},
+		TOTP: TOTPConfig{
+			Issuer:        getEnv("TOTP_ISSUER", "Chat App"),
+			EncryptionKey: getEnv("TOTP_ENCRYPTION_KEY", ""),
+		},
+		Mail: MailConfig{
+			Driver:         MailDriver(getEnv("MAIL_DRIVER", "log")),
+			From:           getEnv("MAIL_FROM", "Chat App <no-reply@localhost>"),
+			SMTPHost:       getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:       getEnvAsInt("SMTP_PORT", 587),
+			SMTPUsername:   getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+			OutboxDir:      getEnv("MAIL_OUTBOX_DIR", ""),
+			TimeoutSeconds: getEnvAsInt("MAIL_TIMEOUT_SECONDS", 10),
+		},
 	}
 
 	// 驗證必要的配置
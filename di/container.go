@@ -7,6 +7,7 @@ import (
 	"chat_app_backend/app/services"
 	"chat_app_backend/config"
 	"fmt"
+	"time"
 )
 
 // Repository容器
@@ -19,6 +20,11 @@ type RepositoryContainer struct {
 	ChannelRepo         repositories.ChannelRepository
 	ChannelCategoryRepo repositories.ChannelCategoryRepository
 	FileRepo            repositories.FileRepository
+	RoleRepo            repositories.RoleRepository
+	InviteRepo          repositories.InviteRepository
+	ModerationRepo      repositories.ModerationRepository
+	AuditLogRepo        repositories.AuditLogRepository
+	UploadSessionRepo   repositories.UploadSessionRepository
 }
 
 // Service容器
@@ -30,24 +36,33 @@ type ServiceContainer struct {
 	ChannelService    services.ChannelService
 	FileUploadService services.FileUploadService
 	ClientManager     services.ClientManager
+	PermissionService services.PermissionService
+	ModerationService services.ModerationService
+	AuditLogService   services.AuditLogService
 }
 
 // Controller容器
 type ControllerContainer struct {
-	HealthController  *controllers.HealthController
-	UserController    *controllers.UserController
-	ChatController    *controllers.ChatController
-	ServerController  *controllers.ServerController
-	FriendController  *controllers.FriendController
-	ChannelController *controllers.ChannelController
-	FileController    *controllers.FileController
+	HealthController     *controllers.HealthController
+	UserController       *controllers.UserController
+	ChatController       *controllers.ChatController
+	ServerController     *controllers.ServerController
+	FriendController     *controllers.FriendController
+	ChannelController    *controllers.ChannelController
+	FileController       *controllers.FileController
+	RoleController       *controllers.RoleController
+	ModerationController *controllers.ModerationController
+	AuditLogController   *controllers.AuditLogController
 }
 
 // Providers容器
 type ProviderContainer struct {
-	ODM          providers.ODM
-	FileProvider providers.FileProvider
-	Cache        providers.CacheProvider
+	ODM            providers.ODM
+	FileProvider   providers.FileProvider
+	Cache          providers.CacheProvider
+	MalwareScanner providers.MalwareScanner
+	TokenDenylist  providers.TokenDenylist
+	Mailer         providers.Mailer
 }
 
 // 初始化Repositories
@@ -64,6 +79,11 @@ func initRepositories(
 		ChannelRepo:         repositories.NewChannelRepository(cfg, providers.ODM),
 		ChannelCategoryRepo: repositories.NewChannelCategoryRepository(providers.ODM),
 		FileRepo:            repositories.NewFileRepository(cfg, providers.ODM),
+		RoleRepo:            repositories.NewRoleRepository(providers.ODM),
+		InviteRepo:          repositories.NewInviteRepository(providers.ODM),
+		ModerationRepo:      repositories.NewModerationRepository(providers.ODM),
+		AuditLogRepo:        repositories.NewAuditLogRepository(providers.ODM),
+		UploadSessionRepo:   repositories.NewUploadSessionRepository(providers.ODM),
 	}
 }
 
@@ -75,7 +95,7 @@ func initServices(
 	redis *providers.RedisWrapper,
 ) *ServiceContainer {
 	// 1. 將 ClientManager 的初始化提前
-	clientManager := services.NewClientManager(providers.Cache)
+	clientManager := services.NewClientManager(providers.Cache, redis.Client)
 
 	// 2. 初始化檔案上傳服務
 	fileUploadService := services.NewFileUploadService(
@@ -83,6 +103,9 @@ func initServices(
 		providers.FileProvider,
 		providers.ODM,
 		repos.FileRepo,
+		repos.UploadSessionRepo,
+		repos.ServerMemberRepo,
+		providers.MalwareScanner,
 	)
 
 	// 3. 現在可以直接創建最終的 UserService
@@ -92,23 +115,37 @@ func initServices(
 		repos.UserRepo,
 		fileUploadService,
 		providers.Cache,
+		providers.TokenDenylist,
+		providers.Mailer,
 	)
 
-	// 4. 創建 ChatService，並傳入已經建立好的 UserService
+	// 4. 初始化集中權限檢查服務（頻道、伺服器與 WebSocket 共用）
+	permissionService := services.NewPermissionService(
+		cfg,
+		repos.ServerRepo,
+		repos.ServerMemberRepo,
+		repos.RoleRepo,
+		repos.ChannelRepo,
+		repos.AuditLogRepo,
+	)
+
+	// 5. 創建 ChatService，並傳入已經建立好的 UserService
 	chatService := services.NewChatService(
 		cfg,
 		providers.ODM,
 		redis.Client,
 		providers.Cache,
+		clientManager,
 		repos.ChatRepo,
 		repos.ServerRepo,
 		repos.ServerMemberRepo,
 		repos.UserRepo,
 		userService,
 		fileUploadService,
+		permissionService,
 	)
 
-	// 5. 創建其他服務
+	// 6. 創建其他服務
 	serverService := services.NewServerService(
 		cfg,
 		providers.ODM,
@@ -122,6 +159,10 @@ func initServices(
 		userService,
 		clientManager,
 		providers.Cache,
+		permissionService,
+		repos.InviteRepo,
+		repos.ModerationRepo,
+		repos.AuditLogRepo,
 	)
 	friendService := services.NewFriendService(
 		cfg,
@@ -135,11 +176,35 @@ func initServices(
 		cfg,
 		providers.ODM,
 		repos.ChannelRepo,
+		repos.ChannelCategoryRepo,
 		repos.ServerRepo,
 		repos.ServerMemberRepo,
 		repos.UserRepo,
 		repos.ChatRepo,
 		providers.Cache,
+		permissionService,
+		repos.AuditLogRepo,
+	)
+
+	// 7. 成員管理服務（踢出、封鎖後需透過 ChatService 讓用戶離開頻道房間）
+	moderationService := services.NewModerationService(
+		cfg,
+		repos.ServerRepo,
+		repos.ServerMemberRepo,
+		repos.UserRepo,
+		repos.ModerationRepo,
+		permissionService,
+		chatService,
+		providers.Cache,
+		repos.AuditLogRepo,
+	)
+
+	// 8. 稽核紀錄查詢服務（紀錄由各服務透過 AuditLogRepo 寫入）
+	auditLogService := services.NewAuditLogService(
+		cfg,
+		repos.AuditLogRepo,
+		repos.UserRepo,
+		permissionService,
 	)
 
 	return &ServiceContainer{
@@ -150,6 +215,9 @@ func initServices(
 		ChannelService:    channelService,
 		FileUploadService: fileUploadService,
 		ClientManager:     clientManager,
+		PermissionService: permissionService,
+		ModerationService: moderationService,
+		AuditLogService:   auditLogService,
 	}
 }
 
@@ -193,6 +261,22 @@ func initControllers(
 			cfg,
 			mongodb.DB,
 			services.FileUploadService,
+			services.ChatService,
+		),
+		RoleController: controllers.NewRoleController(
+			cfg,
+			mongodb.DB,
+			services.PermissionService,
+		),
+		ModerationController: controllers.NewModerationController(
+			cfg,
+			mongodb.DB,
+			services.ModerationService,
+		),
+		AuditLogController: controllers.NewAuditLogController(
+			cfg,
+			mongodb.DB,
+			services.AuditLogService,
 		),
 	}
 }
@@ -226,10 +310,27 @@ func initProviders(
 		cacheProvider = providers.NewRedisCacheProvider(redis.Client)
 	}
 
+	malwareScanner, err := providers.NewMalwareScanner(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("初始化惡意軟體掃描引擎失敗: %v", err))
+	}
+
+	mailer, err := providers.NewMailer(cfg)
+	if err != nil {
+		panic(fmt.Sprintf("初始化寄信服務失敗: %v", err))
+	}
+
+	// access token 撤銷清單需跨實例共享，固定使用 Redis（不受快取類型設定影響）
+	accessTokenTTL := time.Duration(cfg.JWT.AccessExpireMinutes) * time.Minute
+	tokenDenylist := providers.NewCacheTokenDenylist(providers.NewRedisCacheProvider(redis.Client), accessTokenTTL)
+
 	return &ProviderContainer{
-		ODM:          providers.NewODM(mongodb.DB),
-		FileProvider: fileProvider,
-		Cache:        cacheProvider,
+		ODM:            providers.NewODM(mongodb.DB),
+		FileProvider:   fileProvider,
+		Cache:          cacheProvider,
+		MalwareScanner: malwareScanner,
+		TokenDenylist:  tokenDenylist,
+		Mailer:         mailer,
 	}
 }
 
@@ -82,8 +82,11 @@ func main() {
 	// 啟動 ClientManager 健康檢查器
 	go deps.Services.ClientManager.StartHealthChecker(ctx)
 
+	// 訂閱跨實例連線管理事件（踢出、封鎖後離開房間等）
+	go deps.Services.ClientManager.StartEventSubscriber(ctx)
+
 	// 使用依賴容器中的 UserService 來啟動後台任務
-	backgroundTasks := services.NewBackgroundTasks(deps.Services.UserService)
+	backgroundTasks := services.NewBackgroundTasks(deps.Services.UserService, deps.Services.FileUploadService)
 	go backgroundTasks.StartAllBackgroundTasks(ctx)
 
 	// 註冊 pprof（僅限非生產環境，避免暴露敏感效能資訊）
@@ -92,7 +95,7 @@ func main() {
 	}
 
 	// 設置路由
-	routes.SetupRoutes(r, config.AppConfig, redis, deps.Controllers)
+	routes.SetupRoutes(r, config.AppConfig, redis, deps.Controllers, deps.Providers.TokenDenylist)
 
 	// 確保上傳目錄存在 (權限設置為 0750 以符合安全建議)
 	err = os.MkdirAll("uploads", 0750)
@@ -6,7 +6,6 @@ import (
 	"chat_app_backend/config"
 	"chat_app_backend/di"
 	"chat_app_backend/version"
-	"path/filepath"
 	"time"
 
 	"github.com/gin-contrib/cors"
@@ -14,7 +13,7 @@ import (
 	ginprometheus "github.com/zsais/go-gin-prometheus"
 )
 
-func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrapper, controllers *di.ControllerContainer) {
+func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrapper, controllers *di.ControllerContainer, tokenDenylist providers.TokenDenylist) {
 	// 初始化 Prometheus 監控
 	p := ginprometheus.NewPrometheus("gin")
 	p.Use(r)
@@ -27,7 +26,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrappe
 		AllowOrigins:     cfg.Server.AllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-CSRF-NAME", "X-CSRF-TOKEN"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Range", "Content-Disposition", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -47,9 +46,12 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrappe
 	withTimeout := r.Group("/")
 	withTimeout.Use(middlewares.Timeout(30 * time.Second))
 
-	// 設定靜態文件服務 (此處也可以視需求決定是否套用 timeout，Static 通常不需要)
-	uploadsAbsPath := filepath.Join(".", "uploads")
-	r.Static("/uploads", uploadsAbsPath)
+	// 上傳檔案服務：頭像、橫幅與伺服器圖示公開且可快取，其他檔案需登入並具備存取權限
+	// 不套用 timeout，大型檔案下載與 Range 續傳可能超過 30 秒
+	uploads := r.Group("/uploads")
+	uploads.Use(middlewares.OptionalAuth(tokenDenylist))
+	uploads.GET("/*filepath", controllers.FileController.ServeFile)
+	uploads.HEAD("/*filepath", controllers.FileController.ServeFile)
 
 	// 驗證前端來源
 	if cfg.Server.Mode == config.ProductionMode {
@@ -68,6 +70,22 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrappe
 		middlewares.RateLimiter(redis.Client, "login", 5, time.Minute, cfg.Server.DisableRateLimit),
 		controllers.UserController.Login,
 	)
+	public.POST("/login/2fa",
+		middlewares.RateLimiter(redis.Client, "login_2fa", 5, time.Minute, cfg.Server.DisableRateLimit),
+		controllers.UserController.VerifyTwoFactorLogin,
+	)
+	public.POST("/email/verify",
+		middlewares.RateLimiter(redis.Client, "verify_email", 10, time.Minute, cfg.Server.DisableRateLimit),
+		controllers.UserController.VerifyEmail,
+	)
+	public.POST("/password/forgot",
+		middlewares.RateLimiter(redis.Client, "forgot_password", 3, 10*time.Minute, cfg.Server.DisableRateLimit),
+		controllers.UserController.ForgotPassword,
+	)
+	public.POST("/password/reset",
+		middlewares.RateLimiter(redis.Client, "reset_password", 5, time.Minute, cfg.Server.DisableRateLimit),
+		controllers.UserController.ResetPassword,
+	)
 	public.POST("/logout", middlewares.VerifyCSRFToken(), controllers.UserController.Logout)
 	public.POST("/refresh_token",
 		middlewares.RateLimiter(redis.Client, "refresh_token", 10, 5*time.Minute, cfg.Server.DisableRateLimit),
@@ -82,13 +100,13 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrappe
 
 	// 需要認證的路由
 	auth := withTimeout.Group("/")
-	auth.Use(middlewares.Auth())
+	auth.Use(middlewares.Auth(tokenDenylist))
 
 	// WebSocket 特殊處理：需要認證，但不要 Timeout
 	// 注意：這裡使用 auth.Group("/") 但排除 timeout 是比較困難的，
 	// 所以我們建立一個獨立的 wsAuth 組，只包含 Auth 但不包含 Timeout。
 	wsAuth := r.Group("/")
-	wsAuth.Use(middlewares.Auth())
+	wsAuth.Use(middlewares.Auth(tokenDenylist))
 	wsAuth.GET("/ws", controllers.ChatController.HandleConnections)
 
 	authWithCSRF := auth.Group("/")
@@ -104,9 +122,24 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrappe
 	authWithCSRF.DELETE("/user/avatar", controllers.UserController.DeleteUserAvatar)
 	authWithCSRF.DELETE("/user/banner", controllers.UserController.DeleteUserBanner)
 	authWithCSRF.PUT("/user/password", controllers.UserController.UpdateUserPassword)
+	authWithCSRF.POST("/user/email/verification",
+		middlewares.RateLimiter(redis.Client, "send_email_verification", 3, 10*time.Minute, cfg.Server.DisableRateLimit),
+		controllers.UserController.SendEmailVerification,
+	)
 	authWithCSRF.PUT("/user/deactivate", controllers.UserController.DeactivateAccount)
 	authWithCSRF.DELETE("/user/delete", controllers.UserController.DeleteAccount)
 
+	// 兩步驟驗證（TOTP）
+	auth.GET("/user/2fa", controllers.UserController.GetTwoFactorStatus)
+	authWithCSRF.POST("/user/2fa/setup", controllers.UserController.SetupTwoFactor)
+	authWithCSRF.POST("/user/2fa/enable", controllers.UserController.EnableTwoFactor)
+	authWithCSRF.POST("/user/2fa/disable", controllers.UserController.DisableTwoFactor)
+
+	// 登入裝置管理
+	auth.GET("/user/sessions", controllers.UserController.GetSessions)
+	authWithCSRF.DELETE("/user/sessions/:id", controllers.UserController.RevokeSession)
+	authWithCSRF.POST("/user/sessions/revoke-others", controllers.UserController.RevokeOtherSessions)
+
 	// auth.GET("/users/:id/online-status", controllers.UserController.CheckUserOnlineStatus) // 檢查特定用戶在線狀態
 
 	// friend
@@ -135,6 +168,26 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrappe
 	authWithCSRF.POST("/dm_rooms", controllers.ChatController.CreateDMRoom)           // 保存聊天列表
 	auth.GET("/dm_rooms/:room_id/messages", controllers.ChatController.GetDMMessages) // 獲取私聊訊息
 
+	// dm message 編輯與刪除
+	authWithCSRF.PUT("/dm_rooms/:room_id/messages/:id", controllers.ChatController.EditDMMessage)      // 編輯私聊訊息
+	authWithCSRF.DELETE("/dm_rooms/:room_id/messages/:id", controllers.ChatController.DeleteDMMessage) // 刪除私聊訊息
+
+	// dm message 表情回應
+	authWithCSRF.PUT("/dm_rooms/:room_id/messages/:id/reactions/:emoji", controllers.ChatController.AddDMReaction)       // 新增私聊訊息表情回應
+	authWithCSRF.DELETE("/dm_rooms/:room_id/messages/:id/reactions/:emoji", controllers.ChatController.RemoveDMReaction) // 移除私聊訊息表情回應
+
+	// dm 討論串
+	auth.GET("/dm_rooms/:room_id/messages/:id/thread", controllers.ChatController.GetDMThreadMessages) // 獲取私聊討論串回覆
+
+	// dm 已讀狀態
+	authWithCSRF.PUT("/dm_rooms/:room_id/read", controllers.ChatController.MarkDMRoomRead) // 標記私聊已讀
+
+	// 訊息搜尋
+	auth.GET("/search/messages", controllers.ChatController.SearchMessages) // 全文搜尋訊息
+
+	// 訊息附件
+	auth.GET("/attachments/:file_id", controllers.ChatController.GetAttachment) // 獲取訊息附件（需具備房間存取權限）
+
 	// server
 	auth.GET("/servers", controllers.ServerController.GetServerList)
 	authWithCSRF.POST("/servers", controllers.ServerController.CreateServer)
@@ -146,6 +199,34 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrappe
 	authWithCSRF.POST("/servers/:server_id/join", controllers.ServerController.JoinServer)   // 請求加入伺服器
 	authWithCSRF.POST("/servers/:server_id/leave", controllers.ServerController.LeaveServer) // 離開伺服器
 
+	// server 邀請
+	auth.GET("/servers/:server_id/invites", controllers.ServerController.GetServerInvites)                   // 獲取伺服器邀請列表
+	authWithCSRF.POST("/servers/:server_id/invites", controllers.ServerController.CreateInvite)              // 創建邀請
+	authWithCSRF.DELETE("/servers/:server_id/invites/:invite_id", controllers.ServerController.RevokeInvite) // 撤銷邀請
+	authWithCSRF.POST("/invites/:code/accept", controllers.ServerController.AcceptInvite)                    // 透過邀請碼加入伺服器
+
+	// server 成員管理
+	authWithCSRF.POST("/servers/:server_id/members/:user_id/kick", controllers.ModerationController.KickMember)         // 踢出成員
+	auth.GET("/servers/:server_id/bans", controllers.ModerationController.GetServerBans)                                // 獲取封鎖列表
+	authWithCSRF.PUT("/servers/:server_id/bans/:user_id", controllers.ModerationController.BanMember)                   // 封鎖用戶
+	authWithCSRF.DELETE("/servers/:server_id/bans/:user_id", controllers.ModerationController.UnbanMember)              // 解除封鎖
+	authWithCSRF.PUT("/servers/:server_id/members/:user_id/timeout", controllers.ModerationController.TimeoutMember)    // 禁言成員
+	authWithCSRF.DELETE("/servers/:server_id/members/:user_id/timeout", controllers.ModerationController.RemoveTimeout) // 解除禁言
+
+	// server 稽核紀錄
+	auth.GET("/servers/:server_id/audit-log", controllers.AuditLogController.GetServerAuditLog) // 獲取伺服器稽核紀錄
+
+	// server 角色與權限
+	auth.GET("/servers/:server_id/roles", controllers.RoleController.GetServerRoles)                 // 獲取伺服器角色列表
+	authWithCSRF.POST("/servers/:server_id/roles", controllers.RoleController.CreateRole)            // 創建角色
+	authWithCSRF.PUT("/servers/:server_id/roles/:role_id", controllers.RoleController.UpdateRole)    // 更新角色
+	authWithCSRF.DELETE("/servers/:server_id/roles/:role_id", controllers.RoleController.DeleteRole) // 刪除角色
+	auth.GET("/servers/:server_id/permissions/me", controllers.RoleController.GetMyPermissions)      // 獲取自己的有效權限
+
+	// server 成員角色指派
+	authWithCSRF.PUT("/servers/:server_id/members/:user_id/roles/:role_id", controllers.RoleController.AssignRole)      // 指派角色給成員
+	authWithCSRF.DELETE("/servers/:server_id/members/:user_id/roles/:role_id", controllers.RoleController.UnassignRole) // 移除成員角色
+
 	// channel
 	auth.GET("/servers/:server_id/channels", controllers.ChannelController.GetChannelsByServerID)  // 獲取伺服器頻道列表
 	auth.GET("/channels/:channel_id", controllers.ChannelController.GetChannelByID)                // 獲取單個頻道信息
@@ -154,6 +235,31 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrappe
 	authWithCSRF.DELETE("/channels/:channel_id", controllers.ChannelController.DeleteChannel)      // 刪除頻道
 	auth.GET("/channels/:channel_id/messages", controllers.ChatController.GetChannelMessages)      // 獲取頻道訊息
 
+	// channel 類別與排序
+	authWithCSRF.POST("/servers/:server_id/categories", controllers.ChannelController.CreateCategory)     // 創建頻道類別
+	authWithCSRF.PUT("/categories/:category_id", controllers.ChannelController.UpdateCategory)            // 重新命名頻道類別
+	authWithCSRF.DELETE("/categories/:category_id", controllers.ChannelController.DeleteCategory)         // 刪除頻道類別
+	authWithCSRF.PUT("/servers/:server_id/channels/order", controllers.ChannelController.ReorderChannels) // 批量調整類別與頻道排序
+
+	// channel 權限覆寫
+	auth.GET("/channels/:channel_id/overwrites", controllers.RoleController.GetChannelOverwrites)              // 獲取頻道權限覆寫
+	authWithCSRF.PUT("/channels/:channel_id/overwrites", controllers.RoleController.SetChannelOverwrite)       // 新增或取代頻道權限覆寫
+	authWithCSRF.DELETE("/channels/:channel_id/overwrites", controllers.RoleController.DeleteChannelOverwrite) // 刪除頻道權限覆寫
+
+	// channel message 編輯與刪除
+	authWithCSRF.PUT("/channels/:channel_id/messages/:id", controllers.ChatController.EditChannelMessage)      // 編輯頻道訊息
+	authWithCSRF.DELETE("/channels/:channel_id/messages/:id", controllers.ChatController.DeleteChannelMessage) // 刪除頻道訊息
+
+	// channel message 表情回應
+	authWithCSRF.PUT("/channels/:channel_id/messages/:id/reactions/:emoji", controllers.ChatController.AddChannelReaction)       // 新增頻道訊息表情回應
+	authWithCSRF.DELETE("/channels/:channel_id/messages/:id/reactions/:emoji", controllers.ChatController.RemoveChannelReaction) // 移除頻道訊息表情回應
+
+	// channel 討論串
+	auth.GET("/channels/:channel_id/messages/:id/thread", controllers.ChatController.GetChannelThreadMessages) // 獲取頻道討論串回覆
+
+	// channel 已讀狀態
+	authWithCSRF.PUT("/channels/:channel_id/read", controllers.ChatController.MarkChannelRead) // 標記頻道已讀
+
 	// file upload
 	// 上傳路由獨立群組，覆蓋全域的 30s timeout，改為 120s（大型檔案上傳需要更長時間）
 	uploadGroup := authWithCSRF.Group("/")
@@ -162,5 +268,20 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, redis *providers.RedisWrappe
 	uploadGroup.POST("/upload/avatar", controllers.FileController.UploadAvatar)     // 頭像上傳
 	uploadGroup.POST("/upload/document", controllers.FileController.UploadDocument) // 文件上傳
 	auth.GET("/files", controllers.FileController.GetUserFiles)                     // 獲取用戶檔案列表
+	auth.GET("/files/usage", controllers.FileController.GetStorageUsage)            // 獲取儲存用量與配額
 	authWithCSRF.DELETE("/files/:file_id", controllers.FileController.DeleteFile)   // 刪除檔案
+
+	// 檔案縮圖
+	auth.GET("/files/:file_id/variants/:size", controllers.FileController.GetFileVariant) // 獲取指定尺寸的檔案連結
+
+	// 分段上傳（可續傳），每個分段各自一個請求，大型檔案不受單次請求逾時限制
+	authWithCSRF.POST("/upload/sessions", controllers.FileController.InitiateChunkedUpload)                     // 開始分段上傳
+	auth.GET("/upload/sessions/:upload_id", controllers.FileController.GetChunkedUpload)                        // 查詢已接收的分段範圍
+	uploadGroup.PUT("/upload/sessions/:upload_id/chunks/:chunk_number", controllers.FileController.UploadChunk) // 上傳分段
+	uploadGroup.POST("/upload/sessions/:upload_id/complete", controllers.FileController.CompleteChunkedUpload)  // 合併分段並驗證雜湊
+	authWithCSRF.DELETE("/upload/sessions/:upload_id", controllers.FileController.AbortChunkedUpload)           // 放棄分段上傳
+
+	// 預簽名直接上傳（需使用 MinIO/S3），檔案內容不經過 API 伺服器
+	authWithCSRF.POST("/upload/presigned", controllers.FileController.InitiatePresignedUpload)                   // 取得預簽名上傳網址
+	authWithCSRF.POST("/upload/presigned/:upload_id/confirm", controllers.FileController.ConfirmPresignedUpload) // 確認上傳並驗證檔案
 }
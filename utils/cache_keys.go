@@ -21,3 +21,13 @@ func UserActivityThrottleCacheKey(userID string) string {
 func UserServersCacheKey(userID string) string {
 	return fmt.Sprintf("user:%s:servers", userID)
 }
+
+// TwoFactorChallengeAttemptsCacheKey 生成兩步驟登入挑戰驗證失敗次數的快取鍵
+func TwoFactorChallengeAttemptsCacheKey(challengeID string) string {
+	return fmt.Sprintf("two_factor_challenge:%s:attempts", challengeID)
+}
+
+// TwoFactorChallengeUsedCacheKey 生成兩步驟登入挑戰已使用標記的快取鍵
+func TwoFactorChallengeUsedCacheKey(challengeID string) string {
+	return fmt.Sprintf("two_factor_challenge:%s:used", challengeID)
+}
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"chat_app_backend/config"
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"time"
 
@@ -13,10 +15,24 @@ import (
 
 // AccessTokenClaims 定義了 access token 中的聲明
 type AccessTokenClaims struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid,omitempty"` // 發放此 token 的登入工作階段（refresh token 記錄 ID）
+	// 毫秒精度的簽發時間（標準 iat 僅到秒），供撤銷清單判斷同一秒內簽發的 token
+	IssuedAtMilli int64 `json:"iat_ms,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IssuedAtTime 返回 token 的簽發時間，舊版 token 沒有毫秒欄位時使用 iat
+func (c *AccessTokenClaims) IssuedAtTime() time.Time {
+	if c.IssuedAtMilli > 0 {
+		return time.UnixMilli(c.IssuedAtMilli)
+	}
+	if c.IssuedAt != nil {
+		return c.IssuedAt.Time
+	}
+	return time.Time{}
+}
+
 // RefreshTokenClaims 定義了 refresh token 中的聲明
 type RefreshTokenClaims struct {
 	UserID string `json:"user_id"`
@@ -24,12 +40,13 @@ type RefreshTokenClaims struct {
 }
 
 type TokenResponse struct {
+	ID        string // token 的唯一識別碼（jti）
 	Token     string
 	ExpiresAt int64
 }
 
-// 生成 access token
-func GenAccessToken(userID string) (TokenResponse, error) {
+// 生成 access token，sessionID 為對應的登入工作階段
+func GenAccessToken(userID string, sessionID string) (TokenResponse, error) {
 	if config.AppConfig == nil {
 		return TokenResponse{}, errors.New("config not loaded")
 	}
@@ -38,13 +55,18 @@ func GenAccessToken(userID string) (TokenResponse, error) {
 	accessTokenExpireMinutes := config.AppConfig.JWT.AccessExpireMinutes
 
 	accessTokenExpireDuration := time.Duration(accessTokenExpireMinutes) * time.Minute
-	expireTime := time.Now().Add(accessTokenExpireDuration)
+	now := time.Now()
+	expireTime := now.Add(accessTokenExpireDuration)
 	expiresAt := jwt.NewNumericDate(expireTime)
 
-	// 設置 access token 的聲明
+	// 設置 access token 的聲明，jti 與簽發時間供撤銷清單判斷
 	accessTokenClaims := &AccessTokenClaims{
-		UserID: userID,
+		UserID:        userID,
+		SessionID:     sessionID,
+		IssuedAtMilli: now.UnixMilli(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: expiresAt,
 		},
 	}
@@ -58,6 +80,7 @@ func GenAccessToken(userID string) (TokenResponse, error) {
 	}
 
 	return TokenResponse{
+		ID:        accessTokenClaims.ID,
 		Token:     accessTokenString,
 		ExpiresAt: expireTime.Unix(),
 	}, nil
@@ -94,11 +117,185 @@ func GenRefreshToken(userID string) (TokenResponse, error) {
 	}
 
 	return TokenResponse{
+		ID:        refreshTokenClaims.ID,
 		Token:     refreshTokenString,
 		ExpiresAt: expireTime.Unix(),
 	}, nil
 }
 
+// TwoFactorChallengeExpiry 兩步驟登入挑戰 token 的有效時間
+const TwoFactorChallengeExpiry = 5 * time.Minute
+
+// TwoFactorChallengeClaims 定義了兩步驟登入挑戰 token 中的聲明
+type TwoFactorChallengeClaims struct {
+	UserID string `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// twoFactorChallengeSecret 由 access token 密鑰衍生挑戰 token 的簽章密鑰
+// 使用不同密鑰簽章，避免挑戰 token 被當作 access token 使用
+func twoFactorChallengeSecret() []byte {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWT.AccessSecret))
+	mac.Write([]byte("two_factor_challenge"))
+	return mac.Sum(nil)
+}
+
+// 生成兩步驟登入挑戰 token（密碼驗證通過後發給已啟用兩步驟驗證的用戶）
+func GenTwoFactorChallengeToken(userID string) (TokenResponse, error) {
+	if config.AppConfig == nil {
+		return TokenResponse{}, errors.New("config not loaded")
+	}
+
+	expireTime := time.Now().Add(TwoFactorChallengeExpiry)
+
+	challengeClaims := &TwoFactorChallengeClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(expireTime),
+		},
+	}
+
+	challengeToken := jwt.NewWithClaims(jwt.SigningMethodHS256, challengeClaims)
+
+	challengeTokenString, err := challengeToken.SignedString(twoFactorChallengeSecret())
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	return TokenResponse{
+		ID:        challengeClaims.ID,
+		Token:     challengeTokenString,
+		ExpiresAt: expireTime.Unix(),
+	}, nil
+}
+
+// 驗證兩步驟登入挑戰 token 並返回其中的聲明（jti 用於追蹤挑戰的使用次數）
+func ParseTwoFactorChallengeToken(tokenString string) (*TwoFactorChallengeClaims, error) {
+	if config.AppConfig == nil {
+		return nil, errors.New("config not loaded")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &TwoFactorChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return twoFactorChallengeSecret(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*TwoFactorChallengeClaims)
+	if !ok || !token.Valid || claims.UserID == "" || claims.ID == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// EmailTokenPurpose 電子郵件 token 的用途，不同用途使用不同的簽章密鑰，無法互相替代
+type EmailTokenPurpose string
+
+const (
+	EmailTokenPurposeVerification  EmailTokenPurpose = "email_verification"
+	EmailTokenPurposePasswordReset EmailTokenPurpose = "password_reset"
+)
+
+const (
+	EmailVerificationTokenExpiry = 24 * time.Hour   // 電子郵件驗證連結的有效時間
+	PasswordResetTokenExpiry     = 30 * time.Minute // 重設密碼連結的有效時間
+)
+
+// EmailTokenClaims 定義了電子郵件驗證與重設密碼 token 中的聲明
+type EmailTokenClaims struct {
+	UserID  string            `json:"user_id"`
+	Email   string            `json:"email"` // 寄送時的電子郵件，信箱變更後舊 token 即失效
+	Purpose EmailTokenPurpose `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// emailTokenSecret 由 access token 密鑰依用途衍生電子郵件 token 的簽章密鑰
+func emailTokenSecret(purpose EmailTokenPurpose) []byte {
+	mac := hmac.New(sha256.New, []byte(config.AppConfig.JWT.AccessSecret))
+	mac.Write([]byte("email_token:" + string(purpose)))
+	return mac.Sum(nil)
+}
+
+// emailTokenExpiry 電子郵件 token 依用途的有效時間
+func emailTokenExpiry(purpose EmailTokenPurpose) (time.Duration, error) {
+	switch purpose {
+	case EmailTokenPurposeVerification:
+		return EmailVerificationTokenExpiry, nil
+	case EmailTokenPurposePasswordReset:
+		return PasswordResetTokenExpiry, nil
+	default:
+		return 0, errors.New("unknown email token purpose")
+	}
+}
+
+// 生成電子郵件驗證或重設密碼 token
+// 返回的 ID（jti）需記錄於用戶資料，使用時以條件更新清除，確保 token 只能使用一次
+func GenEmailToken(purpose EmailTokenPurpose, userID string, email string) (TokenResponse, error) {
+	if config.AppConfig == nil {
+		return TokenResponse{}, errors.New("config not loaded")
+	}
+
+	expiry, err := emailTokenExpiry(purpose)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	expireTime := time.Now().Add(expiry)
+
+	emailClaims := &EmailTokenClaims{
+		UserID:  userID,
+		Email:   email,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expireTime),
+		},
+	}
+
+	emailToken := jwt.NewWithClaims(jwt.SigningMethodHS256, emailClaims)
+
+	emailTokenString, err := emailToken.SignedString(emailTokenSecret(purpose))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	return TokenResponse{
+		ID:        emailClaims.ID,
+		Token:     emailTokenString,
+		ExpiresAt: expireTime.Unix(),
+	}, nil
+}
+
+// 驗證電子郵件驗證或重設密碼 token 並返回其中的聲明
+func ParseEmailToken(purpose EmailTokenPurpose, tokenString string) (*EmailTokenClaims, error) {
+	if config.AppConfig == nil {
+		return nil, errors.New("config not loaded")
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &EmailTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return emailTokenSecret(purpose), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*EmailTokenClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose || claims.UserID == "" || claims.ID == "" {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
 // 驗證 JWT access token
 func ValidateAccessToken(tokenString string) (bool, error) {
 	if config.AppConfig == nil {
@@ -129,10 +326,10 @@ func ValidateAccessToken(tokenString string) (bool, error) {
 	return false, errors.New("invalid token")
 }
 
-// 從 token 中獲取用戶 ID
-func GetUserFromToken(tokenString string) (string, primitive.ObjectID, error) {
+// 解析並驗證 access token，返回其中的聲明
+func ParseAccessToken(tokenString string) (*AccessTokenClaims, error) {
 	if config.AppConfig == nil {
-		return "", primitive.NilObjectID, errors.New("config not loaded")
+		return nil, errors.New("config not loaded")
 	}
 
 	jwtSecret := []byte(config.AppConfig.JWT.AccessSecret)
@@ -146,12 +343,22 @@ func GetUserFromToken(tokenString string) (string, primitive.ObjectID, error) {
 		return jwtSecret, nil
 	})
 	if err != nil {
-		return "", primitive.NilObjectID, err
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(*AccessTokenClaims)
 	if !ok || !token.Valid {
-		return "", primitive.NilObjectID, errors.New("invalid token")
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// 從 token 中獲取用戶 ID
+func GetUserFromToken(tokenString string) (string, primitive.ObjectID, error) {
+	claims, err := ParseAccessToken(tokenString)
+	if err != nil {
+		return "", primitive.NilObjectID, err
 	}
 
 	userObjectID, err := primitive.ObjectIDFromHex(claims.UserID)
@@ -201,3 +408,23 @@ func GetUserIDFromHeader(c *gin.Context) (string, primitive.ObjectID, error) {
 
 	return userID, userObjectID, nil
 }
+
+// 從 HTTP 請求頭中獲取目前登入工作階段 ID
+func GetSessionIDFromHeader(c *gin.Context) (string, error) {
+	// 優先檢查上下文中的工作階段資訊（用於測試環境）
+	if sessionID, exists := c.Get("session_id"); exists {
+		return sessionID.(string), nil
+	}
+
+	accessToken, err := GetAccessTokenByHeader(c)
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := ParseAccessToken(accessToken)
+	if err != nil {
+		return "", err
+	}
+
+	return claims.SessionID, nil
+}
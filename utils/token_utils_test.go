@@ -26,7 +26,7 @@ func TestGenAccessToken(t *testing.T) {
 	setupTokenConfig()
 	userID := primitive.NewObjectID().Hex()
 
-	tokenRes, err := GenAccessToken(userID)
+	tokenRes, err := GenAccessToken(userID, "")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, tokenRes.Token)
 	assert.True(t, tokenRes.ExpiresAt > time.Now().Unix())
@@ -42,6 +42,17 @@ func TestGenAccessToken(t *testing.T) {
 	claims, ok := token.Claims.(*AccessTokenClaims)
 	assert.True(t, ok)
 	assert.Equal(t, userID, claims.UserID)
+	assert.NotEmpty(t, claims.ID, "應包含 jti 以供撤銷")
+	assert.NotNil(t, claims.IssuedAt)
+	assert.Equal(t, claims.IssuedAt.Unix(), claims.IssuedAtTime().Unix(), "毫秒簽發時間應與 iat 一致")
+	assert.Equal(t, claims.IssuedAtMilli, claims.IssuedAtTime().UnixMilli())
+
+	// 每個 token 的 jti 皆不同
+	another, err := GenAccessToken(userID, "")
+	assert.NoError(t, err)
+	anotherClaims, err := ParseAccessToken(another.Token)
+	assert.NoError(t, err)
+	assert.NotEqual(t, claims.ID, anotherClaims.ID)
 }
 
 func TestGenRefreshToken(t *testing.T) {
@@ -69,7 +80,7 @@ func TestGenRefreshToken(t *testing.T) {
 func TestValidateAccessToken(t *testing.T) {
 	setupTokenConfig()
 	userID := primitive.NewObjectID().Hex()
-	tokenRes, _ := GenAccessToken(userID)
+	tokenRes, _ := GenAccessToken(userID, "")
 
 	t.Run("有效令牌", func(t *testing.T) {
 		valid, err := ValidateAccessToken(tokenRes.Token)
@@ -86,7 +97,7 @@ func TestValidateAccessToken(t *testing.T) {
 	t.Run("過期令牌", func(t *testing.T) {
 		// 建立過期的令牌
 		config.AppConfig.JWT.AccessExpireMinutes = -1
-		expiredTokenRes, _ := GenAccessToken(userID)
+		expiredTokenRes, _ := GenAccessToken(userID, "")
 		config.AppConfig.JWT.AccessExpireMinutes = 60 // 重置
 
 		valid, err := ValidateAccessToken(expiredTokenRes.Token)
@@ -99,7 +110,7 @@ func TestValidateAccessToken(t *testing.T) {
 func TestGetUserIDFromToken(t *testing.T) {
 	setupTokenConfig()
 	userID := primitive.NewObjectID().Hex()
-	tokenRes, _ := GenAccessToken(userID)
+	tokenRes, _ := GenAccessToken(userID, "")
 
 	extractedID, objectID, err := GetUserFromToken(tokenRes.Token)
 	assert.NoError(t, err)
@@ -109,3 +120,82 @@ func TestGetUserIDFromToken(t *testing.T) {
 	_, _, err = GetUserFromToken("invalidtoken")
 	assert.Error(t, err)
 }
+
+func TestTwoFactorChallengeToken(t *testing.T) {
+	setupTokenConfig()
+	userID := primitive.NewObjectID().Hex()
+
+	tokenRes, err := GenTwoFactorChallengeToken(userID)
+	assert.NoError(t, err)
+	assert.True(t, tokenRes.ExpiresAt <= time.Now().Add(TwoFactorChallengeExpiry).Unix())
+
+	claims, err := ParseTwoFactorChallengeToken(tokenRes.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+	assert.Equal(t, tokenRes.ID, claims.ID)
+
+	t.Run("挑戰 token 不能當作 access token 使用", func(t *testing.T) {
+		_, _, err := GetUserFromToken(tokenRes.Token)
+		assert.Error(t, err)
+	})
+
+	t.Run("access token 不能當作挑戰 token 使用", func(t *testing.T) {
+		accessToken, _ := GenAccessToken(userID, "")
+		_, err := ParseTwoFactorChallengeToken(accessToken.Token)
+		assert.Error(t, err)
+	})
+}
+
+func TestEmailToken(t *testing.T) {
+	setupTokenConfig()
+	userID := primitive.NewObjectID().Hex()
+
+	tokenRes, err := GenEmailToken(EmailTokenPurposePasswordReset, userID, "alice@example.com")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokenRes.ID)
+	assert.True(t, tokenRes.ExpiresAt <= time.Now().Add(PasswordResetTokenExpiry).Unix())
+
+	claims, err := ParseEmailToken(EmailTokenPurposePasswordReset, tokenRes.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+	assert.Equal(t, "alice@example.com", claims.Email)
+	assert.Equal(t, tokenRes.ID, claims.ID)
+
+	t.Run("不同用途的 token 不能互相替代", func(t *testing.T) {
+		_, err := ParseEmailToken(EmailTokenPurposeVerification, tokenRes.Token)
+		assert.Error(t, err)
+	})
+
+	t.Run("不能當作 access token 使用", func(t *testing.T) {
+		_, _, err := GetUserFromToken(tokenRes.Token)
+		assert.Error(t, err)
+	})
+
+	t.Run("access token 不能當作電子郵件 token 使用", func(t *testing.T) {
+		accessToken, _ := GenAccessToken(userID, "")
+		_, err := ParseEmailToken(EmailTokenPurposePasswordReset, accessToken.Token)
+		assert.Error(t, err)
+	})
+
+	t.Run("未知的用途", func(t *testing.T) {
+		_, err := GenEmailToken("unknown", userID, "alice@example.com")
+		assert.Error(t, err)
+	})
+}
+
+func TestParseAccessToken(t *testing.T) {
+	setupTokenConfig()
+	userID := primitive.NewObjectID().Hex()
+	sessionID := primitive.NewObjectID().Hex()
+
+	tokenRes, err := GenAccessToken(userID, sessionID)
+	assert.NoError(t, err)
+
+	claims, err := ParseAccessToken(tokenRes.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, claims.UserID)
+	assert.Equal(t, sessionID, claims.SessionID)
+
+	_, err = ParseAccessToken("invalidtoken")
+	assert.Error(t, err)
+}
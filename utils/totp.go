@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // #nosec G505 -- RFC 6238 預設演算法，驗證器 App 皆支援
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP 參數（RFC 6238 預設值，與 Google Authenticator 等驗證器相容）
+const (
+	TOTPPeriod     = 30 // 每個驗證碼的有效秒數
+	TOTPDigits     = 6  // 驗證碼位數
+	TOTPSkew       = 1  // 允許前後各一個時間步的時鐘誤差
+	TOTPSecretSize = 20 // 密鑰長度（位元組），與 HMAC-SHA1 輸出長度相同
+)
+
+// RecoveryCodeLength 復原碼長度（不含分隔符號），約 50 位元的隨機性
+const RecoveryCodeLength = 10
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret 產生 Base32 編碼的 TOTP 密鑰
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, TOTPSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("無法產生 TOTP 密鑰: %w", err)
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// BuildOTPAuthURI 產生驗證器 App 掃描用的 otpauth URI
+// 格式：otpauth://totp/{issuer}:{account}?secret=...&issuer=...&algorithm=SHA1&digits=6&period=30
+func BuildOTPAuthURI(issuer string, account string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTPDigits))
+	query.Set("period", fmt.Sprint(TOTPPeriod))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return uri.String()
+}
+
+// TOTPCounter 取得指定時間的時間步
+func TOTPCounter(t time.Time) int64 {
+	return t.Unix() / TOTPPeriod
+}
+
+// GenerateTOTPCode 產生指定時間步的驗證碼（RFC 4226 HOTP 動態截斷）
+func GenerateTOTPCode(secret string, counter int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", fmt.Errorf("無效的 TOTP 密鑰: %w", err)
+	}
+
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, uint64(counter)) //nolint:gosec // 時間步不會是負數
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for range TOTPDigits {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%modulo), nil
+}
+
+// ValidateTOTPCode 驗證指定時間的驗證碼，允許 TOTPSkew 個時間步的誤差
+// 驗證成功時返回符合的時間步，呼叫端應記錄並拒絕重複使用相同或更早的時間步
+func ValidateTOTPCode(secret string, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := TOTPCounter(t)
+	for counter := current - TOTPSkew; counter <= current+TOTPSkew; counter++ {
+		expected, err := GenerateTOTPCode(secret, counter)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes 產生一次性復原碼，格式為 xxxxx-xxxxx（小寫 Base32）
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	const charset = "abcdefghijklmnopqrstuvwxyz234567"
+
+	codes := make([]string, count)
+	buf := make([]byte, RecoveryCodeLength)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("無法產生復原碼: %w", err)
+		}
+		for j := range buf {
+			// 32 可整除 256，取餘數不會造成偏差
+			buf[j] = charset[int(buf[j])%len(charset)]
+		}
+		half := RecoveryCodeLength / 2
+		codes[i] = string(buf[:half]) + "-" + string(buf[half:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode 計算復原碼的雜湊（忽略大小寫、空白與分隔符號）
+// 復原碼具有足夠的隨機性，不需要使用 bcrypt 等慢速雜湊
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	return SHA256Hash(normalized)
+}
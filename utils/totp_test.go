@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfc6238Secret RFC 6238 附錄 B 的 SHA1 測試密鑰 "12345678901234567890"（Base32）
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// RFC 6238 附錄 B 的測試向量取後 6 位
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := GenerateTOTPCode(rfc6238Secret, TOTPCounter(time.Unix(tt.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, code)
+	}
+
+	_, err := GenerateTOTPCode("not base32!", 1)
+	assert.Error(t, err)
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := TOTPCounter(now)
+
+	counter, ok := ValidateTOTPCode(rfc6238Secret, "005924", now)
+	assert.True(t, ok)
+	assert.Equal(t, current, counter)
+
+	// 前一個時間步的驗證碼仍在容許誤差內
+	previous, _ := GenerateTOTPCode(rfc6238Secret, current-1)
+	counter, ok = ValidateTOTPCode(rfc6238Secret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, current-1, counter)
+
+	// 超過容許誤差
+	expired, _ := GenerateTOTPCode(rfc6238Secret, current-2)
+	_, ok = ValidateTOTPCode(rfc6238Secret, expired, now)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTPCode(rfc6238Secret, "12345", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32, "20 位元組的 Base32 編碼為 32 個字元")
+
+	_, err = GenerateTOTPCode(secret, 1)
+	assert.NoError(t, err)
+}
+
+func TestBuildOTPAuthURI(t *testing.T) {
+	uri := BuildOTPAuthURI("Chat App", "alice@example.com", rfc6238Secret)
+
+	parsed, err := url.Parse(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", parsed.Scheme)
+	assert.Equal(t, "totp", parsed.Host)
+	assert.Equal(t, "/Chat App:alice@example.com", parsed.Path)
+	assert.Equal(t, rfc6238Secret, parsed.Query().Get("secret"))
+	assert.Equal(t, "Chat App", parsed.Query().Get("issuer"))
+	assert.Equal(t, "6", parsed.Query().Get("digits"))
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+
+	pattern := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Regexp(t, pattern, code)
+		assert.False(t, seen[code], "復原碼不應重複")
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	assert.Equal(t, HashRecoveryCode("abcde-fghij"), HashRecoveryCode(" ABCDE FGHIJ "))
+	assert.Equal(t, HashRecoveryCode("abcde-fghij"), HashRecoveryCode("abcdefghij"))
+	assert.NotEqual(t, HashRecoveryCode("abcde-fghij"), HashRecoveryCode("abcde-fghik"))
+}